@@ -8,13 +8,22 @@ import (
 	"encoding/pem"
 	"fmt"
 	"github.com/ardanlabs/conf"
+	"github.com/dgrijalva/jwt-go"
 	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/archive"
+	"github.com/remisb/restaurant/internal/organization"
 	"github.com/remisb/restaurant/internal/platform/auth"
 	"github.com/remisb/restaurant/internal/platform/database"
+	"github.com/remisb/restaurant/internal/platform/s3"
 	"github.com/remisb/restaurant/internal/schema"
 	"github.com/remisb/restaurant/internal/user"
+	"github.com/remisb/restaurant/internal/vote"
+	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -35,6 +44,21 @@ func run() error {
 			Name       string `conf:"default:postgres"`
 			DisableTLS bool   `conf:"default:false"`
 		}
+		Auth struct {
+			KeyID          string        `conf:"default:1"`
+			PrivateKeyFile string        `conf:"default:/app/private.pem"`
+			Algorithm      string        `conf:"default:RS256"`
+			TokenExpires   time.Duration `conf:"default:1h"`
+		}
+		Archive struct {
+			Endpoint        string `conf:"default:s3.amazonaws.com"`
+			Region          string `conf:"default:us-east-1"`
+			Bucket          string `conf:"default:restaurant-archive"`
+			AccessKeyID     string
+			SecretAccessKey string `conf:"noprint"`
+			DisableTLS      bool   `conf:"default:false"`
+			Purge           bool   `conf:"default:false"`
+		}
 		Args conf.Args
 	}
 
@@ -61,13 +85,29 @@ func run() error {
 	var err error
 	switch cfg.Args.Num(0) {
 	case "migrate":
-		err = migrate(dbConfig)
+		err = migrate(dbConfig, cfg.Args.Num(1), cfg.Args.Num(2))
 	case "seed":
-		err = seed(dbConfig)
+		err = seed(dbConfig, cfg.Args.Num(1))
 	case "useradd":
-		err = userAdd(dbConfig, cfg.Args.Num(1), cfg.Args.Num(2))
+		err = userAdd(dbConfig, cfg.Args.Num(1), cfg.Args.Num(2), cfg.Args.Num(3), cfg.Args.Num(4))
+	case "orgadd":
+		err = orgAdd(dbConfig, cfg.Args.Num(1))
 	case "keygen":
 		err = keygen(cfg.Args.Num(1))
+	case "gentoken":
+		err = genToken(dbConfig, cfg.Auth.PrivateKeyFile, cfg.Auth.KeyID, cfg.Auth.Algorithm, cfg.Auth.TokenExpires, cfg.Args.Num(1))
+	case "archive":
+		s3Config := s3.Config{
+			Endpoint:        cfg.Archive.Endpoint,
+			Region:          cfg.Archive.Region,
+			Bucket:          cfg.Archive.Bucket,
+			AccessKeyID:     cfg.Archive.AccessKeyID,
+			SecretAccessKey: cfg.Archive.SecretAccessKey,
+			DisableTLS:      cfg.Archive.DisableTLS,
+		}
+		err = archiveMonth(dbConfig, s3Config, cfg.Archive.Purge, cfg.Args.Num(1))
+	case "votes":
+		err = votes(dbConfig, cfg.Args.Num(1), cfg.Args.Num(2), cfg.Args.Num(3))
 	default:
 		err = errors.New("Must specify a command")
 	}
@@ -79,29 +119,150 @@ func run() error {
 	return nil
 }
 
-func migrate(cfg database.Config) error {
+// migrate manages the schema of the database. The action is up, the default,
+// to apply the pending migrations, down to revert the last n, status to list
+// them or force to record the schema at a version without running anything.
+func migrate(cfg database.Config, action, arg string) error {
+	db, err := database.Open(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+
+	switch action {
+	case "", "up":
+		if err := schema.Migrate(ctx, db); err != nil {
+			return err
+		}
+		fmt.Println("Migrations complete")
+
+	case "down":
+		n := 1
+		if arg != "" {
+			if n, err = strconv.Atoi(arg); err != nil || n < 1 {
+				return errors.New("migrate down takes the number of migrations to revert")
+			}
+		}
+		if err := schema.Down(ctx, db, n); err != nil {
+			return err
+		}
+		fmt.Println("Migrations reverted")
+
+	case "status":
+		statuses, err := schema.Status(ctx, db)
+		if err != nil {
+			return err
+		}
+		for _, st := range statuses {
+			applied := "pending"
+			if st.AppliedAt != nil {
+				applied = st.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%4d  %-32s %s\n", st.Version, st.Description, applied)
+		}
+
+	case "force":
+		version, err := strconv.Atoi(arg)
+		if err != nil {
+			return errors.New("migrate force takes the version to record")
+		}
+		if err := schema.Force(ctx, db, version); err != nil {
+			return err
+		}
+		fmt.Printf("Schema recorded at version %d\n", version)
+
+	default:
+		return errors.Errorf("unknown migrate action %q, use up, down, status or force", action)
+	}
+
+	return nil
+}
+
+// votes audits the vote log. The action is log to print the events of a
+// date, recount to replay them into the tallies of a date in an organization
+// or rebuild to replace the votes and tallies of the dates from through to
+// with the ones replayed from the log. Dates default to today.
+func votes(cfg database.Config, action, arg1, arg2 string) error {
 	db, err := database.Open(cfg)
 	if err != nil {
 		return err
 	}
 	defer db.Close()
 
-	if err := schema.Migrate(db); err != nil {
+	ctx := context.Background()
+	now := time.Now()
+
+	from, err := vote.ParseDate(arg1, now)
+	if err != nil {
 		return err
 	}
 
-	fmt.Println("Migrations complete")
+	switch action {
+	case "log":
+		err := vote.Log(ctx, db, from, from, "", func(e vote.Event) error {
+			fmt.Printf("%8d  %s  %-9s %s  %s  %s\n", e.Seq, e.TimeOccurred.Format(time.RFC3339), e.Type, e.OrgID, e.UserID, e.RestaurantID)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+	case "recount":
+		org := arg2
+		if org == "" {
+			org = auth.DefaultOrg
+		}
+		tallies, err := vote.Recount(ctx, db, from, org)
+		if err != nil {
+			return err
+		}
+		for _, t := range tallies {
+			fmt.Printf("%s  %d\n", t.RestaurantID, t.Votes)
+		}
+
+	case "rebuild":
+		to := from
+		if arg2 != "" {
+			if to, err = vote.ParseDate(arg2, now); err != nil {
+				return err
+			}
+		}
+		n, err := vote.Rebuild(ctx, db, from, to)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Rebuilt %d votes\n", n)
+
+	default:
+		return errors.Errorf("unknown votes action %q, use log, recount or rebuild", action)
+	}
+
 	return nil
 }
 
-func seed(cfg database.Config) error {
+// seed loads seed data into the database. The source is the name of a seed
+// profile, dev by default, or the path of an SQL or YAML seed file.
+func seed(cfg database.Config, source string) error {
 	db, err := database.Open(cfg)
 	if err != nil {
 		return err
 	}
 	defer db.Close()
 
-	if err := schema.Seed(db); err != nil {
+	switch ext := filepath.Ext(source); {
+	case source == "":
+		err = schema.Seed(db)
+	case ext == ".sql" || ext == ".yaml" || ext == ".yml":
+		err = schema.SeedFile(db, source)
+	default:
+		err = schema.SeedProfile(db, source)
+		if err == schema.ErrUnknownProfile {
+			return errors.Errorf("unknown seed profile %q, use one of %s", source, strings.Join(schema.Profiles(), ", "))
+		}
+	}
+	if err != nil {
 		return err
 	}
 
@@ -109,7 +270,10 @@ func seed(cfg database.Config) error {
 	return nil
 }
 
-func userAdd(cfg database.Config, email, password string) error {
+// userAdd creates an administrator, which is how the first one is
+// bootstrapped. The name defaults to "Admin" and the organization to the
+// default one.
+func userAdd(cfg database.Config, email, password, name, orgID string) error {
 	db, err := database.Open(cfg)
 	if err != nil {
 		return err
@@ -135,11 +299,23 @@ func userAdd(cfg database.Config, email, password string) error {
 
 	ctx := context.Background()
 
+	if name == "" {
+		name = "Admin"
+	}
+
 	nu := user.NewUser{
+		Name:            name,
 		Email:           email,
 		Password:        password,
 		PasswordConfirm: password,
 		Roles:           []string{auth.RoleAdmin, auth.RoleUser},
+		OrgID:           orgID,
+	}
+
+	if orgID != "" {
+		if _, err := organization.Retrieve(ctx, db, orgID); err != nil {
+			return errors.Wrapf(err, "retrieving organization %s", orgID)
+		}
 	}
 
 	u, err := user.Create(ctx, db, nu, time.Now())
@@ -151,6 +327,64 @@ func userAdd(cfg database.Config, email, password string) error {
 	return nil
 }
 
+// orgAdd creates an organization. Its first admin is then created with
+// useradd.
+func orgAdd(cfg database.Config, name string) error {
+	if name == "" {
+		return errors.New("orgadd command must be called with the name of the organization")
+	}
+
+	db, err := database.Open(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	org, err := organization.Create(context.Background(), db, organization.NewOrganization{Name: name}, time.Now())
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Organization created with id:", org.ID)
+	return nil
+}
+
+// archiveMonth exports the historical data of a month to object storage. The
+// month is given as YYYY-MM and defaults to the previous month so the command
+// can be scheduled to run at the start of every month.
+func archiveMonth(cfg database.Config, s3Config s3.Config, purge bool, month string) error {
+	db, err := database.Open(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	now := time.Now()
+	m := now.AddDate(0, -1, 0)
+	if month != "" {
+		if m, err = time.Parse("2006-01", month); err != nil {
+			return errors.Wrap(err, "archive month must be formatted as YYYY-MM")
+		}
+	}
+
+	bucket, err := s3.Open(s3Config)
+	if err != nil {
+		return err
+	}
+	store := archive.NewS3(bucket)
+
+	manifests, err := archive.Export(context.Background(), db, store, m, purge, now)
+	if err != nil {
+		return err
+	}
+
+	for _, mf := range manifests {
+		fmt.Printf("Archived %d %s rows to %s\n", mf.Rows, mf.Dataset, mf.ObjectKey)
+	}
+	fmt.Println("Archive complete")
+	return nil
+}
+
 // keygen creates an x509 private key for signing auth tokens.
 func keygen(path string) error {
 	if path == "" {
@@ -161,7 +395,7 @@ func keygen(path string) error {
 	if err != nil {
 		return errors.Wrap(err, "creating private file")
 	}
-	file, err := os.Create(path)
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
 	if err != nil {
 		return errors.Wrap(err, "creating private key file")
 	}
@@ -182,3 +416,52 @@ func keygen(path string) error {
 
 	return nil
 }
+
+// genToken mints a token for the identified user signed with the private key
+// of the API, which is handy to debug requests made on their behalf.
+func genToken(cfg database.Config, privateKeyFile, keyID, algorithm string, expires time.Duration, id string) error {
+	if id == "" {
+		return errors.New("gentoken command must be called with the id of the user")
+	}
+
+	db, err := database.Open(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	keyContents, err := ioutil.ReadFile(privateKeyFile)
+	if err != nil {
+		return errors.Wrap(err, "reading auth private key")
+	}
+
+	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(keyContents)
+	if err != nil {
+		return errors.Wrap(err, "parsing auth private key")
+	}
+
+	f := auth.NewSimpleKeyLookupFunc(keyID, privateKey.Public().(*rsa.PublicKey))
+	authenticator, err := auth.NewAuthenticator(privateKey, keyID, algorithm, f)
+	if err != nil {
+		return errors.Wrap(err, "constructing authenticator")
+	}
+
+	// The command runs with the rights of an administrator to look up any user.
+	now := time.Now()
+	admin := auth.NewClaims("", []string{auth.RoleAdmin}, now, time.Minute)
+
+	u, err := user.Retrieve(context.Background(), admin, db, id)
+	if err != nil {
+		return errors.Wrapf(err, "retrieving user %s", id)
+	}
+
+	claims := auth.NewClaims(u.ID, u.Roles, now, expires)
+	claims.OrgID = u.OrgID
+	token, err := authenticator.GenerateToken(claims)
+	if err != nil {
+		return errors.Wrap(err, "generating token")
+	}
+
+	fmt.Printf("Token expires at %s\n%s\n", now.Add(expires).Format(time.RFC3339), token)
+	return nil
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/analytics"
+	"github.com/remisb/restaurant/internal/platform/web"
+	"go.opentelemetry.io/otel"
+)
+
+// Analytics represents the reporting API method handler set. The reports
+// are read from the replica when there is one.
+type Analytics struct {
+	db *sqlx.DB
+}
+
+// Votes reports the votes cast for the dates of the from and to query
+// parameters grouped by the restaurant or the weekday of the group_by query
+// parameter, restaurant by default.
+func (a *Analytics) Votes(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Analytics.Votes")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	from, to, err := parseDateRange(r, v.Now)
+	if err != nil {
+		return err
+	}
+
+	groupBy := r.URL.Query().Get("group_by")
+	if groupBy == "" {
+		groupBy = analytics.GroupByRestaurant
+	}
+
+	rep, err := analytics.VoteReport(ctx, a.db, from, to, groupBy)
+	if err != nil {
+		switch err {
+		case analytics.ErrInvalidGroup:
+			return requestError(err, http.StatusBadRequest)
+		default:
+			return errors.Wrapf(err, "from %s to %s", from.Format("2006-01-02"), to.Format("2006-01-02"))
+		}
+	}
+
+	return web.Respond(ctx, w, rep, http.StatusOK)
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/audit"
+	"github.com/remisb/restaurant/internal/platform/database"
+	"github.com/remisb/restaurant/internal/platform/web"
+	"github.com/remisb/restaurant/internal/restaurant"
+	"go.opentelemetry.io/otel"
+)
+
+// Audit represents the change history API method handler set.
+type Audit struct {
+	db          *sqlx.DB
+	restaurants restaurant.Store
+}
+
+// List returns who changed the restaurant and its menus, when and what,
+// newest first. Only its owner and admins may see it.
+func (a *Audit) List(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Audit.List")
+	defer span.End()
+
+	id := params["id"]
+	if err := checkRestaurantOwner(ctx, a.restaurants, id); err != nil {
+		return err
+	}
+
+	entries, err := audit.List(ctx, database.Conn(ctx, a.db), id)
+	if err != nil {
+		return errors.Wrapf(err, "ID: %s", id)
+	}
+
+	return web.Respond(ctx, w, entries, http.StatusOK)
+}
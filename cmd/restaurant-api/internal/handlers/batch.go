@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/platform/database"
+	"github.com/remisb/restaurant/internal/platform/web"
+	"go.opentelemetry.io/otel"
+)
+
+// batchRequest is a request made as part of a batch.
+type batchRequest struct {
+	Method  string            `json:"method" validate:"required,oneof=GET POST PUT DELETE"`
+	Path    string            `json:"path" validate:"required,startswith=/v1/"`
+	Headers map[string]string `json:"headers"`
+	Body    json.RawMessage   `json:"body"`
+}
+
+// newBatch is what we require from clients to run a batch of at most 100
+// sub-requests. When Transaction is set the sub-requests run in one database
+// transaction which is only committed if all of them succeed.
+type newBatch struct {
+	Transaction bool           `json:"transaction"`
+	Requests    []batchRequest `json:"requests" validate:"required,min=1,max=100,dive"`
+}
+
+// batchResponse is the outcome of a sub-request. Skipped sub-requests were
+// not run because an earlier one of a transaction failed.
+type batchResponse struct {
+	Status  int             `json:"status"`
+	Skipped bool            `json:"skipped,omitempty"`
+	Body    json.RawMessage `json:"body,omitempty"`
+}
+
+// batchResult is the response to a batch.
+type batchResult struct {
+	Committed bool            `json:"committed,omitempty"`
+	Responses []batchResponse `json:"responses"`
+}
+
+// Batch represents the batch request API method handler set.
+type Batch struct {
+	db  *sqlx.DB
+	app http.Handler
+}
+
+// Run executes the sub-requests of a batch in order through the API, with
+// the credentials of the caller, and returns the outcome of each.
+func (b *Batch) Run(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Batch.Run")
+	defer span.End()
+
+	var nb newBatch
+	if err := web.Decode(r, &nb); err != nil {
+		return errors.Wrap(err, "decoding batch")
+	}
+
+	for _, br := range nb.Requests {
+		if strings.HasPrefix(br.Path, "/v1/batch") {
+			err := errors.New("batches can not be nested")
+			return requestError(err, http.StatusBadRequest)
+		}
+	}
+
+	res := batchResult{
+		Responses: make([]batchResponse, len(nb.Requests)),
+	}
+
+	if !nb.Transaction {
+		for i, br := range nb.Requests {
+			res.Responses[i] = b.serve(ctx, r, br, false)
+		}
+		return web.Respond(ctx, w, res, http.StatusOK)
+	}
+
+	tx, err := b.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "beginning transaction")
+	}
+	defer tx.Rollback()
+
+	txCtx := database.WithTx(ctx, tx)
+
+	failed := false
+	for i, br := range nb.Requests {
+		if failed {
+			res.Responses[i] = batchResponse{Skipped: true}
+			continue
+		}
+
+		res.Responses[i] = b.serve(txCtx, r, br, true)
+		if res.Responses[i].Status >= http.StatusBadRequest {
+			failed = true
+		}
+	}
+
+	if !failed {
+		if err := tx.Commit(); err != nil {
+			return errors.Wrap(err, "committing batch")
+		}
+		res.Committed = true
+
+		// The streams and queues woken up by the sub-requests only learn
+		// about their changes now.
+		database.Committed(txCtx)
+	}
+
+	return web.Respond(ctx, w, res, http.StatusOK)
+}
+
+// serve runs the sub-request through the API and records its response.
+// Idempotency keys are dropped within a transaction since the stored response
+// would outlive a change which is rolled back.
+func (b *Batch) serve(ctx context.Context, parent *http.Request, br batchRequest, inTx bool) batchResponse {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Batch.serve")
+	defer span.End()
+
+	var body []byte
+	if len(br.Body) > 0 && string(br.Body) != "null" {
+		body = br.Body
+	}
+
+	req, err := http.NewRequestWithContext(ctx, br.Method, br.Path, bytes.NewReader(body))
+	if err != nil {
+		return batchResponse{Status: http.StatusBadRequest}
+	}
+	req.RemoteAddr = parent.RemoteAddr
+	req.Host = parent.Host
+	for k, v := range br.Headers {
+		req.Header.Set(k, v)
+	}
+	if inTx {
+		req.Header.Del("Idempotency-Key")
+	}
+	if auth := parent.Header.Get("Authorization"); auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	rec := batchRecorder{
+		header: make(http.Header),
+		status: http.StatusOK,
+	}
+	b.app.ServeHTTP(&rec, req)
+
+	resp := batchResponse{
+		Status: rec.status,
+	}
+	if rec.body.Len() > 0 && json.Valid(rec.body.Bytes()) {
+		resp.Body = rec.body.Bytes()
+	}
+
+	return resp
+}
+
+// batchRecorder is the http.ResponseWriter of a sub-request.
+type batchRecorder struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+// Header implements the http.ResponseWriter interface.
+func (rec *batchRecorder) Header() http.Header {
+	return rec.header
+}
+
+// WriteHeader implements the http.ResponseWriter interface.
+func (rec *batchRecorder) WriteHeader(status int) {
+	if rec.wroteHeader {
+		return
+	}
+	rec.wroteHeader = true
+	rec.status = status
+}
+
+// Write implements the http.ResponseWriter interface.
+func (rec *batchRecorder) Write(b []byte) (int, error) {
+	rec.WriteHeader(http.StatusOK)
+	return rec.body.Write(b)
+}
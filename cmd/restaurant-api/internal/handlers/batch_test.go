@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/database"
+	"github.com/remisb/restaurant/internal/tests"
+	"github.com/remisb/restaurant/internal/vote"
+)
+
+// TestBatch validates sub-requests run in order with the credentials of the
+// caller.
+func TestBatch(t *testing.T) {
+	var paths []string
+	app := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/v1/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error":"not found"}`)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"id":"1"}`)
+	})
+	b := Batch{app: app}
+
+	t.Log("Given the need to run requests in batches.")
+	{
+		t.Log("\tTest 0:\tWhen a sub-request fails outside a transaction.")
+		{
+			body := `{"requests":[
+				{"method":"POST","path":"/v1/restaurant","body":{"name":"Pasta"}},
+				{"method":"GET","path":"/v1/missing"},
+				{"method":"POST","path":"/v1/restaurant","body":{"name":"Pizza"}}
+			]}`
+
+			r := httptest.NewRequest(http.MethodPost, "/v1/batch", strings.NewReader(body))
+			r.Header.Set("Authorization", "Bearer token")
+
+			w := serveRequest(b.Run, r, nil, userClaims(ownerID, auth.RoleUser))
+			if w.Code != http.StatusOK {
+				t.Fatalf("\t%s\tShould receive a status code of 200 : got %d : %s", tests.Failed, w.Code, w.Body)
+			}
+			t.Logf("\t%s\tShould receive a status code of 200.", tests.Success)
+
+			var res batchResult
+			if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+				t.Fatalf("\t%s\tShould decode the response : %s.", tests.Failed, err)
+			}
+
+			want := []int{http.StatusCreated, http.StatusNotFound, http.StatusCreated}
+			for i, st := range want {
+				if res.Responses[i].Status != st {
+					t.Fatalf("\t%s\tShould run every sub-request : got %+v.", tests.Failed, res.Responses)
+				}
+			}
+			if len(paths) != 3 || paths[1] != "GET /v1/missing" {
+				t.Fatalf("\t%s\tShould run every sub-request in order : got %v.", tests.Failed, paths)
+			}
+			t.Logf("\t%s\tShould run every sub-request in order.", tests.Success)
+
+			if string(res.Responses[0].Body) != `{"id":"1"}` {
+				t.Fatalf("\t%s\tShould return the body of each sub-request : got %s.", tests.Failed, res.Responses[0].Body)
+			}
+			t.Logf("\t%s\tShould return the body of each sub-request.", tests.Success)
+		}
+
+		t.Log("\tTest 1:\tWhen a batch is nested.")
+		{
+			body := `{"requests":[{"method":"POST","path":"/v1/batch","body":{"requests":[]}}]}`
+
+			w := serve(b.Run, http.MethodPost, body, nil, userClaims(ownerID, auth.RoleUser))
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("\t%s\tShould receive a status code of 400 : got %d.", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould receive a status code of 400.", tests.Success)
+		}
+	}
+}
+
+// TestBatchAfterCommit validates the vote streams only learn about a vote
+// cast within a batch once the batch is committed.
+func TestBatchAfterCommit(t *testing.T) {
+	t.Log("Given the need to keep the changes of a batch to itself until it is committed.")
+	{
+		t.Log("\tTest 0:\tWhen a vote is cast within a transaction.")
+		{
+			hub := vote.NewHub()
+			vt := Vote{store: newVotes(), policy: votePolicy, hub: hub}
+
+			changed, unsubscribe := hub.Subscribe(now)
+			defer unsubscribe()
+
+			ctx := database.WithTx(context.Background(), &sqlx.Tx{})
+			body := `{"restaurant_id":"` + votedID + `"}`
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)).WithContext(ctx)
+
+			if w := serveRequest(vt.Cast, r, nil, userClaims(otherID, auth.RoleUser)); w.Code != http.StatusCreated {
+				t.Fatalf("\t%s\tShould cast the vote : got %d : %s", tests.Failed, w.Code, w.Body)
+			}
+
+			select {
+			case <-changed:
+				t.Fatalf("\t%s\tShould not wake up the streams before the commit.", tests.Failed)
+			default:
+			}
+			t.Logf("\t%s\tShould not wake up the streams before the commit.", tests.Success)
+
+			database.Committed(ctx)
+
+			select {
+			case <-changed:
+			default:
+				t.Fatalf("\t%s\tShould wake up the streams once committed.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould wake up the streams once committed.", tests.Success)
+		}
+	}
+}
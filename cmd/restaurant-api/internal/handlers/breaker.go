@@ -0,0 +1,375 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/breaker"
+	"github.com/remisb/restaurant/internal/platform/geo"
+	"github.com/remisb/restaurant/internal/restaurant"
+	"github.com/remisb/restaurant/internal/user"
+	"github.com/remisb/restaurant/internal/vote"
+)
+
+// withBreaker returns the stores with their calls going through the circuit
+// breaker. A nil breaker leaves them as they are.
+func (s Stores) withBreaker(b *breaker.Breaker) Stores {
+	if b == nil {
+		return s
+	}
+	return Stores{
+		Restaurants: &breakerRestaurants{next: s.Restaurants, b: b},
+		Menus:       &breakerMenus{next: s.Menus, b: b},
+		Users:       &breakerUsers{next: s.Users, b: b},
+		Votes:       &breakerVotes{next: s.Votes, b: b},
+	}
+}
+
+// guard runs a store call through the breaker. A call rejected by the open
+// breaker responds with a 503 the clients can tell apart from other failures.
+func guard(ctx context.Context, b *breaker.Breaker, fn func(ctx context.Context) error) error {
+	err := b.Do(ctx, fn)
+	if err == breaker.ErrOpen {
+		return requestError(err, http.StatusServiceUnavailable)
+	}
+	return err
+}
+
+// breakerRestaurants guards a restaurant.Store with the breaker.
+type breakerRestaurants struct {
+	next restaurant.Store
+	b    *breaker.Breaker
+}
+
+// List implements the restaurant.Store interface.
+func (s *breakerRestaurants) List(ctx context.Context) ([]restaurant.Restaurant, error) {
+	var rs []restaurant.Restaurant
+	err := guard(ctx, s.b, func(ctx context.Context) error {
+		var err error
+		rs, err = s.next.List(ctx)
+		return err
+	})
+	return rs, err
+}
+
+// ListNearby implements the restaurant.Store interface.
+func (s *breakerRestaurants) ListNearby(ctx context.Context, near geo.Point, radius float64) ([]restaurant.Nearby, error) {
+	var rs []restaurant.Nearby
+	err := guard(ctx, s.b, func(ctx context.Context) error {
+		var err error
+		rs, err = s.next.ListNearby(ctx, near, radius)
+		return err
+	})
+	return rs, err
+}
+
+// FindSimilar implements the restaurant.Store interface.
+func (s *breakerRestaurants) FindSimilar(ctx context.Context, name, address string) ([]restaurant.Match, error) {
+	var ms []restaurant.Match
+	err := guard(ctx, s.b, func(ctx context.Context) error {
+		var err error
+		ms, err = s.next.FindSimilar(ctx, name, address)
+		return err
+	})
+	return ms, err
+}
+
+// Create implements the restaurant.Store interface.
+func (s *breakerRestaurants) Create(ctx context.Context, user auth.Claims, nr restaurant.NewRestaurant, now time.Time) (*restaurant.Restaurant, error) {
+	var r *restaurant.Restaurant
+	err := guard(ctx, s.b, func(ctx context.Context) error {
+		var err error
+		r, err = s.next.Create(ctx, user, nr, now)
+		return err
+	})
+	return r, err
+}
+
+// Retrieve implements the restaurant.Store interface.
+func (s *breakerRestaurants) Retrieve(ctx context.Context, id string) (*restaurant.Restaurant, error) {
+	var r *restaurant.Restaurant
+	err := guard(ctx, s.b, func(ctx context.Context) error {
+		var err error
+		r, err = s.next.Retrieve(ctx, id)
+		return err
+	})
+	return r, err
+}
+
+// Update implements the restaurant.Store interface.
+func (s *breakerRestaurants) Update(ctx context.Context, user auth.Claims, id string, update restaurant.UpdateRestaurant, now time.Time) (*restaurant.Restaurant, error) {
+	var r *restaurant.Restaurant
+	err := guard(ctx, s.b, func(ctx context.Context) error {
+		var err error
+		r, err = s.next.Update(ctx, user, id, update, now)
+		return err
+	})
+	return r, err
+}
+
+// Delete implements the restaurant.Store interface.
+func (s *breakerRestaurants) Delete(ctx context.Context, id string, now time.Time) error {
+	return guard(ctx, s.b, func(ctx context.Context) error {
+		return s.next.Delete(ctx, id, now)
+	})
+}
+
+// AddFavorite implements the restaurant.Store interface.
+func (s *breakerRestaurants) AddFavorite(ctx context.Context, userID, id string, now time.Time) error {
+	return guard(ctx, s.b, func(ctx context.Context) error {
+		return s.next.AddFavorite(ctx, userID, id, now)
+	})
+}
+
+// RemoveFavorite implements the restaurant.Store interface.
+func (s *breakerRestaurants) RemoveFavorite(ctx context.Context, userID, id string) error {
+	return guard(ctx, s.b, func(ctx context.Context) error {
+		return s.next.RemoveFavorite(ctx, userID, id)
+	})
+}
+
+// ListFavorites implements the restaurant.Store interface.
+func (s *breakerRestaurants) ListFavorites(ctx context.Context, userID string) ([]restaurant.Restaurant, error) {
+	var rs []restaurant.Restaurant
+	err := guard(ctx, s.b, func(ctx context.Context) error {
+		var err error
+		rs, err = s.next.ListFavorites(ctx, userID)
+		return err
+	})
+	return rs, err
+}
+
+// ListIncluded implements the restaurant.Store interface.
+func (s *breakerRestaurants) ListIncluded(ctx context.Context, ids []string, in restaurant.Include, now time.Time) (map[string]restaurant.Included, error) {
+	var included map[string]restaurant.Included
+	err := guard(ctx, s.b, func(ctx context.Context) error {
+		var err error
+		included, err = s.next.ListIncluded(ctx, ids, in, now)
+		return err
+	})
+	return included, err
+}
+
+// breakerMenus guards a restaurant.MenuStore with the breaker.
+type breakerMenus struct {
+	next restaurant.MenuStore
+	b    *breaker.Breaker
+}
+
+// CreateMenu implements the restaurant.MenuStore interface.
+func (s *breakerMenus) CreateMenu(ctx context.Context, user auth.Claims, nm restaurant.NewMenu, now time.Time) (*restaurant.Menu, error) {
+	var m *restaurant.Menu
+	err := guard(ctx, s.b, func(ctx context.Context) error {
+		var err error
+		m, err = s.next.CreateMenu(ctx, user, nm, now)
+		return err
+	})
+	return m, err
+}
+
+// RetrieveMenu implements the restaurant.MenuStore interface.
+func (s *breakerMenus) RetrieveMenu(ctx context.Context, id string) (*restaurant.Menu, error) {
+	var m *restaurant.Menu
+	err := guard(ctx, s.b, func(ctx context.Context) error {
+		var err error
+		m, err = s.next.RetrieveMenu(ctx, id)
+		return err
+	})
+	return m, err
+}
+
+// ListMenus implements the restaurant.MenuStore interface.
+func (s *breakerMenus) ListMenus(ctx context.Context, restaurantID string, from time.Time) ([]restaurant.Menu, error) {
+	var ms []restaurant.Menu
+	err := guard(ctx, s.b, func(ctx context.Context) error {
+		var err error
+		ms, err = s.next.ListMenus(ctx, restaurantID, from)
+		return err
+	})
+	return ms, err
+}
+
+// UpdateMenu implements the restaurant.MenuStore interface.
+func (s *breakerMenus) UpdateMenu(ctx context.Context, user auth.Claims, restaurantID string, update restaurant.UpdateMenu, now time.Time) (*restaurant.Menu, error) {
+	var m *restaurant.Menu
+	err := guard(ctx, s.b, func(ctx context.Context) error {
+		var err error
+		m, err = s.next.UpdateMenu(ctx, user, restaurantID, update, now)
+		return err
+	})
+	return m, err
+}
+
+// breakerUsers guards a user.Store with the breaker.
+type breakerUsers struct {
+	next user.Store
+	b    *breaker.Breaker
+}
+
+// List implements the user.Store interface.
+func (s *breakerUsers) List(ctx context.Context) ([]user.User, error) {
+	var us []user.User
+	err := guard(ctx, s.b, func(ctx context.Context) error {
+		var err error
+		us, err = s.next.List(ctx)
+		return err
+	})
+	return us, err
+}
+
+// Retrieve implements the user.Store interface.
+func (s *breakerUsers) Retrieve(ctx context.Context, claims auth.Claims, id string) (*user.User, error) {
+	var u *user.User
+	err := guard(ctx, s.b, func(ctx context.Context) error {
+		var err error
+		u, err = s.next.Retrieve(ctx, claims, id)
+		return err
+	})
+	return u, err
+}
+
+// Create implements the user.Store interface.
+func (s *breakerUsers) Create(ctx context.Context, n user.NewUser, now time.Time) (*user.User, error) {
+	var u *user.User
+	err := guard(ctx, s.b, func(ctx context.Context) error {
+		var err error
+		u, err = s.next.Create(ctx, n, now)
+		return err
+	})
+	return u, err
+}
+
+// Update implements the user.Store interface.
+func (s *breakerUsers) Update(ctx context.Context, claims auth.Claims, id string, upd user.UpdateUser, now time.Time) error {
+	return guard(ctx, s.b, func(ctx context.Context) error {
+		return s.next.Update(ctx, claims, id, upd, now)
+	})
+}
+
+// Delete implements the user.Store interface.
+func (s *breakerUsers) Delete(ctx context.Context, id string, now time.Time) error {
+	return guard(ctx, s.b, func(ctx context.Context) error {
+		return s.next.Delete(ctx, id, now)
+	})
+}
+
+// Authenticate implements the user.Store interface.
+func (s *breakerUsers) Authenticate(ctx context.Context, now time.Time, email, password string) (auth.Claims, error) {
+	var claims auth.Claims
+	err := guard(ctx, s.b, func(ctx context.Context) error {
+		var err error
+		claims, err = s.next.Authenticate(ctx, now, email, password)
+		return err
+	})
+	return claims, err
+}
+
+// breakerVotes guards a vote.Store with the breaker.
+type breakerVotes struct {
+	next vote.Store
+	b    *breaker.Breaker
+}
+
+// Cast implements the vote.Store interface.
+func (s *breakerVotes) Cast(ctx context.Context, user auth.Claims, nv vote.NewVote, policy vote.Policy, now time.Time) (*vote.Vote, error) {
+	var v *vote.Vote
+	err := guard(ctx, s.b, func(ctx context.Context) error {
+		var err error
+		v, err = s.next.Cast(ctx, user, nv, policy, now)
+		return err
+	})
+	return v, err
+}
+
+// Retract implements the vote.Store interface.
+func (s *breakerVotes) Retract(ctx context.Context, user auth.Claims, date time.Time, policy vote.Policy, now time.Time) error {
+	return guard(ctx, s.b, func(ctx context.Context) error {
+		return s.next.Retract(ctx, user, date, policy, now)
+	})
+}
+
+// Tallies implements the vote.Store interface.
+func (s *breakerVotes) Tallies(ctx context.Context, date time.Time) ([]vote.Tally, error) {
+	var ts []vote.Tally
+	err := guard(ctx, s.b, func(ctx context.Context) error {
+		var err error
+		ts, err = s.next.Tallies(ctx, date)
+		return err
+	})
+	return ts, err
+}
+
+// RetrieveTally implements the vote.Store interface.
+func (s *breakerVotes) RetrieveTally(ctx context.Context, restaurantID string, date time.Time) (*vote.Tally, error) {
+	var t *vote.Tally
+	err := guard(ctx, s.b, func(ctx context.Context) error {
+		var err error
+		t, err = s.next.RetrieveTally(ctx, restaurantID, date)
+		return err
+	})
+	return t, err
+}
+
+// Voters implements the vote.Store interface.
+func (s *breakerVotes) Voters(ctx context.Context, restaurantID string, date time.Time) ([]string, error) {
+	var voters []string
+	err := guard(ctx, s.b, func(ctx context.Context) error {
+		var err error
+		voters, err = s.next.Voters(ctx, restaurantID, date)
+		return err
+	})
+	return voters, err
+}
+
+// RetrieveWinner implements the vote.Store interface.
+func (s *breakerVotes) RetrieveWinner(ctx context.Context, date time.Time) (*vote.Winner, error) {
+	var w *vote.Winner
+	err := guard(ctx, s.b, func(ctx context.Context) error {
+		var err error
+		w, err = s.next.RetrieveWinner(ctx, date)
+		return err
+	})
+	return w, err
+}
+
+// TeamTallies implements the vote.Store interface.
+func (s *breakerVotes) TeamTallies(ctx context.Context, teamID string, date time.Time) ([]vote.Tally, error) {
+	var ts []vote.Tally
+	err := guard(ctx, s.b, func(ctx context.Context) error {
+		var err error
+		ts, err = s.next.TeamTallies(ctx, teamID, date)
+		return err
+	})
+	return ts, err
+}
+
+// RetrieveTeamWinner implements the vote.Store interface.
+func (s *breakerVotes) RetrieveTeamWinner(ctx context.Context, teamID string, date time.Time) (*vote.Winner, error) {
+	var w *vote.Winner
+	err := guard(ctx, s.b, func(ctx context.Context) error {
+		var err error
+		w, err = s.next.RetrieveTeamWinner(ctx, teamID, date)
+		return err
+	})
+	return w, err
+}
+
+// History implements the vote.Store interface.
+func (s *breakerVotes) History(ctx context.Context, from, to time.Time, fn func(vote.Vote) error) error {
+	return guard(ctx, s.b, func(ctx context.Context) error {
+		return s.next.History(ctx, from, to, fn)
+	})
+}
+
+// Today implements the vote.Store interface.
+func (s *breakerVotes) Today(ctx context.Context, policy vote.Policy, now time.Time) (time.Time, error) {
+	var today time.Time
+	err := guard(ctx, s.b, func(ctx context.Context) error {
+		var err error
+		today, err = s.next.Today(ctx, policy, now)
+		return err
+	})
+	return today, err
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/remisb/restaurant/internal/memstore"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/breaker"
+	"github.com/remisb/restaurant/internal/platform/web"
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// TestBreaker validates the handlers fail fast with a 503 once the database
+// is down.
+func TestBreaker(t *testing.T) {
+	t.Log("Given the need to fail fast while the database is down.")
+	{
+		t.Log("\tTest 0:\tWhen the store cannot reach the database.")
+		{
+			store := memstore.NewRestaurants()
+			store.Errs["List"] = driver.ErrBadConn
+
+			b := breaker.New(2, time.Minute)
+			stores := Stores{Restaurants: store}.withBreaker(b)
+			res := Restaurant{store: stores.Restaurants}
+
+			for i := 0; i < 2; i++ {
+				if w := serve(res.List, http.MethodGet, "", nil, userClaims(ownerID, auth.RoleUser)); w.Code != http.StatusInternalServerError {
+					t.Fatalf("\t%s\tShould fail while the breaker is closed : got %d.", tests.Failed, w.Code)
+				}
+			}
+			t.Logf("\t%s\tShould fail while the breaker is closed.", tests.Success)
+
+			w := serve(res.List, http.MethodGet, "", nil, userClaims(ownerID, auth.RoleUser))
+			if w.Code != http.StatusServiceUnavailable {
+				t.Fatalf("\t%s\tShould receive a status code of 503 once open : got %d.", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould receive a status code of 503 once open.", tests.Success)
+
+			var er web.ErrorResponse
+			if err := json.NewDecoder(w.Body).Decode(&er); err != nil {
+				t.Fatalf("\t%s\tShould decode the error : %s.", tests.Failed, err)
+			}
+			if er.Code != "DATABASE_UNAVAILABLE" {
+				t.Fatalf("\t%s\tShould identify the outage : got %q.", tests.Failed, er.Code)
+			}
+			t.Logf("\t%s\tShould identify the outage.", tests.Success)
+		}
+	}
+}
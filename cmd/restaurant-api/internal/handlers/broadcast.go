@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/broadcast"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/web"
+	"go.opentelemetry.io/otel"
+)
+
+// Broadcast represents the emergency broadcast API method handler set.
+type Broadcast struct {
+	db       *sqlx.DB
+	channels []string
+	stream   *broadcast.Stream
+}
+
+// Create sends an urgent message to every member over all channels. The
+// deliveries are carried out by the broadcast workers and streams of the
+// replicas; their progress is available from Report.
+func (b *Broadcast) Create(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Broadcast.Create")
+	defer span.End()
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var nb broadcast.NewBroadcast
+	if err := web.Decode(r, &nb); err != nil {
+		return errors.Wrap(err, "decoding new broadcast")
+	}
+
+	bc, err := broadcast.Create(ctx, b.db, claims, nb, b.channels, v.Now)
+	if err != nil {
+		return errors.Wrapf(err, "creating broadcast: %+v", nb)
+	}
+
+	return web.Respond(ctx, w, bc, http.StatusAccepted)
+}
+
+// Report returns the delivery and confirmation status of a broadcast.
+func (b *Broadcast) Report(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Broadcast.Report")
+	defer span.End()
+
+	report, err := broadcast.RetrieveReport(ctx, b.db, params["id"])
+	if err != nil {
+		switch err {
+		case broadcast.ErrNotFound:
+			return requestError(err, http.StatusNotFound)
+		default:
+			return errors.Wrapf(err, "ID: %s", params["id"])
+		}
+	}
+
+	return web.Respond(ctx, w, report, http.StatusOK)
+}
+
+// Inbox returns the broadcasts the caller has not confirmed yet.
+func (b *Broadcast) Inbox(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Broadcast.Inbox")
+	defer span.End()
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	broadcasts, err := broadcast.ListUnconfirmed(ctx, b.db, claims.Subject)
+	if err != nil {
+		return err
+	}
+
+	return web.Respond(ctx, w, broadcasts, http.StatusOK)
+}
+
+// Confirm records that the caller has read a broadcast.
+func (b *Broadcast) Confirm(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Broadcast.Confirm")
+	defer span.End()
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	if err := broadcast.Confirm(ctx, b.db, claims, params["id"], v.Now); err != nil {
+		switch err {
+		case broadcast.ErrNotFound:
+			return requestError(err, http.StatusNotFound)
+		default:
+			return errors.Wrapf(err, "ID: %s", params["id"])
+		}
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// Stream sends the broadcasts to the caller as server-sent events while the
+// connection stays open. Broadcasts sent before it was opened are found in
+// the inbox.
+//
+// Like the vote streams it is not bound by the request timeout and ends when
+// the client disconnects or the service shuts down.
+func (b *Broadcast) Stream(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Broadcast.Stream")
+	defer span.End()
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if b.stream == nil || !ok {
+		err := errors.New("broadcast streams are not supported")
+		return requestError(err, http.StatusNotImplemented)
+	}
+
+	broadcasts, unsubscribe := b.stream.Subscribe(claims.Subject)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	v.StatusCode = http.StatusOK
+
+	conn := r.Context()
+
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-conn.Done():
+			return nil
+		case bc, ok := <-broadcasts:
+			if !ok {
+				return nil
+			}
+			data, err := json.Marshal(bc)
+			if err != nil {
+				return errors.Wrap(err, "encoding broadcast")
+			}
+			if _, err := fmt.Fprintf(w, "event: broadcast\ndata: %s\n\n", data); err != nil {
+				return nil
+			}
+			flusher.Flush()
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return nil
+			}
+			flusher.Flush()
+		}
+	}
+}
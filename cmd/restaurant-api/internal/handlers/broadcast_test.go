@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/remisb/restaurant/internal/broadcast"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// TestBroadcastCreate validates a broadcast needs a message.
+func TestBroadcastCreate(t *testing.T) {
+	t.Log("Given the need to send urgent messages.")
+	{
+		t.Log("\tTest 0:\tWhen the message is missing.")
+		{
+			bc := Broadcast{channels: []string{broadcast.Inbox{}.Name()}}
+
+			w := serve(bc.Create, http.MethodPost, `{"message":""}`, nil, userClaims(ownerID, auth.RoleAdmin))
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("\t%s\tShould receive a status code of 400 : got %d : %s", tests.Failed, w.Code, w.Body)
+			}
+			t.Logf("\t%s\tShould receive a status code of 400.", tests.Success)
+		}
+	}
+}
+
+// TestBroadcastStream validates the broadcasts pushed to a member are
+// streamed as server-sent events.
+func TestBroadcastStream(t *testing.T) {
+	t.Log("Given the need to push urgent messages to the members streaming them.")
+	{
+		t.Log("\tTest 0:\tWhen a broadcast is pushed to the member.")
+		{
+			stream := broadcast.NewStream()
+			bc := Broadcast{stream: stream}
+
+			done := make(chan *httptest.ResponseRecorder)
+			go func() {
+				done <- serve(bc.Stream, http.MethodGet, "", nil, userClaims(otherID, auth.RoleUser))
+			}()
+
+			b := broadcast.Broadcast{ID: "a2b0639f-2cc6-44b8-b97b-15d69dbb511e", Message: "Leave the cafeteria", DateCreated: now}
+			deadline := time.Now().Add(5 * time.Second)
+			for !stream.Push(otherID, b) {
+				if time.Now().After(deadline) {
+					t.Fatalf("\t%s\tShould subscribe the member to the broadcasts.", tests.Failed)
+				}
+				time.Sleep(time.Millisecond)
+			}
+			if stream.Push(ownerID, b) {
+				t.Fatalf("\t%s\tShould not push to the members who are not streaming.", tests.Failed)
+			}
+			stream.Close()
+
+			w := <-done
+			if w.Code != http.StatusOK {
+				t.Fatalf("\t%s\tShould receive a status code of 200 : got %d.", tests.Failed, w.Code)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+				t.Fatalf("\t%s\tShould be an event stream : got %q.", tests.Failed, ct)
+			}
+			t.Logf("\t%s\tShould be an event stream.", tests.Success)
+
+			want := "event: broadcast\ndata: {\"id\":\"a2b0639f-2cc6-44b8-b97b-15d69dbb511e\",\"org_id\":\"\",\"message\":\"Leave the cafeteria\""
+			if got := w.Body.String(); !strings.HasPrefix(got, want) {
+				t.Fatalf("\t%s\tShould send the broadcast : got %q.", tests.Failed, got)
+			}
+			t.Logf("\t%s\tShould send the broadcast.", tests.Success)
+		}
+
+		t.Log("\tTest 1:\tWhen the server does not stream the broadcasts.")
+		{
+			bc := Broadcast{}
+
+			w := serve(bc.Stream, http.MethodGet, "", nil, userClaims(otherID, auth.RoleUser))
+			if w.Code != http.StatusNotImplemented {
+				t.Fatalf("\t%s\tShould receive a status code of 501 : got %d.", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould receive a status code of 501.", tests.Success)
+		}
+	}
+}
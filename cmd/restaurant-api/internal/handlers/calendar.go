@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/remisb/restaurant/internal/calendar"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/web"
+	"go.opentelemetry.io/otel"
+)
+
+// Calendar represents the iCalendar feed API method handler set.
+type Calendar struct {
+	db *sqlx.DB
+}
+
+// CreateToken creates the token the calling user subscribes to the feed of
+// their organization with, revoking the previous one.
+func (c *Calendar) CreateToken(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Calendar.CreateToken")
+	defer span.End()
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	token, err := calendar.CreateToken(ctx, c.db, claims.Subject, v.Now)
+	if err != nil {
+		return err
+	}
+
+	return web.Respond(ctx, w, token, http.StatusCreated)
+}
+
+// Feed returns the winners and the upcoming menus of the organization of the
+// user owning the token query parameter as an iCalendar feed.
+func (c *Calendar) Feed(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Calendar.Feed")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	org, err := calendar.TokenOrg(ctx, c.db, r.URL.Query().Get("token"))
+	if err != nil {
+		switch err {
+		case calendar.ErrInvalidToken:
+			return requestError(err, http.StatusUnauthorized)
+		default:
+			return err
+		}
+	}
+
+	events, err := calendar.Events(ctx, c.db, org, v.Now)
+	if err != nil {
+		return err
+	}
+
+	v.StatusCode = http.StatusOK
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="lunch.ics"`)
+	w.WriteHeader(http.StatusOK)
+
+	return calendar.Write(w, "Lunch", events, v.Now)
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/featureflag"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/ratelimit"
+	"github.com/remisb/restaurant/internal/platform/web"
+	"go.opentelemetry.io/otel"
+)
+
+// capabilityLimit describes a rate limit to clients.
+type capabilityLimit struct {
+	Rate  float64 `json:"rate_per_second"`
+	Burst int     `json:"burst"`
+}
+
+// newCapabilityLimit converts a rate limit to its client representation. A
+// disabled limit is reported as nil.
+func newCapabilityLimit(l ratelimit.Limit) *capabilityLimit {
+	if l.Disabled() {
+		return nil
+	}
+	return &capabilityLimit{Rate: l.Rate, Burst: l.Burst}
+}
+
+// capabilityInfo is the document returned by Capabilities.Retrieve.
+type capabilityInfo struct {
+	APIVersions      []string                    `json:"api_versions"`
+	Features         map[string]bool             `json:"features"`
+	MaxBodySize      int64                       `json:"max_body_size"`
+	VoteMaxDaysAhead int                         `json:"vote_max_days_ahead"`
+	RateLimits       map[string]*capabilityLimit `json:"rate_limits"`
+	WebSocket        bool                        `json:"websocket"`
+	ServerSentEvents bool                        `json:"server_sent_events"`
+}
+
+// These are the transports of the live updates.
+const (
+	streamSSE       = "sse"
+	streamWebSocket = "websocket"
+)
+
+// Capabilities tells clients what this server supports so they do not need
+// to hard code assumptions about it. The features behind a flag are the ones
+// of the organization of the caller.
+type Capabilities struct {
+	info capabilityInfo
+
+	// db holds the feature flags. When nil only the features of the
+	// configuration are reported.
+	db *sqlx.DB
+}
+
+// newCapabilities describes the server built from the API configuration,
+// serving the versions of the API.
+func newCapabilities(cfg APIConfig, versions []int) *Capabilities {
+	apiVersions := make([]string, len(versions))
+	for i, v := range versions {
+		apiVersions[i] = fmt.Sprintf("v%d", v)
+	}
+
+	// The vote counts and the broadcasts are streamed as server-sent events
+	// when the server was built with their hubs.
+	streams := map[string]bool{}
+	if cfg.VoteHub != nil || cfg.BroadcastStream != nil {
+		streams[streamSSE] = true
+	}
+
+	info := capabilityInfo{
+		APIVersions: apiVersions,
+		Features: map[string]bool{
+			"enrichment":    cfg.Enricher != nil,
+			"broadcast":     true,
+			"vote_planning": cfg.VotePolicy.MaxDaysAhead > 0,
+			"changelog":     true,
+			"public_jsonld": true,
+			"idempotency":   true,
+			"webhooks":      true,
+			"batch":         true,
+			"csv_export":    true,
+		},
+		MaxBodySize:      cfg.MaxBodySize,
+		VoteMaxDaysAhead: cfg.VotePolicy.MaxDaysAhead,
+		ServerSentEvents: streams[streamSSE],
+		WebSocket:        streams[streamWebSocket],
+		RateLimits: map[string]*capabilityLimit{
+			"token": newCapabilityLimit(cfg.RateLimits.Token),
+			"vote":  newCapabilityLimit(cfg.RateLimits.Vote),
+		},
+	}
+
+	c := Capabilities{info: info}
+	if cfg.Driver != "sqlite" {
+		c.db = cfg.DB
+	}
+	return &c
+}
+
+// Retrieve returns the features supported by the server.
+func (c *Capabilities) Retrieve(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Capabilities.Retrieve")
+	defer span.End()
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	info := c.info
+	info.Features = make(map[string]bool, len(c.info.Features))
+	for name, on := range c.info.Features {
+		info.Features[name] = on
+	}
+
+	if c.db != nil {
+		flags, err := featureflag.Features(ctx, c.db, claims.Org())
+		if err != nil {
+			return errors.Wrap(err, "listing feature flags")
+		}
+		for name, on := range flags {
+			info.Features[name] = on
+		}
+	}
+
+	return web.Respond(ctx, w, info, http.StatusOK)
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/tests"
+	"github.com/remisb/restaurant/internal/vote"
+)
+
+// TestCapabilities validates the capabilities are those of the server as it
+// was built.
+func TestCapabilities(t *testing.T) {
+	t.Log("Given the need to tell clients what the server supports.")
+	{
+		t.Log("\tTest 0:\tWhen the server streams the votes.")
+		{
+			cfg := APIConfig{
+				VotePolicy:  vote.Policy{MaxDaysAhead: 7},
+				VoteHub:     vote.NewHub(),
+				MaxBodySize: 1 << 20,
+			}
+			caps := newCapabilities(cfg, []int{1, 2})
+
+			w := serve(caps.Retrieve, http.MethodGet, "", nil, userClaims(ownerID, auth.RoleUser))
+			if w.Code != http.StatusOK {
+				t.Fatalf("\t%s\tShould receive a status code of 200 : got %d : %s", tests.Failed, w.Code, w.Body)
+			}
+			t.Logf("\t%s\tShould receive a status code of 200.", tests.Success)
+
+			var info capabilityInfo
+			if err := json.NewDecoder(w.Body).Decode(&info); err != nil {
+				t.Fatalf("\t%s\tShould decode the capabilities : %s.", tests.Failed, err)
+			}
+
+			if got := strings.Join(info.APIVersions, ","); got != "v1,v2" {
+				t.Fatalf("\t%s\tShould list the versions served : got %s.", tests.Failed, got)
+			}
+			t.Logf("\t%s\tShould list the versions served.", tests.Success)
+
+			if info.Features["enrichment"] || !info.Features["vote_planning"] {
+				t.Fatalf("\t%s\tShould tell the features of the configuration : got %v.", tests.Failed, info.Features)
+			}
+			t.Logf("\t%s\tShould tell the features of the configuration.", tests.Success)
+
+			if !info.ServerSentEvents || info.WebSocket {
+				t.Fatalf("\t%s\tShould stream over server-sent events only : got %+v.", tests.Failed, info)
+			}
+			t.Logf("\t%s\tShould stream over server-sent events only.", tests.Success)
+		}
+
+		t.Log("\tTest 1:\tWhen the server has no streams.")
+		{
+			caps := newCapabilities(APIConfig{}, []int{1})
+
+			var info capabilityInfo
+			w := serve(caps.Retrieve, http.MethodGet, "", nil, userClaims(ownerID, auth.RoleUser))
+			if err := json.NewDecoder(w.Body).Decode(&info); err != nil {
+				t.Fatalf("\t%s\tShould decode the capabilities : %s.", tests.Failed, err)
+			}
+			if info.ServerSentEvents || info.WebSocket || strings.Join(info.APIVersions, ",") != "v1" {
+				t.Fatalf("\t%s\tShould report no streams : got %+v.", tests.Failed, info)
+			}
+			t.Logf("\t%s\tShould report no streams.", tests.Success)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/changelog"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/web"
+	"go.opentelemetry.io/otel"
+)
+
+// Changelog represents the release notes API method handler set.
+type Changelog struct {
+	db *sqlx.DB
+}
+
+// List returns the release notes newer than the since query parameter. When
+// it is missing the notes the caller has not seen yet are returned.
+func (c *Changelog) List(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Changelog.List")
+	defer span.End()
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	entries, err := changelog.List(ctx, c.db, claims.Subject, r.URL.Query().Get("since"))
+	if err != nil {
+		return err
+	}
+
+	return web.Respond(ctx, w, entries, http.StatusOK)
+}
+
+// Create adds the release notes for a new version. The changelog is shared by
+// every organization so only the operators may edit it.
+func (c *Changelog) Create(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Changelog.Create")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	if err := checkOperator(ctx); err != nil {
+		return err
+	}
+
+	var ne changelog.NewEntry
+	if err := web.Decode(r, &ne); err != nil {
+		return errors.Wrap(err, "decoding new changelog entry")
+	}
+
+	e, err := changelog.Create(ctx, c.db, ne, v.Now)
+	if err != nil {
+		switch err {
+		case changelog.ErrDuplicateVersion:
+			return requestError(err, http.StatusConflict)
+		default:
+			return errors.Wrapf(err, "creating changelog entry: %+v", ne)
+		}
+	}
+
+	return web.Respond(ctx, w, e, http.StatusCreated)
+}
+
+// Update modifies the release notes identified in the request URL.
+func (c *Changelog) Update(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Changelog.Update")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	if err := checkOperator(ctx); err != nil {
+		return err
+	}
+
+	var upd changelog.UpdateEntry
+	if err := web.Decode(r, &upd); err != nil {
+		return errors.Wrap(err, "decoding changelog update")
+	}
+
+	if err := changelog.Update(ctx, c.db, params["id"], upd, v.Now); err != nil {
+		switch err {
+		case changelog.ErrNotFound:
+			return requestError(err, http.StatusNotFound)
+		default:
+			return errors.Wrapf(err, "updating changelog entry %q: %+v", params["id"], upd)
+		}
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// Delete removes the release notes identified in the request URL.
+func (c *Changelog) Delete(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Changelog.Delete")
+	defer span.End()
+
+	if err := checkOperator(ctx); err != nil {
+		return err
+	}
+
+	if err := changelog.Delete(ctx, c.db, params["id"]); err != nil {
+		switch err {
+		default:
+			return errors.Wrapf(err, "Id: %s", params["id"])
+		}
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// Seen records the latest version the caller has been shown the notes for.
+func (c *Changelog) Seen(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Changelog.Seen")
+	defer span.End()
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var seen changelog.Seen
+	if err := web.Decode(r, &seen); err != nil {
+		return errors.Wrap(err, "decoding seen version")
+	}
+
+	if err := changelog.MarkSeen(ctx, c.db, claims.Subject, seen, v.Now); err != nil {
+		return errors.Wrapf(err, "marking version %s seen", seen.Version)
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
@@ -2,41 +2,237 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
 	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/breaker"
 	"github.com/remisb/restaurant/internal/platform/database"
+	"github.com/remisb/restaurant/internal/platform/job"
 	"github.com/remisb/restaurant/internal/platform/web"
-	"go.opencensus.io/trace"
+	"github.com/remisb/restaurant/internal/schema"
+	"go.opentelemetry.io/otel"
 	"net/http"
+	"runtime/debug"
+	"strings"
+	"sync/atomic"
+	"time"
 )
 
+// Job is a background job reporting its state to the health check.
+type Job interface {
+	Status() job.Status
+}
+
 // Check provides support for orchestration health checks.
 type Check struct {
-	build string
-	db *sqlx.DB
+	build         string
+	db            *sqlx.DB
+	replica       *sqlx.DB
+	draining      *atomic.Bool
+	authenticator *auth.Authenticator
+	jobs          []Job
+	breaker       *breaker.Breaker
+	migrated      bool
+	started       time.Time
+	revision      string
+}
+
+// newCheck constructs the health checks of the service.
+func newCheck(cfg APIConfig) *Check {
+	c := Check{
+		build:         cfg.Build,
+		db:            cfg.DB,
+		replica:       cfg.ReadDB,
+		draining:      cfg.Draining,
+		authenticator: cfg.Authenticator,
+		jobs:          cfg.Jobs,
+		breaker:       cfg.Breaker,
+		migrated:      cfg.Driver != "sqlite",
+		started:       time.Now(),
+	}
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			if s.Key == "vcs.revision" {
+				c.revision = s.Value
+			}
+		}
+	}
+
+	return &c
+}
+
+// health is the response of the health checks.
+type health struct {
+	Version string         `json:"version"`
+	Status  string         `json:"status"`
+	Breaker string         `json:"breaker,omitempty"`
+	Details *healthDetails `json:"details,omitempty"`
+}
+
+// healthDetails describes the internals of the service. It is only shown to
+// administrators and on the debug listener.
+type healthDetails struct {
+	Revision  string          `json:"revision"`
+	Uptime    string          `json:"uptime"`
+	DBLatency string          `json:"db_latency"`
+	DBError   string          `json:"db_error,omitempty"`
+	DBPool    dbPoolStats     `json:"db_pool"`
+	Breaker   *breaker.Status `json:"breaker,omitempty"`
+	Jobs      []job.Status    `json:"jobs"`
+}
+
+// dbPoolStats are the statistics of the database connection pool.
+type dbPoolStats struct {
+	MaxOpen      int    `json:"max_open"`
+	Open         int    `json:"open"`
+	InUse        int    `json:"in_use"`
+	Idle         int    `json:"idle"`
+	WaitCount    int64  `json:"wait_count"`
+	WaitDuration string `json:"wait_duration"`
 }
 
-// Health validates the service is healthy and ready to accept requests.
-func (c *Check) Health(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
-	ctx, span := trace.StartSpan(ctx, "handlers.Check.Health")
+// details collects the internals of the service.
+func (c *Check) details(ctx context.Context) *healthDetails {
+	d := healthDetails{
+		Revision: c.revision,
+		Uptime:   time.Since(c.started).Round(time.Second).String(),
+		Jobs:     []job.Status{},
+	}
+
+	start := time.Now()
+	if err := database.StatusCheck(ctx, c.db); err != nil {
+		d.DBError = err.Error()
+	}
+	d.DBLatency = time.Since(start).String()
+
+	st := c.db.Stats()
+	d.DBPool = dbPoolStats{
+		MaxOpen:      st.MaxOpenConnections,
+		Open:         st.OpenConnections,
+		InUse:        st.InUse,
+		Idle:         st.Idle,
+		WaitCount:    st.WaitCount,
+		WaitDuration: st.WaitDuration.String(),
+	}
+
+	if c.breaker != nil {
+		st := c.breaker.Status()
+		d.Breaker = &st
+	}
+
+	for _, j := range c.jobs {
+		d.Jobs = append(d.Jobs, j.Status())
+	}
+
+	return &d
+}
+
+// isAdmin reports if the request carries the token of an administrator. The
+// health checks are not authenticated so an invalid token is ignored.
+func (c *Check) isAdmin(r *http.Request) bool {
+	if c.authenticator == nil {
+		return false
+	}
+
+	parts := strings.Split(r.Header.Get("Authorization"), " ")
+	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+		return false
+	}
+
+	claims, err := c.authenticator.ParseClaims(parts[1])
+	if err != nil {
+		return false
+	}
+	return claims.HasRole(auth.RoleAdmin)
+}
+
+// breakerState returns the state of the database circuit breaker, blank when
+// there is none.
+func (c *Check) breakerState() string {
+	if c.breaker == nil {
+		return ""
+	}
+	return c.breaker.Status().State
+}
+
+// Debug serves the health check with all details on the debug listener.
+func (c *Check) Debug(w http.ResponseWriter, r *http.Request) {
+	h := health{
+		Version: c.build,
+		Status:  "ok",
+		Breaker: c.breakerState(),
+		Details: c.details(r.Context()),
+	}
+	status := http.StatusOK
+	if h.Details.DBError != "" {
+		h.Status = "db not ready"
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(h)
+}
+
+// Live reports the process is up. It does not depend on anything outside the
+// process so a failing database does not get the service restarted.
+func (c *Check) Live(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Check.Live")
 	defer span.End()
 
-	health := struct {
-		Version string `json:"version"`
-		Status  string `json:"status"`
-	}{
+	h := health{
 		Version: c.build,
+		Status:  "ok",
+	}
+	return web.Respond(ctx, w, h, http.StatusOK)
+}
+
+// Ready validates the service is ready to accept requests. It is not ready
+// while the database is unreachable or not migrated and once the service
+// started shutting down.
+func (c *Check) Ready(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Check.Ready")
+	defer span.End()
+
+	h := health{
+		Version: c.build,
+		Breaker: c.breakerState(),
+	}
+	if c.isAdmin(r) {
+		h.Details = c.details(ctx)
+	}
+
+	// Do not respond by just returning an error when not ready because further
+	// up in the call stack will interpret that as an unhandled error.
+	if c.draining != nil && c.draining.Load() {
+		h.Status = "shutting down"
+		return web.Respond(ctx, w, h, http.StatusServiceUnavailable)
 	}
 
-	// Check if the database is ready.
 	if err := database.StatusCheck(ctx, c.db); err != nil {
+		h.Status = "db not ready"
+		return web.Respond(ctx, w, h, http.StatusServiceUnavailable)
+	}
+
+	if c.replica != nil {
+		if err := database.StatusCheck(ctx, c.replica); err != nil {
+			h.Status = "db replica not ready"
+			return web.Respond(ctx, w, h, http.StatusServiceUnavailable)
+		}
+	}
 
-		// If the database is not ready we will tell the client and use a 500
-		// status. Do not respond by just returning an error because further up in
-		// the call stack will interpret that as an unhandled error.
-		health.Status = "db not ready"
-		return web.Respond(ctx, w, health, http.StatusInternalServerError)
+	if c.migrated {
+		if err := schema.Validate(ctx, c.db); err != nil {
+			if err != schema.ErrPending {
+				return errors.Wrap(err, "checking migrations")
+			}
+			h.Status = "migrations pending"
+			return web.Respond(ctx, w, h, http.StatusServiceUnavailable)
+		}
 	}
 
-	health.Status = "ok"
-	return web.Respond(ctx, w, health, http.StatusOK)
+	h.Status = "ok"
+	return web.Respond(ctx, w, h, http.StatusOK)
 }
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// TestCheckDraining validates a service shutting down is no longer ready but
+// still alive, so it is taken out of the load balancer without a restart.
+func TestCheckDraining(t *testing.T) {
+	var draining atomic.Bool
+	draining.Store(true)
+	c := Check{build: "develop", draining: &draining}
+
+	t.Log("Given the need to drain the service before it shuts down.")
+	{
+		t.Log("\tTest 0:\tWhen the readiness is checked.")
+		{
+			w := serve(c.Ready, http.MethodGet, "", nil, userClaims(otherID))
+			if w.Code != http.StatusServiceUnavailable {
+				t.Fatalf("\t%s\tShould receive a status code of 503 : got %d.", tests.Failed, w.Code)
+			}
+
+			var h health
+			if err := json.NewDecoder(w.Body).Decode(&h); err != nil || h.Status != "shutting down" {
+				t.Fatalf("\t%s\tShould tell it is shutting down : got %+v, %v.", tests.Failed, h, err)
+			}
+			t.Logf("\t%s\tShould tell it is shutting down.", tests.Success)
+		}
+
+		t.Log("\tTest 1:\tWhen the liveness is checked.")
+		{
+			w := serve(c.Live, http.MethodGet, "", nil, userClaims(otherID))
+			if w.Code != http.StatusOK {
+				t.Fatalf("\t%s\tShould receive a status code of 200 : got %d.", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould receive a status code of 200.", tests.Success)
+		}
+	}
+}
+
+// TestCheckDetails validates the internals of the service are only shown to
+// administrators.
+func TestCheckDetails(t *testing.T) {
+	const kid = "4754d86b-7a6d-4df5-9c65-224741361492"
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := auth.NewAuthenticator(key, kid, "RS256", auth.NewSimpleKeyLookupFunc(kid, key.Public().(*rsa.PublicKey)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := func(roles ...string) string {
+		tkn, err := a.GenerateToken(auth.NewClaims(ownerID, roles, time.Now(), time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tkn
+	}
+
+	tt := []struct {
+		name   string
+		header string
+		admin  bool
+	}{
+		{"no token", "", false},
+		{"the token of a user", "Bearer " + token(auth.RoleUser), false},
+		{"an invalid token", "Bearer " + token(auth.RoleAdmin)[1:], false},
+		{"the token of an admin in another scheme", "Basic " + token(auth.RoleAdmin), false},
+		{"the token of an admin", "Bearer " + token(auth.RoleAdmin), true},
+	}
+
+	t.Log("Given the need to hide the internals of the service from anonymous callers.")
+	{
+		var draining atomic.Bool
+		draining.Store(true)
+
+		for i, tc := range tt {
+			t.Logf("\tTest %d:\tWhen checking the health with %s.", i, tc.name)
+			{
+				c := Check{authenticator: a, draining: &draining}
+
+				r := httptest.NewRequest(http.MethodGet, "/", nil)
+				if tc.header != "" {
+					r.Header.Set("Authorization", tc.header)
+				}
+				if got := c.isAdmin(r); got != tc.admin {
+					t.Fatalf("\t%s\tShould tell an admin is calling : got %v.", tests.Failed, got)
+				}
+				t.Logf("\t%s\tShould tell whether an admin is calling.", tests.Success)
+
+				if tc.admin {
+					continue
+				}
+				w := serveRequest(c.Ready, r, nil, auth.Claims{})
+				if strings.Contains(w.Body.String(), `"details"`) {
+					t.Fatalf("\t%s\tShould not show the details : got %s.", tests.Failed, w.Body)
+				}
+				t.Logf("\t%s\tShould not show the details.", tests.Success)
+			}
+		}
+
+		t.Logf("\tTest %d:\tWhen the service has no authenticator.", len(tt))
+		{
+			c := Check{draining: &draining}
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Authorization", "Bearer "+token(auth.RoleAdmin))
+			if c.isAdmin(r) {
+				t.Fatalf("\t%s\tShould not trust the token.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould not trust the token.", tests.Success)
+		}
+	}
+}
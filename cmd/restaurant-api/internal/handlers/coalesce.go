@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/coalesce"
+	"github.com/remisb/restaurant/internal/platform/database"
+	"github.com/remisb/restaurant/internal/restaurant"
+	"github.com/remisb/restaurant/internal/vote"
+)
+
+// withCoalescing returns the stores with the identical concurrent reads of
+// the hot endpoints merged into one call: the restaurant list, the menu of
+// the day and the vote tallies. The merged call runs with the values of the
+// context of the caller that started it but not its cancellation, so the
+// others do not fail when that caller goes away; the query timeout of the
+// stores still bounds it. Only the calls of the same organization are merged
+// since the stores scope their reads to it. The other calls go through as
+// they are.
+func (s Stores) withCoalescing() Stores {
+	s.Restaurants = &coalescedRestaurants{Store: s.Restaurants}
+	s.Menus = &coalescedMenus{MenuStore: s.Menus}
+	s.Votes = &coalescedVotes{Store: s.Votes}
+	return s
+}
+
+// coalescedRestaurants merges the concurrent lists of restaurants.
+type coalescedRestaurants struct {
+	restaurant.Store
+	group coalesce.Group
+}
+
+// List implements the restaurant.Store interface.
+func (s *coalescedRestaurants) List(ctx context.Context) ([]restaurant.Restaurant, error) {
+	if database.InTx(ctx) {
+		return s.Store.List(ctx)
+	}
+
+	v, err, _ := s.group.Do(auth.Org(ctx)+"/list", func() (interface{}, error) {
+		return s.Store.List(coalesce.Detach(ctx))
+	})
+	rs, _ := v.([]restaurant.Restaurant)
+	return rs, err
+}
+
+// coalescedMenus merges the concurrent lookups of the menu of a restaurant.
+type coalescedMenus struct {
+	restaurant.MenuStore
+	group coalesce.Group
+}
+
+// RetrieveMenu implements the restaurant.MenuStore interface.
+func (s *coalescedMenus) RetrieveMenu(ctx context.Context, id string) (*restaurant.Menu, error) {
+	if database.InTx(ctx) {
+		return s.MenuStore.RetrieveMenu(ctx, id)
+	}
+
+	v, err, _ := s.group.Do(auth.Org(ctx)+"/"+id, func() (interface{}, error) {
+		return s.MenuStore.RetrieveMenu(coalesce.Detach(ctx), id)
+	})
+	m, _ := v.(*restaurant.Menu)
+	return m, err
+}
+
+// coalescedVotes merges the concurrent reads of the tallies of a date.
+type coalescedVotes struct {
+	vote.Store
+	group coalesce.Group
+}
+
+// Tallies implements the vote.Store interface.
+func (s *coalescedVotes) Tallies(ctx context.Context, date time.Time) ([]vote.Tally, error) {
+	if database.InTx(ctx) {
+		return s.Store.Tallies(ctx, date)
+	}
+
+	key := auth.Org(ctx) + "/tallies/" + date.Format("2006-01-02")
+	v, err, _ := s.group.Do(key, func() (interface{}, error) {
+		return s.Store.Tallies(coalesce.Detach(ctx), date)
+	})
+	ts, _ := v.([]vote.Tally)
+	return ts, err
+}
+
+// RetrieveTally implements the vote.Store interface.
+func (s *coalescedVotes) RetrieveTally(ctx context.Context, restaurantID string, date time.Time) (*vote.Tally, error) {
+	if database.InTx(ctx) {
+		return s.Store.RetrieveTally(ctx, restaurantID, date)
+	}
+
+	key := auth.Org(ctx) + "/tally/" + restaurantID + "/" + date.Format("2006-01-02")
+	v, err, _ := s.group.Do(key, func() (interface{}, error) {
+		return s.Store.RetrieveTally(coalesce.Detach(ctx), restaurantID, date)
+	})
+	t, _ := v.(*vote.Tally)
+	return t, err
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/database"
+	"github.com/remisb/restaurant/internal/restaurant"
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// blockingRestaurants is a restaurant.Store whose lists wait to be released.
+// The lists made within a transaction see a restaurant the others do not,
+// like one created earlier in the same batch.
+type blockingRestaurants struct {
+	restaurant.Store
+	started chan bool
+	release chan struct{}
+}
+
+// List implements the restaurant.Store interface.
+func (s *blockingRestaurants) List(ctx context.Context) ([]restaurant.Restaurant, error) {
+	inTx := database.InTx(ctx)
+	s.started <- inTx
+	<-s.release
+
+	if inTx {
+		return []restaurant.Restaurant{{Name: "Uncommitted"}}, nil
+	}
+	return []restaurant.Restaurant{}, nil
+}
+
+// TestCoalescingTransactions validates the reads of a batch are not merged
+// with the other reads.
+func TestCoalescingTransactions(t *testing.T) {
+	t.Log("Given the need to keep the reads of a batch to itself.")
+	{
+		t.Log("\tTest 0:\tWhen a batch lists the restaurants while another request does.")
+		{
+			store := &blockingRestaurants{started: make(chan bool, 2), release: make(chan struct{})}
+			stores := Stores{Restaurants: store}.withCoalescing()
+
+			ctx := context.WithValue(context.Background(), auth.Key, userClaims(ownerID, auth.RoleUser))
+			batch := database.WithTx(ctx, &sqlx.Tx{})
+
+			type result struct {
+				rs  []restaurant.Restaurant
+				err error
+			}
+			plain, batched := make(chan result, 1), make(chan result, 1)
+
+			go func() {
+				rs, err := stores.Restaurants.List(ctx)
+				plain <- result{rs, err}
+			}()
+			if inTx := <-store.started; inTx {
+				t.Fatalf("\t%s\tShould list the restaurants outside of the batch first.", tests.Failed)
+			}
+
+			go func() {
+				rs, err := stores.Restaurants.List(batch)
+				batched <- result{rs, err}
+			}()
+			select {
+			case inTx := <-store.started:
+				if !inTx {
+					t.Fatalf("\t%s\tShould list the restaurants within the batch.", tests.Failed)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("\t%s\tShould not merge the read of the batch with the read in flight.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould not merge the read of the batch with the read in flight.", tests.Success)
+
+			close(store.release)
+
+			if r := <-plain; r.err != nil || len(r.rs) != 0 {
+				t.Fatalf("\t%s\tShould not see the changes of the batch : got %+v, %v.", tests.Failed, r.rs, r.err)
+			}
+			t.Logf("\t%s\tShould not see the changes of the batch.", tests.Success)
+
+			if r := <-batched; r.err != nil || len(r.rs) != 1 {
+				t.Fatalf("\t%s\tShould let the batch see its own changes : got %+v, %v.", tests.Failed, r.rs, r.err)
+			}
+			t.Logf("\t%s\tShould let the batch see its own changes.", tests.Success)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/comment"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/web"
+	"github.com/remisb/restaurant/internal/restaurant"
+	"go.opentelemetry.io/otel"
+)
+
+// maxComments is the most comments returned in a page.
+const maxComments = 100
+
+// MenuComment represents the menu comment API method handler set. Every
+// member of the organization may comment on its menus and reply to the
+// comments, the replies of the owner of the restaurant being flagged as such.
+type MenuComment struct {
+	db          *sqlx.DB
+	restaurants restaurant.Store
+	menus       restaurant.MenuStore
+}
+
+// List returns a page of the comments of the menu with their replies. The
+// limit and offset query parameters select the page.
+func (mc *MenuComment) List(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.MenuComment.List")
+	defer span.End()
+
+	limit, offset := 20, 0
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxComments {
+			err := errors.Errorf("limit must be a number between 1 and %d", maxComments)
+			return requestError(err, http.StatusBadRequest)
+		}
+		limit = n
+	}
+	if s := r.URL.Query().Get("offset"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return requestError(errors.New("offset must be a positive number"), http.StatusBadRequest)
+		}
+		offset = n
+	}
+
+	_, m, err := mc.menu(ctx, params)
+	if err != nil {
+		return err
+	}
+
+	comments, err := comment.List(ctx, mc.db, m.ID, limit, offset)
+	if err != nil {
+		return errors.Wrapf(err, "ID: %s", m.ID)
+	}
+
+	return web.RespondList(ctx, w, comments, http.StatusOK)
+}
+
+// Create adds a comment, or a reply to one, to the menu.
+func (mc *MenuComment) Create(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.MenuComment.Create")
+	defer span.End()
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var nc comment.NewComment
+	if err := web.Decode(r, &nc); err != nil {
+		return errors.Wrap(err, "decoding new comment")
+	}
+
+	res, m, err := mc.menu(ctx, params)
+	if err != nil {
+		return err
+	}
+
+	c, err := comment.Create(ctx, mc.db, claims, res.ID, m.ID, res.OwnerUserID == claims.Subject, nc, v.Now)
+	if err != nil {
+		switch err {
+		case comment.ErrInvalidParent:
+			return requestError(err, http.StatusBadRequest)
+		default:
+			return errors.Wrapf(err, "creating comment: %+v", nc)
+		}
+	}
+
+	return web.Respond(ctx, w, c, http.StatusCreated)
+}
+
+// Delete soft deletes the comment of the menu identified in the request URL.
+// Only its author and admins may delete it.
+func (mc *MenuComment) Delete(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.MenuComment.Delete")
+	defer span.End()
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	_, m, err := mc.menu(ctx, params)
+	if err != nil {
+		return err
+	}
+
+	if err := comment.Delete(ctx, mc.db, claims, m.ID, params["id"], v.Now); err != nil {
+		switch err {
+		case comment.ErrNotFound:
+			return requestError(err, http.StatusNotFound)
+		case comment.ErrForbidden:
+			return requestError(err, http.StatusForbidden)
+		default:
+			return errors.Wrapf(err, "ID: %s", params["id"])
+		}
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// menu retrieves the restaurant and its menu identified in the request URL.
+func (mc *MenuComment) menu(ctx context.Context, params map[string]string) (*restaurant.Restaurant, *restaurant.Menu, error) {
+	restaurantID := params["restaurantId"]
+	res, err := mc.restaurants.Retrieve(ctx, restaurantID)
+	if err != nil {
+		switch err {
+		case restaurant.ErrNotFound:
+			return nil, nil, requestError(err, http.StatusNotFound)
+		default:
+			return nil, nil, errors.Wrapf(err, "ID: %s", restaurantID)
+		}
+	}
+
+	m, err := mc.menus.RetrieveMenu(ctx, params["menuId"])
+	if err != nil {
+		switch err {
+		case restaurant.ErrNotFound:
+			return nil, nil, requestError(restaurant.ErrNoMenu, http.StatusNotFound)
+		default:
+			return nil, nil, errors.Wrapf(err, "ID: %s", params["menuId"])
+		}
+	}
+	if m.RestaurantID != res.ID {
+		return nil, nil, requestError(restaurant.ErrNoMenu, http.StatusNotFound)
+	}
+
+	return res, m, nil
+}
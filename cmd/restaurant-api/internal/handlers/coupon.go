@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/coupon"
+	"github.com/remisb/restaurant/internal/platform/web"
+	"github.com/remisb/restaurant/internal/restaurant"
+	"go.opentelemetry.io/otel"
+)
+
+// Coupon represents the coupon API method handler set. Only the owner of the
+// restaurant and admins may manage its coupons, users apply them when placing
+// their orders.
+type Coupon struct {
+	db          *sqlx.DB
+	restaurants restaurant.Store
+}
+
+// List returns the coupons of the restaurant.
+func (c *Coupon) List(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Coupon.List")
+	defer span.End()
+
+	restaurantID := params["restaurantId"]
+	if err := checkRestaurantOwner(ctx, c.restaurants, restaurantID); err != nil {
+		return err
+	}
+
+	coupons, err := coupon.List(ctx, c.db, restaurantID)
+	if err != nil {
+		return errors.Wrapf(err, "ID: %s", restaurantID)
+	}
+
+	return web.RespondList(ctx, w, coupons, http.StatusOK)
+}
+
+// Retrieve returns the coupon of the restaurant identified in the request URL.
+func (c *Coupon) Retrieve(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Coupon.Retrieve")
+	defer span.End()
+
+	restaurantID := params["restaurantId"]
+	if err := checkRestaurantOwner(ctx, c.restaurants, restaurantID); err != nil {
+		return err
+	}
+
+	cp, err := coupon.Retrieve(ctx, c.db, restaurantID, params["id"])
+	if err != nil {
+		switch err {
+		case coupon.ErrNotFound:
+			return requestError(err, http.StatusNotFound)
+		default:
+			return errors.Wrapf(err, "ID: %s", params["id"])
+		}
+	}
+
+	return web.Respond(ctx, w, cp, http.StatusOK)
+}
+
+// Create adds a coupon to the restaurant.
+func (c *Coupon) Create(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Coupon.Create")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	restaurantID := params["restaurantId"]
+	if err := checkRestaurantOwner(ctx, c.restaurants, restaurantID); err != nil {
+		return err
+	}
+
+	var nc coupon.NewCoupon
+	if err := web.Decode(r, &nc); err != nil {
+		return errors.Wrap(err, "decoding new coupon")
+	}
+
+	cp, err := coupon.Create(ctx, c.db, restaurantID, nc, v.Now)
+	if err != nil {
+		switch err {
+		case coupon.ErrInvalidTerms:
+			return requestError(err, http.StatusBadRequest)
+		case coupon.ErrDuplicateCode:
+			return requestError(err, http.StatusConflict)
+		default:
+			return errors.Wrapf(err, "creating coupon: %+v", nc)
+		}
+	}
+
+	return web.Respond(ctx, w, cp, http.StatusCreated)
+}
+
+// Delete removes the coupon of the restaurant identified in the request URL.
+func (c *Coupon) Delete(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Coupon.Delete")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	restaurantID := params["restaurantId"]
+	if err := checkRestaurantOwner(ctx, c.restaurants, restaurantID); err != nil {
+		return err
+	}
+
+	if err := coupon.Delete(ctx, c.db, restaurantID, params["id"], v.Now); err != nil {
+		switch err {
+		default:
+			return errors.Wrapf(err, "Id: %s", params["id"])
+		}
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// Redemptions returns the uses of the coupon of the restaurant identified in
+// the request URL.
+func (c *Coupon) Redemptions(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Coupon.Redemptions")
+	defer span.End()
+
+	restaurantID := params["restaurantId"]
+	if err := checkRestaurantOwner(ctx, c.restaurants, restaurantID); err != nil {
+		return err
+	}
+
+	redemptions, err := coupon.Redemptions(ctx, c.db, restaurantID, params["id"])
+	if err != nil {
+		switch err {
+		case coupon.ErrNotFound:
+			return requestError(err, http.StatusNotFound)
+		default:
+			return errors.Wrapf(err, "ID: %s", params["id"])
+		}
+	}
+
+	return web.RespondList(ctx, w, redemptions, http.StatusOK)
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/web"
+	"github.com/remisb/restaurant/internal/restaurant"
+	"github.com/remisb/restaurant/internal/vote"
+	"go.opentelemetry.io/otel"
+)
+
+// DishVote represents the menu item voting API method handler set, used by
+// the organizations whose voting mode is dish.
+type DishVote struct {
+	db     *sqlx.DB
+	policy vote.Policy
+}
+
+// Cast records the caller's vote for an item of a menu. The body may name a
+// future date to plan a lunch ahead, otherwise the vote is for today.
+func (dv *DishVote) Cast(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.DishVote.Cast")
+	defer span.End()
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var nd vote.NewDishVote
+	if err := web.Decode(r, &nd); err != nil {
+		return errors.Wrap(err, "decoding new dish vote")
+	}
+
+	cast, err := vote.CastDish(ctx, dv.db, claims, nd, dv.policy, v.Now)
+	if err != nil {
+		switch err {
+		case vote.ErrInvalidDate, restaurant.ErrInvalidID, vote.ErrMenuDate:
+			return requestError(err, http.StatusBadRequest)
+		case restaurant.ErrNotFound:
+			return requestError(restaurant.ErrNoMenu, http.StatusNotFound)
+		case vote.ErrItemNotFound:
+			return requestError(err, http.StatusNotFound)
+		case vote.ErrClosed, vote.ErrTooEarly, vote.ErrWrongMode:
+			return requestError(err, http.StatusConflict)
+		default:
+			return errors.Wrapf(err, "casting dish vote: %+v", nd)
+		}
+	}
+
+	return web.Respond(ctx, w, cast, http.StatusCreated)
+}
+
+// Tallies returns the vote counts of the menu items for the date query
+// parameter or today, by category.
+func (dv *DishVote) Tallies(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.DishVote.Tallies")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	date, err := dv.date(ctx, r.URL.Query().Get("date"), v.Now)
+	if err != nil {
+		return err
+	}
+
+	tallies, err := vote.DishTallies(ctx, dv.db, date)
+	if err != nil {
+		return err
+	}
+
+	return web.RespondList(ctx, w, tallies, http.StatusOK)
+}
+
+// Winners returns the winning menu item of each category for the date query
+// parameter or today.
+func (dv *DishVote) Winners(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.DishVote.Winners")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	date, err := dv.date(ctx, r.URL.Query().Get("date"), v.Now)
+	if err != nil {
+		return err
+	}
+
+	winners, err := vote.RetrieveDishWinners(ctx, dv.db, date)
+	if err != nil {
+		switch err {
+		case vote.ErrNoWinner:
+			return requestError(err, http.StatusNotFound)
+		default:
+			return errors.Wrapf(err, "date: %s", date.Format("2006-01-02"))
+		}
+	}
+
+	return web.RespondList(ctx, w, winners, http.StatusOK)
+}
+
+// date returns the date of the query parameter or, when it is blank, today in
+// the time zone of the organization of the caller.
+func (dv *DishVote) date(ctx context.Context, query string, now time.Time) (time.Time, error) {
+	if query != "" {
+		date, err := vote.ParseDate(query, now)
+		if err != nil {
+			return time.Time{}, requestError(err, http.StatusBadRequest)
+		}
+		return date, nil
+	}
+
+	today, err := vote.Today(ctx, dv.db, dv.policy, now)
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "resolving today")
+	}
+	return today, nil
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"github.com/remisb/restaurant/internal/analytics"
+	"github.com/remisb/restaurant/internal/broadcast"
+	"github.com/remisb/restaurant/internal/calendar"
+	"github.com/remisb/restaurant/internal/changelog"
+	"github.com/remisb/restaurant/internal/comment"
+	"github.com/remisb/restaurant/internal/coupon"
+	"github.com/remisb/restaurant/internal/enrichment"
+	"github.com/remisb/restaurant/internal/featureflag"
+	"github.com/remisb/restaurant/internal/media"
+	"github.com/remisb/restaurant/internal/notification"
+	"github.com/remisb/restaurant/internal/notify/push"
+	"github.com/remisb/restaurant/internal/order"
+	"github.com/remisb/restaurant/internal/organization"
+	"github.com/remisb/restaurant/internal/platform/breaker"
+	"github.com/remisb/restaurant/internal/platform/geo"
+	"github.com/remisb/restaurant/internal/platform/web"
+	"github.com/remisb/restaurant/internal/report"
+	"github.com/remisb/restaurant/internal/restaurant"
+	"github.com/remisb/restaurant/internal/tag"
+	"github.com/remisb/restaurant/internal/team"
+	"github.com/remisb/restaurant/internal/user"
+	"github.com/remisb/restaurant/internal/vote"
+	"github.com/remisb/restaurant/internal/webhook"
+)
+
+// errorCodes are the codes clients receive for the expected errors of the
+// business packages. The codes are part of the API and must not change.
+var errorCodes = map[error]string{
+	restaurant.ErrNotFound:          "RESTAURANT_NOT_FOUND",
+	restaurant.ErrInvalidID:         "INVALID_ID",
+	restaurant.ErrForbidden:         "FORBIDDEN",
+	restaurant.ErrVersionConflict:   "VERSION_CONFLICT",
+	restaurant.ErrNoMenu:            "MENU_NOT_FOUND",
+	restaurant.ErrInvalidDate:       "MENU_INVALID_DATE",
+	restaurant.ErrDateOutOfRange:    "MENU_DATE_OUT_OF_RANGE",
+	restaurant.ErrInvalidRadius:     "INVALID_RADIUS",
+	restaurant.ErrInvalidInclude:    "INVALID_INCLUDE",
+	restaurant.ErrUnknownAllergen:   "UNKNOWN_ALLERGEN",
+	restaurant.ErrDuplicate:         "RESTAURANT_DUPLICATE",
+	restaurant.ErrDuplicateName:     "RESTAURANT_NAME_EXISTS",
+	geo.ErrInvalidPoint:             "INVALID_LOCATION",
+	user.ErrNotFound:                "USER_NOT_FOUND",
+	user.ErrInvalidID:               "INVALID_ID",
+	user.ErrForbidden:               "FORBIDDEN",
+	user.ErrAuthenticationFailure:   "AUTHENTICATION_FAILED",
+	user.ErrVersionConflict:         "VERSION_CONFLICT",
+	vote.ErrInvalidDate:             "INVALID_DATE",
+	vote.ErrClosed:                  "VOTING_CLOSED",
+	vote.ErrTooEarly:                "VOTING_NOT_OPEN",
+	vote.ErrNoWinner:                "WINNER_NOT_FOUND",
+	vote.ErrLocked:                  "VOTE_LOCKED",
+	vote.ErrNotVoted:                "VOTE_NOT_FOUND",
+	vote.ErrInvalidID:               "INVALID_ID",
+	vote.ErrWeightExceeded:          "VOTE_WEIGHT_EXCEEDED",
+	vote.ErrWeightNotFound:          "VOTE_WEIGHT_NOT_FOUND",
+	vote.ErrNotDelegated:            "VOTE_NOT_DELEGATED",
+	vote.ErrInvalidDelegate:         "INVALID_DELEGATE",
+	vote.ErrWrongMode:               "VOTING_MODE_MISMATCH",
+	vote.ErrItemNotFound:            "MENU_ITEM_NOT_FOUND",
+	vote.ErrMenuDate:                "MENU_DATE_MISMATCH",
+	vote.ErrAnonymous:               "VOTES_ANONYMOUS",
+	comment.ErrNotFound:             "COMMENT_NOT_FOUND",
+	comment.ErrInvalidID:            "INVALID_ID",
+	comment.ErrInvalidParent:        "INVALID_PARENT_COMMENT",
+	comment.ErrForbidden:            "FORBIDDEN",
+	enrichment.ErrNotFound:          "SUGGESTION_NOT_FOUND",
+	enrichment.ErrInvalidID:         "INVALID_ID",
+	enrichment.ErrNoMatch:           "PLACE_NOT_FOUND",
+	enrichment.ErrDecided:           "SUGGESTION_DECIDED",
+	changelog.ErrNotFound:           "CHANGELOG_NOT_FOUND",
+	changelog.ErrInvalidID:          "INVALID_ID",
+	changelog.ErrDuplicateVersion:   "CHANGELOG_VERSION_EXISTS",
+	broadcast.ErrNotFound:           "BROADCAST_NOT_FOUND",
+	broadcast.ErrInvalidID:          "INVALID_ID",
+	push.ErrNotFound:                "PUSH_DEVICE_NOT_FOUND",
+	webhook.ErrNotFound:             "WEBHOOK_NOT_FOUND",
+	webhook.ErrInvalidID:            "INVALID_ID",
+	webhook.ErrUnknownEvent:         "UNKNOWN_WEBHOOK_EVENT",
+	order.ErrNotFound:               "ORDER_NOT_FOUND",
+	order.ErrMenuNotFound:           "MENU_NOT_FOUND",
+	order.ErrInvalidID:              "INVALID_ID",
+	order.ErrNotWinner:              "RESTAURANT_NOT_WINNER",
+	order.ErrCutoff:                 "ORDERING_CLOSED",
+	order.ErrForbidden:              "FORBIDDEN",
+	order.ErrTransition:             "ORDER_STATUS_CONFLICT",
+	notification.ErrNotFound:        "NOTIFICATION_NOT_FOUND",
+	notification.ErrInvalidID:       "INVALID_ID",
+	organization.ErrNotFound:        "ORGANIZATION_NOT_FOUND",
+	organization.ErrInvalidID:       "INVALID_ID",
+	organization.ErrUserNotFound:    "USER_NOT_FOUND",
+	organization.ErrForbidden:       "FORBIDDEN",
+	organization.ErrInvalidSettings: "INVALID_SETTINGS",
+	featureflag.ErrNotFound:         "FEATURE_FLAG_NOT_FOUND",
+	featureflag.ErrInvalidName:      "INVALID_FEATURE_FLAG",
+	featureflag.ErrDisabled:         "FEATURE_DISABLED",
+	team.ErrNotFound:                "TEAM_NOT_FOUND",
+	team.ErrInvalidID:               "INVALID_ID",
+	team.ErrUserNotFound:            "USER_NOT_FOUND",
+	coupon.ErrNotFound:              "COUPON_NOT_FOUND",
+	coupon.ErrInvalidID:             "INVALID_ID",
+	coupon.ErrDuplicateCode:         "COUPON_CODE_EXISTS",
+	coupon.ErrInvalidTerms:          "INVALID_COUPON_TERMS",
+	coupon.ErrInvalidCode:           "INVALID_COUPON",
+	coupon.ErrExhausted:             "COUPON_USED_UP",
+	media.ErrNotFound:               "IMAGE_NOT_FOUND",
+	media.ErrTooLarge:               "IMAGE_TOO_LARGE",
+	media.ErrUnsupportedType:        "UNSUPPORTED_IMAGE_TYPE",
+	tag.ErrNotFound:                 "TAG_NOT_FOUND",
+	tag.ErrInvalidSlug:              "INVALID_TAG",
+	tag.ErrDuplicateSlug:            "TAG_EXISTS",
+	calendar.ErrInvalidToken:        "INVALID_CALENDAR_TOKEN",
+	analytics.ErrInvalidGroup:       "INVALID_GROUP",
+	report.ErrInvalidMonth:          "INVALID_MONTH",
+	breaker.ErrOpen:                 "DATABASE_UNAVAILABLE",
+}
+
+// requestError wraps an expected error with an HTTP status code and the code
+// of the error. Errors without a code of their own get the code of the status.
+func requestError(err error, status int) error {
+	return web.NewCodedError(err, status, errorCodes[err])
+}
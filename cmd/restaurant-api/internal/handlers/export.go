@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/platform/pdf"
+	"github.com/remisb/restaurant/internal/platform/web"
+	"github.com/remisb/restaurant/internal/report"
+	"github.com/remisb/restaurant/internal/restaurant"
+	"github.com/remisb/restaurant/internal/vote"
+)
+
+// exportRestaurants sends the restaurants as a CSV file.
+func exportRestaurants(ctx context.Context, w http.ResponseWriter, restaurants []restaurant.Restaurant) error {
+	cw, err := web.RespondCSV(ctx, w, "restaurants.csv", []string{"id", "name", "address", "owner_user_id", "website", "phone", "public", "date_created", "date_updated"})
+	if err != nil {
+		return err
+	}
+
+	for _, r := range restaurants {
+		err := cw.Write(r.ID, r.Name, r.Address, r.OwnerUserID, r.Website, r.Phone, strconv.FormatBool(r.Public),
+			r.DateCreated.Format(time.RFC3339), r.DateUpdated.Format(time.RFC3339))
+		if err != nil {
+			return errors.Wrap(err, "writing restaurants")
+		}
+	}
+
+	return cw.Close()
+}
+
+// exportMenus sends the menus as a CSV file.
+func exportMenus(ctx context.Context, w http.ResponseWriter, menus []restaurant.Menu) error {
+	cw, err := web.RespondCSV(ctx, w, "menus.csv", []string{"id", "restaurant_id", "date", "menu"})
+	if err != nil {
+		return err
+	}
+
+	for _, m := range menus {
+		if err := cw.Write(m.ID, m.RestaurantID, m.Date.Format("2006-01-02"), m.Menu); err != nil {
+			return errors.Wrap(err, "writing menus")
+		}
+	}
+
+	return cw.Close()
+}
+
+// The layout of the printed menus in points.
+const (
+	pdfMargin   = 56
+	pdfLogoSize = 96
+)
+
+// exportMenuPDF sends the menu of the restaurant as a printable PDF file. The
+// free text of the menu is printed when it has no items.
+func exportMenuPDF(ctx context.Context, w http.ResponseWriter, res *restaurant.Restaurant, m *restaurant.Menu, logo image.Image) error {
+	doc := pdf.New()
+	page := doc.AddPage()
+	width := pdf.PageWidth - 2*pdfMargin
+	y := float64(pdfMargin)
+
+	// The logo keeps its proportions in the top right corner, leaving the
+	// rest of the width of the heading to the name.
+	heading := width
+	if logo != nil {
+		b := logo.Bounds()
+		lw, lh := float64(pdfLogoSize), float64(pdfLogoSize)
+		if b.Dx() > b.Dy() {
+			lh = lh * float64(b.Dy()) / float64(b.Dx())
+		} else {
+			lw = lw * float64(b.Dx()) / float64(b.Dy())
+		}
+		page.Image(logo, pdf.PageWidth-pdfMargin-lw, y, lw, lh)
+		heading -= pdfLogoSize + 16
+	}
+
+	for _, line := range pdf.Wrap(pdf.HelveticaBold, 22, heading, res.Name) {
+		y += 26
+		page.Text(pdfMargin, y, pdf.HelveticaBold, 22, line)
+	}
+	for _, line := range pdf.Wrap(pdf.Helvetica, 11, heading, res.Address) {
+		y += 15
+		page.Text(pdfMargin, y, pdf.Helvetica, 11, line)
+	}
+	y += 24
+	page.Text(pdfMargin, y, pdf.Helvetica, 13, m.Date.Format("Monday, 2 January 2006"))
+	if logo != nil && y < pdfMargin+pdfLogoSize {
+		y = pdfMargin + pdfLogoSize
+	}
+	y += 14
+	page.Line(pdfMargin, y, pdfMargin+width, y, 0.5)
+	y += 10
+
+	// space starts a new page when the next lines would not fit.
+	space := func(height float64) {
+		if y+height > pdf.PageHeight-pdfMargin {
+			page = doc.AddPage()
+			y = pdfMargin
+		}
+	}
+
+	if len(m.Items) == 0 {
+		for _, item := range menuItems(m.Menu) {
+			lines := pdf.Wrap(pdf.Helvetica, 12, width, item)
+			space(float64(18 * len(lines)))
+			for _, line := range lines {
+				y += 18
+				page.Text(pdfMargin, y, pdf.Helvetica, 12, line)
+			}
+		}
+	}
+
+	for _, item := range m.Items {
+		price := fmt.Sprintf("%d.%02d", item.Price/100, item.Price%100)
+		priceWidth := pdf.Width(pdf.HelveticaBold, 12, price)
+		lines := pdf.Wrap(pdf.HelveticaBold, 12, width-priceWidth-16, item.Name)
+
+		var allergens []string
+		if len(item.Allergens) > 0 {
+			allergens = pdf.Wrap(pdf.Helvetica, 9, width, "Contains: "+strings.Join(item.Allergens, ", "))
+		}
+
+		space(float64(8 + 16*len(lines) + 12*len(allergens)))
+		y += 8
+		for i, line := range lines {
+			y += 16
+			page.Text(pdfMargin, y, pdf.HelveticaBold, 12, line)
+			if i == 0 {
+				page.Text(pdfMargin+width-priceWidth, y, pdf.HelveticaBold, 12, price)
+			}
+		}
+		for _, line := range allergens {
+			y += 12
+			page.Text(pdfMargin, y, pdf.Helvetica, 9, line)
+		}
+	}
+
+	var buf bytes.Buffer
+	if _, err := doc.WriteTo(&buf); err != nil {
+		return errors.Wrap(err, "rendering menu")
+	}
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+	v.StatusCode = http.StatusOK
+
+	name := "menu-" + m.Date.Format("2006-01-02") + ".pdf"
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", name))
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := buf.WriteTo(w); err != nil {
+		return errors.Wrap(err, "writing menu")
+	}
+
+	return nil
+}
+
+// exportMonthlyReport sends the monthly report as a CSV file with a row per
+// team and per restaurant, told apart by the section column.
+func exportMonthlyReport(ctx context.Context, w http.ResponseWriter, rep *report.Monthly) error {
+	name := "report-" + rep.Month + ".csv"
+	cw, err := web.RespondCSV(ctx, w, name, []string{"section", "id", "name", "members", "voters", "votes", "participation", "wins", "orders", "spend"})
+	if err != nil {
+		return err
+	}
+
+	for _, t := range rep.Teams {
+		err := cw.Write("team", t.TeamID, t.Name, strconv.Itoa(t.Members), strconv.Itoa(t.Voters), strconv.Itoa(t.Votes),
+			strconv.FormatFloat(t.Participation, 'f', 3, 64), "", "", "")
+		if err != nil {
+			return errors.Wrap(err, "writing teams")
+		}
+	}
+	for _, r := range rep.Restaurants {
+		err := cw.Write("restaurant", r.RestaurantID, r.Name, "", "", strconv.Itoa(r.Votes),
+			"", strconv.Itoa(r.Wins), strconv.Itoa(r.Orders), report.FormatCents(r.Spend))
+		if err != nil {
+			return errors.Wrap(err, "writing restaurants")
+		}
+	}
+
+	return cw.Close()
+}
+
+// exportVotes streams the votes of the dates from through to as a CSV file.
+func exportVotes(ctx context.Context, w http.ResponseWriter, store vote.Store, from, to time.Time) error {
+	name := "votes-" + from.Format("2006-01-02") + "-" + to.Format("2006-01-02") + ".csv"
+	cw, err := web.RespondCSV(ctx, w, name, []string{"date", "user_id", "restaurant_id", "time_voted"})
+	if err != nil {
+		return err
+	}
+
+	err = store.History(ctx, from, to, func(v vote.Vote) error {
+		return cw.Write(v.Date.Format("2006-01-02"), v.UserID, v.RestaurantID, v.TimeVoted.Format(time.RFC3339))
+	})
+	if err != nil {
+		return errors.Wrap(err, "writing votes")
+	}
+
+	return cw.Close()
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/featureflag"
+	"github.com/remisb/restaurant/internal/platform/web"
+	"go.opentelemetry.io/otel"
+)
+
+// FeatureFlag represents the feature flag API method handler set. The flags
+// apply to the whole deployment so only the admins of the default
+// organization manage them.
+type FeatureFlag struct {
+	db *sqlx.DB
+}
+
+// List returns every flag which was set.
+func (ff *FeatureFlag) List(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.FeatureFlag.List")
+	defer span.End()
+
+	if err := checkOperator(ctx); err != nil {
+		return err
+	}
+
+	flags, err := featureflag.List(ctx, ff.db)
+	if err != nil {
+		return err
+	}
+
+	return web.RespondList(ctx, w, flags, http.StatusOK)
+}
+
+// Retrieve returns the flag identified by the name in the request URL.
+func (ff *FeatureFlag) Retrieve(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.FeatureFlag.Retrieve")
+	defer span.End()
+
+	if err := checkOperator(ctx); err != nil {
+		return err
+	}
+
+	f, err := featureflag.Retrieve(ctx, ff.db, params["name"])
+	if err != nil {
+		switch err {
+		case featureflag.ErrNotFound:
+			return requestError(err, http.StatusNotFound)
+		default:
+			return errors.Wrapf(err, "Name: %s", params["name"])
+		}
+	}
+
+	return web.Respond(ctx, w, f, http.StatusOK)
+}
+
+// Set creates or modifies the flag identified by the name in the request
+// URL. The change applies to the next request.
+func (ff *FeatureFlag) Set(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.FeatureFlag.Set")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	if err := checkOperator(ctx); err != nil {
+		return err
+	}
+
+	var uf featureflag.UpdateFlag
+	if err := web.Decode(r, &uf); err != nil {
+		return errors.Wrap(err, "decoding feature flag")
+	}
+
+	f, err := featureflag.Set(ctx, ff.db, params["name"], uf, v.Now)
+	if err != nil {
+		switch err {
+		default:
+			return errors.Wrapf(err, "setting feature flag %s: %+v", params["name"], uf)
+		}
+	}
+
+	return web.Respond(ctx, w, f, http.StatusOK)
+}
+
+// Delete removes the flag identified by the name in the request URL, turning
+// its feature back to its default.
+func (ff *FeatureFlag) Delete(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.FeatureFlag.Delete")
+	defer span.End()
+
+	if err := checkOperator(ctx); err != nil {
+		return err
+	}
+
+	if err := featureflag.Delete(ctx, ff.db, params["name"]); err != nil {
+		return errors.Wrapf(err, "Name: %s", params["name"])
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
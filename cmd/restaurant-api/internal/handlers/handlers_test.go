@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/remisb/restaurant/internal/mid"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/web"
+)
+
+// now is the time every handler unit test runs at.
+var now = time.Date(2020, time.March, 2, 9, 0, 0, 0, time.UTC)
+
+// ownerID and otherID are the subjects of the users making requests.
+const (
+	ownerID = "5cf37266-3473-4006-984f-9325122678b7"
+	otherID = "45b5fbd3-755f-4379-8f07-a58d4a30fa2f"
+)
+
+// serve runs a handler the way the application does, behind the Errors
+// middleware, without a router or database.
+func serve(h web.Handler, method, body string, params map[string]string, claims auth.Claims) *httptest.ResponseRecorder {
+	return serveRequest(h, httptest.NewRequest(method, "/", strings.NewReader(body)), params, claims)
+}
+
+// serveQuery is serve for handlers reading the query string of the URL.
+func serveQuery(h web.Handler, method, query, body string, claims auth.Claims) *httptest.ResponseRecorder {
+	return serveRequest(h, httptest.NewRequest(method, "/"+query, strings.NewReader(body)), nil, claims)
+}
+
+// serveRequest runs the handler for the request.
+func serveRequest(h web.Handler, r *http.Request, params map[string]string, claims auth.Claims) *httptest.ResponseRecorder {
+	h = mid.Errors(log.New(ioutil.Discard, "", 0))(h)
+
+	w := httptest.NewRecorder()
+
+	v := web.Values{Now: now, Method: r.Method, Header: r.Header}
+	ctx := context.WithValue(r.Context(), web.KeyValues, &v)
+	ctx = context.WithValue(ctx, auth.Key, claims)
+
+	if err := h(ctx, w, r, params); err != nil {
+		w.Code = -1
+	}
+	return w
+}
+
+// userClaims returns the claims of a user with the provided subject.
+func userClaims(subject string, roles ...string) auth.Claims {
+	return auth.NewClaims(subject, roles, now, time.Hour)
+}
@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/web"
+	"github.com/remisb/restaurant/internal/restaurant"
+	"go.opentelemetry.io/otel"
+)
+
+// maxImportRows is the most restaurants a single import may hold.
+const maxImportRows = 1000
+
+// These are the outcomes of importing a row.
+const (
+	importCreated   = "CREATED"
+	importValid     = "VALID"
+	importInvalid   = "INVALID"
+	importDuplicate = "DUPLICATE"
+)
+
+// importRow is the outcome of importing one restaurant. Rows are numbered
+// from 1 and do not count the header of a CSV file.
+type importRow struct {
+	Row     int              `json:"row"`
+	Name    string           `json:"name"`
+	Address string           `json:"address"`
+	Status  string           `json:"status"`
+	ID      string           `json:"id,omitempty"`
+	Fields  []web.FieldError `json:"fields,omitempty"`
+}
+
+// importResult is the response to an import.
+type importResult struct {
+	DryRun     bool        `json:"dry_run"`
+	Created    int         `json:"created"`
+	Invalid    int         `json:"invalid"`
+	Duplicates int         `json:"duplicates"`
+	Rows       []importRow `json:"rows"`
+}
+
+// Import creates the restaurants of an uploaded CSV or JSON file. Each row is
+// validated on its own; invalid rows, restaurants which already exist with
+// the same name and address and those named like another restaurant of the
+// calling user are reported and skipped. With the dry_run query parameter
+// nothing is created.
+//
+// The file is the "file" part of a multipart form. A CSV file has a header
+// naming the name and address columns, a JSON file holds an array of
+// restaurants.
+func (res *Restaurant) Import(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Restaurant.Import")
+	defer span.End()
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	dryRun := false
+	if s := r.URL.Query().Get("dry_run"); s != "" {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			err := errors.New("dry_run must be true or false")
+			return requestError(err, http.StatusBadRequest)
+		}
+		dryRun = b
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		err := errors.New("request must be a multipart form with a file part")
+		return requestError(err, http.StatusBadRequest)
+	}
+	defer file.Close()
+
+	rows, err := readImport(file, header)
+	if err != nil {
+		return requestError(err, http.StatusBadRequest)
+	}
+
+	existing, err := res.store.List(ctx)
+	if err != nil {
+		return errors.Wrap(err, "listing restaurants")
+	}
+	seen := make(map[string]bool)
+	owned := make(map[string]bool)
+	for _, e := range existing {
+		seen[importKey(e.Name, e.Address)] = true
+		if e.OwnerUserID == claims.Subject {
+			owned[strings.ToLower(e.Name)] = true
+		}
+	}
+
+	result := importResult{
+		DryRun: dryRun,
+		Rows:   make([]importRow, len(rows)),
+	}
+
+	for i, nr := range rows {
+		row := importRow{
+			Row:     i + 1,
+			Name:    nr.Name,
+			Address: nr.Address,
+		}
+
+		if err := web.Validate(nr); err != nil {
+			var webErr *web.Error
+			if !errors.As(err, &webErr) {
+				return errors.Wrapf(err, "validating row %d", row.Row)
+			}
+			row.Status = importInvalid
+			row.Fields = webErr.Fields
+			result.Invalid++
+			result.Rows[i] = row
+			continue
+		}
+
+		// The calling user owns the imported restaurants, which must have
+		// names of their own.
+		key := importKey(nr.Name, nr.Address)
+		if seen[key] || owned[strings.ToLower(nr.Name)] {
+			row.Status = importDuplicate
+			result.Duplicates++
+			result.Rows[i] = row
+			continue
+		}
+		seen[key] = true
+		owned[strings.ToLower(nr.Name)] = true
+
+		if dryRun {
+			row.Status = importValid
+			result.Rows[i] = row
+			continue
+		}
+
+		// A restaurant of the calling user which is not listed, like one of
+		// another organization or one created meanwhile, may still hold the
+		// name.
+		created, err := res.store.Create(ctx, claims, nr, v.Now)
+		if err != nil {
+			switch err {
+			case restaurant.ErrDuplicateName:
+				row.Status = importDuplicate
+				result.Duplicates++
+				result.Rows[i] = row
+				continue
+			default:
+				return errors.Wrapf(err, "importing row %d: %+v", row.Row, nr)
+			}
+		}
+		if err := res.created(ctx, created, v.Now); err != nil {
+			return err
+		}
+
+		row.Status = importCreated
+		row.ID = created.ID
+		result.Created++
+		result.Rows[i] = row
+	}
+
+	status := http.StatusOK
+	if result.Created > 0 {
+		status = http.StatusCreated
+	}
+
+	return web.Respond(ctx, w, result, status)
+}
+
+// readImport decodes the restaurants of the uploaded file. The format is
+// taken from the extension of the file name, or its content type.
+func readImport(file multipart.File, header *multipart.FileHeader) ([]restaurant.NewRestaurant, error) {
+	var rows []restaurant.NewRestaurant
+	var err error
+
+	ext := strings.ToLower(filepath.Ext(header.Filename))
+	ct := header.Header.Get("Content-Type")
+	switch {
+	case ext == ".csv" || strings.HasPrefix(ct, "text/csv"):
+		rows, err = readImportCSV(file)
+	case ext == ".json" || strings.HasPrefix(ct, "application/json"):
+		rows, err = readImportJSON(file)
+	default:
+		return nil, errors.New("file must be a .csv or .json file")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case len(rows) == 0:
+		return nil, errors.New("file must contain at least one restaurant")
+	case len(rows) > maxImportRows:
+		return nil, errors.Errorf("file must not contain more than %d restaurants", maxImportRows)
+	}
+
+	return rows, nil
+}
+
+// readImportCSV decodes a CSV file with a header naming the columns.
+func readImportCSV(file io.Reader) ([]restaurant.NewRestaurant, error) {
+	cr := csv.NewReader(file)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, errors.New("csv file must start with a header")
+	}
+
+	name, address := -1, -1
+	for i, col := range header {
+		switch strings.ToLower(strings.TrimSpace(col)) {
+		case "name":
+			name = i
+		case "address":
+			address = i
+		default:
+			return nil, errors.Errorf("csv column %q is not a known field", col)
+		}
+	}
+	if name < 0 || address < 0 {
+		return nil, errors.New("csv header must name the name and address columns")
+	}
+
+	var rows []restaurant.NewRestaurant
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "reading csv file")
+		}
+		if len(rows) == maxImportRows {
+			return nil, errors.Errorf("file must not contain more than %d restaurants", maxImportRows)
+		}
+
+		rows = append(rows, restaurant.NewRestaurant{
+			Name:    strings.TrimSpace(rec[name]),
+			Address: strings.TrimSpace(rec[address]),
+		})
+	}
+
+	return rows, nil
+}
+
+// readImportJSON decodes a JSON file holding an array of restaurants.
+func readImportJSON(file io.Reader) ([]restaurant.NewRestaurant, error) {
+	dec := json.NewDecoder(file)
+	dec.DisallowUnknownFields()
+
+	var rows []restaurant.NewRestaurant
+	if err := dec.Decode(&rows); err != nil {
+		return nil, errors.Wrap(err, "json file must hold an array of restaurants")
+	}
+
+	for i := range rows {
+		rows[i].Name = strings.TrimSpace(rows[i].Name)
+		rows[i].Address = strings.TrimSpace(rows[i].Address)
+	}
+
+	return rows, nil
+}
+
+// importKey identifies a restaurant by its name and address ignoring case
+// and spacing.
+func importKey(name, address string) string {
+	norm := func(s string) string {
+		return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+	}
+	return norm(name) + "\x00" + norm(address)
+}
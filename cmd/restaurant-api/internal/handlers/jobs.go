@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/remisb/restaurant/internal/platform/database"
+	"github.com/remisb/restaurant/internal/platform/job"
+	"github.com/remisb/restaurant/internal/platform/web"
+	"go.opentelemetry.io/otel"
+)
+
+// elections are the background jobs running on one replica only, with the
+// key of the advisory lock electing the replica running them.
+var elections = []struct {
+	job string
+	key int64
+}{
+	{"vote_winner", database.LockVoteWinner},
+	{"retention", database.LockRetention},
+}
+
+// Jobs represents the background jobs API method handler set. The jobs run
+// for the whole deployment so only the admins of the default organization
+// see them.
+type Jobs struct {
+	db      *sqlx.DB
+	jobs    []Job
+	elected bool
+}
+
+// jobsInfo is the state of the background jobs seen from one replica.
+type jobsInfo struct {
+	Jobs    []job.Status `json:"jobs"`
+	Leaders []jobLeader  `json:"leaders"`
+}
+
+// jobLeader is the session of the replica leading a job, nil while no
+// replica leads it.
+type jobLeader struct {
+	Job    string           `json:"job"`
+	Holder *database.Holder `json:"holder"`
+}
+
+// List reports the state of the background jobs of the replica answering
+// and which replica leads each of the jobs running on one replica only.
+func (j *Jobs) List(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Jobs.List")
+	defer span.End()
+
+	if err := checkOperator(ctx); err != nil {
+		return err
+	}
+
+	info := jobsInfo{
+		Jobs:    []job.Status{},
+		Leaders: []jobLeader{},
+	}
+	for _, jb := range j.jobs {
+		info.Jobs = append(info.Jobs, jb.Status())
+	}
+
+	// The elections are only held on PostgreSQL.
+	if j.elected {
+		for _, e := range elections {
+			h, err := database.LockHolder(ctx, j.db, e.key)
+			if err != nil {
+				return err
+			}
+			info.Leaders = append(info.Leaders, jobLeader{Job: e.job, Holder: h})
+		}
+	}
+
+	return web.Respond(ctx, w, info, http.StatusOK)
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/job"
+)
+
+// TestJobsList validates the admins of the service see the role of the
+// replica in the jobs running on one replica only.
+func TestJobsList(t *testing.T) {
+	leader := job.NewTracker("vote_winner")
+	leader.Elected(true, now)
+	standby := job.NewTracker("vote_winner")
+	standby.Elected(false, time.Time{})
+
+	t.Log("Given the need to know which replica runs the background jobs.")
+	{
+		t.Log("\tTest 0:\tWhen a user who is not an admin asks.")
+		{
+			j := Jobs{jobs: []Job{leader}}
+			if w := serve(j.List, http.MethodGet, "", nil, userClaims(otherID)); w.Code != http.StatusForbidden {
+				t.Fatalf("\t✗\tShould forbid the request : got %d.", w.Code)
+			}
+			t.Log("\t✓\tShould forbid the request.")
+		}
+
+		tests := []struct {
+			name  string
+			job   Job
+			role  string
+			since bool
+		}{
+			{"leader", leader, job.RoleLeader, true},
+			{"standby", standby, job.RoleStandby, false},
+		}
+		for i, tt := range tests {
+			t.Logf("\tTest %d:\tWhen the replica is the %s.", i+1, tt.name)
+			{
+				j := Jobs{jobs: []Job{tt.job}}
+				w := serve(j.List, http.MethodGet, "", nil, userClaims(ownerID, auth.RoleAdmin))
+				if w.Code != http.StatusOK {
+					t.Fatalf("\t✗\tShould list the jobs : got %d.", w.Code)
+				}
+
+				var got jobsInfo
+				if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+					t.Fatalf("\t✗\tShould decode the response : %s.", err)
+				}
+				if len(got.Jobs) != 1 || got.Jobs[0].Role != tt.role || (got.Jobs[0].LeaderSince != nil) != tt.since {
+					t.Fatalf("\t✗\tShould report the role %q : got %+v.", tt.role, got.Jobs)
+				}
+				t.Logf("\t✓\tShould report the role %q.", tt.role)
+			}
+		}
+	}
+}
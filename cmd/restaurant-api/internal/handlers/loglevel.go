@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/remisb/restaurant/internal/platform/loglevel"
+)
+
+// logLevel is the document read and written by DebugLogLevel.
+type logLevel struct {
+	Level string `json:"level"`
+}
+
+// DebugLogLevel returns the handler reading the level of the logs with GET
+// and changing it with PUT, meant for the debug listener which is not
+// exposed publicly.
+func DebugLogLevel(f *loglevel.Filter) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			var ll logLevel
+			if err := json.NewDecoder(r.Body).Decode(&ll); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			level, err := loglevel.Parse(ll.Level)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			f.SetLevel(level)
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(logLevel{Level: f.Level().String()})
+	})
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"context"
+	"mime"
+	"net/http"
+	"path"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/media"
+	"github.com/remisb/restaurant/internal/platform/web"
+	"github.com/remisb/restaurant/internal/restaurant"
+	"go.opentelemetry.io/otel"
+)
+
+// formOverhead is the room left for the rest of an upload form on top of the
+// size of its image.
+const formOverhead = 64 << 10
+
+// Media represents the image upload API method handler set. Only the owner of
+// the restaurant and admins may upload its images.
+type Media struct {
+	db          *sqlx.DB
+	restaurants restaurant.Store
+	uploader    *media.Uploader
+}
+
+// UploadPhoto adds the image sent in the image field of the form to the
+// photos of the restaurant.
+func (md *Media) UploadPhoto(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Media.UploadPhoto")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	id := params["id"]
+	if err := checkRestaurantOwner(ctx, md.restaurants, id); err != nil {
+		return err
+	}
+
+	img, err := md.upload(ctx, w, r, "restaurants/"+id)
+	if err != nil {
+		return err
+	}
+
+	rest, err := restaurant.AddPhoto(ctx, md.db, id, img.URL, img.ThumbnailURL, v.Now)
+	if err != nil {
+		switch err {
+		case restaurant.ErrNotFound:
+			return requestError(err, http.StatusNotFound)
+		case restaurant.ErrVersionConflict:
+			return requestError(err, http.StatusConflict)
+		default:
+			return errors.Wrapf(err, "ID: %s", id)
+		}
+	}
+
+	return web.Respond(ctx, w, rest, http.StatusCreated)
+}
+
+// UploadMenuImage sets the image sent in the image field of the form as the
+// image of the menu.
+func (md *Media) UploadMenuImage(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Media.UploadMenuImage")
+	defer span.End()
+
+	restaurantID := params["restaurantId"]
+	if err := checkRestaurantOwner(ctx, md.restaurants, restaurantID); err != nil {
+		return err
+	}
+
+	img, err := md.upload(ctx, w, r, "restaurants/"+restaurantID+"/menus")
+	if err != nil {
+		return err
+	}
+
+	m, err := restaurant.SetMenuImage(ctx, md.db, restaurantID, params["menuId"], img.URL, img.ThumbnailURL)
+	if err != nil {
+		switch err {
+		case restaurant.ErrNotFound:
+			return requestError(err, http.StatusNotFound)
+		default:
+			return errors.Wrapf(err, "ID: %s", params["menuId"])
+		}
+	}
+
+	return web.Respond(ctx, w, m, http.StatusCreated)
+}
+
+// Serve writes the image stored under the key of the request URL. Only the
+// images stored on the local disk are served by the API.
+func (md *Media) Serve(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Media.Serve")
+	defer span.End()
+
+	disk, ok := md.uploader.Storage().(*media.Disk)
+	if !ok {
+		return requestError(media.ErrNotFound, http.StatusNotFound)
+	}
+
+	f, err := disk.Open(params["key"])
+	if err != nil {
+		switch err {
+		case media.ErrNotFound:
+			return requestError(err, http.StatusNotFound)
+		default:
+			return errors.Wrapf(err, "key: %s", params["key"])
+		}
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return errors.Wrapf(err, "key: %s", params["key"])
+	}
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+	v.StatusCode = http.StatusOK
+
+	// The keys are unique so the images never change.
+	w.Header().Set("Content-Type", mime.TypeByExtension(path.Ext(params["key"])))
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	http.ServeContent(w, r, "", fi.ModTime(), f)
+
+	return nil
+}
+
+// upload stores the image sent in the image field of the form under the
+// prefix.
+func (md *Media) upload(ctx context.Context, w http.ResponseWriter, r *http.Request, prefix string) (*media.Image, error) {
+	data, err := web.DecodeFile(w, r, "image", md.uploader.MaxSize()+formOverhead)
+	if err != nil {
+		return nil, err
+	}
+
+	img, err := md.uploader.Upload(ctx, prefix, data)
+	if err != nil {
+		switch err {
+		case media.ErrTooLarge:
+			return nil, requestError(err, http.StatusRequestEntityTooLarge)
+		case media.ErrUnsupportedType:
+			return nil, requestError(err, http.StatusUnsupportedMediaType)
+		default:
+			return nil, errors.Wrap(err, "uploading image")
+		}
+	}
+
+	return img, nil
+}
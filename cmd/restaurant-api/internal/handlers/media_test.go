@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/remisb/restaurant/internal/media"
+	"github.com/remisb/restaurant/internal/memstore"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/restaurant"
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// TestMediaUpload validates uploads are checked before they are stored.
+func TestMediaUpload(t *testing.T) {
+	const id = "a2b0639f-2cc6-44b8-b97b-15d69dbb511e"
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("image", "menu.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write([]byte("<html></html>"))
+	mw.Close()
+
+	md := Media{
+		restaurants: memstore.NewRestaurants(restaurant.Restaurant{ID: id, OwnerUserID: ownerID}),
+		uploader:    media.NewUploader(media.NewDisk(t.TempDir(), "/v1/media"), 1<<20),
+	}
+
+	tt := []struct {
+		name   string
+		claims auth.Claims
+		status int
+	}{
+		{"someone else's restaurant", userClaims(otherID, auth.RoleUser), http.StatusForbidden},
+		{"something other than an image", userClaims(ownerID, auth.RoleUser), http.StatusUnsupportedMediaType},
+	}
+
+	t.Log("Given the need to check uploaded images.")
+	{
+		for i, tc := range tt {
+			t.Logf("\tTest %d:\tWhen uploading %s.", i, tc.name)
+			{
+				r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body.Bytes()))
+				r.Header.Set("Content-Type", mw.FormDataContentType())
+
+				w := serveRequest(md.UploadPhoto, r, map[string]string{"id": id}, tc.claims)
+				if w.Code != tc.status {
+					t.Fatalf("\t%s\tShould receive a status code of %d : got %d : %s", tests.Failed, tc.status, w.Code, w.Body)
+				}
+				t.Logf("\t%s\tShould receive a status code of %d.", tests.Success, tc.status)
+			}
+		}
+	}
+}
+
+// TestMediaServe validates the images stored on the local disk are served.
+func TestMediaServe(t *testing.T) {
+	disk := media.NewDisk(t.TempDir(), "/v1/media")
+	if err := disk.Put(context.Background(), "restaurants/1/a.png", "image/png", []byte("png")); err != nil {
+		t.Fatal(err)
+	}
+	md := Media{uploader: media.NewUploader(disk, 1<<20)}
+
+	tt := []struct {
+		key    string
+		status int
+	}{
+		{"restaurants/1/a.png", http.StatusOK},
+		{"restaurants/1/b.png", http.StatusNotFound},
+		{"../a.png", http.StatusNotFound},
+	}
+
+	t.Log("Given the need to serve the stored images.")
+	{
+		for i, tc := range tt {
+			t.Logf("\tTest %d:\tWhen requesting %s.", i, tc.key)
+			{
+				w := serve(md.Serve, http.MethodGet, "", map[string]string{"key": tc.key}, auth.Claims{})
+				if w.Code != tc.status {
+					t.Fatalf("\t%s\tShould receive a status code of %d : got %d : %s", tests.Failed, tc.status, w.Code, w.Body)
+				}
+				if tc.status == http.StatusOK && (w.Body.String() != "png" || w.Header().Get("Content-Type") != "image/png") {
+					t.Fatalf("\t%s\tShould receive the image : got %q %q", tests.Failed, w.Header().Get("Content-Type"), w.Body)
+				}
+				t.Logf("\t%s\tShould receive a status code of %d.", tests.Success, tc.status)
+			}
+		}
+	}
+}
@@ -2,72 +2,238 @@ package handlers
 
 import (
 	"context"
-	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/authz"
+	"github.com/remisb/restaurant/internal/media"
+	"github.com/remisb/restaurant/internal/notification"
 	"github.com/remisb/restaurant/internal/platform/auth"
 	"github.com/remisb/restaurant/internal/platform/web"
 	"github.com/remisb/restaurant/internal/restaurant"
-	"go.opencensus.io/trace"
+	"github.com/remisb/restaurant/internal/stats"
+	"github.com/remisb/restaurant/internal/vote"
+	"github.com/remisb/restaurant/internal/webhook"
+	"go.opentelemetry.io/otel"
+	"image"
 	"net/http"
+	"time"
 )
 
+// Menu represents the restaurant menu API method handler set.
 type Menu struct {
-	db *sqlx.DB
+	store       restaurant.MenuStore
+	restaurants restaurant.Store
+	votes       vote.Store
+	webhooks    *webhook.Notifier
+	notifier    *notification.Notifier
+	uploader    *media.Uploader
+	views       *stats.Store
+}
+
+// linkedMenu is a menu with the links to its related resources.
+type linkedMenu struct {
+	restaurant.Menu
+	Links web.Links `json:"links"`
+}
+
+// menuLinks returns the links of a menu to itself, its restaurant and the
+// votes cast for the restaurant on the date of the menu.
+func menuLinks(m restaurant.Menu) web.Links {
+	return web.Links{
+		"self":       web.Link("/v1/restaurant/:restaurantId/menu/:menuId", m.RestaurantID, m.ID),
+		"restaurant": web.Link("/v1/restaurant/:id", m.RestaurantID),
+		"votes":      web.Link("/v1/restaurant/:id/votes", m.RestaurantID) + "?date=" + m.Date.Format("2006-01-02"),
+	}
 }
 
 // List gets all existing restaurants in the system.
 func (m *Menu) List(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
-	ctx, span := trace.StartSpan(ctx, "handlers.Menu.List")
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Menu.List")
 	defer span.End()
 
-	restaurants, err := restaurant.List(ctx, m.db)
+	restaurants, err := m.restaurants.List(ctx)
 	if err != nil {
 		return err
 	}
 
-	return web.Respond(ctx, w, restaurants, http.StatusOK)
+	return web.RespondList(ctx, w, restaurants, http.StatusOK)
+}
+
+// ListMenus returns the menus of a restaurant from the date of the from query
+// parameter on, or all of them. The items containing any of the allergens of
+// the exclude_allergens query parameter, like nuts,gluten, are left out.
+func (m *Menu) ListMenus(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Menu.ListMenus")
+	defer span.End()
+
+	excluded, err := restaurant.ParseAllergens(r.URL.Query().Get("exclude_allergens"))
+	if err != nil {
+		return requestError(err, http.StatusBadRequest)
+	}
+
+	var from time.Time
+	if s := r.URL.Query().Get("from"); s != "" {
+		d, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			err := errors.New("from must be a date formatted as YYYY-MM-DD")
+			return requestError(err, http.StatusBadRequest)
+		}
+		from = d
+	}
+
+	menus, err := m.store.ListMenus(ctx, params["restaurantId"], from)
+	if err != nil {
+		switch err {
+		default:
+			return errors.Wrapf(err, "ID: %s", params["restaurantId"])
+		}
+	}
+
+	if len(excluded) > 0 {
+		for i := range menus {
+			menus[i].Items = menus[i].Items.Exclude(excluded)
+		}
+	}
+
+	if web.WantsCSV(r) {
+		return exportMenus(ctx, w, menus)
+	}
+
+	linked := make([]linkedMenu, len(menus))
+	for i, menu := range menus {
+		linked[i] = linkedMenu{menu, menuLinks(menu)}
+	}
+
+	return web.RespondList(ctx, w, linked, http.StatusOK)
 }
 
 func (m *Menu) RetrieveMenu(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
-	ctx, span := trace.StartSpan(ctx, "handlers.Menu.Retrieve")
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Menu.Retrieve")
 	defer span.End()
 
-	menuRetrieved, err := restaurant.MenuRetrieve(ctx, m.db, params["restaurantId"])
+	excluded, err := restaurant.ParseAllergens(r.URL.Query().Get("exclude_allergens"))
+	if err != nil {
+		return requestError(err, http.StatusBadRequest)
+	}
+
+	menuRetrieved, err := m.store.RetrieveMenu(ctx, params["restaurantId"])
 	if err != nil {
 		switch err {
-		case restaurant.ErrInvalidID:
-			return web.NewRequestError(err, http.StatusBadRequest)
 		case restaurant.ErrNotFound:
-			return web.NewRequestError(err, http.StatusNotFound)
+			return requestError(err, http.StatusNotFound)
 		default:
 			return errors.Wrapf(err, "ID: %s", params["id"])
 		}
 	}
+	if len(excluded) > 0 {
+		menuRetrieved.Items = menuRetrieved.Items.Exclude(excluded)
+	}
 
-	return web.Respond(ctx, w, menuRetrieved, http.StatusOK)
+	// A view which is not counted is not worth failing the request for.
+	_ = m.views.CountView(ctx, menuRetrieved.ID)
+
+	return web.Respond(ctx, w, linkedMenu{*menuRetrieved, menuLinks(*menuRetrieved)}, http.StatusOK)
 }
 
-func (m *Menu) RetrieveVotes(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
-	ctx, span := trace.StartSpan(ctx, "handlers.Menu.Retrieve")
+// PDF renders a menu of the restaurant as a printable document with its
+// items, their prices and allergens and the first photo of the restaurant as
+// its logo.
+func (m *Menu) PDF(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Menu.PDF")
 	defer span.End()
 
-	menuRetrieved, err := restaurant.MenuRetrieve(ctx, m.db, params["restaurantId"])
+	restaurantID := params["restaurantId"]
+	res, err := m.restaurants.Retrieve(ctx, restaurantID)
 	if err != nil {
 		switch err {
-		case restaurant.ErrInvalidID:
-			return web.NewRequestError(err, http.StatusBadRequest)
 		case restaurant.ErrNotFound:
-			return web.NewRequestError(err, http.StatusNotFound)
+			return requestError(err, http.StatusNotFound)
 		default:
-			return errors.Wrapf(err, "ID: %s", params["id"])
+			return errors.Wrapf(err, "ID: %s", restaurantID)
+		}
+	}
+
+	menu, err := m.store.RetrieveMenu(ctx, params["menuId"])
+	if err != nil {
+		switch err {
+		case restaurant.ErrNotFound:
+			return requestError(err, http.StatusNotFound)
+		default:
+			return errors.Wrapf(err, "ID: %s", params["menuId"])
+		}
+	}
+	if menu.RestaurantID != res.ID {
+		return requestError(restaurant.ErrNotFound, http.StatusNotFound)
+	}
+
+	// The menu is printed without a logo rather than not at all when the
+	// photo cannot be read.
+	var logo image.Image
+	if m.uploader != nil && len(res.Photos) > 0 {
+		url := res.Photos[0]
+		if len(res.Thumbnails) > 0 && res.Thumbnails[0] != "" {
+			url = res.Thumbnails[0]
+		}
+		if logo, err = m.uploader.Load(ctx, url); err != nil {
+			logo = nil
+		}
+	}
+
+	return exportMenuPDF(ctx, w, res, menu, logo)
+}
+
+// RetrieveVotes returns the number of votes the restaurant received for the
+// date query parameter or today. It is read from the running tally so it does
+// not count the votes. The voters are listed along with it unless the votes
+// are anonymous.
+func (m *Menu) RetrieveVotes(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Menu.RetrieveVotes")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	restaurantID := params["restaurantId"]
+	if _, err := m.restaurants.Retrieve(ctx, restaurantID); err != nil {
+		switch err {
+		case restaurant.ErrNotFound:
+			return requestError(err, http.StatusNotFound)
+		default:
+			return errors.Wrapf(err, "ID: %s", restaurantID)
 		}
 	}
 
-	return web.Respond(ctx, w, menuRetrieved, http.StatusOK)
+	date, err := vote.ParseDate(r.URL.Query().Get("date"), v.Now)
+	if err != nil {
+		return requestError(err, http.StatusBadRequest)
+	}
+
+	tally, err := m.votes.RetrieveTally(ctx, restaurantID, date)
+	if err != nil {
+		return errors.Wrapf(err, "ID: %s", restaurantID)
+	}
+
+	voters, err := m.votes.Voters(ctx, restaurantID, date)
+	if err != nil && err != vote.ErrAnonymous {
+		return errors.Wrapf(err, "ID: %s", restaurantID)
+	}
+
+	resp := struct {
+		*vote.Tally
+		Anonymous bool     `json:"anonymous"`
+		Voters    []string `json:"voters,omitempty"`
+	}{
+		Tally:     tally,
+		Anonymous: err == vote.ErrAnonymous,
+		Voters:    voters,
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
 }
 
 func (m *Menu) CreateMenu(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
-	ctx, span := trace.StartSpan(ctx, "handlers.Menu.CreateMenu")
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Menu.CreateMenu")
 	defer span.End()
 
 	claims, ok := ctx.Value(auth.Key).(auth.Claims)
@@ -91,37 +257,49 @@ func (m *Menu) CreateMenu(ctx context.Context, w http.ResponseWriter, r *http.Re
 		return restaurant.ErrInvalidID
 	}
 
-	restaurantRes, err := restaurant.Retrieve(ctx, m.db, restaurantId)
+	restaurantRes, err := m.restaurants.Retrieve(ctx, restaurantId)
 	if err != nil {
 		switch err {
-		case restaurant.ErrInvalidID:
-			return web.NewRequestError(err, http.StatusBadRequest)
 		case restaurant.ErrNotFound:
-			return web.NewRequestError(err, http.StatusNotFound)
+			return requestError(err, http.StatusNotFound)
 		case restaurant.ErrForbidden:
-			return web.NewRequestError(err, http.StatusForbidden)
+			return requestError(err, http.StatusForbidden)
 		default:
 			return errors.Wrapf(err, "retrieving restaurant id: %s", restaurantId)
 		}
 	}
 
-	if restaurantRes.OwnerUserID != claims.Subject {
-		return web.NewRequestError(err, http.StatusForbidden)
+	if !authz.Allowed(claims, authz.Create, authz.Resource{Kind: authz.Menu, Owner: restaurantRes.OwnerUserID}) {
+		return requestError(restaurant.ErrForbidden, http.StatusForbidden)
 	}
 
-	restResult, err := restaurant.CreateMenu(ctx, m.db, claims, nm, v.Now)
+	restResult, err := m.store.CreateMenu(ctx, claims, nm, v.Now)
 	if err != nil {
-		return errors.Wrapf(err, "creating new menu: %+v", nm)
+		switch err {
+		case restaurant.ErrInvalidDate, restaurant.ErrDateOutOfRange:
+			return requestError(err, http.StatusBadRequest)
+		default:
+			return errors.Wrapf(err, "creating new menu: %+v", nm)
+		}
 	}
 
 	if restaurantRes == nil {
 		return restaurant.ErrNotFound
 	}
-	return web.Respond(ctx, w, restResult, http.StatusCreated)
+
+	if err := m.webhooks.Notify(ctx, webhook.EventMenuPublished, restResult, v.Now); err != nil {
+		return errors.Wrapf(err, "notifying webhooks of menu for restaurant %s", restaurantId)
+	}
+
+	if err := m.notifier.MenuPublished(ctx, restaurantId, restResult, v.Now); err != nil {
+		return errors.Wrapf(err, "notifying users of menu for restaurant %s", restaurantId)
+	}
+
+	return web.Respond(ctx, w, linkedMenu{*restResult, menuLinks(*restResult)}, http.StatusCreated)
 }
 
 func (m *Menu) Update(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
-	ctx, span := trace.StartSpan(ctx, "handlers.Menu.Update")
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Menu.Update")
 	defer span.End()
 
 	claims, ok := ctx.Value(auth.Key).(auth.Claims)
@@ -134,22 +312,73 @@ func (m *Menu) Update(ctx context.Context, w http.ResponseWriter, r *http.Reques
 		return web.NewShutdownError("web value missing from context")
 	}
 
-	var up restaurant.UpdateMenu
+	up := restaurant.UpdateMenu{Today: v.Now}
 	if err := web.Decode(r, &up); err != nil {
 		return errors.Wrap(err, "request decode")
 	}
 
-	if err := restaurant.MenuUpdate(ctx, m.db, claims, params["restaurantId"], up, v.Now); err != nil {
+	return m.update(ctx, w, r, claims, params["restaurantId"], up, v.Now)
+}
+
+// Patch applies a JSON merge patch to a menu of the restaurant. The IDs of the
+// restaurant and the menu are part of the request URL.
+func (m *Menu) Patch(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Menu.Patch")
+	defer span.End()
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	current, err := m.store.RetrieveMenu(ctx, params["menuId"])
+	if err != nil {
+		switch err {
+		case restaurant.ErrNotFound:
+			return requestError(err, http.StatusNotFound)
+		default:
+			return errors.Wrapf(err, "retrieving menu %q", params["menuId"])
+		}
+	}
+	if current.RestaurantID != params["restaurantId"] {
+		return requestError(restaurant.ErrNotFound, http.StatusNotFound)
+	}
+
+	patch := restaurant.NewPatchMenu(*current)
+	if err := web.DecodePatch(r, &patch); err != nil {
+		return errors.Wrap(err, "request decode")
+	}
+
+	up := patch.Update(current.ID, v.Now)
+	if err := web.Validate(up); err != nil {
+		return errors.Wrap(err, "validating patched menu")
+	}
+
+	return m.update(ctx, w, r, claims, params["restaurantId"], up, v.Now)
+}
+
+// update applies the changes to the menu for Update and Patch.
+func (m *Menu) update(ctx context.Context, w http.ResponseWriter, r *http.Request, claims auth.Claims, restaurantID string, up restaurant.UpdateMenu, now time.Time) error {
+	updated, err := m.store.UpdateMenu(ctx, claims, restaurantID, up, now)
+	if err != nil {
 		switch err {
-		case restaurant.ErrInvalidID:
-			return web.NewRequestError(err, http.StatusBadRequest)
 		case restaurant.ErrNotFound:
-			return web.NewRequestError(err, http.StatusNotFound)
+			return requestError(err, http.StatusNotFound)
 		case restaurant.ErrForbidden:
-			return web.NewRequestError(err, http.StatusForbidden)
+			return requestError(err, http.StatusForbidden)
+		case restaurant.ErrVersionConflict:
+			return requestError(err, http.StatusConflict)
+		case restaurant.ErrInvalidDate:
+			return requestError(err, http.StatusBadRequest)
 		default:
-			return errors.Wrapf(err, "updating menu %q: %+v", params["restaurantId"], up)
+			return errors.Wrapf(err, "updating menu %q: %+v", restaurantID, up)
 		}
 	}
-	return nil
+
+	return web.RespondUpdated(ctx, w, r, linkedMenu{*updated, menuLinks(*updated)})
 }
@@ -0,0 +1,525 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/remisb/restaurant/internal/memstore"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/restaurant"
+	"github.com/remisb/restaurant/internal/tests"
+	"github.com/remisb/restaurant/internal/vote"
+)
+
+// TestMenuCreate validates only the owner of a restaurant or an admin
+// publishes its menu.
+func TestMenuCreate(t *testing.T) {
+	const id = "a2b0639f-2cc6-44b8-b97b-15d69dbb511e"
+	existing := restaurant.Restaurant{ID: id, Name: "Pizza Place", OwnerUserID: ownerID}
+
+	tt := []struct {
+		name   string
+		id     string
+		claims auth.Claims
+		status int
+	}{
+		{"owner", id, userClaims(ownerID, auth.RoleAdmin), http.StatusCreated},
+		{"admin not owner", id, userClaims(otherID, auth.RoleAdmin), http.StatusCreated},
+		{"user not owner", id, userClaims(otherID, auth.RoleUser), http.StatusForbidden},
+		{"missing restaurant", "ad1b9a26-e1a1-4e4c-9a1b-3a2b5f6f0d3b", userClaims(ownerID, auth.RoleAdmin), http.StatusNotFound},
+	}
+
+	t.Log("Given the need to publish menus.")
+	{
+		for i, tc := range tt {
+			t.Logf("\tTest %d:\tWhen handling %s.", i, tc.name)
+			{
+				restaurants := memstore.NewRestaurants(existing)
+				menus := memstore.NewMenus(restaurants)
+				m := Menu{store: menus, restaurants: restaurants}
+
+				body := `{"restaurant_id":"` + tc.id + `","menu":"Lasagne"}`
+				w := serve(m.CreateMenu, http.MethodPost, body, map[string]string{"restaurantId": tc.id}, tc.claims)
+				if w.Code != tc.status {
+					t.Fatalf("\t%s\tShould receive a status code of %d : got %d : %s", tests.Failed, tc.status, w.Code, w.Body)
+				}
+				t.Logf("\t%s\tShould receive a status code of %d.", tests.Success, tc.status)
+
+				want := 0
+				if tc.status == http.StatusCreated {
+					want = 1
+				}
+				list, _ := menus.ListMenus(context.Background(), tc.id, now.AddDate(0, 0, -1))
+				if len(list) != want {
+					t.Fatalf("\t%s\tShould store the menu only when published : got %d menus.", tests.Failed, len(list))
+				}
+				t.Logf("\t%s\tShould store the menu only when published.", tests.Success)
+			}
+		}
+	}
+}
+
+// TestMenuVotes validates the votes of a restaurant are read from the tally.
+func TestMenuVotes(t *testing.T) {
+	existing := restaurant.Restaurant{ID: votedID, Name: "Pizza Place", OwnerUserID: ownerID}
+
+	tt := []struct {
+		name   string
+		id     string
+		query  string
+		status int
+		votes  int
+	}{
+		{"today", votedID, "", http.StatusOK, 1},
+		{"another date", votedID, "?date=2020-03-03", http.StatusOK, 0},
+		{"invalid date", votedID, "?date=monday", http.StatusBadRequest, 0},
+		{"missing restaurant", "ad1b9a26-e1a1-4e4c-9a1b-3a2b5f6f0d3b", "", http.StatusNotFound, 0},
+	}
+
+	t.Log("Given the need to count the votes of a restaurant.")
+	{
+		for i, tc := range tt {
+			t.Logf("\tTest %d:\tWhen handling %s.", i, tc.name)
+			{
+				restaurants := memstore.NewRestaurants(existing)
+				votes := memstore.NewVotes(restaurants)
+				m := Menu{restaurants: restaurants, votes: votes}
+
+				nv := vote.NewVote{RestaurantID: votedID}
+				if _, err := votes.Cast(context.Background(), userClaims(otherID, auth.RoleUser), nv, votePolicy, now); err != nil {
+					t.Fatalf("\t%s\tShould cast a vote : %s.", tests.Failed, err)
+				}
+
+				r := httptest.NewRequest(http.MethodGet, "/"+tc.query, nil)
+				w := serveRequest(m.RetrieveVotes, r, map[string]string{"restaurantId": tc.id}, userClaims(ownerID, auth.RoleUser))
+				if w.Code != tc.status {
+					t.Fatalf("\t%s\tShould receive a status code of %d : got %d : %s", tests.Failed, tc.status, w.Code, w.Body)
+				}
+				t.Logf("\t%s\tShould receive a status code of %d.", tests.Success, tc.status)
+
+				if tc.status != http.StatusOK {
+					continue
+				}
+
+				var got vote.Tally
+				if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+					t.Fatalf("\t%s\tShould decode the tally : %s.", tests.Failed, err)
+				}
+				if got.Votes != tc.votes {
+					t.Fatalf("\t%s\tShould count %d votes : got %d.", tests.Failed, tc.votes, got.Votes)
+				}
+				t.Logf("\t%s\tShould count %d votes.", tests.Success, tc.votes)
+			}
+		}
+	}
+}
+
+// TestMenuVoters validates the voters of a restaurant are only shown when its
+// votes are not anonymous.
+func TestMenuVoters(t *testing.T) {
+	tt := []struct {
+		name       string
+		restaurant bool
+		org        bool
+		voters     int
+	}{
+		{"visible votes", false, false, 1},
+		{"anonymous restaurant", true, false, 0},
+		{"anonymous organization", false, true, 0},
+	}
+
+	t.Log("Given the need to hide who voted when votes are anonymous.")
+	{
+		for i, tc := range tt {
+			t.Logf("\tTest %d:\tWhen handling %s.", i, tc.name)
+			{
+				restaurants := memstore.NewRestaurants(restaurant.Restaurant{ID: votedID, Name: "Pizza Place", OwnerUserID: ownerID, AnonymousVotes: tc.restaurant})
+				votes := memstore.NewVotes(restaurants)
+				votes.SetAnonymous(tc.org)
+				m := Menu{restaurants: restaurants, votes: votes}
+
+				nv := vote.NewVote{RestaurantID: votedID}
+				if _, err := votes.Cast(context.Background(), userClaims(otherID, auth.RoleUser), nv, votePolicy, now); err != nil {
+					t.Fatalf("\t%s\tShould cast a vote : %s.", tests.Failed, err)
+				}
+
+				r := httptest.NewRequest(http.MethodGet, "/", nil)
+				w := serveRequest(m.RetrieveVotes, r, map[string]string{"restaurantId": votedID}, userClaims(ownerID, auth.RoleUser))
+				if w.Code != http.StatusOK {
+					t.Fatalf("\t%s\tShould receive a status code of 200 : got %d : %s", tests.Failed, w.Code, w.Body)
+				}
+
+				var got struct {
+					Votes     int      `json:"votes"`
+					Anonymous bool     `json:"anonymous"`
+					Voters    []string `json:"voters"`
+				}
+				if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+					t.Fatalf("\t%s\tShould decode the votes : %s.", tests.Failed, err)
+				}
+				if got.Votes != 1 || len(got.Voters) != tc.voters || got.Anonymous != (tc.voters == 0) {
+					t.Fatalf("\t%s\tShould list %d voters along with the count : got %+v.", tests.Failed, tc.voters, got)
+				}
+				t.Logf("\t%s\tShould list %d voters along with the count.", tests.Success, tc.voters)
+
+				err := votes.History(context.Background(), now.AddDate(0, 0, -1), now.AddDate(0, 0, 1), func(v vote.Vote) error {
+					if (v.UserID == "") != (tc.voters == 0) {
+						t.Fatalf("\t%s\tShould only keep the voter in the history when visible : got %q.", tests.Failed, v.UserID)
+					}
+					return nil
+				})
+				if err != nil {
+					t.Fatalf("\t%s\tShould read the history : %s.", tests.Failed, err)
+				}
+				t.Logf("\t%s\tShould only keep the voter in the history when visible.", tests.Success)
+			}
+		}
+	}
+}
+
+// TestMenuCreateDate validates menus are published for the day they are
+// served on.
+func TestMenuCreateDate(t *testing.T) {
+	const id = "a2b0639f-2cc6-44b8-b97b-15d69dbb511e"
+	claims := userClaims(ownerID, auth.RoleAdmin)
+	params := map[string]string{"restaurantId": id}
+	tomorrow := now.AddDate(0, 0, 1).Format(restaurant.MenuDateLayout)
+
+	t.Log("Given the need to publish menus ahead.")
+	{
+		t.Log("\tTest 0:\tWhen publishing the menu of tomorrow.")
+		{
+			restaurants := memstore.NewRestaurants(restaurant.Restaurant{ID: id, Name: "Pizza Place", OwnerUserID: ownerID})
+			m := Menu{store: memstore.NewMenus(restaurants), restaurants: restaurants}
+
+			body := `{"restaurant_id":"` + id + `","date":"` + tomorrow + `","menu":"Lasagne"}`
+			w := serve(m.CreateMenu, http.MethodPost, body, params, claims)
+			if w.Code != http.StatusCreated {
+				t.Fatalf("\t%s\tShould receive a status code of 201 : got %d : %s", tests.Failed, w.Code, w.Body)
+			}
+			t.Logf("\t%s\tShould receive a status code of 201.", tests.Success)
+
+			var created restaurant.Menu
+			if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+				t.Fatalf("\t%s\tShould decode the menu : %s.", tests.Failed, err)
+			}
+			if got := created.Date.Format(restaurant.MenuDateLayout); got != tomorrow {
+				t.Fatalf("\t%s\tShould be dated %s : got %s.", tests.Failed, tomorrow, got)
+			}
+			t.Logf("\t%s\tShould be dated %s.", tests.Success, tomorrow)
+		}
+
+		t.Log("\tTest 1:\tWhen publishing a menu with a badly formatted date.")
+		{
+			restaurants := memstore.NewRestaurants(restaurant.Restaurant{ID: id, Name: "Pizza Place", OwnerUserID: ownerID})
+			m := Menu{store: memstore.NewMenus(restaurants), restaurants: restaurants}
+
+			body := `{"restaurant_id":"` + id + `","date":"tomorrow","menu":"Lasagne"}`
+			if w := serve(m.CreateMenu, http.MethodPost, body, params, claims); w.Code != http.StatusBadRequest {
+				t.Fatalf("\t%s\tShould receive a status code of 400 : got %d.", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould receive a status code of 400.", tests.Success)
+		}
+
+		t.Log("\tTest 2:\tWhen publishing a menu dated years ago.")
+		{
+			restaurants := memstore.NewRestaurants(restaurant.Restaurant{ID: id, Name: "Pizza Place", OwnerUserID: ownerID})
+			menus := memstore.NewMenus(restaurants)
+			m := Menu{store: menus, restaurants: restaurants}
+
+			past := time.Now().AddDate(-3, 0, 0).Format(restaurant.MenuDateLayout)
+			body := `{"restaurant_id":"` + id + `","date":"` + past + `","menu":"Lasagne"}`
+			if w := serve(m.CreateMenu, http.MethodPost, body, params, claims); w.Code != http.StatusBadRequest {
+				t.Fatalf("\t%s\tShould receive a status code of 400 : got %d.", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould receive a status code of 400.", tests.Success)
+
+			nm := restaurant.NewMenu{RestaurantID: id, Date: past, Menu: "Lasagne"}
+			if _, err := menus.CreateMenu(context.Background(), claims, nm, time.Now()); err != restaurant.ErrDateOutOfRange {
+				t.Fatalf("\t%s\tShould be refused by the store : got %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be refused by the store.", tests.Success)
+		}
+	}
+}
+
+// TestMenuAllergens validates leaving out the menu items with allergens the
+// user cannot eat.
+func TestMenuAllergens(t *testing.T) {
+	const id = "a2b0639f-2cc6-44b8-b97b-15d69dbb511e"
+	restaurants := memstore.NewRestaurants(restaurant.Restaurant{ID: id, Name: "Pizza Place", OwnerUserID: ownerID})
+	menus := memstore.NewMenus(restaurants, restaurant.Menu{
+		ID:           "ad1b9a26-e1a1-4e4c-9a1b-3a2b5f6f0d3b",
+		RestaurantID: id,
+		Date:         now,
+		Items: restaurant.MenuItems{
+			{Name: "Pesto Pasta", Price: 1200, Allergens: []string{"gluten", "nuts", "milk"}},
+			{Name: "Risotto", Price: 1400, Allergens: []string{"milk"}},
+			{Name: "Salad", Price: 900},
+		},
+	})
+	m := Menu{store: menus, restaurants: restaurants}
+	claims := userClaims(otherID, auth.RoleUser)
+	params := map[string]string{"restaurantId": id}
+
+	t.Log("Given the need to filter menus for dietary restrictions.")
+	{
+		t.Log("\tTest 0:\tWhen excluding nuts and gluten.")
+		{
+			r := httptest.NewRequest(http.MethodGet, "/?exclude_allergens=nuts,gluten", nil)
+			w := serveRequest(m.ListMenus, r, params, claims)
+			if w.Code != http.StatusOK {
+				t.Fatalf("\t%s\tShould receive a status code of 200 : got %d.", tests.Failed, w.Code)
+			}
+
+			var listed []restaurant.Menu
+			if err := json.NewDecoder(w.Body).Decode(&listed); err != nil {
+				t.Fatalf("\t%s\tShould decode the menus : %s.", tests.Failed, err)
+			}
+			if len(listed) != 1 || len(listed[0].Items) != 2 || listed[0].Items[0].Name != "Risotto" || listed[0].Items[1].Name != "Salad" {
+				t.Fatalf("\t%s\tShould leave out the items with the allergens : got %+v.", tests.Failed, listed)
+			}
+			t.Logf("\t%s\tShould leave out the items with the allergens.", tests.Success)
+		}
+
+		t.Log("\tTest 1:\tWhen excluding an unknown allergen.")
+		{
+			r := httptest.NewRequest(http.MethodGet, "/?exclude_allergens=chocolate", nil)
+			if w := serveRequest(m.ListMenus, r, params, claims); w.Code != http.StatusBadRequest {
+				t.Fatalf("\t%s\tShould receive a status code of 400 : got %d.", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould receive a status code of 400.", tests.Success)
+		}
+
+		t.Log("\tTest 2:\tWhen publishing an item with an unknown allergen.")
+		{
+			body := `{"restaurant_id":"` + id + `","menu":"Cake","items":[{"name":"Cake","price":500,"allergens":["chocolate"]}]}`
+			if w := serve(m.CreateMenu, http.MethodPost, body, params, userClaims(ownerID, auth.RoleAdmin)); w.Code != http.StatusBadRequest {
+				t.Fatalf("\t%s\tShould receive a status code of 400 : got %d.", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould receive a status code of 400.", tests.Success)
+		}
+	}
+}
+
+// TestMenuPDF validates menus are printed for their restaurant only.
+func TestMenuPDF(t *testing.T) {
+	const id = "a2b0639f-2cc6-44b8-b97b-15d69dbb511e"
+	const otherRestaurantID = "5cf37266-3473-4006-984f-9325122678b7"
+	const menuID = "ad1b9a26-e1a1-4e4c-9a1b-3a2b5f6f0d3b"
+	restaurants := memstore.NewRestaurants(
+		restaurant.Restaurant{ID: id, Name: "Pizza Place", OwnerUserID: ownerID},
+		restaurant.Restaurant{ID: otherRestaurantID, Name: "Sushi Bar", OwnerUserID: ownerID},
+	)
+	menus := memstore.NewMenus(restaurants, restaurant.Menu{
+		ID:           menuID,
+		RestaurantID: id,
+		Date:         now,
+		Items: restaurant.MenuItems{
+			{Name: "Pesto Pasta", Price: 1250, Allergens: []string{"gluten", "nuts"}},
+		},
+	})
+	m := Menu{store: menus, restaurants: restaurants}
+	claims := userClaims(otherID, auth.RoleUser)
+
+	t.Log("Given the need to print menus.")
+	{
+		t.Log("\tTest 0:\tWhen printing a menu of the restaurant.")
+		{
+			w := serve(m.PDF, http.MethodGet, "", map[string]string{"restaurantId": id, "menuId": menuID}, claims)
+			if w.Code != http.StatusOK {
+				t.Fatalf("\t%s\tShould receive a status code of 200 : got %d.", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould receive a status code of 200.", tests.Success)
+
+			if got := w.Header().Get("Content-Type"); got != "application/pdf" {
+				t.Fatalf("\t%s\tShould send a PDF file : got %q.", tests.Failed, got)
+			}
+			body := w.Body.String()
+			if !strings.HasPrefix(body, "%PDF-") || !strings.Contains(body, "(Pesto Pasta) Tj") || !strings.Contains(body, "(12.50) Tj") {
+				t.Fatalf("\t%s\tShould print the items with their prices.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould print the items with their prices.", tests.Success)
+		}
+
+		t.Log("\tTest 1:\tWhen printing the menu of another restaurant.")
+		{
+			w := serve(m.PDF, http.MethodGet, "", map[string]string{"restaurantId": otherRestaurantID, "menuId": menuID}, claims)
+			if w.Code != http.StatusNotFound {
+				t.Fatalf("\t%s\tShould receive a status code of 404 : got %d.", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould receive a status code of 404.", tests.Success)
+		}
+
+		t.Log("\tTest 3:\tWhen an admin patches the menu of another owner.")
+		{
+			params := map[string]string{"restaurantId": id, "menuId": menuID}
+			admin := userClaims(otherID, auth.RoleAdmin)
+			if w := serve(m.Patch, http.MethodPatch, `{"menu":"Calzone"}`, params, admin); w.Code != http.StatusNoContent {
+				t.Fatalf("\t%s\tShould receive a status code of 204 : got %d : %s", tests.Failed, w.Code, w.Body)
+			}
+			t.Logf("\t%s\tShould receive a status code of 204.", tests.Success)
+
+			got, err := menus.RetrieveMenu(context.Background(), menuID)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to retrieve the menu : %s.", tests.Failed, err)
+			}
+			if got.Menu != "Calzone" {
+				t.Fatalf("\t%s\tShould change the menu : got %q.", tests.Failed, got.Menu)
+			}
+			t.Logf("\t%s\tShould change the menu.", tests.Success)
+		}
+
+		t.Log("\tTest 4:\tWhen a user patches the menu of another owner.")
+		{
+			params := map[string]string{"restaurantId": id, "menuId": menuID}
+			user := userClaims(otherID, auth.RoleUser)
+			if w := serve(m.Patch, http.MethodPatch, `{"menu":"Sushi"}`, params, user); w.Code != http.StatusForbidden {
+				t.Fatalf("\t%s\tShould receive a status code of 403 : got %d.", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould receive a status code of 403.", tests.Success)
+		}
+	}
+}
+
+// TestMenuPatch validates merge patches of the menus of a restaurant.
+func TestMenuPatch(t *testing.T) {
+	const id = "a2b0639f-2cc6-44b8-b97b-15d69dbb511e"
+	const menuID = "ad1b9a26-e1a1-4e4c-9a1b-3a2b5f6f0d3b"
+	const otherRestaurantID = "5cf37266-3473-4006-984f-9325122678b7"
+	restaurants := memstore.NewRestaurants(
+		restaurant.Restaurant{ID: id, Name: "Pizza Place", OwnerUserID: ownerID},
+		restaurant.Restaurant{ID: otherRestaurantID, Name: "Sushi Bar", OwnerUserID: ownerID},
+	)
+	menus := memstore.NewMenus(restaurants, restaurant.Menu{
+		ID:           menuID,
+		RestaurantID: id,
+		Date:         now,
+		Menu:         "Pizza",
+		Items:        restaurant.MenuItems{{Name: "Margherita", Price: 1000}},
+		Version:      1,
+	})
+	m := Menu{store: menus, restaurants: restaurants}
+	claims := userClaims(ownerID, auth.RoleAdmin)
+
+	t.Log("Given the need to patch menus.")
+	{
+		t.Log("\tTest 0:\tWhen clearing the items of the menu.")
+		{
+			params := map[string]string{"restaurantId": id, "menuId": menuID}
+			if w := serve(m.Patch, http.MethodPatch, `{"items":null}`, params, claims); w.Code != http.StatusNoContent {
+				t.Fatalf("\t%s\tShould receive a status code of 204 : got %d : %s", tests.Failed, w.Code, w.Body)
+			}
+			t.Logf("\t%s\tShould receive a status code of 204.", tests.Success)
+
+			got, err := menus.RetrieveMenu(context.Background(), menuID)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to retrieve the menu : %s.", tests.Failed, err)
+			}
+			if len(got.Items) != 0 || got.Menu != "Pizza" || got.Version != 2 {
+				t.Fatalf("\t%s\tShould clear the items only : got %+v.", tests.Failed, got)
+			}
+			t.Logf("\t%s\tShould clear the items only.", tests.Success)
+		}
+
+		t.Log("\tTest 1:\tWhen clearing the text of the menu.")
+		{
+			params := map[string]string{"restaurantId": id, "menuId": menuID}
+			if w := serve(m.Patch, http.MethodPatch, `{"menu":null}`, params, claims); w.Code != http.StatusBadRequest {
+				t.Fatalf("\t%s\tShould receive a status code of 400 : got %d.", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould receive a status code of 400.", tests.Success)
+		}
+
+		t.Log("\tTest 2:\tWhen patching the menu through another restaurant.")
+		{
+			params := map[string]string{"restaurantId": otherRestaurantID, "menuId": menuID}
+			if w := serve(m.Patch, http.MethodPatch, `{"menu":"Sushi"}`, params, claims); w.Code != http.StatusNotFound {
+				t.Fatalf("\t%s\tShould receive a status code of 404 : got %d.", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould receive a status code of 404.", tests.Success)
+		}
+
+		t.Log("\tTest 3:\tWhen an admin patches the menu of another owner.")
+		{
+			params := map[string]string{"restaurantId": id, "menuId": menuID}
+			admin := userClaims(otherID, auth.RoleAdmin)
+			if w := serve(m.Patch, http.MethodPatch, `{"menu":"Calzone"}`, params, admin); w.Code != http.StatusNoContent {
+				t.Fatalf("\t%s\tShould receive a status code of 204 : got %d : %s", tests.Failed, w.Code, w.Body)
+			}
+			t.Logf("\t%s\tShould receive a status code of 204.", tests.Success)
+
+			got, err := menus.RetrieveMenu(context.Background(), menuID)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to retrieve the menu : %s.", tests.Failed, err)
+			}
+			if got.Menu != "Calzone" {
+				t.Fatalf("\t%s\tShould change the menu : got %q.", tests.Failed, got.Menu)
+			}
+			t.Logf("\t%s\tShould change the menu.", tests.Success)
+		}
+
+		t.Log("\tTest 4:\tWhen a user patches the menu of another owner.")
+		{
+			params := map[string]string{"restaurantId": id, "menuId": menuID}
+			user := userClaims(otherID, auth.RoleUser)
+			if w := serve(m.Patch, http.MethodPatch, `{"menu":"Sushi"}`, params, user); w.Code != http.StatusForbidden {
+				t.Fatalf("\t%s\tShould receive a status code of 403 : got %d.", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould receive a status code of 403.", tests.Success)
+		}
+
+		t.Log("\tTest 5:\tWhen moving the menu years away from today.")
+		{
+			params := map[string]string{"restaurantId": id, "menuId": menuID}
+			for _, date := range []string{"2017-03-02T00:00:00Z", "2023-03-02T00:00:00Z"} {
+				if w := serve(m.Patch, http.MethodPatch, `{"date":"`+date+`"}`, params, claims); w.Code != http.StatusBadRequest {
+					t.Fatalf("\t%s\tShould receive a status code of 400 for %s : got %d.", tests.Failed, date, w.Code)
+				}
+			}
+			t.Logf("\t%s\tShould receive a status code of 400.", tests.Success)
+		}
+	}
+}
+
+// TestMenuLinks validates menus come with the links to their related
+// resources.
+func TestMenuLinks(t *testing.T) {
+	const id = "a2b0639f-2cc6-44b8-b97b-15d69dbb511e"
+	const menuID = "ad1b9a26-e1a1-4e4c-9a1b-3a2b5f6f0d3b"
+	restaurants := memstore.NewRestaurants(restaurant.Restaurant{ID: id, Name: "Pizza Place", OwnerUserID: ownerID})
+	menus := memstore.NewMenus(restaurants, restaurant.Menu{ID: menuID, RestaurantID: id, Date: now, Menu: "Pizza"})
+	m := Menu{store: menus, restaurants: restaurants}
+
+	t.Log("Given the need to navigate from a menu.")
+	{
+		t.Log("\tTest 0:\tWhen listing the menus of the restaurant.")
+		{
+			w := serve(m.ListMenus, http.MethodGet, "", map[string]string{"restaurantId": id}, userClaims(otherID, auth.RoleUser))
+			var got []linkedMenu
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatalf("\t%s\tShould be able to decode the response : %v", tests.Failed, err)
+			}
+			if len(got) != 1 {
+				t.Fatalf("\t%s\tShould receive the menu : got %+v.", tests.Failed, got)
+			}
+
+			want := map[string]string{
+				"self":       "/v1/restaurant/" + id + "/menu/" + menuID,
+				"restaurant": "/v1/restaurant/" + id,
+				"votes":      "/v1/restaurant/" + id + "/votes?date=" + now.Format("2006-01-02"),
+			}
+			for rel, link := range want {
+				if got[0].Links[rel] != link {
+					t.Fatalf("\t%s\tShould link %s to %s : got %s.", tests.Failed, rel, link, got[0].Links[rel])
+				}
+			}
+			t.Logf("\t%s\tShould receive the links.", tests.Success)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/notification"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/web"
+	"go.opentelemetry.io/otel"
+)
+
+// Notification represents the in-app notification API method handler set.
+type Notification struct {
+	db *sqlx.DB
+}
+
+// List returns the latest notifications of the calling user. With the unread
+// query parameter set to true only the unread ones are returned.
+func (n *Notification) List(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Notification.List")
+	defer span.End()
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	unread := r.URL.Query().Get("unread") == "true"
+
+	notifications, err := notification.List(ctx, n.db, claims.Subject, unread)
+	if err != nil {
+		return err
+	}
+
+	return web.RespondList(ctx, w, notifications, http.StatusOK)
+}
+
+// MarkRead marks a notification of the calling user as read.
+func (n *Notification) MarkRead(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Notification.MarkRead")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	if err := notification.MarkRead(ctx, n.db, claims.Subject, params["id"], v.Now); err != nil {
+		switch err {
+		case notification.ErrNotFound:
+			return requestError(err, http.StatusNotFound)
+		default:
+			return errors.Wrapf(err, "ID: %s", params["id"])
+		}
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// MarkAllRead marks every notification of the calling user as read.
+func (n *Notification) MarkAllRead(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Notification.MarkAllRead")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	if err := notification.MarkAllRead(ctx, n.db, claims.Subject, v.Now); err != nil {
+		return err
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// Preferences returns the notification preferences of the calling user.
+func (n *Notification) Preferences(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Notification.Preferences")
+	defer span.End()
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	p, err := notification.RetrievePreferences(ctx, n.db, claims.Subject)
+	if err != nil {
+		return err
+	}
+
+	return web.Respond(ctx, w, p, http.StatusOK)
+}
+
+// UpdatePreferences changes the notification preferences of the calling
+// user.
+func (n *Notification) UpdatePreferences(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Notification.UpdatePreferences")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	var up notification.UpdatePreferences
+	if err := web.Decode(r, &up); err != nil {
+		return errors.Wrap(err, "decoding preferences update")
+	}
+
+	p, err := notification.ChangePreferences(ctx, n.db, claims.Subject, up, v.Now)
+	if err != nil {
+		return errors.Wrapf(err, "updating preferences of user %s: %+v", claims.Subject, up)
+	}
+
+	return web.Respond(ctx, w, p, http.StatusOK)
+}
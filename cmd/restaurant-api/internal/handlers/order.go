@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/authz"
+	"github.com/remisb/restaurant/internal/coupon"
+	"github.com/remisb/restaurant/internal/order"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/web"
+	"github.com/remisb/restaurant/internal/restaurant"
+	"github.com/remisb/restaurant/internal/vote"
+	"go.opentelemetry.io/otel"
+)
+
+// Order represents the pre-order API method handler set.
+type Order struct {
+	db          *sqlx.DB
+	restaurants restaurant.Store
+	policy      order.Policy
+}
+
+// Place orders dishes from the menu of the restaurant which won the vote for
+// the date of the menu.
+func (o *Order) Place(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Order.Place")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	var no order.NewOrder
+	if err := web.Decode(r, &no); err != nil {
+		return errors.Wrap(err, "decoding new order")
+	}
+
+	placed, err := order.Place(ctx, o.db, claims, params["restaurantId"], params["menuId"], no, o.policy, v.Now)
+	if err != nil {
+		switch err {
+		case coupon.ErrInvalidCode:
+			return requestError(err, http.StatusBadRequest)
+		case order.ErrMenuNotFound:
+			return requestError(err, http.StatusNotFound)
+		case order.ErrNotWinner, order.ErrCutoff, coupon.ErrExhausted:
+			return requestError(err, http.StatusConflict)
+		default:
+			return errors.Wrapf(err, "placing order: %+v", no)
+		}
+	}
+
+	return web.Respond(ctx, w, placed, http.StatusCreated)
+}
+
+// List returns the orders of a restaurant for the date query parameter or
+// today. Only the owner of the restaurant and admins may list them.
+func (o *Order) List(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Order.List")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	restaurantID := params["restaurantId"]
+	if err := o.checkOwner(ctx, restaurantID); err != nil {
+		return err
+	}
+
+	date, err := vote.ParseDate(r.URL.Query().Get("date"), v.Now)
+	if err != nil {
+		return requestError(err, http.StatusBadRequest)
+	}
+
+	orders, err := order.List(ctx, o.db, restaurantID, date)
+	if err != nil {
+		return errors.Wrapf(err, "ID: %s", restaurantID)
+	}
+
+	return web.RespondList(ctx, w, orders, http.StatusOK)
+}
+
+// Cancel cancels an order of the calling user before the cutoff.
+func (o *Order) Cancel(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Order.Cancel")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	if err := order.Cancel(ctx, o.db, claims, params["id"], o.policy, v.Now); err != nil {
+		switch err {
+		case order.ErrNotFound:
+			return requestError(err, http.StatusNotFound)
+		case order.ErrForbidden:
+			return requestError(err, http.StatusForbidden)
+		case order.ErrTransition, order.ErrCutoff:
+			return requestError(err, http.StatusConflict)
+		default:
+			return errors.Wrapf(err, "ID: %s", params["id"])
+		}
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// UpdateStatus moves an order to another state. Only the owner of the
+// restaurant and admins may follow its orders up.
+func (o *Order) UpdateStatus(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Order.UpdateStatus")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var us order.UpdateStatus
+	if err := web.Decode(r, &us); err != nil {
+		return errors.Wrap(err, "decoding order status")
+	}
+
+	current, err := order.Retrieve(ctx, o.db, params["id"])
+	if err != nil {
+		switch err {
+		case order.ErrNotFound:
+			return requestError(err, http.StatusNotFound)
+		default:
+			return errors.Wrapf(err, "ID: %s", params["id"])
+		}
+	}
+
+	if err := o.checkOwner(ctx, current.RestaurantID); err != nil {
+		return err
+	}
+
+	if err := order.ChangeStatus(ctx, o.db, current.ID, us, v.Now); err != nil {
+		switch err {
+		case order.ErrTransition:
+			return requestError(err, http.StatusConflict)
+		default:
+			return errors.Wrapf(err, "ID: %s", current.ID)
+		}
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// checkOwner returns a request error unless the calling user owns the
+// restaurant or is an admin.
+func (o *Order) checkOwner(ctx context.Context, restaurantID string) error {
+	return checkRestaurantOwner(ctx, o.restaurants, restaurantID)
+}
+
+// checkRestaurantOwner returns a request error unless the calling user owns
+// the restaurant or is an admin.
+func checkRestaurantOwner(ctx context.Context, restaurants restaurant.Store, restaurantID string) error {
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	rest, err := restaurants.Retrieve(ctx, restaurantID)
+	if err != nil {
+		switch err {
+		case restaurant.ErrNotFound:
+			return requestError(err, http.StatusNotFound)
+		default:
+			return errors.Wrapf(err, "ID: %s", restaurantID)
+		}
+	}
+
+	if !authz.Allowed(claims, authz.Manage, authz.Resource{Kind: authz.Restaurant, Owner: rest.OwnerUserID}) {
+		return requestError(restaurant.ErrForbidden, http.StatusForbidden)
+	}
+
+	return nil
+}
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/authz"
+	"github.com/remisb/restaurant/internal/featureflag"
+	"github.com/remisb/restaurant/internal/organization"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/web"
+	"go.opentelemetry.io/otel"
+)
+
+// Organization represents the organization API method handler set. The
+// organizations are managed by the admins of the default organization, which
+// runs the deployment. The admins of the other ones manage their members
+// through the user endpoints.
+type Organization struct {
+	db *sqlx.DB
+}
+
+// Retrieve returns the organization of the calling user.
+func (o *Organization) Retrieve(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Organization.Retrieve")
+	defer span.End()
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	org, err := organization.Retrieve(ctx, o.db, claims.Org())
+	if err != nil {
+		switch err {
+		case organization.ErrNotFound:
+			return requestError(err, http.StatusNotFound)
+		default:
+			return errors.Wrapf(err, "ID: %s", claims.Org())
+		}
+	}
+
+	return web.Respond(ctx, w, org, http.StatusOK)
+}
+
+// Settings returns the settings of the organization of the calling user.
+func (o *Organization) Settings(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Organization.Settings")
+	defer span.End()
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	s, err := organization.RetrieveSettings(ctx, o.db, claims.Org())
+	if err != nil {
+		return errors.Wrapf(err, "ID: %s", claims.Org())
+	}
+
+	return web.Respond(ctx, w, s, http.StatusOK)
+}
+
+// UpdateSettings changes the settings of the organization of the calling
+// admin. The voting rules read them when applied so the change takes effect
+// right away.
+func (o *Organization) UpdateSettings(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Organization.UpdateSettings")
+	defer span.End()
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var us organization.UpdateSettings
+	if err := web.Decode(r, &us); err != nil {
+		return errors.Wrap(err, "decoding settings update")
+	}
+
+	s, err := organization.ChangeSettings(ctx, o.db, claims.Org(), us, v.Now)
+	if err != nil {
+		switch err {
+		case organization.ErrNotFound:
+			return requestError(err, http.StatusNotFound)
+		case organization.ErrInvalidSettings:
+			return requestError(err, http.StatusBadRequest)
+		case featureflag.ErrDisabled:
+			return requestError(err, http.StatusForbidden)
+		default:
+			return errors.Wrapf(err, "updating settings of organization %s: %+v", claims.Org(), us)
+		}
+	}
+
+	return web.Respond(ctx, w, s, http.StatusOK)
+}
+
+// List returns every organization.
+func (o *Organization) List(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Organization.List")
+	defer span.End()
+
+	if err := checkOperator(ctx); err != nil {
+		return err
+	}
+
+	orgs, err := organization.List(ctx, o.db)
+	if err != nil {
+		return err
+	}
+
+	return web.RespondList(ctx, w, orgs, http.StatusOK)
+}
+
+// Create adds an organization.
+func (o *Organization) Create(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Organization.Create")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	if err := checkOperator(ctx); err != nil {
+		return err
+	}
+
+	var no organization.NewOrganization
+	if err := web.Decode(r, &no); err != nil {
+		return errors.Wrap(err, "decoding new organization")
+	}
+
+	org, err := organization.Create(ctx, o.db, no, v.Now)
+	if err != nil {
+		return errors.Wrapf(err, "creating organization: %+v", no)
+	}
+
+	return web.Respond(ctx, w, org, http.StatusCreated)
+}
+
+// AddMember moves a user to the organization.
+func (o *Organization) AddMember(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Organization.AddMember")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	if err := checkOperator(ctx); err != nil {
+		return err
+	}
+
+	if err := organization.Move(ctx, o.db, params["userId"], params["id"], v.Now); err != nil {
+		switch err {
+		case organization.ErrNotFound, organization.ErrUserNotFound:
+			return requestError(err, http.StatusNotFound)
+		default:
+			return errors.Wrapf(err, "moving user %s to organization %s", params["userId"], params["id"])
+		}
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// checkOperator rejects the callers who are not admins of the default
+// organization.
+func checkOperator(ctx context.Context) error {
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	if !authz.Allowed(claims, authz.Manage, authz.Resource{Kind: authz.Service}) {
+		return requestError(organization.ErrForbidden, http.StatusForbidden)
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/organization"
+	"github.com/remisb/restaurant/internal/platform/web"
+	"github.com/remisb/restaurant/internal/restaurant"
+	"github.com/remisb/restaurant/internal/stats"
+	"go.opentelemetry.io/otel"
+)
+
+// Public represents the unauthenticated API method handler set. Only
+// restaurants their owners marked as public are served.
+type Public struct {
+	db    *sqlx.DB
+	views *stats.Store
+}
+
+// jsonldMenuItem is a schema.org MenuItem.
+type jsonldMenuItem struct {
+	Type string `json:"@type"`
+	Name string `json:"name"`
+}
+
+// jsonldMenuSection is a schema.org MenuSection.
+type jsonldMenuSection struct {
+	Type        string           `json:"@type"`
+	Name        string           `json:"name"`
+	HasMenuItem []jsonldMenuItem `json:"hasMenuItem"`
+}
+
+// jsonldMenu is a schema.org Menu.
+type jsonldMenu struct {
+	Type           string              `json:"@type"`
+	Name           string              `json:"name"`
+	HasMenuSection []jsonldMenuSection `json:"hasMenuSection"`
+}
+
+// jsonldRestaurant is a schema.org Restaurant.
+type jsonldRestaurant struct {
+	Context   string     `json:"@context"`
+	Type      string     `json:"@type"`
+	ID        string     `json:"@id"`
+	Name      string     `json:"name"`
+	Address   string     `json:"address,omitempty"`
+	Telephone string     `json:"telephone,omitempty"`
+	URL       string     `json:"url,omitempty"`
+	Image     []string   `json:"image,omitempty"`
+	HasMenu   jsonldMenu `json:"hasMenu"`
+}
+
+// publicMenu is the menu of the day shown to anonymous diners, leaving the
+// internal fields of the restaurant and the menu out.
+type publicMenu struct {
+	Restaurant   string   `json:"restaurant"`
+	Address      string   `json:"address,omitempty"`
+	Website      string   `json:"website,omitempty"`
+	Phone        string   `json:"phone,omitempty"`
+	Date         string   `json:"date"`
+	Items        []string `json:"items"`
+	ImageURL     string   `json:"image_url,omitempty"`
+	ThumbnailURL string   `json:"thumbnail_url,omitempty"`
+}
+
+// TodayMenu returns today's menu of a public restaurant for the diners who
+// scanned its QR code without an account.
+func (p *Public) TodayMenu(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Public.TodayMenu")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	res, err := p.retrieve(ctx, params["id"])
+	if err != nil {
+		return err
+	}
+
+	// The menu of today is the one of the day in the time zone of the
+	// organization of the restaurant.
+	today, err := organization.Today(ctx, p.db, res.OrgID, v.Now)
+	if err != nil {
+		return errors.Wrapf(err, "ID: %s", res.ID)
+	}
+	menus, err := restaurant.MenuList(ctx, p.db, res.ID, today)
+	if err != nil {
+		return errors.Wrapf(err, "ID: %s", res.ID)
+	}
+	if len(menus) == 0 || !menus[0].Date.Equal(today) {
+		return requestError(restaurant.ErrNoMenu, http.StatusNotFound)
+	}
+	m := menus[0]
+
+	// A view which is not counted is not worth failing the request for.
+	_ = p.views.CountView(ctx, m.ID)
+
+	pm := publicMenu{
+		Restaurant:   res.Name,
+		Address:      res.Address,
+		Website:      res.Website,
+		Phone:        res.Phone,
+		Date:         m.Date.Format("2006-01-02"),
+		Items:        dishes(m),
+		ImageURL:     m.ImageURL,
+		ThumbnailURL: m.ThumbnailURL,
+	}
+
+	// The menu of the day rarely changes so caches may serve it for a bit,
+	// sparing the database the bursts of diners arriving together.
+	w.Header().Set("Cache-Control", "public, max-age=60")
+
+	return web.Respond(ctx, w, pm, http.StatusOK)
+}
+
+// JSONLD returns schema.org structured data describing a public restaurant
+// and its upcoming menus so search engines can index them.
+func (p *Public) JSONLD(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Public.JSONLD")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	res, err := p.retrieve(ctx, params["id"])
+	if err != nil {
+		return err
+	}
+
+	today, err := organization.Today(ctx, p.db, res.OrgID, v.Now)
+	if err != nil {
+		return errors.Wrapf(err, "ID: %s", res.ID)
+	}
+	menus, err := restaurant.MenuList(ctx, p.db, res.ID, today)
+	if err != nil {
+		return errors.Wrapf(err, "ID: %s", res.ID)
+	}
+
+	doc := jsonldRestaurant{
+		Context:   "https://schema.org",
+		Type:      "Restaurant",
+		ID:        res.ID,
+		Name:      res.Name,
+		Address:   res.Address,
+		Telephone: res.Phone,
+		URL:       res.Website,
+		Image:     res.Photos,
+		HasMenu: jsonldMenu{
+			Type:           "Menu",
+			Name:           res.Name + " menu",
+			HasMenuSection: []jsonldMenuSection{},
+		},
+	}
+
+	// Each daily menu becomes a section with its items.
+	for _, m := range menus {
+		section := jsonldMenuSection{
+			Type:        "MenuSection",
+			Name:        m.Date.Format("2006-01-02"),
+			HasMenuItem: []jsonldMenuItem{},
+		}
+		for _, item := range dishes(m) {
+			section.HasMenuItem = append(section.HasMenuItem, jsonldMenuItem{Type: "MenuItem", Name: item})
+		}
+		doc.HasMenu.HasMenuSection = append(doc.HasMenu.HasMenuSection, section)
+	}
+
+	return web.Respond(ctx, w, doc, http.StatusOK)
+}
+
+// retrieve finds the public restaurant identified by a given ID. Private
+// restaurants are reported as missing so their existence does not leak to
+// anonymous clients.
+func (p *Public) retrieve(ctx context.Context, id string) (*restaurant.Restaurant, error) {
+	res, err := restaurant.Retrieve(ctx, p.db, id)
+	if err != nil {
+		switch err {
+		case restaurant.ErrNotFound:
+			return nil, requestError(err, http.StatusNotFound)
+		default:
+			return nil, errors.Wrapf(err, "ID: %s", id)
+		}
+	}
+
+	if !res.Public {
+		return nil, requestError(restaurant.ErrNotFound, http.StatusNotFound)
+	}
+
+	return res, nil
+}
+
+// dishes returns the names of the items of the menu. Menus posted as text
+// only have one item per line of the text.
+func dishes(m restaurant.Menu) []string {
+	if len(m.Items) == 0 {
+		return menuItems(m.Menu)
+	}
+	names := make([]string, len(m.Items))
+	for i, item := range m.Items {
+		names[i] = item.Name
+	}
+	return names
+}
+
+// menuItems splits the free text of a menu into its items, one per line.
+func menuItems(menu string) []string {
+	items := []string{}
+	for _, line := range strings.Split(menu, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			items = append(items, line)
+		}
+	}
+	return items
+}
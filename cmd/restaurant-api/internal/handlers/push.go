@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/notify/push"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/web"
+	"go.opentelemetry.io/otel"
+)
+
+// PushDevice represents the push notification device API method handler set.
+type PushDevice struct {
+	db *sqlx.DB
+}
+
+// Register lets the caller receive the broadcasts on a mobile device.
+func (pd *PushDevice) Register(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.PushDevice.Register")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	var nd push.NewDevice
+	if err := web.Decode(r, &nd); err != nil {
+		return errors.Wrap(err, "decoding new device")
+	}
+
+	d, err := push.Register(ctx, pd.db, claims.Subject, nd, v.Now)
+	if err != nil {
+		return errors.Wrapf(err, "registering device of user %s", claims.Subject)
+	}
+
+	return web.Respond(ctx, w, d, http.StatusCreated)
+}
+
+// Unregister stops the pushes to a device of the caller.
+func (pd *PushDevice) Unregister(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.PushDevice.Unregister")
+	defer span.End()
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	if err := push.Unregister(ctx, pd.db, claims.Subject, params["token"]); err != nil {
+		switch err {
+		case push.ErrNotFound:
+			return requestError(err, http.StatusNotFound)
+		default:
+			return errors.Wrapf(err, "unregistering device of user %s", claims.Subject)
+		}
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
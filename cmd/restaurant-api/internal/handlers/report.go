@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/web"
+	"github.com/remisb/restaurant/internal/report"
+	"go.opentelemetry.io/otel"
+)
+
+// Report represents the monthly report API method handler set.
+type Report struct {
+	db *sqlx.DB
+}
+
+// Monthly returns the report of the organization of the caller for the
+// month query parameter formatted as YYYY-MM, the last month by default.
+func (rp *Report) Monthly(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Report.Monthly")
+	defer span.End()
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	month, err := report.ParseMonth(r.URL.Query().Get("month"), v.Now)
+	if err != nil {
+		return requestError(err, http.StatusBadRequest)
+	}
+
+	rep, err := report.Generate(ctx, rp.db, claims.Org(), month)
+	if err != nil {
+		return errors.Wrapf(err, "month: %s", month.Format("2006-01"))
+	}
+
+	if web.WantsCSV(r) {
+		return exportMonthlyReport(ctx, w, rep)
+	}
+
+	return web.Respond(ctx, w, rep, http.StatusOK)
+}
@@ -2,53 +2,205 @@ package handlers
 
 import (
 	"context"
-	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/authz"
+	"github.com/remisb/restaurant/internal/enrichment"
+	"github.com/remisb/restaurant/internal/geocoding"
 	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/database"
+	"github.com/remisb/restaurant/internal/platform/geo"
 	"github.com/remisb/restaurant/internal/platform/web"
 	"github.com/remisb/restaurant/internal/restaurant"
-	"go.opencensus.io/trace"
+	"github.com/remisb/restaurant/internal/webhook"
+	"go.opentelemetry.io/otel"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 )
 
+// Restaurant represents the Restaurant API method handler set.
 type Restaurant struct {
-	db *sqlx.DB
+	store    restaurant.Store
+	enricher *enrichment.Worker
+	geocoder *geocoding.Worker
+	webhooks *webhook.Notifier
+
+	// strictDelete answers 404 when deleting a restaurant which does not
+	// exist rather than the 204 of an idempotent delete.
+	strictDelete bool
+}
+
+// listedRestaurant is a restaurant of the list flagged when it is a favorite
+// of the calling user. Restaurants searched near a position come with their
+// distance in meters from it, and with the related data asked for.
+type listedRestaurant struct {
+	restaurant.Restaurant
+	restaurant.Included
+	IsFavorite bool      `json:"is_favorite"`
+	Distance   *float64  `json:"distance,omitempty"`
+	Links      web.Links `json:"links"`
 }
 
-// List gets all existing restaurants in the system.
+// linkedRestaurant is a restaurant with the links to its related resources.
+type linkedRestaurant struct {
+	restaurant.Restaurant
+	Links web.Links `json:"links"`
+}
+
+// restaurantLinks returns the links of a restaurant to itself, its menu of
+// today, the votes cast for it and its owner.
+func restaurantLinks(rest restaurant.Restaurant) web.Links {
+	return web.Links{
+		"self":  web.Link("/v1/restaurant/:id", rest.ID),
+		"menu":  web.Link("/v1/restaurant/:id/menu", rest.ID),
+		"votes": web.Link("/v1/restaurant/:id/votes", rest.ID),
+		"owner": web.Link("/v1/users/:id", rest.OwnerUserID),
+	}
+}
+
+// List gets all existing restaurants in the system. With the near query
+// parameter, written as lat,lng, it gets the restaurants within the radius
+// meters of the position instead, the closest first. Every tag query
+// parameter keeps the restaurants having that tag. The include query
+// parameter lists the related data to embed among menu, votes and rating,
+// and the fields query parameter the members to send.
 func (res *Restaurant) List(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
-	ctx, span := trace.StartSpan(ctx, "handlers.Restaurant.List")
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Restaurant.List")
 	defer span.End()
 
-	restaurants, err := restaurant.List(ctx, res.db)
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	include, err := restaurant.ParseInclude(r.URL.Query().Get("include"))
+	if err != nil {
+		return requestError(err, http.StatusBadRequest)
+	}
+
+	var restaurants []restaurant.Restaurant
+	var distances map[string]float64
+	if r.URL.Query().Get("near") != "" {
+		near, radius, err := parseNear(r.URL.Query())
+		if err != nil {
+			return requestError(err, http.StatusBadRequest)
+		}
+
+		nearby, err := res.store.ListNearby(ctx, near, radius)
+		if err != nil {
+			return err
+		}
+
+		distances = make(map[string]float64, len(nearby))
+		for _, n := range nearby {
+			restaurants = append(restaurants, n.Restaurant)
+			distances[n.ID] = n.Distance
+		}
+	} else {
+		var err error
+		if restaurants, err = res.store.List(ctx); err != nil {
+			return err
+		}
+	}
+
+	if tags := r.URL.Query()["tag"]; len(tags) > 0 {
+		tagged := []restaurant.Restaurant{}
+		for _, rest := range restaurants {
+			if rest.HasTags(tags) {
+				tagged = append(tagged, rest)
+			}
+		}
+		restaurants = tagged
+	}
+
+	if web.WantsCSV(r) {
+		return exportRestaurants(ctx, w, restaurants)
+	}
+
+	favorites, err := res.store.ListFavorites(ctx, claims.Subject)
 	if err != nil {
 		return err
 	}
+	favorite := make(map[string]bool, len(favorites))
+	for _, f := range favorites {
+		favorite[f.ID] = true
+	}
+
+	ids := make([]string, len(restaurants))
+	for i, rest := range restaurants {
+		ids[i] = rest.ID
+	}
+	included, err := res.store.ListIncluded(ctx, ids, include, v.Now)
+	if err != nil {
+		return err
+	}
+
+	listed := make([]listedRestaurant, len(restaurants))
+	for i, rest := range restaurants {
+		listed[i] = listedRestaurant{Restaurant: rest, Included: included[rest.ID], IsFavorite: favorite[rest.ID], Links: restaurantLinks(rest)}
+		if d, ok := distances[rest.ID]; ok {
+			listed[i].Distance = &d
+		}
+	}
+
+	return web.RespondListFields(ctx, w, listed, web.Fields(r), http.StatusOK)
+}
+
+// parseNear parses the position and the radius of a search for nearby
+// restaurants. The radius defaults to restaurant.DefaultRadius.
+func parseNear(q url.Values) (geo.Point, float64, error) {
+	near, err := geo.ParsePoint(q.Get("near"))
+	if err != nil {
+		return geo.Point{}, 0, err
+	}
 
-	return web.Respond(ctx, w, restaurants, http.StatusOK)
+	radius := float64(restaurant.DefaultRadius)
+	if s := q.Get("radius"); s != "" {
+		radius, err = strconv.ParseFloat(s, 64)
+		if err != nil || radius <= 0 || radius > restaurant.MaxRadius {
+			return geo.Point{}, 0, restaurant.ErrInvalidRadius
+		}
+	}
+
+	return near, radius, nil
 }
 
 func (res *Restaurant) Retrieve(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
-	ctx, span := trace.StartSpan(ctx, "handlers.Restaurant.Retrieve")
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Restaurant.Retrieve")
 	defer span.End()
 
-	restRetrieved, err := restaurant.Retrieve(ctx, res.db, params["id"])
+	restRetrieved, err := res.store.Retrieve(ctx, params["id"])
 	if err != nil {
 		switch err {
-		case restaurant.ErrInvalidID:
-			return web.NewRequestError(err, http.StatusBadRequest)
 		case restaurant.ErrNotFound:
-			return web.NewRequestError(err, http.StatusNotFound)
+			return requestError(err, http.StatusNotFound)
 		default:
 			return errors.Wrapf(err, "ID: %s", params["id"])
 		}
 	}
 
-	return web.Respond(ctx, w, restRetrieved, http.StatusOK)
+	web.LastModified(w, restRetrieved.DateUpdated)
+	return web.Respond(ctx, w, linkedRestaurant{*restRetrieved, restaurantLinks(*restRetrieved)}, http.StatusOK)
+}
+
+// duplicateResponse is the conflict reported when creating a restaurant
+// similar to existing ones, listing them.
+type duplicateResponse struct {
+	web.ErrorResponse
+	Matches []restaurant.Match `json:"matches"`
 }
 
+// Create adds a restaurant. Unless the force query parameter is set to true
+// it is refused with the candidate matches when similar restaurants exist,
+// so the same restaurant is not added twice by different users.
 func (res *Restaurant) Create(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
-	ctx, span := trace.StartSpan(ctx, "handlers.Restaurant.Create")
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Restaurant.Create")
 	defer span.End()
 
 	claims, ok := ctx.Value(auth.Key).(auth.Claims)
@@ -66,18 +218,68 @@ func (res *Restaurant) Create(ctx context.Context, w http.ResponseWriter, r *htt
 		return errors.Wrap(err, "decoding new restaurant")
 	}
 
-	restResult, err := restaurant.Create(ctx, res.db, claims, nr, v.Now)
+	if r.URL.Query().Get("force") != "true" {
+		matches, err := res.store.FindSimilar(ctx, nr.Name, nr.Address)
+		if err != nil {
+			return errors.Wrapf(err, "finding restaurants similar to: %+v", nr)
+		}
+		if len(matches) > 0 {
+			dr := duplicateResponse{
+				ErrorResponse: web.ErrorResponse{
+					Code:  errorCodes[restaurant.ErrDuplicate],
+					Error: restaurant.ErrDuplicate.Error(),
+				},
+				Matches: matches,
+			}
+			return web.Respond(ctx, w, dr, http.StatusConflict)
+		}
+	}
+
+	restResult, err := res.store.Create(ctx, claims, nr, v.Now)
 	if err != nil {
-		return errors.Wrapf(err, "creating new restaurant: %+v", nr)
+		switch err {
+		case geo.ErrInvalidPoint:
+			return requestError(err, http.StatusBadRequest)
+		case restaurant.ErrDuplicateName:
+			return requestError(err, http.StatusConflict)
+		default:
+			return errors.Wrapf(err, "creating new restaurant: %+v", nr)
+		}
+	}
+
+	if err := res.created(ctx, restResult, v.Now); err != nil {
+		return err
+	}
+
+	return web.Respond(ctx, w, linkedRestaurant{*restResult, restaurantLinks(*restResult)}, http.StatusCreated)
+}
+
+// created starts the work following the creation of a restaurant.
+func (res *Restaurant) created(ctx context.Context, r *restaurant.Restaurant, now time.Time) error {
+
+	// Look up public data about the new restaurant in the background. The
+	// owner decides later which of the suggested values to keep. Within a
+	// batch the restaurant is only found once the batch is committed.
+	if res.enricher != nil {
+		database.AfterCommit(ctx, func() { res.enricher.Enqueue(r.ID) })
+	}
+
+	// Restaurants created without a location are located from their address.
+	if res.geocoder != nil && r.Latitude == nil {
+		database.AfterCommit(ctx, func() { res.geocoder.Enqueue(r.ID) })
+	}
+
+	if err := res.webhooks.Notify(ctx, webhook.EventRestaurantCreated, r, now); err != nil {
+		return errors.Wrapf(err, "notifying webhooks of restaurant %s", r.ID)
 	}
 
-	return web.Respond(ctx, w, restResult, http.StatusCreated)
+	return nil
 }
 
 // Update decodes the body of a request to update an existing restaurant. The ID
 // of the restaurant is part of the request URL.
 func (res *Restaurant) Update(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
-	ctx, span := trace.StartSpan(ctx, "handlers.Restaurant.Update")
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Restaurant.Update")
 	defer span.End()
 
 	claims, ok := ctx.Value(auth.Key).(auth.Claims)
@@ -95,31 +297,120 @@ func (res *Restaurant) Update(ctx context.Context, w http.ResponseWriter, r *htt
 		return errors.Wrap(err, "")
 	}
 
-	if err := restaurant.Update(ctx, res.db, claims, params["id"], up, v.Now); err != nil {
+	// A new address without a new location moves the restaurant to wherever
+	// the address is found.
+	geocode := up.Address != nil && up.Latitude == nil
+
+	return res.update(ctx, w, r, claims, params["id"], up, geocode, v.Now)
+}
+
+// Patch applies a JSON merge patch to an existing restaurant. Unlike with
+// Update the fields the patch sets to null are cleared. The ID of the
+// restaurant is part of the request URL.
+func (res *Restaurant) Patch(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Restaurant.Patch")
+	defer span.End()
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	current, err := res.store.Retrieve(ctx, params["id"])
+	if err != nil {
 		switch err {
-		case restaurant.ErrInvalidID:
-			return web.NewRequestError(err, http.StatusBadRequest)
 		case restaurant.ErrNotFound:
-			return web.NewRequestError(err, http.StatusNotFound)
+			return requestError(err, http.StatusNotFound)
+		default:
+			return errors.Wrapf(err, "Id: %s", params["id"])
+		}
+	}
+
+	patch := restaurant.NewPatchRestaurant(*current)
+	if err := web.DecodePatch(r, &patch); err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	// The restaurant is moved to wherever its new address is found unless the
+	// patch moves it too.
+	geocode := patch.Address != current.Address &&
+		sameCoordinate(patch.Latitude, current.Latitude) && sameCoordinate(patch.Longitude, current.Longitude)
+
+	return res.update(ctx, w, r, claims, params["id"], patch.Update(), geocode, v.Now)
+}
+
+// update applies the changes to the restaurant for Update and Patch. When
+// geocode is set the restaurant is geocoded again once updated.
+func (res *Restaurant) update(ctx context.Context, w http.ResponseWriter, r *http.Request, claims auth.Claims, id string, up restaurant.UpdateRestaurant, geocode bool, now time.Time) error {
+	updated, err := res.store.Update(ctx, claims, id, up, now)
+	if err != nil {
+		switch err {
+		case restaurant.ErrNotFound:
+			return requestError(err, http.StatusNotFound)
 		case restaurant.ErrForbidden:
-			return web.NewRequestError(err, http.StatusForbidden)
+			return requestError(err, http.StatusForbidden)
+		case restaurant.ErrVersionConflict:
+			return requestError(err, http.StatusConflict)
+		case geo.ErrInvalidPoint:
+			return requestError(err, http.StatusBadRequest)
+		case restaurant.ErrDuplicateName:
+			return requestError(err, http.StatusConflict)
 		default:
-			return errors.Wrapf(err, "updating restaurant %q: %+v", params["id"], up)
+			return errors.Wrapf(err, "updating restaurant %q: %+v", id, up)
 		}
 	}
 
-	return web.Respond(ctx, w, nil, http.StatusNoContent)
+	if res.geocoder != nil && geocode {
+		database.AfterCommit(ctx, func() { res.geocoder.Enqueue(id) })
+	}
+
+	return web.RespondUpdated(ctx, w, r, linkedRestaurant{*updated, restaurantLinks(*updated)})
+}
+
+// sameCoordinate reports whether both coordinates are unknown or equal.
+func sameCoordinate(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
 }
 
 // Delete removes a single restaurant identified by an ID in the request URL.
+// Only the owner of the restaurant or an admin may delete it.
 func (res *Restaurant) Delete(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
-	ctx, span := trace.StartSpan(ctx, "handlers.Restaurant.Delete")
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Restaurant.Delete")
 	defer span.End()
 
-	if err := restaurant.Delete(ctx, res.db, params["id"]); err != nil {
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	// A missing restaurant goes on to the same answer as a delete which
+	// found nothing.
+	existing, err := res.store.Retrieve(ctx, params["id"])
+	if err == nil {
+		if !authz.Allowed(claims, authz.Delete, authz.Resource{Kind: authz.Restaurant, Owner: existing.OwnerUserID}) {
+			return requestError(restaurant.ErrForbidden, http.StatusForbidden)
+		}
+		err = res.store.Delete(ctx, params["id"], v.Now)
+	}
+	if err != nil {
 		switch err {
-		case restaurant.ErrInvalidID:
-			return web.NewRequestError(err, http.StatusBadRequest)
+		case restaurant.ErrNotFound:
+			if res.strictDelete {
+				return requestError(err, http.StatusNotFound)
+			}
 		default:
 			return errors.Wrapf(err, "Id: %s", params["id"])
 		}
@@ -127,3 +418,75 @@ func (res *Restaurant) Delete(ctx context.Context, w http.ResponseWriter, r *htt
 
 	return web.Respond(ctx, w, nil, http.StatusNoContent)
 }
+
+// AddFavorite marks the restaurant as a favorite of the calling user.
+func (res *Restaurant) AddFavorite(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Restaurant.AddFavorite")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	if err := res.store.AddFavorite(ctx, claims.Subject, params["id"], v.Now); err != nil {
+		switch err {
+		case restaurant.ErrNotFound:
+			return requestError(err, http.StatusNotFound)
+		default:
+			return errors.Wrapf(err, "ID: %s", params["id"])
+		}
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// RemoveFavorite removes the restaurant from the favorites of the calling
+// user.
+func (res *Restaurant) RemoveFavorite(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Restaurant.RemoveFavorite")
+	defer span.End()
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	if err := res.store.RemoveFavorite(ctx, claims.Subject, params["id"]); err != nil {
+		switch err {
+		default:
+			return errors.Wrapf(err, "ID: %s", params["id"])
+		}
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// ListFavorites returns the favorite restaurants of the calling user, the
+// most recently added first.
+func (res *Restaurant) ListFavorites(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Restaurant.ListFavorites")
+	defer span.End()
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	favorites, err := res.store.ListFavorites(ctx, claims.Subject)
+	if err != nil {
+		return err
+	}
+
+	linked := make([]linkedRestaurant, len(favorites))
+	for i, f := range favorites {
+		linked[i] = linkedRestaurant{f, restaurantLinks(f)}
+	}
+
+	return web.RespondList(ctx, w, linked, http.StatusOK)
+}
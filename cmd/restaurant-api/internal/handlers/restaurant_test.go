@@ -0,0 +1,758 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/remisb/restaurant/internal/memstore"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/web"
+	"github.com/remisb/restaurant/internal/restaurant"
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// TestRestaurantErrors validates the restaurant handlers map store errors to
+// the right status codes.
+func TestRestaurantErrors(t *testing.T) {
+	const id = "a2b0639f-2cc6-44b8-b97b-15d69dbb511e"
+	existing := restaurant.Restaurant{ID: id, Name: "Pizza Place", OwnerUserID: ownerID, Version: 1, DateUpdated: now}
+
+	tt := []struct {
+		name   string
+		method string
+		body   string
+		id     string
+		claims auth.Claims
+		errs   map[string]error
+		status int
+	}{
+		{"retrieve", http.MethodGet, "", id, userClaims(otherID, auth.RoleUser), nil, http.StatusOK},
+		{"retrieve missing", http.MethodGet, "", "ad1b9a26-e1a1-4e4c-9a1b-3a2b5f6f0d3b", userClaims(otherID, auth.RoleUser), nil, http.StatusNotFound},
+		{"retrieve failure", http.MethodGet, "", id, userClaims(otherID, auth.RoleUser), map[string]error{"Retrieve": errors.New("db down")}, http.StatusInternalServerError},
+		{"create", http.MethodPost, `{"name":"Sushi","address":"Main St"}`, "", userClaims(ownerID, auth.RoleUser), nil, http.StatusCreated},
+		{"create invalid", http.MethodPost, `{"name":"Sushi"}`, "", userClaims(ownerID, auth.RoleUser), nil, http.StatusBadRequest},
+		{"create failure", http.MethodPost, `{"name":"Sushi","address":"Main St"}`, "", userClaims(ownerID, auth.RoleUser), map[string]error{"Create": errors.New("db down")}, http.StatusInternalServerError},
+		{"create duplicate", http.MethodPost, `{"name":"Pizza place","address":"Main St"}`, "", userClaims(ownerID, auth.RoleUser), nil, http.StatusConflict},
+		{"create duplicate check failure", http.MethodPost, `{"name":"Sushi","address":"Main St"}`, "", userClaims(ownerID, auth.RoleUser), map[string]error{"FindSimilar": errors.New("db down")}, http.StatusInternalServerError},
+		{"update", http.MethodPut, `{"name":"Pasta Place","version":1}`, id, userClaims(ownerID, auth.RoleUser), nil, http.StatusNoContent},
+		{"update not owner", http.MethodPut, `{"name":"Pasta Place","version":1}`, id, userClaims(otherID, auth.RoleUser), nil, http.StatusForbidden},
+		{"update admin", http.MethodPut, `{"name":"Pasta Place","version":1}`, id, userClaims(otherID, auth.RoleAdmin), nil, http.StatusNoContent},
+		{"update missing", http.MethodPut, `{"name":"Pasta Place","version":1}`, "ad1b9a26-e1a1-4e4c-9a1b-3a2b5f6f0d3b", userClaims(ownerID, auth.RoleUser), nil, http.StatusNotFound},
+		{"update stale version", http.MethodPut, `{"name":"Pasta Place","version":2}`, id, userClaims(ownerID, auth.RoleUser), nil, http.StatusConflict},
+		{"update without version", http.MethodPut, `{"name":"Pasta Place"}`, id, userClaims(ownerID, auth.RoleUser), nil, http.StatusBadRequest},
+		{"delete", http.MethodDelete, "", id, userClaims(ownerID, auth.RoleAdmin), nil, http.StatusNoContent},
+		{"delete missing", http.MethodDelete, "", "ad1b9a26-e1a1-4e4c-9a1b-3a2b5f6f0d3b", userClaims(ownerID, auth.RoleAdmin), nil, http.StatusNoContent},
+		{"delete owner", http.MethodDelete, "", id, userClaims(ownerID, auth.RoleUser), nil, http.StatusNoContent},
+		{"delete not owner", http.MethodDelete, "", id, userClaims(otherID, auth.RoleUser), nil, http.StatusForbidden},
+	}
+
+	t.Log("Given the need to map restaurant store errors to status codes.")
+	{
+		for i, tc := range tt {
+			t.Logf("\tTest %d:\tWhen handling %s.", i, tc.name)
+			{
+				store := memstore.NewRestaurants(existing)
+				for m, err := range tc.errs {
+					store.Errs[m] = err
+				}
+				res := Restaurant{store: store}
+
+				var h = res.Retrieve
+				switch tc.method {
+				case http.MethodPost:
+					h = res.Create
+				case http.MethodPut:
+					h = res.Update
+				case http.MethodDelete:
+					h = res.Delete
+				}
+
+				w := serve(h, tc.method, tc.body, map[string]string{"id": tc.id}, tc.claims)
+				if w.Code != tc.status {
+					t.Fatalf("\t%s\tShould receive a status code of %d : got %d : %s", tests.Failed, tc.status, w.Code, w.Body)
+				}
+				t.Logf("\t%s\tShould receive a status code of %d.", tests.Success, tc.status)
+			}
+		}
+	}
+}
+
+// TestRestaurantStrictDelete validates deleting a missing restaurant answers
+// 404 once deletes are strict.
+func TestRestaurantStrictDelete(t *testing.T) {
+	const id = "a2b0639f-2cc6-44b8-b97b-15d69dbb511e"
+	res := Restaurant{
+		store:        memstore.NewRestaurants(restaurant.Restaurant{ID: id, Name: "Pizza Place", OwnerUserID: ownerID}),
+		strictDelete: true,
+	}
+	claims := userClaims(ownerID, auth.RoleAdmin)
+
+	tt := []struct {
+		name   string
+		id     string
+		status int
+	}{
+		{"an existing restaurant", id, http.StatusNoContent},
+		{"the deleted restaurant again", id, http.StatusNotFound},
+		{"a restaurant which never existed", "ad1b9a26-e1a1-4e4c-9a1b-3a2b5f6f0d3b", http.StatusNotFound},
+	}
+
+	t.Log("Given the need to tell clients the restaurant they delete does not exist.")
+	{
+		for i, tc := range tt {
+			t.Logf("\tTest %d:\tWhen deleting %s.", i, tc.name)
+			{
+				w := serve(res.Delete, http.MethodDelete, "", map[string]string{"id": tc.id}, claims)
+				if w.Code != tc.status {
+					t.Fatalf("\t%s\tShould receive a status code of %d : got %d : %s", tests.Failed, tc.status, w.Code, w.Body)
+				}
+				t.Logf("\t%s\tShould receive a status code of %d.", tests.Success, tc.status)
+			}
+		}
+	}
+}
+
+// TestRestaurantPatch validates merge patches change the fields they give and
+// clear the fields they set to null.
+func TestRestaurantPatch(t *testing.T) {
+	const id = "a2b0639f-2cc6-44b8-b97b-15d69dbb511e"
+	existing := restaurant.Restaurant{ID: id, Name: "Pizza Place", Address: "Main St", Website: "https://pizza.example", Phone: "555-0100", OwnerUserID: ownerID, Version: 1}
+
+	tt := []struct {
+		name   string
+		body   string
+		id     string
+		status int
+		want   func(r restaurant.Restaurant) bool
+	}{
+		{"changing the name", `{"name":"Pasta Place"}`, id, http.StatusNoContent, func(r restaurant.Restaurant) bool {
+			return r.Name == "Pasta Place" && r.Website == "https://pizza.example"
+		}},
+		{"clearing the website", `{"website":null,"phone":null}`, id, http.StatusNoContent, func(r restaurant.Restaurant) bool {
+			return r.Name == "Pizza Place" && r.Website == "" && r.Phone == ""
+		}},
+		{"clearing the name", `{"name":null}`, id, http.StatusBadRequest, nil},
+		{"an unknown field", `{"owner_user_id":"x"}`, id, http.StatusBadRequest, nil},
+		{"a stale version", `{"name":"Pasta Place","version":2}`, id, http.StatusConflict, nil},
+		{"a missing restaurant", `{"name":"Pasta Place"}`, "ad1b9a26-e1a1-4e4c-9a1b-3a2b5f6f0d3b", http.StatusNotFound, nil},
+	}
+
+	t.Log("Given the need to patch restaurants.")
+	{
+		for i, tc := range tt {
+			t.Logf("\tTest %d:\tWhen patching with %s.", i, tc.name)
+			{
+				store := memstore.NewRestaurants(existing)
+				res := Restaurant{store: store}
+
+				w := serve(res.Patch, http.MethodPatch, tc.body, map[string]string{"id": tc.id}, userClaims(ownerID, auth.RoleUser))
+				if w.Code != tc.status {
+					t.Fatalf("\t%s\tShould receive a status code of %d : got %d : %s", tests.Failed, tc.status, w.Code, w.Body)
+				}
+				t.Logf("\t%s\tShould receive a status code of %d.", tests.Success, tc.status)
+
+				if tc.want == nil {
+					continue
+				}
+				got, err := store.Retrieve(context.Background(), id)
+				if err != nil {
+					t.Fatalf("\t%s\tShould be able to retrieve the restaurant : %s.", tests.Failed, err)
+				}
+				if !tc.want(*got) || got.Version != 2 {
+					t.Fatalf("\t%s\tShould get the patched restaurant : got %+v.", tests.Failed, got)
+				}
+				t.Logf("\t%s\tShould get the patched restaurant.", tests.Success)
+			}
+		}
+	}
+}
+
+// TestRestaurantDuplicate validates a restaurant similar to an existing one
+// is only created when forced.
+func TestRestaurantDuplicate(t *testing.T) {
+	existing := restaurant.Restaurant{ID: "a2b0639f-2cc6-44b8-b97b-15d69dbb511e", Name: "Pizza Place", Address: "12 Main St", OwnerUserID: ownerID}
+	res := Restaurant{store: memstore.NewRestaurants(existing)}
+	claims := userClaims(otherID, auth.RoleUser)
+	body := `{"name":"The Pizza Place","address":"12 Main Street"}`
+
+	t.Log("Given the need to keep restaurants from being added twice.")
+	{
+		t.Log("\tTest 0:\tWhen creating a similar restaurant.")
+		{
+			w := serveQuery(res.Create, http.MethodPost, "", body, claims)
+			if w.Code != http.StatusConflict {
+				t.Fatalf("\t%s\tShould receive a status code of 409 : got %d.", tests.Failed, w.Code)
+			}
+
+			var got duplicateResponse
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatalf("\t%s\tShould decode the conflict : %s.", tests.Failed, err)
+			}
+			if got.Code != "RESTAURANT_DUPLICATE" || len(got.Matches) != 1 || got.Matches[0].ID != existing.ID {
+				t.Fatalf("\t%s\tShould list the existing restaurant : got %+v.", tests.Failed, got)
+			}
+			t.Logf("\t%s\tShould list the existing restaurant.", tests.Success)
+		}
+
+		t.Log("\tTest 1:\tWhen forcing the creation.")
+		{
+			if w := serveQuery(res.Create, http.MethodPost, "?force=true", body, claims); w.Code != http.StatusCreated {
+				t.Fatalf("\t%s\tShould receive a status code of 201 : got %d.", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould receive a status code of 201.", tests.Success)
+		}
+
+		t.Log("\tTest 2:\tWhen the owner forces a restaurant named like one of theirs.")
+		{
+			w := serveQuery(res.Create, http.MethodPost, "?force=true", `{"name":"PIZZA PLACE","address":"Harbour Rd 3"}`, userClaims(ownerID, auth.RoleUser))
+			if w.Code != http.StatusConflict {
+				t.Fatalf("\t%s\tShould receive a status code of 409 : got %d.", tests.Failed, w.Code)
+			}
+
+			var got web.ErrorResponse
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil || got.Code != "RESTAURANT_NAME_EXISTS" {
+				t.Fatalf("\t%s\tShould report the name exists : got %+v : %v.", tests.Failed, got, err)
+			}
+			t.Logf("\t%s\tShould report the name exists.", tests.Success)
+		}
+	}
+}
+
+// TestRestaurantImport validates restaurants are imported row by row with
+// invalid and duplicate rows skipped.
+func TestRestaurantImport(t *testing.T) {
+	existing := restaurant.Restaurant{ID: "a2b0639f-2cc6-44b8-b97b-15d69dbb511e", Name: "Pizza Place", Address: "Old Town", OwnerUserID: ownerID}
+
+	const file = "name,address\nSushi,Main St\n  PIZZA place ,Old  Town\n,Nowhere\nsushi,main st\n"
+	want := []string{importCreated, importDuplicate, importInvalid, importDuplicate}
+
+	upload := func(query string) *http.Request {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		fw, _ := mw.CreateFormFile("file", "restaurants.csv")
+		fw.Write([]byte(file))
+		mw.Close()
+
+		r := httptest.NewRequest(http.MethodPost, "/"+query, &body)
+		r.Header.Set("Content-Type", mw.FormDataContentType())
+		return r
+	}
+
+	t.Log("Given the need to import restaurants from a file.")
+	{
+		for i, dryRun := range []bool{false, true} {
+			t.Logf("\tTest %d:\tWhen importing a CSV file with dry run %v.", i, dryRun)
+			{
+				store := memstore.NewRestaurants(existing)
+				res := Restaurant{store: store}
+
+				query, status := "", http.StatusCreated
+				if dryRun {
+					query, status = "?dry_run=true", http.StatusOK
+				}
+
+				w := serveRequest(res.Import, upload(query), nil, userClaims(ownerID, auth.RoleUser))
+				if w.Code != status {
+					t.Fatalf("\t%s\tShould receive a status code of %d : got %d : %s", tests.Failed, status, w.Code, w.Body)
+				}
+				t.Logf("\t%s\tShould receive a status code of %d.", tests.Success, status)
+
+				var result importResult
+				if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
+					t.Fatalf("\t%s\tShould decode the result : %s.", tests.Failed, err)
+				}
+
+				for j, st := range want {
+					if dryRun && st == importCreated {
+						st = importValid
+					}
+					if result.Rows[j].Status != st {
+						t.Fatalf("\t%s\tShould report row %d as %s : got %+v.", tests.Failed, j+1, st, result.Rows[j])
+					}
+				}
+				t.Logf("\t%s\tShould report the outcome of every row.", tests.Success)
+
+				created := 1
+				if dryRun {
+					created = 0
+				}
+				list, _ := store.List(context.Background())
+				if n := len(list) - 1; n != created {
+					t.Fatalf("\t%s\tShould create %d restaurants : got %d.", tests.Failed, created, n)
+				}
+				t.Logf("\t%s\tShould create only the valid new restaurants.", tests.Success)
+			}
+		}
+
+		t.Log("\tTest 2:\tWhen importing a name the user holds in another organization.")
+		{
+			const acme = "0b1c9e0e-2f4f-4d36-9f5c-3f5f0d6c1a77"
+			other := restaurant.Restaurant{ID: "ad1b9a26-e1a1-4e4c-9a1b-3a2b5f6f0d3b", Name: "Sushi", Address: "Harbor", OwnerUserID: ownerID, OrgID: acme}
+			res := Restaurant{store: memstore.NewRestaurants(existing, other)}
+
+			w := serveRequest(res.Import, upload(""), nil, userClaims(ownerID, auth.RoleUser))
+			if w.Code != http.StatusOK {
+				t.Fatalf("\t%s\tShould receive a status code of %d : got %d : %s", tests.Failed, http.StatusOK, w.Code, w.Body)
+			}
+			t.Logf("\t%s\tShould receive a status code of %d.", tests.Success, http.StatusOK)
+
+			var result importResult
+			if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
+				t.Fatalf("\t%s\tShould decode the result : %s.", tests.Failed, err)
+			}
+			if result.Rows[0].Status != importDuplicate || result.Created != 0 || result.Duplicates != 3 {
+				t.Fatalf("\t%s\tShould report the taken name as a duplicate : got %+v.", tests.Failed, result)
+			}
+			t.Logf("\t%s\tShould report the taken name as a duplicate.", tests.Success)
+		}
+	}
+}
+
+// TestRestaurantFavorites validates users can mark restaurants as favorites
+// and see them flagged in the list.
+func TestRestaurantFavorites(t *testing.T) {
+	const id = "a2b0639f-2cc6-44b8-b97b-15d69dbb511e"
+	res := Restaurant{store: memstore.NewRestaurants(
+		restaurant.Restaurant{ID: id, Name: "Pizza Place", OwnerUserID: ownerID, Version: 1},
+		restaurant.Restaurant{ID: "ad1b9a26-e1a1-4e4c-9a1b-3a2b5f6f0d3b", Name: "Sushi", OwnerUserID: ownerID, Version: 1},
+	)}
+	claims := userClaims(otherID, auth.RoleUser)
+
+	// favorites returns the IDs listed as favorites by both endpoints.
+	favorites := func() ([]string, []string) {
+		var listed []listedRestaurant
+		if err := json.NewDecoder(serve(res.List, http.MethodGet, "", nil, claims).Body).Decode(&listed); err != nil {
+			t.Fatalf("\t%s\tShould decode the list : %s.", tests.Failed, err)
+		}
+		var flagged []string
+		for _, r := range listed {
+			if r.IsFavorite {
+				flagged = append(flagged, r.ID)
+			}
+		}
+
+		var rs []restaurant.Restaurant
+		if err := json.NewDecoder(serve(res.ListFavorites, http.MethodGet, "", nil, claims).Body).Decode(&rs); err != nil {
+			t.Fatalf("\t%s\tShould decode the favorites : %s.", tests.Failed, err)
+		}
+		var ids []string
+		for _, r := range rs {
+			ids = append(ids, r.ID)
+		}
+		return flagged, ids
+	}
+
+	t.Log("Given the need to keep favorite restaurants.")
+	{
+		t.Log("\tTest 0:\tWhen marking a restaurant as a favorite.")
+		{
+			for i := 0; i < 2; i++ {
+				if w := serve(res.AddFavorite, http.MethodPut, "", map[string]string{"id": id}, claims); w.Code != http.StatusNoContent {
+					t.Fatalf("\t%s\tShould receive a status code of 204 : got %d.", tests.Failed, w.Code)
+				}
+			}
+			t.Logf("\t%s\tShould receive a status code of 204 every time.", tests.Success)
+
+			flagged, ids := favorites()
+			if len(flagged) != 1 || flagged[0] != id || len(ids) != 1 || ids[0] != id {
+				t.Fatalf("\t%s\tShould list the favorite : got %v and %v.", tests.Failed, flagged, ids)
+			}
+			t.Logf("\t%s\tShould list the favorite.", tests.Success)
+		}
+
+		t.Log("\tTest 1:\tWhen marking an unknown restaurant.")
+		{
+			w := serve(res.AddFavorite, http.MethodPut, "", map[string]string{"id": "5cf37266-3473-4006-984f-9325122678b7"}, claims)
+			if w.Code != http.StatusNotFound {
+				t.Fatalf("\t%s\tShould receive a status code of 404 : got %d.", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould receive a status code of 404.", tests.Success)
+		}
+
+		t.Log("\tTest 2:\tWhen removing the favorite.")
+		{
+			if w := serve(res.RemoveFavorite, http.MethodDelete, "", map[string]string{"id": id}, claims); w.Code != http.StatusNoContent {
+				t.Fatalf("\t%s\tShould receive a status code of 204 : got %d.", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould receive a status code of 204.", tests.Success)
+
+			flagged, ids := favorites()
+			if len(flagged) != 0 || len(ids) != 0 {
+				t.Fatalf("\t%s\tShould no longer list it : got %v and %v.", tests.Failed, flagged, ids)
+			}
+			t.Logf("\t%s\tShould no longer list it.", tests.Success)
+		}
+	}
+}
+
+// TestRestaurantOrganizations validates users only see the restaurants of
+// their organization.
+func TestRestaurantOrganizations(t *testing.T) {
+	const (
+		acme   = "0b1c9e0e-2f4f-4d36-9f5c-3f5f0d6c1a77"
+		ours   = "a2b0639f-2cc6-44b8-b97b-15d69dbb511e"
+		theirs = "ad1b9a26-e1a1-4e4c-9a1b-3a2b5f6f0d3b"
+	)
+	res := Restaurant{store: memstore.NewRestaurants(
+		restaurant.Restaurant{ID: ours, Name: "Pizza Place", OwnerUserID: ownerID, Version: 1},
+		restaurant.Restaurant{ID: theirs, Name: "Sushi", OwnerUserID: otherID, OrgID: acme, Version: 1},
+	)}
+	claims := userClaims(otherID, auth.RoleUser)
+	claims.OrgID = acme
+
+	t.Log("Given the need to keep the restaurants of organizations apart.")
+	{
+		t.Log("\tTest 0:\tWhen listing the restaurants.")
+		{
+			var listed []listedRestaurant
+			if err := json.NewDecoder(serve(res.List, http.MethodGet, "", nil, claims).Body).Decode(&listed); err != nil {
+				t.Fatalf("\t%s\tShould decode the list : %s.", tests.Failed, err)
+			}
+			if len(listed) != 1 || listed[0].ID != theirs {
+				t.Fatalf("\t%s\tShould only list the restaurants of the organization : got %+v.", tests.Failed, listed)
+			}
+			t.Logf("\t%s\tShould only list the restaurants of the organization.", tests.Success)
+		}
+
+		t.Log("\tTest 1:\tWhen retrieving a restaurant of another organization.")
+		{
+			if w := serve(res.Retrieve, http.MethodGet, "", map[string]string{"id": ours}, claims); w.Code != http.StatusNotFound {
+				t.Fatalf("\t%s\tShould receive a status code of 404 : got %d.", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould receive a status code of 404.", tests.Success)
+		}
+
+		t.Log("\tTest 2:\tWhen creating a restaurant.")
+		{
+			w := serve(res.Create, http.MethodPost, `{"name":"Tacos","address":"Main St"}`, nil, claims)
+			if w.Code != http.StatusCreated {
+				t.Fatalf("\t%s\tShould receive a status code of 201 : got %d.", tests.Failed, w.Code)
+			}
+
+			var created restaurant.Restaurant
+			if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+				t.Fatalf("\t%s\tShould decode the restaurant : %s.", tests.Failed, err)
+			}
+			if w := serve(res.Retrieve, http.MethodGet, "", map[string]string{"id": created.ID}, userClaims(ownerID, auth.RoleUser)); w.Code != http.StatusNotFound {
+				t.Fatalf("\t%s\tShould hide it from the other organizations : got %d.", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould hide it from the other organizations.", tests.Success)
+		}
+	}
+}
+
+// TestRestaurantNearby validates searching restaurants around a position.
+func TestRestaurantNearby(t *testing.T) {
+	const (
+		closest = "a2b0639f-2cc6-44b8-b97b-15d69dbb511e"
+		further = "ad1b9a26-e1a1-4e4c-9a1b-3a2b5f6f0d3b"
+	)
+	at := func(lat, lng float64) (*float64, *float64) { return &lat, &lng }
+
+	closestRes := restaurant.Restaurant{ID: closest, Name: "Pizza Place", OwnerUserID: ownerID, Version: 1}
+	closestRes.Latitude, closestRes.Longitude = at(54.6870, 25.2800)
+	furtherRes := restaurant.Restaurant{ID: further, Name: "Sushi", OwnerUserID: ownerID, Version: 1}
+	furtherRes.Latitude, furtherRes.Longitude = at(54.7000, 25.2800)
+	unknownRes := restaurant.Restaurant{ID: "5cf37266-3473-4006-984f-9325122678b7", Name: "Tacos", OwnerUserID: ownerID, Version: 1}
+
+	res := Restaurant{store: memstore.NewRestaurants(furtherRes, unknownRes, closestRes)}
+	claims := userClaims(otherID, auth.RoleUser)
+
+	t.Log("Given the need to find the restaurants around a position.")
+	{
+		t.Log("	Test 0:	When searching within the default radius.")
+		{
+			w := serveQuery(res.List, http.MethodGet, "?near=54.6872,25.2797", "", claims)
+			if w.Code != http.StatusOK {
+				t.Fatalf("	%s	Should receive a status code of 200 : got %d.", tests.Failed, w.Code)
+			}
+
+			var listed []listedRestaurant
+			if err := json.NewDecoder(w.Body).Decode(&listed); err != nil {
+				t.Fatalf("	%s	Should decode the list : %s.", tests.Failed, err)
+			}
+			if len(listed) != 2 || listed[0].ID != closest || listed[1].ID != further {
+				t.Fatalf("	%s	Should list the located restaurants closest first : got %+v.", tests.Failed, listed)
+			}
+			t.Logf("	%s	Should list the located restaurants closest first.", tests.Success)
+
+			if listed[0].Distance == nil || *listed[0].Distance > 100 {
+				t.Fatalf("	%s	Should tell the distance of the restaurants : got %v.", tests.Failed, listed[0].Distance)
+			}
+			t.Logf("	%s	Should tell the distance of the restaurants.", tests.Success)
+		}
+
+		t.Log("	Test 1:	When searching within a smaller radius.")
+		{
+			var listed []listedRestaurant
+			w := serveQuery(res.List, http.MethodGet, "?near=54.6872,25.2797&radius=500", "", claims)
+			if err := json.NewDecoder(w.Body).Decode(&listed); err != nil {
+				t.Fatalf("	%s	Should decode the list : %s.", tests.Failed, err)
+			}
+			if len(listed) != 1 || listed[0].ID != closest {
+				t.Fatalf("	%s	Should only list the restaurants within the radius : got %+v.", tests.Failed, listed)
+			}
+			t.Logf("	%s	Should only list the restaurants within the radius.", tests.Success)
+		}
+
+		t.Log("	Test 2:	When searching with invalid parameters.")
+		{
+			for _, q := range []string{"?near=north", "?near=54.6872,25.2797&radius=-1", "?near=54.6872,25.2797&radius=1000000"} {
+				if w := serveQuery(res.List, http.MethodGet, q, "", claims); w.Code != http.StatusBadRequest {
+					t.Fatalf("	%s	Should receive a status code of 400 for %s : got %d.", tests.Failed, q, w.Code)
+				}
+			}
+			t.Logf("	%s	Should receive a status code of 400.", tests.Success)
+		}
+
+		t.Log("	Test 3:	When creating a restaurant with half a location.")
+		{
+			w := serve(res.Create, http.MethodPost, `{"name":"Kebab","address":"Main St","latitude":54.68}`, nil, claims)
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("	%s	Should receive a status code of 400 : got %d.", tests.Failed, w.Code)
+			}
+			t.Logf("	%s	Should receive a status code of 400.", tests.Success)
+		}
+	}
+}
+
+// TestRestaurantTagFilter validates filtering the restaurants by tag.
+func TestRestaurantTagFilter(t *testing.T) {
+	const (
+		pizza = "a2b0639f-2cc6-44b8-b97b-15d69dbb511e"
+		sushi = "ad1b9a26-e1a1-4e4c-9a1b-3a2b5f6f0d3b"
+	)
+	res := Restaurant{store: memstore.NewRestaurants(
+		restaurant.Restaurant{ID: pizza, Name: "Pizza Place", OwnerUserID: ownerID, Tags: []string{"italian", "vegan"}, Version: 1},
+		restaurant.Restaurant{ID: sushi, Name: "Sushi", OwnerUserID: ownerID, Tags: []string{"japanese", "vegan"}, Version: 1},
+	)}
+	claims := userClaims(otherID, auth.RoleUser)
+
+	tt := []struct {
+		query string
+		ids   []string
+	}{
+		{"?tag=vegan", []string{pizza, sushi}},
+		{"?tag=italian", []string{pizza}},
+		{"?tag=vegan&tag=japanese", []string{sushi}},
+		{"?tag=mexican", nil},
+	}
+
+	t.Log("Given the need to filter the restaurants by tag.")
+	{
+		for i, tc := range tt {
+			t.Logf("	Test %d:	When listing with %s.", i, tc.query)
+			{
+				var listed []listedRestaurant
+				if err := json.NewDecoder(serveQuery(res.List, http.MethodGet, tc.query, "", claims).Body).Decode(&listed); err != nil {
+					t.Fatalf("	%s	Should decode the list : %s.", tests.Failed, err)
+				}
+
+				var ids []string
+				for _, r := range listed {
+					ids = append(ids, r.ID)
+				}
+				sort.Strings(ids)
+				if fmt.Sprint(ids) != fmt.Sprint(tc.ids) {
+					t.Fatalf("	%s	Should list %v : got %v.", tests.Failed, tc.ids, ids)
+				}
+				t.Logf("	%s	Should list %v.", tests.Success, tc.ids)
+			}
+		}
+	}
+}
+
+// discardWriter is a ResponseWriter which throws away the response so the
+// benchmarks measure the handlers alone.
+type discardWriter struct {
+	header http.Header
+}
+
+func (d *discardWriter) Header() http.Header         { return d.header }
+func (d *discardWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (d *discardWriter) WriteHeader(int)             {}
+
+// BenchmarkRestaurantList measures listing restaurants. The list is streamed
+// so the memory of a response does not grow with the number of restaurants.
+func BenchmarkRestaurantList(b *testing.B) {
+	for _, n := range []int{50, 5000} {
+		rs := make([]restaurant.Restaurant, n)
+		for i := range rs {
+			rs[i] = restaurant.Restaurant{
+				ID:          fmt.Sprintf("%08d-2cc6-44b8-b97b-15d69dbb511e", i),
+				Name:        "Pizza Place",
+				Address:     "1 Main Street",
+				OwnerUserID: ownerID,
+				Version:     1,
+				DateCreated: now,
+				DateUpdated: now,
+			}
+		}
+		res := Restaurant{store: memstore.NewRestaurants(rs...)}
+
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			w := discardWriter{header: http.Header{}}
+			ctx := context.WithValue(r.Context(), auth.Key, userClaims(ownerID, auth.RoleUser))
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				v := web.Values{Now: now, Method: r.Method, Header: r.Header}
+				if err := res.List(context.WithValue(ctx, web.KeyValues, &v), &w, r, nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// TestRestaurantListInclude validates the related data asked for is embedded
+// in the listed restaurants.
+func TestRestaurantListInclude(t *testing.T) {
+	const id = "a2b0639f-2cc6-44b8-b97b-15d69dbb511e"
+	const unrated = "ad1b9a26-e1a1-4e4c-9a1b-3a2b5f6f0d3b"
+	store := memstore.NewRestaurants(
+		restaurant.Restaurant{ID: id, Name: "Pizza Place", OwnerUserID: ownerID},
+		restaurant.Restaurant{ID: unrated, Name: "Sushi Bar", OwnerUserID: ownerID},
+	)
+	votes, rating := 3, 4.5
+	store.SetIncluded(id, restaurant.Included{
+		Menu:   &restaurant.Menu{ID: "5cf37266-3473-4006-984f-9325122678b7", RestaurantID: id, Date: now, Menu: "Pizza"},
+		Votes:  &votes,
+		Rating: &rating,
+	})
+	res := Restaurant{store: store}
+	claims := userClaims(otherID, auth.RoleUser)
+
+	// list returns the listed restaurants by ID.
+	list := func(query string) map[string]listedRestaurant {
+		var listed []listedRestaurant
+		if err := json.NewDecoder(serveQuery(res.List, http.MethodGet, query, "", claims).Body).Decode(&listed); err != nil {
+			t.Fatalf("\t%s\tShould decode the list : %s.", tests.Failed, err)
+		}
+		byID := make(map[string]listedRestaurant, len(listed))
+		for _, r := range listed {
+			byID[r.ID] = r
+		}
+		return byID
+	}
+
+	t.Log("Given the need to embed related data in the list of restaurants.")
+	{
+		t.Log("\tTest 0:\tWhen nothing is included.")
+		{
+			listed := list("")
+			if r := listed[id]; r.Menu != nil || r.Votes != nil || r.Rating != nil {
+				t.Fatalf("\t%s\tShould embed nothing : got %+v.", tests.Failed, r.Included)
+			}
+			t.Logf("\t%s\tShould embed nothing.", tests.Success)
+		}
+
+		t.Log("\tTest 1:\tWhen including the menu, votes and rating.")
+		{
+			listed := list("?include=menu,votes,rating")
+			r := listed[id]
+			if r.Menu == nil || r.Menu.Menu != "Pizza" || r.Votes == nil || *r.Votes != 3 || r.Rating == nil || *r.Rating != 4.5 {
+				t.Fatalf("\t%s\tShould embed the menu, votes and rating : got %+v.", tests.Failed, r.Included)
+			}
+			t.Logf("\t%s\tShould embed the menu, votes and rating.", tests.Success)
+
+			u := listed[unrated]
+			if u.Menu != nil || u.Votes == nil || *u.Votes != 0 || u.Rating != nil {
+				t.Fatalf("\t%s\tShould embed no votes without menu and rating : got %+v.", tests.Failed, u.Included)
+			}
+			t.Logf("\t%s\tShould embed no votes without menu and rating.", tests.Success)
+		}
+
+		t.Log("\tTest 2:\tWhen including the votes only.")
+		{
+			r := list("?include=votes")[id]
+			if r.Menu != nil || r.Votes == nil || *r.Votes != 3 || r.Rating != nil {
+				t.Fatalf("\t%s\tShould embed the votes only : got %+v.", tests.Failed, r.Included)
+			}
+			t.Logf("\t%s\tShould embed the votes only.", tests.Success)
+		}
+
+		t.Log("\tTest 3:\tWhen including unknown data.")
+		{
+			if w := serveQuery(res.List, http.MethodGet, "?include=owner", "", claims); w.Code != http.StatusBadRequest {
+				t.Fatalf("\t%s\tShould receive a status code of 400 : got %d.", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould receive a status code of 400.", tests.Success)
+		}
+	}
+}
+
+// TestRestaurantListFields validates the listed restaurants only carry the
+// fields asked for.
+func TestRestaurantListFields(t *testing.T) {
+	const id = "a2b0639f-2cc6-44b8-b97b-15d69dbb511e"
+	res := Restaurant{store: memstore.NewRestaurants(restaurant.Restaurant{ID: id, Name: "Pizza Place", Address: "Main St", OwnerUserID: ownerID})}
+
+	t.Log("Given the need to trim the list of restaurants.")
+	{
+		t.Log("\tTest 0:\tWhen asking for the id, name and address.")
+		{
+			w := serveQuery(res.List, http.MethodGet, "?fields=id,name,address", "", userClaims(otherID, auth.RoleUser))
+			want := `[{"id":"` + id + `","name":"Pizza Place","address":"Main St"}]`
+			if got := strings.TrimSpace(w.Body.String()); got != want {
+				t.Fatalf("\t%s\tShould only receive the fields : got %s.", tests.Failed, got)
+			}
+			t.Logf("\t%s\tShould only receive the fields.", tests.Success)
+		}
+	}
+}
+
+// TestRestaurantLinks validates restaurants come with the links to their
+// related resources.
+func TestRestaurantLinks(t *testing.T) {
+	const id = "a2b0639f-2cc6-44b8-b97b-15d69dbb511e"
+	res := Restaurant{store: memstore.NewRestaurants(restaurant.Restaurant{ID: id, Name: "Pizza Place", OwnerUserID: ownerID})}
+
+	want := web.Links{
+		"self":  "/v1/restaurant/" + id,
+		"menu":  "/v1/restaurant/" + id + "/menu",
+		"votes": "/v1/restaurant/" + id + "/votes",
+		"owner": "/v1/users/" + ownerID,
+	}
+
+	t.Log("Given the need to navigate from a restaurant.")
+	{
+		t.Log("\tTest 0:\tWhen retrieving the restaurant.")
+		{
+			w := serve(res.Retrieve, http.MethodGet, "", map[string]string{"id": id}, userClaims(otherID, auth.RoleUser))
+			var got linkedRestaurant
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatalf("\t%s\tShould be able to decode the response : %v", tests.Failed, err)
+			}
+			if fmt.Sprint(got.Links) != fmt.Sprint(want) {
+				t.Fatalf("\t%s\tShould receive the links : got %v.", tests.Failed, got.Links)
+			}
+			t.Logf("\t%s\tShould receive the links.", tests.Success)
+		}
+
+		t.Log("\tTest 1:\tWhen listing the restaurants.")
+		{
+			w := serve(res.List, http.MethodGet, "", nil, userClaims(otherID, auth.RoleUser))
+			var got []listedRestaurant
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatalf("\t%s\tShould be able to decode the response : %v", tests.Failed, err)
+			}
+			if len(got) != 1 || fmt.Sprint(got[0].Links) != fmt.Sprint(want) {
+				t.Fatalf("\t%s\tShould receive the links : got %+v.", tests.Failed, got)
+			}
+			t.Logf("\t%s\tShould receive the links.", tests.Success)
+		}
+	}
+}
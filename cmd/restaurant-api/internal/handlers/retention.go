@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/remisb/restaurant/internal/platform/web"
+	"github.com/remisb/restaurant/internal/retention"
+	"go.opentelemetry.io/otel"
+)
+
+// Retention represents the data retention API method handler set. The
+// policies apply to the whole deployment so only the admins of the default
+// organization see them.
+type Retention struct {
+	db       *sqlx.DB
+	policies []retention.Policy
+}
+
+// DryRun reports the rows of every table the retention policies would purge
+// now, without deleting them.
+func (rt *Retention) DryRun(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Retention.DryRun")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	if err := checkOperator(ctx); err != nil {
+		return err
+	}
+
+	purges, err := retention.Apply(ctx, rt.db, rt.policies, true, v.Now)
+	if err != nil {
+		return err
+	}
+
+	return web.RespondList(ctx, w, purges, http.StatusOK)
+}
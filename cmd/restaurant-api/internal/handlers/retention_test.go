@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// TestRetentionDryRun validates only the admins of the service see what the
+// retention policies would purge.
+func TestRetentionDryRun(t *testing.T) {
+	acme := userClaims(ownerID, auth.RoleAdmin)
+	acme.OrgID = "0b1c9e0e-2f4f-4d36-9f5c-3f5f0d6c1a77"
+
+	tt := []struct {
+		name   string
+		claims auth.Claims
+		status int
+	}{
+		{"a user", userClaims(otherID, auth.RoleUser), http.StatusForbidden},
+		{"the admin of another organization", acme, http.StatusForbidden},
+		{"an admin of the service", userClaims(ownerID, auth.RoleAdmin), http.StatusOK},
+	}
+
+	t.Log("Given the need to review the retention policies before they purge.")
+	{
+		for i, tc := range tt {
+			t.Logf("\tTest %d:\tWhen %s asks.", i, tc.name)
+			{
+				rt := Retention{}
+
+				w := serve(rt.DryRun, http.MethodGet, "", nil, tc.claims)
+				if w.Code != tc.status {
+					t.Fatalf("\t%s\tShould receive a status code of %d : got %d.", tests.Failed, tc.status, w.Code)
+				}
+				t.Logf("\t%s\tShould receive a status code of %d.", tests.Success, tc.status)
+			}
+		}
+	}
+}
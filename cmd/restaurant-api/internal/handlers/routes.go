@@ -2,59 +2,559 @@ package handlers
 
 import (
 	"github.com/jmoiron/sqlx"
+	"github.com/remisb/restaurant/internal/broadcast"
+	"github.com/remisb/restaurant/internal/change"
+	"github.com/remisb/restaurant/internal/enrichment"
+	"github.com/remisb/restaurant/internal/featureflag"
+	"github.com/remisb/restaurant/internal/geocoding"
+	"github.com/remisb/restaurant/internal/media"
 	"github.com/remisb/restaurant/internal/mid"
+	"github.com/remisb/restaurant/internal/notification"
+	"github.com/remisb/restaurant/internal/order"
 	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/breaker"
+	"github.com/remisb/restaurant/internal/platform/clock"
+	"github.com/remisb/restaurant/internal/platform/database"
+	"github.com/remisb/restaurant/internal/platform/ratelimit"
 	"github.com/remisb/restaurant/internal/platform/web"
+	"github.com/remisb/restaurant/internal/restaurant"
+	"github.com/remisb/restaurant/internal/retention"
+	"github.com/remisb/restaurant/internal/stats"
+	"github.com/remisb/restaurant/internal/user"
+	"github.com/remisb/restaurant/internal/vote"
+	"github.com/remisb/restaurant/internal/webhook"
 	"log"
 	"net/http"
 	"os"
+	"sync/atomic"
+	"time"
 )
 
 const (
-	GET = "GET"
-	PUT = "PUT"
-	POST = "POST"
+	GET    = "GET"
+	PUT    = "PUT"
+	POST   = "POST"
+	PATCH  = "PATCH"
 	DELETE = "DELETE"
 )
 
-func API(build string, shutdown chan os.Signal, log *log.Logger, db *sqlx.DB, authenticator *auth.Authenticator) http.Handler {
-	app := web.NewApp(shutdown, mid.Logger(log), mid.Errors(log), mid.Metrics(), mid.Panics(log))
+// APIConfig contains all the systems and settings required by the handlers.
+type APIConfig struct {
+	Build          string
+	Shutdown       chan os.Signal
+	Log            *log.Logger
+	DB             *sqlx.DB
+	Authenticator  *auth.Authenticator
+	Enricher       *enrichment.Worker
+	Geocoder       *geocoding.Worker
+	VotePolicy     vote.Policy
+	OrderPolicy    order.Policy
+	VoteHub        *vote.Hub
+	RateLimiter    ratelimit.Store
+	RateLimits     RateLimits
+	MaxBodySize    int64
+	RequestTimeout time.Duration
+	IdempotencyTTL time.Duration
+	StatsCacheTTL  time.Duration
 
-	check := Check{
-		build: build,
-		db: db,
+	// IdempotencyLease is how long a request holds its Idempotency-Key
+	// before a retry may claim it, as when the replica handling it stopped.
+	// It must exceed the longest request timeout.
+	IdempotencyLease time.Duration
+
+	// LongRequestTimeout replaces RequestTimeout for the imports, the
+	// batches and the PDF exports, which take longer than other requests.
+	// The streams have no deadline.
+	LongRequestTimeout time.Duration
+
+	// Changes tells about the menus and votes changed by any replica, so
+	// the cached dashboards are dropped and the vote streams woken. When nil
+	// only the changes made through this API are seen.
+	Changes *change.Feed
+
+	// Webhooks queues the events delivered to the registered webhooks.
+	Webhooks *webhook.Notifier
+
+	// BroadcastStream pushes the broadcasts to the members streaming them,
+	// BroadcastChannels are the other channels the broadcast workers deliver
+	// them over.
+	BroadcastStream   *broadcast.Stream
+	BroadcastChannels []broadcast.Channel
+
+	// Uploader stores the uploaded images of the restaurants and menus.
+	Uploader *media.Uploader
+
+	// Notifier adds the in-app notifications of the handlers. When nil no
+	// notifications are added.
+	Notifier *notification.Notifier
+
+	// Draining is set once the service is shutting down so it reports it is
+	// no longer ready for requests.
+	Draining *atomic.Bool
+
+	// Jobs are the background jobs reported by the health check.
+	Jobs []Job
+
+	// ReadDB is a read-only replica of DB serving the read-heavy listings.
+	// When nil they are read from DB.
+	ReadDB *sqlx.DB
+
+	// Stores hold the data of the handlers. Stores left nil are backed by DB.
+	Stores Stores
+
+	// Driver is the database driver of DB, postgres when blank, pgx or
+	// sqlite. The schema migrations only apply to PostgreSQL.
+	Driver string
+
+	// Breaker guards the calls of the stores so they fail fast while the
+	// database is down. When nil the calls are not guarded.
+	Breaker *breaker.Breaker
+
+	// RetentionPolicies are the retention policies reported by the dry run
+	// of the purge.
+	RetentionPolicies []retention.Policy
+
+	// StrictDelete answers 404 when deleting a restaurant which does not
+	// exist or was already deleted. Otherwise deleting it again answers 204
+	// like the first time.
+	StrictDelete bool
+
+	// NegotiateVersion lets clients ask for a version of the API with the
+	// Accept header, as in application/vnd.restaurant.v2+json, whatever the
+	// version in the path.
+	NegotiateVersion bool
+
+	// Clock tells the time of the requests. When nil it is the clock of the
+	// system.
+	Clock clock.Clock
+
+	// PanicReporters report the panics of the handlers, like to Sentry, on
+	// top of logging them.
+	PanicReporters []mid.PanicReporter
+
+	// QueryTimeout bounds every call of the stores backed by the database.
+	// Zero leaves them unbounded.
+	QueryTimeout time.Duration
+}
+
+// Stores are the stores used by the handlers. They can be replaced by other
+// implementations, like the in-memory ones of the memstore package in tests.
+type Stores struct {
+	Restaurants restaurant.Store
+	Menus       restaurant.MenuStore
+	Users       user.Store
+	Votes       vote.Store
+}
+
+// withDefaults returns the stores with the missing ones backed by the
+// database.
+func (s Stores) withDefaults(db *database.DB) Stores {
+	if s.Restaurants == nil {
+		s.Restaurants = restaurant.NewStore(db)
+	}
+	if s.Menus == nil {
+		s.Menus = restaurant.NewMenuStore(db)
+	}
+	if s.Users == nil {
+		s.Users = user.NewStore(db)
+	}
+	if s.Votes == nil {
+		s.Votes = vote.NewStore(db)
+	}
+	return s
+}
+
+// DebugHealth returns the health check showing the internals of the service,
+// meant for the debug listener which is not exposed publicly.
+func DebugHealth(cfg APIConfig) http.Handler {
+	return http.HandlerFunc(newCheck(cfg).Debug)
+}
+
+// RateLimits holds the rate limits of the route groups which need protecting
+// from abuse. A zero value leaves the group unlimited.
+type RateLimits struct {
+	Token  ratelimit.Limit
+	Vote   ratelimit.Limit
+	Public ratelimit.Limit
+}
+
+// API constructs an http.Handler with all application routes defined.
+func API(cfg APIConfig) http.Handler {
+	limiter := cfg.RateLimiter
+	if limiter == nil {
+		limiter = ratelimit.NewMemory()
 	}
+	tokenLimit := mid.RateLimit(limiter, mid.RateLimitPolicy{Group: "token", PerIP: cfg.RateLimits.Token})
+	publicLimit := mid.RateLimit(limiter, mid.RateLimitPolicy{Group: "public", PerIP: cfg.RateLimits.Public})
+	voteLimit := mid.RateLimit(limiter, mid.RateLimitPolicy{Group: "vote", PerIP: cfg.RateLimits.Vote, PerUser: cfg.RateLimits.Vote})
+	long := mid.Timeout(cfg.LongRequestTimeout)
+	stream := mid.Timeout(0)
 
-	app.Handle(GET, "/v1/health", check.Health)
+	// Idempotency keys are stored in PostgreSQL, with SQLite the requests
+	// carrying one are processed like any other.
+	var idempotent web.Middleware
+	if cfg.Driver != "sqlite" {
+		idempotent = mid.Idempotency(cfg.DB, cfg.IdempotencyTTL, cfg.IdempotencyLease)
+	}
 
-	u := User{
-		db: db,
-		authenticator: authenticator,
+	db := database.NewDB(cfg.DB, cfg.ReadDB)
+	db.SetQueryTimeout(cfg.QueryTimeout)
+	stores := cfg.Stores.withDefaults(db).withBreaker(cfg.Breaker).withCoalescing()
+
+	app := web.NewApp(cfg.Shutdown, mid.Logger(cfg.Log), mid.Errors(cfg.Log), mid.Metrics(), mid.Panics(cfg.Log, cfg.PanicReporters...), mid.MaxBodySize(cfg.MaxBodySize), mid.Timeout(cfg.RequestTimeout))
+	if cfg.Clock != nil {
+		app.SetClock(cfg.Clock)
+	}
+
+	// Routes of the API, served by the same handlers under /v1 and /v2. The
+	// lists of version 2 come in an envelope. Most of the routes require an
+	// authenticated user and some an administrator.
+	versions := []int{1, 2}
+	var negotiate []web.Middleware
+	if cfg.NegotiateVersion {
+		negotiate = append(negotiate, web.NegotiateVersion(versions...))
 	}
+	api := app.Versions(versions, negotiate...)
+	authed := api.Group("", mid.Authenticate(cfg.Authenticator))
+	admin := authed.Group("", mid.HasRole(auth.RoleAdmin))
+
+	check := newCheck(cfg)
+	api.Handle(GET, "/health", check.Ready)
+	api.Handle(GET, "/health/live", check.Live)
+	api.Handle(GET, "/health/ready", check.Ready)
 
-	app.Handle(GET, "/v1/users", u.List, mid.Authenticate(authenticator), mid.HasRole(auth.RoleAdmin))
-	app.Handle(POST, "/v1/users", u.Create, mid.Authenticate(authenticator), mid.HasRole(auth.RoleAdmin))
+	caps := newCapabilities(cfg, versions)
+	authed.Handle(GET, "/capabilities", caps.Retrieve)
 
-	app.Handle(GET, "/v1/users/token", u.Token)
+	u := User{
+		store:         stores.Users,
+		authenticator: cfg.Authenticator,
+	}
+	admin.Handle(GET, "/users", u.List)
+	admin.Handle(POST, "/users", u.Create)
+	authed.Handle(GET, "/users/:id<uuid>", u.Retrieve)
+	api.Handle(GET, "/users/token", u.Token, tokenLimit)
 
 	// Register restaurant and menu endpoints.
+	restaurants := authed.Group("/restaurant")
+
 	r := Restaurant{
-		db: db,
+		store:        stores.Restaurants,
+		enricher:     cfg.Enricher,
+		geocoder:     cfg.Geocoder,
+		webhooks:     cfg.Webhooks,
+		strictDelete: cfg.StrictDelete,
 	}
-	app.Handle(GET, "/v1/restaurant", r.List, mid.Authenticate(authenticator))
-	app.Handle(POST, "/v1/restaurant", r.Create, mid.Authenticate(authenticator))
-	app.Handle(GET, "/v1/restaurant/:id", r.Retrieve, mid.Authenticate(authenticator))
-	app.Handle(PUT, "/v1/restaurant/:id", r.Update, mid.Authenticate(authenticator))
-	app.Handle(DELETE, "/v1/restaurant/:id", r.Delete, mid.Authenticate(authenticator))
+	restaurants.Handle(GET, "", r.List)
+	restaurants.Handle(POST, "", r.Create, idempotent)
+	restaurants.Handle(POST, "/import", r.Import, long, idempotent)
+	restaurants.Handle(GET, "/:id<uuid>", r.Retrieve)
+	restaurants.Handle(PUT, "/:id<uuid>", r.Update)
+	restaurants.Handle(PATCH, "/:id<uuid>", r.Patch)
+	restaurants.Handle(DELETE, "/:id<uuid>", r.Delete)
+	restaurants.Handle(PUT, "/:id<uuid>/favorite", r.AddFavorite)
+	restaurants.Handle(DELETE, "/:id<uuid>/favorite", r.RemoveFavorite)
+	authed.Handle(GET, "/users/me/favorites", r.ListFavorites)
 
-	// restaurant menu handlers
+	// The dashboards of the owners are cached as they are reloaded far more
+	// often than they change.
+	statsStore := stats.NewStore(db, cfg.StatsCacheTTL)
+	cfg.Changes.Subscribe(func(e change.Event) {
+		statsStore.Invalidate(e.RestaurantID)
+		switch {
+		case e.All():
+			cfg.VoteHub.NotifyAll()
+		case e.Table == change.TableVote:
+			cfg.VoteHub.Notify(e.Day())
+		}
+	})
 
-	// Register restaurant and menu endpoints.
+	// restaurant menu handlers
 	m := Menu{
-		db: db,
+		store:       stores.Menus,
+		restaurants: stores.Restaurants,
+		votes:       stores.Votes,
+		webhooks:    cfg.Webhooks,
+		notifier:    cfg.Notifier,
+		uploader:    cfg.Uploader,
+		views:       statsStore,
+	}
+	restaurants.Handle(GET, "/:restaurantId<uuid>/menu", m.RetrieveMenu)
+	restaurants.Handle(GET, "/:restaurantId<uuid>/menus", m.ListMenus)
+	restaurants.Handle(GET, "/:restaurantId<uuid>/menu/:menuId<uuid>/pdf", m.PDF, long)
+	restaurants.Handle(GET, "/:restaurantId<uuid>/votes", m.RetrieveVotes)
+	restaurants.Handle(POST, "/:restaurantId<uuid>/menu", m.CreateMenu, mid.HasRole(auth.RoleAdmin), idempotent)
+	restaurants.Handle(PATCH, "/:restaurantId<uuid>/menu/:menuId<uuid>", m.Patch, mid.HasRole(auth.RoleAdmin))
+
+	// Register lunch voting endpoints.
+	vt := Vote{
+		store:       stores.Votes,
+		restaurants: stores.Restaurants,
+		policy:      cfg.VotePolicy,
+		hub:         cfg.VoteHub,
+	}
+	authed.Handle(POST, "/votes", vt.Cast, voteLimit, idempotent)
+	authed.Handle(DELETE, "/votes/today", vt.Retract, voteLimit)
+	restaurants.Handle(GET, "/:restaurantId<uuid>/votes/stream", vt.Stream, stream)
+	authed.Handle(GET, "/votes/tally", vt.Tallies)
+	authed.Handle(GET, "/votes/winner", vt.Winner)
+	admin.Handle(GET, "/votes", vt.History)
+
+	// Register the batch endpoint. Its sub-requests go through the whole
+	// application like any other request.
+	b := Batch{
+		db:  cfg.DB,
+		app: app,
+	}
+	authed.Handle(POST, "/batch", b.Run, long)
+
+	// The remaining routes are backed by the tables of the PostgreSQL schema
+	// only, SQLite has none of them nor organizations to scope them to.
+	if cfg.Driver == "sqlite" {
+		return app
+	}
+
+	// Register organization endpoints.
+	org := Organization{
+		db: cfg.DB,
 	}
-	app.Handle(GET, "/v1/restaurant/:restaurantId/menu", m.RetrieveMenu, mid.Authenticate(authenticator))
-	app.Handle(GET, "/v1/restaurant/:restaurantId/votes", m.RetrieveVotes, mid.Authenticate(authenticator))
-	app.Handle(POST, "/v1/restaurant/:restaurantId/menu", m.CreateMenu, mid.Authenticate(authenticator), mid.HasRole(auth.RoleAdmin))
+	authed.Handle(GET, "/organization", org.Retrieve)
+	authed.Handle(GET, "/organization/settings", org.Settings)
+	admin.Handle(GET, "/settings", org.Settings)
+	admin.Handle(PUT, "/settings", org.UpdateSettings)
+	admin.Handle(GET, "/organizations", org.List)
+	admin.Handle(POST, "/organizations", org.Create)
+	admin.Handle(PUT, "/organizations/:id<uuid>/members/:userId<uuid>", org.AddMember)
+
+	// Register the feature flags turning risky features on per organization.
+	ff := FeatureFlag{
+		db: cfg.DB,
+	}
+	admin.Handle(GET, "/flags", ff.List)
+	admin.Handle(GET, "/flags/:name<snake>", ff.Retrieve)
+	admin.Handle(PUT, "/flags/:name<snake>", ff.Set)
+	admin.Handle(DELETE, "/flags/:name<snake>", ff.Delete)
+
+	// Register team endpoints.
+	tm := Team{
+		db: cfg.DB,
+	}
+	authed.Handle(GET, "/teams", tm.List)
+	admin.Handle(POST, "/teams", tm.Create)
+	authed.Handle(GET, "/teams/:id<uuid>", tm.Retrieve)
+	admin.Handle(PUT, "/teams/:id<uuid>", tm.Update)
+	admin.Handle(DELETE, "/teams/:id<uuid>", tm.Delete)
+	authed.Handle(GET, "/teams/:id<uuid>/members", tm.Members)
+	admin.Handle(PUT, "/teams/:id<uuid>/members/:userId<uuid>", tm.AddMember)
+	admin.Handle(DELETE, "/teams/:id<uuid>/members/:userId<uuid>", tm.RemoveMember)
+
+	// Register image upload endpoints. The images stored on the local disk
+	// are served without authentication like any public asset.
+	md := Media{
+		db:          cfg.DB,
+		restaurants: stores.Restaurants,
+		uploader:    cfg.Uploader,
+	}
+	restaurants.Handle(POST, "/:id<uuid>/photos", md.UploadPhoto)
+	restaurants.Handle(POST, "/:restaurantId<uuid>/menu/:menuId<uuid>/image", md.UploadMenuImage)
+	api.Handle(GET, "/media/*key", md.Serve)
+
+	// Register tag endpoints. Owners tag their restaurants with the tags
+	// the admins defined.
+	tg := Tag{
+		db: cfg.DB,
+	}
+	authed.Handle(GET, "/tags", tg.List)
+	admin.Handle(POST, "/tags", tg.Create)
+	authed.Handle(GET, "/tags/:slug<slug>", tg.Retrieve)
+	admin.Handle(PUT, "/tags/:slug<slug>", tg.Update)
+	admin.Handle(DELETE, "/tags/:slug<slug>", tg.Delete)
+	restaurants.Handle(PUT, "/:id<uuid>/tags", tg.Assign)
+
+	// Register restaurant enrichment endpoints.
+	s := Suggestion{
+		db:       cfg.DB,
+		enricher: cfg.Enricher,
+	}
+	restaurants.Handle(GET, "/:id<uuid>/suggestions", s.List)
+	restaurants.Handle(POST, "/:id<uuid>/suggestions", s.Enrich)
+	restaurants.Handle(POST, "/:id<uuid>/suggestions/:suggestionId<uuid>/accept", s.Accept)
+	restaurants.Handle(POST, "/:id<uuid>/suggestions/:suggestionId<uuid>/reject", s.Reject)
+
+	// Register the voting for menu items of the organizations voting for
+	// dishes rather than restaurants.
+	dv := DishVote{
+		db:     cfg.DB,
+		policy: cfg.VotePolicy,
+	}
+	dishes := authed.Group("/votes/dishes", mid.Feature(cfg.DB, featureflag.DishVoting))
+	dishes.Handle(POST, "", dv.Cast, voteLimit, idempotent)
+	dishes.Handle(GET, "/tally", dv.Tallies)
+	dishes.Handle(GET, "/winner", dv.Winners)
+
+	// Register the weights allowing users to cast several votes at once and
+	// the delegation of votes to another user.
+	vw := VoteWeight{
+		db: cfg.DB,
+	}
+	admin.Handle(GET, "/votes/weights", vw.List)
+	admin.Handle(POST, "/votes/weights", vw.Create)
+	admin.Handle(DELETE, "/votes/weights/:id<uuid>", vw.Delete)
+	authed.Handle(PUT, "/users/me/delegate", vw.Delegate)
+	authed.Handle(DELETE, "/users/me/delegate", vw.Undelegate)
+
+	// Register the dashboard of the restaurant owners and the ratings it
+	// shows.
+	st := Stats{
+		restaurants: stores.Restaurants,
+		store:       statsStore,
+	}
+	restaurants.Handle(GET, "/:id<uuid>/stats", st.Restaurant)
+	restaurants.Handle(PUT, "/:id<uuid>/rating", st.Rate)
+
+	// Register the change history of the restaurants and their menus.
+	au := Audit{
+		db:          cfg.DB,
+		restaurants: stores.Restaurants,
+	}
+	restaurants.Handle(GET, "/:id<uuid>/audit", au.List)
+
+	// Register reporting endpoints for the office managers.
+	an := Analytics{
+		db: db.Replica(),
+	}
+	admin.Handle(GET, "/analytics/votes", an.Votes)
+
+	rp := Report{
+		db: db.Replica(),
+	}
+	admin.Handle(GET, "/reports/monthly", rp.Monthly)
+
+	// Register pre-order endpoints.
+	o := Order{
+		db:          cfg.DB,
+		restaurants: stores.Restaurants,
+		policy:      cfg.OrderPolicy,
+	}
+	restaurants.Handle(POST, "/:restaurantId<uuid>/menu/:menuId<uuid>/orders", o.Place, idempotent)
+	restaurants.Handle(GET, "/:restaurantId<uuid>/orders", o.List)
+	authed.Handle(POST, "/orders/:id<uuid>/cancel", o.Cancel)
+	authed.Handle(PUT, "/orders/:id<uuid>/status", o.UpdateStatus)
+
+	// Register the discussion of the menus.
+	mc := MenuComment{
+		db:          cfg.DB,
+		restaurants: stores.Restaurants,
+		menus:       stores.Menus,
+	}
+	restaurants.Handle(GET, "/:restaurantId<uuid>/menu/:menuId<uuid>/comments", mc.List)
+	restaurants.Handle(POST, "/:restaurantId<uuid>/menu/:menuId<uuid>/comments", mc.Create, idempotent)
+	restaurants.Handle(DELETE, "/:restaurantId<uuid>/menu/:menuId<uuid>/comments/:id<uuid>", mc.Delete)
+
+	// Register coupon endpoints.
+	cp := Coupon{
+		db:          cfg.DB,
+		restaurants: stores.Restaurants,
+	}
+	restaurants.Handle(GET, "/:restaurantId<uuid>/coupons", cp.List)
+	restaurants.Handle(POST, "/:restaurantId<uuid>/coupons", cp.Create, idempotent)
+	restaurants.Handle(GET, "/:restaurantId<uuid>/coupons/:id<uuid>", cp.Retrieve)
+	restaurants.Handle(DELETE, "/:restaurantId<uuid>/coupons/:id<uuid>", cp.Delete)
+	restaurants.Handle(GET, "/:restaurantId<uuid>/coupons/:id<uuid>/redemptions", cp.Redemptions)
+
+	// Register in-app notification endpoints.
+	nt := Notification{
+		db: cfg.DB,
+	}
+	authed.Handle(GET, "/users/me/notifications", nt.List)
+	authed.Handle(POST, "/users/me/notifications/read", nt.MarkAllRead)
+	authed.Handle(POST, "/users/me/notifications/:id<uuid>/read", nt.MarkRead)
+	authed.Handle(GET, "/users/me/preferences", nt.Preferences)
+	authed.Handle(PUT, "/users/me/preferences", nt.UpdatePreferences)
+
+	// Register Telegram bot endpoints.
+	tgm := Telegram{
+		db: cfg.DB,
+	}
+	authed.Handle(POST, "/users/me/telegram/code", tgm.CreateCode)
+
+	// Register the calendar feed. Calendar apps subscribe with a token in the
+	// URL as they cannot authenticate like the other clients.
+	cal := Calendar{
+		db: cfg.DB,
+	}
+	authed.Handle(POST, "/users/me/calendar/token", cal.CreateToken)
+	api.Handle(GET, "/calendar.ics", cal.Feed, publicLimit)
+
+	// Register release notes endpoints.
+	cl := Changelog{
+		db: cfg.DB,
+	}
+	authed.Handle(GET, "/changelog", cl.List)
+	admin.Handle(POST, "/changelog", cl.Create)
+	admin.Handle(PUT, "/changelog/:id<uuid>", cl.Update)
+	admin.Handle(DELETE, "/changelog/:id<uuid>", cl.Delete)
+	authed.Handle(POST, "/changelog/seen", cl.Seen)
+
+	// Register emergency broadcast endpoints. The in-app inbox is always
+	// available in addition to the stream and the configured channels.
+	bcChannels := []string{broadcast.Inbox{}.Name()}
+	if cfg.BroadcastStream != nil {
+		bcChannels = append(bcChannels, broadcast.ChannelStream)
+	}
+	for _, ch := range cfg.BroadcastChannels {
+		bcChannels = append(bcChannels, ch.Name())
+	}
+	bc := Broadcast{
+		db:       cfg.DB,
+		channels: bcChannels,
+		stream:   cfg.BroadcastStream,
+	}
+	admin.Handle(POST, "/admin/broadcast", bc.Create)
+	admin.Handle(GET, "/admin/broadcast/:id<uuid>", bc.Report)
+	authed.Handle(GET, "/broadcast", bc.Inbox)
+	authed.Handle(GET, "/broadcast/stream", bc.Stream, stream)
+	authed.Handle(POST, "/broadcast/:id<uuid>/confirm", bc.Confirm)
+
+	// Register the devices receiving the broadcasts as push notifications.
+	pd := PushDevice{
+		db: cfg.DB,
+	}
+	authed.Handle(POST, "/push/devices", pd.Register)
+	authed.Handle(DELETE, "/push/devices/:token", pd.Unregister)
+
+	// Register webhook subscription endpoints.
+	wh := Webhook{
+		db: cfg.DB,
+	}
+	admin.Handle(POST, "/admin/webhooks", wh.Create)
+	admin.Handle(GET, "/admin/webhooks", wh.List)
+	admin.Handle(DELETE, "/admin/webhooks/:id<uuid>", wh.Delete)
+	admin.Handle(GET, "/admin/webhooks/:id<uuid>/deliveries", wh.Deliveries)
+
+	// Register the data retention endpoint.
+	rt := Retention{
+		db:       cfg.DB,
+		policies: cfg.RetentionPolicies,
+	}
+	admin.Handle(GET, "/admin/retention", rt.DryRun)
+
+	// Register the background jobs endpoint.
+	jb := Jobs{
+		db:      cfg.DB,
+		jobs:    cfg.Jobs,
+		elected: cfg.Driver != "sqlite",
+	}
+	admin.Handle(GET, "/admin/jobs", jb.List)
+
+	// Register unauthenticated endpoints for public restaurants. They are
+	// limited per IP address since anyone may call them.
+	p := Public{
+		db:    db.Replica(),
+		views: statsStore,
+	}
+	api.Handle(GET, "/public/restaurant/:id<uuid>/jsonld", p.JSONLD, publicLimit)
+	api.Handle(GET, "/public/restaurant/:id<uuid>/menu/today", p.TodayMenu, publicLimit)
+
 	return app
 }
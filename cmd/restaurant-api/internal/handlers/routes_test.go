@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// TestAPIDriver validates the routes backed by the PostgreSQL schema only
+// are not served from SQLite.
+func TestAPIDriver(t *testing.T) {
+	tt := []struct {
+		driver string
+		path   string
+		status int
+	}{
+		{"postgres", "/v1/organization", http.StatusUnauthorized},
+		{"postgres", "/v1/broadcast", http.StatusUnauthorized},
+		{"sqlite", "/v1/organization", http.StatusNotFound},
+		{"sqlite", "/v1/flags", http.StatusNotFound},
+		{"sqlite", "/v1/teams", http.StatusNotFound},
+		{"sqlite", "/v1/broadcast", http.StatusNotFound},
+		{"sqlite", "/v1/restaurant", http.StatusUnauthorized},
+		{"sqlite", "/v1/votes/tally", http.StatusUnauthorized},
+	}
+
+	t.Log("Given the need to serve the routes the database supports.")
+	{
+		for i, tc := range tt {
+			t.Logf("\tTest %d:\tWhen requesting %s from %s.", i, tc.path, tc.driver)
+			{
+				app := API(APIConfig{
+					Shutdown: make(chan os.Signal, 1),
+					Log:      log.New(ioutil.Discard, "", 0),
+					Driver:   tc.driver,
+				})
+
+				w := httptest.NewRecorder()
+				app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+				if w.Code != tc.status {
+					t.Fatalf("\t%s\tShould receive a status code of %d : got %d.", tests.Failed, tc.status, w.Code)
+				}
+				t.Logf("\t%s\tShould receive a status code of %d.", tests.Success, tc.status)
+			}
+		}
+	}
+}
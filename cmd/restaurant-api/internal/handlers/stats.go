@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/web"
+	"github.com/remisb/restaurant/internal/restaurant"
+	"github.com/remisb/restaurant/internal/stats"
+	"go.opentelemetry.io/otel"
+)
+
+// Stats represents the restaurant dashboard API method handler set.
+type Stats struct {
+	restaurants restaurant.Store
+	store       *stats.Store
+}
+
+// Restaurant returns the dashboard of the restaurant for the dates of the
+// from and to query parameters. Only its owner and admins may see it.
+func (s *Stats) Restaurant(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Stats.Restaurant")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	id := params["id"]
+	if err := checkRestaurantOwner(ctx, s.restaurants, id); err != nil {
+		return err
+	}
+
+	from, to, err := parseDateRange(r, v.Now)
+	if err != nil {
+		return err
+	}
+
+	st, err := s.store.Restaurant(ctx, id, from, to, v.Now)
+	if err != nil {
+		return errors.Wrapf(err, "ID: %s", id)
+	}
+
+	return web.Respond(ctx, w, st, http.StatusOK)
+}
+
+// Rate sets the rating of the restaurant by the calling user.
+func (s *Stats) Rate(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Stats.Rate")
+	defer span.End()
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	id := params["id"]
+	if _, err := s.restaurants.Retrieve(ctx, id); err != nil {
+		switch err {
+		case restaurant.ErrNotFound:
+			return requestError(err, http.StatusNotFound)
+		default:
+			return errors.Wrapf(err, "ID: %s", id)
+		}
+	}
+
+	var nr stats.NewRating
+	if err := web.Decode(r, &nr); err != nil {
+		return errors.Wrap(err, "decoding rating")
+	}
+
+	if err := s.store.Rate(ctx, claims.Subject, id, nr, v.Now); err != nil {
+		return errors.Wrapf(err, "ID: %s", id)
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
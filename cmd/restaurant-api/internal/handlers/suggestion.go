@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/authz"
+	"github.com/remisb/restaurant/internal/enrichment"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/database"
+	"github.com/remisb/restaurant/internal/platform/web"
+	"github.com/remisb/restaurant/internal/restaurant"
+	"go.opentelemetry.io/otel"
+)
+
+// Suggestion represents the restaurant enrichment API method handler set.
+type Suggestion struct {
+	db       *sqlx.DB
+	enricher *enrichment.Worker
+}
+
+// List returns the suggestions external providers made for a restaurant of
+// the organization of the user.
+func (s *Suggestion) List(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Suggestion.List")
+	defer span.End()
+
+	res, err := restaurant.Retrieve(ctx, s.db, params["id"])
+	if err != nil {
+		switch err {
+		case restaurant.ErrNotFound:
+			return requestError(err, http.StatusNotFound)
+		default:
+			return errors.Wrapf(err, "ID: %s", params["id"])
+		}
+	}
+
+	suggestions, err := enrichment.List(ctx, s.db, res.ID)
+	if err != nil {
+		return errors.Wrapf(err, "ID: %s", params["id"])
+	}
+
+	return web.Respond(ctx, w, suggestions, http.StatusOK)
+}
+
+// Enrich queues the restaurant to be looked up at the configured provider.
+func (s *Suggestion) Enrich(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Suggestion.Enrich")
+	defer span.End()
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	if s.enricher == nil {
+		err := errors.New("restaurant enrichment is not enabled")
+		return requestError(err, http.StatusNotImplemented)
+	}
+
+	res, err := restaurant.Retrieve(ctx, s.db, params["id"])
+	if err != nil {
+		switch err {
+		case restaurant.ErrNotFound:
+			return requestError(err, http.StatusNotFound)
+		default:
+			return errors.Wrapf(err, "ID: %s", params["id"])
+		}
+	}
+
+	if !authz.Allowed(claims, authz.Update, authz.Resource{Kind: authz.Restaurant, Owner: res.OwnerUserID}) {
+		return requestError(restaurant.ErrForbidden, http.StatusForbidden)
+	}
+
+	// Within a batch the refresh waits for the batch to be committed, and
+	// is dropped like any other when the queue is full by then.
+	if database.InTx(ctx) {
+		database.AfterCommit(ctx, func() { s.enricher.Enqueue(res.ID) })
+	} else if !s.enricher.Enqueue(res.ID) {
+		err := errors.New("enrichment queue is full")
+		return requestError(err, http.StatusServiceUnavailable)
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// Accept applies a suggested value to the restaurant.
+func (s *Suggestion) Accept(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Suggestion.Accept")
+	defer span.End()
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	if err := enrichment.Accept(ctx, s.db, claims, params["id"], params["suggestionId"], v.Now); err != nil {
+		return suggestionError(err, params["suggestionId"])
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// Reject discards a suggested value leaving the restaurant unchanged.
+func (s *Suggestion) Reject(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Suggestion.Reject")
+	defer span.End()
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	if err := enrichment.Reject(ctx, s.db, claims, params["id"], params["suggestionId"], v.Now); err != nil {
+		return suggestionError(err, params["suggestionId"])
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// suggestionError maps the errors of deciding on a suggestion to responses.
+func suggestionError(err error, id string) error {
+	switch err {
+	case enrichment.ErrNotFound, restaurant.ErrNotFound:
+		return requestError(err, http.StatusNotFound)
+	case enrichment.ErrDecided:
+		return requestError(err, http.StatusConflict)
+	case restaurant.ErrForbidden:
+		return requestError(err, http.StatusForbidden)
+	default:
+		return errors.Wrapf(err, "Id: %s", id)
+	}
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/remisb/restaurant/internal/enrichment"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/restaurant"
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// TestSuggestion validates the errors of the enrichment endpoints map to the
+// right status codes.
+func TestSuggestion(t *testing.T) {
+	tt := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"a missing suggestion", enrichment.ErrNotFound, http.StatusNotFound},
+		{"a missing restaurant", restaurant.ErrNotFound, http.StatusNotFound},
+		{"a decided suggestion", enrichment.ErrDecided, http.StatusConflict},
+		{"another owner", restaurant.ErrForbidden, http.StatusForbidden},
+		{"a failure", errors.New("db down"), http.StatusInternalServerError},
+	}
+
+	t.Log("Given the need to decide on the suggestions of external providers.")
+	{
+		for i, tc := range tt {
+			t.Logf("\tTest %d:\tWhen deciding fails with %s.", i, tc.name)
+			{
+				h := func(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+					return suggestionError(tc.err, "a2b0639f-2cc6-44b8-b97b-15d69dbb511e")
+				}
+
+				w := serve(h, http.MethodPost, "", nil, userClaims(ownerID, auth.RoleUser))
+				if w.Code != tc.status {
+					t.Fatalf("\t%s\tShould receive a status code of %d : got %d.", tests.Failed, tc.status, w.Code)
+				}
+				t.Logf("\t%s\tShould receive a status code of %d.", tests.Success, tc.status)
+			}
+		}
+
+		t.Logf("\tTest %d:\tWhen enrichment is not enabled.", len(tt))
+		{
+			s := Suggestion{}
+
+			w := serve(s.Enrich, http.MethodPost, "", map[string]string{"id": "a2b0639f-2cc6-44b8-b97b-15d69dbb511e"}, userClaims(ownerID, auth.RoleUser))
+			if w.Code != http.StatusNotImplemented {
+				t.Fatalf("\t%s\tShould receive a status code of 501 : got %d.", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould receive a status code of 501.", tests.Success)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/web"
+	"github.com/remisb/restaurant/internal/restaurant"
+	"github.com/remisb/restaurant/internal/tag"
+	"go.opentelemetry.io/otel"
+)
+
+// Tag represents the tag API method handler set. The tags are those of the
+// organization of the caller and are managed by its admins.
+type Tag struct {
+	db *sqlx.DB
+}
+
+// List returns the tags of the organization with the number of restaurants
+// having each of them.
+func (t *Tag) List(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Tag.List")
+	defer span.End()
+
+	tags, err := tag.List(ctx, t.db)
+	if err != nil {
+		return err
+	}
+
+	return web.RespondList(ctx, w, tags, http.StatusOK)
+}
+
+// Retrieve returns the tag identified by the slug in the request URL.
+func (t *Tag) Retrieve(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Tag.Retrieve")
+	defer span.End()
+
+	tg, err := tag.Retrieve(ctx, t.db, params["slug"])
+	if err != nil {
+		switch err {
+		case tag.ErrNotFound:
+			return requestError(err, http.StatusNotFound)
+		default:
+			return errors.Wrapf(err, "slug: %s", params["slug"])
+		}
+	}
+
+	return web.Respond(ctx, w, tg, http.StatusOK)
+}
+
+// Create adds a tag to the organization.
+func (t *Tag) Create(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Tag.Create")
+	defer span.End()
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var nt tag.NewTag
+	if err := web.Decode(r, &nt); err != nil {
+		return errors.Wrap(err, "decoding new tag")
+	}
+
+	tg, err := tag.Create(ctx, t.db, claims, nt, v.Now)
+	if err != nil {
+		switch err {
+		case tag.ErrInvalidSlug:
+			return requestError(err, http.StatusBadRequest)
+		case tag.ErrDuplicateSlug:
+			return requestError(err, http.StatusConflict)
+		default:
+			return errors.Wrapf(err, "creating tag: %+v", nt)
+		}
+	}
+
+	return web.Respond(ctx, w, tg, http.StatusCreated)
+}
+
+// Update modifies the tag identified by the slug in the request URL.
+func (t *Tag) Update(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Tag.Update")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var upd tag.UpdateTag
+	if err := web.Decode(r, &upd); err != nil {
+		return errors.Wrap(err, "decoding tag update")
+	}
+
+	if err := tag.Update(ctx, t.db, params["slug"], upd, v.Now); err != nil {
+		switch err {
+		case tag.ErrNotFound:
+			return requestError(err, http.StatusNotFound)
+		default:
+			return errors.Wrapf(err, "updating tag %q: %+v", params["slug"], upd)
+		}
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// Delete removes the tag identified by the slug in the request URL from the
+// organization and its restaurants.
+func (t *Tag) Delete(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Tag.Delete")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	if err := tag.Delete(ctx, t.db, params["slug"], v.Now); err != nil {
+		switch err {
+		default:
+			return errors.Wrapf(err, "slug: %s", params["slug"])
+		}
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// Assign replaces the tags of the restaurant identified in the request URL
+// and returns the tagged restaurant.
+func (t *Tag) Assign(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Tag.Assign")
+	defer span.End()
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var assigned struct {
+		Tags []string `json:"tags" validate:"required"`
+	}
+	if err := web.Decode(r, &assigned); err != nil {
+		return errors.Wrap(err, "decoding restaurant tags")
+	}
+
+	res, err := tag.Assign(ctx, t.db, claims, params["id"], assigned.Tags, v.Now)
+	if err != nil {
+		switch err {
+		case tag.ErrInvalidSlug:
+			return requestError(err, http.StatusBadRequest)
+		case restaurant.ErrNotFound, tag.ErrNotFound:
+			return requestError(err, http.StatusNotFound)
+		case restaurant.ErrForbidden:
+			return requestError(err, http.StatusForbidden)
+		case restaurant.ErrVersionConflict:
+			return requestError(err, http.StatusConflict)
+		default:
+			return errors.Wrapf(err, "tagging restaurant %q: %v", params["id"], assigned.Tags)
+		}
+	}
+
+	return web.Respond(ctx, w, res, http.StatusOK)
+}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/web"
+	"github.com/remisb/restaurant/internal/team"
+	"go.opentelemetry.io/otel"
+)
+
+// Team represents the team API method handler set. The teams are those of
+// the organization of the caller and are managed by its admins.
+type Team struct {
+	db *sqlx.DB
+}
+
+// List returns the teams of the organization.
+func (t *Team) List(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Team.List")
+	defer span.End()
+
+	teams, err := team.List(ctx, t.db)
+	if err != nil {
+		return err
+	}
+
+	return web.RespondList(ctx, w, teams, http.StatusOK)
+}
+
+// Retrieve returns the team identified in the request URL.
+func (t *Team) Retrieve(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Team.Retrieve")
+	defer span.End()
+
+	tm, err := team.Retrieve(ctx, t.db, params["id"])
+	if err != nil {
+		switch err {
+		case team.ErrNotFound:
+			return requestError(err, http.StatusNotFound)
+		default:
+			return errors.Wrapf(err, "ID: %s", params["id"])
+		}
+	}
+
+	return web.Respond(ctx, w, tm, http.StatusOK)
+}
+
+// Create adds a team to the organization.
+func (t *Team) Create(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Team.Create")
+	defer span.End()
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var nt team.NewTeam
+	if err := web.Decode(r, &nt); err != nil {
+		return errors.Wrap(err, "decoding new team")
+	}
+
+	tm, err := team.Create(ctx, t.db, claims, nt, v.Now)
+	if err != nil {
+		return errors.Wrapf(err, "creating team: %+v", nt)
+	}
+
+	return web.Respond(ctx, w, tm, http.StatusCreated)
+}
+
+// Update modifies the team identified in the request URL.
+func (t *Team) Update(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Team.Update")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var upd team.UpdateTeam
+	if err := web.Decode(r, &upd); err != nil {
+		return errors.Wrap(err, "decoding team update")
+	}
+
+	if err := team.Update(ctx, t.db, params["id"], upd, v.Now); err != nil {
+		switch err {
+		case team.ErrNotFound:
+			return requestError(err, http.StatusNotFound)
+		default:
+			return errors.Wrapf(err, "updating team %q: %+v", params["id"], upd)
+		}
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// Delete removes the team identified in the request URL.
+func (t *Team) Delete(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Team.Delete")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	if err := team.Delete(ctx, t.db, params["id"], v.Now); err != nil {
+		switch err {
+		default:
+			return errors.Wrapf(err, "Id: %s", params["id"])
+		}
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// Members returns the members of the team identified in the request URL.
+func (t *Team) Members(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Team.Members")
+	defer span.End()
+
+	members, err := team.Members(ctx, t.db, params["id"])
+	if err != nil {
+		switch err {
+		case team.ErrNotFound:
+			return requestError(err, http.StatusNotFound)
+		default:
+			return errors.Wrapf(err, "ID: %s", params["id"])
+		}
+	}
+
+	return web.RespondList(ctx, w, members, http.StatusOK)
+}
+
+// AddMember adds a user of the organization to the team.
+func (t *Team) AddMember(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Team.AddMember")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	if err := team.AddMember(ctx, t.db, params["id"], params["userId"], v.Now); err != nil {
+		switch err {
+		case team.ErrNotFound, team.ErrUserNotFound:
+			return requestError(err, http.StatusNotFound)
+		default:
+			return errors.Wrapf(err, "adding user %s to team %s", params["userId"], params["id"])
+		}
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// RemoveMember removes a user from the team.
+func (t *Team) RemoveMember(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Team.RemoveMember")
+	defer span.End()
+
+	if err := team.RemoveMember(ctx, t.db, params["id"], params["userId"]); err != nil {
+		switch err {
+		case team.ErrNotFound:
+			return requestError(err, http.StatusNotFound)
+		default:
+			return errors.Wrapf(err, "removing user %s from team %s", params["userId"], params["id"])
+		}
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
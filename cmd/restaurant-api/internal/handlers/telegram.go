@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/web"
+	"github.com/remisb/restaurant/internal/telegram"
+	"go.opentelemetry.io/otel"
+)
+
+// Telegram represents the Telegram bot API method handler set.
+type Telegram struct {
+	db *sqlx.DB
+}
+
+// CreateCode creates the code the calling user sends to the bot to link a
+// Telegram chat to their account.
+func (t *Telegram) CreateCode(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Telegram.CreateCode")
+	defer span.End()
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	code, err := telegram.CreateCode(ctx, t.db, claims.Subject, v.Now)
+	if err != nil {
+		return err
+	}
+
+	return web.Respond(ctx, w, code, http.StatusCreated)
+}
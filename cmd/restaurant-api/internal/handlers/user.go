@@ -2,39 +2,39 @@ package handlers
 
 import (
 	"context"
-	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
 	"github.com/remisb/restaurant/internal/platform/auth"
 	"github.com/remisb/restaurant/internal/platform/web"
 	"github.com/remisb/restaurant/internal/user"
-	"go.opencensus.io/trace"
+	"go.opentelemetry.io/otel"
 	"net/http"
 )
 
 // User represents the User API method handler set.
 type User struct {
-	db            *sqlx.DB
+	store         user.Store
 	authenticator *auth.Authenticator
 
 	// ADD OTHER STATE LIKE THE LOGGER AND CONFIG HERE.
 }
 
-// List returns all the existing users in the system.
+// List returns all the existing users in the system. The fields query
+// parameter lists the members of the users to send.
 func (u *User) List(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
-	ctx, span := trace.StartSpan(ctx, "handlers.User.List")
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.User.List")
 	defer span.End()
 
-	users, err := user.List(ctx, u.db)
+	users, err := u.store.List(ctx)
 	if err != nil {
 		return err
 	}
 
-	return web.Respond(ctx, w, users, http.StatusOK)
+	return web.RespondListFields(ctx, w, users, web.Fields(r), http.StatusOK)
 }
 
 // Retrieve returns the specified user from the system.
 func (u *User) Retrieve(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
-	ctx, span := trace.StartSpan(ctx, "handlers.User.Retrieve")
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.User.Retrieve")
 	defer span.End()
 
 	claims, ok := ctx.Value(auth.Key).(auth.Claims)
@@ -42,15 +42,13 @@ func (u *User) Retrieve(ctx context.Context, w http.ResponseWriter, r *http.Requ
 		return errors.New("claims missing from context")
 	}
 
-	usr, err := user.Retrieve(ctx, claims, u.db, params["id"])
+	usr, err := u.store.Retrieve(ctx, claims, params["id"])
 	if err != nil {
 		switch err {
-		case user.ErrInvalidID:
-			return web.NewRequestError(err, http.StatusBadRequest)
 		case user.ErrNotFound:
-			return web.NewRequestError(err, http.StatusNotFound)
+			return requestError(err, http.StatusNotFound)
 		case user.ErrForbidden:
-			return web.NewRequestError(err, http.StatusForbidden)
+			return requestError(err, http.StatusForbidden)
 		default:
 			return errors.Wrapf(err, "Id: %s", params["id"])
 		}
@@ -61,7 +59,7 @@ func (u *User) Retrieve(ctx context.Context, w http.ResponseWriter, r *http.Requ
 
 // Create inserts a new user into the system.
 func (u *User) Create(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
-	ctx, span := trace.StartSpan(ctx, "handlers.User.Create")
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.User.Create")
 	defer span.End()
 
 	v, ok := ctx.Value(web.KeyValues).(*web.Values)
@@ -74,7 +72,10 @@ func (u *User) Create(ctx context.Context, w http.ResponseWriter, r *http.Reques
 		return errors.Wrap(err, "")
 	}
 
-	usr, err := user.Create(ctx, u.db, nu, v.Now)
+	// Admins add users to their own organization.
+	nu.OrgID = auth.Org(ctx)
+
+	usr, err := u.store.Create(ctx, nu, v.Now)
 	if err != nil {
 		return errors.Wrapf(err, "User: %+v", &usr)
 	}
@@ -84,7 +85,7 @@ func (u *User) Create(ctx context.Context, w http.ResponseWriter, r *http.Reques
 
 // Update updates the specified user in the system.
 func (u *User) Update(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
-	ctx, span := trace.StartSpan(ctx, "handlers.User.Update")
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.User.Update")
 	defer span.End()
 
 	v, ok := ctx.Value(web.KeyValues).(*web.Values)
@@ -102,15 +103,17 @@ func (u *User) Update(ctx context.Context, w http.ResponseWriter, r *http.Reques
 		return errors.Wrap(err, "")
 	}
 
-	err := user.Update(ctx, claims, u.db, params["id"], upd, v.Now)
+	err := u.store.Update(ctx, claims, params["id"], upd, v.Now)
 	if err != nil {
 		switch err {
 		case user.ErrInvalidID:
-			return web.NewRequestError(err, http.StatusBadRequest)
+			return requestError(err, http.StatusBadRequest)
 		case user.ErrNotFound:
-			return web.NewRequestError(err, http.StatusNotFound)
+			return requestError(err, http.StatusNotFound)
 		case user.ErrForbidden:
-			return web.NewRequestError(err, http.StatusForbidden)
+			return requestError(err, http.StatusForbidden)
+		case user.ErrVersionConflict:
+			return requestError(err, http.StatusConflict)
 		default:
 			return errors.Wrapf(err, "ID: %s  User: %+v", params["id"], &upd)
 		}
@@ -121,18 +124,23 @@ func (u *User) Update(ctx context.Context, w http.ResponseWriter, r *http.Reques
 
 // Delete removes the specified user from the system.
 func (u *User) Delete(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
-	ctx, span := trace.StartSpan(ctx, "handlers.User.Delete")
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.User.Delete")
 	defer span.End()
 
-	err := user.Delete(ctx, u.db, params["id"])
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	err := u.store.Delete(ctx, params["id"], v.Now)
 	if err != nil {
 		switch err {
 		case user.ErrInvalidID:
-			return web.NewRequestError(err, http.StatusBadRequest)
+			return requestError(err, http.StatusBadRequest)
 		case user.ErrNotFound:
-			return web.NewRequestError(err, http.StatusNotFound)
+			return requestError(err, http.StatusNotFound)
 		case user.ErrForbidden:
-			return web.NewRequestError(err, http.StatusForbidden)
+			return requestError(err, http.StatusForbidden)
 		default:
 			return errors.Wrapf(err, "Id: %s", params["id"])
 		}
@@ -144,7 +152,7 @@ func (u *User) Delete(ctx context.Context, w http.ResponseWriter, r *http.Reques
 // Token handles a request to authenticate a user. It expects a request using
 // Basic Auth with a user's email and password. It responds with a JWT.
 func (u *User) Token(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
-	ctx, span := trace.StartSpan(ctx, "handlers.User.Token")
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.User.Token")
 	defer span.End()
 
 	v, ok := ctx.Value(web.KeyValues).(*web.Values)
@@ -155,14 +163,14 @@ func (u *User) Token(ctx context.Context, w http.ResponseWriter, r *http.Request
 	email, pass, ok := r.BasicAuth()
 	if !ok {
 		err := errors.New("must provide email and password in Basic auth")
-		return web.NewRequestError(err, http.StatusUnauthorized)
+		return requestError(err, http.StatusUnauthorized)
 	}
 
-	claims, err := user.Authenticate(ctx, u.db, v.Now, email, pass)
+	claims, err := u.store.Authenticate(ctx, v.Now, email, pass)
 	if err != nil {
 		switch err {
 		case user.ErrAuthenticationFailure:
-			return web.NewRequestError(err, http.StatusUnauthorized)
+			return requestError(err, http.StatusUnauthorized)
 		default:
 			return errors.Wrap(err, "authenticating")
 		}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/remisb/restaurant/internal/memstore"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/tests"
+	"github.com/remisb/restaurant/internal/user"
+)
+
+// TestUserToken validates tokens are only issued for valid credentials.
+func TestUserToken(t *testing.T) {
+	store := memstore.NewUsers()
+	nu := user.NewUser{Name: "Ann", Email: "ann@example.com", Roles: []string{auth.RoleUser}, Password: "gophers"}
+	if _, err := store.Create(context.Background(), nu, now); err != nil {
+		t.Fatal(err)
+	}
+
+	tt := []struct {
+		name     string
+		email    string
+		password string
+		status   int
+	}{
+		{"no credentials", "", "", http.StatusUnauthorized},
+		{"wrong password", "ann@example.com", "cats", http.StatusUnauthorized},
+		{"unknown email", "bob@example.com", "gophers", http.StatusUnauthorized},
+	}
+
+	t.Log("Given the need to issue tokens.")
+	{
+		for i, tc := range tt {
+			t.Logf("\tTest %d:\tWhen handling %s.", i, tc.name)
+			{
+				u := User{store: store}
+
+				r := httptest.NewRequest(http.MethodGet, "/", nil)
+				if tc.email != "" {
+					r.SetBasicAuth(tc.email, tc.password)
+				}
+
+				w := serveRequest(u.Token, r, nil, auth.Claims{})
+				if w.Code != tc.status {
+					t.Fatalf("\t%s\tShould receive a status code of %d : got %d : %s", tests.Failed, tc.status, w.Code, w.Body)
+				}
+				t.Logf("\t%s\tShould receive a status code of %d.", tests.Success, tc.status)
+			}
+		}
+	}
+}
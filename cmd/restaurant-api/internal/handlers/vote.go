@@ -0,0 +1,389 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/coalesce"
+	"github.com/remisb/restaurant/internal/platform/database"
+	"github.com/remisb/restaurant/internal/platform/web"
+	"github.com/remisb/restaurant/internal/restaurant"
+	"github.com/remisb/restaurant/internal/team"
+	"github.com/remisb/restaurant/internal/vote"
+	"go.opentelemetry.io/otel"
+)
+
+// keepAlive is how often a comment is sent on an idle vote stream so proxies
+// do not close the connection.
+const keepAlive = 15 * time.Second
+
+// Vote represents the lunch voting API method handler set.
+type Vote struct {
+	store       vote.Store
+	restaurants restaurant.Store
+	policy      vote.Policy
+	hub         *vote.Hub
+	keepAlive   time.Duration
+}
+
+// Cast records the caller's vote. The body may name a future date to plan
+// a lunch ahead, otherwise the vote is for today.
+func (vt *Vote) Cast(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Vote.Cast")
+	defer span.End()
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var nv vote.NewVote
+	if err := web.Decode(r, &nv); err != nil {
+		return errors.Wrap(err, "decoding new vote")
+	}
+
+	cast, err := vt.store.Cast(ctx, claims, nv, vt.policy, v.Now)
+	if err != nil {
+		switch err {
+		case vote.ErrInvalidDate, restaurant.ErrInvalidID:
+			return requestError(err, http.StatusBadRequest)
+		case restaurant.ErrNotFound:
+			return requestError(err, http.StatusNotFound)
+		case vote.ErrClosed, vote.ErrTooEarly, vote.ErrWrongMode:
+			return requestError(err, http.StatusConflict)
+		case vote.ErrLocked, vote.ErrWeightExceeded, vote.ErrNotDelegated:
+			return requestError(err, http.StatusForbidden)
+		default:
+			return errors.Wrapf(err, "casting vote: %+v", nv)
+		}
+	}
+
+	database.AfterCommit(ctx, func() { vt.hub.Notify(cast.Date) })
+
+	return web.Respond(ctx, w, cast, http.StatusCreated)
+}
+
+// Retract removes the caller's vote for today. Votes can be retracted, or
+// changed by casting another one, until the deadline.
+func (vt *Vote) Retract(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Vote.Retract")
+	defer span.End()
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	date, err := vt.date(ctx, "", v.Now)
+	if err != nil {
+		return err
+	}
+
+	if err := vt.store.Retract(ctx, claims, date, vt.policy, v.Now); err != nil {
+		switch err {
+		case vote.ErrNotVoted:
+			return requestError(err, http.StatusNotFound)
+		case vote.ErrLocked:
+			return requestError(err, http.StatusForbidden)
+		default:
+			return errors.Wrapf(err, "retracting vote of %s", claims.Subject)
+		}
+	}
+
+	database.AfterCommit(ctx, func() { vt.hub.Notify(date) })
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// Tallies returns the vote counts for the date query parameter or today,
+// only counting the votes of the members of the team query parameter when
+// it is set.
+func (vt *Vote) Tallies(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Vote.Tallies")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	date, err := vt.date(ctx, r.URL.Query().Get("date"), v.Now)
+	if err != nil {
+		return err
+	}
+
+	var tallies []vote.Tally
+	if id := r.URL.Query().Get("team"); id != "" {
+		tallies, err = vt.store.TeamTallies(ctx, id, date)
+	} else {
+		tallies, err = vt.store.Tallies(ctx, date)
+	}
+	if err != nil {
+		switch err {
+		case team.ErrInvalidID:
+			return requestError(err, http.StatusBadRequest)
+		case team.ErrNotFound:
+			return requestError(err, http.StatusNotFound)
+		default:
+			return err
+		}
+	}
+
+	return web.RespondList(ctx, w, tallies, http.StatusOK)
+}
+
+// Winner returns the winner of the date query parameter or today, the
+// winner of the team query parameter when it is set.
+func (vt *Vote) Winner(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Vote.Winner")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	date, err := vt.date(ctx, r.URL.Query().Get("date"), v.Now)
+	if err != nil {
+		return err
+	}
+
+	var winner *vote.Winner
+	if id := r.URL.Query().Get("team"); id != "" {
+		winner, err = vt.store.RetrieveTeamWinner(ctx, id, date)
+	} else {
+		winner, err = vt.store.RetrieveWinner(ctx, date)
+	}
+	if err != nil {
+		switch err {
+		case team.ErrInvalidID:
+			return requestError(err, http.StatusBadRequest)
+		case vote.ErrNoWinner, team.ErrNotFound:
+			return requestError(err, http.StatusNotFound)
+		default:
+			return errors.Wrapf(err, "date: %s", date.Format("2006-01-02"))
+		}
+	}
+
+	return web.Respond(ctx, w, winner, http.StatusOK)
+}
+
+// maxHistory is the longest range of dates the vote history covers at once.
+const maxHistory = 366 * 24 * time.Hour
+
+// History returns the votes cast for the dates of the from and to query
+// parameters, by default those of the last 30 days.
+func (vt *Vote) History(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Vote.History")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	today, err := vt.date(ctx, "", v.Now)
+	if err != nil {
+		return err
+	}
+
+	from, to, err := parseDateRange(r, today)
+	if err != nil {
+		return err
+	}
+
+	if web.WantsCSV(r) {
+		return exportVotes(ctx, w, vt.store, from, to)
+	}
+
+	votes := []vote.Vote{}
+	err = vt.store.History(ctx, from, to, func(vo vote.Vote) error {
+		votes = append(votes, vo)
+		return nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "from %s to %s", from.Format("2006-01-02"), to.Format("2006-01-02"))
+	}
+
+	return web.Respond(ctx, w, votes, http.StatusOK)
+}
+
+// date returns the date of the query parameter or, when it is blank, today in
+// the time zone of the organization of the caller. Votes are cast for the
+// dates of that time zone so it is also the one they are read in.
+func (vt *Vote) date(ctx context.Context, query string, now time.Time) (time.Time, error) {
+	if query != "" {
+		date, err := vote.ParseDate(query, now)
+		if err != nil {
+			return time.Time{}, requestError(err, http.StatusBadRequest)
+		}
+		return date, nil
+	}
+
+	today, err := vt.store.Today(ctx, vt.policy, now)
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "resolving today")
+	}
+	return today, nil
+}
+
+// parseDateRange returns the dates of the from and to query parameters. They
+// default to the 30 days up to today and may be up to maxHistory apart.
+func parseDateRange(r *http.Request, now time.Time) (time.Time, time.Time, error) {
+	to, err := vote.ParseDate(r.URL.Query().Get("to"), now)
+	if err != nil {
+		return time.Time{}, time.Time{}, requestError(err, http.StatusBadRequest)
+	}
+	from := to.AddDate(0, 0, -30)
+	if s := r.URL.Query().Get("from"); s != "" {
+		if from, err = vote.ParseDate(s, now); err != nil {
+			return time.Time{}, time.Time{}, requestError(err, http.StatusBadRequest)
+		}
+	}
+
+	switch {
+	case to.Before(from):
+		err := errors.New("from must not be after to")
+		return time.Time{}, time.Time{}, requestError(err, http.StatusBadRequest)
+	case to.Sub(from) > maxHistory:
+		err := errors.New("from and to must not be more than a year apart")
+		return time.Time{}, time.Time{}, requestError(err, http.StatusBadRequest)
+	}
+
+	return from, to, nil
+}
+
+// Stream sends the vote count of the restaurant for the date query parameter
+// or today as server-sent events. The current count is sent right away and
+// again whenever a vote changes it. Only the restaurants of the organization
+// of the caller can be streamed.
+//
+// The stream is not bound by the request timeout of the application. It ends
+// when the client disconnects or the service shuts down, so a WriteTimeout on
+// the server cuts it short.
+func (vt *Vote) Stream(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Vote.Stream")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	restaurantID := params["restaurantId"]
+
+	date, err := vt.date(ctx, r.URL.Query().Get("date"), v.Now)
+	if err != nil {
+		return err
+	}
+
+	if _, err := vt.restaurants.Retrieve(ctx, restaurantID); err != nil {
+		switch err {
+		case restaurant.ErrNotFound:
+			return requestError(err, http.StatusNotFound)
+		default:
+			return errors.Wrapf(err, "ID: %s", restaurantID)
+		}
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if vt.hub == nil || !ok {
+		err := errors.New("vote streams are not supported")
+		return requestError(err, http.StatusNotImplemented)
+	}
+
+	// Subscribe before reading the first count so no vote falls in between.
+	changed, unsubscribe := vt.hub.Subscribe(date)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	v.StatusCode = http.StatusOK
+
+	// The counts are read with the claims of ctx, which scope the stores to
+	// the organization of the caller, until the client goes away. Unlike ctx
+	// the stream carries no request deadline.
+	conn, cancel := streamContext(ctx, r)
+	defer cancel()
+
+	interval := vt.keepAlive
+	if interval <= 0 {
+		interval = keepAlive
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := -1
+	for {
+		t, err := vt.store.RetrieveTally(conn, restaurantID, date)
+		if err != nil {
+			if conn.Err() != nil {
+				return nil
+			}
+			return errors.Wrapf(err, "counting votes of %s", date.Format("2006-01-02"))
+		}
+
+		if t.Votes != last {
+			data, err := json.Marshal(t)
+			if err != nil {
+				return errors.Wrap(err, "encoding tally")
+			}
+			if _, err := fmt.Fprintf(w, "event: tally\ndata: %s\n\n", data); err != nil {
+				return nil
+			}
+			flusher.Flush()
+			last = t.Votes
+		}
+
+	wait:
+		for {
+			select {
+			case <-conn.Done():
+				return nil
+			case _, ok := <-changed:
+				if !ok {
+					return nil
+				}
+				break wait
+			case <-ticker.C:
+				if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+					return nil
+				}
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+// streamContext returns a context carrying the values of ctx, like the claims
+// of the caller, which is canceled when the client of the request goes away
+// or cancel is called but not when the deadline of ctx passes.
+func streamContext(ctx context.Context, r *http.Request) (context.Context, context.CancelFunc) {
+	conn, cancel := context.WithCancel(coalesce.Detach(ctx))
+	go func() {
+		select {
+		case <-r.Context().Done():
+			cancel()
+		case <-conn.Done():
+		}
+	}()
+	return conn, cancel
+}
@@ -0,0 +1,408 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/remisb/restaurant/internal/memstore"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/web"
+	"github.com/remisb/restaurant/internal/restaurant"
+	"github.com/remisb/restaurant/internal/tests"
+	"github.com/remisb/restaurant/internal/vote"
+)
+
+// votedID is the restaurant the tests vote for.
+const votedID = "a2b0639f-2cc6-44b8-b97b-15d69dbb511e"
+
+// votePolicy keeps voting open for the day of now.
+var votePolicy = vote.Policy{MaxDaysAhead: 7, Deadline: 11 * time.Hour}
+
+// newVotes constructs a Votes store knowing the restaurant voted for.
+func newVotes() *memstore.Votes {
+	return memstore.NewVotes(memstore.NewRestaurants(restaurant.Restaurant{ID: votedID}))
+}
+
+// TestVoteErrors validates the vote handlers map store errors to the right
+// status codes.
+func TestVoteErrors(t *testing.T) {
+	const body = `{"restaurant_id":"` + votedID + `"}`
+
+	tt := []struct {
+		name   string
+		winner bool
+		body   string
+		query  string
+		err    error
+		status int
+	}{
+		{"cast", false, body, "", nil, http.StatusCreated},
+		{"cast invalid body", false, `{"restaurant_id":"abc"}`, "", nil, http.StatusBadRequest},
+		{"cast invalid date", false, `{"restaurant_id":"a2b0639f-2cc6-44b8-b97b-15d69dbb511e","date":"monday"}`, "", nil, http.StatusBadRequest},
+		{"cast missing restaurant", false, body, "", restaurant.ErrNotFound, http.StatusNotFound},
+		{"cast unknown restaurant", false, `{"restaurant_id":"ad1b9a26-e1a1-4e4c-9a1b-3a2b5f6f0d3b"}`, "", nil, http.StatusNotFound},
+		{"cast past the deadline", false, `{"restaurant_id":"` + votedID + `","date":"2020-03-01"}`, "", nil, http.StatusConflict},
+		{"cast closed", false, body, "", vote.ErrClosed, http.StatusConflict},
+		{"cast too early", false, body, "", vote.ErrTooEarly, http.StatusConflict},
+		{"cast failure", false, body, "", errors.New("db down"), http.StatusInternalServerError},
+		{"winner missing", true, "", "", nil, http.StatusNotFound},
+		{"winner invalid date", true, "", "?date=monday", nil, http.StatusBadRequest},
+		{"winner failure", true, "", "", errors.New("db down"), http.StatusInternalServerError},
+	}
+
+	t.Log("Given the need to map vote store errors to status codes.")
+	{
+		for i, tc := range tt {
+			t.Logf("\tTest %d:\tWhen handling %s.", i, tc.name)
+			{
+				store := newVotes()
+				vt := Vote{store: store, policy: votePolicy}
+
+				h, method := vt.Cast, http.MethodPost
+				if tc.winner {
+					h, method = vt.Winner, http.MethodGet
+					store.Errs["RetrieveWinner"] = tc.err
+				} else {
+					store.Errs["Cast"] = tc.err
+				}
+
+				w := serveQuery(h, method, tc.query, tc.body, userClaims(ownerID, auth.RoleUser))
+				if w.Code != tc.status {
+					t.Fatalf("\t%s\tShould receive a status code of %d : got %d : %s", tests.Failed, tc.status, w.Code, w.Body)
+				}
+				t.Logf("\t%s\tShould receive a status code of %d.", tests.Success, tc.status)
+			}
+		}
+	}
+}
+
+// TestVoteTeams validates the tally and the winner of a team are returned
+// when the team query parameter is set.
+func TestVoteTeams(t *testing.T) {
+	const teamID = "5cf37266-3473-4006-984f-9325122678b7"
+	const body = `{"restaurant_id":"` + votedID + `"}`
+
+	store := newVotes()
+	store.SetTeam(teamID, ownerID)
+	vt := Vote{store: store, policy: votePolicy}
+
+	t.Log("Given the need to count the votes and get the winner of a team.")
+	{
+		for _, id := range []string{ownerID, otherID} {
+			if w := serveQuery(vt.Cast, http.MethodPost, "", body, userClaims(id, auth.RoleUser)); w.Code != http.StatusCreated {
+				t.Fatalf("\t%s\tShould cast the vote of %s : got %d : %s", tests.Failed, id, w.Code, w.Body)
+			}
+		}
+
+		t.Log("\tTest 0:\tWhen counting the votes of the team.")
+		{
+			w := serveQuery(vt.Tallies, http.MethodGet, "?team="+teamID, "", userClaims(ownerID, auth.RoleUser))
+			var tallies []vote.Tally
+			if err := json.NewDecoder(w.Body).Decode(&tallies); err != nil {
+				t.Fatalf("\t%s\tShould decode the tallies : %s.", tests.Failed, err)
+			}
+			if len(tallies) != 1 || tallies[0].Votes != 1 {
+				t.Fatalf("\t%s\tShould only count the vote of the member : got %+v", tests.Failed, tallies)
+			}
+			t.Logf("\t%s\tShould only count the vote of the member.", tests.Success)
+		}
+
+		t.Log("\tTest 1:\tWhen getting the winner of the team.")
+		{
+			if w := serveQuery(vt.Winner, http.MethodGet, "?team="+teamID, "", userClaims(ownerID, auth.RoleUser)); w.Code != http.StatusNotFound {
+				t.Fatalf("\t%s\tShould receive a status code of 404 before it is computed : got %d", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould receive a status code of 404 before it is computed.", tests.Success)
+
+			store.SetTeamWinner(vote.Winner{Date: now.Truncate(24 * time.Hour), TeamID: teamID, RestaurantID: votedID, Votes: 1})
+			w := serveQuery(vt.Winner, http.MethodGet, "?team="+teamID, "", userClaims(ownerID, auth.RoleUser))
+			var winner vote.Winner
+			if err := json.NewDecoder(w.Body).Decode(&winner); err != nil {
+				t.Fatalf("\t%s\tShould decode the winner : %s.", tests.Failed, err)
+			}
+			if winner.TeamID != teamID || winner.RestaurantID != votedID {
+				t.Fatalf("\t%s\tShould receive the winner of the team : got %+v", tests.Failed, winner)
+			}
+			t.Logf("\t%s\tShould receive the winner of the team.", tests.Success)
+		}
+
+		t.Log("\tTest 2:\tWhen asking for an unknown team.")
+		{
+			const unknown = "ad1b9a26-e1a1-4e4c-9a1b-3a2b5f6f0d3b"
+			for _, h := range []web.Handler{vt.Tallies, vt.Winner} {
+				if w := serveQuery(h, http.MethodGet, "?team="+unknown, "", userClaims(ownerID, auth.RoleUser)); w.Code != http.StatusNotFound {
+					t.Fatalf("\t%s\tShould receive a status code of 404 : got %d", tests.Failed, w.Code)
+				}
+			}
+			t.Logf("\t%s\tShould receive a status code of 404.", tests.Success)
+		}
+	}
+}
+
+// TestVoteRetract validates votes can be changed and retracted until the
+// deadline and are locked afterwards.
+func TestVoteRetract(t *testing.T) {
+	const body = `{"restaurant_id":"` + votedID + `"}`
+	closedPolicy := vote.Policy{MaxDaysAhead: 7, Deadline: 8 * time.Hour}
+
+	t.Log("Given the need to change my mind about lunch.")
+	{
+		t.Log("\tTest 0:\tWhen voting is open.")
+		{
+			vt := Vote{store: newVotes(), policy: votePolicy}
+
+			for i := 0; i < 2; i++ {
+				if w := serveQuery(vt.Cast, http.MethodPost, "", body, userClaims(ownerID, auth.RoleUser)); w.Code != http.StatusCreated {
+					t.Fatalf("\t%s\tShould cast and change the vote : got %d : %s", tests.Failed, w.Code, w.Body)
+				}
+			}
+			t.Logf("\t%s\tShould cast and change the vote.", tests.Success)
+
+			if w := serveQuery(vt.Retract, http.MethodDelete, "", "", userClaims(ownerID, auth.RoleUser)); w.Code != http.StatusNoContent {
+				t.Fatalf("\t%s\tShould retract the vote : got %d : %s", tests.Failed, w.Code, w.Body)
+			}
+			t.Logf("\t%s\tShould retract the vote.", tests.Success)
+
+			if w := serveQuery(vt.Retract, http.MethodDelete, "", "", userClaims(ownerID, auth.RoleUser)); w.Code != http.StatusNotFound {
+				t.Fatalf("\t%s\tShould receive a status code of 404 once retracted : got %d", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould receive a status code of 404 once retracted.", tests.Success)
+		}
+
+		t.Log("\tTest 1:\tWhen the deadline has passed.")
+		{
+			store := newVotes()
+			vt := Vote{store: store, policy: votePolicy}
+			if w := serveQuery(vt.Cast, http.MethodPost, "", body, userClaims(ownerID, auth.RoleUser)); w.Code != http.StatusCreated {
+				t.Fatalf("\t%s\tShould cast the vote : got %d : %s", tests.Failed, w.Code, w.Body)
+			}
+
+			vt.policy = closedPolicy
+			for _, h := range []struct {
+				name    string
+				handler web.Handler
+				method  string
+				body    string
+			}{
+				{"change", vt.Cast, http.MethodPost, body},
+				{"retract", vt.Retract, http.MethodDelete, ""},
+			} {
+				w := serveQuery(h.handler, h.method, "", h.body, userClaims(ownerID, auth.RoleUser))
+				if w.Code != http.StatusForbidden {
+					t.Fatalf("\t%s\tShould not %s the vote : got %d : %s", tests.Failed, h.name, w.Code, w.Body)
+				}
+				var er web.ErrorResponse
+				if err := json.NewDecoder(w.Body).Decode(&er); err != nil || er.Code != "VOTE_LOCKED" {
+					t.Fatalf("\t%s\tShould tell the vote is locked : got %q, %v.", tests.Failed, er.Code, err)
+				}
+				t.Logf("\t%s\tShould not %s the vote.", tests.Success, h.name)
+			}
+		}
+	}
+}
+
+// TestVoteWeights validates users cast several votes up to their weight and
+// vote on behalf of the users who delegated their vote to them.
+func TestVoteWeights(t *testing.T) {
+	tt := []struct {
+		name   string
+		body   string
+		status int
+		votes  int
+	}{
+		{"several votes within the weight", `{"restaurant_id":"` + votedID + `","count":3}`, http.StatusCreated, 3},
+		{"more votes than the weight", `{"restaurant_id":"` + votedID + `","count":4}`, http.StatusForbidden, 0},
+		{"a vote on behalf of a delegator", `{"restaurant_id":"` + votedID + `","on_behalf_of":"` + otherID + `"}`, http.StatusCreated, 1},
+		{"several votes on behalf of a delegator", `{"restaurant_id":"` + votedID + `","on_behalf_of":"` + otherID + `","count":2}`, http.StatusForbidden, 0},
+		{"a vote on behalf of another user", `{"restaurant_id":"` + votedID + `","on_behalf_of":"` + votedID + `"}`, http.StatusForbidden, 0},
+	}
+
+	t.Log("Given the need to weigh and delegate votes.")
+	{
+		for i, tc := range tt {
+			t.Logf("\tTest %d:\tWhen casting %s.", i, tc.name)
+			{
+				store := newVotes()
+				store.SetWeight(ownerID, 3)
+				store.SetDelegation(otherID, ownerID)
+				vt := Vote{store: store, policy: votePolicy}
+
+				w := serveQuery(vt.Cast, http.MethodPost, "", tc.body, userClaims(ownerID, auth.RoleUser))
+				if w.Code != tc.status {
+					t.Fatalf("\t%s\tShould receive a status code of %d : got %d : %s", tests.Failed, tc.status, w.Code, w.Body)
+				}
+				t.Logf("\t%s\tShould receive a status code of %d.", tests.Success, tc.status)
+
+				tally, err := store.RetrieveTally(context.Background(), votedID, now.Truncate(24*time.Hour))
+				if err != nil || tally.Votes != tc.votes {
+					t.Fatalf("\t%s\tShould count %d votes : got %+v, %v.", tests.Failed, tc.votes, tally, err)
+				}
+				t.Logf("\t%s\tShould count %d votes.", tests.Success, tc.votes)
+			}
+		}
+	}
+}
+
+// TestVoteTimeZone validates today is the date of the time zone of the
+// policy when it is still yesterday there.
+func TestVoteTimeZone(t *testing.T) {
+	const teamID = "5cf37266-3473-4006-984f-9325122678b7"
+	const body = `{"restaurant_id":"` + votedID + `"}`
+
+	// It is 23:00 of the day before now in Honolulu, before the deadline.
+	honolulu := time.FixedZone("HST", -10*60*60)
+	policy := vote.Policy{MaxDaysAhead: 7, Deadline: 23*time.Hour + 30*time.Minute, Location: honolulu}
+	yesterday := time.Date(2020, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+	store := newVotes()
+	store.SetTeam(teamID, ownerID)
+	store.SetTeamWinner(vote.Winner{Date: yesterday, TeamID: teamID, RestaurantID: votedID, Votes: 1})
+	vt := Vote{store: store, policy: policy}
+
+	t.Log("Given the need to vote in the time zone of the organization.")
+	{
+		t.Log("\tTest 0:\tWhen it is still yesterday in the time zone.")
+		{
+			w := serveQuery(vt.Cast, http.MethodPost, "", body, userClaims(ownerID, auth.RoleUser))
+			var cast vote.Vote
+			if err := json.NewDecoder(w.Body).Decode(&cast); err != nil || !cast.Date.Equal(yesterday) {
+				t.Fatalf("\t%s\tShould cast the vote for yesterday : got %d : %+v, %v", tests.Failed, w.Code, cast, err)
+			}
+			t.Logf("\t%s\tShould cast the vote for yesterday.", tests.Success)
+
+			w = serveQuery(vt.Tallies, http.MethodGet, "", "", userClaims(ownerID, auth.RoleUser))
+			var tallies []vote.Tally
+			if err := json.NewDecoder(w.Body).Decode(&tallies); err != nil || len(tallies) != 1 || tallies[0].Votes != 1 {
+				t.Fatalf("\t%s\tShould count the votes of yesterday : got %+v, %v", tests.Failed, tallies, err)
+			}
+			t.Logf("\t%s\tShould count the votes of yesterday.", tests.Success)
+
+			w = serveQuery(vt.Winner, http.MethodGet, "?team="+teamID, "", userClaims(ownerID, auth.RoleUser))
+			var winner vote.Winner
+			if err := json.NewDecoder(w.Body).Decode(&winner); err != nil || winner.RestaurantID != votedID {
+				t.Fatalf("\t%s\tShould receive the winner of yesterday : got %d : %+v, %v", tests.Failed, w.Code, winner, err)
+			}
+			t.Logf("\t%s\tShould receive the winner of yesterday.", tests.Success)
+
+			if w := serveQuery(vt.Retract, http.MethodDelete, "", "", userClaims(ownerID, auth.RoleUser)); w.Code != http.StatusNoContent {
+				t.Fatalf("\t%s\tShould retract the vote of yesterday : got %d : %s", tests.Failed, w.Code, w.Body)
+			}
+			t.Logf("\t%s\tShould retract the vote of yesterday.", tests.Success)
+		}
+	}
+}
+
+// TestVoteStream validates the vote count of a restaurant is streamed as
+// server-sent events.
+func TestVoteStream(t *testing.T) {
+	const restaurantID = votedID
+
+	t.Log("Given the need to stream the votes of a restaurant.")
+	{
+		t.Log("\tTest 0:\tWhen the stream ends with the service.")
+		{
+			restaurants := memstore.NewRestaurants(restaurant.Restaurant{ID: restaurantID})
+			hub := vote.NewHub()
+			vt := Vote{store: memstore.NewVotes(restaurants), restaurants: restaurants, policy: votePolicy, hub: hub}
+
+			body := `{"restaurant_id":"` + restaurantID + `"}`
+			if w := serve(vt.Cast, http.MethodPost, body, nil, userClaims(otherID, auth.RoleUser)); w.Code != http.StatusCreated {
+				t.Fatalf("\t%s\tShould cast a vote : got %d.", tests.Failed, w.Code)
+			}
+
+			hub.Close()
+
+			w := serve(vt.Stream, http.MethodGet, "", map[string]string{"restaurantId": restaurantID}, userClaims(ownerID, auth.RoleUser))
+			if w.Code != http.StatusOK {
+				t.Fatalf("\t%s\tShould receive a status code of 200 : got %d.", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould receive a status code of 200.", tests.Success)
+
+			if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+				t.Fatalf("\t%s\tShould be an event stream : got %q.", tests.Failed, ct)
+			}
+			t.Logf("\t%s\tShould be an event stream.", tests.Success)
+
+			want := "event: tally\ndata: {\"restaurant_id\":\"" + restaurantID + "\",\"votes\":1}\n\n"
+			if got := w.Body.String(); !strings.HasPrefix(got, want) {
+				t.Fatalf("\t%s\tShould send the current count : got %q.", tests.Failed, got)
+			}
+			t.Logf("\t%s\tShould send the current count.", tests.Success)
+		}
+
+		t.Log("\tTest 1:\tWhen streaming the votes of a restaurant of another organization.")
+		{
+			const acme = "0b1c9e0e-2f4f-4d36-9f5c-3f5f0d6c1a77"
+			restaurants := memstore.NewRestaurants(restaurant.Restaurant{ID: restaurantID, OrgID: acme})
+			hub := vote.NewHub()
+			vt := Vote{store: memstore.NewVotes(restaurants), restaurants: restaurants, policy: votePolicy, hub: hub}
+
+			theirs := userClaims(otherID, auth.RoleUser)
+			theirs.OrgID = acme
+			body := `{"restaurant_id":"` + restaurantID + `"}`
+			if w := serve(vt.Cast, http.MethodPost, body, nil, theirs); w.Code != http.StatusCreated {
+				t.Fatalf("\t%s\tShould cast a vote in the other organization : got %d.", tests.Failed, w.Code)
+			}
+
+			hub.Close()
+
+			w := serve(vt.Stream, http.MethodGet, "", map[string]string{"restaurantId": restaurantID}, userClaims(ownerID, auth.RoleUser))
+			if w.Code != http.StatusNotFound {
+				t.Fatalf("\t%s\tShould receive a status code of 404 : got %d.", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould receive a status code of 404.", tests.Success)
+
+			if strings.Contains(w.Body.String(), "event: tally") {
+				t.Fatalf("\t%s\tShould not send the count : got %q.", tests.Failed, w.Body)
+			}
+			t.Logf("\t%s\tShould not send the count.", tests.Success)
+		}
+	}
+}
+
+// TestVoteHistoryCSV validates the vote history is exported as a CSV file.
+func TestVoteHistoryCSV(t *testing.T) {
+	const restaurantID = votedID
+
+	t.Log("Given the need to export the vote history.")
+	{
+		t.Log("\tTest 0:\tWhen asking for CSV.")
+		{
+			store := newVotes()
+			vt := Vote{store: store, policy: votePolicy}
+
+			body := `{"restaurant_id":"` + restaurantID + `"}`
+			if w := serve(vt.Cast, http.MethodPost, body, nil, userClaims(otherID, auth.RoleUser)); w.Code != http.StatusCreated {
+				t.Fatalf("\t%s\tShould cast a vote : got %d.", tests.Failed, w.Code)
+			}
+
+			w := serveQuery(vt.History, http.MethodGet, "?format=csv", "", userClaims(ownerID, auth.RoleAdmin))
+			if w.Code != http.StatusOK {
+				t.Fatalf("\t%s\tShould receive a status code of 200 : got %d : %s", tests.Failed, w.Code, w.Body)
+			}
+			t.Logf("\t%s\tShould receive a status code of 200.", tests.Success)
+
+			want := "date,user_id,restaurant_id,time_voted\n2020-03-02," + otherID + "," + restaurantID + ",2020-03-02T09:00:00Z\n"
+			if got := w.Body.String(); got != want {
+				t.Fatalf("\t%s\tShould list the votes : got %q.", tests.Failed, got)
+			}
+			t.Logf("\t%s\tShould list the votes.", tests.Success)
+		}
+
+		t.Log("\tTest 1:\tWhen the range is reversed.")
+		{
+			vt := Vote{store: newVotes()}
+
+			w := serveQuery(vt.History, http.MethodGet, "?from=2020-03-02&to=2020-03-01", "", userClaims(ownerID, auth.RoleAdmin))
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("\t%s\tShould receive a status code of 400 : got %d.", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould receive a status code of 400.", tests.Success)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/web"
+	"github.com/remisb/restaurant/internal/webhook"
+	"go.opentelemetry.io/otel"
+)
+
+// maxDeliveries is the most deliveries returned by the delivery log.
+const maxDeliveries = 500
+
+// Webhook represents the webhook subscription API method handler set.
+type Webhook struct {
+	db *sqlx.DB
+}
+
+// Create registers a callback URL for the requested events. The response
+// holds the secret used to sign the deliveries; it is not shown again.
+func (wh *Webhook) Create(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Webhook.Create")
+	defer span.End()
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	var nw webhook.NewWebhook
+	if err := web.Decode(r, &nw); err != nil {
+		return errors.Wrap(err, "decoding new webhook")
+	}
+
+	hook, err := webhook.Create(ctx, wh.db, claims, nw, v.Now)
+	if err != nil {
+		switch err {
+		case webhook.ErrUnknownEvent:
+			return requestError(err, http.StatusBadRequest)
+		default:
+			return errors.Wrapf(err, "creating webhook: %s", nw.URL)
+		}
+	}
+
+	return web.Respond(ctx, w, hook, http.StatusCreated)
+}
+
+// List returns the webhooks registered in the organization of the admin.
+func (wh *Webhook) List(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Webhook.List")
+	defer span.End()
+
+	hooks, err := webhook.List(ctx, wh.db)
+	if err != nil {
+		return err
+	}
+
+	return web.Respond(ctx, w, hooks, http.StatusOK)
+}
+
+// Delete removes a webhook; pending deliveries to it are dropped.
+func (wh *Webhook) Delete(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Webhook.Delete")
+	defer span.End()
+
+	if err := webhook.Delete(ctx, wh.db, params["id"]); err != nil {
+		switch err {
+		case webhook.ErrNotFound:
+			return requestError(err, http.StatusNotFound)
+		default:
+			return errors.Wrapf(err, "ID: %s", params["id"])
+		}
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// Deliveries returns the latest deliveries of a webhook. The limit query
+// parameter caps how many are returned.
+func (wh *Webhook) Deliveries(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.Webhook.Deliveries")
+	defer span.End()
+
+	limit := 50
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxDeliveries {
+			err := errors.Errorf("limit must be a number between 1 and %d", maxDeliveries)
+			return requestError(err, http.StatusBadRequest)
+		}
+		limit = n
+	}
+
+	deliveries, err := webhook.Deliveries(ctx, wh.db, params["id"], limit)
+	if err != nil {
+		switch err {
+		case webhook.ErrNotFound:
+			return requestError(err, http.StatusNotFound)
+		default:
+			return errors.Wrapf(err, "ID: %s", params["id"])
+		}
+	}
+
+	return web.Respond(ctx, w, deliveries, http.StatusOK)
+}
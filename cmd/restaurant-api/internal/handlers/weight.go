@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/web"
+	"github.com/remisb/restaurant/internal/team"
+	"github.com/remisb/restaurant/internal/vote"
+	"go.opentelemetry.io/otel"
+)
+
+// VoteWeight represents the weighted and delegated voting API method handler
+// set.
+type VoteWeight struct {
+	db *sqlx.DB
+}
+
+// List returns the weights of the organization of the caller.
+func (vw *VoteWeight) List(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.VoteWeight.List")
+	defer span.End()
+
+	weights, err := vote.ListWeights(ctx, vw.db)
+	if err != nil {
+		return err
+	}
+
+	return web.Respond(ctx, w, weights, http.StatusOK)
+}
+
+// Create adds a weight to the organization of the caller.
+func (vw *VoteWeight) Create(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.VoteWeight.Create")
+	defer span.End()
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var nw vote.NewWeight
+	if err := web.Decode(r, &nw); err != nil {
+		return errors.Wrap(err, "decoding new vote weight")
+	}
+
+	weight, err := vote.CreateWeight(ctx, vw.db, claims, nw, v.Now)
+	if err != nil {
+		switch err {
+		case team.ErrInvalidID:
+			return requestError(err, http.StatusBadRequest)
+		case team.ErrNotFound:
+			return requestError(err, http.StatusNotFound)
+		default:
+			return errors.Wrapf(err, "creating vote weight: %+v", nw)
+		}
+	}
+
+	return web.Respond(ctx, w, weight, http.StatusCreated)
+}
+
+// Delete removes the weight identified in the request URL.
+func (vw *VoteWeight) Delete(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.VoteWeight.Delete")
+	defer span.End()
+
+	if err := vote.DeleteWeight(ctx, vw.db, params["id"]); err != nil {
+		switch err {
+		case vote.ErrWeightNotFound:
+			return requestError(err, http.StatusNotFound)
+		default:
+			return errors.Wrapf(err, "Id: %s", params["id"])
+		}
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// Delegate authorizes another user of the organization to vote on behalf of
+// the caller.
+func (vw *VoteWeight) Delegate(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.VoteWeight.Delegate")
+	defer span.End()
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	v, ok := ctx.Value(web.KeyValues).(*web.Values)
+	if !ok {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var nd vote.NewDelegation
+	if err := web.Decode(r, &nd); err != nil {
+		return errors.Wrap(err, "decoding new vote delegation")
+	}
+
+	d, err := vote.Delegate(ctx, vw.db, claims, nd, v.Now)
+	if err != nil {
+		switch err {
+		case vote.ErrInvalidDelegate:
+			return requestError(err, http.StatusBadRequest)
+		default:
+			return errors.Wrapf(err, "delegating vote of %s: %+v", claims.Subject, nd)
+		}
+	}
+
+	return web.Respond(ctx, w, d, http.StatusOK)
+}
+
+// Undelegate takes the vote of the caller back from their delegate.
+func (vw *VoteWeight) Undelegate(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "handlers.VoteWeight.Undelegate")
+	defer span.End()
+
+	claims, ok := ctx.Value(auth.Key).(auth.Claims)
+	if !ok {
+		return web.NewShutdownError("claims missing from context")
+	}
+
+	if err := vote.Undelegate(ctx, vw.db, claims); err != nil {
+		return errors.Wrapf(err, "undelegating vote of %s", claims.Subject)
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
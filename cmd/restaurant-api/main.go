@@ -3,19 +3,52 @@ package main
 import (
 	"context"
 	"crypto/rsa"
+	"crypto/tls"
 	"expvar"
 	"fmt"
 	"github.com/ardanlabs/conf"
 	"github.com/dgrijalva/jwt-go"
+	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
 	"github.com/remisb/restaurant/cmd/restaurant-api/internal/handlers"
+	"github.com/remisb/restaurant/internal/broadcast"
+	"github.com/remisb/restaurant/internal/change"
+	"github.com/remisb/restaurant/internal/enrichment"
+	"github.com/remisb/restaurant/internal/geocoding"
+	"github.com/remisb/restaurant/internal/media"
+	"github.com/remisb/restaurant/internal/mid"
+	"github.com/remisb/restaurant/internal/notification"
+	"github.com/remisb/restaurant/internal/notify/email"
+	"github.com/remisb/restaurant/internal/notify/push"
+	"github.com/remisb/restaurant/internal/notify/slack"
+	"github.com/remisb/restaurant/internal/order"
+	"github.com/remisb/restaurant/internal/outbox"
 	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/breaker"
+	"github.com/remisb/restaurant/internal/platform/clock"
+	"github.com/remisb/restaurant/internal/platform/conntrack"
 	"github.com/remisb/restaurant/internal/platform/database"
+	"github.com/remisb/restaurant/internal/platform/loglevel"
+	"github.com/remisb/restaurant/internal/platform/ratelimit"
+	"github.com/remisb/restaurant/internal/platform/s3"
+	"github.com/remisb/restaurant/internal/platform/secrets"
+	"github.com/remisb/restaurant/internal/platform/tracing"
+	"github.com/remisb/restaurant/internal/report"
+	"github.com/remisb/restaurant/internal/retention"
+	"github.com/remisb/restaurant/internal/schema"
+	"github.com/remisb/restaurant/internal/sentry"
+	"github.com/remisb/restaurant/internal/sqlite"
+	"github.com/remisb/restaurant/internal/telegram"
+	"github.com/remisb/restaurant/internal/vote"
+	"github.com/remisb/restaurant/internal/webhook"
+	"golang.org/x/crypto/acme/autocert"
 	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync/atomic"
 	"syscall"
 	"time"
 )
@@ -31,27 +64,193 @@ func main() {
 }
 
 func run() error {
-	log := log.New(os.Stdout, "SALES : ", log.LstdFlags|log.Lmicroseconds|log.Lshortfile)
+	logFilter := loglevel.NewFilter(os.Stdout, loglevel.Info)
+	log := log.New(logFilter, "SALES : ", log.LstdFlags|log.Lmicroseconds|log.Lshortfile)
 
 	var cfg struct {
+		Log struct {
+			Level string `conf:"default:info"`
+		}
 		Web struct {
-			APIHost         string
-			DebugHost       string
-			ReadTimeout     time.Duration
-			WriteTimeout    time.Duration
-			ShutdownTimeout time.Duration
+			APIHost              string
+			DebugHost            string
+			DisableDebug         bool
+			DebugShutdownTimeout time.Duration `conf:"default:5s"`
+			ReadTimeout          time.Duration
+			WriteTimeout         time.Duration
+			ShutdownTimeout      time.Duration
+			DrainDelay           time.Duration `conf:"default:0s"`
+			MaxBodySize          int64         `conf:"default:1048576"`
+			RequestTimeout       time.Duration `conf:"default:10s"`
+			LongRequestTimeout   time.Duration `conf:"default:2m"`
+			IdempotencyTTL       time.Duration `conf:"default:24h"`
+			IdempotencyLease     time.Duration `conf:"default:5m"`
+			StatsCacheTTL        time.Duration `conf:"default:1m"`
+			TLSCertFile          string
+			TLSKeyFile           string
+			AutocertHosts        []string
+			AutocertDir          string `conf:"default:/var/cache/restaurant-api/autocert"`
+			RedirectHost         string
+			StrictDelete         bool
+			NegotiateVersion     bool
 		}
 		DB struct {
+			// Driver is postgres (lib/pq), pgx or sqlite. PgxExecMode is the
+			// way pgx runs the statements, see database.Config.
+			Driver      string `conf:"default:postgres"`
+			PgxExecMode string `conf:"default:cache_statement"`
+			Path        string `conf:"default:restaurant.db"`
+
 			User       string `conf:"default:postgres"`
 			Password   string `conf:"default:postgres,noprint"`
 			Host       string `conf:"default:0.0.0.0"`
 			Name       string `conf:"default:postgres"`
 			DisableTLS bool   `conf:"default:false"`
+			ReadHost   string
+
+			MaxOpenConns    int           `conf:"default:25"`
+			MaxIdleConns    int           `conf:"default:25"`
+			ConnMaxLifetime time.Duration `conf:"default:5m"`
+			StartupTimeout  time.Duration `conf:"default:1m"`
+
+			// Automigrate applies the pending migrations at startup, one
+			// replica at a time, giving up after MigrateTimeout.
+			Automigrate    bool          `conf:"default:false"`
+			MigrateTimeout time.Duration `conf:"default:10m"`
+
+			// Statements slower than SlowQueryThreshold are logged, 0
+			// disables it.
+			SlowQueryThreshold time.Duration `conf:"default:200ms"`
+
+			// The database cancels statements running longer than
+			// StatementTimeout and the calls of the stores of the API end
+			// after QueryTimeout, 0 disables them.
+			StatementTimeout time.Duration `conf:"default:30s"`
+			QueryTimeout     time.Duration `conf:"default:5s"`
+
+			// The store calls fail fast for BreakerCooldown after as many
+			// consecutive outages as BreakerThreshold, 0 disables it.
+			BreakerThreshold int           `conf:"default:5"`
+			BreakerCooldown  time.Duration `conf:"default:10s"`
 		}
 		Auth struct {
-			KeyID          string `conf:"default:1"`
-			PrivateKeyFile string `conf:"default:/app/private.pem"`
-			Algorithm      string `conf:"default:RS256"`
+			KeyID          string        `conf:"default:1"`
+			PrivateKeyFile string        `conf:"default:/app/private.pem"`
+			Algorithm      string        `conf:"default:RS256"`
+			ClockSkew      time.Duration `conf:"default:30s"`
+		}
+		Trace struct {
+			Exporter    string  `conf:"default:none"`
+			URL         string  `conf:"default:http://jaeger:14268/api/traces"`
+			Probability float64 `conf:"default:0.05"`
+		}
+		Vote struct {
+			MaxDaysAhead   int           `conf:"default:7"`
+			Deadline       time.Duration `conf:"default:11h"`
+			WinnerInterval time.Duration `conf:"default:1m"`
+			TallyInterval  time.Duration `conf:"default:10m"`
+		}
+		Order struct {
+			Cutoff time.Duration `conf:"default:11h30m"`
+		}
+		RateLimit struct {
+			TokenRate   float64 `conf:"default:0.2"`
+			TokenBurst  int     `conf:"default:5"`
+			VoteRate    float64 `conf:"default:1"`
+			VoteBurst   int     `conf:"default:10"`
+			PublicRate  float64 `conf:"default:2"`
+			PublicBurst int     `conf:"default:20"`
+		}
+		Email struct {
+			DevMode     bool          `conf:"default:true"`
+			Host        string        `conf:"default:localhost"`
+			Port        int           `conf:"default:587"`
+			From        string        `conf:"default:lunch@example.com"`
+			QueueSize   int           `conf:"default:1000"`
+			MaxAttempts int           `conf:"default:5"`
+			Backoff     time.Duration `conf:"default:30s"`
+			Password    string        `conf:"noprint"`
+			Username    string
+		}
+		Report struct {
+			Email    bool          `conf:"default:false"`
+			Interval time.Duration `conf:"default:1h"`
+		}
+		Slack struct {
+			MenuTime   time.Duration `conf:"default:9h"`
+			Timeout    time.Duration `conf:"default:5s"`
+			WebhookURL string        `conf:"noprint"`
+		}
+		Telegram struct {
+			APIURL  string        `conf:"default:https://api.telegram.org"`
+			Timeout time.Duration `conf:"default:10s"`
+			Token   string        `conf:"noprint"`
+		}
+		Sentry struct {
+			DSN     string        `conf:"noprint"`
+			Timeout time.Duration `conf:"default:5s"`
+		}
+		Media struct {
+			Storage         string `conf:"default:disk"`
+			Dir             string `conf:"default:media"`
+			BaseURL         string
+			MaxSize         int64  `conf:"default:5242880"`
+			Endpoint        string `conf:"default:s3.amazonaws.com"`
+			Region          string `conf:"default:us-east-1"`
+			Bucket          string `conf:"default:restaurant-media"`
+			AccessKeyID     string
+			SecretAccessKey string `conf:"noprint"`
+			DisableTLS      bool   `conf:"default:false"`
+		}
+		Enrichment struct {
+			Provider  string `conf:"default:none"`
+			URL       string `conf:"default:https://maps.googleapis.com/maps/api/place"`
+			APIKey    string `conf:"noprint"`
+			QueueSize int    `conf:"default:100"`
+		}
+		Geocoding struct {
+			Provider  string `conf:"default:none"`
+			URL       string `conf:"default:https://nominatim.openstreetmap.org"`
+			UserAgent string `conf:"default:restaurant-api"`
+			QueueSize int    `conf:"default:100"`
+		}
+		Webhook struct {
+			Interval    time.Duration `conf:"default:10s"`
+			Timeout     time.Duration `conf:"default:10s"`
+			MaxAttempts int           `conf:"default:8"`
+		}
+		Broadcast struct {
+			Interval time.Duration `conf:"default:1s"`
+		}
+		Push struct {
+			URL     string
+			Timeout time.Duration `conf:"default:10s"`
+		}
+		Outbox struct {
+			Publisher string        `conf:"default:none"`
+			URL       string        `conf:"default:nats://nats:4222"`
+			Topic     string        `conf:"default:restaurant.events"`
+			Interval  time.Duration `conf:"default:1s"`
+		}
+		Retention struct {
+			Interval           time.Duration `conf:"default:24h"`
+			Votes              time.Duration `conf:"default:8760h"`
+			DeletedRestaurants time.Duration `conf:"default:720h"`
+		}
+		Secrets struct {
+			Backend            string        `conf:"default:none"`
+			RefreshInterval    time.Duration `conf:"default:5m"`
+			Timeout            time.Duration `conf:"default:10s"`
+			VaultAddr          string        `conf:"default:http://vault:8200"`
+			VaultToken         string        `conf:"noprint"`
+			VaultMount         string        `conf:"default:secret"`
+			VaultPath          string        `conf:"default:restaurant-api"`
+			AWSRegion          string        `conf:"default:us-east-1"`
+			AWSSecretID        string        `conf:"default:restaurant-api"`
+			AWSEndpoint        string
+			AWSAccessKeyID     string
+			AWSSecretAccessKey string `conf:"noprint"`
+			AWSSessionToken    string `conf:"noprint"`
 		}
 	}
 
@@ -67,6 +266,12 @@ func run() error {
 		return errors.Wrap(err, "parsing config")
 	}
 
+	logLevel, err := loglevel.Parse(cfg.Log.Level)
+	if err != nil {
+		return errors.Wrap(err, "parsing log level")
+	}
+	logFilter.SetLevel(logLevel)
+
 	// App Starting
 	expvar.NewString("build").Set(build)
 	log.Printf("main : Started : Application initializing : version %q", build)
@@ -78,12 +283,68 @@ func run() error {
 	}
 	log.Printf("main : Config :\n%v\n", out)
 
+	// Fetch Secrets
+	//
+	// The secrets found in the backend replace those of the configuration.
+	// They are refreshed periodically, new database connections using the
+	// latest password while the other secrets apply once the service
+	// restarts.
+
+	log.Printf("main : Started : Initializing secrets : %s", cfg.Secrets.Backend)
+
+	var secretSource secrets.Source
+	switch cfg.Secrets.Backend {
+	case "none":
+	case "vault":
+		secretSource = secrets.NewVault(cfg.Secrets.VaultAddr, cfg.Secrets.VaultToken, cfg.Secrets.VaultMount, cfg.Secrets.VaultPath, cfg.Secrets.Timeout)
+	case "aws":
+		secretSource = secrets.NewAWS(secrets.AWSConfig{
+			Region:          cfg.Secrets.AWSRegion,
+			SecretID:        cfg.Secrets.AWSSecretID,
+			AccessKeyID:     cfg.Secrets.AWSAccessKeyID,
+			SecretAccessKey: cfg.Secrets.AWSSecretAccessKey,
+			SessionToken:    cfg.Secrets.AWSSessionToken,
+			Endpoint:        cfg.Secrets.AWSEndpoint,
+		}, cfg.Secrets.Timeout)
+	default:
+		return errors.Errorf("unknown secrets backend %q", cfg.Secrets.Backend)
+	}
+
+	var secretStore *secrets.Store
+	var privateKeyPEM string
+	if secretSource != nil {
+		secretStore, err = secrets.NewStore(context.Background(), secretSource)
+		if err != nil {
+			return errors.Wrap(err, "fetching secrets")
+		}
+
+		cfg.DB.Password = secretStore.Get(secrets.DBPassword, cfg.DB.Password)
+		cfg.Email.Password = secretStore.Get(secrets.EmailPassword, cfg.Email.Password)
+		cfg.Slack.WebhookURL = secretStore.Get(secrets.SlackWebhookURL, cfg.Slack.WebhookURL)
+		cfg.Telegram.Token = secretStore.Get(secrets.TelegramToken, cfg.Telegram.Token)
+		cfg.Media.SecretAccessKey = secretStore.Get(secrets.MediaSecretAccessKey, cfg.Media.SecretAccessKey)
+		cfg.Enrichment.APIKey = secretStore.Get(secrets.EnrichmentAPIKey, cfg.Enrichment.APIKey)
+		privateKeyPEM = secretStore.Get(secrets.AuthPrivateKey, "")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go secretStore.Run(ctx, log, cfg.Secrets.RefreshInterval, func(names []string) {
+			for _, name := range names {
+				if name != secrets.DBPassword {
+					log.Printf("main : secret %s changed : restart to apply it", name)
+				}
+			}
+		})
+	}
+
 	// Initialize authentication support
 
 	log.Println("main : Started : Initializing authentication support")
-	keyContents, err := ioutil.ReadFile(cfg.Auth.PrivateKeyFile)
-	if err != nil {
-		return errors.Wrap(err, "reading auth private key")
+	keyContents := []byte(privateKeyPEM)
+	if len(keyContents) == 0 {
+		if keyContents, err = ioutil.ReadFile(cfg.Auth.PrivateKeyFile); err != nil {
+			return errors.Wrap(err, "reading auth private key")
+		}
 	}
 
 	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(keyContents)
@@ -96,40 +357,488 @@ func run() error {
 	if err != nil {
 		return errors.Wrap(err, "constructing authenticator")
 	}
+	authenticator.SetClockSkew(cfg.Auth.ClockSkew)
+
+	votePolicy := vote.Policy{
+		MaxDaysAhead: cfg.Vote.MaxDaysAhead,
+		Deadline:     cfg.Vote.Deadline,
+	}
+
+	// The handlers and the vote jobs all tell the time with the same clock.
+	clk := clock.System
 
 	// Start Database
+	//
+	// PostgreSQL backs every feature. SQLite only backs the restaurants,
+	// menus, users and votes for local development, the background jobs
+	// needing PostgreSQL are not started with it.
+
+	log.Printf("main . Started : Initializing database support : %s", cfg.DB.Driver)
+
+	var (
+		db         *sqlx.DB
+		readDB     *sqlx.DB
+		stores     handlers.Stores
+		listener   *database.Listener
+		bcListener *database.Listener
+	)
+
+	switch cfg.DB.Driver {
+	case database.DriverPQ, database.DriverPgx:
+		// Containers are often started before the database is up, so wait for
+		// it instead of failing right away.
+		dbCtx, dbCancel := context.WithTimeout(context.Background(), cfg.DB.StartupTimeout)
+		defer dbCancel()
+
+		dbConfig := database.Config{
+			Driver:      cfg.DB.Driver,
+			PgxExecMode: cfg.DB.PgxExecMode,
+
+			User:       cfg.DB.User,
+			Password:   cfg.DB.Password,
+			Host:       cfg.DB.Host,
+			Name:       cfg.DB.Name,
+			DisableTLS: cfg.DB.DisableTLS,
+			ReadHost:   cfg.DB.ReadHost,
+
+			MaxOpenConns:    cfg.DB.MaxOpenConns,
+			MaxIdleConns:    cfg.DB.MaxIdleConns,
+			ConnMaxLifetime: cfg.DB.ConnMaxLifetime,
 
-	log.Println("main . Started : Initializing database support")
+			StatementTimeout:   cfg.DB.StatementTimeout,
+			SlowQueryThreshold: cfg.DB.SlowQueryThreshold,
+			Log:                log,
+		}
+		if secretStore != nil {
+			dbConfig.PasswordFunc = secretStore.Func(secrets.DBPassword, cfg.DB.Password)
+		}
+
+		db, err = database.OpenAndWait(dbCtx, dbConfig)
+		if err != nil {
+			return errors.Wrap(err, "connecting to db")
+		}
+		database.PublishStats("db", db)
+		defer func() {
+			log.Printf("main : Database Stopping : %s", cfg.DB.Host)
+		}()
 
-	db, err := database.Open(database.Config{
-		User:       cfg.DB.User,
-		Password:   cfg.DB.Password,
-		Host:       cfg.DB.Host,
-		Name:       cfg.DB.Name,
-		DisableTLS: cfg.DB.DisableTLS,
+		if cfg.DB.Automigrate {
+			log.Println("main : Started : Applying pending migrations")
+			migCtx, migCancel := context.WithTimeout(context.Background(), cfg.DB.MigrateTimeout)
+			err := schema.Migrate(migCtx, db)
+			migCancel()
+			if err != nil {
+				return errors.Wrap(err, "migrating db")
+			}
+		}
+
+		// Refuse to serve a database missing migrations this binary relies
+		// on, or whose applied migrations were edited.
+		if err := schema.Validate(dbCtx, db); err != nil {
+			return errors.Wrap(err, "validating schema, apply the migrations with restaurant-admin migrate")
+		}
+
+		// The database announces the menus and votes changed by the other
+		// replicas and the jobs.
+		listener, err = database.Listen(dbConfig, log, change.Channel)
+		if err != nil {
+			return errors.Wrap(err, "listening for changes")
+		}
+		defer listener.Close()
+
+		// The broadcasts are pushed by the replica their recipients stream
+		// from, which learns about them on a connection of its own.
+		bcListener, err = database.Listen(dbConfig, log, broadcast.NotifyChannel)
+		if err != nil {
+			return errors.Wrap(err, "listening for broadcasts")
+		}
+		defer bcListener.Close()
+
+		// The listings of restaurants and menus are read from the replica when
+		// there is one.
+		if dbConfig.ReadHost != "" {
+			readDB, err = database.OpenAndWait(dbCtx, dbConfig.Replica())
+			if err != nil {
+				return errors.Wrap(err, "connecting to db replica")
+			}
+			database.PublishStats("db_replica", readDB)
+			defer func() {
+				log.Printf("main : Database Replica Stopping : %s", cfg.DB.ReadHost)
+			}()
+		}
+
+	case "sqlite":
+		if cfg.Enrichment.Provider != "none" {
+			return errors.New("restaurant enrichment needs the postgres database driver")
+		}
+		if cfg.Geocoding.Provider != "none" {
+			return errors.New("restaurant geocoding needs the postgres database driver")
+		}
+
+		db, err = sqlite.Open(cfg.DB.Path)
+		if err != nil {
+			return errors.Wrap(err, "opening sqlite database")
+		}
+		defer func() {
+			log.Printf("main : Database Stopping : %s", cfg.DB.Path)
+		}()
+
+		// The local database starts with the dev seeds so there are users to
+		// log in with.
+		if err := schema.Seed(db); err != nil {
+			return errors.Wrap(err, "seeding sqlite database")
+		}
+
+		stores = handlers.Stores{
+			Restaurants: sqlite.NewRestaurants(db),
+			Menus:       sqlite.NewMenus(db),
+			Users:       sqlite.NewUsers(db),
+			Votes:       sqlite.NewVotes(db, votePolicy),
+		}
+
+	default:
+		return errors.Errorf("unknown database driver %q", cfg.DB.Driver)
+	}
+
+	postgres := cfg.DB.Driver != "sqlite"
+
+	var dbBreaker *breaker.Breaker
+	if cfg.DB.BreakerThreshold > 0 {
+		dbBreaker = breaker.New(cfg.DB.BreakerThreshold, cfg.DB.BreakerCooldown)
+	}
+
+	// Start Enrichment Worker
+
+	var jobs []handlers.Job
+	if secretStore != nil {
+		jobs = append(jobs, secretStore)
+	}
+
+	var enricher *enrichment.Worker
+	switch cfg.Enrichment.Provider {
+	case "none":
+	case "places":
+		log.Println("main : Started : Initializing enrichment support")
+
+		provider := enrichment.NewPlaces(cfg.Enrichment.URL, cfg.Enrichment.APIKey)
+		enricher = enrichment.NewWorker(log, db, provider, cfg.Enrichment.QueueSize)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go enricher.Run(ctx)
+
+		jobs = append(jobs, enricher)
+	default:
+		return errors.Errorf("unknown enrichment provider %q", cfg.Enrichment.Provider)
+	}
+
+	// Start Geocoding Worker
+
+	var geocoder *geocoding.Worker
+	switch cfg.Geocoding.Provider {
+	case "none":
+	case "nominatim":
+		log.Println("main : Started : Initializing geocoding support")
+
+		provider := geocoding.NewNominatim(cfg.Geocoding.URL, cfg.Geocoding.UserAgent)
+		geocoder = geocoding.NewWorker(log, db, provider, cfg.Geocoding.QueueSize)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go geocoder.Run(ctx)
+
+		jobs = append(jobs, geocoder)
+	default:
+		return errors.Errorf("unknown geocoding provider %q", cfg.Geocoding.Provider)
+	}
+
+	// Initialize Image Storage
+	//
+	// The images stored on the local disk are served by the API, the base
+	// URL tells where when it is behind a proxy. The base URL of S3 storage is
+	// the public address of the bucket, the endpoint of the bucket when blank.
+
+	log.Printf("main : Started : Initializing image storage : %s", cfg.Media.Storage)
+
+	var imageStorage media.Storage
+	switch cfg.Media.Storage {
+	case "disk":
+		baseURL := cfg.Media.BaseURL
+		if baseURL == "" {
+			baseURL = "/v1/media"
+		}
+		imageStorage = media.NewDisk(cfg.Media.Dir, baseURL)
+	case "s3":
+		bucket, err := s3.Open(s3.Config{
+			Endpoint:        cfg.Media.Endpoint,
+			Region:          cfg.Media.Region,
+			Bucket:          cfg.Media.Bucket,
+			AccessKeyID:     cfg.Media.AccessKeyID,
+			SecretAccessKey: cfg.Media.SecretAccessKey,
+			DisableTLS:      cfg.Media.DisableTLS,
+		})
+		if err != nil {
+			return errors.Wrap(err, "configuring s3 image storage")
+		}
+		imageStorage = media.NewS3(bucket, cfg.Media.BaseURL)
+	default:
+		return errors.Errorf("unknown image storage %q", cfg.Media.Storage)
+	}
+	uploader := media.NewUploader(imageStorage, cfg.Media.MaxSize)
+
+	// Start Email Queue
+	//
+	// In dev mode the emails are written to the log instead of being sent.
+
+	log.Printf("main : Started : Initializing email queue : dev mode %v", cfg.Email.DevMode)
+
+	var sender email.Sender = email.NewLog(log)
+	if !cfg.Email.DevMode {
+		sender = email.NewSMTP(email.Config{
+			Host:     cfg.Email.Host,
+			Port:     cfg.Email.Port,
+			Username: cfg.Email.Username,
+			Password: cfg.Email.Password,
+			From:     cfg.Email.From,
+		})
+	}
+	emails := email.NewQueue(log, sender, cfg.Email.QueueSize, cfg.Email.MaxAttempts, cfg.Email.Backoff)
+	{
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go emails.Run(ctx)
+
+		jobs = append(jobs, emails)
+	}
+
+	// Start Winner Scheduler
+	//
+	// Every replica runs the scheduler but only the one elected computes and
+	// announces the winners.
+
+	log.Println("main : Started : Initializing vote winner scheduler")
+
+	if postgres {
+		announcers := []vote.Announcer{email.NewDigest(db, emails)}
+		var openers []vote.Opener
+		if cfg.Slack.WebhookURL != "" {
+			log.Println("main : Started : Posting the menus and the winner to Slack")
+			s := slack.New(db, cfg.Slack.WebhookURL, cfg.Slack.Timeout)
+			announcers = append(announcers, s)
+			openers = append(openers, s)
+		}
+
+		if cfg.Telegram.Token != "" {
+			log.Println("main : Started : Initializing Telegram bot")
+			client := telegram.NewClient(cfg.Telegram.APIURL, cfg.Telegram.Token, cfg.Telegram.Timeout)
+			bot := telegram.NewBot(log, db, client, votePolicy)
+			announcers = append(announcers, bot)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go bot.Run(ctx)
+
+			jobs = append(jobs, bot)
+		}
+
+		scheduler := vote.NewScheduler(log, db, votePolicy, cfg.Vote.WinnerInterval, announcers...).
+			OpenAt(cfg.Slack.MenuTime, openers...).
+			Clock(clk).
+			Leader(database.NewLeader(db, database.LockVoteWinner))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go scheduler.Run(ctx)
+
+		jobs = append(jobs, scheduler)
+	}
+
+	// Start Monthly Report Mailer
+	//
+	// Once a month has ended its report is emailed to the admins of every
+	// organization.
+
+	if cfg.Report.Email {
+		if !postgres {
+			return errors.New("monthly report emails need the postgres database driver")
+		}
+
+		log.Println("main : Started : Initializing monthly report mailer")
+
+		mailer := report.NewMailer(log, db, emails, cfg.Report.Interval)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go mailer.Run(ctx)
+
+		jobs = append(jobs, mailer)
+	}
+
+	// Start Vote Tally Reconciler
+
+	log.Println("main : Started : Initializing vote tally reconciler")
+
+	if postgres {
+		reconciler := vote.NewReconciler(log, db, cfg.Vote.TallyInterval).Clock(clk)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go reconciler.Run(ctx)
+
+		jobs = append(jobs, reconciler)
+	}
+
+	// Start Webhook Delivery Worker
+
+	log.Println("main : Started : Initializing webhook delivery")
+
+	var webhooks *webhook.Notifier
+	var notifier *notification.Notifier
+	if postgres {
+		webhooks = webhook.NewNotifier(db)
+		notifier = notification.NewNotifier(db)
+
+		worker := webhook.NewWorker(log, db, cfg.Webhook.Interval, cfg.Webhook.Timeout, cfg.Webhook.MaxAttempts)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go worker.Run(ctx)
+
+		jobs = append(jobs, worker)
+	}
+
+	// Start Broadcast Delivery Worker
+	//
+	// The broadcasts are emailed, and pushed to the mobile devices when a
+	// Gorush server is configured.
+
+	log.Println("main : Started : Initializing broadcast delivery")
+
+	broadcastChannels := []broadcast.Channel{email.NewBroadcaster(sender)}
+	if postgres {
+		if cfg.Push.URL != "" {
+			broadcastChannels = append(broadcastChannels, push.NewGateway(db, cfg.Push.URL, cfg.Push.Timeout))
+		}
+
+		worker := broadcast.NewWorker(log, db, broadcastChannels, cfg.Broadcast.Interval).Clock(clk)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go worker.Run(ctx)
+
+		jobs = append(jobs, worker)
+	}
+
+	// Start Outbox Relay
+	//
+	// Domain events are always recorded. Without a message bus they are
+	// marked published right away and pruned.
+
+	log.Printf("main : Started : Initializing outbox relay : %s", cfg.Outbox.Publisher)
+
+	var publisher outbox.Publisher
+	switch cfg.Outbox.Publisher {
+	case "none":
+		publisher = outbox.Discard{}
+	case "nats":
+		publisher, err = outbox.NewNATS(cfg.Outbox.URL, cfg.Outbox.Topic)
+		if err != nil {
+			return errors.Wrap(err, "configuring NATS publisher")
+		}
+	case "kafka":
+		publisher = outbox.NewKafka(cfg.Outbox.URL, cfg.Outbox.Topic)
+	default:
+		return errors.Errorf("unknown outbox publisher %q", cfg.Outbox.Publisher)
+	}
+
+	if postgres {
+		relay := outbox.NewRelay(log, db, publisher, cfg.Outbox.Interval)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go relay.Run(ctx)
+
+		jobs = append(jobs, relay)
+	}
+
+	// Start Retention Purger
+	//
+	// Votes and deleted restaurants older than their retention are purged
+	// once a day by a single replica. A zero retention keeps them forever.
+
+	log.Println("main : Started : Initializing retention purger")
+
+	retentionPolicies := []retention.Policy{
+		{Dataset: retention.Votes, Age: cfg.Retention.Votes},
+		{Dataset: retention.DeletedRestaurants, Age: cfg.Retention.DeletedRestaurants},
+	}
+
+	if postgres {
+		purger := retention.NewPurger(log, db, retentionPolicies, cfg.Retention.Interval).
+			Clock(clk).
+			Leader(database.NewLeader(db, database.LockRetention))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go purger.Run(ctx)
+
+		jobs = append(jobs, purger)
+	}
+
+	// Start Tracing Support
+
+	log.Println("main : Started : Initializing tracing support")
+
+	traceShutdown, err := tracing.Init(tracing.Config{
+		ServiceName: "restaurant-api",
+		Version:     build,
+		Exporter:    cfg.Trace.Exporter,
+		URL:         cfg.Trace.URL,
+		Probability: cfg.Trace.Probability,
 	})
 	if err != nil {
-		return errors.Wrap(err, "connecting to db")
+		return errors.Wrap(err, "starting tracing")
 	}
 	defer func() {
-		log.Printf("main : Database Stopping : %s", cfg.DB.Host)
+		log.Printf("main : Tracing Stopping : %s", cfg.Trace.Exporter)
+		if err := traceShutdown(context.Background()); err != nil {
+			log.Printf("main : Tracing Stopping : %v", err)
+		}
 	}()
 
-	// Start Tracing Support
-
 	// Start Debug Service
 	//
 	// /debug/pprof - Added to the default mux by importing the net/http/pprof package.
 	// /debug/vars - Added to the default mux by importing the expvar package.
 	//
-	// Not concerned with shutting this down when the application is shutdown.
+	// The debug service is stopped after the API so the metrics of the
+	// requests being drained stay visible. It can be disabled in production.
 
-	log.Println("main : Started : Initializing debugging support" )
+	if !cfg.Web.DisableDebug {
+		log.Println("main : Started : Initializing debugging support" )
 
-	go func() {
-		log.Printf("main : Debug Listening %s", cfg.Web.DebugHost)
-		log.Printf("main : Debug Listener closed : %v", http.ListenAndServe(cfg.Web.DebugHost, http.DefaultServeMux))
-	}()
+		debug := http.Server{
+			Addr:    cfg.Web.DebugHost,
+			Handler: http.DefaultServeMux,
+		}
+
+		go func() {
+			log.Printf("main : Debug Listening %s", cfg.Web.DebugHost)
+			if err := debug.ListenAndServe(); err != http.ErrServerClosed {
+				log.Printf("main : Debug Listener closed : %v", err)
+			}
+		}()
+
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), cfg.Web.DebugShutdownTimeout)
+			defer cancel()
+
+			if err := debug.Shutdown(ctx); err != nil {
+				log.Printf("main : Debug shutdown did not complete in %v : %v", cfg.Web.DebugShutdownTimeout, err)
+				debug.Close()
+			}
+		}()
+	}
 
 	// Start API Service
 
@@ -138,20 +847,153 @@ func run() error {
 	shutdown := make(chan os.Signal, 1)
 	signal.Notify(shutdown, os.Interrupt, syscall.SIGTERM)
 
+	var draining atomic.Bool
+
+	// Open vote and broadcast streams are ended on shutdown, they would hold it up until
+	// the shutdown timeout otherwise.
+	voteHub := vote.NewHub()
+	broadcastStream := broadcast.NewStream().Clock(clk)
+	if bcListener != nil {
+		go broadcastStream.Run(bcListener.Notifications(), db, log)
+	}
+
+	// The caches and vote streams learn about the changes made elsewhere
+	// when the database announces them.
+	var changes *change.Feed
+	if listener != nil {
+		changes = change.NewFeed()
+		go changes.Run(listener.Notifications(), log)
+	}
+
+	// Panics of the handlers are reported to Sentry when it is configured,
+	// on top of being logged.
+	var panicReporters []mid.PanicReporter
+	if cfg.Sentry.DSN != "" {
+		reporter, err := sentry.New(log, cfg.Sentry.DSN, build, cfg.Sentry.Timeout)
+		if err != nil {
+			return errors.Wrap(err, "constructing sentry reporter")
+		}
+		panicReporters = append(panicReporters, reporter)
+	}
+
+	apiCfg := handlers.APIConfig{
+		Build:          build,
+		Shutdown:       shutdown,
+		Log:            log,
+		DB:             db,
+		ReadDB:         readDB,
+		Authenticator:  authenticator,
+		Enricher:       enricher,
+		Geocoder:       geocoder,
+		VotePolicy:     votePolicy,
+		OrderPolicy:    order.Policy{Cutoff: cfg.Order.Cutoff},
+		VoteHub:        voteHub,
+		RateLimiter:    ratelimit.NewMemory(),
+		MaxBodySize:    cfg.Web.MaxBodySize,
+		RequestTimeout: cfg.Web.RequestTimeout,
+		IdempotencyTTL: cfg.Web.IdempotencyTTL,
+		StatsCacheTTL:  cfg.Web.StatsCacheTTL,
+		Changes:        changes,
+		Webhooks:       webhooks,
+		Notifier:       notifier,
+		Uploader:       uploader,
+		Draining:       &draining,
+		RateLimits: handlers.RateLimits{
+			Token:  ratelimit.Limit{Rate: cfg.RateLimit.TokenRate, Burst: cfg.RateLimit.TokenBurst},
+			Vote:   ratelimit.Limit{Rate: cfg.RateLimit.VoteRate, Burst: cfg.RateLimit.VoteBurst},
+			Public: ratelimit.Limit{Rate: cfg.RateLimit.PublicRate, Burst: cfg.RateLimit.PublicBurst},
+		},
+		LongRequestTimeout: cfg.Web.LongRequestTimeout,
+		IdempotencyLease:   cfg.Web.IdempotencyLease,
+		Jobs:               jobs,
+		Stores:             stores,
+		Driver:             cfg.DB.Driver,
+		Breaker:            dbBreaker,
+		RetentionPolicies:  retentionPolicies,
+		StrictDelete:       cfg.Web.StrictDelete,
+		NegotiateVersion:   cfg.Web.NegotiateVersion,
+		Clock:              clk,
+		PanicReporters:     panicReporters,
+		QueryTimeout:       cfg.DB.QueryTimeout,
+		BroadcastStream:    broadcastStream,
+		BroadcastChannels:  broadcastChannels,
+	}
+
+	// The debug listener shows the health check with all details and lets
+	// the level of the logs be changed while diagnosing an incident.
+	http.DefaultServeMux.Handle("/debug/health", handlers.DebugHealth(apiCfg))
+	http.DefaultServeMux.Handle("/debug/loglevel", handlers.DebugLogLevel(logFilter))
+
 	api := http.Server{
-		Addr: cfg.Web.APIHost,
-		Handler: handlers.API(build, shutdown, log, db, authenticator),
+		Addr:         cfg.Web.APIHost,
+		Handler:      handlers.API(apiCfg),
 		ReadTimeout: cfg.Web.ReadTimeout,
 		WriteTimeout: cfg.Web.WriteTimeout,
 	}
 
+	// Terminate TLS when certificates are configured. With autocert the
+	// certificates of the hosts are requested from Let's Encrypt.
+	var redirect http.Handler
+	switch {
+	case len(cfg.Web.AutocertHosts) > 0:
+		m := autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(cfg.Web.AutocertHosts...),
+			Cache:      autocert.DirCache(cfg.Web.AutocertDir),
+		}
+		api.TLSConfig = m.TLSConfig()
+		redirect = m.HTTPHandler(redirectHTTPS(cfg.Web.APIHost))
+
+	case cfg.Web.TLSCertFile != "":
+		cert, err := tls.LoadX509KeyPair(cfg.Web.TLSCertFile, cfg.Web.TLSKeyFile)
+		if err != nil {
+			return errors.Wrap(err, "loading tls certificate")
+		}
+		api.TLSConfig = &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		}
+		redirect = redirectHTTPS(cfg.Web.APIHost)
+	}
+
 	serverErrors := make(chan error, 1)
 
+	// The listener is wrapped so connection counts show up on the debug
+	// listener next to the request metrics.
+	ln, err := net.Listen("tcp", api.Addr)
+	if err != nil {
+		return errors.Wrap(err, "listening for api requests")
+	}
+
 	go func() {
-		log.Printf("main : API listening on %s", api.Addr)
-		serverErrors <- api.ListenAndServe()
+		if api.TLSConfig == nil {
+			log.Printf("main : API listening on %s", api.Addr)
+			serverErrors <- api.Serve(conntrack.NewListener(ln))
+			return
+		}
+
+		api.TLSConfig = conntrack.TLSConfig(api.TLSConfig)
+		log.Printf("main : API listening on %s with TLS", api.Addr)
+		serverErrors <- api.ServeTLS(conntrack.NewListener(ln), "", "")
 	}()
 
+	// The plain listener only sends clients over to HTTPS and answers the
+	// Let's Encrypt challenges.
+	var plain *http.Server
+	if redirect != nil && cfg.Web.RedirectHost != "" {
+		plain = &http.Server{
+			Addr:         cfg.Web.RedirectHost,
+			Handler:      redirect,
+			ReadTimeout:  cfg.Web.ReadTimeout,
+			WriteTimeout: cfg.Web.WriteTimeout,
+		}
+
+		go func() {
+			log.Printf("main : Redirect listening on %s", plain.Addr)
+			serverErrors <- plain.ListenAndServe()
+		}()
+	}
+
 	// Shutdown
 
 	select {
@@ -160,10 +1002,23 @@ func run() error {
 
 		case sig := <- shutdown:
 			log.Printf("main : %v : Start shtdown", sig)
+			draining.Store(true)
+			voteHub.Close()
+			broadcastStream.Close()
+
+			// Give load balancers time to see the service is no longer ready
+			// before it stops accepting connections.
+			time.Sleep(cfg.Web.DrainDelay)
 
 			ctx, cancel := context.WithTimeout(context.Background(), cfg.Web.ShutdownTimeout)
 			defer cancel()
 
+			if plain != nil {
+				if err := plain.Shutdown(ctx); err != nil {
+					log.Printf("main : Redirect shutdown did not complete : %v", err)
+				}
+			}
+
 			err := api.Shutdown(ctx)
 			if err != nil {
 				log.Printf("main : Graceful shutdown did not complete in %v : %v", cfg.Web.ShutdownTimeout, err)
@@ -179,3 +1034,28 @@ func run() error {
 	}
 	return nil
 }
+
+// redirectHTTPS sends requests to the same URL over HTTPS on the port the API
+// is listening on. Requests other than GET and HEAD are redirected with 308 so
+// clients send them again with the same method and body.
+func redirectHTTPS(apiHost string) http.Handler {
+	_, port, _ := net.SplitHostPort(apiHost)
+
+	f := func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+
+		status := http.StatusMovedPermanently
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			status = http.StatusPermanentRedirect
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), status)
+	}
+
+	return http.HandlerFunc(f)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// TestRedirectHTTPS validates the plain listener sends the requests to the
+// same URL over HTTPS.
+func TestRedirectHTTPS(t *testing.T) {
+	tt := []struct {
+		name     string
+		apiHost  string
+		method   string
+		url      string
+		status   int
+		location string
+	}{
+		{"a request", "0.0.0.0:443", http.MethodGet, "http://example.com/v1/restaurant?limit=10&offset=20", http.StatusMovedPermanently, "https://example.com/v1/restaurant?limit=10&offset=20"},
+		{"a request with a port", "0.0.0.0:443", http.MethodGet, "http://example.com:80/v1/health", http.StatusMovedPermanently, "https://example.com/v1/health"},
+		{"a request to another port", ":8443", http.MethodHead, "http://example.com/v1/health", http.StatusMovedPermanently, "https://example.com:8443/v1/health"},
+		{"an IPv6 host", ":8443", http.MethodGet, "http://[::1]:80/v1/health", http.StatusMovedPermanently, "https://[::1]:8443/v1/health"},
+		{"an escaped path", "0.0.0.0:443", http.MethodGet, "http://example.com/v1/tags/caf%C3%A9%2Fbar?q=a+b", http.StatusMovedPermanently, "https://example.com/v1/tags/caf%C3%A9%2Fbar?q=a+b"},
+		{"a POST", "0.0.0.0:443", http.MethodPost, "http://example.com/v1/votes?date=2020-03-02", http.StatusPermanentRedirect, "https://example.com/v1/votes?date=2020-03-02"},
+		{"a DELETE", "0.0.0.0:443", http.MethodDelete, "http://example.com/v1/votes/today", http.StatusPermanentRedirect, "https://example.com/v1/votes/today"},
+	}
+
+	t.Log("Given the need to serve the API over HTTPS only.")
+	{
+		for i, tc := range tt {
+			t.Logf("\tTest %d:\tWhen redirecting %s.", i, tc.name)
+			{
+				w := httptest.NewRecorder()
+				redirectHTTPS(tc.apiHost).ServeHTTP(w, httptest.NewRequest(tc.method, tc.url, nil))
+
+				if w.Code != tc.status {
+					t.Fatalf("\t%s\tShould receive a status code of %d : got %d.", tests.Failed, tc.status, w.Code)
+				}
+				t.Logf("\t%s\tShould receive a status code of %d.", tests.Success, tc.status)
+
+				if got := w.Header().Get("Location"); got != tc.location {
+					t.Fatalf("\t%s\tShould keep the host, path and query : got %s.", tests.Failed, got)
+				}
+				t.Logf("\t%s\tShould keep the host, path and query.", tests.Success)
+			}
+		}
+	}
+}
@@ -0,0 +1,7 @@
+//go:build sqlite
+
+package main
+
+// The SQLite driver is only linked with the sqlite build tag so the default
+// build does not depend on it. See internal/sqlite.
+import _ "modernc.org/sqlite"
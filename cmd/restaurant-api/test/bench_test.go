@@ -0,0 +1,69 @@
+package test
+
+import (
+	"github.com/remisb/restaurant/cmd/restaurant-api/internal/handlers"
+	"github.com/remisb/restaurant/internal/platform/database"
+	"github.com/remisb/restaurant/internal/schema"
+	"github.com/remisb/restaurant/internal/tests"
+	"github.com/remisb/restaurant/internal/vote"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+// loadRestaurantID is the ID of the second restaurant of the load-test seeds,
+// md5('restaurant2'), which has a menu for every day of the last 30 days.
+const loadRestaurantID = "0637f105-8958-d23d-36f5-6430439f6947"
+
+// BenchmarkListDrivers compares the throughput of the list endpoints with
+// the lib/pq and the pgx drivers, against a database seeded with the
+// load-test profile. Run it with
+//
+//	go test ./cmd/restaurant-api/test -run NONE -bench ListDrivers
+func BenchmarkListDrivers(b *testing.B) {
+	for _, driver := range []string{database.DriverPQ, database.DriverPgx} {
+		test := tests.NewIntegrationDriver(b, driver)
+		if err := schema.SeedProfile(test.DB, "load-test"); err != nil {
+			test.Teardown()
+			b.Fatalf("seeding load-test profile: %s", err)
+		}
+
+		app := handlers.API(handlers.APIConfig{
+			Build:         "develop",
+			Shutdown:      make(chan os.Signal, 1),
+			Log:           log.New(io.Discard, "", 0),
+			DB:            test.DB,
+			Driver:        driver,
+			Authenticator: test.Authenticator,
+			VotePolicy:    vote.Policy{MaxDaysAhead: 7, Deadline: 11 * time.Hour},
+		})
+		token := test.Token("admin@example.com", "gophers")
+
+		b.Run(driver+"/restaurants", benchmarkList(app, token, "/v1/restaurant"))
+		b.Run(driver+"/menus", benchmarkList(app, token, "/v1/restaurant/"+loadRestaurantID+"/menus"))
+
+		test.Teardown()
+	}
+}
+
+// benchmarkList requests the list at the url b.N times in parallel.
+func benchmarkList(app http.Handler, token, url string) func(b *testing.B) {
+	return func(b *testing.B) {
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				r := createRequestBody(GET, url, token, nil)
+				w := httptest.NewRecorder()
+				app.ServeHTTP(w, r)
+				if w.Code != http.StatusOK {
+					b.Errorf("%s: status %d, want %d: %s", url, w.Code, http.StatusOK, w.Body)
+					return
+				}
+			}
+		})
+	}
+}
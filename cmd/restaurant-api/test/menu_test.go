@@ -26,10 +26,11 @@ func (rt *RestaurantTests) crudMenu(t *testing.T) {
 // postMenu201 validates a restaurant menu can be created with the endpoint.
 func (mt *RestaurantTests) postMenu201(t *testing.T) {
 
-	location := time.FixedZone("UTC+03", 3*60*60)
+	today := time.Now().UTC()
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
 	newMenu := restaurant.NewMenu{
 		RestaurantID: "a224a8d6-3f9e-4b11-9900-e81a25d80702",
-		Date:         time.Date(2020, 03, 10, 0, 0, 0, 0, location),
+		Date:         today.Format(restaurant.MenuDateLayout),
 		Menu:         "Test menu content",
 	}
 
@@ -58,7 +59,7 @@ func (mt *RestaurantTests) postMenu201(t *testing.T) {
 			want := m
 			want.Votes = 0
 			want.RestaurantID = newMenu.RestaurantID
-			want.Date = newMenu.Date
+			want.Date = today
 			want.Menu = newMenu.Menu
 
 			if diff := cmp.Diff(want, m); diff != "" {
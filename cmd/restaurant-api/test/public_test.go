@@ -0,0 +1,86 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/remisb/restaurant/cmd/restaurant-api/internal/handlers"
+	"github.com/remisb/restaurant/internal/tests"
+	"github.com/remisb/restaurant/internal/vote"
+)
+
+// TestPublicTodayMenu validates the menu of the day shown to anonymous diners
+// lists the structured items of the menu, or the lines of its text when it
+// has none.
+func TestPublicTodayMenu(t *testing.T) {
+	test := tests.NewIntegration(t)
+	defer test.Teardown()
+
+	const (
+		itemsID = "0ce90028-69cb-4e9c-9af0-7bbada50d5b6"
+		textID  = "71b8fb90-24eb-4012-9048-3ba210aac0f6"
+	)
+	today := time.Now().UTC().Format("2006-01-02")
+
+	const qr = `UPDATE restaurant SET public = TRUE WHERE restaurant_id IN ($1, $2)`
+	if _, err := test.DB.Exec(qr, itemsID, textID); err != nil {
+		t.Fatalf("publishing restaurants: %s", err)
+	}
+
+	const qm = `INSERT INTO menu (menu_id, restaurant_id, date, menu, votes, items) VALUES
+		('d4b6f7e5-9c7b-4ebf-8f94-3a0a5c3e4d04', $1, $3, 'Soup of the day', 0,
+			'[{"name": "Cepelinai", "price": 750, "allergens": []}, {"name": "Kibinai", "price": 450, "allergens": []}]'),
+		('e5c7a8f6-ad8c-4fc0-9a05-4b1b6d4f5e05', $2, $3, 'Beet soup' || chr(10) || 'Dumplings', 0, '[]')`
+	if _, err := test.DB.Exec(qm, itemsID, textID, today); err != nil {
+		t.Fatalf("seeding menus: %s", err)
+	}
+
+	shutdown := make(chan os.Signal, 1)
+	app := handlers.API(handlers.APIConfig{
+		Build:         "develop",
+		Shutdown:      shutdown,
+		Log:           test.Log,
+		DB:            test.DB,
+		Authenticator: test.Authenticator,
+		VotePolicy:    vote.Policy{MaxDaysAhead: 7, Deadline: 11 * time.Hour},
+	})
+
+	tt := []struct {
+		name  string
+		id    string
+		items []string
+	}{
+		{"a menu with items", itemsID, []string{"Cepelinai", "Kibinai"}},
+		{"a menu posted as text", textID, []string{"Beet soup", "Dumplings"}},
+	}
+
+	t.Log("Given the need to show the menu of the day to anonymous diners.")
+	{
+		for i, tc := range tt {
+			tests.LogInfof(t, i, "When showing %s.", tc.name)
+			{
+				r := httptest.NewRequest(http.MethodGet, "/v1/public/restaurant/"+tc.id+"/menu/today", nil)
+				w := httptest.NewRecorder()
+				app.ServeHTTP(w, r)
+
+				tests.AssertStatusCode(t, http.StatusOK, w.Code)
+
+				var pm struct {
+					Items []string `json:"items"`
+				}
+				if err := json.NewDecoder(w.Body).Decode(&pm); err != nil {
+					tests.LogFailf(t, "Should be able to unmarshal the response : %v", err)
+				}
+				if got := strings.Join(pm.Items, ","); got != strings.Join(tc.items, ",") {
+					tests.LogFailf(t, "Should list the items %v : got %v", tc.items, pm.Items)
+				}
+				tests.LogSuccess(t, "Should list the items of the menu.")
+			}
+		}
+	}
+}
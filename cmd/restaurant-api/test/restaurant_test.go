@@ -10,12 +10,14 @@ import (
 	"github.com/remisb/restaurant/internal/platform/web"
 	"github.com/remisb/restaurant/internal/restaurant"
 	"github.com/remisb/restaurant/internal/tests"
+	"github.com/remisb/restaurant/internal/vote"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
+	"time"
 )
 
 const (
@@ -43,7 +45,14 @@ func TestRestaurants(t *testing.T) {
 
 	shutdown := make(chan os.Signal, 1)
 	restaurantTests := RestaurantTests{
-		app:        handlers.API("develop", shutdown, test.Log, test.DB, test.Authenticator),
+		app: handlers.API(handlers.APIConfig{
+			Build:         "develop",
+			Shutdown:      shutdown,
+			Log:           test.Log,
+			DB:            test.DB,
+			Authenticator: test.Authenticator,
+			VotePolicy:    vote.Policy{MaxDaysAhead: 7, Deadline: 11 * time.Hour},
+		}),
 		userToken:  test.Token("user@example.com", "gophers"),
 		adminToken: test.Token("admin@example.com", "gophers"),
 	}
@@ -85,6 +94,7 @@ func (rt *RestaurantTests) postRestaurant400(t *testing.T) {
 			t.Logf("\t%s\tShould be able to unmarshal the response to an error type.", tests.Success)
 
 			want := web.ErrorResponse{
+				Code:  web.CodeValidationFailed,
 				Error: "field validation error",
 				Fields: []web.FieldError{
 					{Field: "name", Error: "name is a required field"},
@@ -228,7 +238,8 @@ func (rt *RestaurantTests) deleteRestaurantNotFound(t *testing.T) {
 // putRestaurant404 validates updating a restaurant that does not exist.
 func (rt *RestaurantTests) putRestaurant400(t *testing.T) {
 	up := restaurant.UpdateRestaurant{
-		Name: tests.StringPointer("Nonexistent"),
+		Name:    tests.StringPointer("Nonexistent"),
+		Version: tests.IntPointer(1),
 	}
 
 	id := "12345"
@@ -264,7 +275,8 @@ func (rt *RestaurantTests) putRestaurant400(t *testing.T) {
 // putRestaurant404 validates updating a restaurant that does not exist.
 func (rt *RestaurantTests) putRestaurant404(t *testing.T) {
 	up := restaurant.UpdateRestaurant{
-		Name: tests.StringPointer("Nonexistent"),
+		Name:    tests.StringPointer("Nonexistent"),
+		Version: tests.IntPointer(1),
 	}
 
 	id := "9b468f90-1cf1-4377-b3fa-68b450d632a0"
@@ -437,7 +449,7 @@ func (rt *RestaurantTests) getRestaurant200(t *testing.T, id string) {
 
 // putRestaurant204 validates updating a restaurant that does exist.
 func (rt *RestaurantTests) putRestaurant204(t *testing.T, id string) {
-	body := `{"name": "Test restaurant", "Address": "test address"}`
+	body := `{"name": "Test restaurant", "Address": "test address", "version": 1}`
 
 	r := createRequestBody(PUT, "/v1/restaurant/"+id, rt.userToken, strings.NewReader(body))
 	w := httptest.NewRecorder()
@@ -464,6 +476,20 @@ func (rt *RestaurantTests) putRestaurant204(t *testing.T, id string) {
 				tests.LogFailf(t, "Should see an updated Name : got %q want %q", ru.Name, "Test restaurant")
 			}
 			tests.LogSuccess(t, "Should see an updated Name.")
+
+			if ru.Version != 2 {
+				tests.LogFailf(t, "Should see the next Version : got %d want %d", ru.Version, 2)
+			}
+			tests.LogSuccess(t, "Should see the next Version.")
+		}
+
+		tests.LogInfo(t, 1, "When sending the update again with the previous version.")
+		{
+			r := createRequestBody(PUT, "/v1/restaurant/"+id, rt.userToken, strings.NewReader(body))
+			w := httptest.NewRecorder()
+			rt.app.ServeHTTP(w, r)
+
+			tests.AssertStatusCode(t, http.StatusConflict, w.Code)
 		}
 	}
 }
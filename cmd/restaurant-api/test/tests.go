@@ -44,7 +44,12 @@ func NewUnit(t *testing.T) (*sqlx.DB, func()) {
 
 	c := databasetest.StartContainer(t)
 
-	db, err := database.Open(database.Config{
+	t.Log("waiting for database to be ready")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	db, err := database.OpenAndWait(ctx, database.Config{
 		User:       "postgres",
 		Password:   "postgres",
 		Host:       c.Host,
@@ -52,30 +57,12 @@ func NewUnit(t *testing.T) (*sqlx.DB, func()) {
 		DisableTLS: true,
 	})
 	if err != nil {
-		t.Fatalf("opening database connection: %v", err)
-	}
-
-	t.Log("waiting for database to be ready")
-
-	// Wait for the database to be ready. Wait 100ms longer between each attempt.
-	// Do not try more than 20 times.
-	var pingError error
-	maxAttempts := 20
-	for attempts := 1; attempts <= maxAttempts; attempts++ {
-		pingError = db.Ping()
-		if pingError == nil {
-			break
-		}
-		time.Sleep(time.Duration(attempts) * 100 * time.Millisecond)
-	}
-
-	if pingError != nil {
 		databasetest.DumpContainerLogs(t, c)
 		databasetest.StopContainer(t, c)
-		t.Fatalf("waiting for database to be ready: %v", pingError)
+		t.Fatalf("waiting for database to be ready: %v", err)
 	}
 
-	if err := schema.Migrate(db); err != nil {
+	if err := schema.Migrate(ctx, db); err != nil {
 		databasetest.StopContainer(t, c)
 		t.Fatalf("migrating: %s", err)
 	}
@@ -9,10 +9,12 @@ import (
 	"github.com/remisb/restaurant/internal/platform/web"
 	"github.com/remisb/restaurant/internal/tests"
 	"github.com/remisb/restaurant/internal/user"
+	"github.com/remisb/restaurant/internal/vote"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 )
 
 func TestUsers(t *testing.T) {
@@ -21,7 +23,14 @@ func TestUsers(t *testing.T) {
 
 	shutdown := make(chan os.Signal, 1)
 	tests := UserTests{
-		app:        handlers.API("develop", shutdown, test.Log, test.DB, test.Authenticator),
+		app: handlers.API(handlers.APIConfig{
+			Build:         "develop",
+			Shutdown:      shutdown,
+			Log:           test.Log,
+			DB:            test.DB,
+			Authenticator: test.Authenticator,
+			VotePolicy:    vote.Policy{MaxDaysAhead: 7, Deadline: 11 * time.Hour},
+		}),
 		userToken:  test.Token("user@example.com", "gophers"),
 		adminToken: test.Token("admin@example.com", "gophers"),
 	}
@@ -110,6 +119,7 @@ func (ut *UserTests) postUser400(t *testing.T) {
 
 			// Define what we want to see.
 			want := web.ErrorResponse{
+				Code:  web.CodeValidationFailed,
 				Error: "field validation error",
 				Fields: []web.FieldError{
 					{Field: "name", Error: "name is a required field"},
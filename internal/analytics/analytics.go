@@ -0,0 +1,143 @@
+// Package analytics reports how the organizations vote for lunch, aggregated
+// in the database so the reports stay cheap over long ranges of dates.
+package analytics
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/database"
+	"go.opentelemetry.io/otel"
+)
+
+// The ways the votes are grouped by.
+const (
+	GroupByRestaurant = "restaurant"
+	GroupByWeekday    = "weekday"
+)
+
+// Predefined errors identify expected failure conditions.
+var (
+	// ErrInvalidGroup is used when votes are grouped by an unknown key.
+	ErrInvalidGroup = errors.New("group_by must be restaurant or weekday")
+)
+
+// Votes is the report of the votes cast between From and To.
+type Votes struct {
+	From          time.Time `json:"from"`
+	To            time.Time `json:"to"`
+	GroupBy       string    `json:"group_by"`
+	Votes         int       `db:"votes" json:"votes"`
+	Voters        int       `db:"voters" json:"voters"`
+	Users         int       `db:"users" json:"users"`
+	Days          int       `db:"days" json:"days"`
+	Participation float64   `json:"participation"`
+	Groups        []Group   `json:"groups"`
+}
+
+// Group is the share of the votes of a restaurant or a weekday. Wins counts
+// the dates the restaurant won, or the dates of the weekday with a winner.
+// Participation is the share of the users who voted on an average day.
+type Group struct {
+	Key           string  `db:"key" json:"key"`
+	Name          string  `db:"name" json:"name"`
+	Votes         int     `db:"votes" json:"votes"`
+	Wins          int     `db:"wins" json:"wins"`
+	Days          int     `db:"days" json:"days"`
+	Share         float64 `json:"share"`
+	Participation float64 `json:"participation"`
+}
+
+// weekdays are the names of the ISO days of the week.
+var weekdays = []string{"", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday"}
+
+// VoteReport aggregates the votes cast in the organization of the context
+// for the dates from through to, grouped by restaurant or weekday.
+func VoteReport(ctx context.Context, db *sqlx.DB, from, to time.Time, groupBy string) (*Votes, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.analytics.VoteReport")
+	defer span.End()
+
+	org := auth.Org(ctx)
+	conn := database.Conn(ctx, db)
+
+	var groupQuery string
+	switch groupBy {
+	case GroupByRestaurant:
+		groupQuery = `SELECT r.restaurant_id::text AS key, r.name,
+				COALESCE(v.votes, 0) AS votes, COALESCE(w.wins, 0) AS wins, COALESCE(v.days, 0) AS days
+			FROM (SELECT restaurant_id, COUNT(*) AS votes, COUNT(DISTINCT date) AS days FROM vote
+				WHERE date >= $1 AND date <= $2 AND ($3 = '' OR org_id::text = $3)
+				GROUP BY restaurant_id) AS v
+			FULL JOIN (SELECT restaurant_id, COUNT(*) AS wins FROM winner
+				WHERE date >= $1 AND date <= $2 AND ($3 = '' OR org_id::text = $3)
+				GROUP BY restaurant_id) AS w USING (restaurant_id)
+			JOIN restaurant AS r USING (restaurant_id)
+			ORDER BY votes DESC, wins DESC, r.name`
+	case GroupByWeekday:
+		groupQuery = `SELECT EXTRACT(ISODOW FROM d.date)::int::text AS key, '' AS name,
+				SUM(d.votes)::int AS votes, COUNT(w.date)::int AS wins, COUNT(*)::int AS days
+			FROM (SELECT date, COUNT(*) AS votes FROM vote
+				WHERE date >= $1 AND date <= $2 AND ($3 = '' OR org_id::text = $3)
+				GROUP BY date) AS d
+			LEFT JOIN (SELECT DISTINCT date FROM winner
+				WHERE date >= $1 AND date <= $2 AND ($3 = '' OR org_id::text = $3)) AS w ON w.date = d.date
+			GROUP BY key
+			ORDER BY key`
+	default:
+		return nil, ErrInvalidGroup
+	}
+
+	rep := Votes{
+		From:    from,
+		To:      to,
+		GroupBy: groupBy,
+		Groups:  []Group{},
+	}
+
+	const qt = `SELECT COUNT(*) AS votes, COUNT(DISTINCT user_id) AS voters, COUNT(DISTINCT date) AS days,
+			(SELECT COUNT(*) FROM users WHERE deleted_at IS NULL AND ($3 = '' OR org_id::text = $3)) AS users
+		FROM vote
+		WHERE date >= $1 AND date <= $2 AND ($3 = '' OR org_id::text = $3)`
+	if err := sqlx.GetContext(ctx, conn, &rep, qt, from, to, org); err != nil {
+		return nil, errors.Wrap(err, "selecting vote totals")
+	}
+
+	if err := sqlx.SelectContext(ctx, conn, &rep.Groups, groupQuery, from, to, org); err != nil {
+		return nil, errors.Wrapf(err, "selecting votes by %s", groupBy)
+	}
+
+	// A user votes at most once a day so the votes of a day are its voters.
+	rep.Participation = participation(rep.Votes, rep.Days, rep.Users)
+	for i := range rep.Groups {
+		g := &rep.Groups[i]
+		if rep.Votes > 0 {
+			g.Share = float64(g.Votes) / float64(rep.Votes)
+		}
+		if groupBy == GroupByWeekday {
+			g.Name = weekday(g.Key)
+			g.Participation = participation(g.Votes, g.Days, rep.Users)
+		}
+	}
+
+	return &rep, nil
+}
+
+// participation returns the share of the users who voted on an average day of
+// the days with votes.
+func participation(votes, days, users int) float64 {
+	if days == 0 || users == 0 {
+		return 0
+	}
+	return float64(votes) / float64(days*users)
+}
+
+// weekday returns the name of the ISO day of the week.
+func weekday(key string) string {
+	if len(key) != 1 || key[0] < '1' || key[0] > '7' {
+		return key
+	}
+	return weekdays[key[0]-'0']
+}
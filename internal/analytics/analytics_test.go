@@ -0,0 +1,54 @@
+package analytics
+
+import (
+	"testing"
+
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// TestParticipation validates the share of the users voting on an average
+// day.
+func TestParticipation(t *testing.T) {
+	tt := []struct {
+		name  string
+		votes int
+		days  int
+		users int
+		want  float64
+	}{
+		{"everyone voting", 20, 2, 10, 1},
+		{"half voting", 10, 2, 10, 0.5},
+		{"no votes", 0, 0, 10, 0},
+		{"no users", 0, 0, 0, 0},
+	}
+
+	t.Log("Given the need to report the participation in the votes.")
+	{
+		for i, tc := range tt {
+			t.Logf("\tTest %d:\tWhen %s.", i, tc.name)
+			{
+				if got := participation(tc.votes, tc.days, tc.users); got != tc.want {
+					t.Fatalf("\t%s\tShould be %v : got %v.", tests.Failed, tc.want, got)
+				}
+				t.Logf("\t%s\tShould be %v.", tests.Success, tc.want)
+			}
+		}
+	}
+}
+
+// TestWeekday validates the names of the ISO days of the week.
+func TestWeekday(t *testing.T) {
+	t.Log("Given the need to name the weekdays of the report.")
+	{
+		t.Log("\tTest 0:\tWhen naming Monday and Sunday.")
+		{
+			if got := weekday("1"); got != "monday" {
+				t.Fatalf("\t%s\tShould name 1 monday : got %q.", tests.Failed, got)
+			}
+			if got := weekday("7"); got != "sunday" {
+				t.Fatalf("\t%s\tShould name 7 sunday : got %q.", tests.Failed, got)
+			}
+			t.Logf("\t%s\tShould name the days.", tests.Success)
+		}
+	}
+}
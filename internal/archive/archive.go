@@ -0,0 +1,293 @@
+// Package archive moves immutable historical data out of the database into
+// Parquet files in object storage. Files are partitioned by month and every
+// exported partition is recorded in the archive_manifest table.
+package archive
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+	"go.opentelemetry.io/otel"
+)
+
+// ErrNotClosed is returned when asked to archive a month which has not ended
+// yet so its data may still change.
+var ErrNotClosed = errors.New("Month has not ended yet")
+
+// Manifest records a single archived partition.
+type Manifest struct {
+	Dataset      string    `db:"dataset" json:"dataset"`
+	Month        time.Time `db:"month" json:"month"`
+	ObjectKey    string    `db:"object_key" json:"object_key"`
+	Rows         int       `db:"row_count" json:"row_count"`
+	DateExported time.Time `db:"date_exported" json:"date_exported"`
+}
+
+// dataset describes a table which can be archived. Rows are selected with
+// query and removed with the purge statements, all taking the start and end
+// of the month. The statements run in order so no foreign key is left
+// dangling. newRow creates the value each selected row is scanned into and
+// written to the Parquet file as.
+type dataset struct {
+	name   string
+	query  string
+	purge  []string
+	newRow func() scanner
+}
+
+// scanner is implemented by the Parquet row types.
+type scanner interface {
+	scan(rows *sqlx.Rows) error
+}
+
+// datasets lists everything archived by Export.
+//
+// Purging the votes also removes the vote events and the tallies of the month,
+// vote.Rebuild would otherwise replay the events and bring the votes back.
+var datasets = []dataset{
+	{
+		name: "votes",
+		query: `SELECT date, user_id, restaurant_id, org_id, weight, cast_by, time_voted
+			FROM vote WHERE date >= $1 AND date < $2 ORDER BY date`,
+		purge: []string{
+			`DELETE FROM vote WHERE date >= $1 AND date < $2`,
+			`DELETE FROM vote_event WHERE date >= $1 AND date < $2`,
+			`DELETE FROM menu_vote_tally WHERE date >= $1 AND date < $2`,
+		},
+		newRow: func() scanner { return new(voteRow) },
+	},
+	{
+		name: "orders",
+		query: `SELECT o.order_id, o.menu_id, o.restaurant_id, o.user_id, o.date, o.status,
+			o.total, o.discount, o.date_created, o.date_updated,
+			COALESCE((SELECT json_agg(json_build_object('name', i.name, 'quantity', i.quantity, 'unit_price', i.unit_price) ORDER BY i.position)
+				FROM order_item AS i WHERE i.order_id = o.order_id), '[]')::text
+			FROM orders AS o WHERE o.date >= $1 AND o.date < $2 ORDER BY o.date`,
+		purge: []string{
+			`DELETE FROM order_item WHERE order_id IN (SELECT order_id FROM orders WHERE date >= $1 AND date < $2)`,
+			`DELETE FROM orders WHERE date >= $1 AND date < $2`,
+		},
+		newRow: func() scanner { return new(orderRow) },
+	},
+	{
+		name: "audit",
+		query: `SELECT audit_id, entity_type, entity_id, restaurant_id, action, user_id, changes::text, date_created
+			FROM entity_audit WHERE date_created >= $1 AND date_created < $2 ORDER BY date_created`,
+		purge: []string{
+			`DELETE FROM entity_audit WHERE date_created >= $1 AND date_created < $2`,
+		},
+		newRow: func() scanner { return new(auditRow) },
+	},
+}
+
+// voteRow is the Parquet layout of an archived vote.
+type voteRow struct {
+	Date         int64  `parquet:"name=date, type=INT64, convertedtype=TIMESTAMP_MILLIS"`
+	UserID       string `parquet:"name=user_id, type=BYTE_ARRAY, convertedtype=UTF8"`
+	RestaurantID string `parquet:"name=restaurant_id, type=BYTE_ARRAY, convertedtype=UTF8"`
+	OrgID        string `parquet:"name=org_id, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Weight       int32  `parquet:"name=weight, type=INT32"`
+	CastBy       string `parquet:"name=cast_by, type=BYTE_ARRAY, convertedtype=UTF8"`
+	TimeVoted    int64  `parquet:"name=time_voted, type=INT64, convertedtype=TIMESTAMP_MILLIS"`
+}
+
+func (v *voteRow) scan(rows *sqlx.Rows) error {
+	var date time.Time
+	var timeVoted sql.NullTime
+	if err := rows.Scan(&date, &v.UserID, &v.RestaurantID, &v.OrgID, &v.Weight, &v.CastBy, &timeVoted); err != nil {
+		return err
+	}
+	v.Date = millis(date)
+	if timeVoted.Valid {
+		v.TimeVoted = millis(timeVoted.Time)
+	}
+	return nil
+}
+
+// orderRow is the Parquet layout of an archived order. Its items are kept as
+// a JSON array.
+type orderRow struct {
+	OrderID      string `parquet:"name=order_id, type=BYTE_ARRAY, convertedtype=UTF8"`
+	MenuID       string `parquet:"name=menu_id, type=BYTE_ARRAY, convertedtype=UTF8"`
+	RestaurantID string `parquet:"name=restaurant_id, type=BYTE_ARRAY, convertedtype=UTF8"`
+	UserID       string `parquet:"name=user_id, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Date         int64  `parquet:"name=date, type=INT64, convertedtype=TIMESTAMP_MILLIS"`
+	Status       string `parquet:"name=status, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Total        int32  `parquet:"name=total, type=INT32"`
+	Discount     int32  `parquet:"name=discount, type=INT32"`
+	DateCreated  int64  `parquet:"name=date_created, type=INT64, convertedtype=TIMESTAMP_MILLIS"`
+	DateUpdated  int64  `parquet:"name=date_updated, type=INT64, convertedtype=TIMESTAMP_MILLIS"`
+	Items        string `parquet:"name=items, type=BYTE_ARRAY, convertedtype=UTF8"`
+}
+
+func (o *orderRow) scan(rows *sqlx.Rows) error {
+	var date, created, updated time.Time
+	if err := rows.Scan(&o.OrderID, &o.MenuID, &o.RestaurantID, &o.UserID, &date, &o.Status,
+		&o.Total, &o.Discount, &created, &updated, &o.Items); err != nil {
+		return err
+	}
+	o.Date = millis(date)
+	o.DateCreated = millis(created)
+	o.DateUpdated = millis(updated)
+	return nil
+}
+
+// auditRow is the Parquet layout of an archived audit entry. Its changes are
+// kept as a JSON object.
+type auditRow struct {
+	AuditID      string `parquet:"name=audit_id, type=BYTE_ARRAY, convertedtype=UTF8"`
+	EntityType   string `parquet:"name=entity_type, type=BYTE_ARRAY, convertedtype=UTF8"`
+	EntityID     string `parquet:"name=entity_id, type=BYTE_ARRAY, convertedtype=UTF8"`
+	RestaurantID string `parquet:"name=restaurant_id, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Action       string `parquet:"name=action, type=BYTE_ARRAY, convertedtype=UTF8"`
+	UserID       string `parquet:"name=user_id, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Changes      string `parquet:"name=changes, type=BYTE_ARRAY, convertedtype=UTF8"`
+	DateCreated  int64  `parquet:"name=date_created, type=INT64, convertedtype=TIMESTAMP_MILLIS"`
+}
+
+func (a *auditRow) scan(rows *sqlx.Rows) error {
+	var created time.Time
+	if err := rows.Scan(&a.AuditID, &a.EntityType, &a.EntityID, &a.RestaurantID, &a.Action,
+		&a.UserID, &a.Changes, &created); err != nil {
+		return err
+	}
+	a.DateCreated = millis(created)
+	return nil
+}
+
+// Export archives every dataset for the month containing the given time.
+// Partitions found in the manifest are skipped so the job can safely be
+// rerun. When purge is set the archived rows are deleted from the database.
+func Export(ctx context.Context, db *sqlx.DB, store Storage, month time.Time, purge bool, now time.Time) ([]Manifest, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.archive.Export")
+	defer span.End()
+
+	from := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+	if !now.UTC().After(to) {
+		return nil, ErrNotClosed
+	}
+
+	manifests := []Manifest{}
+	for _, ds := range datasets {
+		m, err := exportDataset(ctx, db, store, ds, from, to, purge, now)
+		if err != nil {
+			return manifests, errors.Wrapf(err, "archiving %s for %s", ds.name, from.Format("2006-01"))
+		}
+		if m != nil {
+			manifests = append(manifests, *m)
+		}
+	}
+
+	return manifests, nil
+}
+
+// List returns the manifest of every archived partition.
+func List(ctx context.Context, db *sqlx.DB) ([]Manifest, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.archive.List")
+	defer span.End()
+
+	manifests := []Manifest{}
+	const q = `SELECT * FROM archive_manifest ORDER BY month, dataset`
+	if err := db.SelectContext(ctx, &manifests, q); err != nil {
+		return nil, errors.Wrap(err, "selecting manifests")
+	}
+
+	return manifests, nil
+}
+
+// exportDataset writes a single partition and records it in the manifest.
+func exportDataset(ctx context.Context, db *sqlx.DB, store Storage, ds dataset, from, to time.Time, purge bool, now time.Time) (*Manifest, error) {
+	var exists bool
+	const qe = `SELECT EXISTS (SELECT 1 FROM archive_manifest WHERE dataset = $1 AND month = $2)`
+	if err := db.GetContext(ctx, &exists, qe, ds.name, from); err != nil {
+		return nil, errors.Wrap(err, "checking manifest")
+	}
+	if exists {
+		return nil, nil
+	}
+
+	rows, err := db.QueryxContext(ctx, ds.query, from, to)
+	if err != nil {
+		return nil, errors.Wrap(err, "selecting rows")
+	}
+	defer rows.Close()
+
+	var buf bytes.Buffer
+	pw, err := writer.NewParquetWriterFromWriter(&buf, ds.newRow(), 1)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating parquet writer")
+	}
+	pw.CompressionType = parquet.CompressionCodec_SNAPPY
+
+	var n int
+	for rows.Next() {
+		row := ds.newRow()
+		if err := row.scan(rows); err != nil {
+			return nil, errors.Wrap(err, "scanning row")
+		}
+		if err := pw.Write(row); err != nil {
+			return nil, errors.Wrap(err, "writing parquet row")
+		}
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterating rows")
+	}
+	if err := pw.WriteStop(); err != nil {
+		return nil, errors.Wrap(err, "finishing parquet file")
+	}
+
+	m := Manifest{
+		Dataset:      ds.name,
+		Month:        from,
+		ObjectKey:    fmt.Sprintf("%s/year=%04d/month=%02d/%s.parquet", ds.name, from.Year(), from.Month(), ds.name),
+		Rows:         n,
+		DateExported: now.UTC(),
+	}
+
+	if err := store.Put(ctx, m.ObjectKey, buf.Bytes()); err != nil {
+		return nil, err
+	}
+
+	// The manifest entry and the purge are committed together so rows are
+	// never deleted without a record of where they went.
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "beginning transaction")
+	}
+	defer tx.Rollback()
+
+	const qi = `INSERT INTO archive_manifest
+		(dataset, month, object_key, row_count, date_exported)
+		VALUES ($1, $2, $3, $4, $5)`
+	if _, err := tx.ExecContext(ctx, qi, m.Dataset, m.Month, m.ObjectKey, m.Rows, m.DateExported); err != nil {
+		return nil, errors.Wrap(err, "inserting manifest")
+	}
+
+	if purge {
+		for _, q := range ds.purge {
+			if _, err := tx.ExecContext(ctx, q, from, to); err != nil {
+				return nil, errors.Wrap(err, "purging rows")
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrap(err, "committing manifest")
+	}
+
+	return &m, nil
+}
+
+// millis converts a time to milliseconds since the Unix epoch.
+func millis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
@@ -0,0 +1,153 @@
+package archive_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/archive"
+	"github.com/remisb/restaurant/internal/schema"
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// memStorage is an archive.Storage keeping the objects in memory.
+type memStorage struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+// Put implements the archive.Storage interface.
+func (s *memStorage) Put(ctx context.Context, key string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[key] = data
+	return nil
+}
+
+// history is a month of votes, orders and audit entries followed by a vote,
+// an order and an audit entry of the next month.
+const history = `
+INSERT INTO vote (date, user_id, restaurant_id, org_id, weight, cast_by, time_voted) VALUES
+	('2020-02-03', '5cf37266-3473-4006-984f-9325122678b7', '0ce90028-69cb-4e9c-9af0-7bbada50d5b6', '00000000-0000-0000-0000-000000000001', 2, 'delegate', '2020-02-03 09:00:00'),
+	('2020-02-04', '5cf37266-3473-4006-984f-9325122678b7', '71b8fb90-24eb-4012-9048-3ba210aac0f6', '00000000-0000-0000-0000-000000000001', 1, '', '2020-02-04 09:00:00'),
+	('2020-03-02', '5cf37266-3473-4006-984f-9325122678b7', '0ce90028-69cb-4e9c-9af0-7bbada50d5b6', '00000000-0000-0000-0000-000000000001', 1, '', '2020-03-02 09:00:00');
+INSERT INTO vote_event (type, date, user_id, restaurant_id, org_id, time_occurred, weight, cast_by) VALUES
+	('cast', '2020-02-03', '5cf37266-3473-4006-984f-9325122678b7', '0ce90028-69cb-4e9c-9af0-7bbada50d5b6', '00000000-0000-0000-0000-000000000001', '2020-02-03 09:00:00', 2, 'delegate'),
+	('cast', '2020-02-04', '5cf37266-3473-4006-984f-9325122678b7', '71b8fb90-24eb-4012-9048-3ba210aac0f6', '00000000-0000-0000-0000-000000000001', '2020-02-04 09:00:00', 1, ''),
+	('cast', '2020-03-02', '5cf37266-3473-4006-984f-9325122678b7', '0ce90028-69cb-4e9c-9af0-7bbada50d5b6', '00000000-0000-0000-0000-000000000001', '2020-03-02 09:00:00', 1, '');
+INSERT INTO menu_vote_tally (date, restaurant_id, votes) VALUES
+	('2020-02-03', '0ce90028-69cb-4e9c-9af0-7bbada50d5b6', 2),
+	('2020-02-04', '71b8fb90-24eb-4012-9048-3ba210aac0f6', 1),
+	('2020-03-02', '0ce90028-69cb-4e9c-9af0-7bbada50d5b6', 1);
+INSERT INTO orders (order_id, menu_id, restaurant_id, user_id, date, status, total, date_created, date_updated) VALUES
+	('a1f3c4d2-6f4e-4b8e-9c61-0d7d2f0b1a01', 'b2e4d5c3-7a5f-4c9f-8d72-1e8e3a1c2b02', '0ce90028-69cb-4e9c-9af0-7bbada50d5b6', '5cf37266-3473-4006-984f-9325122678b7', '2020-02-03', 'delivered', 850, '2020-02-03 10:00:00', '2020-02-03 12:00:00'),
+	('c3a5e6d4-8b6a-4dae-9e83-2f9f4b2d3c03', 'b2e4d5c3-7a5f-4c9f-8d72-1e8e3a1c2b02', '0ce90028-69cb-4e9c-9af0-7bbada50d5b6', '5cf37266-3473-4006-984f-9325122678b7', '2020-03-02', 'placed', 420, '2020-03-02 10:00:00', '2020-03-02 10:00:00');
+INSERT INTO order_item (order_id, position, name, quantity, unit_price) VALUES
+	('a1f3c4d2-6f4e-4b8e-9c61-0d7d2f0b1a01', 0, 'Soup', 2, 425),
+	('c3a5e6d4-8b6a-4dae-9e83-2f9f4b2d3c03', 0, 'Salad', 1, 420);
+INSERT INTO entity_audit (audit_id, entity_type, entity_id, restaurant_id, action, user_id, changes, date_created) VALUES
+	('d4b6f7e5-9c7b-4ebf-8f94-3a0a5c3e4d04', 'restaurant', '0ce90028-69cb-4e9c-9af0-7bbada50d5b6', '0ce90028-69cb-4e9c-9af0-7bbada50d5b6', 'update', '5cf37266-3473-4006-984f-9325122678b7', '{"name": ["Paiks", "Paikis"]}', '2020-02-10 08:00:00'),
+	('e5c7a8f6-ad8c-4fc0-9a05-4b1b6d4f5e05', 'restaurant', '0ce90028-69cb-4e9c-9af0-7bbada50d5b6', '0ce90028-69cb-4e9c-9af0-7bbada50d5b6', 'update', '5cf37266-3473-4006-984f-9325122678b7', '{}', '2020-03-02 08:00:00');
+`
+
+// TestExport validates a month is archived and purged with the rows
+// depending on it while the next month is left alone.
+func TestExport(t *testing.T) {
+	db, teardown := tests.NewUnit(t)
+	defer teardown()
+
+	if err := schema.SeedProfile(db, "dev"); err != nil {
+		t.Fatalf("seeding: %s", err)
+	}
+	if _, err := db.Exec(history); err != nil {
+		t.Fatalf("seeding history: %s", err)
+	}
+
+	t.Log("Given the need to archive the history of a month.")
+	{
+		ctx := tests.Context()
+		month := time.Date(2020, time.February, 1, 0, 0, 0, 0, time.UTC)
+		store := &memStorage{objects: map[string][]byte{}}
+
+		t.Log("\tTest 0:\tWhen archiving a month which has not ended yet.")
+		{
+			now := time.Date(2020, time.February, 20, 0, 0, 0, 0, time.UTC)
+			if _, err := archive.Export(ctx, db, store, month, true, now); errors.Cause(err) != archive.ErrNotClosed {
+				t.Fatalf("\t%s\tShould refuse to archive the month : %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould refuse to archive the month.", tests.Success)
+		}
+
+		t.Log("\tTest 1:\tWhen archiving and purging an ended month.")
+		{
+			now := time.Date(2020, time.March, 2, 12, 0, 0, 0, time.UTC)
+			manifests, err := archive.Export(ctx, db, store, month, true, now)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to archive the month : %s.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to archive the month.", tests.Success)
+
+			rows := map[string]int{}
+			for _, m := range manifests {
+				rows[m.Dataset] = m.Rows
+				if len(store.objects[m.ObjectKey]) == 0 {
+					t.Fatalf("\t%s\tShould store the %s at %s.", tests.Failed, m.Dataset, m.ObjectKey)
+				}
+				if !strings.HasPrefix(m.ObjectKey, m.Dataset+"/year=2020/month=02/") {
+					t.Fatalf("\t%s\tShould partition the %s by month : got %s.", tests.Failed, m.Dataset, m.ObjectKey)
+				}
+			}
+			want := map[string]int{"votes": 2, "orders": 1, "audit": 1}
+			for ds, n := range want {
+				if rows[ds] != n {
+					t.Fatalf("\t%s\tShould archive %d %s : got %d.", tests.Failed, n, ds, rows[ds])
+				}
+			}
+			t.Logf("\t%s\tShould archive the votes, orders and audit entries of the month.", tests.Success)
+
+			left := map[string]string{
+				"vote":            `SELECT COUNT(*) FROM vote`,
+				"vote_event":      `SELECT COUNT(*) FROM vote_event`,
+				"menu_vote_tally": `SELECT COUNT(*) FROM menu_vote_tally`,
+				"orders":          `SELECT COUNT(*) FROM orders`,
+				"order_item":      `SELECT COUNT(*) FROM order_item`,
+				"entity_audit":    `SELECT COUNT(*) FROM entity_audit`,
+			}
+			for table, q := range left {
+				var n int
+				if err := db.GetContext(ctx, &n, q); err != nil {
+					t.Fatalf("\t%s\tShould be able to count the %s rows : %s.", tests.Failed, table, err)
+				}
+				if n != 1 {
+					t.Fatalf("\t%s\tShould keep only the %s row of the next month : got %d.", tests.Failed, table, n)
+				}
+			}
+			t.Logf("\t%s\tShould purge the month and keep the next one.", tests.Success)
+		}
+
+		t.Log("\tTest 2:\tWhen archiving the same month again.")
+		{
+			now := time.Date(2020, time.March, 3, 12, 0, 0, 0, time.UTC)
+			manifests, err := archive.Export(ctx, db, store, month, true, now)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to rerun the archive : %s.", tests.Failed, err)
+			}
+			if len(manifests) != 0 {
+				t.Fatalf("\t%s\tShould skip the archived partitions : got %+v.", tests.Failed, manifests)
+			}
+			t.Logf("\t%s\tShould skip the archived partitions.", tests.Success)
+
+			all, err := archive.List(ctx, db)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to list the manifest : %s.", tests.Failed, err)
+			}
+			if len(all) != 3 {
+				t.Fatalf("\t%s\tShould list a partition per dataset : got %d.", tests.Failed, len(all))
+			}
+			t.Logf("\t%s\tShould list a partition per dataset.", tests.Success)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package archive
+
+import (
+	"context"
+
+	"github.com/remisb/restaurant/internal/platform/s3"
+)
+
+// Storage is where archived files are written to.
+type Storage interface {
+	Put(ctx context.Context, key string, data []byte) error
+}
+
+// S3 stores archived files in a bucket of any S3 compatible object storage.
+type S3 struct {
+	bucket *s3.Bucket
+}
+
+// NewS3 constructs a Storage writing to the bucket.
+func NewS3(bucket *s3.Bucket) *S3 {
+	return &S3{bucket: bucket}
+}
+
+// Put implements the Storage interface.
+func (s *S3) Put(ctx context.Context, key string, data []byte) error {
+	return s.bucket.Put(ctx, key, "application/vnd.apache.parquet", data)
+}
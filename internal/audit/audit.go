@@ -0,0 +1,116 @@
+// Package audit records who changed the restaurants and menus, when and
+// what. Entries are written in the transaction of the change so the history
+// never holds a change which was rolled back.
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+)
+
+// ignored are the fields changing on every write. They are left out of the
+// diffs as they tell nothing the entry does not.
+var ignored = map[string]bool{
+	"version":      true,
+	"date_created": true,
+	"date_updated": true,
+}
+
+// Diff compares the JSON documents of the entities field by field. Either
+// may be nil when the entity is created or deleted.
+func Diff(before, after interface{}) (Changes, error) {
+	b, err := fields(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := fields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := Changes{}
+	for k, av := range a {
+		if ignored[k] {
+			continue
+		}
+		bv, ok := b[k]
+		if ok && bytes.Equal(bv, av) {
+			continue
+		}
+		changes[k] = Change{Before: bv, After: av}
+	}
+	for k, bv := range b {
+		if _, ok := a[k]; ok || ignored[k] {
+			continue
+		}
+		changes[k] = Change{Before: bv}
+	}
+
+	return changes, nil
+}
+
+// fields returns the encoded fields of the JSON document of the entity.
+func fields(v interface{}) (map[string]json.RawMessage, error) {
+	m := map[string]json.RawMessage{}
+	if v == nil {
+		return m, nil
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, errors.Wrap(err, "encoding entity")
+	}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, errors.Wrap(err, "decoding entity fields")
+	}
+
+	return m, nil
+}
+
+// Record adds the change to the history. It must be given the transaction
+// making the change. Updates leaving every field as it was are not recorded.
+func Record(ctx context.Context, tx sqlx.ExecerContext, ne NewEntry, now time.Time) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.audit.Record")
+	defer span.End()
+
+	changes, err := Diff(ne.Before, ne.After)
+	if err != nil {
+		return err
+	}
+	if ne.Action == ActionUpdate && len(changes) == 0 {
+		return nil
+	}
+
+	const q = `INSERT INTO entity_audit
+		(audit_id, entity_type, entity_id, restaurant_id, action, user_id, changes, date_created)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	_, err = tx.ExecContext(ctx, q, uuid.New().String(), ne.EntityType, ne.EntityID, ne.RestaurantID,
+		ne.Action, ne.UserID, changes, now.UTC())
+	if err != nil {
+		return errors.Wrapf(err, "inserting audit entry of %s %s", ne.EntityType, ne.EntityID)
+	}
+
+	return nil
+}
+
+// List returns the history of the restaurant and its menus, newest first.
+func List(ctx context.Context, db sqlx.QueryerContext, restaurantID string) ([]Entry, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.audit.List")
+	defer span.End()
+
+	entries := []Entry{}
+	const q = `SELECT * FROM entity_audit WHERE restaurant_id = $1
+		ORDER BY date_created DESC, audit_id`
+	if err := sqlx.SelectContext(ctx, db, &entries, q, restaurantID); err != nil {
+		return nil, errors.Wrap(err, "selecting audit entries")
+	}
+
+	return entries, nil
+}
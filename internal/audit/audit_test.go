@@ -0,0 +1,58 @@
+package audit
+
+import (
+	"testing"
+
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// TestDiff validates only the changed fields are kept and the fields
+// changing on every write are left out.
+func TestDiff(t *testing.T) {
+	type entity struct {
+		Name    string `json:"name"`
+		Phone   string `json:"phone"`
+		Version int    `json:"version"`
+	}
+	before := entity{Name: "Nacho", Phone: "123", Version: 1}
+
+	t.Log("Given the need to record the changes of an entity.")
+	{
+		t.Log("\tTest 0:\tWhen updating a field.")
+		{
+			changes, err := Diff(before, entity{Name: "Taco", Phone: "123", Version: 2})
+			if err != nil {
+				t.Fatalf("\t%s\tShould compare the entities : %s.", tests.Failed, err)
+			}
+			c, ok := changes["name"]
+			if len(changes) != 1 || !ok || string(c.Before) != `"Nacho"` || string(c.After) != `"Taco"` {
+				t.Fatalf("\t%s\tShould only keep the name : got %+v.", tests.Failed, changes)
+			}
+			t.Logf("\t%s\tShould only keep the name.", tests.Success)
+		}
+
+		t.Log("\tTest 1:\tWhen only the version changes.")
+		{
+			changes, err := Diff(before, entity{Name: "Nacho", Phone: "123", Version: 2})
+			if err != nil {
+				t.Fatalf("\t%s\tShould compare the entities : %s.", tests.Failed, err)
+			}
+			if len(changes) != 0 {
+				t.Fatalf("\t%s\tShould find no changes : got %+v.", tests.Failed, changes)
+			}
+			t.Logf("\t%s\tShould find no changes.", tests.Success)
+		}
+
+		t.Log("\tTest 2:\tWhen creating an entity.")
+		{
+			changes, err := Diff(nil, before)
+			if err != nil {
+				t.Fatalf("\t%s\tShould compare the entities : %s.", tests.Failed, err)
+			}
+			if len(changes) != 2 || changes["phone"].Before != nil || string(changes["phone"].After) != `"123"` {
+				t.Fatalf("\t%s\tShould keep every field as added : got %+v.", tests.Failed, changes)
+			}
+			t.Logf("\t%s\tShould keep every field as added.", tests.Success)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package audit
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// These are the types of the audited entities.
+const (
+	EntityRestaurant = "restaurant"
+	EntityMenu       = "menu"
+)
+
+// These are the actions recorded for an entity.
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// Entry is a change made to an entity by a user. Entries of menus carry the
+// restaurant of the menu so the history of a restaurant includes its menus.
+type Entry struct {
+	ID           string    `db:"audit_id" json:"id"`
+	EntityType   string    `db:"entity_type" json:"entity_type"`
+	EntityID     string    `db:"entity_id" json:"entity_id"`
+	RestaurantID string    `db:"restaurant_id" json:"restaurant_id"`
+	Action       string    `db:"action" json:"action"`
+	UserID       string    `db:"user_id" json:"user_id"`
+	Changes      Changes   `db:"changes" json:"changes"`
+	DateCreated  time.Time `db:"date_created" json:"date_created"`
+}
+
+// NewEntry is what we require to record a change. Before is blank for
+// created entities and After for deleted ones.
+type NewEntry struct {
+	EntityType   string
+	EntityID     string
+	RestaurantID string
+	Action       string
+	UserID       string
+	Before       interface{}
+	After        interface{}
+}
+
+// Change is the value of a field before and after a change.
+type Change struct {
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
+
+// Changes are the changed fields of an entity stored as a JSON document.
+type Changes map[string]Change
+
+// Value implements the driver.Valuer interface.
+func (c Changes) Value() (driver.Value, error) {
+	if c == nil {
+		c = Changes{}
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return nil, errors.Wrap(err, "encoding changes")
+	}
+	return string(b), nil
+}
+
+// Scan implements the sql.Scanner interface.
+func (c *Changes) Scan(src interface{}) error {
+	var b []byte
+	switch v := src.(type) {
+	case nil:
+		*c = Changes{}
+		return nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return errors.Errorf("cannot scan %T into changes", src)
+	}
+	if err := json.Unmarshal(b, c); err != nil {
+		return errors.Wrap(err, "decoding changes")
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+// Package authz decides what the users of the API may do with its resources.
+// Handlers and domain functions ask Allowed instead of comparing roles and
+// owners themselves, so an action gets the same answer wherever it is checked.
+package authz
+
+import (
+	"github.com/remisb/restaurant/internal/platform/auth"
+)
+
+// Action is what a user wants to do with a resource.
+type Action string
+
+// These are the actions checked by the policies.
+const (
+	Read   Action = "read"
+	Create Action = "create"
+	Update Action = "update"
+	Delete Action = "delete"
+
+	// Manage is running the business of a resource without changing it, like
+	// following the orders of a restaurant.
+	Manage Action = "manage"
+)
+
+// Kind is the kind of resource an action is taken on.
+type Kind string
+
+// These are the kinds of resources with a policy.
+const (
+	Restaurant Kind = "restaurant"
+	Menu       Kind = "menu"
+	User       Kind = "user"
+	Comment    Kind = "comment"
+	Order      Kind = "order"
+
+	// Service is the service as a whole: its organizations, feature flags
+	// and data retention.
+	Service Kind = "service"
+)
+
+// Resource is what an action is taken on.
+type Resource struct {
+	Kind Kind
+
+	// Owner is the ID of the user the resource belongs to: the owner of the
+	// restaurant for restaurants and their menus, the user itself for users,
+	// the author of a comment and the user who placed an order.
+	Owner string
+}
+
+// policy reports if the subject may take the action on the resource.
+type policy func(sub auth.Claims, action Action, res Resource) bool
+
+// policies holds the policy of each kind of resource. A kind without one is
+// forbidden to everyone.
+var policies = map[Kind]policy{
+	Restaurant: restaurantPolicy,
+	Menu:       menuPolicy,
+	User:       userPolicy,
+	Comment:    commentPolicy,
+	Order:      orderPolicy,
+	Service:    servicePolicy,
+}
+
+// Allowed reports if the subject may take the action on the resource.
+func Allowed(sub auth.Claims, action Action, res Resource) bool {
+	p, ok := policies[res.Kind]
+	if !ok {
+		return false
+	}
+	return p(sub, action, res)
+}
+
+// restaurantPolicy lets anyone read and create restaurants. Only their owner
+// or an admin may change, delete or manage them.
+func restaurantPolicy(sub auth.Claims, action Action, res Resource) bool {
+	switch action {
+	case Read, Create:
+		return true
+	case Update, Delete, Manage:
+		return isOwner(sub, res) || sub.HasRole(auth.RoleAdmin)
+	}
+	return false
+}
+
+// menuPolicy lets anyone read menus. Like the restaurant itself, only its
+// owner or an admin publishes and changes its menus.
+func menuPolicy(sub auth.Claims, action Action, res Resource) bool {
+	switch action {
+	case Read:
+		return true
+	case Create, Update, Delete:
+		return isOwner(sub, res) || sub.HasRole(auth.RoleAdmin)
+	}
+	return false
+}
+
+// userPolicy lets users read and change their own account. Admins may do
+// anything with any account.
+func userPolicy(sub auth.Claims, action Action, res Resource) bool {
+	if sub.HasRole(auth.RoleAdmin) {
+		return true
+	}
+	switch action {
+	case Read, Update:
+		return isOwner(sub, res)
+	}
+	return false
+}
+
+// commentPolicy lets anyone read and write comments. Only their author or an
+// admin may delete them.
+func commentPolicy(sub auth.Claims, action Action, res Resource) bool {
+	switch action {
+	case Read, Create:
+		return true
+	case Delete:
+		return isOwner(sub, res) || sub.HasRole(auth.RoleAdmin)
+	}
+	return false
+}
+
+// orderPolicy lets users place orders and only the user who placed an order
+// read or cancel it.
+func orderPolicy(sub auth.Claims, action Action, res Resource) bool {
+	switch action {
+	case Create:
+		return true
+	case Read, Update:
+		return isOwner(sub, res)
+	}
+	return false
+}
+
+// servicePolicy lets the admins of the default organization, the operators
+// of the service, do anything with it.
+func servicePolicy(sub auth.Claims, action Action, res Resource) bool {
+	return sub.HasRole(auth.RoleAdmin) && sub.Org() == auth.DefaultOrg
+}
+
+// isOwner reports if the subject owns the resource. A resource without an
+// owner belongs to no one.
+func isOwner(sub auth.Claims, res Resource) bool {
+	return res.Owner != "" && res.Owner == sub.Subject
+}
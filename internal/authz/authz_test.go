@@ -0,0 +1,109 @@
+package authz
+
+import (
+	"testing"
+	"time"
+
+	"github.com/remisb/restaurant/internal/platform/auth"
+)
+
+// TestAllowed validates the policy of every kind of resource.
+func TestAllowed(t *testing.T) {
+	const (
+		ownerID  = "a2b0639f-2cc6-44b8-b97b-15d69dbb511e"
+		otherID  = "5cf37266-3473-4006-984f-9325122678b7"
+		otherOrg = "d9c3ac4f-84a6-4d4c-9b1e-1b2a0d7c0e4b"
+	)
+
+	now := time.Date(2019, time.March, 24, 0, 0, 0, 0, time.UTC)
+	owner := auth.NewClaims(ownerID, []string{auth.RoleUser}, now, time.Hour)
+	user := auth.NewClaims(otherID, []string{auth.RoleUser}, now, time.Hour)
+	admin := auth.NewClaims(otherID, []string{auth.RoleAdmin}, now, time.Hour)
+	tenantAdmin := auth.NewClaims(otherID, []string{auth.RoleAdmin}, now, time.Hour)
+	tenantAdmin.OrgID = otherOrg
+
+	tt := []struct {
+		name    string
+		sub     auth.Claims
+		action  Action
+		kind    Kind
+		allowed bool
+	}{
+		{"user reads restaurant", user, Read, Restaurant, true},
+		{"user creates restaurant", user, Create, Restaurant, true},
+		{"owner updates restaurant", owner, Update, Restaurant, true},
+		{"admin updates restaurant", admin, Update, Restaurant, true},
+		{"user updates restaurant", user, Update, Restaurant, false},
+		{"owner deletes restaurant", owner, Delete, Restaurant, true},
+		{"admin deletes restaurant", admin, Delete, Restaurant, true},
+		{"user deletes restaurant", user, Delete, Restaurant, false},
+		{"owner manages restaurant", owner, Manage, Restaurant, true},
+		{"admin manages restaurant", admin, Manage, Restaurant, true},
+		{"user manages restaurant", user, Manage, Restaurant, false},
+
+		{"user reads menu", user, Read, Menu, true},
+		{"owner creates menu", owner, Create, Menu, true},
+		{"admin creates menu", admin, Create, Menu, true},
+		{"user creates menu", user, Create, Menu, false},
+		{"owner updates menu", owner, Update, Menu, true},
+		{"admin updates menu", admin, Update, Menu, true},
+		{"user updates menu", user, Update, Menu, false},
+		{"owner deletes menu", owner, Delete, Menu, true},
+		{"admin deletes menu", admin, Delete, Menu, true},
+		{"user deletes menu", user, Delete, Menu, false},
+
+		{"user reads self", owner, Read, User, true},
+		{"user reads other", user, Read, User, false},
+		{"admin reads other", admin, Read, User, true},
+		{"user updates self", owner, Update, User, true},
+		{"user updates other", user, Update, User, false},
+		{"user creates user", user, Create, User, false},
+		{"admin creates user", admin, Create, User, true},
+		{"user deletes self", owner, Delete, User, false},
+		{"admin deletes user", admin, Delete, User, true},
+
+		{"user reads comment", user, Read, Comment, true},
+		{"user writes comment", user, Create, Comment, true},
+		{"author deletes comment", owner, Delete, Comment, true},
+		{"admin deletes comment", admin, Delete, Comment, true},
+		{"user deletes comment", user, Delete, Comment, false},
+		{"author updates comment", owner, Update, Comment, false},
+
+		{"user places order", user, Create, Order, true},
+		{"user reads own order", owner, Read, Order, true},
+		{"user reads order", user, Read, Order, false},
+		{"user cancels own order", owner, Update, Order, true},
+		{"user cancels order", user, Update, Order, false},
+		{"admin cancels order", admin, Update, Order, false},
+
+		{"operator manages service", admin, Manage, Service, true},
+		{"tenant admin manages service", tenantAdmin, Manage, Service, false},
+		{"user manages service", user, Manage, Service, false},
+
+		{"admin unknown kind", admin, Read, Kind("unknown"), false},
+		{"owner unknown action", owner, Action("unknown"), Restaurant, false},
+	}
+
+	t.Log("Given the need to authorize the actions of users.")
+	{
+		for i, tc := range tt {
+			t.Logf("\tTest %d:\tWhen %s.", i, tc.name)
+			{
+				res := Resource{Kind: tc.kind, Owner: ownerID}
+				if got := Allowed(tc.sub, tc.action, res); got != tc.allowed {
+					t.Fatalf("\t✗\tShould be allowed %v : got %v.", tc.allowed, got)
+				}
+				t.Logf("\t✓\tShould be allowed %v.", tc.allowed)
+			}
+		}
+	}
+
+	t.Log("\tTest 48:\tWhen a resource has no owner.")
+	{
+		anonymous := auth.NewClaims("", []string{auth.RoleUser}, now, time.Hour)
+		if Allowed(anonymous, Update, Resource{Kind: Menu}) {
+			t.Fatal("\t✗\tShould not be owned by a subject without ID.")
+		}
+		t.Log("\t✓\tShould not be owned by a subject without ID.")
+	}
+}
@@ -0,0 +1,252 @@
+// Package broadcast sends urgent messages to every member over all available
+// channels and tracks whether each recipient received and confirmed them.
+package broadcast
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"go.opentelemetry.io/otel"
+)
+
+// Predefined errors identify expected failure conditions.
+var (
+	// ErrNotFound is used when a specific Broadcast is requested but does not exist.
+	ErrNotFound = errors.New("Broadcast not found")
+
+	// ErrInvalidID is used when an invalid UUID is provided.
+	ErrInvalidID = errors.New("ID is not in its proper form")
+)
+
+// Create stores the broadcast along with a pending delivery for every member
+// of the organization of the sender and channel, named as the channels name
+// themselves. The deliveries are carried out by Deliver, and by the Stream
+// for the server-sent events.
+func Create(ctx context.Context, db *sqlx.DB, user auth.Claims, nb NewBroadcast, channels []string, now time.Time) (*Broadcast, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.broadcast.Create")
+	defer span.End()
+
+	b := Broadcast{
+		ID:          uuid.New().String(),
+		OrgID:       user.Org(),
+		Message:     nb.Message,
+		SenderID:    user.Subject,
+		DateCreated: now.UTC(),
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "beginning transaction")
+	}
+	defer tx.Rollback()
+
+	const qb = `INSERT INTO broadcast
+		(broadcast_id, org_id, message, sender_user_id, date_created)
+		VALUES ($1, $2, $3, $4, $5)`
+	if _, err := tx.ExecContext(ctx, qb, b.ID, b.OrgID, b.Message, b.SenderID, b.DateCreated); err != nil {
+		return nil, errors.Wrap(err, "inserting broadcast")
+	}
+
+	const qd = `INSERT INTO broadcast_delivery
+		(broadcast_id, user_id, channel, status, error)
+		SELECT $1, user_id, $2, $3, '' FROM users WHERE org_id = $4 AND deleted_at IS NULL`
+	for _, ch := range channels {
+		if _, err := tx.ExecContext(ctx, qd, b.ID, ch, StatusPending, user.Org()); err != nil {
+			return nil, errors.Wrapf(err, "inserting %s deliveries", ch)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrap(err, "committing broadcast")
+	}
+
+	return &b, nil
+}
+
+// Deliver sends up to limit of the pending deliveries over their channels
+// and records the outcome. A failure to reach one recipient does not stop
+// delivery to the others. The deliveries stay locked until then so the
+// worker of another replica does not send them at the same time. It returns
+// the number of deliveries attempted.
+func Deliver(ctx context.Context, db *sqlx.DB, channels []Channel, limit int, now time.Time) (int, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.broadcast.Deliver")
+	defer span.End()
+
+	byName := make(map[string]Channel, len(channels))
+	names := make([]string, 0, len(channels))
+	for _, ch := range channels {
+		byName[ch.Name()] = ch
+		names = append(names, ch.Name())
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, errors.Wrap(err, "beginning transaction")
+	}
+	defer tx.Rollback()
+
+	var pending []struct {
+		Broadcast
+		Recipient
+		Channel string `db:"channel"`
+	}
+	const qp = `SELECT b.*, u.user_id, u.name, u.email, d.channel FROM broadcast_delivery AS d
+		JOIN broadcast AS b ON b.broadcast_id = d.broadcast_id
+		JOIN users AS u ON u.user_id = d.user_id
+		WHERE d.status = $1 AND d.channel = ANY($2)
+		ORDER BY b.date_created, d.user_id
+		LIMIT $3
+		FOR UPDATE OF d SKIP LOCKED`
+	if err := tx.SelectContext(ctx, &pending, qp, StatusPending, pq.Array(names), limit); err != nil {
+		return 0, errors.Wrap(err, "selecting pending deliveries")
+	}
+
+	for _, p := range pending {
+		status, msg := StatusSent, ""
+		if err := byName[p.Channel].Send(ctx, p.Recipient, p.Broadcast); err != nil {
+			status, msg = StatusFailed, err.Error()
+		}
+
+		const qu = `UPDATE broadcast_delivery SET
+			"status" = $4,
+			"error" = $5,
+			"date_sent" = $6
+			WHERE broadcast_id = $1 AND user_id = $2 AND channel = $3`
+		if _, err := tx.ExecContext(ctx, qu, p.Broadcast.ID, p.UserID, p.Channel, status, msg, now.UTC()); err != nil {
+			return 0, errors.Wrapf(err, "updating %s delivery", p.Channel)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, errors.Wrap(err, "committing deliveries")
+	}
+
+	return len(pending), nil
+}
+
+// Retrieve finds the broadcast identified by a given ID in the organization
+// of the claims in ctx.
+func Retrieve(ctx context.Context, db *sqlx.DB, id string) (*Broadcast, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.broadcast.Retrieve")
+	defer span.End()
+
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrInvalidID
+	}
+
+	var b Broadcast
+	const q = `SELECT * FROM broadcast WHERE broadcast_id = $1
+		AND ($2 = '' OR org_id::text = $2)`
+	if err := db.GetContext(ctx, &b, q, id, auth.Org(ctx)); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, errors.Wrapf(err, "selecting broadcast %q", id)
+	}
+
+	return &b, nil
+}
+
+// RetrieveReport builds the delivery and confirmation report of a broadcast
+// of the organization of the claims in ctx.
+func RetrieveReport(ctx context.Context, db *sqlx.DB, id string) (*Report, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.broadcast.RetrieveReport")
+	defer span.End()
+
+	b, err := Retrieve(ctx, db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	r := Report{
+		Broadcast:     *b,
+		Channels:      []ChannelReport{},
+		Deliveries:    []Delivery{},
+		Confirmations: []Confirmation{},
+	}
+
+	const qd = `SELECT * FROM broadcast_delivery WHERE broadcast_id = $1 ORDER BY user_id, channel`
+	if err := db.SelectContext(ctx, &r.Deliveries, qd, id); err != nil {
+		return nil, errors.Wrap(err, "selecting deliveries")
+	}
+
+	const qc = `SELECT * FROM broadcast_confirmation WHERE broadcast_id = $1 ORDER BY date_confirmed`
+	if err := db.SelectContext(ctx, &r.Confirmations, qc, id); err != nil {
+		return nil, errors.Wrap(err, "selecting confirmations")
+	}
+
+	const qs = `SELECT channel,
+			COUNT(*) FILTER (WHERE status = 'PENDING') AS pending,
+			COUNT(*) FILTER (WHERE status = 'SENT') AS sent,
+			COUNT(*) FILTER (WHERE status = 'FAILED') AS failed
+		FROM broadcast_delivery WHERE broadcast_id = $1
+		GROUP BY channel ORDER BY channel`
+	if err := db.SelectContext(ctx, &r.Channels, qs, id); err != nil {
+		return nil, errors.Wrap(err, "selecting channel summary")
+	}
+
+	recipients := map[string]bool{}
+	for _, d := range r.Deliveries {
+		recipients[d.UserID] = true
+	}
+	r.Recipients = len(recipients)
+	r.Confirmed = len(r.Confirmations)
+
+	return &r, nil
+}
+
+// ListUnconfirmed gets the broadcasts the user has not confirmed yet, newest
+// first. This is the in-app inbox of urgent messages.
+func ListUnconfirmed(ctx context.Context, db *sqlx.DB, userID string) ([]Broadcast, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.broadcast.ListUnconfirmed")
+	defer span.End()
+
+	broadcasts := []Broadcast{}
+	const q = `SELECT DISTINCT b.* FROM broadcast AS b
+		JOIN broadcast_delivery AS d ON d.broadcast_id = b.broadcast_id
+		LEFT JOIN broadcast_confirmation AS c ON c.broadcast_id = b.broadcast_id AND c.user_id = d.user_id
+		WHERE d.user_id = $1 AND c.user_id IS NULL
+		ORDER BY b.date_created DESC`
+	if err := db.SelectContext(ctx, &broadcasts, q, userID); err != nil {
+		return nil, errors.Wrap(err, "selecting unconfirmed broadcasts")
+	}
+
+	return broadcasts, nil
+}
+
+// Confirm records that the user acknowledged the broadcast. Only its
+// recipients may confirm it, the broadcast is not found for anyone else.
+// Confirming more than once keeps the first confirmation.
+func Confirm(ctx context.Context, db *sqlx.DB, user auth.Claims, id string, now time.Time) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.broadcast.Confirm")
+	defer span.End()
+
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrInvalidID
+	}
+
+	var recipient bool
+	const qr = `SELECT EXISTS (SELECT 1 FROM broadcast_delivery WHERE broadcast_id = $1 AND user_id = $2)`
+	if err := db.GetContext(ctx, &recipient, qr, id, user.Subject); err != nil {
+		return errors.Wrap(err, "selecting delivery")
+	}
+	if !recipient {
+		return ErrNotFound
+	}
+
+	const q = `INSERT INTO broadcast_confirmation
+		(broadcast_id, user_id, date_confirmed)
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING`
+	if _, err := db.ExecContext(ctx, q, id, user.Subject, now.UTC()); err != nil {
+		return errors.Wrap(err, "inserting confirmation")
+	}
+
+	return nil
+}
@@ -0,0 +1,208 @@
+package broadcast_test
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/broadcast"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/clock"
+	"github.com/remisb/restaurant/internal/platform/database"
+	"github.com/remisb/restaurant/internal/schema"
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// The members of the organization of the dev seed.
+const (
+	adminID = "5cf37266-3473-4006-984f-9325122678b7"
+	userID  = "45b5fbd3-755f-4379-8f07-a58d4a30fa2f"
+)
+
+// unreachable is a broadcast.Channel failing to reach one of the members.
+type unreachable struct {
+	userID string
+}
+
+// Name implements the broadcast.Channel interface.
+func (u unreachable) Name() string {
+	return "email"
+}
+
+// Send implements the broadcast.Channel interface.
+func (u unreachable) Send(ctx context.Context, to broadcast.Recipient, b broadcast.Broadcast) error {
+	if to.UserID == u.userID {
+		return errors.New("mailbox full")
+	}
+	return nil
+}
+
+// summary returns the report of the channel of the broadcast.
+func summary(t *testing.T, r *broadcast.Report, channel string) broadcast.ChannelReport {
+	for _, c := range r.Channels {
+		if c.Channel == channel {
+			return c
+		}
+	}
+	t.Fatalf("\t%s\tShould report the %s channel : got %+v.", tests.Failed, channel, r.Channels)
+	return broadcast.ChannelReport{}
+}
+
+// TestBroadcast validates a broadcast reaches the members over each channel
+// and the report tells how far it went.
+func TestBroadcast(t *testing.T) {
+	db, teardown := tests.NewUnit(t)
+	defer teardown()
+
+	if err := schema.SeedProfile(db, "dev"); err != nil {
+		t.Fatalf("seeding: %s", err)
+	}
+
+	now := time.Date(2020, time.March, 2, 9, 0, 0, 0, time.UTC)
+	admin := auth.NewClaims(adminID, []string{auth.RoleAdmin, auth.RoleUser}, now, time.Hour)
+	channels := []string{broadcast.Inbox{}.Name(), broadcast.ChannelStream, "email"}
+
+	t.Log("Given the need to reach every member with an urgent message.")
+	{
+		ctx := tests.Context()
+
+		var b *broadcast.Broadcast
+
+		t.Log("\tTest 0:\tWhen an admin sends a broadcast.")
+		{
+			var err error
+			b, err = broadcast.Create(ctx, db, admin, broadcast.NewBroadcast{Message: "Leave the cafeteria"}, channels, now)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to create the broadcast : %s.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to create the broadcast.", tests.Success)
+
+			r, err := broadcast.RetrieveReport(ctx, db, b.ID)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to retrieve the report : %s.", tests.Failed, err)
+			}
+			if r.Recipients != 2 || len(r.Deliveries) != 6 {
+				t.Fatalf("\t%s\tShould deliver to every member over every channel : got %d recipients, %d deliveries.", tests.Failed, r.Recipients, len(r.Deliveries))
+			}
+			for _, ch := range channels {
+				if c := summary(t, r, ch); c.Pending != 2 {
+					t.Fatalf("\t%s\tShould leave the %s deliveries pending : got %+v.", tests.Failed, ch, c)
+				}
+			}
+			t.Logf("\t%s\tShould leave a delivery pending per member and channel.", tests.Success)
+		}
+
+		t.Log("\tTest 1:\tWhen the worker delivers the broadcast.")
+		{
+			sent := now.Add(time.Second)
+			n, err := broadcast.Deliver(ctx, db, []broadcast.Channel{broadcast.Inbox{}, unreachable{userID}}, 100, sent)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to deliver the broadcast : %s.", tests.Failed, err)
+			}
+			if n != 4 {
+				t.Fatalf("\t%s\tShould attempt the deliveries of its channels only : got %d.", tests.Failed, n)
+			}
+			t.Logf("\t%s\tShould attempt the deliveries of its channels only.", tests.Success)
+
+			r, err := broadcast.RetrieveReport(ctx, db, b.ID)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to retrieve the report : %s.", tests.Failed, err)
+			}
+			if c := summary(t, r, "inbox"); c.Sent != 2 {
+				t.Fatalf("\t%s\tShould deliver to every inbox : got %+v.", tests.Failed, c)
+			}
+			if c := summary(t, r, "email"); c.Sent != 1 || c.Failed != 1 {
+				t.Fatalf("\t%s\tShould record the email which failed : got %+v.", tests.Failed, c)
+			}
+			if c := summary(t, r, broadcast.ChannelStream); c.Pending != 2 {
+				t.Fatalf("\t%s\tShould leave the stream deliveries to the streams : got %+v.", tests.Failed, c)
+			}
+			t.Logf("\t%s\tShould record the outcome of every delivery.", tests.Success)
+
+			for _, d := range r.Deliveries {
+				if d.Channel == "email" && d.UserID == userID && d.Error != "mailbox full" {
+					t.Fatalf("\t%s\tShould record why the delivery failed : got %+v.", tests.Failed, d)
+				}
+				if d.Status != broadcast.StatusPending && (d.DateSent == nil || !d.DateSent.Equal(sent)) {
+					t.Fatalf("\t%s\tShould record when the delivery was sent : got %+v.", tests.Failed, d)
+				}
+			}
+			t.Logf("\t%s\tShould record why and when.", tests.Success)
+
+			if n, err := broadcast.Deliver(ctx, db, []broadcast.Channel{broadcast.Inbox{}, unreachable{userID}}, 100, sent); err != nil || n != 0 {
+				t.Fatalf("\t%s\tShould not deliver twice : got %d, %v.", tests.Failed, n, err)
+			}
+			t.Logf("\t%s\tShould not deliver twice.", tests.Success)
+		}
+
+		t.Log("\tTest 2:\tWhen the database announces the stream deliveries.")
+		{
+			pushed := now.Add(2 * time.Second)
+			stream := broadcast.NewStream().Clock(clock.Fixed(pushed))
+			broadcasts, unsubscribe := stream.Subscribe(userID)
+			defer unsubscribe()
+
+			notes := make(chan database.Notification, 2)
+			notes <- database.Notification{Channel: broadcast.NotifyChannel, Payload: `{"broadcast_id":"` + b.ID + `","user_id":"` + userID + `"}`}
+			notes <- database.Notification{Channel: broadcast.NotifyChannel, Payload: `{"broadcast_id":"` + b.ID + `","user_id":"` + adminID + `"}`}
+			close(notes)
+			stream.Run(notes, db, log.New(ioutil.Discard, "", 0))
+
+			select {
+			case got := <-broadcasts:
+				if got.ID != b.ID || got.Message != "Leave the cafeteria" {
+					t.Fatalf("\t%s\tShould push the broadcast to the stream : got %+v.", tests.Failed, got)
+				}
+			default:
+				t.Fatalf("\t%s\tShould push the broadcast to the stream.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould push the broadcast to the stream.", tests.Success)
+
+			r, err := broadcast.RetrieveReport(ctx, db, b.ID)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to retrieve the report : %s.", tests.Failed, err)
+			}
+			if c := summary(t, r, broadcast.ChannelStream); c.Sent != 1 || c.Pending != 1 {
+				t.Fatalf("\t%s\tShould record the push and leave the member not streaming pending : got %+v.", tests.Failed, c)
+			}
+			t.Logf("\t%s\tShould record the push and leave the member not streaming pending.", tests.Success)
+		}
+
+		t.Log("\tTest 3:\tWhen a member confirms the broadcast.")
+		{
+			user := auth.NewClaims(userID, []string{auth.RoleUser}, now, time.Hour)
+			if err := broadcast.Confirm(ctx, db, user, b.ID, now.Add(time.Minute)); err != nil {
+				t.Fatalf("\t%s\tShould be able to confirm the broadcast : %s.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to confirm the broadcast.", tests.Success)
+
+			stranger := auth.NewClaims("2df32931-3072-4d11-8109-d1f0988c26b3", []string{auth.RoleUser}, now, time.Hour)
+			if err := broadcast.Confirm(ctx, db, stranger, b.ID, now); err != broadcast.ErrNotFound {
+				t.Fatalf("\t%s\tShould not let others confirm it : got %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not let others confirm it.", tests.Success)
+
+			r, err := broadcast.RetrieveReport(ctx, db, b.ID)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to retrieve the report : %s.", tests.Failed, err)
+			}
+			if r.Confirmed != 1 || r.Confirmations[0].UserID != userID {
+				t.Fatalf("\t%s\tShould report the confirmation : got %+v.", tests.Failed, r.Confirmations)
+			}
+			t.Logf("\t%s\tShould report the confirmation.", tests.Success)
+
+			inbox, err := broadcast.ListUnconfirmed(ctx, db, userID)
+			if err != nil || len(inbox) != 0 {
+				t.Fatalf("\t%s\tShould clear the inbox of the member : got %+v, %v.", tests.Failed, inbox, err)
+			}
+			inbox, err = broadcast.ListUnconfirmed(ctx, db, adminID)
+			if err != nil || len(inbox) != 1 {
+				t.Fatalf("\t%s\tShould keep it in the inbox of the others : got %+v, %v.", tests.Failed, inbox, err)
+			}
+			t.Logf("\t%s\tShould keep it in the inbox of the others only.", tests.Success)
+		}
+	}
+}
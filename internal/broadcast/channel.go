@@ -0,0 +1,24 @@
+package broadcast
+
+import "context"
+
+// Channel delivers a Broadcast to a recipient over one medium such as email
+// or push notifications.
+type Channel interface {
+	Name() string
+	Send(ctx context.Context, to Recipient, b Broadcast) error
+}
+
+// Inbox is the in-app channel. Recipients find their unconfirmed broadcasts
+// through the API so there is nothing to send and delivery always succeeds.
+type Inbox struct{}
+
+// Name implements the Channel interface.
+func (Inbox) Name() string {
+	return "inbox"
+}
+
+// Send implements the Channel interface.
+func (Inbox) Send(ctx context.Context, to Recipient, b Broadcast) error {
+	return nil
+}
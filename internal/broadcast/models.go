@@ -0,0 +1,68 @@
+package broadcast
+
+import "time"
+
+// These are the states of a single Delivery.
+const (
+	StatusPending = "PENDING"
+	StatusSent    = "SENT"
+	StatusFailed  = "FAILED"
+)
+
+// Broadcast is an urgent message sent to every member at once, for example
+// to evacuate the cafeteria or recall a dish.
+type Broadcast struct {
+	ID          string    `db:"broadcast_id" json:"id"`
+	OrgID       string    `db:"org_id" json:"org_id"`
+	Message     string    `db:"message" json:"message"`
+	SenderID    string    `db:"sender_user_id" json:"sender_user_id"`
+	DateCreated time.Time `db:"date_created" json:"date_created"`
+}
+
+// NewBroadcast is what we require from admins when sending a Broadcast.
+type NewBroadcast struct {
+	Message string `json:"message" validate:"required"`
+}
+
+// Recipient is a member a Broadcast is delivered to.
+type Recipient struct {
+	UserID string `db:"user_id"`
+	Name   string `db:"name"`
+	Email  string `db:"email"`
+}
+
+// Delivery is the outcome of sending a Broadcast to one recipient over one
+// channel.
+type Delivery struct {
+	BroadcastID string     `db:"broadcast_id" json:"broadcast_id"`
+	UserID      string     `db:"user_id" json:"user_id"`
+	Channel     string     `db:"channel" json:"channel"`
+	Status      string     `db:"status" json:"status"`
+	Error       string     `db:"error" json:"error,omitempty"`
+	DateSent    *time.Time `db:"date_sent" json:"date_sent,omitempty"`
+}
+
+// Confirmation records that a recipient acknowledged a Broadcast.
+type Confirmation struct {
+	BroadcastID   string    `db:"broadcast_id" json:"broadcast_id"`
+	UserID        string    `db:"user_id" json:"user_id"`
+	DateConfirmed time.Time `db:"date_confirmed" json:"date_confirmed"`
+}
+
+// ChannelReport sums up the deliveries of a single channel.
+type ChannelReport struct {
+	Channel string `db:"channel" json:"channel"`
+	Pending int    `db:"pending" json:"pending"`
+	Sent    int    `db:"sent" json:"sent"`
+	Failed  int    `db:"failed" json:"failed"`
+}
+
+// Report tells admins how far a Broadcast has reached its recipients.
+type Report struct {
+	Broadcast     Broadcast       `json:"broadcast"`
+	Recipients    int             `json:"recipients"`
+	Confirmed     int             `json:"confirmed"`
+	Channels      []ChannelReport `json:"channels"`
+	Deliveries    []Delivery      `json:"deliveries"`
+	Confirmations []Confirmation  `json:"confirmations"`
+}
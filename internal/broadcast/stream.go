@@ -0,0 +1,178 @@
+package broadcast
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/platform/clock"
+	"github.com/remisb/restaurant/internal/platform/database"
+)
+
+// ChannelStream is the name of the deliveries over server-sent events.
+const ChannelStream = "sse"
+
+// NotifyChannel is the channel the database announces the deliveries over
+// server-sent events on, as they are created.
+const NotifyChannel = "restaurant_broadcast"
+
+// Stream is the server-sent events channel. Every replica of the service
+// learns about the deliveries from the database and pushes them to the
+// recipients streaming from it; the others find them in their inbox.
+type Stream struct {
+	mu     sync.Mutex
+	subs   map[chan Broadcast]string
+	closed bool
+	clock  clock.Clock
+}
+
+// NewStream constructs a Stream without recipients.
+func NewStream() *Stream {
+	return &Stream{
+		subs:  make(map[chan Broadcast]string),
+		clock: clock.System,
+	}
+}
+
+// Clock sets the clock telling when the deliveries were pushed, the clock of
+// the system by default. It must be called before Run.
+func (s *Stream) Clock(c clock.Clock) *Stream {
+	s.clock = c
+	return s
+}
+
+// Run pushes the broadcasts of the deliveries announced by the database to
+// the recipients streaming from this replica and records them as sent,
+// until the channel is closed. The deliveries of the recipients who are not
+// streaming from it are left alone: another replica records them, or they
+// stay pending and the broadcast waits in the inbox.
+func (s *Stream) Run(notes <-chan database.Notification, db *sqlx.DB, log *log.Logger) {
+	for n := range notes {
+
+		// The deliveries announced while the connection was lost are
+		// missed, their recipients find them in the inbox.
+		if n.Channel == "" {
+			continue
+		}
+
+		var d struct {
+			BroadcastID string `json:"broadcast_id"`
+			UserID      string `json:"user_id"`
+		}
+		if err := json.Unmarshal([]byte(n.Payload), &d); err != nil || d.BroadcastID == "" {
+			log.Printf("broadcast : ERROR : malformed notification %q", n.Payload)
+			continue
+		}
+
+		if err := s.deliver(context.Background(), db, d.BroadcastID, d.UserID); err != nil {
+			log.Printf("broadcast : ERROR : %+v", err)
+		}
+	}
+}
+
+// deliver pushes the broadcast to the recipient when streaming from this
+// replica and records the delivery as sent.
+func (s *Stream) deliver(ctx context.Context, db *sqlx.DB, id, userID string) error {
+	if !s.streaming(userID) {
+		return nil
+	}
+
+	b, err := Retrieve(ctx, db, id)
+	if err != nil {
+		return errors.Wrapf(err, "retrieving broadcast %s", id)
+	}
+
+	if !s.Push(userID, *b) {
+		return nil
+	}
+
+	const q = `UPDATE broadcast_delivery SET
+		"status" = $4,
+		"date_sent" = $5
+		WHERE broadcast_id = $1 AND user_id = $2 AND channel = $3 AND status = $6`
+	if _, err := db.ExecContext(ctx, q, id, userID, ChannelStream, StatusSent, s.clock.Now().UTC(), StatusPending); err != nil {
+		return errors.Wrap(err, "updating stream delivery")
+	}
+
+	return nil
+}
+
+// streaming reports whether the user streams the broadcasts from this
+// replica.
+func (s *Stream) streaming(userID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range s.subs {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// Push hands the broadcast to the streams of the user on this replica and
+// reports whether any of them took it. It never blocks; a stream which did
+// not pick up the previous broadcasts yet misses this one.
+func (s *Stream) Push(userID string, b Broadcast) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sent := false
+	for ch, id := range s.subs {
+		if id != userID {
+			continue
+		}
+		select {
+		case ch <- b:
+			sent = true
+		default:
+		}
+	}
+
+	return sent
+}
+
+// Subscribe streams the broadcasts sent to the user. The channel is closed
+// when the Stream is closed. The returned function stops streaming and must
+// be called once done.
+func (s *Stream) Subscribe(userID string) (<-chan Broadcast, func()) {
+	ch := make(chan Broadcast, 8)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	s.subs[ch] = userID
+
+	unsubscribe := func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if _, ok := s.subs[ch]; ok {
+			delete(s.subs, ch)
+			close(ch)
+		}
+	}
+
+	return ch, unsubscribe
+}
+
+// Close ends every subscription so open streams finish when the service
+// shuts down. Later subscriptions end right away.
+func (s *Stream) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for ch := range s.subs {
+		delete(s.subs, ch)
+		close(ch)
+	}
+	s.closed = true
+}
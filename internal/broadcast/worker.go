@@ -0,0 +1,85 @@
+package broadcast
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/remisb/restaurant/internal/platform/clock"
+	"github.com/remisb/restaurant/internal/platform/job"
+)
+
+// batchSize is the number of pending deliveries sent per transaction.
+const batchSize = 100
+
+// Worker delivers the pending broadcasts over the in-app inbox and its
+// channels. The workers of every replica share the deliveries, each is sent
+// by one of them. Deliveries interrupted by a shutdown stay pending and are
+// sent once a worker runs again.
+type Worker struct {
+	log      *log.Logger
+	db       *sqlx.DB
+	channels []Channel
+	interval time.Duration
+	tracker  *job.Tracker
+	clock    clock.Clock
+}
+
+// NewWorker constructs a Worker looking for pending deliveries every
+// interval.
+func NewWorker(log *log.Logger, db *sqlx.DB, channels []Channel, interval time.Duration) *Worker {
+	return &Worker{
+		log:      log,
+		db:       db,
+		channels: append([]Channel{Inbox{}}, channels...),
+		interval: interval,
+		tracker:  job.NewTracker("broadcast_delivery"),
+		clock:    clock.System,
+	}
+}
+
+// Clock sets the clock telling when the deliveries were sent, the clock of
+// the system by default. It must be called before Run.
+func (w *Worker) Clock(c clock.Clock) *Worker {
+	w.clock = c
+	return w
+}
+
+// Status reports the state of the worker to the health check.
+func (w *Worker) Status() job.Status {
+	return w.tracker.Status()
+}
+
+// Run sends the pending deliveries until the context is canceled.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.tick(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// tick sends every pending delivery, a batch at a time.
+func (w *Worker) tick(ctx context.Context) {
+	var err error
+	for {
+		var n int
+		n, err = Deliver(ctx, w.db, w.channels, batchSize, w.clock.Now())
+		if err != nil || n < batchSize {
+			break
+		}
+	}
+
+	if err != nil {
+		w.log.Printf("broadcast : ERROR : %+v", err)
+	}
+	w.tracker.Record(err, w.clock.Now())
+}
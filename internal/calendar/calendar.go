@@ -0,0 +1,187 @@
+// Package calendar publishes the winners and the upcoming menus of an
+// organization as an iCalendar feed. Calendar apps cannot send the token of a
+// user so each user subscribes with a feed token of their own instead.
+package calendar
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/organization"
+	"github.com/remisb/restaurant/internal/platform/database"
+	"go.opentelemetry.io/otel"
+)
+
+// Predefined errors identify expected failure conditions.
+var (
+	// ErrInvalidToken is used when a feed token does not exist or its user
+	// was deleted.
+	ErrInvalidToken = errors.New("Calendar token is invalid")
+)
+
+// The span of the feed around the day it is requested.
+const (
+	PastDays     = 30
+	UpcomingDays = 14
+)
+
+// Token is the secret a user subscribes to their feed with. Only its hash is
+// stored so it is shown once, when it is created.
+type Token struct {
+	Token       string    `json:"token"`
+	DateCreated time.Time `json:"date_created"`
+}
+
+// Event is an all day event of the feed.
+type Event struct {
+	UID         string
+	Date        time.Time
+	Summary     string
+	Description string
+	Location    string
+}
+
+// CreateToken creates a feed token for the user, replacing the previous one
+// so a leaked feed can be revoked.
+func CreateToken(ctx context.Context, db *sqlx.DB, userID string, now time.Time) (*Token, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.calendar.CreateToken")
+	defer span.End()
+
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return nil, errors.Wrap(err, "generating token")
+	}
+
+	t := Token{
+		Token:       hex.EncodeToString(b),
+		DateCreated: now.UTC(),
+	}
+
+	tx, err := database.Begin(ctx, db)
+	if err != nil {
+		return nil, errors.Wrap(err, "beginning transaction")
+	}
+	defer tx.Rollback()
+
+	const qd = `DELETE FROM calendar_token WHERE user_id = $1`
+	if _, err := tx.ExecContext(ctx, qd, userID); err != nil {
+		return nil, errors.Wrap(err, "deleting previous token")
+	}
+
+	const qi = `INSERT INTO calendar_token (token_hash, user_id, date_created) VALUES ($1, $2, $3)`
+	if _, err := tx.ExecContext(ctx, qi, hash(t.Token), userID, t.DateCreated); err != nil {
+		return nil, errors.Wrap(err, "inserting token")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrap(err, "committing token")
+	}
+
+	return &t, nil
+}
+
+// TokenOrg returns the organization of the user the feed token belongs to.
+func TokenOrg(ctx context.Context, db *sqlx.DB, token string) (string, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.calendar.TokenOrg")
+	defer span.End()
+
+	if token == "" {
+		return "", ErrInvalidToken
+	}
+
+	var org string
+	const q = `SELECT u.org_id FROM calendar_token AS t
+		JOIN users AS u ON u.user_id = t.user_id
+		WHERE t.token_hash = $1 AND u.deleted_at IS NULL`
+	if err := db.GetContext(ctx, &org, q, hash(token)); err != nil {
+		if err == sql.ErrNoRows {
+			return "", ErrInvalidToken
+		}
+		return "", errors.Wrap(err, "selecting token user")
+	}
+
+	return org, nil
+}
+
+// Events returns the winners of the organization of the last PastDays days
+// and its menus of the next UpcomingDays days, ordered by date. The days are
+// those of the time zone of the organization.
+func Events(ctx context.Context, db *sqlx.DB, org string, now time.Time) ([]Event, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.calendar.Events")
+	defer span.End()
+
+	today, err := organization.Today(ctx, db, org, now)
+	if err != nil {
+		return nil, err
+	}
+
+	var winners []struct {
+		Date       time.Time `db:"date"`
+		Restaurant string    `db:"name"`
+		Address    string    `db:"address"`
+		Votes      int       `db:"votes"`
+	}
+	const qw = `SELECT w.date, r.name, r.address, w.votes FROM winner AS w
+		JOIN restaurant AS r ON r.restaurant_id = w.restaurant_id
+		WHERE w.org_id = $1 AND w.date >= $2
+		ORDER BY w.date`
+	if err := db.SelectContext(ctx, &winners, qw, org, today.AddDate(0, 0, -PastDays)); err != nil {
+		return nil, errors.Wrap(err, "selecting winners")
+	}
+
+	var menus []struct {
+		ID         string    `db:"menu_id"`
+		Date       time.Time `db:"date"`
+		Restaurant string    `db:"name"`
+		Address    string    `db:"address"`
+		Menu       string    `db:"menu"`
+	}
+	const qm = `SELECT m.menu_id, m.date, r.name, r.address, m.menu FROM menu AS m
+		JOIN restaurant AS r ON r.restaurant_id = m.restaurant_id
+		WHERE r.org_id = $1 AND m.date >= $2 AND m.date < $3
+			AND m.deleted_at IS NULL AND r.deleted_at IS NULL
+		ORDER BY m.date, r.name`
+	if err := db.SelectContext(ctx, &menus, qm, org, today, today.AddDate(0, 0, UpcomingDays)); err != nil {
+		return nil, errors.Wrap(err, "selecting menus")
+	}
+
+	events := make([]Event, 0, len(winners)+len(menus))
+	for _, w := range winners {
+		events = append(events, Event{
+			UID:         fmt.Sprintf("winner-%s-%s", w.Date.Format("20060102"), org),
+			Date:        w.Date,
+			Summary:     "Lunch at " + w.Restaurant,
+			Description: fmt.Sprintf("%s won the vote with %d votes.", w.Restaurant, w.Votes),
+			Location:    w.Address,
+		})
+	}
+	for _, m := range menus {
+		events = append(events, Event{
+			UID:         "menu-" + m.ID,
+			Date:        m.Date,
+			Summary:     "Menu: " + m.Restaurant,
+			Description: m.Menu,
+			Location:    m.Address,
+		})
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Date.Before(events[j].Date)
+	})
+
+	return events, nil
+}
+
+// hash returns the stored form of the token.
+func hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
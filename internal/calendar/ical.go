@@ -0,0 +1,78 @@
+package calendar
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// maxLine is the length in octets a line of an iCalendar file is folded at.
+const maxLine = 75
+
+// Write writes the events as an iCalendar file (RFC 5545) named name.
+func Write(w io.Writer, name string, events []Event, now time.Time) error {
+	bw := bufio.NewWriter(w)
+	stamp := now.UTC().Format("20060102T150405Z")
+
+	line := func(s string) {
+		writeFolded(bw, s)
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//remisb//restaurant//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	line("X-WR-CALNAME:" + escape(name))
+	for _, e := range events {
+		line("BEGIN:VEVENT")
+		line("UID:" + e.UID)
+		line("DTSTAMP:" + stamp)
+		line("DTSTART;VALUE=DATE:" + e.Date.Format("20060102"))
+		line("DTEND;VALUE=DATE:" + e.Date.AddDate(0, 0, 1).Format("20060102"))
+		line("SUMMARY:" + escape(e.Summary))
+		if e.Description != "" {
+			line("DESCRIPTION:" + escape(e.Description))
+		}
+		if e.Location != "" {
+			line("LOCATION:" + escape(e.Location))
+		}
+		line("TRANSP:TRANSPARENT")
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+
+	if err := bw.Flush(); err != nil {
+		return errors.Wrap(err, "writing calendar")
+	}
+
+	return nil
+}
+
+// writeFolded writes the content line ended by CRLF, folding it into lines of
+// at most maxLine octets without splitting a character.
+func writeFolded(w *bufio.Writer, s string) {
+	n := 0
+	for _, r := range s {
+		size := len(string(r))
+		if n+size > maxLine {
+			w.WriteString("\r\n ")
+			n = 1
+		}
+		w.WriteRune(r)
+		n += size
+	}
+	w.WriteString("\r\n")
+}
+
+// escape escapes the text of a property value.
+var escape = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+).Replace
@@ -0,0 +1,60 @@
+package calendar
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// TestWrite validates the events are written as an iCalendar file.
+func TestWrite(t *testing.T) {
+	now := time.Date(2019, time.March, 24, 9, 30, 0, 0, time.UTC)
+	events := []Event{
+		{
+			UID:         "menu-1",
+			Date:        time.Date(2019, time.March, 25, 0, 0, 0, 0, time.UTC),
+			Summary:     "Menu: Pizza, Pasta; Co",
+			Description: "Soup\nPesto Pasta " + strings.Repeat("with basil ", 10),
+		},
+	}
+
+	t.Log("Given the need to subscribe to the lunches from a calendar app.")
+	{
+		t.Log("\tTest 0:\tWhen writing an upcoming menu.")
+		{
+			var buf bytes.Buffer
+			if err := Write(&buf, "Lunch", events, now); err != nil {
+				t.Fatalf("\t%s\tShould write the calendar : %s.", tests.Failed, err)
+			}
+			out := buf.String()
+
+			for _, want := range []string{
+				"BEGIN:VCALENDAR\r\n",
+				"DTSTAMP:20190324T093000Z\r\n",
+				"DTSTART;VALUE=DATE:20190325\r\n",
+				"DTEND;VALUE=DATE:20190326\r\n",
+				"SUMMARY:Menu: Pizza\\, Pasta\\; Co\r\n",
+				"DESCRIPTION:Soup\\nPesto Pasta",
+				"END:VCALENDAR\r\n",
+			} {
+				if !strings.Contains(out, want) {
+					t.Fatalf("\t%s\tShould contain %q : got %q.", tests.Failed, want, out)
+				}
+			}
+			t.Logf("\t%s\tShould write the event with escaped text.", tests.Success)
+
+			for _, line := range strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n") {
+				if len(line) > maxLine {
+					t.Fatalf("\t%s\tShould fold long lines : got %q.", tests.Failed, line)
+				}
+			}
+			if !strings.Contains(out, "\r\n ") {
+				t.Fatalf("\t%s\tShould continue folded lines with a space : got %q.", tests.Failed, out)
+			}
+			t.Logf("\t%s\tShould fold long lines.", tests.Success)
+		}
+	}
+}
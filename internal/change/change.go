@@ -0,0 +1,100 @@
+// Package change tells every replica of the service about the changes of the
+// menus and the votes, whichever replica or job made them, so each refreshes
+// its caches and streams. PostgreSQL announces the changes with NOTIFY from
+// triggers on the tables, no message bus is needed.
+package change
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/remisb/restaurant/internal/platform/database"
+)
+
+// Channel is the channel the changes are announced on.
+const Channel = "restaurant_change"
+
+// These are the tables whose changes are announced.
+const (
+	TableMenu = "menu"
+	TableVote = "vote"
+)
+
+// Event is a change of the menu or of the votes of a restaurant on a date.
+// The zero Event means anything may have changed, as after the connection
+// to the database was lost.
+type Event struct {
+	Table        string `json:"table"`
+	RestaurantID string `json:"restaurant_id"`
+	Date         string `json:"date"`
+}
+
+// All reports whether anything may have changed.
+func (e Event) All() bool {
+	return e == Event{}
+}
+
+// Day returns the date of the change, the zero time when it is unknown.
+func (e Event) Day() time.Time {
+	d, _ := time.Parse("2006-01-02", e.Date)
+	return d
+}
+
+// Feed hands the events to the functions subscribed to them. A nil Feed
+// hands out nothing.
+type Feed struct {
+	mu   sync.Mutex
+	subs []func(Event)
+}
+
+// NewFeed constructs a Feed without subscribers.
+func NewFeed() *Feed {
+	return &Feed{}
+}
+
+// Subscribe calls fn with every event from now on. The events are handed
+// out one at a time so fn must return quickly.
+func (f *Feed) Subscribe(fn func(Event)) {
+	if f == nil {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.subs = append(f.subs, fn)
+}
+
+// Publish hands the event to the subscribers.
+func (f *Feed) Publish(e Event) {
+	if f == nil {
+		return
+	}
+
+	f.mu.Lock()
+	subs := f.subs
+	f.mu.Unlock()
+
+	for _, fn := range subs {
+		fn(e)
+	}
+}
+
+// Run publishes the events of the notifications until the channel is
+// closed. Notifications which are not events are logged and skipped.
+func (f *Feed) Run(notes <-chan database.Notification, log *log.Logger) {
+	for n := range notes {
+		if n.Channel == "" {
+			f.Publish(Event{})
+			continue
+		}
+
+		var e Event
+		if err := json.Unmarshal([]byte(n.Payload), &e); err != nil || e.All() {
+			log.Printf("change : ERROR : malformed notification %q", n.Payload)
+			continue
+		}
+		f.Publish(e)
+	}
+}
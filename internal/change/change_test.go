@@ -0,0 +1,63 @@
+package change
+
+import (
+	"bytes"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/remisb/restaurant/internal/platform/database"
+)
+
+// TestFeed validates the notifications of the database reach the subscribers
+// as events.
+func TestFeed(t *testing.T) {
+	t.Log("Given the need to learn about the changes made by other replicas.")
+	{
+		f := NewFeed()
+		var got []Event
+		f.Subscribe(func(e Event) { got = append(got, e) })
+
+		notes := make(chan database.Notification, 3)
+		notes <- database.Notification{Channel: Channel, Payload: `{"table":"vote","restaurant_id":"a2b0639f-2cc6-44b8-b97b-15d69dbb511e","date":"2020-03-02"}`}
+		notes <- database.Notification{Channel: Channel, Payload: `not json`}
+		notes <- database.Notification{}
+		close(notes)
+
+		var logs bytes.Buffer
+		f.Run(notes, log.New(&logs, "", 0))
+
+		t.Log("\tTest 0:\tWhen a vote changed.")
+		{
+			if len(got) != 2 {
+				t.Fatalf("\t✗\tShould publish the events only : got %+v", got)
+			}
+			t.Log("\t✓\tShould publish the events only.")
+
+			e := got[0]
+			if e.Table != TableVote || e.RestaurantID != "a2b0639f-2cc6-44b8-b97b-15d69dbb511e" || e.All() {
+				t.Fatalf("\t✗\tShould describe the change : got %+v", e)
+			}
+			if want := time.Date(2020, time.March, 2, 0, 0, 0, 0, time.UTC); !e.Day().Equal(want) {
+				t.Fatalf("\t✗\tShould tell the day of the change : got %v, want %v", e.Day(), want)
+			}
+			t.Log("\t✓\tShould describe the change.")
+		}
+
+		t.Log("\tTest 1:\tWhen a notification is malformed.")
+		{
+			if logs.Len() == 0 {
+				t.Fatal("\t✗\tShould log the notification.")
+			}
+			t.Log("\t✓\tShould log the notification.")
+		}
+
+		t.Log("\tTest 2:\tWhen the connection to the database was lost.")
+		{
+			if !got[1].All() {
+				t.Fatalf("\t✗\tShould tell anything may have changed : got %+v", got[1])
+			}
+			t.Log("\t✓\tShould tell anything may have changed.")
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package changelog
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/platform/database"
+	"go.opentelemetry.io/otel"
+)
+
+// Predefined errors identify expected failure conditions.
+var (
+	// ErrNotFound is used when a specific Entry is requested but does not exist.
+	ErrNotFound = errors.New("Changelog entry not found")
+
+	// ErrInvalidID is used when an invalid UUID is provided.
+	ErrInvalidID = errors.New("ID is not in its proper form")
+
+	// ErrDuplicateVersion is used when an Entry for the version already exists.
+	ErrDuplicateVersion = errors.New("Changelog entry for this version already exists")
+)
+
+// List gets the entries for versions newer than since, newest first. When
+// since is blank the latest version the user has seen is used instead so
+// clients only show notes the user did not read yet.
+func List(ctx context.Context, db *sqlx.DB, userID, since string) ([]Entry, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.changelog.List")
+	defer span.End()
+
+	if since == "" {
+		const q = `SELECT version FROM changelog_seen WHERE user_id = $1`
+		if err := db.GetContext(ctx, &since, q, userID); err != nil && err != sql.ErrNoRows {
+			return nil, errors.Wrap(err, "selecting seen version")
+		}
+	}
+
+	entries := []Entry{}
+	const q = `SELECT * FROM changelog`
+	if err := db.SelectContext(ctx, &entries, q); err != nil {
+		return nil, errors.Wrap(err, "selecting changelog")
+	}
+
+	// Versions are ordered numerically which SQL can not do on text columns.
+	newer := []Entry{}
+	for _, e := range entries {
+		if since == "" || compareVersions(e.Version, since) > 0 {
+			newer = append(newer, e)
+		}
+	}
+	sort.Slice(newer, func(i, j int) bool {
+		return compareVersions(newer[i].Version, newer[j].Version) > 0
+	})
+
+	return newer, nil
+}
+
+// Create adds the notes for a new version.
+func Create(ctx context.Context, db *sqlx.DB, ne NewEntry, now time.Time) (*Entry, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.changelog.Create")
+	defer span.End()
+
+	e := Entry{
+		ID:          uuid.New().String(),
+		Version:     ne.Version,
+		Title:       ne.Title,
+		Notes:       ne.Notes,
+		DateCreated: now.UTC(),
+		DateUpdated: now.UTC(),
+	}
+
+	const q = `INSERT INTO changelog
+		(entry_id, version, title, notes, date_created, date_updated)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+
+	if _, err := db.ExecContext(ctx, q, e.ID, e.Version, e.Title, e.Notes, e.DateCreated, e.DateUpdated); err != nil {
+		if v, ok := database.AsViolation(err); ok && v.Unique {
+			return nil, ErrDuplicateVersion
+		}
+		return nil, errors.Wrap(err, "inserting changelog entry")
+	}
+
+	return &e, nil
+}
+
+// Retrieve finds the entry identified by a given ID.
+func Retrieve(ctx context.Context, db *sqlx.DB, id string) (*Entry, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.changelog.Retrieve")
+	defer span.End()
+
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrInvalidID
+	}
+
+	var e Entry
+	const q = `SELECT * FROM changelog WHERE entry_id = $1`
+	if err := db.GetContext(ctx, &e, q, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, errors.Wrapf(err, "selecting changelog entry %q", id)
+	}
+
+	return &e, nil
+}
+
+// Update modifies the notes of an existing version.
+func Update(ctx context.Context, db *sqlx.DB, id string, upd UpdateEntry, now time.Time) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.changelog.Update")
+	defer span.End()
+
+	e, err := Retrieve(ctx, db, id)
+	if err != nil {
+		return err
+	}
+
+	if upd.Title != nil {
+		e.Title = *upd.Title
+	}
+	if upd.Notes != nil {
+		e.Notes = *upd.Notes
+	}
+	e.DateUpdated = now
+
+	const q = `UPDATE changelog SET
+		"title" = $2,
+		"notes" = $3,
+		"date_updated" = $4
+		WHERE entry_id = $1`
+	if _, err := db.ExecContext(ctx, q, id, e.Title, e.Notes, e.DateUpdated); err != nil {
+		return errors.Wrap(err, "updating changelog entry")
+	}
+
+	return nil
+}
+
+// Delete removes the entry identified by a given ID.
+func Delete(ctx context.Context, db *sqlx.DB, id string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.changelog.Delete")
+	defer span.End()
+
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrInvalidID
+	}
+
+	const q = `DELETE FROM changelog WHERE entry_id = $1`
+	if _, err := db.ExecContext(ctx, q, id); err != nil {
+		return errors.Wrapf(err, "deleting changelog entry %s", id)
+	}
+
+	return nil
+}
+
+// MarkSeen records that the user has been shown the notes up to the version.
+// A version older than the one already recorded is ignored.
+func MarkSeen(ctx context.Context, db *sqlx.DB, userID string, seen Seen, now time.Time) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.changelog.MarkSeen")
+	defer span.End()
+
+	var current string
+	const qs = `SELECT version FROM changelog_seen WHERE user_id = $1`
+	if err := db.GetContext(ctx, &current, qs, userID); err != nil && err != sql.ErrNoRows {
+		return errors.Wrap(err, "selecting seen version")
+	}
+	if current != "" && compareVersions(seen.Version, current) <= 0 {
+		return nil
+	}
+
+	const q = `INSERT INTO changelog_seen
+		(user_id, version, date_seen)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET
+		"version" = EXCLUDED.version,
+		"date_seen" = EXCLUDED.date_seen`
+	if _, err := db.ExecContext(ctx, q, userID, seen.Version, now.UTC()); err != nil {
+		return errors.Wrap(err, "updating seen version")
+	}
+
+	return nil
+}
+
+// compareVersions compares two dotted versions like 1.10.2 numerically
+// returning -1, 0 or 1. A leading v is ignored and missing parts count as 0.
+func compareVersions(a, b string) int {
+	as := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bs := strings.Split(strings.TrimPrefix(b, "v"), ".")
+
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var an, bn int
+		if i < len(as) {
+			an, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			bn, _ = strconv.Atoi(bs[i])
+		}
+		switch {
+		case an < bn:
+			return -1
+		case an > bn:
+			return 1
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,34 @@
+package changelog
+
+import (
+	"testing"
+
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// TestCompareVersions validates versions are ordered numerically.
+func TestCompareVersions(t *testing.T) {
+	tt := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0.0", "1.0.0", 0},
+		{"1.0", "1.0.0", 0},
+		{"v1.2.0", "1.2.0", 0},
+		{"1.10.0", "1.9.0", 1},
+		{"1.9.3", "1.10.0", -1},
+		{"2.0.0", "1.99.99", 1},
+		{"1.0.1", "1.0", 1},
+	}
+
+	t.Log("Given the need to order changelog versions.")
+	{
+		for i, tc := range tt {
+			t.Logf("\tTest %d:\tWhen comparing %s and %s.", i, tc.a, tc.b)
+			if got := compareVersions(tc.a, tc.b); got != tc.want {
+				t.Fatalf("\t%s\tShould get %d : got %d.", tests.Failed, tc.want, got)
+			}
+			tests.LogSuccess(t, "Should get the expected result.")
+		}
+	}
+}
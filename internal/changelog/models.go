@@ -0,0 +1,33 @@
+package changelog
+
+import "time"
+
+// Entry holds the release notes of a single version of the service.
+type Entry struct {
+	ID          string    `db:"entry_id" json:"id"`
+	Version     string    `db:"version" json:"version"`
+	Title       string    `db:"title" json:"title"`
+	Notes       string    `db:"notes" json:"notes"`
+	DateCreated time.Time `db:"date_created" json:"date_created"`
+	DateUpdated time.Time `db:"date_updated" json:"date_updated"`
+}
+
+// NewEntry is what we require from admins when adding an Entry.
+type NewEntry struct {
+	Version string `json:"version" validate:"required"`
+	Title   string `json:"title" validate:"required"`
+	Notes   string `json:"notes" validate:"required"`
+}
+
+// UpdateEntry defines what information may be provided to modify an existing
+// Entry. All fields are optional so clients can send just the fields they want
+// changed.
+type UpdateEntry struct {
+	Title *string `json:"title"`
+	Notes *string `json:"notes"`
+}
+
+// Seen records the latest version a user has been shown the notes for.
+type Seen struct {
+	Version string `json:"version" validate:"required"`
+}
@@ -0,0 +1,162 @@
+// Package comment lets users discuss the menus they vote on, like asking
+// whether the soup is vegetarian, and the owners of the restaurants answer.
+// Comments are soft deleted so a thread keeps its place in the history.
+package comment
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/authz"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/database"
+	"go.opentelemetry.io/otel"
+)
+
+// Predefined errors identify expected failure conditions.
+var (
+	// ErrNotFound is used when a specific Comment is requested but does not
+	// exist.
+	ErrNotFound = errors.New("Comment not found")
+
+	// ErrInvalidID is used when an invalid UUID is provided.
+	ErrInvalidID = errors.New("ID is not in its proper form")
+
+	// ErrInvalidParent is used when replying to a comment of another menu, to
+	// a deleted comment or to a reply.
+	ErrInvalidParent = errors.New("Replies can only be made to a comment of the menu")
+
+	// ErrForbidden occurs when someone other than its author or an admin
+	// deletes a comment.
+	ErrForbidden = errors.New("Attempted action is not allowed")
+)
+
+// Create adds the comment of the user to the menu of the restaurant. byOwner
+// tells the user owns the restaurant, which checking is left to the caller.
+func Create(ctx context.Context, db *sqlx.DB, user auth.Claims, restaurantID, menuID string, byOwner bool, nc NewComment, now time.Time) (*Comment, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.comment.Create")
+	defer span.End()
+
+	c := Comment{
+		ID:           uuid.New().String(),
+		MenuID:       menuID,
+		RestaurantID: restaurantID,
+		UserID:       user.Subject,
+		Body:         nc.Body,
+		ByOwner:      byOwner,
+		DateCreated:  now.UTC(),
+	}
+
+	if nc.ParentID != "" {
+		var parent Comment
+		const qp = `SELECT * FROM menu_comment WHERE comment_id = $1 AND deleted_at IS NULL`
+		if err := sqlx.GetContext(ctx, database.Conn(ctx, db), &parent, qp, nc.ParentID); err != nil {
+			if err == sql.ErrNoRows {
+				return nil, ErrInvalidParent
+			}
+			return nil, errors.Wrapf(err, "selecting comment %s", nc.ParentID)
+		}
+		if parent.MenuID != menuID || parent.ParentID != nil {
+			return nil, ErrInvalidParent
+		}
+		c.ParentID = &parent.ID
+	}
+
+	const q = `INSERT INTO menu_comment
+		(comment_id, menu_id, restaurant_id, parent_id, user_id, body, by_owner, date_created)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	_, err := database.Conn(ctx, db).ExecContext(ctx, q, c.ID, c.MenuID, c.RestaurantID, c.ParentID, c.UserID, c.Body, c.ByOwner, c.DateCreated)
+	if err != nil {
+		return nil, errors.Wrap(err, "inserting comment")
+	}
+
+	return &c, nil
+}
+
+// List returns a page of the comments of the menu, oldest first, each along
+// with its replies. limit and offset count the comments, not their replies.
+// The replies to a deleted comment go along with it.
+func List(ctx context.Context, db *sqlx.DB, menuID string, limit, offset int) ([]Comment, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.comment.List")
+	defer span.End()
+
+	comments := []Comment{}
+	const q = `SELECT * FROM menu_comment
+		WHERE menu_id = $1 AND parent_id IS NULL AND deleted_at IS NULL
+		ORDER BY date_created, comment_id
+		LIMIT $2 OFFSET $3`
+	if err := sqlx.SelectContext(ctx, database.Conn(ctx, db), &comments, q, menuID, limit, offset); err != nil {
+		return nil, errors.Wrap(err, "selecting comments")
+	}
+	if len(comments) == 0 {
+		return comments, nil
+	}
+
+	ids := make([]string, len(comments))
+	for i, c := range comments {
+		ids[i] = c.ID
+	}
+
+	var replies []Comment
+	const qr = `SELECT * FROM menu_comment
+		WHERE parent_id = ANY($1) AND deleted_at IS NULL
+		ORDER BY date_created, comment_id`
+	if err := sqlx.SelectContext(ctx, database.Conn(ctx, db), &replies, qr, pq.StringArray(ids)); err != nil {
+		return nil, errors.Wrap(err, "selecting replies")
+	}
+
+	return thread(comments, replies), nil
+}
+
+// thread attaches the replies, in their order, to the comments they answer.
+func thread(comments, replies []Comment) []Comment {
+	index := make(map[string]int, len(comments))
+	for i, c := range comments {
+		index[c.ID] = i
+	}
+	for _, r := range replies {
+		if r.ParentID == nil {
+			continue
+		}
+		if i, ok := index[*r.ParentID]; ok {
+			comments[i].Replies = append(comments[i].Replies, r)
+		}
+	}
+	return comments
+}
+
+// Delete soft deletes the comment of the menu. Only its author and admins may
+// delete a comment.
+func Delete(ctx context.Context, db *sqlx.DB, user auth.Claims, menuID, id string, now time.Time) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.comment.Delete")
+	defer span.End()
+
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrInvalidID
+	}
+
+	var c Comment
+	const qc = `SELECT * FROM menu_comment WHERE comment_id = $1 AND menu_id = $2 AND deleted_at IS NULL`
+	if err := sqlx.GetContext(ctx, database.Conn(ctx, db), &c, qc, id, menuID); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		return errors.Wrapf(err, "selecting comment %s", id)
+	}
+
+	if !authz.Allowed(user, authz.Delete, authz.Resource{Kind: authz.Comment, Owner: c.UserID}) {
+		return ErrForbidden
+	}
+
+	const q = `UPDATE menu_comment SET "deleted_at" = $2 WHERE comment_id = $1 AND deleted_at IS NULL`
+	if _, err := database.Conn(ctx, db).ExecContext(ctx, q, id, now.UTC()); err != nil {
+		return errors.Wrapf(err, "deleting comment %s", id)
+	}
+
+	return nil
+}
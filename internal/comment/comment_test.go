@@ -0,0 +1,37 @@
+package comment
+
+import (
+	"testing"
+
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// TestThread validates the replies are attached to the comments they answer
+// in their order.
+func TestThread(t *testing.T) {
+	parent := func(id string) *string { return &id }
+
+	t.Log("Given the need to show the comments of a menu as threads.")
+	{
+		t.Log("\tTest 0:\tWhen comments have replies.")
+		{
+			comments := []Comment{{ID: "soup"}, {ID: "dessert"}}
+			replies := []Comment{
+				{ID: "owner", ParentID: parent("soup"), ByOwner: true},
+				{ID: "thanks", ParentID: parent("soup")},
+				{ID: "other", ParentID: parent("another page")},
+			}
+
+			got := thread(comments, replies)
+			if len(got[0].Replies) != 2 || got[0].Replies[0].ID != "owner" || got[0].Replies[1].ID != "thanks" {
+				t.Fatalf("\t%s\tShould attach the replies in their order : got %+v.", tests.Failed, got[0].Replies)
+			}
+			t.Logf("\t%s\tShould attach the replies in their order.", tests.Success)
+
+			if len(got[1].Replies) != 0 {
+				t.Fatalf("\t%s\tShould leave the comments without replies alone : got %+v.", tests.Failed, got[1].Replies)
+			}
+			t.Logf("\t%s\tShould leave the comments without replies alone.", tests.Success)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package comment
+
+import "time"
+
+// Comment is a message a user left on a menu, like a question about one of
+// its dishes. A reply has the ID of the comment it answers as ParentID, and
+// ByOwner tells the replies of the owner of the restaurant apart.
+type Comment struct {
+	ID           string     `db:"comment_id" json:"id"`
+	MenuID       string     `db:"menu_id" json:"menu_id"`
+	RestaurantID string     `db:"restaurant_id" json:"restaurant_id"`
+	ParentID     *string    `db:"parent_id" json:"parent_id,omitempty"`
+	UserID       string     `db:"user_id" json:"user_id"`
+	Body         string     `db:"body" json:"body"`
+	ByOwner      bool       `db:"by_owner" json:"by_owner"`
+	DateCreated  time.Time  `db:"date_created" json:"date_created"`
+	DeletedAt    *time.Time `db:"deleted_at" json:"-"`
+
+	// Replies are the replies to a comment, oldest first. Replies have none.
+	Replies []Comment `db:"-" json:"replies,omitempty"`
+}
+
+// NewComment is what we require from users when commenting on a menu. A
+// reply gives the ID of the comment it answers, which cannot be a reply
+// itself.
+type NewComment struct {
+	Body     string `json:"body" validate:"required,max=2000"`
+	ParentID string `json:"parent_id" validate:"omitempty,uuid"`
+}
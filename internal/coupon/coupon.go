@@ -0,0 +1,221 @@
+// Package coupon lets restaurant owners hand out discount codes users apply
+// to their orders. The codes are checked and their uses counted when the
+// orders are placed.
+package coupon
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/platform/database"
+	"go.opentelemetry.io/otel"
+)
+
+// Predefined errors identify expected failure conditions.
+var (
+	// ErrNotFound is used when a specific Coupon is requested but does not
+	// exist.
+	ErrNotFound = errors.New("Coupon not found")
+
+	// ErrInvalidID is used when an invalid UUID is provided.
+	ErrInvalidID = errors.New("ID is not in its proper form")
+
+	// ErrDuplicateCode is used when the restaurant already has a Coupon with
+	// the code.
+	ErrDuplicateCode = errors.New("Coupon with this code already exists")
+
+	// ErrInvalidTerms is used when a percentage is over 100 or the validity
+	// window ends before it starts.
+	ErrInvalidTerms = errors.New("Coupon terms are not valid")
+
+	// ErrInvalidCode occurs when applying a code the restaurant does not have
+	// or which is not valid at the time.
+	ErrInvalidCode = errors.New("Coupon code is not valid")
+
+	// ErrExhausted occurs when applying a coupon which was used as many times
+	// as it may be, overall or by the user.
+	ErrExhausted = errors.New("Coupon has been used up")
+)
+
+// selectCoupons selects the coupons along with the number of times they were
+// used. The orders which were cancelled do not count.
+const selectCoupons = `SELECT c.*, (SELECT COUNT(*) FROM coupon_redemption AS r
+		JOIN orders AS o ON o.order_id = r.order_id
+		WHERE r.coupon_id = c.coupon_id AND o.status <> 'CANCELLED') AS redemptions
+	FROM coupon AS c`
+
+// Create adds a coupon to the restaurant. Checking the caller may manage the
+// coupons of the restaurant is left to the caller.
+func Create(ctx context.Context, db *sqlx.DB, restaurantID string, nc NewCoupon, now time.Time) (*Coupon, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.coupon.Create")
+	defer span.End()
+
+	if nc.Kind == KindPercent && nc.Value > 100 {
+		return nil, ErrInvalidTerms
+	}
+	if !nc.ValidFrom.Before(nc.ValidUntil) {
+		return nil, ErrInvalidTerms
+	}
+
+	c := Coupon{
+		ID:             uuid.New().String(),
+		RestaurantID:   restaurantID,
+		Code:           strings.ToUpper(nc.Code),
+		Kind:           nc.Kind,
+		Value:          nc.Value,
+		ValidFrom:      nc.ValidFrom.UTC(),
+		ValidUntil:     nc.ValidUntil.UTC(),
+		MaxRedemptions: nc.MaxRedemptions,
+		MaxPerUser:     nc.MaxPerUser,
+		DateCreated:    now.UTC(),
+	}
+
+	const q = `INSERT INTO coupon
+		(coupon_id, restaurant_id, code, kind, value, valid_from, valid_until, max_redemptions, max_per_user, date_created)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+	if _, err := database.Conn(ctx, db).ExecContext(ctx, q, c.ID, c.RestaurantID, c.Code, c.Kind, c.Value, c.ValidFrom, c.ValidUntil, c.MaxRedemptions, c.MaxPerUser, c.DateCreated); err != nil {
+		if v, ok := database.AsViolation(err); ok && v.Unique {
+			return nil, ErrDuplicateCode
+		}
+		return nil, errors.Wrap(err, "inserting coupon")
+	}
+
+	return &c, nil
+}
+
+// List returns the coupons of the restaurant, the most recent first.
+func List(ctx context.Context, db *sqlx.DB, restaurantID string) ([]Coupon, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.coupon.List")
+	defer span.End()
+
+	coupons := []Coupon{}
+	const q = selectCoupons + ` WHERE c.restaurant_id = $1 AND c.deleted_at IS NULL
+		ORDER BY c.date_created DESC`
+	if err := sqlx.SelectContext(ctx, database.Conn(ctx, db), &coupons, q, restaurantID); err != nil {
+		return nil, errors.Wrap(err, "selecting coupons")
+	}
+
+	return coupons, nil
+}
+
+// Retrieve finds the coupon of the restaurant identified by a given ID.
+func Retrieve(ctx context.Context, db *sqlx.DB, restaurantID, id string) (*Coupon, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.coupon.Retrieve")
+	defer span.End()
+
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrInvalidID
+	}
+
+	var c Coupon
+	const q = selectCoupons + ` WHERE c.coupon_id = $1 AND c.restaurant_id = $2 AND c.deleted_at IS NULL`
+	if err := sqlx.GetContext(ctx, database.Conn(ctx, db), &c, q, id, restaurantID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, errors.Wrapf(err, "selecting coupon %q", id)
+	}
+
+	return &c, nil
+}
+
+// Delete removes the coupon of the restaurant. The orders it was applied to
+// keep their discount.
+func Delete(ctx context.Context, db *sqlx.DB, restaurantID, id string, now time.Time) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.coupon.Delete")
+	defer span.End()
+
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrInvalidID
+	}
+
+	const q = `UPDATE coupon SET
+		"deleted_at" = $3
+		WHERE coupon_id = $1 AND restaurant_id = $2 AND deleted_at IS NULL`
+	if _, err := database.Conn(ctx, db).ExecContext(ctx, q, id, restaurantID, now.UTC()); err != nil {
+		return errors.Wrapf(err, "deleting coupon %s", id)
+	}
+
+	return nil
+}
+
+// Redemptions returns the uses of the coupon, the most recent first.
+func Redemptions(ctx context.Context, db *sqlx.DB, restaurantID, id string) ([]Redemption, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.coupon.Redemptions")
+	defer span.End()
+
+	if _, err := Retrieve(ctx, db, restaurantID, id); err != nil {
+		return nil, err
+	}
+
+	redemptions := []Redemption{}
+	const q = `SELECT * FROM coupon_redemption WHERE coupon_id = $1 ORDER BY date_redeemed DESC`
+	if err := sqlx.SelectContext(ctx, database.Conn(ctx, db), &redemptions, q, id); err != nil {
+		return nil, errors.Wrapf(err, "selecting redemptions of coupon %s", id)
+	}
+
+	return redemptions, nil
+}
+
+// Redeem applies the coupon of the restaurant with the code to the order of
+// the user totalling total cents and returns its discount. It runs in the
+// transaction placing the order: the coupon stays locked until it ends so
+// concurrent orders cannot use it past its limits.
+func Redeem(ctx context.Context, tx sqlx.ExtContext, restaurantID, code, userID, orderID string, total int, now time.Time) (int, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.coupon.Redeem")
+	defer span.End()
+
+	var c Coupon
+	const qc = `SELECT c.*, 0 AS redemptions FROM coupon AS c
+		WHERE c.restaurant_id = $1 AND c.code = $2 AND c.deleted_at IS NULL
+		FOR UPDATE`
+	if err := sqlx.GetContext(ctx, tx, &c, qc, restaurantID, strings.ToUpper(code)); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, ErrInvalidCode
+		}
+		return 0, errors.Wrap(err, "selecting coupon")
+	}
+	if !c.Valid(now.UTC()) {
+		return 0, ErrInvalidCode
+	}
+
+	var used struct {
+		All  int `db:"all_uses"`
+		User int `db:"user_uses"`
+	}
+	const qu = `SELECT COUNT(*) AS all_uses, COUNT(*) FILTER (WHERE r.user_id = $2) AS user_uses
+		FROM coupon_redemption AS r
+		JOIN orders AS o ON o.order_id = r.order_id
+		WHERE r.coupon_id = $1 AND o.status <> 'CANCELLED'`
+	if err := sqlx.GetContext(ctx, tx, &used, qu, c.ID, userID); err != nil {
+		return 0, errors.Wrapf(err, "counting redemptions of coupon %s", c.ID)
+	}
+	if c.MaxRedemptions != nil && used.All >= *c.MaxRedemptions {
+		return 0, ErrExhausted
+	}
+	if c.MaxPerUser != nil && used.User >= *c.MaxPerUser {
+		return 0, ErrExhausted
+	}
+
+	r := Redemption{
+		OrderID:      orderID,
+		CouponID:     c.ID,
+		UserID:       userID,
+		Discount:     c.Discount(total),
+		DateRedeemed: now.UTC(),
+	}
+
+	const qr = `INSERT INTO coupon_redemption
+		(order_id, coupon_id, user_id, discount, date_redeemed)
+		VALUES ($1, $2, $3, $4, $5)`
+	if _, err := tx.ExecContext(ctx, qr, r.OrderID, r.CouponID, r.UserID, r.Discount, r.DateRedeemed); err != nil {
+		return 0, errors.Wrapf(err, "inserting redemption of coupon %s", c.ID)
+	}
+
+	return r.Discount, nil
+}
@@ -0,0 +1,64 @@
+package coupon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// TestDiscount validates the discount of the coupons never exceeds the total.
+func TestDiscount(t *testing.T) {
+	tt := []struct {
+		name  string
+		kind  string
+		value int
+		total int
+		want  int
+	}{
+		{"percentage", KindPercent, 10, 2550, 255},
+		{"whole percentage", KindPercent, 100, 2550, 2550},
+		{"fixed", KindFixed, 500, 2550, 500},
+		{"fixed over the total", KindFixed, 5000, 2550, 2550},
+	}
+
+	t.Log("Given the need to take coupons off orders.")
+	{
+		for i, tc := range tt {
+			t.Logf("\tTest %d:\tWhen applying a %s coupon.", i, tc.name)
+			c := Coupon{Kind: tc.kind, Value: tc.value}
+			if got := c.Discount(tc.total); got != tc.want {
+				t.Fatalf("\t%s\tShould take %d off : got %d.", tests.Failed, tc.want, got)
+			}
+			tests.LogSuccess(t, "Should take the expected discount off.")
+		}
+	}
+}
+
+// TestValid validates coupons are only valid within their window.
+func TestValid(t *testing.T) {
+	from := time.Date(2020, time.March, 1, 0, 0, 0, 0, time.UTC)
+	c := Coupon{ValidFrom: from, ValidUntil: from.AddDate(0, 0, 7)}
+
+	tt := []struct {
+		name string
+		now  time.Time
+		want bool
+	}{
+		{"before the window", from.Add(-time.Second), false},
+		{"at its start", from, true},
+		{"within the window", from.AddDate(0, 0, 3), true},
+		{"at its end", from.AddDate(0, 0, 7), false},
+	}
+
+	t.Log("Given the need to limit coupons to their validity window.")
+	{
+		for i, tc := range tt {
+			t.Logf("\tTest %d:\tWhen applying a coupon %s.", i, tc.name)
+			if got := c.Valid(tc.now); got != tc.want {
+				t.Fatalf("\t%s\tShould get %v : got %v.", tests.Failed, tc.want, got)
+			}
+			tests.LogSuccess(t, "Should get the expected result.")
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package coupon
+
+import "time"
+
+// These are the kinds of Coupon. A percentage coupon takes Value percent off
+// the order while a fixed one takes Value cents off.
+const (
+	KindPercent = "PERCENT"
+	KindFixed   = "FIXED"
+)
+
+// Coupon is a discount code of a restaurant users apply to their orders.
+// The limits are unset when there is none.
+type Coupon struct {
+	ID             string     `db:"coupon_id" json:"id"`
+	RestaurantID   string     `db:"restaurant_id" json:"restaurant_id"`
+	Code           string     `db:"code" json:"code"`
+	Kind           string     `db:"kind" json:"kind"`
+	Value          int        `db:"value" json:"value"`
+	ValidFrom      time.Time  `db:"valid_from" json:"valid_from"`
+	ValidUntil     time.Time  `db:"valid_until" json:"valid_until"`
+	MaxRedemptions *int       `db:"max_redemptions" json:"max_redemptions,omitempty"`
+	MaxPerUser     *int       `db:"max_per_user" json:"max_per_user,omitempty"`
+	Redemptions    int        `db:"redemptions" json:"redemptions"`
+	DateCreated    time.Time  `db:"date_created" json:"date_created"`
+	DeletedAt      *time.Time `db:"deleted_at" json:"-"`
+}
+
+// NewCoupon is what we require from restaurant owners when creating a
+// Coupon. The code is matched regardless of case.
+type NewCoupon struct {
+	Code           string    `json:"code" validate:"required,alphanum,max=32"`
+	Kind           string    `json:"kind" validate:"required,oneof=PERCENT FIXED"`
+	Value          int       `json:"value" validate:"required,min=1"`
+	ValidFrom      time.Time `json:"valid_from" validate:"required"`
+	ValidUntil     time.Time `json:"valid_until" validate:"required"`
+	MaxRedemptions *int      `json:"max_redemptions" validate:"omitempty,min=1"`
+	MaxPerUser     *int      `json:"max_per_user" validate:"omitempty,min=1"`
+}
+
+// Redemption is the use of a Coupon for an order.
+type Redemption struct {
+	OrderID      string    `db:"order_id" json:"order_id"`
+	CouponID     string    `db:"coupon_id" json:"coupon_id"`
+	UserID       string    `db:"user_id" json:"user_id"`
+	Discount     int       `db:"discount" json:"discount"`
+	DateRedeemed time.Time `db:"date_redeemed" json:"date_redeemed"`
+}
+
+// Valid reports if the coupon may be used at now.
+func (c Coupon) Valid(now time.Time) bool {
+	return !now.Before(c.ValidFrom) && now.Before(c.ValidUntil)
+}
+
+// Discount returns the cents the coupon takes off an order totalling total
+// cents. It never takes more than the total.
+func (c Coupon) Discount(total int) int {
+	d := c.Value
+	if c.Kind == KindPercent {
+		d = total * c.Value / 100
+	}
+	if d > total {
+		d = total
+	}
+	return d
+}
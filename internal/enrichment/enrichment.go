@@ -0,0 +1,235 @@
+package enrichment
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/authz"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/restaurant"
+	"go.opentelemetry.io/otel"
+)
+
+// Predefined errors identify expected failure conditions.
+var (
+	// ErrNotFound is used when a specific Suggestion is requested but does not exist.
+	ErrNotFound = errors.New("Suggestion not found")
+
+	// ErrInvalidID is used when an invalid UUID is provided.
+	ErrInvalidID = errors.New("ID is not in its proper form")
+
+	// ErrNoMatch is returned by a Provider when it has no data for a restaurant.
+	ErrNoMatch = errors.New("No matching place found")
+
+	// ErrDecided occurs when an owner tries to accept or reject a Suggestion
+	// which is no longer pending.
+	ErrDecided = errors.New("Suggestion has already been decided")
+)
+
+// Provider looks up public data about a restaurant from an external source.
+type Provider interface {
+
+	// Name identifies the provider in the provenance of stored suggestions.
+	Name() string
+
+	// Lookup finds the place best matching the name and address. It returns
+	// ErrNoMatch when the provider knows nothing about the restaurant.
+	Lookup(ctx context.Context, name, address string) (*Place, error)
+}
+
+// Enrich asks the provider about the identified restaurant and stores every
+// field value which differs from the current data as a pending Suggestion.
+// Values which were already suggested before are not stored again so
+// rejected values are not offered to the owner twice.
+func Enrich(ctx context.Context, db *sqlx.DB, p Provider, restaurantID string, now time.Time) ([]Suggestion, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.enrichment.Enrich")
+	defer span.End()
+
+	r, err := restaurant.Retrieve(ctx, db, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+
+	place, err := p.Lookup(ctx, r.Name, r.Address)
+	if err != nil {
+		if err == ErrNoMatch {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "looking up restaurant %q at %s", r.ID, p.Name())
+	}
+
+	values := map[string][]string{}
+	if place.Website != "" && place.Website != r.Website {
+		values[FieldWebsite] = []string{place.Website}
+	}
+	if place.Phone != "" && place.Phone != r.Phone {
+		values[FieldPhone] = []string{place.Phone}
+	}
+	for _, photo := range place.Photos {
+		if !contains(r.Photos, photo) {
+			values[FieldPhotos] = append(values[FieldPhotos], photo)
+		}
+	}
+
+	const q = `INSERT INTO restaurant_suggestion
+		(suggestion_id, restaurant_id, field, value, provider, source_id, status, date_fetched)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (restaurant_id, field, value) DO NOTHING`
+
+	suggestions := []Suggestion{}
+	for field, vs := range values {
+		for _, v := range vs {
+			s := Suggestion{
+				ID:           uuid.New().String(),
+				RestaurantID: r.ID,
+				Field:        field,
+				Value:        v,
+				Provider:     p.Name(),
+				SourceID:     place.SourceID,
+				Status:       StatusPending,
+				DateFetched:  now.UTC(),
+			}
+
+			res, err := db.ExecContext(ctx, q, s.ID, s.RestaurantID, s.Field, s.Value, s.Provider, s.SourceID, s.Status, s.DateFetched)
+			if err != nil {
+				return nil, errors.Wrap(err, "inserting suggestion")
+			}
+			if n, err := res.RowsAffected(); err == nil && n == 0 {
+				continue
+			}
+			suggestions = append(suggestions, s)
+		}
+	}
+
+	return suggestions, nil
+}
+
+// List gets all suggestions made for the identified restaurant.
+func List(ctx context.Context, db *sqlx.DB, restaurantID string) ([]Suggestion, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.enrichment.List")
+	defer span.End()
+
+	if _, err := uuid.Parse(restaurantID); err != nil {
+		return nil, ErrInvalidID
+	}
+
+	suggestions := []Suggestion{}
+	const q = `SELECT * FROM restaurant_suggestion WHERE restaurant_id = $1 ORDER BY date_fetched, field`
+	if err := db.SelectContext(ctx, &suggestions, q, restaurantID); err != nil {
+		return nil, errors.Wrap(err, "selecting suggestions")
+	}
+
+	return suggestions, nil
+}
+
+// Retrieve finds the suggestion identified by a given ID.
+func Retrieve(ctx context.Context, db *sqlx.DB, restaurantID, id string) (*Suggestion, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.enrichment.Retrieve")
+	defer span.End()
+
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrInvalidID
+	}
+
+	var s Suggestion
+	const q = `SELECT * FROM restaurant_suggestion WHERE suggestion_id = $1 AND restaurant_id = $2`
+	if err := db.GetContext(ctx, &s, q, id, restaurantID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, errors.Wrap(err, "selecting single suggestion")
+	}
+
+	return &s, nil
+}
+
+// Accept applies the suggested value to the restaurant and marks the
+// suggestion as accepted. Only the owner of the restaurant or an admin may
+// accept a suggestion.
+func Accept(ctx context.Context, db *sqlx.DB, user auth.Claims, restaurantID, id string, now time.Time) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.enrichment.Accept")
+	defer span.End()
+
+	s, err := Retrieve(ctx, db, restaurantID, id)
+	if err != nil {
+		return err
+	}
+	if s.Status != StatusPending {
+		return ErrDecided
+	}
+
+	r, err := restaurant.Retrieve(ctx, db, restaurantID)
+	if err != nil {
+		return err
+	}
+
+	var up restaurant.UpdateRestaurant
+	switch s.Field {
+	case FieldWebsite:
+		up.Website = &s.Value
+	case FieldPhone:
+		up.Phone = &s.Value
+	case FieldPhotos:
+		up.Photos = append(r.Photos, s.Value)
+	}
+
+	if _, err := restaurant.Update(ctx, db, user, restaurantID, up, now); err != nil {
+		return err
+	}
+
+	return decide(ctx, db, s.ID, StatusAccepted, now)
+}
+
+// Reject marks the suggestion as rejected leaving the restaurant unchanged.
+// Only the owner of the restaurant or an admin may reject a suggestion.
+func Reject(ctx context.Context, db *sqlx.DB, user auth.Claims, restaurantID, id string, now time.Time) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.enrichment.Reject")
+	defer span.End()
+
+	s, err := Retrieve(ctx, db, restaurantID, id)
+	if err != nil {
+		return err
+	}
+	if s.Status != StatusPending {
+		return ErrDecided
+	}
+
+	r, err := restaurant.Retrieve(ctx, db, restaurantID)
+	if err != nil {
+		return err
+	}
+
+	if !authz.Allowed(user, authz.Update, authz.Resource{Kind: authz.Restaurant, Owner: r.OwnerUserID}) {
+		return restaurant.ErrForbidden
+	}
+
+	return decide(ctx, db, s.ID, StatusRejected, now)
+}
+
+// decide records the owner's decision on a suggestion.
+func decide(ctx context.Context, db *sqlx.DB, id, status string, now time.Time) error {
+	const q = `UPDATE restaurant_suggestion SET
+		"status" = $2,
+		"date_decided" = $3
+		WHERE suggestion_id = $1`
+
+	if _, err := db.ExecContext(ctx, q, id, status, now.UTC()); err != nil {
+		return errors.Wrap(err, "updating suggestion")
+	}
+
+	return nil
+}
+
+// contains reports whether v is present in list.
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
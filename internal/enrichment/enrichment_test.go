@@ -0,0 +1,153 @@
+package enrichment_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/remisb/restaurant/internal/enrichment"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/restaurant"
+	"github.com/remisb/restaurant/internal/schema"
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// fixed is a Provider finding the same place for every restaurant.
+type fixed struct {
+	place *enrichment.Place
+}
+
+// Name implements the enrichment.Provider interface.
+func (f fixed) Name() string {
+	return "fixed"
+}
+
+// Lookup implements the enrichment.Provider interface.
+func (f fixed) Lookup(ctx context.Context, name, address string) (*enrichment.Place, error) {
+	if f.place == nil {
+		return nil, enrichment.ErrNoMatch
+	}
+	return f.place, nil
+}
+
+// TestEnrich validates the values found by a provider are offered once to
+// the owner, who may accept or reject them.
+func TestEnrich(t *testing.T) {
+	db, teardown := tests.NewUnit(t)
+	defer teardown()
+
+	if err := schema.SeedProfile(db, "dev"); err != nil {
+		t.Fatalf("seeding: %s", err)
+	}
+
+	const (
+		restaurantID = "0ce90028-69cb-4e9c-9af0-7bbada50d5b6"
+		ownerID      = "5cf37266-3473-4006-984f-9325122678b7"
+		otherID      = "45b5fbd3-755f-4379-8f07-a58d4a30fa2f"
+	)
+	now := time.Date(2020, time.March, 2, 9, 0, 0, 0, time.UTC)
+	owner := auth.NewClaims(ownerID, []string{auth.RoleUser}, now, time.Hour)
+	other := auth.NewClaims(otherID, []string{auth.RoleUser}, now, time.Hour)
+
+	p := fixed{&enrichment.Place{
+		SourceID: "ChIJ1",
+		Website:  "https://paikis.lt",
+		Photos:   []string{"https://photos/1", "https://photos/2"},
+	}}
+
+	t.Log("Given the need to offer public data about a restaurant to its owner.")
+	{
+		ctx := tests.Context()
+
+		suggested := map[string]enrichment.Suggestion{}
+
+		t.Log("\tTest 0:\tWhen the provider finds the restaurant.")
+		{
+			ss, err := enrichment.Enrich(ctx, db, p, restaurantID, now)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to enrich the restaurant : %s.", tests.Failed, err)
+			}
+			if len(ss) != 3 {
+				t.Fatalf("\t%s\tShould suggest every new value : got %+v.", tests.Failed, ss)
+			}
+			for _, s := range ss {
+				if s.Status != enrichment.StatusPending || s.Provider != "fixed" || s.SourceID != "ChIJ1" {
+					t.Fatalf("\t%s\tShould record where the values came from : got %+v.", tests.Failed, s)
+				}
+				suggested[s.Value] = s
+			}
+			t.Logf("\t%s\tShould suggest every new value.", tests.Success)
+
+			if ss, err := enrichment.Enrich(ctx, db, fixed{}, restaurantID, now); err != nil || len(ss) != 0 {
+				t.Fatalf("\t%s\tShould suggest nothing without a match : got %+v, %v.", tests.Failed, ss, err)
+			}
+			t.Logf("\t%s\tShould suggest nothing without a match.", tests.Success)
+		}
+
+		t.Log("\tTest 1:\tWhen the owner decides on the suggestions.")
+		{
+			website := suggested["https://paikis.lt"]
+
+			if err := enrichment.Accept(ctx, db, other, restaurantID, website.ID, now); err != restaurant.ErrForbidden {
+				t.Fatalf("\t%s\tShould not let others accept it : got %v.", tests.Failed, err)
+			}
+			if err := enrichment.Reject(ctx, db, other, restaurantID, website.ID, now); err != restaurant.ErrForbidden {
+				t.Fatalf("\t%s\tShould not let others reject it : got %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not let others decide.", tests.Success)
+
+			if err := enrichment.Accept(ctx, db, owner, restaurantID, website.ID, now); err != nil {
+				t.Fatalf("\t%s\tShould be able to accept the website : %s.", tests.Failed, err)
+			}
+			if err := enrichment.Accept(ctx, db, owner, restaurantID, suggested["https://photos/1"].ID, now); err != nil {
+				t.Fatalf("\t%s\tShould be able to accept the photo : %s.", tests.Failed, err)
+			}
+			if err := enrichment.Reject(ctx, db, owner, restaurantID, suggested["https://photos/2"].ID, now); err != nil {
+				t.Fatalf("\t%s\tShould be able to reject the photo : %s.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to decide.", tests.Success)
+
+			r, err := restaurant.Retrieve(ctx, db, restaurantID)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to retrieve the restaurant : %s.", tests.Failed, err)
+			}
+			if r.Website != "https://paikis.lt" || len(r.Photos) != 1 || r.Photos[0] != "https://photos/1" {
+				t.Fatalf("\t%s\tShould apply the accepted values only : got %q, %v.", tests.Failed, r.Website, r.Photos)
+			}
+			t.Logf("\t%s\tShould apply the accepted values only.", tests.Success)
+
+			if err := enrichment.Reject(ctx, db, owner, restaurantID, website.ID, now); err != enrichment.ErrDecided {
+				t.Fatalf("\t%s\tShould not decide twice : got %v.", tests.Failed, err)
+			}
+			if err := enrichment.Accept(ctx, db, owner, "2df32931-3072-4d11-8109-d1f0988c26b3", website.ID, now); err != enrichment.ErrNotFound {
+				t.Fatalf("\t%s\tShould not decide for another restaurant : got %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould decide once for the restaurant only.", tests.Success)
+		}
+
+		t.Log("\tTest 2:\tWhen the provider is asked again.")
+		{
+			ss, err := enrichment.Enrich(ctx, db, p, restaurantID, now.Add(time.Hour))
+			if err != nil || len(ss) != 0 {
+				t.Fatalf("\t%s\tShould not offer the same values twice : got %+v, %v.", tests.Failed, ss, err)
+			}
+			t.Logf("\t%s\tShould not offer the same values twice.", tests.Success)
+
+			all, err := enrichment.List(ctx, db, restaurantID)
+			if err != nil || len(all) != 3 {
+				t.Fatalf("\t%s\tShould list every suggestion : got %+v, %v.", tests.Failed, all, err)
+			}
+			decided := map[string]int{}
+			for _, s := range all {
+				decided[s.Status]++
+				if s.DateDecided == nil {
+					t.Fatalf("\t%s\tShould record when it was decided : got %+v.", tests.Failed, s)
+				}
+			}
+			if decided[enrichment.StatusAccepted] != 2 || decided[enrichment.StatusRejected] != 1 {
+				t.Fatalf("\t%s\tShould record the decisions : got %v.", tests.Failed, decided)
+			}
+			t.Logf("\t%s\tShould record the decisions.", tests.Success)
+		}
+	}
+}
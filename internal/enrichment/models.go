@@ -0,0 +1,40 @@
+package enrichment
+
+import "time"
+
+// These are the fields of a Restaurant a provider may suggest values for.
+const (
+	FieldWebsite = "website"
+	FieldPhone   = "phone"
+	FieldPhotos  = "photos"
+)
+
+// These are the states a Suggestion moves through.
+const (
+	StatusPending  = "PENDING"
+	StatusAccepted = "ACCEPTED"
+	StatusRejected = "REJECTED"
+)
+
+// Place is the public data a Provider found for a restaurant.
+type Place struct {
+	SourceID string
+	Website  string
+	Phone    string
+	Photos   []string
+}
+
+// Suggestion is a single field value pulled from an external provider which
+// the restaurant owner may accept or reject. Provider, SourceID and
+// DateFetched record where the value came from.
+type Suggestion struct {
+	ID           string     `db:"suggestion_id" json:"id"`
+	RestaurantID string     `db:"restaurant_id" json:"restaurant_id"`
+	Field        string     `db:"field" json:"field"`
+	Value        string     `db:"value" json:"value"`
+	Provider     string     `db:"provider" json:"provider"`
+	SourceID     string     `db:"source_id" json:"source_id"`
+	Status       string     `db:"status" json:"status"`
+	DateFetched  time.Time  `db:"date_fetched" json:"date_fetched"`
+	DateDecided  *time.Time `db:"date_decided" json:"date_decided,omitempty"`
+}
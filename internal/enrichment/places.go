@@ -0,0 +1,136 @@
+package enrichment
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+)
+
+// PlacesURL is the base URL of the Google Places web service.
+const PlacesURL = "https://maps.googleapis.com/maps/api/place"
+
+// Places is a Provider speaking the Google Places web service protocol. Any
+// service implementing the findplacefromtext and details endpoints can be
+// used by pointing BaseURL at it.
+type Places struct {
+	BaseURL string
+	APIKey  string
+	Client  *http.Client
+}
+
+// NewPlaces constructs a Places provider for the service at baseURL.
+func NewPlaces(baseURL, apiKey string) *Places {
+	return &Places{
+		BaseURL: baseURL,
+		APIKey:  apiKey,
+		Client:  &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)},
+	}
+}
+
+// Name implements the Provider interface.
+func (p *Places) Name() string {
+	return "places"
+}
+
+// Lookup implements the Provider interface. It finds the best matching place
+// for the name and address and then retrieves its details.
+func (p *Places) Lookup(ctx context.Context, name, address string) (*Place, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.enrichment.Places.Lookup")
+	defer span.End()
+
+	var found struct {
+		Status     string `json:"status"`
+		Candidates []struct {
+			PlaceID string `json:"place_id"`
+		} `json:"candidates"`
+	}
+
+	q := make(url.Values)
+	q.Set("input", name+", "+address)
+	q.Set("inputtype", "textquery")
+	q.Set("fields", "place_id")
+	if err := p.get(ctx, "/findplacefromtext/json", q, &found); err != nil {
+		return nil, err
+	}
+
+	switch found.Status {
+	case "OK":
+	case "ZERO_RESULTS":
+		return nil, ErrNoMatch
+	default:
+		return nil, errors.Errorf("find place status %s", found.Status)
+	}
+	if len(found.Candidates) == 0 {
+		return nil, ErrNoMatch
+	}
+
+	var details struct {
+		Status string `json:"status"`
+		Result struct {
+			Website string `json:"website"`
+			Phone   string `json:"international_phone_number"`
+			Photos  []struct {
+				Reference string `json:"photo_reference"`
+			} `json:"photos"`
+		} `json:"result"`
+	}
+
+	placeID := found.Candidates[0].PlaceID
+	q = make(url.Values)
+	q.Set("place_id", placeID)
+	q.Set("fields", "website,international_phone_number,photos")
+	if err := p.get(ctx, "/details/json", q, &details); err != nil {
+		return nil, err
+	}
+	if details.Status != "OK" {
+		return nil, errors.Errorf("place details status %s", details.Status)
+	}
+
+	place := Place{
+		SourceID: placeID,
+		Website:  details.Result.Website,
+		Phone:    details.Result.Phone,
+	}
+
+	// Photo URLs are stored without the API key so it never leaks to clients.
+	for _, photo := range details.Result.Photos {
+		pq := make(url.Values)
+		pq.Set("maxwidth", "800")
+		pq.Set("photo_reference", photo.Reference)
+		place.Photos = append(place.Photos, p.BaseURL+"/photo?"+pq.Encode())
+	}
+
+	return &place, nil
+}
+
+// get performs a request against the provider and decodes the JSON response.
+func (p *Places) get(ctx context.Context, path string, q url.Values, val interface{}) error {
+	q.Set("key", p.APIKey)
+
+	req, err := http.NewRequest(http.MethodGet, p.BaseURL+path+"?"+q.Encode(), nil)
+	if err != nil {
+		return errors.Wrap(err, "creating request")
+	}
+	req = req.WithContext(ctx)
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "calling %s", path)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("calling %s: status %d", path, resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(val); err != nil {
+		return errors.Wrapf(err, "decoding %s response", path)
+	}
+
+	return nil
+}
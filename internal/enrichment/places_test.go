@@ -0,0 +1,90 @@
+package enrichment_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/remisb/restaurant/internal/enrichment"
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// TestPlaces validates the place found by the Google Places protocol is read
+// from the find and details responses.
+func TestPlaces(t *testing.T) {
+	var found, details string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("key") != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/findplacefromtext/json":
+			w.Write([]byte(found))
+		case "/details/json":
+			if r.URL.Query().Get("place_id") != "ChIJ1" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write([]byte(details))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	p := enrichment.NewPlaces(srv.URL, "secret")
+
+	t.Log("Given the need to look up restaurants at Google Places.")
+	{
+		t.Log("\tTest 0:\tWhen the restaurant is found.")
+		{
+			found = `{"status":"OK","candidates":[{"place_id":"ChIJ1"},{"place_id":"ChIJ2"}]}`
+			details = `{"status":"OK","result":{"website":"https://paikis.lt","international_phone_number":"+370 600 00000","photos":[{"photo_reference":"ref-1"}]}}`
+
+			place, err := p.Lookup(context.Background(), "Paikis", "A. Smetonos g. 5")
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to look up the restaurant : %s.", tests.Failed, err)
+			}
+			if place.SourceID != "ChIJ1" || place.Website != "https://paikis.lt" || place.Phone != "+370 600 00000" {
+				t.Fatalf("\t%s\tShould read the details of the best match : got %+v.", tests.Failed, place)
+			}
+			t.Logf("\t%s\tShould read the details of the best match.", tests.Success)
+
+			if len(place.Photos) != 1 || !strings.HasPrefix(place.Photos[0], srv.URL+"/photo?") || !strings.Contains(place.Photos[0], "photo_reference=ref-1") {
+				t.Fatalf("\t%s\tShould link the photos : got %v.", tests.Failed, place.Photos)
+			}
+			if strings.Contains(place.Photos[0], "secret") {
+				t.Fatalf("\t%s\tShould not leak the API key in the photos : got %v.", tests.Failed, place.Photos)
+			}
+			t.Logf("\t%s\tShould link the photos without the API key.", tests.Success)
+		}
+
+		t.Log("\tTest 1:\tWhen the restaurant is unknown.")
+		{
+			found = `{"status":"ZERO_RESULTS","candidates":[]}`
+
+			if _, err := p.Lookup(context.Background(), "Nowhere", ""); err != enrichment.ErrNoMatch {
+				t.Fatalf("\t%s\tShould report no match : got %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould report no match.", tests.Success)
+		}
+
+		t.Log("\tTest 2:\tWhen the service refuses the request.")
+		{
+			found = `{"status":"REQUEST_DENIED"}`
+
+			if _, err := p.Lookup(context.Background(), "Paikis", ""); err == nil || err == enrichment.ErrNoMatch {
+				t.Fatalf("\t%s\tShould report the failure : got %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould report the failure.", tests.Success)
+
+			if _, err := enrichment.NewPlaces(srv.URL, "wrong").Lookup(context.Background(), "Paikis", ""); err == nil {
+				t.Fatalf("\t%s\tShould report an HTTP failure.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould report an HTTP failure.", tests.Success)
+		}
+	}
+}
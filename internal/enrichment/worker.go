@@ -0,0 +1,67 @@
+package enrichment
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/remisb/restaurant/internal/platform/job"
+)
+
+// Worker enriches restaurants in the background. Restaurants are queued by
+// ID and looked up one at a time so a slow provider never holds up a request.
+type Worker struct {
+	log      *log.Logger
+	db       *sqlx.DB
+	provider Provider
+	queue    chan string
+	tracker  *job.Tracker
+}
+
+// NewWorker constructs a Worker able to hold size pending restaurants.
+func NewWorker(log *log.Logger, db *sqlx.DB, provider Provider, size int) *Worker {
+	return &Worker{
+		log:      log,
+		db:       db,
+		provider: provider,
+		queue:    make(chan string, size),
+		tracker:  job.NewTracker("enrichment"),
+	}
+}
+
+// Status reports the state of the worker to the health check.
+func (w *Worker) Status() job.Status {
+	st := w.tracker.Status()
+	st.Backlog = len(w.queue)
+	return st
+}
+
+// Enqueue schedules the restaurant for enrichment. It never blocks and
+// reports false when the queue is full.
+func (w *Worker) Enqueue(restaurantID string) bool {
+	select {
+	case w.queue <- restaurantID:
+		return true
+	default:
+		return false
+	}
+}
+
+// Run processes queued restaurants until the context is canceled.
+func (w *Worker) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case id := <-w.queue:
+			suggestions, err := Enrich(ctx, w.db, w.provider, id, time.Now())
+			w.tracker.Record(err, time.Now())
+			if err != nil {
+				w.log.Printf("enrichment : %s : ERROR : %+v", id, err)
+				continue
+			}
+			w.log.Printf("enrichment : %s : %d suggestions from %s", id, len(suggestions), w.provider.Name())
+		}
+	}
+}
@@ -0,0 +1,206 @@
+// Package featureflag turns risky features on per organization or for a
+// percentage of them without redeploying. Flags are stored in the database
+// and read every time a feature is checked so toggling one applies at once.
+package featureflag
+
+import (
+	"context"
+	"database/sql"
+	"hash/fnv"
+	"regexp"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/platform/database"
+	"go.opentelemetry.io/otel"
+)
+
+// These are the features behind a flag.
+const (
+	// DishVoting lets organizations switch to voting for dishes.
+	DishVoting = "dish_voting"
+)
+
+// defaults tells whether the features are on for everyone while their flag
+// was never set. Features which shipped before their flag stay on.
+var defaults = map[string]bool{
+	DishVoting: true,
+}
+
+// Predefined errors identify expected failure conditions.
+var (
+	// ErrNotFound is used when a specific Flag is requested but was never
+	// set.
+	ErrNotFound = errors.New("Feature flag not found")
+
+	// ErrInvalidName is used when a name is not made of lowercase letters,
+	// digits and underscores.
+	ErrInvalidName = errors.New("Feature flag name is not in its proper form")
+
+	// ErrDisabled occurs when using a feature which is off for the
+	// organization of the caller.
+	ErrDisabled = errors.New("Feature is not enabled")
+)
+
+// validName matches the names of the flags.
+var validName = regexp.MustCompile(`^[a-z0-9]+(_[a-z0-9]+)*$`)
+
+// On reports whether the feature is on for the organization.
+func (f Flag) On(org string) bool {
+	if f.Enabled {
+		return true
+	}
+	for _, o := range f.Orgs {
+		if o == org {
+			return true
+		}
+	}
+	return bucket(f.Name, org) < f.Percentage
+}
+
+// bucket places the organization in one of 100 buckets of the rollout of the
+// flag. Hashing the name along with it rolls flags out to different
+// organizations first.
+func bucket(name, org string) int {
+	h := fnv.New32a()
+	h.Write([]byte(name + ":" + org))
+	return int(h.Sum32() % 100)
+}
+
+// List gets every flag which was set.
+func List(ctx context.Context, db *sqlx.DB) ([]Flag, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.featureflag.List")
+	defer span.End()
+
+	flags := []Flag{}
+	const q = `SELECT * FROM feature_flag ORDER BY name`
+	if err := sqlx.SelectContext(ctx, database.Conn(ctx, db), &flags, q); err != nil {
+		return nil, errors.Wrap(err, "selecting feature flags")
+	}
+
+	return flags, nil
+}
+
+// Features reports whether every feature behind a flag is on for the
+// organization, the features whose flag was never set included.
+func Features(ctx context.Context, db *sqlx.DB, org string) (map[string]bool, error) {
+	flags, err := List(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	return features(flags, org), nil
+}
+
+// features reports whether the features of the flags and the defaults are on
+// for the organization.
+func features(flags []Flag, org string) map[string]bool {
+	on := make(map[string]bool, len(defaults)+len(flags))
+	for name, enabled := range defaults {
+		on[name] = enabled
+	}
+	for _, f := range flags {
+		on[f.Name] = f.On(org)
+	}
+	return on
+}
+
+// Retrieve gets the flag of the name.
+func Retrieve(ctx context.Context, db *sqlx.DB, name string) (*Flag, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.featureflag.Retrieve")
+	defer span.End()
+
+	f, err := load(ctx, database.Conn(ctx, db), name)
+	if err != nil {
+		return nil, err
+	}
+	if f.DateCreated.IsZero() {
+		return nil, ErrNotFound
+	}
+
+	return f, nil
+}
+
+// load gets the flag of the name, its default when it was never set.
+func load(ctx context.Context, q sqlx.QueryerContext, name string) (*Flag, error) {
+	f := Flag{Name: name, Enabled: defaults[name], Orgs: pq.StringArray{}}
+	const qs = `SELECT * FROM feature_flag WHERE name = $1`
+	if err := sqlx.GetContext(ctx, q, &f, qs, name); err != nil && err != sql.ErrNoRows {
+		return nil, errors.Wrapf(err, "selecting feature flag %q", name)
+	}
+
+	return &f, nil
+}
+
+// Set creates or modifies the flag of the name.
+func Set(ctx context.Context, db *sqlx.DB, name string, uf UpdateFlag, now time.Time) (*Flag, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.featureflag.Set")
+	defer span.End()
+
+	if !validName.MatchString(name) {
+		return nil, ErrInvalidName
+	}
+
+	f, err := load(ctx, database.Conn(ctx, db), name)
+	if err != nil {
+		return nil, err
+	}
+
+	if uf.Description != nil {
+		f.Description = *uf.Description
+	}
+	if uf.Enabled != nil {
+		f.Enabled = *uf.Enabled
+	}
+	if uf.Orgs != nil {
+		f.Orgs = uf.Orgs
+	}
+	if uf.Percentage != nil {
+		f.Percentage = *uf.Percentage
+	}
+	if f.DateCreated.IsZero() {
+		f.DateCreated = now.UTC()
+	}
+	f.DateUpdated = now.UTC()
+
+	const q = `INSERT INTO feature_flag
+		(name, description, enabled, orgs, percentage, date_created, date_updated)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (name) DO UPDATE SET
+		"description" = EXCLUDED.description,
+		"enabled" = EXCLUDED.enabled,
+		"orgs" = EXCLUDED.orgs,
+		"percentage" = EXCLUDED.percentage,
+		"date_updated" = EXCLUDED.date_updated`
+	_, err = database.Conn(ctx, db).ExecContext(ctx, q, f.Name, f.Description, f.Enabled, f.Orgs, f.Percentage, f.DateCreated, f.DateUpdated)
+	if err != nil {
+		return nil, errors.Wrapf(err, "setting feature flag %q", name)
+	}
+
+	return f, nil
+}
+
+// Delete removes the flag of the name, turning its feature back to its
+// default.
+func Delete(ctx context.Context, db *sqlx.DB, name string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.featureflag.Delete")
+	defer span.End()
+
+	const q = `DELETE FROM feature_flag WHERE name = $1`
+	if _, err := database.Conn(ctx, db).ExecContext(ctx, q, name); err != nil {
+		return errors.Wrapf(err, "deleting feature flag %q", name)
+	}
+
+	return nil
+}
+
+// Enabled reports whether the feature of the name is on for the
+// organization. The business packages check their features with it.
+func Enabled(ctx context.Context, q sqlx.QueryerContext, name, org string) (bool, error) {
+	f, err := load(ctx, q, name)
+	if err != nil {
+		return false, err
+	}
+	return f.On(org), nil
+}
@@ -0,0 +1,83 @@
+package featureflag
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// TestOn validates which organizations a flag turns its feature on for.
+func TestOn(t *testing.T) {
+	const org = "00000000-0000-0000-0000-000000000001"
+
+	t.Log("Given the need to turn features on per organization.")
+	{
+		t.Log("\tTest 0:\tWhen the flag is enabled or lists the organization.")
+		{
+			if !(Flag{Name: "beta", Enabled: true}).On(org) {
+				t.Fatalf("\t%s\tShould be on for every organization.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould be on for every organization.", tests.Success)
+
+			f := Flag{Name: "beta", Orgs: pq.StringArray{org}}
+			if !f.On(org) {
+				t.Fatalf("\t%s\tShould be on for the listed organization.", tests.Failed)
+			}
+			if f.On("00000000-0000-0000-0000-000000000002") {
+				t.Fatalf("\t%s\tShould be off for the other organizations.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould be on for the listed organization only.", tests.Success)
+		}
+
+		t.Log("\tTest 1:\tWhen the flag is rolled out to a percentage.")
+		{
+			on := 0
+			for i := 0; i < 1000; i++ {
+				if (Flag{Name: "beta", Percentage: 30}).On(fmt.Sprintf("org-%d", i)) {
+					on++
+				}
+			}
+			if on < 200 || on > 400 {
+				t.Fatalf("\t%s\tShould be on for about 30%% of them : got %d of 1000.", tests.Failed, on)
+			}
+			t.Logf("\t%s\tShould be on for about 30%% of them.", tests.Success)
+
+			for i := 0; i < 100; i++ {
+				o := fmt.Sprintf("org-%d", i)
+				if (Flag{Name: "beta", Percentage: 30}).On(o) && !(Flag{Name: "beta", Percentage: 60}).On(o) {
+					t.Fatalf("\t%s\tShould stay on for %s as the rollout grows.", tests.Failed, o)
+				}
+			}
+			t.Logf("\t%s\tShould stay on as the rollout grows.", tests.Success)
+		}
+	}
+}
+
+// TestFeatures validates the features of an organization cover the flags set
+// and the defaults of the others.
+func TestFeatures(t *testing.T) {
+	const org = "00000000-0000-0000-0000-000000000001"
+
+	t.Log("Given the need to tell the features on for an organization.")
+	{
+		t.Log("\tTest 0:\tWhen some flags were set.")
+		{
+			on := features([]Flag{
+				{Name: "beta", Orgs: pq.StringArray{org}},
+				{Name: "gamma", Orgs: pq.StringArray{"00000000-0000-0000-0000-000000000002"}},
+			}, org)
+
+			if !on["beta"] || on["gamma"] {
+				t.Fatalf("\t%s\tShould evaluate the flags set for the organization : got %v.", tests.Failed, on)
+			}
+			t.Logf("\t%s\tShould evaluate the flags set for the organization.", tests.Success)
+
+			if enabled, ok := on[DishVoting]; !ok || !enabled {
+				t.Fatalf("\t%s\tShould report the default of the flags never set : got %v.", tests.Failed, on)
+			}
+			t.Logf("\t%s\tShould report the default of the flags never set.", tests.Success)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package featureflag
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Flag turns a feature on for every organization when Enabled, or else for
+// the organizations listed in Orgs and the given Percentage of the others.
+// An organization stays in or out of a percentage rollout as it grows.
+type Flag struct {
+	Name        string         `db:"name" json:"name"`
+	Description string         `db:"description" json:"description"`
+	Enabled     bool           `db:"enabled" json:"enabled"`
+	Orgs        pq.StringArray `db:"orgs" json:"orgs"`
+	Percentage  int            `db:"percentage" json:"percentage"`
+	DateCreated time.Time      `db:"date_created" json:"date_created"`
+	DateUpdated time.Time      `db:"date_updated" json:"date_updated"`
+}
+
+// UpdateFlag defines what information may be provided to set a Flag. All
+// fields are optional so clients can send just the fields they want changed.
+// A flag which does not exist yet is created starting from its default.
+type UpdateFlag struct {
+	Description *string  `json:"description" validate:"omitempty,max=200"`
+	Enabled     *bool    `json:"enabled"`
+	Orgs        []string `json:"orgs" validate:"omitempty,dive,uuid"`
+	Percentage  *int     `json:"percentage" validate:"omitempty,min=0,max=100"`
+}
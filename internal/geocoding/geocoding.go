@@ -0,0 +1,56 @@
+// Package geocoding finds the location of restaurants from their address.
+package geocoding
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/platform/geo"
+	"github.com/remisb/restaurant/internal/restaurant"
+	"go.opentelemetry.io/otel"
+)
+
+// ErrNoMatch is returned by a Geocoder when it cannot locate an address.
+var ErrNoMatch = errors.New("No matching location found")
+
+// Geocoder locates addresses using an external source.
+type Geocoder interface {
+
+	// Name identifies the geocoder in the logs.
+	Name() string
+
+	// Geocode finds the position of the address. It returns ErrNoMatch when
+	// the address cannot be located.
+	Geocode(ctx context.Context, address string) (geo.Point, error)
+}
+
+// Locate asks the geocoder for the position of the identified restaurant and
+// stores it. It returns nil when the address cannot be located, leaving the
+// restaurant without a location.
+func Locate(ctx context.Context, db *sqlx.DB, g Geocoder, restaurantID string, now time.Time) (*geo.Point, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.geocoding.Locate")
+	defer span.End()
+
+	r, err := restaurant.Retrieve(ctx, db, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := g.Geocode(ctx, r.Address)
+	if err != nil {
+		if err == ErrNoMatch {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "geocoding restaurant %q at %s", r.ID, g.Name())
+	}
+
+	// The address is checked again when storing the location so one which
+	// changed in the meantime is not given the location of the old one.
+	if err := restaurant.SetLocation(ctx, db, r.ID, r.Address, p, now); err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
@@ -0,0 +1,92 @@
+package geocoding
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/platform/geo"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+)
+
+// NominatimURL is the base URL of the public OpenStreetMap Nominatim service.
+const NominatimURL = "https://nominatim.openstreetmap.org"
+
+// Nominatim is a Geocoder speaking the OpenStreetMap Nominatim protocol. A
+// self hosted instance can be used by pointing BaseURL at it.
+type Nominatim struct {
+	BaseURL   string
+	UserAgent string
+	Client    *http.Client
+}
+
+// NewNominatim constructs a Nominatim geocoder for the service at baseURL.
+// The public service requires a user agent identifying the application.
+func NewNominatim(baseURL, userAgent string) *Nominatim {
+	return &Nominatim{
+		BaseURL:   baseURL,
+		UserAgent: userAgent,
+		Client:    &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)},
+	}
+}
+
+// Name implements the Geocoder interface.
+func (n *Nominatim) Name() string {
+	return "nominatim"
+}
+
+// Geocode implements the Geocoder interface. It keeps the best match of the
+// search for the address.
+func (n *Nominatim) Geocode(ctx context.Context, address string) (geo.Point, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.geocoding.Nominatim.Geocode")
+	defer span.End()
+
+	q := make(url.Values)
+	q.Set("q", address)
+	q.Set("format", "jsonv2")
+	q.Set("limit", "1")
+
+	req, err := http.NewRequest(http.MethodGet, n.BaseURL+"/search?"+q.Encode(), nil)
+	if err != nil {
+		return geo.Point{}, errors.Wrap(err, "creating request")
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("User-Agent", n.UserAgent)
+
+	resp, err := n.Client.Do(req)
+	if err != nil {
+		return geo.Point{}, errors.Wrap(err, "calling search")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return geo.Point{}, errors.Errorf("calling search: status %d", resp.StatusCode)
+	}
+
+	// Nominatim writes the coordinates as strings.
+	var found []struct {
+		Lat string `json:"lat"`
+		Lon string `json:"lon"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&found); err != nil {
+		return geo.Point{}, errors.Wrap(err, "decoding search response")
+	}
+	if len(found) == 0 {
+		return geo.Point{}, ErrNoMatch
+	}
+
+	lat, err := strconv.ParseFloat(found[0].Lat, 64)
+	if err != nil {
+		return geo.Point{}, errors.Wrapf(err, "parsing latitude %q", found[0].Lat)
+	}
+	lng, err := strconv.ParseFloat(found[0].Lon, 64)
+	if err != nil {
+		return geo.Point{}, errors.Wrapf(err, "parsing longitude %q", found[0].Lon)
+	}
+
+	return geo.Point{Lat: lat, Lng: lng}, nil
+}
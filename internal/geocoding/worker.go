@@ -0,0 +1,70 @@
+package geocoding
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/remisb/restaurant/internal/platform/job"
+)
+
+// Worker locates restaurants in the background. Restaurants are queued by ID
+// and geocoded one at a time so a slow geocoder never holds up a request.
+type Worker struct {
+	log      *log.Logger
+	db       *sqlx.DB
+	geocoder Geocoder
+	queue    chan string
+	tracker  *job.Tracker
+}
+
+// NewWorker constructs a Worker able to hold size pending restaurants.
+func NewWorker(log *log.Logger, db *sqlx.DB, geocoder Geocoder, size int) *Worker {
+	return &Worker{
+		log:      log,
+		db:       db,
+		geocoder: geocoder,
+		queue:    make(chan string, size),
+		tracker:  job.NewTracker("geocoding"),
+	}
+}
+
+// Status reports the state of the worker to the health check.
+func (w *Worker) Status() job.Status {
+	st := w.tracker.Status()
+	st.Backlog = len(w.queue)
+	return st
+}
+
+// Enqueue schedules the restaurant for geocoding. It never blocks and reports
+// false when the queue is full.
+func (w *Worker) Enqueue(restaurantID string) bool {
+	select {
+	case w.queue <- restaurantID:
+		return true
+	default:
+		return false
+	}
+}
+
+// Run processes queued restaurants until the context is canceled.
+func (w *Worker) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case id := <-w.queue:
+			p, err := Locate(ctx, w.db, w.geocoder, id, time.Now())
+			w.tracker.Record(err, time.Now())
+			switch {
+			case err != nil:
+				w.log.Printf("geocoding : %s : ERROR : %+v", id, err)
+			case p == nil:
+				w.log.Printf("geocoding : %s : no location from %s", id, w.geocoder.Name())
+			default:
+				w.log.Printf("geocoding : %s : located at %f,%f by %s", id, p.Lat, p.Lng, w.geocoder.Name())
+			}
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// Package idempotency remembers the responses of mutating requests sent with
+// an Idempotency-Key header so a retried request is answered with the stored
+// response instead of being processed again.
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/platform/database"
+	"go.opentelemetry.io/otel"
+)
+
+// Predefined errors identify expected failure conditions.
+var (
+	// ErrInProgress is used when a request with the same key is still being
+	// processed.
+	ErrInProgress = errors.New("A request with this Idempotency-Key is in progress")
+
+	// ErrMismatch is used when a key is reused for a different request.
+	ErrMismatch = errors.New("Idempotency-Key was used for a different request")
+)
+
+// Record is the stored outcome of a request. A Status of zero means the
+// request is still being processed, since DateCreated.
+type Record struct {
+	Key         string    `db:"idempotency_key"`
+	UserID      string    `db:"user_id"`
+	RequestHash string    `db:"request_hash"`
+	Status      int       `db:"status"`
+	ContentType string    `db:"content_type"`
+	Body        []byte    `db:"body"`
+	DateCreated time.Time `db:"date_created"`
+}
+
+// Reserve claims the key of the user for the request identified by hash. It
+// returns the stored record when the key was already used by the same
+// request and has completed. Keys older than ttl are reclaimed, and so are
+// the keys held longer than lease by a request which never completed, like
+// when the replica handling it stopped. The claim is identified by now, the
+// time Complete and Release are given.
+func Reserve(ctx context.Context, db *sqlx.DB, userID, key, hash string, ttl, lease time.Duration, now time.Time) (*Record, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.idempotency.Reserve")
+	defer span.End()
+
+	now = claimTime(now)
+
+	const qi = `INSERT INTO idempotency_key
+		(idempotency_key, user_id, request_hash, status, content_type, body, date_created)
+		VALUES ($1, $2, $3, 0, '', '', $4)
+		ON CONFLICT (idempotency_key, user_id) DO UPDATE SET
+			request_hash = EXCLUDED.request_hash,
+			status = 0,
+			content_type = '',
+			body = '',
+			date_created = EXCLUDED.date_created
+		WHERE idempotency_key.date_created < $5
+			OR (idempotency_key.status = 0 AND idempotency_key.date_created < $6)`
+	res, err := database.Conn(ctx, db).ExecContext(ctx, qi, key, userID, hash, now, now.Add(-ttl), now.Add(-lease))
+	if err != nil {
+		return nil, errors.Wrap(err, "reserving idempotency key")
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 1 {
+		return nil, nil
+	}
+
+	var rec Record
+	const qs = `SELECT * FROM idempotency_key WHERE idempotency_key = $1 AND user_id = $2`
+	if err := sqlx.GetContext(ctx, database.Conn(ctx, db), &rec, qs, key, userID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrInProgress
+		}
+		return nil, errors.Wrap(err, "selecting idempotency key")
+	}
+
+	switch {
+	case rec.RequestHash != hash:
+		return nil, ErrMismatch
+	case rec.Status == 0:
+		return nil, ErrInProgress
+	}
+
+	return &rec, nil
+}
+
+// Complete stores the response of the request which claimed the key at
+// claimed. Nothing is stored once another request reclaimed the key.
+func Complete(ctx context.Context, db *sqlx.DB, userID, key string, claimed time.Time, status int, contentType string, body []byte) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.idempotency.Complete")
+	defer span.End()
+
+	// Responses without a body, like 204 No Content, are stored empty.
+	if body == nil {
+		body = []byte{}
+	}
+
+	const q = `UPDATE idempotency_key SET status = $4, content_type = $5, body = $6
+		WHERE idempotency_key = $1 AND user_id = $2 AND date_created = $3 AND status = 0`
+	if _, err := database.Conn(ctx, db).ExecContext(ctx, q, key, userID, claimTime(claimed), status, contentType, body); err != nil {
+		return errors.Wrap(err, "completing idempotency key")
+	}
+
+	return nil
+}
+
+// Release frees the key claimed at claimed so the request can be retried,
+// used when the request failed without a response worth repeating.
+func Release(ctx context.Context, db *sqlx.DB, userID, key string, claimed time.Time) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.idempotency.Release")
+	defer span.End()
+
+	const q = `DELETE FROM idempotency_key WHERE idempotency_key = $1 AND user_id = $2 AND date_created = $3 AND status = 0`
+	if _, err := database.Conn(ctx, db).ExecContext(ctx, q, key, userID, claimTime(claimed)); err != nil {
+		return errors.Wrap(err, "releasing idempotency key")
+	}
+
+	return nil
+}
+
+// claimTime is the time of a claim as the database stores it, to the
+// microsecond in UTC.
+func claimTime(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Microsecond)
+}
@@ -0,0 +1,126 @@
+package idempotency_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/remisb/restaurant/internal/idempotency"
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// TestReserve validates a key is held by one request at a time, answers
+// the retries of that request with its response and refuses other requests.
+func TestReserve(t *testing.T) {
+	db, teardown := tests.NewUnit(t)
+	defer teardown()
+
+	const (
+		userID = "45b5fbd3-755f-4379-8f07-a58d4a30fa2f"
+		ttl    = 24 * time.Hour
+		lease  = time.Minute
+	)
+	now := time.Date(2020, time.March, 2, 9, 0, 0, 0, time.UTC)
+
+	t.Log("Given the need to process a request sent with an Idempotency-Key once.")
+	{
+		ctx := tests.Context()
+
+		t.Log("\tTest 0:\tWhen the request is retried while it is processed.")
+		{
+			if rec, err := idempotency.Reserve(ctx, db, userID, "order-1", "hash-1", ttl, lease, now); err != nil || rec != nil {
+				t.Fatalf("\t%s\tShould claim the key : got %+v, %v.", tests.Failed, rec, err)
+			}
+			t.Logf("\t%s\tShould claim the key.", tests.Success)
+
+			if _, err := idempotency.Reserve(ctx, db, userID, "order-1", "hash-1", ttl, lease, now.Add(time.Second)); err != idempotency.ErrInProgress {
+				t.Fatalf("\t%s\tShould tell the request is in progress : got %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould tell the request is in progress.", tests.Success)
+		}
+
+		t.Log("\tTest 1:\tWhen the request is retried once it completed.")
+		{
+			if err := idempotency.Complete(ctx, db, userID, "order-1", now, 201, "application/json", []byte(`{"id":"1"}`)); err != nil {
+				t.Fatalf("\t%s\tShould be able to complete the request : %s.", tests.Failed, err)
+			}
+
+			rec, err := idempotency.Reserve(ctx, db, userID, "order-1", "hash-1", ttl, lease, now.Add(time.Hour))
+			if err != nil || rec == nil {
+				t.Fatalf("\t%s\tShould return the stored response : got %+v, %v.", tests.Failed, rec, err)
+			}
+			if rec.Status != 201 || rec.ContentType != "application/json" || string(rec.Body) != `{"id":"1"}` {
+				t.Fatalf("\t%s\tShould return the stored response : got %+v.", tests.Failed, rec)
+			}
+			t.Logf("\t%s\tShould return the stored response.", tests.Success)
+		}
+
+		t.Log("\tTest 2:\tWhen the key is reused for a request with another body.")
+		{
+			if _, err := idempotency.Reserve(ctx, db, userID, "order-1", "hash-2", ttl, lease, now.Add(time.Hour)); err != idempotency.ErrMismatch {
+				t.Fatalf("\t%s\tShould refuse the other request : got %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould refuse the other request.", tests.Success)
+		}
+
+		t.Log("\tTest 3:\tWhen the request holding the key never completes.")
+		{
+			if rec, err := idempotency.Reserve(ctx, db, userID, "order-2", "hash-1", ttl, lease, now); err != nil || rec != nil {
+				t.Fatalf("\t%s\tShould claim the key : got %+v, %v.", tests.Failed, rec, err)
+			}
+
+			retried := now.Add(lease + time.Second)
+			if rec, err := idempotency.Reserve(ctx, db, userID, "order-2", "hash-1", ttl, lease, retried); err != nil || rec != nil {
+				t.Fatalf("\t%s\tShould let a retry claim the key once the lease expired : got %+v, %v.", tests.Failed, rec, err)
+			}
+			t.Logf("\t%s\tShould let a retry claim the key once the lease expired.", tests.Success)
+
+			if err := idempotency.Complete(ctx, db, userID, "order-2", now, 500, "", []byte("failed")); err != nil {
+				t.Fatalf("\t%s\tShould be able to complete the first request : %s.", tests.Failed, err)
+			}
+			if err := idempotency.Complete(ctx, db, userID, "order-2", retried, 201, "", []byte("retried")); err != nil {
+				t.Fatalf("\t%s\tShould be able to complete the retry : %s.", tests.Failed, err)
+			}
+
+			rec, err := idempotency.Reserve(ctx, db, userID, "order-2", "hash-1", ttl, lease, retried.Add(time.Second))
+			if err != nil || rec == nil || rec.Status != 201 || string(rec.Body) != "retried" {
+				t.Fatalf("\t%s\tShould keep the response of the retry : got %+v, %v.", tests.Failed, rec, err)
+			}
+			t.Logf("\t%s\tShould keep the response of the retry.", tests.Success)
+		}
+
+		t.Log("\tTest 4:\tWhen requests with the same key arrive at once.")
+		{
+			const n = 8
+
+			var wg sync.WaitGroup
+			errs := make(chan error, n)
+			for i := 0; i < n; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, err := idempotency.Reserve(ctx, db, userID, "order-3", "hash-1", ttl, lease, now)
+					errs <- err
+				}()
+			}
+			wg.Wait()
+			close(errs)
+
+			claimed, busy := 0, 0
+			for err := range errs {
+				switch err {
+				case nil:
+					claimed++
+				case idempotency.ErrInProgress:
+					busy++
+				default:
+					t.Fatalf("\t%s\tShould be able to reserve the key : %s.", tests.Failed, err)
+				}
+			}
+			if claimed != 1 || busy != n-1 {
+				t.Fatalf("\t%s\tShould let one request claim the key : got %d claimed, %d in progress.", tests.Failed, claimed, busy)
+			}
+			t.Logf("\t%s\tShould let one request claim the key.", tests.Success)
+		}
+	}
+}
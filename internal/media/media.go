@@ -0,0 +1,156 @@
+// Package media stores the images uploaded for the restaurants and their
+// menus along with a thumbnail of each.
+package media
+
+import (
+	"bytes"
+	"context"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+)
+
+// ThumbnailSize is the size of the longest side of the thumbnails.
+const ThumbnailSize = 320
+
+// Predefined errors identify expected failure conditions.
+var (
+	// ErrNotFound is used when a stored image is requested but does not
+	// exist.
+	ErrNotFound = errors.New("Image not found")
+
+	// ErrTooLarge is used when an image is larger than allowed.
+	ErrTooLarge = errors.New("Image is too large")
+
+	// ErrUnsupportedType is used when an upload is not a JPEG, PNG or GIF
+	// image or cannot be decoded.
+	ErrUnsupportedType = errors.New("Image must be a JPEG, PNG or GIF")
+)
+
+// extensions are the file extensions of the supported image types.
+var extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// Image is an uploaded image and its thumbnail.
+type Image struct {
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnail_url"`
+}
+
+// Uploader checks the uploaded images and writes them to the storage.
+type Uploader struct {
+	storage Storage
+	maxSize int64
+}
+
+// NewUploader constructs an Uploader accepting images up to maxSize bytes.
+func NewUploader(storage Storage, maxSize int64) *Uploader {
+	return &Uploader{storage: storage, maxSize: maxSize}
+}
+
+// MaxSize returns the size of the largest image accepted.
+func (u *Uploader) MaxSize() int64 {
+	return u.maxSize
+}
+
+// Storage returns where the images are written to.
+func (u *Uploader) Storage() Storage {
+	return u.storage
+}
+
+// Upload stores the image under the prefix along with its thumbnail. The
+// type is told by the content, not by what the client claims.
+func (u *Uploader) Upload(ctx context.Context, prefix string, data []byte) (*Image, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.media.Upload")
+	defer span.End()
+
+	if int64(len(data)) > u.maxSize {
+		return nil, ErrTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	ext, ok := extensions[contentType]
+	if !ok {
+		return nil, ErrUnsupportedType
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedType
+	}
+
+	// The thumbnails of GIF images are PNG images showing their first frame.
+	thumbType, thumbExt := contentType, ext
+	if contentType == "image/gif" {
+		thumbType, thumbExt = "image/png", ".png"
+	}
+	thumb, err := encode(Thumbnail(img, ThumbnailSize), thumbType)
+	if err != nil {
+		return nil, err
+	}
+
+	id := uuid.New().String()
+	key := prefix + "/" + id + ext
+	thumbKey := prefix + "/" + id + "_thumb" + thumbExt
+
+	if err := u.storage.Put(ctx, key, contentType, data); err != nil {
+		return nil, err
+	}
+	if err := u.storage.Put(ctx, thumbKey, thumbType, thumb); err != nil {
+		return nil, err
+	}
+
+	return &Image{URL: u.storage.URL(key), ThumbnailURL: u.storage.URL(thumbKey)}, nil
+}
+
+// Load reads and decodes the stored image at the URL. Images which are not
+// in the storage are not found.
+func (u *Uploader) Load(ctx context.Context, url string) (image.Image, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.media.Load")
+	defer span.End()
+
+	key := strings.TrimPrefix(url, u.storage.URL(""))
+	if key == url || key == "" {
+		return nil, ErrNotFound
+	}
+
+	data, err := u.storage.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.Wrapf(err, "decoding %s", key)
+	}
+
+	return img, nil
+}
+
+// encode encodes the image as the content type.
+func encode(img image.Image, contentType string) ([]byte, error) {
+	var buf bytes.Buffer
+
+	var err error
+	switch contentType {
+	case "image/png":
+		err = png.Encode(&buf, img)
+	default:
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85})
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "encoding thumbnail")
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,105 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// memStorage keeps the images in memory.
+type memStorage map[string][]byte
+
+// Put implements the Storage interface.
+func (s memStorage) Put(ctx context.Context, key, contentType string, data []byte) error {
+	s[key] = data
+	return nil
+}
+
+// Get implements the Storage interface.
+func (s memStorage) Get(ctx context.Context, key string) ([]byte, error) {
+	data, ok := s[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return data, nil
+}
+
+// URL implements the Storage interface.
+func (s memStorage) URL(key string) string {
+	return "http://media.test/" + key
+}
+
+// TestUpload validates images are stored with their thumbnail and other
+// uploads are rejected.
+func TestUpload(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 800, 400))); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Log("Given the need to store uploaded images.")
+	{
+		t.Log("\tTest 0:\tWhen uploading a PNG image.")
+		{
+			storage := memStorage{}
+			img, err := NewUploader(storage, 1<<20).Upload(context.Background(), "restaurants/1", buf.Bytes())
+			if err != nil {
+				t.Fatalf("\t%s\tShould store the image : %s.", tests.Failed, err)
+			}
+			if !strings.HasSuffix(img.URL, ".png") || !strings.HasSuffix(img.ThumbnailURL, "_thumb.png") {
+				t.Fatalf("\t%s\tShould return the URLs of the image and its thumbnail : got %+v.", tests.Failed, img)
+			}
+
+			thumb, err := png.Decode(bytes.NewReader(storage[strings.TrimPrefix(img.ThumbnailURL, "http://media.test/")]))
+			if err != nil {
+				t.Fatalf("\t%s\tShould store the thumbnail : %s.", tests.Failed, err)
+			}
+			if b := thumb.Bounds(); b.Dx() != ThumbnailSize || b.Dy() != ThumbnailSize/2 {
+				t.Fatalf("\t%s\tShould scale the thumbnail down : got %v.", tests.Failed, b)
+			}
+			tests.LogSuccess(t, "Should store the image and its thumbnail.")
+		}
+
+		t.Log("\tTest 1:\tWhen uploading an image larger than allowed.")
+		{
+			if _, err := NewUploader(memStorage{}, 10).Upload(context.Background(), "p", buf.Bytes()); err != ErrTooLarge {
+				t.Fatalf("\t%s\tShould reject the image : got %v.", tests.Failed, err)
+			}
+			tests.LogSuccess(t, "Should reject the image.")
+		}
+
+		t.Log("\tTest 2:\tWhen uploading something other than an image.")
+		{
+			if _, err := NewUploader(memStorage{}, 1<<20).Upload(context.Background(), "p", []byte("<html></html>")); err != ErrUnsupportedType {
+				t.Fatalf("\t%s\tShould reject the upload : got %v.", tests.Failed, err)
+			}
+			tests.LogSuccess(t, "Should reject the upload.")
+		}
+	}
+}
+
+// TestDiskPath validates the keys of the disk storage cannot leave its
+// directory.
+func TestDiskPath(t *testing.T) {
+	d := NewDisk("/var/media", "/v1/media")
+
+	t.Log("Given the need to keep the images in their directory.")
+	{
+		for i, key := range []string{"../etc/passwd", "a/../../b", "/a/b", ""} {
+			t.Logf("\tTest %d:\tWhen using the key %q.", i, key)
+			if _, err := d.path(key); err != ErrNotFound {
+				t.Fatalf("\t%s\tShould not find it : got %v.", tests.Failed, err)
+			}
+			tests.LogSuccess(t, "Should not find it.")
+		}
+
+		if name, err := d.path("restaurants/1/a.png"); err != nil || name != "/var/media/restaurants/1/a.png" {
+			t.Fatalf("\t%s\tShould find a valid key : got %q, %v.", tests.Failed, name, err)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package media
+
+import (
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/platform/s3"
+)
+
+// Storage is where the uploaded images are written to. URL returns the
+// address clients download an image from.
+type Storage interface {
+	Put(ctx context.Context, key, contentType string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	URL(key string) string
+}
+
+// Disk stores the images in a directory of the local disk. They are served
+// by the API under the base URL.
+type Disk struct {
+	dir     string
+	baseURL string
+}
+
+// NewDisk constructs a Storage writing to the directory.
+func NewDisk(dir, baseURL string) *Disk {
+	return &Disk{dir: dir, baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+// Put implements the Storage interface.
+func (d *Disk) Put(ctx context.Context, key, contentType string, data []byte) error {
+	name, err := d.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return errors.Wrapf(err, "creating directory of %s", key)
+	}
+	if err := os.WriteFile(name, data, 0644); err != nil {
+		return errors.Wrapf(err, "writing %s", key)
+	}
+
+	return nil
+}
+
+// Get implements the Storage interface.
+func (d *Disk) Get(ctx context.Context, key string) ([]byte, error) {
+	name, err := d.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, errors.Wrapf(err, "reading %s", key)
+	}
+
+	return data, nil
+}
+
+// URL implements the Storage interface.
+func (d *Disk) URL(key string) string {
+	return d.baseURL + "/" + key
+}
+
+// Open opens the image stored with the key.
+func (d *Disk) Open(key string) (*os.File, error) {
+	name, err := d.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, errors.Wrapf(err, "opening %s", key)
+	}
+
+	return f, nil
+}
+
+// path returns the file of the key. Keys leaving the directory are not
+// found.
+func (d *Disk) path(key string) (string, error) {
+	clean := path.Clean("/" + key)
+	if clean == "/" || clean[1:] != key {
+		return "", ErrNotFound
+	}
+	return filepath.Join(d.dir, filepath.FromSlash(clean)), nil
+}
+
+// S3 stores the images in a bucket of any S3 compatible object storage. The
+// bucket must allow public reads of the images.
+type S3 struct {
+	bucket  *s3.Bucket
+	baseURL string
+}
+
+// NewS3 constructs a Storage writing to the bucket. The base URL is the
+// public address of the bucket, its address on the endpoint when blank.
+func NewS3(bucket *s3.Bucket, baseURL string) *S3 {
+	if baseURL == "" {
+		baseURL = bucket.URL()
+	}
+
+	return &S3{bucket: bucket, baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+// Put implements the Storage interface.
+func (s *S3) Put(ctx context.Context, key, contentType string, data []byte) error {
+	return s.bucket.Put(ctx, key, contentType, data)
+}
+
+// Get implements the Storage interface.
+func (s *S3) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := s.bucket.Get(ctx, key)
+	if err == s3.ErrNotFound {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+// URL implements the Storage interface.
+func (s *S3) URL(key string) string {
+	return s.baseURL + "/" + key
+}
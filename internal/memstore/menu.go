@@ -0,0 +1,167 @@
+package memstore
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/remisb/restaurant/internal/authz"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/restaurant"
+)
+
+// Menus is an in-memory restaurant.MenuStore. It checks who owns the menus
+// with the restaurants store.
+type Menus struct {
+	Errs map[string]error
+
+	mu          sync.Mutex
+	restaurants restaurant.Store
+	data        map[string]restaurant.Menu
+}
+
+// NewMenus constructs a Menus store of the restaurants holding the provided
+// menus.
+func NewMenus(restaurants restaurant.Store, ms ...restaurant.Menu) *Menus {
+	s := Menus{
+		Errs:        make(map[string]error),
+		restaurants: restaurants,
+		data:        make(map[string]restaurant.Menu),
+	}
+	for _, m := range ms {
+		s.data[m.ID] = m
+	}
+	return &s
+}
+
+// CreateMenu implements the restaurant.MenuStore interface.
+func (s *Menus) CreateMenu(ctx context.Context, user auth.Claims, nm restaurant.NewMenu, now time.Time) (*restaurant.Menu, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.Errs["CreateMenu"]; err != nil {
+		return nil, err
+	}
+
+	// Organizations have no time zone here so a menu without a date is for
+	// today in UTC.
+	now = now.UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	date, err := restaurant.ParseMenuDate(nm.Date, today)
+	if err != nil {
+		return nil, err
+	}
+	if err := restaurant.CheckMenuDate(date, today); err != nil {
+		return nil, err
+	}
+
+	m := restaurant.Menu{
+		ID:           uuid.New().String(),
+		RestaurantID: nm.RestaurantID,
+		Date:         date,
+		Menu:         nm.Menu,
+		Items:        nm.Items,
+		Version:      1,
+	}
+	if m.Items == nil {
+		m.Items = restaurant.MenuItems{}
+	}
+	s.data[m.ID] = m
+
+	return &m, nil
+}
+
+// RetrieveMenu implements the restaurant.MenuStore interface.
+func (s *Menus) RetrieveMenu(ctx context.Context, id string) (*restaurant.Menu, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.Errs["RetrieveMenu"]; err != nil {
+		return nil, err
+	}
+
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, restaurant.ErrInvalidID
+	}
+
+	m, ok := s.data[id]
+	if !ok {
+		return nil, restaurant.ErrNotFound
+	}
+	return &m, nil
+}
+
+// ListMenus implements the restaurant.MenuStore interface.
+func (s *Menus) ListMenus(ctx context.Context, restaurantID string, from time.Time) ([]restaurant.Menu, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.Errs["ListMenus"]; err != nil {
+		return nil, err
+	}
+
+	if _, err := uuid.Parse(restaurantID); err != nil {
+		return nil, restaurant.ErrInvalidID
+	}
+
+	menus := []restaurant.Menu{}
+	for _, m := range s.data {
+		if m.RestaurantID == restaurantID && !m.Date.Before(from) {
+			menus = append(menus, m)
+		}
+	}
+	sort.Slice(menus, func(i, j int) bool { return menus[i].Date.Before(menus[j].Date) })
+
+	return menus, nil
+}
+
+// UpdateMenu implements the restaurant.MenuStore interface.
+func (s *Menus) UpdateMenu(ctx context.Context, user auth.Claims, restaurantID string, update restaurant.UpdateMenu, now time.Time) (*restaurant.Menu, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.Errs["UpdateMenu"]; err != nil {
+		return nil, err
+	}
+
+	r, err := s.restaurants.Retrieve(ctx, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	if !authz.Allowed(user, authz.Update, authz.Resource{Kind: authz.Menu, Owner: r.OwnerUserID}) {
+		return nil, restaurant.ErrForbidden
+	}
+
+	if _, err := uuid.Parse(update.ID); err != nil {
+		return nil, restaurant.ErrInvalidID
+	}
+
+	m, ok := s.data[update.ID]
+	if !ok || m.RestaurantID != restaurantID {
+		return nil, restaurant.ErrNotFound
+	}
+
+	if update.Version != nil && *update.Version != m.Version {
+		return nil, restaurant.ErrVersionConflict
+	}
+
+	if update.Menu != "" {
+		m.Menu = update.Menu
+	}
+	if update.Date != "" {
+		date, err := restaurant.ParseMenuDate(update.Date, m.Date)
+		if err != nil {
+			return nil, err
+		}
+		m.Date = date
+	}
+	if update.Items != nil {
+		m.Items = update.Items
+	}
+	m.Version++
+	s.data[m.ID] = m
+
+	return &m, nil
+}
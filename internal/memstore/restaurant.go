@@ -0,0 +1,391 @@
+// Package memstore provides in-memory implementations of the store
+// interfaces so handlers can be unit tested in milliseconds without a
+// database. The stores behave like the database ones, returning the same
+// errors, and every store returns the error scripted in Errs for a method
+// name before doing any work.
+package memstore
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/remisb/restaurant/internal/authz"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/geo"
+	"github.com/remisb/restaurant/internal/restaurant"
+)
+
+// Restaurants is an in-memory restaurant.Store.
+type Restaurants struct {
+	Errs map[string]error
+
+	mu        sync.Mutex
+	data      map[string]restaurant.Restaurant
+	favorites map[string]map[string]time.Time
+	included  map[string]restaurant.Included
+}
+
+// NewRestaurants constructs a Restaurants store holding the provided
+// restaurants.
+func NewRestaurants(rs ...restaurant.Restaurant) *Restaurants {
+	s := Restaurants{
+		Errs:      make(map[string]error),
+		data:      make(map[string]restaurant.Restaurant),
+		favorites: make(map[string]map[string]time.Time),
+		included:  make(map[string]restaurant.Included),
+	}
+	for _, r := range rs {
+		s.data[r.ID] = r
+	}
+	return &s
+}
+
+// List implements the restaurant.Store interface.
+func (s *Restaurants) List(ctx context.Context) ([]restaurant.Restaurant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.Errs["List"]; err != nil {
+		return nil, err
+	}
+
+	rs := make([]restaurant.Restaurant, 0, len(s.data))
+	for _, r := range s.data {
+		if r.DateDeleted == nil && visible(ctx, r) {
+			rs = append(rs, r)
+		}
+	}
+	sort.Slice(rs, func(i, j int) bool { return rs[i].DateCreated.Before(rs[j].DateCreated) })
+	return rs, nil
+}
+
+// ListNearby implements the restaurant.Store interface.
+func (s *Restaurants) ListNearby(ctx context.Context, near geo.Point, radius float64) ([]restaurant.Nearby, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.Errs["ListNearby"]; err != nil {
+		return nil, err
+	}
+
+	rs := []restaurant.Nearby{}
+	for _, r := range s.data {
+		p, ok := r.Location()
+		if !ok || r.DateDeleted != nil || !visible(ctx, r) {
+			continue
+		}
+		if d := geo.Distance(near, p); d <= radius {
+			rs = append(rs, restaurant.Nearby{Restaurant: r, Distance: d})
+		}
+	}
+	sort.Slice(rs, func(i, j int) bool { return rs[i].Distance < rs[j].Distance })
+	return rs, nil
+}
+
+// FindSimilar implements the restaurant.Store interface.
+func (s *Restaurants) FindSimilar(ctx context.Context, name, address string) ([]restaurant.Match, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.Errs["FindSimilar"]; err != nil {
+		return nil, err
+	}
+
+	rs := []restaurant.Restaurant{}
+	for _, r := range s.data {
+		if r.DateDeleted == nil && visible(ctx, r) {
+			rs = append(rs, r)
+		}
+	}
+	return restaurant.Duplicates(rs, name, address), nil
+}
+
+// Create implements the restaurant.Store interface.
+func (s *Restaurants) Create(ctx context.Context, user auth.Claims, nr restaurant.NewRestaurant, now time.Time) (*restaurant.Restaurant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.Errs["Create"]; err != nil {
+		return nil, err
+	}
+
+	loc, err := nr.Location()
+	if err != nil {
+		return nil, err
+	}
+
+	if s.nameTaken(user.Subject, nr.Name, "") {
+		return nil, restaurant.ErrDuplicateName
+	}
+
+	r := restaurant.Restaurant{
+		ID:          uuid.New().String(),
+		Name:        nr.Name,
+		Address:     nr.Address,
+		OwnerUserID: user.Subject,
+		OrgID:       user.Org(),
+		Photos:      pq.StringArray{},
+		Thumbnails:  pq.StringArray{},
+		Tags:        pq.StringArray{},
+		Version:     1,
+		DateCreated: now.UTC(),
+		DateUpdated: now.UTC(),
+	}
+	if loc != nil {
+		r.Latitude, r.Longitude = &loc.Lat, &loc.Lng
+	}
+	s.data[r.ID] = r
+
+	return &r, nil
+}
+
+// Retrieve implements the restaurant.Store interface.
+func (s *Restaurants) Retrieve(ctx context.Context, id string) (*restaurant.Restaurant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.Errs["Retrieve"]; err != nil {
+		return nil, err
+	}
+
+	r, err := s.retrieve(id)
+	if err != nil {
+		return nil, err
+	}
+	if !visible(ctx, r) {
+		return nil, restaurant.ErrNotFound
+	}
+	return &r, nil
+}
+
+// Update implements the restaurant.Store interface.
+func (s *Restaurants) Update(ctx context.Context, user auth.Claims, id string, update restaurant.UpdateRestaurant, now time.Time) (*restaurant.Restaurant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.Errs["Update"]; err != nil {
+		return nil, err
+	}
+
+	loc, err := update.Location()
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := s.retrieve(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !authz.Allowed(user, authz.Update, authz.Resource{Kind: authz.Restaurant, Owner: r.OwnerUserID}) {
+		return nil, restaurant.ErrForbidden
+	}
+
+	if update.Version != nil && *update.Version != r.Version {
+		return nil, restaurant.ErrVersionConflict
+	}
+
+	if update.Name != nil {
+		if s.nameTaken(r.OwnerUserID, *update.Name, r.ID) {
+			return nil, restaurant.ErrDuplicateName
+		}
+		r.Name = *update.Name
+	}
+	if update.Address != nil {
+		r.Address = *update.Address
+	}
+	if update.Website != nil {
+		r.Website = *update.Website
+	}
+	if update.Phone != nil {
+		r.Phone = *update.Phone
+	}
+	if update.Photos != nil {
+		r.SetPhotos(update.Photos)
+	}
+	if update.Public != nil {
+		r.Public = *update.Public
+	}
+	if update.AnonymousVotes != nil {
+		r.AnonymousVotes = *update.AnonymousVotes
+	}
+	if loc != nil {
+		r.Latitude, r.Longitude = &loc.Lat, &loc.Lng
+	}
+	r.Version++
+	r.DateUpdated = now
+	s.data[id] = r
+
+	return &r, nil
+}
+
+// Delete implements the restaurant.Store interface.
+func (s *Restaurants) Delete(ctx context.Context, id string, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.Errs["Delete"]; err != nil {
+		return err
+	}
+
+	if _, err := uuid.Parse(id); err != nil {
+		return restaurant.ErrInvalidID
+	}
+
+	// Like in the database the restaurant is only marked as deleted.
+	r, ok := s.data[id]
+	if !ok || r.DateDeleted != nil {
+		return restaurant.ErrNotFound
+	}
+	deleted := now.UTC()
+	r.DateDeleted = &deleted
+	r.Version++
+	s.data[id] = r
+	return nil
+}
+
+// AddFavorite implements the restaurant.Store interface.
+func (s *Restaurants) AddFavorite(ctx context.Context, userID, id string, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.Errs["AddFavorite"]; err != nil {
+		return err
+	}
+
+	if _, err := s.retrieve(id); err != nil {
+		return err
+	}
+
+	if s.favorites[userID] == nil {
+		s.favorites[userID] = make(map[string]time.Time)
+	}
+	if _, ok := s.favorites[userID][id]; !ok {
+		s.favorites[userID][id] = now.UTC()
+	}
+	return nil
+}
+
+// RemoveFavorite implements the restaurant.Store interface.
+func (s *Restaurants) RemoveFavorite(ctx context.Context, userID, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.Errs["RemoveFavorite"]; err != nil {
+		return err
+	}
+
+	if _, err := uuid.Parse(id); err != nil {
+		return restaurant.ErrInvalidID
+	}
+
+	delete(s.favorites[userID], id)
+	return nil
+}
+
+// ListFavorites implements the restaurant.Store interface.
+func (s *Restaurants) ListFavorites(ctx context.Context, userID string) ([]restaurant.Restaurant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.Errs["ListFavorites"]; err != nil {
+		return nil, err
+	}
+
+	favorites := s.favorites[userID]
+	rs := []restaurant.Restaurant{}
+	for id := range favorites {
+		if r, ok := s.data[id]; ok && r.DateDeleted == nil {
+			rs = append(rs, r)
+		}
+	}
+	sort.Slice(rs, func(i, j int) bool { return favorites[rs[i].ID].After(favorites[rs[j].ID]) })
+	return rs, nil
+}
+
+// SetIncluded sets the related data embedded in the restaurant, which the
+// store does not keep otherwise.
+func (s *Restaurants) SetIncluded(id string, inc restaurant.Included) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.included[id] = inc
+}
+
+// ListIncluded implements the restaurant.Store interface. The related data
+// is the one set with SetIncluded, with no votes by default.
+func (s *Restaurants) ListIncluded(ctx context.Context, ids []string, in restaurant.Include, now time.Time) (map[string]restaurant.Included, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.Errs["ListIncluded"]; err != nil {
+		return nil, err
+	}
+
+	included := make(map[string]restaurant.Included, len(ids))
+	if !in.Any() {
+		return included, nil
+	}
+	for _, id := range ids {
+		set := s.included[id]
+		var inc restaurant.Included
+		if in.Menu {
+			inc.Menu = set.Menu
+		}
+		if in.Votes {
+			votes := 0
+			if set.Votes != nil {
+				votes = *set.Votes
+			}
+			inc.Votes = &votes
+		}
+		if in.Rating {
+			inc.Rating = set.Rating
+		}
+		included[id] = inc
+	}
+	return included, nil
+}
+
+// visible reports whether the restaurant belongs to the organization of the
+// claims in ctx, mirroring the scope of the database queries. Restaurants
+// without an organization belong to the default one.
+func visible(ctx context.Context, r restaurant.Restaurant) bool {
+	org := auth.Org(ctx)
+	if org == "" {
+		return true
+	}
+	if r.OrgID == "" {
+		return org == auth.DefaultOrg
+	}
+	return org == r.OrgID
+}
+
+// nameTaken reports whether another restaurant of the owner than the one of
+// the ID has the name, mirroring the unique index of the database.
+func (s *Restaurants) nameTaken(owner, name, id string) bool {
+	for _, r := range s.data {
+		if r.ID != id && r.DateDeleted == nil && r.OwnerUserID == owner && strings.EqualFold(r.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// retrieve mirrors the checks of restaurant.Retrieve.
+func (s *Restaurants) retrieve(id string) (restaurant.Restaurant, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return restaurant.Restaurant{}, restaurant.ErrInvalidID
+	}
+
+	r, ok := s.data[id]
+	if !ok || r.DateDeleted != nil {
+		return restaurant.Restaurant{}, restaurant.ErrNotFound
+	}
+	return r, nil
+}
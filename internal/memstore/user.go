@@ -0,0 +1,183 @@
+package memstore
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/remisb/restaurant/internal/authz"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/user"
+)
+
+// Users is an in-memory user.Store. Passwords are kept in the clear by user
+// ID, hashing them would make the tests slow.
+type Users struct {
+	Errs map[string]error
+
+	mu        sync.Mutex
+	data      map[string]user.User
+	passwords map[string]string
+}
+
+// NewUsers constructs an empty Users store.
+func NewUsers() *Users {
+	return &Users{
+		Errs:      make(map[string]error),
+		data:      make(map[string]user.User),
+		passwords: make(map[string]string),
+	}
+}
+
+// List implements the user.Store interface.
+func (s *Users) List(ctx context.Context) ([]user.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.Errs["List"]; err != nil {
+		return nil, err
+	}
+
+	us := make([]user.User, 0, len(s.data))
+	for _, u := range s.data {
+		if u.DateDeleted == nil {
+			us = append(us, u)
+		}
+	}
+	return us, nil
+}
+
+// Retrieve implements the user.Store interface.
+func (s *Users) Retrieve(ctx context.Context, claims auth.Claims, id string) (*user.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.Errs["Retrieve"]; err != nil {
+		return nil, err
+	}
+
+	u, err := s.retrieve(claims, id)
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// Create implements the user.Store interface.
+func (s *Users) Create(ctx context.Context, n user.NewUser, now time.Time) (*user.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.Errs["Create"]; err != nil {
+		return nil, err
+	}
+
+	u := user.User{
+		ID:          uuid.New().String(),
+		Name:        n.Name,
+		Email:       n.Email,
+		Roles:       n.Roles,
+		Version:     1,
+		DateCreated: now.UTC(),
+		DateUpdated: now.UTC(),
+	}
+	s.data[u.ID] = u
+	s.passwords[u.ID] = n.Password
+
+	return &u, nil
+}
+
+// Update implements the user.Store interface.
+func (s *Users) Update(ctx context.Context, claims auth.Claims, id string, upd user.UpdateUser, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.Errs["Update"]; err != nil {
+		return err
+	}
+
+	u, err := s.retrieve(claims, id)
+	if err != nil {
+		return err
+	}
+
+	if upd.Version != nil && *upd.Version != u.Version {
+		return user.ErrVersionConflict
+	}
+
+	if upd.Name != nil {
+		u.Name = *upd.Name
+	}
+	if upd.Email != nil {
+		u.Email = *upd.Email
+	}
+	if upd.Roles != nil {
+		u.Roles = upd.Roles
+	}
+	if upd.Password != nil {
+		s.passwords[u.ID] = *upd.Password
+	}
+	u.Version++
+	u.DateUpdated = now
+	s.data[id] = u
+
+	return nil
+}
+
+// Delete implements the user.Store interface.
+func (s *Users) Delete(ctx context.Context, id string, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.Errs["Delete"]; err != nil {
+		return err
+	}
+
+	if _, err := uuid.Parse(id); err != nil {
+		return user.ErrInvalidID
+	}
+
+	// Like in the database the user is only marked as deleted.
+	if u, ok := s.data[id]; ok && u.DateDeleted == nil {
+		deleted := now.UTC()
+		u.DateDeleted = &deleted
+		u.Version++
+		s.data[id] = u
+	}
+	return nil
+}
+
+// Authenticate implements the user.Store interface.
+func (s *Users) Authenticate(ctx context.Context, now time.Time, email, password string) (auth.Claims, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.Errs["Authenticate"]; err != nil {
+		return auth.Claims{}, err
+	}
+
+	for _, u := range s.data {
+		if u.Email == email && u.DateDeleted == nil && s.passwords[u.ID] == password {
+			return auth.NewClaims(u.ID, u.Roles, now, time.Hour), nil
+		}
+	}
+	return auth.Claims{}, user.ErrAuthenticationFailure
+}
+
+// retrieve mirrors the checks of user.Retrieve.
+func (s *Users) retrieve(claims auth.Claims, id string) (user.User, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return user.User{}, user.ErrInvalidID
+	}
+
+	if !authz.Allowed(claims, authz.Read, authz.Resource{Kind: authz.User, Owner: id}) {
+		return user.User{}, user.ErrForbidden
+	}
+
+	u, ok := s.data[id]
+	if !ok || u.DateDeleted != nil {
+		return user.User{}, user.ErrNotFound
+	}
+	return u, nil
+}
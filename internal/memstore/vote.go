@@ -0,0 +1,373 @@
+package memstore
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/restaurant"
+	"github.com/remisb/restaurant/internal/team"
+	"github.com/remisb/restaurant/internal/vote"
+)
+
+// Votes is an in-memory vote.Store. Votes are checked against the policy and
+// the restaurants store like in the database. Winners are not computed, they
+// are set with SetWinner and SetTeamWinner. Teams are set with SetTeam, the
+// weights of the users with SetWeight and delegations with SetDelegation.
+// The votes of the restaurants flagged so are anonymous, like those of every
+// restaurant once SetAnonymous is called.
+type Votes struct {
+	Errs map[string]error
+
+	mu          sync.Mutex
+	restaurants restaurant.Store
+	votes       []vote.Vote
+	winners     map[string]vote.Winner
+	teams       map[string][]string
+	teamWinners map[string]vote.Winner
+	weights     map[string]int
+	delegations map[string]string
+	anonymous   bool
+}
+
+// NewVotes constructs an empty Votes store for the restaurants.
+func NewVotes(restaurants restaurant.Store) *Votes {
+	return &Votes{
+		Errs:        make(map[string]error),
+		restaurants: restaurants,
+		winners:     make(map[string]vote.Winner),
+		teams:       make(map[string][]string),
+		teamWinners: make(map[string]vote.Winner),
+		weights:     make(map[string]int),
+		delegations: make(map[string]string),
+	}
+}
+
+// SetWeight allows the user to cast up to max votes at once.
+func (s *Votes) SetWeight(userID string, max int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.weights[userID] = max
+}
+
+// SetDelegation authorizes the delegate to vote on behalf of the user.
+func (s *Votes) SetDelegation(userID, delegateID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.delegations[userID] = delegateID
+}
+
+// SetAnonymous makes the votes of the organization anonymous.
+func (s *Votes) SetAnonymous(anonymous bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.anonymous = anonymous
+}
+
+// SetTeam stores the team with its members.
+func (s *Votes) SetTeam(teamID string, userIDs ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.teams[teamID] = userIDs
+}
+
+// SetTeamWinner stores the winner of its team and date.
+func (s *Votes) SetTeamWinner(w vote.Winner) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.teamWinners[w.TeamID+"/"+w.Date.Format("2006-01-02")] = w
+}
+
+// SetWinner stores the winner of its date.
+func (s *Votes) SetWinner(w vote.Winner) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.winners[w.Date.Format("2006-01-02")] = w
+}
+
+// Cast implements the vote.Store interface.
+func (s *Votes) Cast(ctx context.Context, user auth.Claims, nv vote.NewVote, policy vote.Policy, now time.Time) (*vote.Vote, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.Errs["Cast"]; err != nil {
+		return nil, err
+	}
+
+	date, err := vote.ParseDate(nv.Date, policy.Today(now))
+	if err != nil {
+		return nil, err
+	}
+
+	v := vote.Vote{
+		Date:         date,
+		UserID:       user.Subject,
+		RestaurantID: nv.RestaurantID,
+		Weight:       nv.Count,
+		TimeVoted:    now.UTC(),
+	}
+	if v.Weight == 0 {
+		v.Weight = 1
+	}
+	if nv.OnBehalfOf != "" && nv.OnBehalfOf != user.Subject {
+		v.UserID, v.CastBy = nv.OnBehalfOf, user.Subject
+	}
+
+	if err := policy.Check(date, now); err != nil {
+		if err == vote.ErrClosed && s.find(date, v.UserID) >= 0 {
+			return nil, vote.ErrLocked
+		}
+		return nil, err
+	}
+
+	limit := 1
+	if v.CastBy != "" {
+		if s.delegations[v.UserID] != v.CastBy {
+			return nil, vote.ErrNotDelegated
+		}
+	} else if max, ok := s.weights[user.Subject]; ok {
+		limit = max
+	}
+	if v.Weight > limit {
+		return nil, vote.ErrWeightExceeded
+	}
+
+	if _, err := s.restaurants.Retrieve(ctx, nv.RestaurantID); err != nil {
+		return nil, err
+	}
+
+	// A user has a single vote per date which is replaced.
+	if i := s.find(date, v.UserID); i >= 0 {
+		s.votes = append(s.votes[:i], s.votes[i+1:]...)
+	}
+	s.votes = append(s.votes, v)
+
+	return &v, nil
+}
+
+// Retract implements the vote.Store interface.
+func (s *Votes) Retract(ctx context.Context, user auth.Claims, date time.Time, policy vote.Policy, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.Errs["Retract"]; err != nil {
+		return err
+	}
+
+	if err := policy.Check(date, now); err == vote.ErrClosed {
+		return vote.ErrLocked
+	}
+
+	i := s.find(date, user.Subject)
+	if i < 0 {
+		return vote.ErrNotVoted
+	}
+	s.votes = append(s.votes[:i], s.votes[i+1:]...)
+
+	return nil
+}
+
+// find returns the index of the user's vote for the date, -1 when they have
+// not voted.
+func (s *Votes) find(date time.Time, userID string) int {
+	for i, v := range s.votes {
+		if v.Date.Equal(date) && v.UserID == userID {
+			return i
+		}
+	}
+	return -1
+}
+
+// Tallies implements the vote.Store interface.
+func (s *Votes) Tallies(ctx context.Context, date time.Time) ([]vote.Tally, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.Errs["Tallies"]; err != nil {
+		return nil, err
+	}
+
+	return s.tallies(date, nil), nil
+}
+
+// tallies counts the votes of the date, only the votes of the users when
+// they are not nil.
+func (s *Votes) tallies(date time.Time, users map[string]bool) []vote.Tally {
+	// The votes are kept in the order they were cast so the first vote of a
+	// restaurant is seen first, which breaks ties like in the database.
+	counts := make(map[string]int)
+	var order []string
+	for _, v := range s.votes {
+		if !v.Date.Equal(date) || (users != nil && !users[v.UserID]) {
+			continue
+		}
+		if _, ok := counts[v.RestaurantID]; !ok {
+			order = append(order, v.RestaurantID)
+		}
+		counts[v.RestaurantID] += v.Weight
+	}
+
+	tallies := []vote.Tally{}
+	for _, id := range order {
+		tallies = append(tallies, vote.Tally{RestaurantID: id, Votes: counts[id]})
+	}
+	sort.SliceStable(tallies, func(i, j int) bool { return tallies[i].Votes > tallies[j].Votes })
+
+	return tallies
+}
+
+// RetrieveTally implements the vote.Store interface.
+func (s *Votes) RetrieveTally(ctx context.Context, restaurantID string, date time.Time) (*vote.Tally, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.Errs["RetrieveTally"]; err != nil {
+		return nil, err
+	}
+
+	t := vote.Tally{RestaurantID: restaurantID}
+	if _, err := s.restaurants.Retrieve(ctx, restaurantID); err != nil {
+		return &t, nil
+	}
+	for _, v := range s.votes {
+		if v.Date.Equal(date) && v.RestaurantID == restaurantID {
+			t.Votes += v.Weight
+		}
+	}
+	return &t, nil
+}
+
+// Voters implements the vote.Store interface.
+func (s *Votes) Voters(ctx context.Context, restaurantID string, date time.Time) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.Errs["Voters"]; err != nil {
+		return nil, err
+	}
+	if s.hidden(ctx, restaurantID) {
+		return nil, vote.ErrAnonymous
+	}
+
+	voters := []string{}
+	for _, v := range s.votes {
+		if v.Date.Equal(date) && v.RestaurantID == restaurantID {
+			voters = append(voters, v.UserID)
+		}
+	}
+	return voters, nil
+}
+
+// hidden reports whether the votes for the restaurant are anonymous.
+func (s *Votes) hidden(ctx context.Context, restaurantID string) bool {
+	if s.anonymous {
+		return true
+	}
+	r, err := s.restaurants.Retrieve(ctx, restaurantID)
+	return err == nil && r.AnonymousVotes
+}
+
+// RetrieveWinner implements the vote.Store interface.
+func (s *Votes) RetrieveWinner(ctx context.Context, date time.Time) (*vote.Winner, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.Errs["RetrieveWinner"]; err != nil {
+		return nil, err
+	}
+
+	w, ok := s.winners[date.Format("2006-01-02")]
+	if !ok {
+		return nil, vote.ErrNoWinner
+	}
+	return &w, nil
+}
+
+// TeamTallies implements the vote.Store interface.
+func (s *Votes) TeamTallies(ctx context.Context, teamID string, date time.Time) ([]vote.Tally, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.Errs["TeamTallies"]; err != nil {
+		return nil, err
+	}
+
+	members, ok := s.teams[teamID]
+	if !ok {
+		return nil, team.ErrNotFound
+	}
+
+	users := make(map[string]bool, len(members))
+	for _, id := range members {
+		users[id] = true
+	}
+	return s.tallies(date, users), nil
+}
+
+// RetrieveTeamWinner implements the vote.Store interface.
+func (s *Votes) RetrieveTeamWinner(ctx context.Context, teamID string, date time.Time) (*vote.Winner, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.Errs["RetrieveTeamWinner"]; err != nil {
+		return nil, err
+	}
+
+	if _, ok := s.teams[teamID]; !ok {
+		return nil, team.ErrNotFound
+	}
+
+	w, ok := s.teamWinners[teamID+"/"+date.Format("2006-01-02")]
+	if !ok {
+		return nil, vote.ErrNoWinner
+	}
+	return &w, nil
+}
+
+// History implements the vote.Store interface.
+func (s *Votes) History(ctx context.Context, from, to time.Time, fn func(vote.Vote) error) error {
+	s.mu.Lock()
+	votes := append([]vote.Vote(nil), s.votes...)
+	for i, v := range votes {
+		if s.hidden(ctx, v.RestaurantID) {
+			votes[i].UserID, votes[i].CastBy = "", ""
+		}
+	}
+	err := s.Errs["History"]
+	s.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	sort.SliceStable(votes, func(i, j int) bool { return votes[i].Date.Before(votes[j].Date) })
+	for _, v := range votes {
+		if v.Date.Before(from) || v.Date.After(to) {
+			continue
+		}
+		if err := fn(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Today implements the vote.Store interface. Organizations have no settings
+// of their own so the time zone of the policy is used.
+func (s *Votes) Today(ctx context.Context, policy vote.Policy, now time.Time) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.Errs["Today"]; err != nil {
+		return time.Time{}, err
+	}
+	return policy.Today(now), nil
+}
@@ -0,0 +1,33 @@
+package mid
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/remisb/restaurant/internal/platform/web"
+)
+
+// MaxBodySize limits the size of request bodies. Reading past the limit
+// fails and web.Decode turns that failure into a 413 response. Uploads sent
+// as multipart/form-data are left to the limit of their handlers, which
+// read them with web.DecodeFile.
+func MaxBodySize(limit int64) web.Middleware {
+
+	// This is the actual middleware function to be executed.
+	f := func(after web.Handler) web.Handler {
+
+		// Wrap this handler around the next one provided.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+			if limit > 0 && !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+				r.Body = http.MaxBytesReader(w, r.Body, limit)
+			}
+
+			return after(ctx, w, r, params)
+		}
+
+		return h
+	}
+
+	return f
+}
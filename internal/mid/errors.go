@@ -2,10 +2,13 @@ package mid
 
 import (
 	"context"
+	"errors"
+	"github.com/remisb/restaurant/internal/platform/database"
 	"github.com/remisb/restaurant/internal/platform/web"
-	"go.opencensus.io/trace"
+	"go.opentelemetry.io/otel"
 	"log"
 	"net/http"
+	"strings"
 )
 
 // Errors handles errors coming out of the call chain. It detects normal
@@ -18,7 +21,7 @@ func Errors(log *log.Logger) web.Middleware {
 
 		// Create the handler that will be attached in the middleware chain.
 		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
-			ctx, span := trace.StartSpan(ctx, "internal.mid.Errors")
+			ctx, span := otel.Tracer("").Start(ctx, "internal.mid.Errors")
 			defer span.End()
 
 			// If the context is missing this value, request the service
@@ -33,6 +36,19 @@ func Errors(log *log.Logger) web.Middleware {
 				// Log the error.
 				log.Printf("%s : ERROR : %+v", v.TraceID, err)
 
+				// A request running out of time is reported as a timeout
+				// instead of an internal error.
+				if errors.Is(err, context.DeadlineExceeded) {
+					err = web.NewRequestError(context.DeadlineExceeded, http.StatusGatewayTimeout)
+				}
+
+				// A statement violating a constraint of the schema is a
+				// conflict or a reference to nothing rather than an
+				// internal error.
+				if vi, ok := database.AsViolation(err); ok {
+					err = violationError(vi)
+				}
+
 				// Respond to the error.
 				if err := web.RespondError(ctx, w, err); err != nil {
 					return err
@@ -54,3 +70,43 @@ func Errors(log *log.Logger) web.Middleware {
 
 	return f
 }
+
+// These are the errors of the requests violating a constraint of the schema.
+var (
+	ErrAlreadyExists    = errors.New("Resource already exists")
+	ErrInvalidReference = errors.New("Referenced resource does not exist")
+)
+
+// constraintFields are the request fields checked by the constraints whose
+// columns are not named like the field.
+var constraintFields = map[string]string{
+	"restaurant_owner_name_idx": "name",
+	"coupon_code_idx":           "code",
+}
+
+// violationError reports the violation of a unique constraint as a 409 and
+// of a foreign key as a 422, naming the field of the constraint.
+func violationError(vi database.Violation) error {
+	field, ok := constraintFields[vi.Constraint]
+	if !ok {
+		field = strings.Join(vi.Columns, ",")
+	}
+	if field == "" {
+		field = vi.Constraint
+	}
+
+	if vi.Unique {
+		return &web.Error{
+			Err:    ErrAlreadyExists,
+			Status: http.StatusConflict,
+			Code:   web.CodeAlreadyExists,
+			Fields: []web.FieldError{{Field: field, Error: "must be unique"}},
+		}
+	}
+	return &web.Error{
+		Err:    ErrInvalidReference,
+		Status: http.StatusUnprocessableEntity,
+		Code:   web.CodeInvalidReference,
+		Fields: []web.FieldError{{Field: field, Error: "must refer to an existing resource"}},
+	}
+}
@@ -0,0 +1,47 @@
+package mid
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/remisb/restaurant/internal/featureflag"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/web"
+	"go.opentelemetry.io/otel"
+)
+
+// Feature answers 404 Not Found, as if the route did not exist, while the
+// feature of the name is off for the organization of the caller. It must be
+// used after Authenticate.
+func Feature(db *sqlx.DB, name string) web.Middleware {
+
+	// This is the actual middleware function to be executed.
+	f := func(after web.Handler) web.Handler {
+
+		// Wrap this handler around the next one provided.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+			ctx, span := otel.Tracer("").Start(ctx, "internal.mid.Feature")
+			defer span.End()
+
+			claims, ok := ctx.Value(auth.Key).(auth.Claims)
+			if !ok {
+				return web.NewShutdownError("claims missing from context")
+			}
+
+			on, err := featureflag.Enabled(ctx, db, name, claims.Org())
+			if err != nil {
+				return err
+			}
+			if !on {
+				return web.NewRequestError(featureflag.ErrDisabled, http.StatusNotFound)
+			}
+
+			return after(ctx, w, r, params)
+		}
+
+		return h
+	}
+
+	return f
+}
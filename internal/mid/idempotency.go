@@ -0,0 +1,129 @@
+package mid
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/idempotency"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/web"
+	"go.opentelemetry.io/otel"
+)
+
+// Idempotency answers a request carrying an Idempotency-Key header that was
+// already processed with the stored response instead of running the handler
+// again. Keys are scoped to the authenticated user so it must be used after
+// Authenticate. Only responses written by the handler are stored, a request
+// ending in an error or a 5xx response may be retried with the same key. A
+// request holds its key for lease at most, a retry claims it afterwards.
+func Idempotency(db *sqlx.DB, ttl, lease time.Duration) web.Middleware {
+
+	// This is the actual middleware function to be executed.
+	f := func(after web.Handler) web.Handler {
+
+		// Wrap this handler around the next one provided.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+			ctx, span := otel.Tracer("").Start(ctx, "internal.mid.Idempotency")
+			defer span.End()
+
+			key := r.Header.Get("Idempotency-Key")
+			if key == "" {
+				return after(ctx, w, r, params)
+			}
+
+			v, ok := ctx.Value(web.KeyValues).(*web.Values)
+			if !ok {
+				return web.NewShutdownError("web value missing from context")
+			}
+
+			claims, ok := ctx.Value(auth.Key).(auth.Claims)
+			if !ok {
+				return web.NewShutdownError("claims missing from context")
+			}
+
+			// The body identifies the request along with the route so it is
+			// read up front and handed to the handler again.
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				return web.NewRequestError(err, http.StatusBadRequest)
+			}
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+			sum := sha256.New()
+			sum.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+			sum.Write(body)
+			hash := hex.EncodeToString(sum.Sum(nil))
+
+			rec, err := idempotency.Reserve(ctx, db, claims.Subject, key, hash, ttl, lease, v.Now)
+			if err != nil {
+				switch err {
+				case idempotency.ErrInProgress:
+					return web.NewRequestError(err, http.StatusConflict)
+				case idempotency.ErrMismatch:
+					return web.NewRequestError(err, http.StatusUnprocessableEntity)
+				default:
+					return errors.Wrapf(err, "key: %s", key)
+				}
+			}
+
+			// Replay the response of the earlier request.
+			if rec != nil {
+				v.StatusCode = rec.Status
+				if rec.ContentType != "" {
+					w.Header().Set("Content-Type", rec.ContentType)
+				}
+				w.Header().Set("Idempotent-Replayed", "true")
+				w.WriteHeader(rec.Status)
+				_, err := w.Write(rec.Body)
+				return err
+			}
+
+			rw := responseRecorder{ResponseWriter: w}
+			if err := after(ctx, &rw, r, params); err != nil {
+				if rerr := idempotency.Release(ctx, db, claims.Subject, key, v.Now); rerr != nil {
+					return errors.Wrapf(rerr, "handling %v", err)
+				}
+				return err
+			}
+
+			if rw.status >= http.StatusInternalServerError {
+				return idempotency.Release(ctx, db, claims.Subject, key, v.Now)
+			}
+
+			return idempotency.Complete(ctx, db, claims.Subject, key, v.Now, rw.status, w.Header().Get("Content-Type"), rw.body.Bytes())
+		}
+
+		return h
+	}
+
+	return f
+}
+
+// responseRecorder keeps a copy of the response written by a handler.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+// WriteHeader records the status code before writing it.
+func (rw *responseRecorder) WriteHeader(status int) {
+	rw.status = status
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+// Write records the body before writing it.
+func (rw *responseRecorder) Write(b []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
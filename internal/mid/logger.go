@@ -3,7 +3,7 @@ package mid
 import (
 	"context"
 	"github.com/remisb/restaurant/internal/platform/web"
-	"go.opencensus.io/trace"
+	"go.opentelemetry.io/otel"
 	"log"
 	"net/http"
 	"time"
@@ -13,7 +13,7 @@ func Logger(log *log.Logger) web.Middleware {
 	f := func(before web.Handler) web.Handler {
 
 		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
-			ctx, span := trace.StartSpan(ctx, "internal.mid.Logger")
+			ctx, span := otel.Tracer("").Start(ctx, "internal.mid.Logger")
 			defer span.End()
 
 			// If the context is missing this value, request the service
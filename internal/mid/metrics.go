@@ -4,20 +4,22 @@ import (
 	"context"
 	"expvar"
 	"github.com/remisb/restaurant/internal/platform/web"
-	"go.opencensus.io/trace"
+	"go.opentelemetry.io/otel"
 	"net/http"
 	"runtime"
 )
 
 // m contains the global program counters for the application.
 var m = struct {
-	gr  *expvar.Int
-	req *expvar.Int
-	err *expvar.Int
+	gr     *expvar.Int
+	req    *expvar.Int
+	err    *expvar.Int
+	panics *expvar.Int
 }{
-	gr:  expvar.NewInt("goroutines"),
-	req: expvar.NewInt("requests"),
-	err: expvar.NewInt("errors"),
+	gr:     expvar.NewInt("goroutines"),
+	req:    expvar.NewInt("requests"),
+	err:    expvar.NewInt("errors"),
+	panics: expvar.NewInt("panics"),
 }
 
 // Metrics updates program counters.
@@ -28,7 +30,7 @@ func Metrics() web.Middleware {
 
 		// Wrap this handler around the next one provided.
 		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
-			ctx, span := trace.StartSpan(ctx, "internal.mid.Metrics")
+			ctx, span := otel.Tracer("").Start(ctx, "internal.mid.Metrics")
 			defer span.End()
 
 			err := before(ctx, w, r, params)
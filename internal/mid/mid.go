@@ -5,7 +5,7 @@ import (
 	"errors"
 	"github.com/remisb/restaurant/internal/platform/auth"
 	"github.com/remisb/restaurant/internal/platform/web"
-	"go.opencensus.io/trace"
+	"go.opentelemetry.io/otel"
 	"net/http"
 	"strings"
 )
@@ -25,7 +25,7 @@ func Authenticate(authenticator *auth.Authenticator) web.Middleware {
 
 		// Wrap this handler around the next one provided.
 		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
-			ctx, span := trace.StartSpan(ctx, "internal.mid.Authenticate")
+			ctx, span := otel.Tracer("").Start(ctx, "internal.mid.Authenticate")
 			defer span.End()
 
 			// Parse the authorization header. Expected header is of
@@ -61,7 +61,7 @@ func HasRole(roles ...string) web.Middleware {
 	f := func(after web.Handler) web.Handler {
 
 		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
-			ctx, span := trace.StartSpan(ctx, "internal.mid.HasRole")
+			ctx, span := otel.Tracer("").Start(ctx, "internal.mid.HasRole")
 			defer span.End()
 
 			claims, ok := ctx.Value(auth.Key).(auth.Claims)
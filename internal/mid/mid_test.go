@@ -0,0 +1,364 @@
+package mid_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/idempotency"
+	"github.com/remisb/restaurant/internal/mid"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/web"
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// TestAuthenticate validates the Authorization header must hold a bearer
+// token and the claims of the token are handed to the next handler.
+func TestAuthenticate(t *testing.T) {
+	const kid = "4754d86b-7a6d-4df5-9c65-224741361492"
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := auth.NewAuthenticator(key, kid, "RS256", auth.NewSimpleKeyLookupFunc(kid, key.Public().(*rsa.PublicKey)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims := auth.NewClaims("5cf37266-3473-4006-984f-9325122678b7", []string{auth.RoleUser}, time.Now(), time.Hour)
+	token, err := a.GenerateToken(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tt := []struct {
+		name   string
+		header string
+		status int
+	}{
+		{"a bearer token", "Bearer " + token, http.StatusOK},
+		{"a lower case scheme", "bearer " + token, http.StatusOK},
+		{"no header", "", http.StatusUnauthorized},
+		{"a basic scheme", "Basic dXNlcjpwYXNz", http.StatusUnauthorized},
+		{"a missing token", "Bearer", http.StatusUnauthorized},
+		{"a double space", "Bearer  " + token, http.StatusUnauthorized},
+		{"trailing data", "Bearer " + token + " extra", http.StatusUnauthorized},
+		{"an invalid token", "Bearer " + token[:len(token)-4], http.StatusUnauthorized},
+	}
+
+	t.Log("Given the need to authenticate requests.")
+	{
+		for i, tc := range tt {
+			t.Logf("\tTest %d:\tWhen sending %s.", i, tc.name)
+			{
+				var got auth.Claims
+				next := func(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+					got, _ = ctx.Value(auth.Key).(auth.Claims)
+					return nil
+				}
+				h := mid.Authenticate(a)(next)
+
+				r := httptest.NewRequest(http.MethodGet, "/", nil)
+				if tc.header != "" {
+					r.Header.Set("Authorization", tc.header)
+				}
+
+				err := h(context.Background(), httptest.NewRecorder(), r, nil)
+				if tc.status == http.StatusOK {
+					if err != nil {
+						t.Fatalf("\t%s\tShould be authenticated : %v.", tests.Failed, err)
+					}
+					if got.Subject != claims.Subject {
+						t.Fatalf("\t%s\tShould pass the claims on : got subject %q.", tests.Failed, got.Subject)
+					}
+					t.Logf("\t%s\tShould be authenticated.", tests.Success)
+					continue
+				}
+
+				webErr, ok := errors.Cause(err).(*web.Error)
+				if !ok || webErr.Status != tc.status {
+					t.Fatalf("\t%s\tShould receive a status code of %d : got %v.", tests.Failed, tc.status, err)
+				}
+				t.Logf("\t%s\tShould receive a status code of %d.", tests.Success, tc.status)
+			}
+		}
+	}
+}
+
+// TestErrorsViolation validates the violations of the constraints of the
+// schema are reported as client errors naming the field.
+func TestErrorsViolation(t *testing.T) {
+	tt := []struct {
+		name   string
+		err    *pq.Error
+		status int
+		body   string
+	}{
+		{
+			"a duplicate email",
+			&pq.Error{Code: "23505", Constraint: "users_email_key", Detail: "Key (email)=(admin@example.com) already exists."},
+			http.StatusConflict,
+			`{"code":"ALREADY_EXISTS","error":"Resource already exists","fields":[{"field":"email","error":"must be unique"}]}`,
+		},
+		{
+			"a duplicate restaurant name",
+			&pq.Error{Code: "23505", Constraint: "restaurant_owner_name_idx", Detail: "Key (owner_user_id, lower(name::text))=(a, b) already exists."},
+			http.StatusConflict,
+			`{"code":"ALREADY_EXISTS","error":"Resource already exists","fields":[{"field":"name","error":"must be unique"}]}`,
+		},
+		{
+			"a missing restaurant",
+			&pq.Error{Code: "23503", Constraint: "menu_restaurant_id_fkey", Detail: `Key (restaurant_id)=(a) is not present in table "restaurant".`},
+			http.StatusUnprocessableEntity,
+			`{"code":"INVALID_REFERENCE","error":"Referenced resource does not exist","fields":[{"field":"restaurant_id","error":"must refer to an existing resource"}]}`,
+		},
+		{
+			"another database error",
+			&pq.Error{Code: "42P01"},
+			http.StatusInternalServerError,
+			`{"code":"INTERNAL_ERROR","error":"Internal Server Error"}`,
+		},
+	}
+
+	t.Log("Given the need to report the violations of constraints.")
+	{
+		for i, tc := range tt {
+			t.Logf("\tTest %d:\tWhen the handler fails with %s.", i, tc.name)
+			{
+				next := func(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+					return errors.Wrap(tc.err, "inserting")
+				}
+				h := mid.Errors(log.New(ioutil.Discard, "", 0))(next)
+
+				w := httptest.NewRecorder()
+				ctx := context.WithValue(context.Background(), web.KeyValues, &web.Values{Method: http.MethodPost})
+				if err := h(ctx, w, httptest.NewRequest(http.MethodPost, "/", nil), nil); err != nil {
+					t.Fatalf("\t%s\tShould handle the error : %v.", tests.Failed, err)
+				}
+
+				if w.Code != tc.status {
+					t.Fatalf("\t%s\tShould receive a status code of %d : got %d.", tests.Failed, tc.status, w.Code)
+				}
+				t.Logf("\t%s\tShould receive a status code of %d.", tests.Success, tc.status)
+
+				if got := strings.TrimSpace(w.Body.String()); got != tc.body {
+					t.Fatalf("\t%s\tShould name the field : got %s.", tests.Failed, got)
+				}
+				t.Logf("\t%s\tShould name the field.", tests.Success)
+			}
+		}
+	}
+}
+
+// panicReporter records the panics reported to it.
+type panicReporter struct {
+	traceID string
+	err     *mid.PanicError
+}
+
+// ReportPanic implements the mid.PanicReporter interface.
+func (pr *panicReporter) ReportPanic(ctx context.Context, r *http.Request, traceID string, err *mid.PanicError) {
+	pr.traceID = traceID
+	pr.err = err
+}
+
+// TestPanics validates a panicking handler fails with its stack, is reported
+// and answers its trace ID.
+func TestPanics(t *testing.T) {
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+
+	var pr panicReporter
+	next := func(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+		panic("out of lunch")
+	}
+	logger := log.New(ioutil.Discard, "", 0)
+	h := mid.Errors(logger)(mid.Panics(logger, &pr)(next))
+
+	t.Log("Given the need to recover from panics.")
+	{
+		t.Log("\tTest 0:\tWhen the handler panics.")
+		{
+			w := httptest.NewRecorder()
+			ctx := context.WithValue(context.Background(), web.KeyValues, &web.Values{TraceID: traceID, Method: http.MethodGet})
+			if err := h(ctx, w, httptest.NewRequest(http.MethodGet, "/", nil), nil); err != nil {
+				t.Fatalf("\t%s\tShould handle the panic : %v.", tests.Failed, err)
+			}
+
+			if w.Code != http.StatusInternalServerError {
+				t.Fatalf("\t%s\tShould receive a status code of 500 : got %d.", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould receive a status code of 500.", tests.Success)
+
+			want := `{"code":"INTERNAL_ERROR","error":"Internal Server Error","trace_id":"` + traceID + `"}`
+			if got := strings.TrimSpace(w.Body.String()); got != want {
+				t.Fatalf("\t%s\tShould receive the trace ID : got %s.", tests.Failed, got)
+			}
+			t.Logf("\t%s\tShould receive the trace ID.", tests.Success)
+
+			if pr.err == nil || pr.traceID != traceID || pr.err.Value != "out of lunch" || !strings.Contains(string(pr.err.Stack), "goroutine") {
+				t.Fatalf("\t%s\tShould report the panic with its stack : got %+v.", tests.Failed, pr)
+			}
+			t.Logf("\t%s\tShould report the panic with its stack.", tests.Success)
+		}
+	}
+}
+
+// TestTimeout validates the deadline of a request is set by the innermost
+// Timeout, lifted by a zero one, and reported as a gateway timeout.
+func TestTimeout(t *testing.T) {
+	type claimsKey struct{}
+
+	var (
+		deadline time.Time
+		bounded  bool
+		claims   interface{}
+	)
+	record := func(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+		deadline, bounded = ctx.Deadline()
+		claims = ctx.Value(claimsKey{})
+		return nil
+	}
+	wait := func(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+		<-ctx.Done()
+		return errors.New("pq: canceling statement due to user request")
+	}
+	logger := log.New(ioutil.Discard, "", 0)
+
+	t.Log("Given the need to bound the time a request takes.")
+	{
+		t.Log("\tTest 0:\tWhen a route sets a longer timeout than the application.")
+		{
+			h := mid.Timeout(time.Second)(mid.Timeout(time.Hour)(record))
+			ctx := context.WithValue(context.Background(), claimsKey{}, "owner")
+
+			start := time.Now()
+			if err := h(ctx, httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil), nil); err != nil {
+				t.Fatalf("\t%s\tShould run the handler : %v.", tests.Failed, err)
+			}
+			if !bounded || deadline.Sub(start) < time.Minute {
+				t.Fatalf("\t%s\tShould set the deadline of the route : got %v.", tests.Failed, deadline.Sub(start))
+			}
+			t.Logf("\t%s\tShould set the deadline of the route.", tests.Success)
+
+			if claims != "owner" {
+				t.Fatalf("\t%s\tShould keep the values of the request : got %v.", tests.Failed, claims)
+			}
+			t.Logf("\t%s\tShould keep the values of the request.", tests.Success)
+		}
+
+		t.Log("\tTest 1:\tWhen a route lifts the timeout of the application.")
+		{
+			h := mid.Timeout(time.Millisecond)(mid.Timeout(0)(record))
+			ctx := context.WithValue(context.Background(), claimsKey{}, "owner")
+
+			if err := h(ctx, httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil), nil); err != nil {
+				t.Fatalf("\t%s\tShould run the handler : %v.", tests.Failed, err)
+			}
+			if bounded {
+				t.Fatalf("\t%s\tShould leave the request without a deadline : got %v.", tests.Failed, deadline)
+			}
+			t.Logf("\t%s\tShould leave the request without a deadline.", tests.Success)
+
+			if claims != "owner" {
+				t.Fatalf("\t%s\tShould keep the values of the request : got %v.", tests.Failed, claims)
+			}
+			t.Logf("\t%s\tShould keep the values of the request.", tests.Success)
+		}
+
+		t.Log("\tTest 2:\tWhen the handler runs past the deadline.")
+		{
+			h := mid.Errors(logger)(mid.Timeout(10 * time.Millisecond)(wait))
+			w := httptest.NewRecorder()
+			ctx := context.WithValue(context.Background(), web.KeyValues, &web.Values{Method: http.MethodGet})
+
+			if err := h(ctx, w, httptest.NewRequest(http.MethodGet, "/", nil), nil); err != nil {
+				t.Fatalf("\t%s\tShould handle the timeout : %v.", tests.Failed, err)
+			}
+			if w.Code != http.StatusGatewayTimeout {
+				t.Fatalf("\t%s\tShould receive a status code of 504 : got %d.", tests.Failed, w.Code)
+			}
+			t.Logf("\t%s\tShould receive a status code of 504.", tests.Success)
+		}
+	}
+}
+
+// TestIdempotency validates a request retried with its Idempotency-Key is
+// answered with the stored response without running the handler again.
+func TestIdempotency(t *testing.T) {
+	db, teardown := tests.NewUnit(t)
+	defer teardown()
+
+	now := time.Date(2020, time.March, 2, 9, 0, 0, 0, time.UTC)
+	claims := auth.NewClaims("45b5fbd3-755f-4379-8f07-a58d4a30fa2f", []string{auth.RoleUser}, now, time.Hour)
+
+	runs := 0
+	create := func(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+		runs++
+		return web.Respond(ctx, w, map[string]int{"run": runs}, http.StatusCreated)
+	}
+	h := mid.Errors(log.New(ioutil.Discard, "", 0))(mid.Idempotency(db, 24*time.Hour, time.Minute)(create))
+
+	send := func(key, body string) (*httptest.ResponseRecorder, error) {
+		r := httptest.NewRequest(http.MethodPost, "/v1/orders", strings.NewReader(body))
+		r.Header.Set("Idempotency-Key", key)
+		ctx := context.WithValue(context.Background(), web.KeyValues, &web.Values{Now: now})
+		ctx = context.WithValue(ctx, auth.Key, claims)
+		w := httptest.NewRecorder()
+		return w, h(ctx, w, r, nil)
+	}
+
+	t.Log("Given the need to process a request sent with an Idempotency-Key once.")
+	{
+		t.Log("\tTest 0:\tWhen the request is retried.")
+		{
+			first, err := send("order-1", `{"name":"Soup"}`)
+			if err != nil || first.Code != http.StatusCreated {
+				t.Fatalf("\t%s\tShould run the handler : got %d, %v.", tests.Failed, first.Code, err)
+			}
+
+			w, err := send("order-1", `{"name":"Soup"}`)
+			if err != nil {
+				t.Fatalf("\t%s\tShould replay the response : %v.", tests.Failed, err)
+			}
+			if w.Code != http.StatusCreated || w.Body.String() != first.Body.String() || w.Header().Get("Idempotent-Replayed") != "true" {
+				t.Fatalf("\t%s\tShould replay the response : got %d %q.", tests.Failed, w.Code, w.Body)
+			}
+			if runs != 1 {
+				t.Fatalf("\t%s\tShould run the handler once : got %d.", tests.Failed, runs)
+			}
+			t.Logf("\t%s\tShould replay the response without running the handler.", tests.Success)
+		}
+
+		t.Log("\tTest 1:\tWhen the key is reused with another body.")
+		{
+			w, err := send("order-1", `{"name":"Salad"}`)
+			if err != nil || w.Code != http.StatusUnprocessableEntity {
+				t.Fatalf("\t%s\tShould receive a status code of 422 : got %d, %v.", tests.Failed, w.Code, err)
+			}
+			t.Logf("\t%s\tShould receive a status code of 422.", tests.Success)
+		}
+
+		t.Log("\tTest 2:\tWhen the request is retried while it is processed.")
+		{
+			if _, err := idempotency.Reserve(tests.Context(), db, claims.Subject, "order-2", "held", 24*time.Hour, time.Minute, now); err != nil {
+				t.Fatalf("\t%s\tShould claim the key : %v.", tests.Failed, err)
+			}
+
+			w, err := send("order-2", `{"name":"Soup"}`)
+			if err != nil || w.Code != http.StatusConflict {
+				t.Fatalf("\t%s\tShould receive a status code of 409 : got %d, %v.", tests.Failed, w.Code, err)
+			}
+			t.Logf("\t%s\tShould receive a status code of 409.", tests.Success)
+		}
+	}
+}
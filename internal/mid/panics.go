@@ -2,16 +2,35 @@ package mid
 
 import (
 	"context"
-	"github.com/pkg/errors"
+	"fmt"
 	"github.com/remisb/restaurant/internal/platform/web"
 	"log"
 	"net/http"
 	"runtime/debug"
 )
 
+// PanicError is the error of a handler which panicked. It carries the stack
+// of the goroutine at the time of the panic.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+// Error implements the error interface.
+func (err *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", err.Value)
+}
+
+// PanicReporter reports the panics of the handlers outside of the logs, for
+// example to an error tracker like Sentry. It must not block the request.
+type PanicReporter interface {
+	ReportPanic(ctx context.Context, r *http.Request, traceID string, err *PanicError)
+}
+
 // Panics recovers from panics and converts the panic to an error so it is
-// reported in Metrics and handled in Errors.
-func Panics(log *log.Logger) web.Middleware {
+// reported in Metrics and handled in Errors. Every panic is counted, logged
+// with its stack and handed to the reporters.
+func Panics(log *log.Logger, reporters ...PanicReporter) web.Middleware {
 
 	// This is the actual middleware function to be executed.
 	f := func(after web.Handler) web.Handler {
@@ -29,11 +48,18 @@ func Panics(log *log.Logger) web.Middleware {
 			// Defer a function to recover from a panic and set the err return
 			// variable after the fact.
 			defer func() {
-				if r := recover(); r != nil {
-					err = errors.Errorf("panic: %v", r)
+				if rec := recover(); rec != nil {
+					pe := &PanicError{Value: rec, Stack: debug.Stack()}
+					err = pe
+
+					m.panics.Add(1)
 
 					// Log the Go stack trace for this panic'd goroutine.
-					log.Printf("%s :\n%s", v.TraceID, debug.Stack())
+					log.Printf("%s : ERROR : %v\n%s", v.TraceID, pe, pe.Stack)
+
+					for _, rep := range reporters {
+						rep.ReportPanic(ctx, r, v.TraceID, pe)
+					}
 				}
 			}()
 
@@ -0,0 +1,77 @@
+package mid
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/ratelimit"
+	"github.com/remisb/restaurant/internal/platform/web"
+	"go.opentelemetry.io/otel"
+)
+
+// ErrRateLimited is returned when a client made too many requests.
+var ErrRateLimited = errors.New("too many requests")
+
+// RateLimitPolicy holds the limits of a group of routes. Every client IP gets
+// a bucket limited by PerIP and every authenticated user a bucket limited by
+// PerUser. Buckets are only shared by the routes of the same Group.
+type RateLimitPolicy struct {
+	Group   string
+	PerIP   ratelimit.Limit
+	PerUser ratelimit.Limit
+}
+
+// RateLimit rejects requests with 429 Too Many Requests once the client has
+// used up its tokens. The Retry-After header tells the client when to try
+// again. To limit per user it must be used after Authenticate.
+func RateLimit(store ratelimit.Store, policy RateLimitPolicy) web.Middleware {
+
+	// This is the actual middleware function to be executed.
+	f := func(after web.Handler) web.Handler {
+
+		// Wrap this handler around the next one provided.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+			ctx, span := otel.Tracer("").Start(ctx, "internal.mid.RateLimit")
+			defer span.End()
+
+			v, ok := ctx.Value(web.KeyValues).(*web.Values)
+			if !ok {
+				return web.NewShutdownError("web value missing from context")
+			}
+
+			ip, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				ip = r.RemoteAddr
+			}
+
+			ok, wait, err := store.Take(ctx, policy.Group+":ip:"+ip, policy.PerIP, v.Now)
+			if err != nil {
+				return err
+			}
+
+			if claims, isAuth := ctx.Value(auth.Key).(auth.Claims); ok && isAuth {
+				ok, wait, err = store.Take(ctx, policy.Group+":user:"+claims.Subject, policy.PerUser, v.Now)
+				if err != nil {
+					return err
+				}
+			}
+
+			if !ok {
+				seconds := int(math.Ceil(wait.Seconds()))
+				w.Header().Set("Retry-After", strconv.Itoa(seconds))
+				return web.NewRequestError(ErrRateLimited, http.StatusTooManyRequests)
+			}
+
+			return after(ctx, w, r, params)
+		}
+
+		return h
+	}
+
+	return f
+}
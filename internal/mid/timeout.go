@@ -0,0 +1,70 @@
+package mid
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/platform/web"
+)
+
+// timeoutKey marks the contexts whose deadline was set by Timeout.
+type timeoutKey struct{}
+
+// Timeout sets a deadline on the request context so database calls and
+// other downstream work are canceled when the request takes too long. An
+// error returned after the deadline passed is reported as
+// context.DeadlineExceeded which Errors turns into 504 Gateway Timeout.
+//
+// A Timeout replaces the deadline of the Timeout run before it, so a group
+// or a route can lift or extend the one set for the whole application. A
+// zero d leaves the request without a deadline, like a stream needs.
+func Timeout(d time.Duration) web.Middleware {
+
+	// This is the actual middleware function to be executed.
+	f := func(after web.Handler) web.Handler {
+
+		// Wrap this handler around the next one provided.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+
+			// The values of ctx are kept but the request is only canceled
+			// when its client goes away, no longer by the previous deadline.
+			if set, _ := ctx.Value(timeoutKey{}).(bool); set {
+				ctx = context.WithValue(valuesOf{Context: r.Context(), values: ctx}, timeoutKey{}, false)
+			}
+			if d <= 0 {
+				return after(ctx, w, r, params)
+			}
+
+			ctx, cancel := context.WithTimeout(context.WithValue(ctx, timeoutKey{}, true), d)
+			defer cancel()
+
+			err := after(ctx, w, r, params)
+
+			// Drivers report a canceled query in their own way so the error
+			// is replaced when the deadline is what stopped the handler.
+			if err != nil && ctx.Err() == context.DeadlineExceeded && !web.IsShutdown(err) {
+				return errors.WithMessage(context.DeadlineExceeded, err.Error())
+			}
+
+			return err
+		}
+
+		return h
+	}
+
+	return f
+}
+
+// valuesOf is a context with the deadline and cancellation of the embedded
+// context and the values of another.
+type valuesOf struct {
+	context.Context
+	values context.Context
+}
+
+// Value implements the context.Context interface.
+func (c valuesOf) Value(key interface{}) interface{} {
+	return c.values.Value(key)
+}
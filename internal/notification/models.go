@@ -0,0 +1,67 @@
+package notification
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// These are the types of Notification. Channels such as push or email can
+// render the Data of a type the way they see fit.
+const (
+	TypeMenuPublished   = "menu.published"
+	TypeWinnerAnnounced = "winner.announced"
+	TypeOrderConfirmed  = "order.confirmed"
+)
+
+// Notification tells a user about an event of interest to them, like the
+// menu of a favorite restaurant being published.
+type Notification struct {
+	ID          string          `db:"notification_id" json:"id"`
+	UserID      string          `db:"user_id" json:"user_id"`
+	Type        string          `db:"type" json:"type"`
+	Message     string          `db:"message" json:"message"`
+	Data        json.RawMessage `db:"data" json:"data"`
+	DateCreated time.Time       `db:"date_created" json:"date_created"`
+	DateRead    *time.Time      `db:"date_read" json:"date_read,omitempty"`
+}
+
+// These are the channels users are notified on besides the app. Slack
+// direct messages are not sent yet, users choosing slack only follow the
+// posts of the Slack channel of the deployment and get no emails.
+const (
+	ChannelEmail = "email"
+	ChannelSlack = "slack"
+	ChannelNone  = "none"
+)
+
+// These are how often the winner digest is emailed. The weekly digest sums
+// up the winners of the week along with the winner of its Friday.
+const (
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// Preferences are the notification choices of a user. DietaryFilters are
+// the allergens the user avoids, the menus without any dish free of them
+// not being notified. With FavoritesOnly the user is only told about the
+// winners among their favorite restaurants.
+type Preferences struct {
+	UserID          string         `db:"user_id" json:"-"`
+	Channel         string         `db:"channel" json:"channel"`
+	DigestFrequency string         `db:"digest_frequency" json:"digest_frequency"`
+	DietaryFilters  pq.StringArray `db:"dietary_filters" json:"dietary_filters"`
+	FavoritesOnly   bool           `db:"favorites_only" json:"favorites_only"`
+	DateUpdated     time.Time      `db:"date_updated" json:"date_updated"`
+}
+
+// UpdatePreferences defines what information may be provided to modify the
+// preferences. All fields are optional so clients can send just the fields
+// they want changed.
+type UpdatePreferences struct {
+	Channel         *string  `json:"channel" validate:"omitempty,oneof=email slack none"`
+	DigestFrequency *string  `json:"digest_frequency" validate:"omitempty,oneof=daily weekly"`
+	DietaryFilters  []string `json:"dietary_filters" validate:"omitempty,dive,oneof=celery crustaceans eggs fish gluten lupin milk molluscs mustard nuts peanuts sesame soy sulphites"`
+	FavoritesOnly   *bool    `json:"favorites_only"`
+}
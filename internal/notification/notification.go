@@ -0,0 +1,211 @@
+// Package notification keeps the in-app notifications of the users. They are
+// added in the same transaction as the event they tell about.
+package notification
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/platform/database"
+	"github.com/remisb/restaurant/internal/restaurant"
+	"go.opentelemetry.io/otel"
+)
+
+// Predefined errors identify expected failure conditions.
+var (
+	// ErrNotFound is used when a specific Notification is requested but does
+	// not exist or belongs to another user.
+	ErrNotFound = errors.New("Notification not found")
+
+	// ErrInvalidID is used when an invalid UUID is provided.
+	ErrInvalidID = errors.New("ID is not in its proper form")
+)
+
+// listLimit is the most notifications returned by List.
+const listLimit = 100
+
+// Add stores a notification of the type for each user.
+func Add(ctx context.Context, db sqlx.ExtContext, userIDs []string, typ, message string, data interface{}, now time.Time) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.notification.Add")
+	defer span.End()
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return errors.Wrap(err, "encoding data")
+	}
+
+	const q = `INSERT INTO notification
+		(notification_id, user_id, type, message, data, date_created)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+	for _, userID := range userIDs {
+		if _, err := db.ExecContext(ctx, q, uuid.New().String(), userID, typ, message, payload, now.UTC()); err != nil {
+			return errors.Wrapf(err, "inserting %s notification", typ)
+		}
+	}
+
+	return nil
+}
+
+// MenuPublished notifies the users who marked the restaurant of the menu as
+// a favorite, leaving out those the menu does not suit the dietary filters
+// of.
+func MenuPublished(ctx context.Context, db sqlx.ExtContext, restaurantID string, menu *restaurant.Menu, now time.Time) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.notification.MenuPublished")
+	defer span.End()
+
+	var favorites []struct {
+		UserID  string         `db:"user_id"`
+		Filters pq.StringArray `db:"dietary_filters"`
+	}
+	const qu = `SELECT f.user_id, COALESCE(p.dietary_filters, '{}') AS dietary_filters FROM favorite AS f
+		JOIN users AS u ON u.user_id = f.user_id
+		LEFT JOIN user_preferences AS p ON p.user_id = f.user_id
+		WHERE f.restaurant_id = $1 AND u.deleted_at IS NULL`
+	if err := sqlx.SelectContext(ctx, db, &favorites, qu, restaurantID); err != nil {
+		return errors.Wrap(err, "selecting favorite users")
+	}
+
+	var users []string
+	for _, f := range favorites {
+		if suits(menu.Items, f.Filters) {
+			users = append(users, f.UserID)
+		}
+	}
+	if len(users) == 0 {
+		return nil
+	}
+
+	name, err := restaurantName(ctx, db, restaurantID)
+	if err != nil {
+		return err
+	}
+
+	msg := fmt.Sprintf("%s published a new menu.", name)
+	return Add(ctx, db, users, TypeMenuPublished, msg, menu, now)
+}
+
+// WinnerAnnounced notifies the users of the organization who voted for the
+// date of the winning restaurant. Users only notified of their favorites are
+// left out unless it is one of them.
+func WinnerAnnounced(ctx context.Context, db sqlx.ExtContext, date time.Time, orgID, restaurantID string, winner interface{}, now time.Time) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.notification.WinnerAnnounced")
+	defer span.End()
+
+	var users []string
+	const qu = `SELECT v.user_id FROM vote AS v
+		LEFT JOIN user_preferences AS p ON p.user_id = v.user_id
+		WHERE v.date = $1 AND v.org_id = $2 AND (p.favorites_only IS NOT TRUE OR EXISTS (
+			SELECT 1 FROM favorite AS f WHERE f.user_id = v.user_id AND f.restaurant_id = $3
+		))`
+	if err := sqlx.SelectContext(ctx, db, &users, qu, date, orgID, restaurantID); err != nil {
+		return errors.Wrap(err, "selecting voters")
+	}
+	if len(users) == 0 {
+		return nil
+	}
+
+	name, err := restaurantName(ctx, db, restaurantID)
+	if err != nil {
+		return err
+	}
+
+	msg := fmt.Sprintf("%s won the vote for %s.", name, date.Format("2006-01-02"))
+	return Add(ctx, db, users, TypeWinnerAnnounced, msg, winner, now)
+}
+
+// OrderConfirmed notifies the user that the restaurant confirmed their order.
+func OrderConfirmed(ctx context.Context, db sqlx.ExtContext, userID, restaurantID string, order interface{}, now time.Time) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.notification.OrderConfirmed")
+	defer span.End()
+
+	name, err := restaurantName(ctx, db, restaurantID)
+	if err != nil {
+		return err
+	}
+
+	msg := fmt.Sprintf("%s confirmed your order.", name)
+	return Add(ctx, db, []string{userID}, TypeOrderConfirmed, msg, order, now)
+}
+
+// List returns the latest notifications of the user, the most recent first.
+// With unread only the notifications not read yet are returned.
+func List(ctx context.Context, db *sqlx.DB, userID string, unread bool) ([]Notification, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.notification.List")
+	defer span.End()
+
+	notifications := []Notification{}
+	const q = `SELECT * FROM notification
+		WHERE user_id = $1 AND (NOT $2 OR date_read IS NULL)
+		ORDER BY date_created DESC
+		LIMIT $3`
+	if err := sqlx.SelectContext(ctx, database.Conn(ctx, db), &notifications, q, userID, unread, listLimit); err != nil {
+		return nil, errors.Wrap(err, "selecting notifications")
+	}
+
+	return notifications, nil
+}
+
+// MarkRead marks the notification of the user as read. Marking it again
+// keeps the time it was first read.
+func MarkRead(ctx context.Context, db *sqlx.DB, userID, id string, now time.Time) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.notification.MarkRead")
+	defer span.End()
+
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrInvalidID
+	}
+
+	const q = `UPDATE notification SET
+		"date_read" = COALESCE(date_read, $3)
+		WHERE notification_id = $1 AND user_id = $2`
+	res, err := database.Conn(ctx, db).ExecContext(ctx, q, id, userID, now.UTC())
+	if err != nil {
+		return errors.Wrapf(err, "updating notification %s", id)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrapf(err, "updating notification %s", id)
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// MarkAllRead marks every unread notification of the user as read.
+func MarkAllRead(ctx context.Context, db *sqlx.DB, userID string, now time.Time) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.notification.MarkAllRead")
+	defer span.End()
+
+	const q = `UPDATE notification SET
+		"date_read" = $2
+		WHERE user_id = $1 AND date_read IS NULL`
+	if _, err := database.Conn(ctx, db).ExecContext(ctx, q, userID, now.UTC()); err != nil {
+		return errors.Wrap(err, "updating notifications")
+	}
+
+	return nil
+}
+
+// restaurantName returns the name of the restaurant the notifications are
+// about.
+func restaurantName(ctx context.Context, db sqlx.ExtContext, restaurantID string) (string, error) {
+	var name string
+	const q = `SELECT name FROM restaurant WHERE restaurant_id = $1`
+	if err := sqlx.GetContext(ctx, db, &name, q, restaurantID); err != nil {
+		if err == sql.ErrNoRows {
+			return "", errors.Errorf("restaurant %s not found", restaurantID)
+		}
+		return "", errors.Wrap(err, "selecting restaurant name")
+	}
+	return name, nil
+}
@@ -0,0 +1,31 @@
+package notification
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/remisb/restaurant/internal/platform/database"
+	"github.com/remisb/restaurant/internal/restaurant"
+)
+
+// Notifier adds notifications for the handlers which do not work with the
+// database directly. A nil Notifier drops them.
+type Notifier struct {
+	db *sqlx.DB
+}
+
+// NewNotifier constructs a Notifier storing notifications in the database.
+func NewNotifier(db *sqlx.DB) *Notifier {
+	return &Notifier{db: db}
+}
+
+// MenuPublished notifies the users who marked the restaurant of the menu as
+// a favorite. The notifications are part of the transaction carried by ctx,
+// if any.
+func (n *Notifier) MenuPublished(ctx context.Context, restaurantID string, menu *restaurant.Menu, now time.Time) error {
+	if n == nil {
+		return nil
+	}
+	return MenuPublished(ctx, database.Conn(ctx, n.db), restaurantID, menu, now)
+}
@@ -0,0 +1,87 @@
+package notification
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/platform/database"
+	"github.com/remisb/restaurant/internal/restaurant"
+	"go.opentelemetry.io/otel"
+)
+
+// RetrievePreferences gets the notification preferences of the user.
+func RetrievePreferences(ctx context.Context, db *sqlx.DB, userID string) (*Preferences, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.notification.RetrievePreferences")
+	defer span.End()
+
+	return loadPreferences(ctx, database.Conn(ctx, db), userID)
+}
+
+// loadPreferences gets the preferences of the user, the defaults when they
+// never changed them.
+func loadPreferences(ctx context.Context, q sqlx.QueryerContext, userID string) (*Preferences, error) {
+	p := Preferences{
+		UserID:          userID,
+		Channel:         ChannelEmail,
+		DigestFrequency: DigestDaily,
+		DietaryFilters:  pq.StringArray{},
+	}
+	const qs = `SELECT * FROM user_preferences WHERE user_id = $1`
+	if err := sqlx.GetContext(ctx, q, &p, qs, userID); err != nil && err != sql.ErrNoRows {
+		return nil, errors.Wrapf(err, "selecting preferences of user %s", userID)
+	}
+
+	return &p, nil
+}
+
+// ChangePreferences modifies the notification preferences of the user.
+func ChangePreferences(ctx context.Context, db *sqlx.DB, userID string, up UpdatePreferences, now time.Time) (*Preferences, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.notification.ChangePreferences")
+	defer span.End()
+
+	p, err := loadPreferences(ctx, database.Conn(ctx, db), userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if up.Channel != nil {
+		p.Channel = *up.Channel
+	}
+	if up.DigestFrequency != nil {
+		p.DigestFrequency = *up.DigestFrequency
+	}
+	if up.DietaryFilters != nil {
+		p.DietaryFilters = up.DietaryFilters
+	}
+	if up.FavoritesOnly != nil {
+		p.FavoritesOnly = *up.FavoritesOnly
+	}
+	p.DateUpdated = now.UTC()
+
+	const q = `INSERT INTO user_preferences
+		(user_id, channel, digest_frequency, dietary_filters, favorites_only, date_updated)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id) DO UPDATE SET
+		"channel" = EXCLUDED.channel,
+		"digest_frequency" = EXCLUDED.digest_frequency,
+		"dietary_filters" = EXCLUDED.dietary_filters,
+		"favorites_only" = EXCLUDED.favorites_only,
+		"date_updated" = EXCLUDED.date_updated`
+	_, err = database.Conn(ctx, db).ExecContext(ctx, q, p.UserID, p.Channel, p.DigestFrequency, p.DietaryFilters, p.FavoritesOnly, p.DateUpdated)
+	if err != nil {
+		return nil, errors.Wrapf(err, "updating preferences of user %s", userID)
+	}
+
+	return p, nil
+}
+
+// suits reports whether a menu of the items suits the dietary filters of a
+// user, which is when one of its dishes is free of the allergens. A menu
+// without structured items always suits.
+func suits(items restaurant.MenuItems, filters []string) bool {
+	return len(items) == 0 || len(filters) == 0 || len(items.Exclude(filters)) > 0
+}
@@ -0,0 +1,40 @@
+package notification
+
+import (
+	"testing"
+
+	"github.com/remisb/restaurant/internal/restaurant"
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// TestSuits validates the menus notified to users with dietary filters.
+func TestSuits(t *testing.T) {
+	items := restaurant.MenuItems{
+		{Name: "Margherita", Allergens: []string{"gluten", "milk"}},
+		{Name: "Salad", Allergens: []string{"mustard"}},
+	}
+
+	t.Log("Given the need to notify users only of the menus they can eat from.")
+	{
+		tt := []struct {
+			name    string
+			items   restaurant.MenuItems
+			filters []string
+			want    bool
+		}{
+			{"without filters", items, nil, true},
+			{"with a dish free of the allergens", items, []string{"gluten"}, true},
+			{"without a dish free of the allergens", items, []string{"milk", "mustard"}, false},
+			{"without structured items", nil, []string{"milk"}, true},
+		}
+		for i, tc := range tt {
+			t.Logf("\tTest %d:\tWhen checking a menu %s.", i, tc.name)
+			{
+				if got := suits(tc.items, tc.filters); got != tc.want {
+					t.Fatalf("\t%s\tShould report %v : got %v.", tests.Failed, tc.want, got)
+				}
+				t.Logf("\t%s\tShould report %v.", tests.Success, tc.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package email
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/broadcast"
+	"github.com/remisb/restaurant/internal/organization"
+)
+
+// Broadcaster emails the urgent broadcasts to their recipients. It is a
+// broadcast.Channel. The emails are sent right away instead of through the
+// Queue so the delivery report tells whether they were.
+type Broadcaster struct {
+	sender Sender
+}
+
+// NewBroadcaster constructs a Broadcaster sending its emails with the sender.
+func NewBroadcaster(sender Sender) *Broadcaster {
+	return &Broadcaster{sender: sender}
+}
+
+// Name implements the broadcast.Channel interface.
+func (b *Broadcaster) Name() string {
+	return organization.ChannelEmail
+}
+
+// Send implements the broadcast.Channel interface.
+func (b *Broadcaster) Send(ctx context.Context, to broadcast.Recipient, bc broadcast.Broadcast) error {
+	if to.Email == "" {
+		return errors.New("recipient has no email address")
+	}
+
+	m, err := Render(TemplateBroadcast, to.Email, Broadcast{
+		Name:    to.Name,
+		Message: bc.Message,
+		Date:    bc.DateCreated,
+	})
+	if err != nil {
+		return err
+	}
+
+	return b.sender.Send(ctx, m)
+}
@@ -0,0 +1,133 @@
+package email
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/organization"
+	"github.com/remisb/restaurant/internal/vote"
+	"go.opentelemetry.io/otel"
+)
+
+// Digest emails the winner of a date to the users who voted for it, and the
+// winners of the week on Fridays to those preferring a weekly digest. It is
+// a vote.Announcer.
+type Digest struct {
+	db    *sqlx.DB
+	queue *Queue
+}
+
+// NewDigest constructs a Digest queueing its emails on the queue.
+func NewDigest(db *sqlx.DB, queue *Queue) *Digest {
+	return &Digest{db: db, queue: queue}
+}
+
+// Channel implements the vote.Announcer interface.
+func (d *Digest) Channel() string {
+	return organization.ChannelEmail
+}
+
+// Announce implements the vote.Announcer interface.
+func (d *Digest) Announce(ctx context.Context, w vote.Winner) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.notify.email.Digest.Announce")
+	defer span.End()
+
+	var restaurant string
+	const qr = `SELECT name FROM restaurant WHERE restaurant_id = $1`
+	if err := d.db.GetContext(ctx, &restaurant, qr, w.RestaurantID); err != nil {
+		return errors.Wrap(err, "selecting restaurant name")
+	}
+
+	// Voters who chose another channel, a weekly digest or only hearing
+	// about their favorites are skipped.
+	var voters []voter
+	const qv = `SELECT u.name, u.email FROM vote AS v
+		JOIN users AS u ON u.user_id = v.user_id
+		LEFT JOIN user_preferences AS p ON p.user_id = u.user_id
+		WHERE v.date = $1 AND v.org_id = $2 AND u.deleted_at IS NULL
+		AND COALESCE(p.channel, 'email') = 'email'
+		AND COALESCE(p.digest_frequency, 'daily') = 'daily'
+		AND (p.favorites_only IS NOT TRUE OR EXISTS (
+			SELECT 1 FROM favorite AS f WHERE f.user_id = u.user_id AND f.restaurant_id = $3))`
+	if err := d.db.SelectContext(ctx, &voters, qv, w.Date, w.OrgID, w.RestaurantID); err != nil {
+		return errors.Wrap(err, "selecting voters")
+	}
+
+	dropped := 0
+	for _, v := range voters {
+		m, err := Render(TemplateWinnerDigest, v.Email, WinnerDigest{
+			Name:       v.Name,
+			Restaurant: restaurant,
+			Date:       w.Date,
+			Votes:      w.Votes,
+		})
+		if err != nil {
+			return err
+		}
+		if !d.queue.Enqueue(m) {
+			dropped++
+		}
+	}
+
+	if dropped > 0 {
+		return errors.Errorf("email queue full, %d of %d digests dropped", dropped, len(voters))
+	}
+
+	if w.Date.Weekday() == time.Friday {
+		return d.weekly(ctx, w)
+	}
+	return nil
+}
+
+// voter is a recipient of the digests.
+type voter struct {
+	Name  string `db:"name"`
+	Email string `db:"email"`
+}
+
+// weekly emails the winners of the week ending with the winner to the users
+// who voted during the week and chose a weekly digest.
+func (d *Digest) weekly(ctx context.Context, w vote.Winner) error {
+	monday := w.Date.AddDate(0, 0, -4)
+
+	var winners []WeeklyWinner
+	const qw = `SELECT w.date, r.name AS restaurant, w.votes FROM winner AS w
+		JOIN restaurant AS r ON r.restaurant_id = w.restaurant_id
+		WHERE w.org_id = $1 AND w.date BETWEEN $2 AND $3
+		ORDER BY w.date`
+	if err := d.db.SelectContext(ctx, &winners, qw, w.OrgID, monday, w.Date); err != nil {
+		return errors.Wrap(err, "selecting winners of the week")
+	}
+
+	var voters []voter
+	const qv = `SELECT DISTINCT u.name, u.email FROM vote AS v
+		JOIN users AS u ON u.user_id = v.user_id
+		JOIN user_preferences AS p ON p.user_id = u.user_id
+		WHERE v.date BETWEEN $1 AND $2 AND v.org_id = $3 AND u.deleted_at IS NULL
+		AND p.channel = 'email' AND p.digest_frequency = 'weekly'`
+	if err := d.db.SelectContext(ctx, &voters, qv, monday, w.Date, w.OrgID); err != nil {
+		return errors.Wrap(err, "selecting weekly voters")
+	}
+
+	dropped := 0
+	for _, v := range voters {
+		m, err := Render(TemplateWeeklyDigest, v.Email, WeeklyDigest{
+			Name:    v.Name,
+			Week:    monday,
+			Winners: winners,
+		})
+		if err != nil {
+			return err
+		}
+		if !d.queue.Enqueue(m) {
+			dropped++
+		}
+	}
+
+	if dropped > 0 {
+		return errors.Errorf("email queue full, %d of %d weekly digests dropped", dropped, len(voters))
+	}
+	return nil
+}
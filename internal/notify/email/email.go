@@ -0,0 +1,94 @@
+// Package email sends the emails of the service, such as the daily winner
+// digest, over SMTP. Messages are rendered from templates and sent by a Queue
+// in the background so a slow mail server never holds up a request.
+package email
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Message is a plain text email to a single recipient.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers messages.
+type Sender interface {
+	Send(ctx context.Context, m Message) error
+}
+
+// Config is the SMTP server the messages are sent through.
+type Config struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// SMTP sends messages through an SMTP server. The connection is upgraded to
+// TLS when the server supports it.
+type SMTP struct {
+	cfg Config
+}
+
+// NewSMTP constructs a Sender for the SMTP server.
+func NewSMTP(cfg Config) *SMTP {
+	return &SMTP{cfg: cfg}
+}
+
+// Send implements the Sender interface.
+func (s *SMTP) Send(ctx context.Context, m Message) error {
+	var auth smtp.Auth
+	if s.cfg.Username != "" {
+		auth = smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)
+	}
+
+	addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.Port))
+	if err := smtp.SendMail(addr, auth, s.cfg.From, []string{m.To}, encode(s.cfg.From, m, time.Now())); err != nil {
+		return errors.Wrapf(err, "sending email to %s", m.To)
+	}
+	return nil
+}
+
+// Log writes messages to the log instead of sending them, for development.
+type Log struct {
+	log *log.Logger
+}
+
+// NewLog constructs a Sender writing messages to the logger.
+func NewLog(log *log.Logger) *Log {
+	return &Log{log: log}
+}
+
+// Send implements the Sender interface.
+func (l *Log) Send(ctx context.Context, m Message) error {
+	l.log.Printf("email : to %s : %s\n%s", m.To, m.Subject, m.Body)
+	return nil
+}
+
+// encode formats the message as described in RFC 5322.
+func encode(from string, m Message, now time.Time) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", m.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(m.Body)
+	return b.Bytes()
+}
@@ -0,0 +1,122 @@
+package email
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// TestRender validates every template renders a subject and a body.
+func TestRender(t *testing.T) {
+	date := time.Date(2020, time.March, 2, 0, 0, 0, 0, time.UTC)
+
+	tt := []struct {
+		name string
+		data interface{}
+		want string
+	}{
+		{TemplatePasswordReset, PasswordReset{Name: "Ann", URL: "https://lunch.example.com/reset/abc", Expires: date}, "https://lunch.example.com/reset/abc"},
+		{TemplateWinnerDigest, WinnerDigest{Name: "Ann", Restaurant: "Pizza Place", Date: date, Votes: 3}, "Pizza Place won the vote"},
+		{TemplateReservationConfirmation, ReservationConfirmation{Name: "Ann", Restaurant: "Pizza Place", Date: date.Add(12 * time.Hour), People: 4}, "table for 4"},
+		{TemplateMonthlyReport, MonthlyReport{Name: "Ann", Month: date, Days: 20, Votes: 150, Orders: 40, Spend: "480.00", Restaurants: []MonthlyRestaurant{{Name: "Pizza Place", Wins: 12, Votes: 90}}}, "Pizza Place: 12 wins, 90 votes"},
+		{TemplateWeeklyDigest, WeeklyDigest{Name: "Ann", Week: date, Winners: []WeeklyWinner{{Date: date, Restaurant: "Pizza Place", Votes: 1}}}, "Monday: Pizza Place with 1 vote\n"},
+		{TemplateBroadcast, Broadcast{Name: "Ann", Message: "Leave the cafeteria now.", Date: date}, "Leave the cafeteria now."},
+	}
+
+	t.Log("Given the need to render emails from templates.")
+	{
+		for i, tc := range tt {
+			t.Logf("\tTest %d:\tWhen rendering the %s template.", i, tc.name)
+			{
+				m, err := Render(tc.name, "ann@example.com", tc.data)
+				if err != nil {
+					t.Fatalf("\t%s\tShould render the message : %v.", tests.Failed, err)
+				}
+				if m.To != "ann@example.com" || m.Subject == "" || strings.Contains(m.Subject, "\n") {
+					t.Fatalf("\t%s\tShould address the message with a subject : got %+v.", tests.Failed, m)
+				}
+				if !strings.Contains(m.Body, tc.want) {
+					t.Fatalf("\t%s\tShould render the data in the body : got %q.", tests.Failed, m.Body)
+				}
+				tests.LogSuccess(t, "Should render the message.")
+			}
+		}
+
+		t.Log("\tTest 5:\tWhen rendering an unknown template.")
+		{
+			if _, err := Render("unknown", "ann@example.com", nil); err == nil {
+				t.Fatalf("\t%s\tShould fail.", tests.Failed)
+			}
+			tests.LogSuccess(t, "Should fail.")
+		}
+	}
+}
+
+// flakySender fails the first sends of every message.
+type flakySender struct {
+	mu       sync.Mutex
+	failures int
+	attempts int
+	sent     chan Message
+}
+
+func (s *flakySender) Send(ctx context.Context, m Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.attempts++
+	if s.attempts <= s.failures {
+		return errors.New("connection refused")
+	}
+	s.sent <- m
+	return nil
+}
+
+// TestQueue validates failed messages are retried.
+func TestQueue(t *testing.T) {
+	logger := log.New(ioutil.Discard, "", 0)
+
+	t.Log("Given the need to send emails despite a flaky mail server.")
+	{
+		t.Log("\tTest 0:\tWhen the first attempts fail.")
+		{
+			sender := flakySender{failures: 2, sent: make(chan Message, 1)}
+			q := NewQueue(logger, &sender, 10, 3, time.Millisecond)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go q.Run(ctx)
+
+			if !q.Enqueue(Message{To: "ann@example.com", Subject: "Lunch"}) {
+				t.Fatalf("\t%s\tShould queue the message.", tests.Failed)
+			}
+
+			select {
+			case m := <-sender.sent:
+				if m.To != "ann@example.com" {
+					t.Fatalf("\t%s\tShould send the message : got %+v.", tests.Failed, m)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("\t%s\tShould send the message.", tests.Failed)
+			}
+			tests.LogSuccess(t, "Should send the message on the third attempt.")
+
+			// The attempt is recorded once the sender returns.
+			st := q.Status()
+			for deadline := time.Now().Add(time.Second); st.Runs < 3 && time.Now().Before(deadline); st = q.Status() {
+				time.Sleep(time.Millisecond)
+			}
+			if st.Failures != 2 || st.Runs != 3 {
+				t.Fatalf("\t%s\tShould record every attempt : got %+v.", tests.Failed, st)
+			}
+			tests.LogSuccess(t, "Should record every attempt.")
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package email
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/remisb/restaurant/internal/platform/job"
+)
+
+// pending is a message waiting to be sent.
+type pending struct {
+	msg      Message
+	attempts int
+}
+
+// Queue sends messages in the background. A message which failed to be sent
+// is retried with an exponential backoff until it was attempted maxAttempts
+// times. The queue is kept in memory so pending messages are lost when the
+// service stops.
+type Queue struct {
+	log         *log.Logger
+	sender      Sender
+	queue       chan pending
+	maxAttempts int
+	backoff     time.Duration
+	tracker     *job.Tracker
+}
+
+// NewQueue constructs a Queue able to hold size pending messages. The first
+// retry of a message waits for backoff.
+func NewQueue(log *log.Logger, sender Sender, size, maxAttempts int, backoff time.Duration) *Queue {
+	return &Queue{
+		log:         log,
+		sender:      sender,
+		queue:       make(chan pending, size),
+		maxAttempts: maxAttempts,
+		backoff:     backoff,
+		tracker:     job.NewTracker("email"),
+	}
+}
+
+// Status reports the state of the queue to the health check.
+func (q *Queue) Status() job.Status {
+	st := q.tracker.Status()
+	st.Backlog = len(q.queue)
+	return st
+}
+
+// Enqueue schedules the message for sending. It never blocks and reports
+// false when the queue is full.
+func (q *Queue) Enqueue(m Message) bool {
+	return q.push(pending{msg: m})
+}
+
+// Run sends queued messages until the context is canceled.
+func (q *Queue) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case p := <-q.queue:
+			q.send(ctx, p)
+		}
+	}
+}
+
+// send attempts to send the message once and schedules a retry when it
+// fails.
+func (q *Queue) send(ctx context.Context, p pending) {
+	p.attempts++
+	err := q.sender.Send(ctx, p.msg)
+	q.tracker.Record(err, time.Now())
+	if err == nil {
+		return
+	}
+
+	if p.attempts >= q.maxAttempts {
+		q.log.Printf("email : to %s : giving up after %d attempts : ERROR : %+v", p.msg.To, p.attempts, err)
+		return
+	}
+	q.log.Printf("email : to %s : attempt %d : ERROR : %+v", p.msg.To, p.attempts, err)
+
+	delay := q.backoff << uint(p.attempts-1)
+	time.AfterFunc(delay, func() {
+		if !q.push(p) {
+			q.log.Printf("email : to %s : queue full, dropping retry", p.msg.To)
+		}
+	})
+}
+
+// push adds the message to the queue unless it is full.
+func (q *Queue) push(p pending) bool {
+	select {
+	case q.queue <- p:
+		return true
+	default:
+		return false
+	}
+}
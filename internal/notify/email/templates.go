@@ -0,0 +1,129 @@
+package email
+
+import (
+	"bytes"
+	"embed"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// These are the templates messages are rendered from. Each template defines
+// a subject and a body.
+const (
+	TemplatePasswordReset           = "password_reset"
+	TemplateWinnerDigest            = "winner_digest"
+	TemplateReservationConfirmation = "reservation_confirmation"
+	TemplateMonthlyReport           = "monthly_report"
+	TemplateWeeklyDigest            = "weekly_digest"
+	TemplateBroadcast               = "broadcast"
+)
+
+// PasswordReset is the data of the password reset template.
+type PasswordReset struct {
+	Name    string
+	URL     string
+	Expires time.Time
+}
+
+// WinnerDigest is the data of the daily winner digest template.
+type WinnerDigest struct {
+	Name       string
+	Restaurant string
+	Date       time.Time
+	Votes      int
+}
+
+// WeeklyDigest is the data of the weekly winner digest template. Week is
+// the Monday of the week.
+type WeeklyDigest struct {
+	Name    string
+	Week    time.Time
+	Winners []WeeklyWinner
+}
+
+// WeeklyWinner is a winner of the weekly digest template.
+type WeeklyWinner struct {
+	Date       time.Time `db:"date"`
+	Restaurant string    `db:"restaurant"`
+	Votes      int       `db:"votes"`
+}
+
+// ReservationConfirmation is the data of the reservation confirmation
+// template.
+type ReservationConfirmation struct {
+	Name       string
+	Restaurant string
+	Date       time.Time
+	People     int
+}
+
+// MonthlyReport is the data of the monthly report template. Spend is the
+// formatted amount and Participation a percentage.
+type MonthlyReport struct {
+	Name        string
+	Month       time.Time
+	Days        int
+	Votes       int
+	Orders      int
+	Spend       string
+	Teams       []MonthlyTeam
+	Restaurants []MonthlyRestaurant
+}
+
+// MonthlyTeam is a team of the monthly report template.
+type MonthlyTeam struct {
+	Name          string
+	Participation int
+}
+
+// MonthlyRestaurant is a restaurant of the monthly report template.
+type MonthlyRestaurant struct {
+	Name  string
+	Wins  int
+	Votes int
+}
+
+// Broadcast is the data of the urgent broadcast template.
+type Broadcast struct {
+	Name    string
+	Message string
+	Date    time.Time
+}
+
+//go:embed templates/*.tmpl
+var files embed.FS
+
+// templates are parsed once, by name.
+var templates = func() map[string]*template.Template {
+	ts := make(map[string]*template.Template)
+	for _, name := range []string{TemplatePasswordReset, TemplateWinnerDigest, TemplateReservationConfirmation, TemplateMonthlyReport, TemplateWeeklyDigest, TemplateBroadcast} {
+		ts[name] = template.Must(template.ParseFS(files, "templates/"+name+".tmpl"))
+	}
+	return ts
+}()
+
+// Render renders the message of the template for the recipient.
+func Render(name, to string, data interface{}) (Message, error) {
+	t, ok := templates[name]
+	if !ok {
+		return Message{}, errors.Errorf("unknown email template %q", name)
+	}
+
+	var subject, body bytes.Buffer
+	if err := t.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, errors.Wrapf(err, "rendering %s subject", name)
+	}
+	if err := t.ExecuteTemplate(&body, "body", data); err != nil {
+		return Message{}, errors.Wrapf(err, "rendering %s body", name)
+	}
+
+	m := Message{
+		To:      to,
+		Subject: strings.TrimSpace(subject.String()),
+		Body:    strings.TrimLeft(body.String(), "\n"),
+	}
+	return m, nil
+}
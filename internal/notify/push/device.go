@@ -0,0 +1,70 @@
+package push
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+)
+
+// ErrNotFound is used when a Device is unregistered but was not registered
+// by the user.
+var ErrNotFound = errors.New("Device not found")
+
+// Register stores the device of the user. A device registered by another
+// user before, like when the phone changed hands, moves to this one.
+func Register(ctx context.Context, db *sqlx.DB, userID string, nd NewDevice, now time.Time) (*Device, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.notify.push.Register")
+	defer span.End()
+
+	d := Device{
+		Token:       nd.Token,
+		UserID:      userID,
+		Platform:    nd.Platform,
+		DateCreated: now.UTC(),
+	}
+
+	const q = `INSERT INTO push_device
+		(token, user_id, platform, date_created)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (token) DO UPDATE SET
+			user_id = EXCLUDED.user_id,
+			platform = EXCLUDED.platform,
+			date_created = EXCLUDED.date_created`
+	if _, err := db.ExecContext(ctx, q, d.Token, d.UserID, d.Platform, d.DateCreated); err != nil {
+		return nil, errors.Wrap(err, "inserting device")
+	}
+
+	return &d, nil
+}
+
+// Unregister removes the device of the user so it no longer receives push
+// notifications.
+func Unregister(ctx context.Context, db *sqlx.DB, userID, token string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.notify.push.Unregister")
+	defer span.End()
+
+	const q = `DELETE FROM push_device WHERE token = $1 AND user_id = $2`
+	res, err := db.ExecContext(ctx, q, token, userID)
+	if err != nil {
+		return errors.Wrap(err, "deleting device")
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// devices gets the devices of the user.
+func devices(ctx context.Context, db *sqlx.DB, userID string) ([]Device, error) {
+	devices := []Device{}
+	const q = `SELECT * FROM push_device WHERE user_id = $1 ORDER BY date_created`
+	if err := db.SelectContext(ctx, &devices, q, userID); err != nil {
+		return nil, errors.Wrap(err, "selecting devices")
+	}
+
+	return devices, nil
+}
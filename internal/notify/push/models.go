@@ -0,0 +1,23 @@
+package push
+
+import "time"
+
+// These are the platforms of the devices.
+const (
+	PlatformIOS     = "ios"
+	PlatformAndroid = "android"
+)
+
+// Device is a mobile device of a user receiving push notifications.
+type Device struct {
+	Token       string    `db:"token" json:"token"`
+	UserID      string    `db:"user_id" json:"user_id"`
+	Platform    string    `db:"platform" json:"platform"`
+	DateCreated time.Time `db:"date_created" json:"date_created"`
+}
+
+// NewDevice is what we require from users when registering a Device.
+type NewDevice struct {
+	Token    string `json:"token" validate:"required,max=4096"`
+	Platform string `json:"platform" validate:"required,oneof=ios android"`
+}
@@ -0,0 +1,129 @@
+// Package push sends push notifications to the mobile devices of the users
+// through a Gorush gateway, which relays them to APNs and FCM.
+package push
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/broadcast"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+)
+
+// ChannelPush is the name of the deliveries over push notifications.
+const ChannelPush = "push"
+
+// platforms are the numbers Gorush knows the platforms by.
+var platforms = map[string]int{
+	PlatformIOS:     1,
+	PlatformAndroid: 2,
+}
+
+// Gateway pushes the urgent broadcasts to the devices of their recipients.
+// It is a broadcast.Channel.
+type Gateway struct {
+	db     *sqlx.DB
+	url    string
+	client *http.Client
+}
+
+// NewGateway constructs a Gateway for the Gorush server at the url.
+func NewGateway(db *sqlx.DB, url string, timeout time.Duration) *Gateway {
+	return &Gateway{
+		db:  db,
+		url: strings.TrimSuffix(url, "/"),
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
+		},
+	}
+}
+
+// Name implements the broadcast.Channel interface.
+func (g *Gateway) Name() string {
+	return ChannelPush
+}
+
+// Send implements the broadcast.Channel interface. It fails for recipients
+// without a registered device.
+func (g *Gateway) Send(ctx context.Context, to broadcast.Recipient, b broadcast.Broadcast) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.notify.push.Send")
+	defer span.End()
+
+	ds, err := devices(ctx, g.db, to.UserID)
+	if err != nil {
+		return err
+	}
+	if len(ds) == 0 {
+		return errors.New("recipient has no push device")
+	}
+
+	return g.send(ctx, ds, b)
+}
+
+// send asks the gateway to push the broadcast to the devices, one
+// notification per platform.
+func (g *Gateway) send(ctx context.Context, ds []Device, b broadcast.Broadcast) error {
+	type notification struct {
+		Tokens   []string          `json:"tokens"`
+		Platform int               `json:"platform"`
+		Title    string            `json:"title"`
+		Message  string            `json:"message"`
+		Priority string            `json:"priority"`
+		Data     map[string]string `json:"data"`
+	}
+
+	var body struct {
+		Notifications []notification `json:"notifications"`
+	}
+	byPlatform := map[int]int{}
+	for _, d := range ds {
+		p := platforms[d.Platform]
+		i, ok := byPlatform[p]
+		if !ok {
+			i = len(body.Notifications)
+			byPlatform[p] = i
+			body.Notifications = append(body.Notifications, notification{
+				Platform: p,
+				Title:    "Urgent message",
+				Message:  b.Message,
+				Priority: "high",
+				Data:     map[string]string{"broadcast_id": b.ID},
+			})
+		}
+		body.Notifications[i].Tokens = append(body.Notifications[i].Tokens, d.Token)
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return errors.Wrap(err, "encoding notifications")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.url+"/api/push", bytes.NewReader(data))
+	if err != nil {
+		return errors.Wrap(err, "creating request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "pushing notifications")
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.Errorf("pushing notifications: unexpected status %s", resp.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package push
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/remisb/restaurant/internal/broadcast"
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// TestSend validates the broadcasts are pushed through the gateway with a
+// notification per platform.
+func TestSend(t *testing.T) {
+	var got struct {
+		Notifications []struct {
+			Tokens   []string          `json:"tokens"`
+			Platform int               `json:"platform"`
+			Message  string            `json:"message"`
+			Data     map[string]string `json:"data"`
+		} `json:"notifications"`
+	}
+	var path string
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	g := NewGateway(nil, srv.URL+"/", 5*time.Second)
+	b := broadcast.Broadcast{ID: "a2b0639f-2cc6-44b8-b97b-15d69dbb511e", Message: "Leave the cafeteria"}
+	devices := []Device{
+		{Token: "phone", Platform: PlatformIOS},
+		{Token: "tablet", Platform: PlatformAndroid},
+		{Token: "pad", Platform: PlatformIOS},
+	}
+
+	t.Log("Given the need to push urgent messages to mobile devices.")
+	{
+		t.Log("\tTest 0:\tWhen the member has devices on both platforms.")
+		{
+			if err := g.send(context.Background(), devices, b); err != nil {
+				t.Fatalf("\t%s\tShould push the broadcast : %s.", tests.Failed, err)
+			}
+			if path != "/api/push" {
+				t.Fatalf("\t%s\tShould post to the push endpoint : got %s.", tests.Failed, path)
+			}
+			t.Logf("\t%s\tShould push the broadcast.", tests.Success)
+
+			if len(got.Notifications) != 2 {
+				t.Fatalf("\t%s\tShould send a notification per platform : got %+v.", tests.Failed, got.Notifications)
+			}
+			ios, android := got.Notifications[0], got.Notifications[1]
+			if ios.Platform != 1 || len(ios.Tokens) != 2 || ios.Tokens[1] != "pad" || android.Platform != 2 || android.Tokens[0] != "tablet" {
+				t.Fatalf("\t%s\tShould send a notification per platform : got %+v.", tests.Failed, got.Notifications)
+			}
+			t.Logf("\t%s\tShould send a notification per platform.", tests.Success)
+
+			if ios.Message != b.Message || ios.Data["broadcast_id"] != b.ID {
+				t.Fatalf("\t%s\tShould carry the broadcast : got %+v.", tests.Failed, ios)
+			}
+			t.Logf("\t%s\tShould carry the broadcast.", tests.Success)
+		}
+
+		t.Log("\tTest 1:\tWhen the gateway fails.")
+		{
+			status = http.StatusInternalServerError
+			if err := g.send(context.Background(), devices, b); err == nil {
+				t.Fatalf("\t%s\tShould fail the delivery.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould fail the delivery.", tests.Success)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+// Package slack posts the lunch of the day to a Slack channel through an
+// incoming webhook: the menus when voting opens and the winner once it
+// closes.
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/organization"
+	"github.com/remisb/restaurant/internal/vote"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+)
+
+// Slack posts to the incoming webhook at its URL. It is both a vote.Opener
+// and a vote.Announcer.
+type Slack struct {
+	db     *sqlx.DB
+	url    string
+	client *http.Client
+}
+
+// New constructs a Slack posting to the incoming webhook at the url.
+func New(db *sqlx.DB, url string, timeout time.Duration) *Slack {
+	return &Slack{
+		db:  db,
+		url: url,
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
+		},
+	}
+}
+
+// Open implements the vote.Opener interface by posting the menus of the date.
+func (s *Slack) Open(ctx context.Context, date time.Time) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.notify.slack.Open")
+	defer span.End()
+
+	var menus []struct {
+		Restaurant string `db:"name"`
+		Menu       string `db:"menu"`
+	}
+	const q = `SELECT r.name, m.menu FROM menu AS m
+		JOIN restaurant AS r ON r.restaurant_id = m.restaurant_id
+		WHERE m.date = $1 AND m.deleted_at IS NULL AND r.deleted_at IS NULL
+		ORDER BY r.name`
+	if err := s.db.SelectContext(ctx, &menus, q, date); err != nil {
+		return errors.Wrap(err, "selecting menus")
+	}
+
+	// Nothing is posted on days without menus, like weekends.
+	if len(menus) == 0 {
+		return nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Voting for lunch on %s is open. Today's menus:", date.Format("Monday, January 2"))
+	for _, m := range menus {
+		fmt.Fprintf(&b, "\n• *%s*: %s", m.Restaurant, m.Menu)
+	}
+
+	return s.post(ctx, b.String())
+}
+
+// Channel implements the vote.Announcer interface.
+func (s *Slack) Channel() string {
+	return organization.ChannelSlack
+}
+
+// Announce implements the vote.Announcer interface by posting the winner.
+func (s *Slack) Announce(ctx context.Context, w vote.Winner) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.notify.slack.Announce")
+	defer span.End()
+
+	var name string
+	const q = `SELECT name FROM restaurant WHERE restaurant_id = $1`
+	if err := s.db.GetContext(ctx, &name, q, w.RestaurantID); err != nil {
+		return errors.Wrap(err, "selecting restaurant name")
+	}
+
+	text := fmt.Sprintf("*%s* won the vote for lunch on %s with %d votes.", name, w.Date.Format("Monday, January 2"), w.Votes)
+	return s.post(ctx, text)
+}
+
+// post sends the text as a message to the webhook.
+func (s *Slack) post(ctx context.Context, text string) error {
+	body, err := json.Marshal(struct {
+		Text string `json:"text"`
+	}{text})
+	if err != nil {
+		return errors.Wrap(err, "encoding message")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "creating request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "posting message")
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.Errorf("posting message: unexpected status %s", resp.Status)
+	}
+
+	return nil
+}
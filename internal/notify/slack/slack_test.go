@@ -0,0 +1,55 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// TestPost validates messages are posted to the incoming webhook.
+func TestPost(t *testing.T) {
+	t.Log("Given the need to post messages to Slack.")
+	{
+		var got struct {
+			Text string `json:"text"`
+		}
+		status := http.StatusOK
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(status)
+		}))
+		defer srv.Close()
+
+		s := New(nil, srv.URL, time.Second)
+
+		t.Log("\tTest 0:\tWhen the webhook accepts the message.")
+		{
+			if err := s.post(context.Background(), "hello"); err != nil {
+				t.Fatalf("\t%s\tShould be able to post the message : %s.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to post the message.", tests.Success)
+
+			if got.Text != "hello" {
+				t.Fatalf("\t%s\tShould send the text : got %q.", tests.Failed, got.Text)
+			}
+			t.Logf("\t%s\tShould send the text.", tests.Success)
+		}
+
+		t.Log("\tTest 1:\tWhen the webhook rejects the message.")
+		{
+			status = http.StatusNotFound
+			if err := s.post(context.Background(), "hello"); err == nil {
+				t.Fatalf("\t%s\tShould fail on an unexpected status.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould fail on an unexpected status.", tests.Success)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package order
+
+import "time"
+
+// These are the states of an Order. An order is placed by a user, confirmed
+// by the restaurant and delivered, or cancelled on the way.
+const (
+	StatusPlaced    = "PLACED"
+	StatusConfirmed = "CONFIRMED"
+	StatusDelivered = "DELIVERED"
+	StatusCancelled = "CANCELLED"
+)
+
+// transitions are the states an order may move to from each state.
+var transitions = map[string][]string{
+	StatusPlaced:    {StatusConfirmed, StatusCancelled},
+	StatusConfirmed: {StatusDelivered, StatusCancelled},
+}
+
+// CanTransition reports if an order may move from one state to the other.
+func CanTransition(from, to string) bool {
+	for _, s := range transitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Order is a user's pre-order from the menu of the restaurant which won the
+// vote for a date. Prices are in cents and Total is net of the Discount of
+// the coupon applied to the order.
+type Order struct {
+	ID           string    `db:"order_id" json:"id"`
+	MenuID       string    `db:"menu_id" json:"menu_id"`
+	RestaurantID string    `db:"restaurant_id" json:"restaurant_id"`
+	UserID       string    `db:"user_id" json:"user_id"`
+	Date         time.Time `db:"date" json:"date"`
+	Status       string    `db:"status" json:"status"`
+	Total        int       `db:"total" json:"total"`
+	Discount     int       `db:"discount" json:"discount"`
+	Items        []Item    `db:"-" json:"items"`
+	DateCreated  time.Time `db:"date_created" json:"date_created"`
+	DateUpdated  time.Time `db:"date_updated" json:"date_updated"`
+}
+
+// Item is a dish of an Order. The menu is free text so the name and price
+// are the ones the user read from it.
+type Item struct {
+	OrderID   string `db:"order_id" json:"-"`
+	Position  int    `db:"position" json:"-"`
+	Name      string `db:"name" json:"name"`
+	Quantity  int    `db:"quantity" json:"quantity"`
+	UnitPrice int    `db:"unit_price" json:"unit_price"`
+}
+
+// NewOrder is what we require from users when placing an Order.
+type NewOrder struct {
+	Items  []NewItem `json:"items" validate:"required,min=1,dive"`
+	Coupon string    `json:"coupon" validate:"omitempty,alphanum,max=32"`
+}
+
+// NewItem is a dish of a NewOrder.
+type NewItem struct {
+	Name      string `json:"name" validate:"required"`
+	Quantity  int    `json:"quantity" validate:"required,min=1"`
+	UnitPrice int    `json:"unit_price" validate:"min=0"`
+}
+
+// UpdateStatus is what we require from restaurant owners to move an Order
+// to another state.
+type UpdateStatus struct {
+	Status string `json:"status" validate:"required,oneof=CONFIRMED DELIVERED CANCELLED"`
+}
+
+// Policy bounds when orders may be placed and cancelled. Ordering for a date
+// closes at Cutoff on that day.
+type Policy struct {
+	Cutoff time.Duration
+}
+
+// Check returns ErrCutoff when ordering for the date has closed by now.
+func (p Policy) Check(date, now time.Time) error {
+	if !now.UTC().Before(date.Add(p.Cutoff)) {
+		return ErrCutoff
+	}
+	return nil
+}
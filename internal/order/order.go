@@ -0,0 +1,322 @@
+// Package order lets users pre-order from the menu of the restaurant which
+// won the daily vote and the restaurant owners follow the orders up.
+package order
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/authz"
+	"github.com/remisb/restaurant/internal/coupon"
+	"github.com/remisb/restaurant/internal/notification"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/database"
+	"go.opentelemetry.io/otel"
+)
+
+// Predefined errors identify expected failure conditions.
+var (
+	// ErrNotFound is used when a specific Order is requested but does not exist.
+	ErrNotFound = errors.New("Order not found")
+
+	// ErrMenuNotFound is used when ordering from a menu which does not exist
+	// or is not a menu of the restaurant.
+	ErrMenuNotFound = errors.New("Menu not found")
+
+	// ErrInvalidID is used when an invalid UUID is provided.
+	ErrInvalidID = errors.New("ID is not in its proper form")
+
+	// ErrNotWinner occurs when ordering from a restaurant which did not win
+	// the vote for the date of the menu, or before the winner is known.
+	ErrNotWinner = errors.New("Restaurant did not win the vote for this date")
+
+	// ErrCutoff occurs when ordering or cancelling after the cutoff.
+	ErrCutoff = errors.New("Ordering has closed for this date")
+
+	// ErrForbidden occurs when a user tries to change an order which is not
+	// theirs.
+	ErrForbidden = errors.New("Attempted action is not allowed")
+
+	// ErrTransition occurs when an order cannot move to the requested state.
+	ErrTransition = errors.New("Order cannot move to this status")
+)
+
+// Place stores the order of the user from the menu. The menu must belong to
+// the restaurant, the restaurant must have won the vote of the organization
+// or of a team of the user for the date of the menu and the cutoff of the
+// date must not have passed. The coupon of the order, if any, is redeemed
+// along with it.
+func Place(ctx context.Context, db *sqlx.DB, user auth.Claims, restaurantID, menuID string, no NewOrder, policy Policy, now time.Time) (*Order, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.order.Place")
+	defer span.End()
+
+	if _, err := uuid.Parse(restaurantID); err != nil {
+		return nil, ErrInvalidID
+	}
+	if _, err := uuid.Parse(menuID); err != nil {
+		return nil, ErrInvalidID
+	}
+
+	var menu struct {
+		RestaurantID string    `db:"restaurant_id"`
+		Date         time.Time `db:"date"`
+	}
+	const qm = `SELECT restaurant_id, date FROM menu WHERE menu_id = $1 AND deleted_at IS NULL`
+	if err := sqlx.GetContext(ctx, database.Conn(ctx, db), &menu, qm, menuID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrMenuNotFound
+		}
+		return nil, errors.Wrapf(err, "selecting menu %s", menuID)
+	}
+	if menu.RestaurantID != restaurantID {
+		return nil, ErrMenuNotFound
+	}
+
+	if err := policy.Check(menu.Date, now); err != nil {
+		return nil, err
+	}
+
+	// The restaurant may have won the vote of the organization or of one of
+	// the teams of the user.
+	var won bool
+	const qw = `SELECT EXISTS (
+		SELECT 1 FROM winner WHERE date = $1 AND org_id = $2 AND restaurant_id = $3
+		UNION ALL
+		SELECT 1 FROM team_winner AS w
+		JOIN team_member AS m ON m.team_id = w.team_id
+		WHERE w.date = $1 AND m.user_id = $4 AND w.restaurant_id = $3)`
+	if err := sqlx.GetContext(ctx, database.Conn(ctx, db), &won, qw, menu.Date, user.Org(), restaurantID, user.Subject); err != nil {
+		return nil, errors.Wrap(err, "selecting winner")
+	}
+	if !won {
+		return nil, ErrNotWinner
+	}
+
+	o := Order{
+		ID:           uuid.New().String(),
+		MenuID:       menuID,
+		RestaurantID: restaurantID,
+		UserID:       user.Subject,
+		Date:         menu.Date,
+		Status:       StatusPlaced,
+		Items:        make([]Item, len(no.Items)),
+		DateCreated:  now.UTC(),
+		DateUpdated:  now.UTC(),
+	}
+	for i, ni := range no.Items {
+		o.Items[i] = Item{
+			OrderID:   o.ID,
+			Position:  i,
+			Name:      ni.Name,
+			Quantity:  ni.Quantity,
+			UnitPrice: ni.UnitPrice,
+		}
+		o.Total += ni.Quantity * ni.UnitPrice
+	}
+
+	tx, err := database.Begin(ctx, db)
+	if err != nil {
+		return nil, errors.Wrap(err, "beginning transaction")
+	}
+	defer tx.Rollback()
+
+	if no.Coupon != "" {
+		d, err := coupon.Redeem(ctx, tx, restaurantID, no.Coupon, user.Subject, o.ID, o.Total, now)
+		if err != nil {
+			return nil, err
+		}
+		o.Discount = d
+		o.Total -= d
+	}
+
+	const qo = `INSERT INTO orders
+		(order_id, menu_id, restaurant_id, user_id, date, status, total, discount, date_created, date_updated)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+	if _, err := tx.ExecContext(ctx, qo, o.ID, o.MenuID, o.RestaurantID, o.UserID, o.Date, o.Status, o.Total, o.Discount, o.DateCreated, o.DateUpdated); err != nil {
+		return nil, errors.Wrap(err, "inserting order")
+	}
+
+	const qi = `INSERT INTO order_item
+		(order_id, position, name, quantity, unit_price)
+		VALUES ($1, $2, $3, $4, $5)`
+	for _, it := range o.Items {
+		if _, err := tx.ExecContext(ctx, qi, it.OrderID, it.Position, it.Name, it.Quantity, it.UnitPrice); err != nil {
+			return nil, errors.Wrap(err, "inserting order item")
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrap(err, "committing order")
+	}
+
+	return &o, nil
+}
+
+// Retrieve finds the order identified by a given ID along with its items.
+func Retrieve(ctx context.Context, db *sqlx.DB, id string) (*Order, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.order.Retrieve")
+	defer span.End()
+
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrInvalidID
+	}
+
+	var o Order
+	const q = `SELECT * FROM orders WHERE order_id = $1`
+	if err := sqlx.GetContext(ctx, database.Conn(ctx, db), &o, q, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, errors.Wrapf(err, "selecting order %q", id)
+	}
+
+	orders := []Order{o}
+	if err := withItems(ctx, db, orders); err != nil {
+		return nil, err
+	}
+
+	return &orders[0], nil
+}
+
+// List returns the orders of the restaurant for the date along with their
+// items, in the order they were placed.
+func List(ctx context.Context, db *sqlx.DB, restaurantID string, date time.Time) ([]Order, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.order.List")
+	defer span.End()
+
+	if _, err := uuid.Parse(restaurantID); err != nil {
+		return nil, ErrInvalidID
+	}
+
+	orders := []Order{}
+	const q = `SELECT * FROM orders WHERE restaurant_id = $1 AND date = $2 ORDER BY date_created`
+	if err := sqlx.SelectContext(ctx, database.Conn(ctx, db), &orders, q, restaurantID, date); err != nil {
+		return nil, errors.Wrap(err, "selecting orders")
+	}
+
+	if err := withItems(ctx, db, orders); err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+// Cancel cancels the order of the user. Only orders which were not confirmed
+// yet may be cancelled and only before the cutoff of their date.
+func Cancel(ctx context.Context, db *sqlx.DB, user auth.Claims, id string, policy Policy, now time.Time) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.order.Cancel")
+	defer span.End()
+
+	o, err := Retrieve(ctx, db, id)
+	if err != nil {
+		return err
+	}
+
+	if !authz.Allowed(user, authz.Update, authz.Resource{Kind: authz.Order, Owner: o.UserID}) {
+		return ErrForbidden
+	}
+	if o.Status != StatusPlaced {
+		return ErrTransition
+	}
+	if err := policy.Check(o.Date, now); err != nil {
+		return err
+	}
+
+	return setStatus(ctx, database.Conn(ctx, db), o, StatusCancelled, now)
+}
+
+// ChangeStatus moves the order to another state. The user who placed it is
+// notified when it is confirmed. Checking the caller may follow the orders of
+// the restaurant up is left to the caller.
+func ChangeStatus(ctx context.Context, db *sqlx.DB, id string, us UpdateStatus, now time.Time) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.order.ChangeStatus")
+	defer span.End()
+
+	o, err := Retrieve(ctx, db, id)
+	if err != nil {
+		return err
+	}
+
+	if !CanTransition(o.Status, us.Status) {
+		return ErrTransition
+	}
+
+	tx, err := database.Begin(ctx, db)
+	if err != nil {
+		return errors.Wrap(err, "beginning transaction")
+	}
+	defer tx.Rollback()
+
+	if err := setStatus(ctx, tx, o, us.Status, now); err != nil {
+		return err
+	}
+
+	if us.Status == StatusConfirmed {
+		o.Status = us.Status
+		if err := notification.OrderConfirmed(ctx, tx, o.UserID, o.RestaurantID, o, now); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, "committing order status")
+	}
+
+	return nil
+}
+
+// setStatus stores the new state of the order. The current state in the
+// WHERE clause catches orders changed since they were read.
+func setStatus(ctx context.Context, db sqlx.ExecerContext, o *Order, status string, now time.Time) error {
+	const q = `UPDATE orders SET
+		"status" = $3,
+		"date_updated" = $4
+		WHERE order_id = $1 AND status = $2`
+	res, err := db.ExecContext(ctx, q, o.ID, o.Status, status, now.UTC())
+	if err != nil {
+		return errors.Wrapf(err, "updating order %s", o.ID)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrapf(err, "updating order %s", o.ID)
+	}
+	if n == 0 {
+		return ErrTransition
+	}
+
+	return nil
+}
+
+// withItems loads the items of the orders.
+func withItems(ctx context.Context, db *sqlx.DB, orders []Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(orders))
+	index := make(map[string]int, len(orders))
+	for i, o := range orders {
+		ids[i] = o.ID
+		index[o.ID] = i
+		orders[i].Items = []Item{}
+	}
+
+	items := []Item{}
+	const q = `SELECT * FROM order_item WHERE order_id = ANY($1) ORDER BY order_id, position`
+	if err := sqlx.SelectContext(ctx, database.Conn(ctx, db), &items, q, pq.Array(ids)); err != nil {
+		return errors.Wrap(err, "selecting order items")
+	}
+
+	for _, it := range items {
+		i := index[it.OrderID]
+		orders[i].Items = append(orders[i].Items, it)
+	}
+
+	return nil
+}
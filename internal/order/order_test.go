@@ -0,0 +1,64 @@
+package order
+
+import (
+	"testing"
+	"time"
+
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// TestCanTransition validates the states an order may move to.
+func TestCanTransition(t *testing.T) {
+	tt := []struct {
+		from, to string
+		want     bool
+	}{
+		{StatusPlaced, StatusConfirmed, true},
+		{StatusPlaced, StatusCancelled, true},
+		{StatusPlaced, StatusDelivered, false},
+		{StatusConfirmed, StatusDelivered, true},
+		{StatusConfirmed, StatusCancelled, true},
+		{StatusConfirmed, StatusPlaced, false},
+		{StatusDelivered, StatusCancelled, false},
+		{StatusCancelled, StatusConfirmed, false},
+	}
+
+	t.Log("Given the need to follow orders up.")
+	{
+		for i, tc := range tt {
+			t.Logf("\tTest %d:\tWhen moving an order from %s to %s.", i, tc.from, tc.to)
+			if got := CanTransition(tc.from, tc.to); got != tc.want {
+				t.Fatalf("\t%s\tShould get %v : got %v.", tests.Failed, tc.want, got)
+			}
+			tests.LogSuccess(t, "Should get the expected result.")
+		}
+	}
+}
+
+// TestPolicyCheck validates ordering closes at the cutoff of the date.
+func TestPolicyCheck(t *testing.T) {
+	date := time.Date(2020, time.March, 2, 0, 0, 0, 0, time.UTC)
+	p := Policy{Cutoff: 11*time.Hour + 30*time.Minute}
+
+	tt := []struct {
+		name string
+		now  time.Time
+		want error
+	}{
+		{"the day before", date.Add(-time.Hour), nil},
+		{"before the cutoff", date.Add(11 * time.Hour), nil},
+		{"at the cutoff", date.Add(p.Cutoff), ErrCutoff},
+		{"the day after", date.AddDate(0, 0, 1), ErrCutoff},
+	}
+
+	t.Log("Given the need to close ordering at the cutoff.")
+	{
+		for i, tc := range tt {
+			t.Logf("\tTest %d:\tWhen ordering %s.", i, tc.name)
+			if got := p.Check(date, tc.now); got != tc.want {
+				t.Fatalf("\t%s\tShould get %v : got %v.", tests.Failed, tc.want, got)
+			}
+			tests.LogSuccess(t, "Should get the expected result.")
+		}
+	}
+}
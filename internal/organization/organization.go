@@ -0,0 +1,127 @@
+// Package organization manages the companies served by a deployment. The
+// users, restaurants, menus and votes of an organization are only visible to
+// its members.
+package organization
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+)
+
+// Predefined errors identify expected failure conditions.
+var (
+	// ErrNotFound is used when a specific Organization is requested but does
+	// not exist.
+	ErrNotFound = errors.New("Organization not found")
+
+	// ErrInvalidID is used when an invalid UUID is provided.
+	ErrInvalidID = errors.New("ID is not in its proper form")
+
+	// ErrUserNotFound is used when moving a user who does not exist.
+	ErrUserNotFound = errors.New("User not found")
+
+	// ErrForbidden occurs when someone other than an admin of the default
+	// organization, which runs the deployment, manages the organizations.
+	ErrForbidden = errors.New("Attempted action is not allowed")
+)
+
+// Organization is a company whose members vote for lunch together.
+type Organization struct {
+	ID          string    `db:"org_id" json:"id"`
+	Name        string    `db:"name" json:"name"`
+	DateCreated time.Time `db:"date_created" json:"date_created"`
+}
+
+// NewOrganization is what we require to create an Organization.
+type NewOrganization struct {
+	Name string `json:"name" validate:"required"`
+}
+
+// Create adds an organization.
+func Create(ctx context.Context, db *sqlx.DB, no NewOrganization, now time.Time) (*Organization, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.organization.Create")
+	defer span.End()
+
+	o := Organization{
+		ID:          uuid.New().String(),
+		Name:        no.Name,
+		DateCreated: now.UTC(),
+	}
+
+	const q = `INSERT INTO organization (org_id, name, date_created) VALUES ($1, $2, $3)`
+	if _, err := db.ExecContext(ctx, q, o.ID, o.Name, o.DateCreated); err != nil {
+		return nil, errors.Wrap(err, "inserting organization")
+	}
+
+	return &o, nil
+}
+
+// Retrieve finds the organization identified by a given ID.
+func Retrieve(ctx context.Context, db *sqlx.DB, id string) (*Organization, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.organization.Retrieve")
+	defer span.End()
+
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrInvalidID
+	}
+
+	var o Organization
+	const q = `SELECT * FROM organization WHERE org_id = $1`
+	if err := db.GetContext(ctx, &o, q, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, errors.Wrapf(err, "selecting organization %q", id)
+	}
+
+	return &o, nil
+}
+
+// List returns every organization, oldest first.
+func List(ctx context.Context, db *sqlx.DB) ([]Organization, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.organization.List")
+	defer span.End()
+
+	orgs := []Organization{}
+	const q = `SELECT * FROM organization ORDER BY date_created`
+	if err := db.SelectContext(ctx, &orgs, q); err != nil {
+		return nil, errors.Wrap(err, "selecting organizations")
+	}
+
+	return orgs, nil
+}
+
+// Move makes the user a member of the organization. The votes the user cast
+// stay with the organization they were cast in.
+func Move(ctx context.Context, db *sqlx.DB, userID, id string, now time.Time) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.organization.Move")
+	defer span.End()
+
+	if _, err := uuid.Parse(userID); err != nil {
+		return ErrInvalidID
+	}
+	if _, err := Retrieve(ctx, db, id); err != nil {
+		return err
+	}
+
+	const q = `UPDATE users SET
+		"org_id" = $2,
+		"date_updated" = $3,
+		"version" = version + 1
+		WHERE user_id = $1 AND deleted_at IS NULL`
+	res, err := db.ExecContext(ctx, q, userID, id, now.UTC())
+	if err != nil {
+		return errors.Wrapf(err, "moving user %s", userID)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,228 @@
+package organization
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/featureflag"
+	"github.com/remisb/restaurant/internal/platform/database"
+	"go.opentelemetry.io/otel"
+)
+
+// These are the voting modes of an organization. Its members vote either for
+// a restaurant or for one menu item of each category.
+const (
+	VotingRestaurant = "restaurant"
+	VotingDish       = "dish"
+)
+
+// These are the ways a tie for the winner is broken. The restaurant voted for
+// first wins, or one of the tied restaurants is drawn by the date so the draw
+// gives the same result every time it is made.
+const (
+	TieFirstVote = "first_vote"
+	TieRandom    = "random"
+)
+
+// These are the channels the winner is announced on.
+const (
+	ChannelEmail    = "email"
+	ChannelSlack    = "slack"
+	ChannelTelegram = "telegram"
+	ChannelInApp    = "in_app"
+)
+
+// ErrInvalidSettings is used when the deadline is not formatted as HH:MM or
+// the timezone is unknown.
+var ErrInvalidSettings = errors.New("Deadline must be formatted as HH:MM and timezone must be a known time zone")
+
+// Settings are the business rules of an organization, defaulting to those of
+// the deployment. An organization which never changed them has the defaults.
+// AnonymousVotes hides who voted for what from the results, only leaving the
+// counts. VoteDeadline is the time of day, in Timezone, voting closes at,
+// blank for the deadline of the deployment. A restaurant which won the last
+// StreakLimit lunches cannot win the next one, no limit applies when it is 0.
+type Settings struct {
+	OrgID          string         `db:"org_id" json:"org_id"`
+	VotingMode     string         `db:"voting_mode" json:"voting_mode"`
+	AnonymousVotes bool           `db:"anonymous_votes" json:"anonymous_votes"`
+	VoteDeadline   string         `db:"vote_deadline" json:"vote_deadline"`
+	Timezone       string         `db:"timezone" json:"timezone"`
+	StreakLimit    int            `db:"streak_limit" json:"streak_limit"`
+	TieBreak       string         `db:"tie_break" json:"tie_break"`
+	Channels       pq.StringArray `db:"notification_channels" json:"notification_channels"`
+	DateUpdated    time.Time      `db:"date_updated" json:"date_updated"`
+}
+
+// Deadline returns the time after midnight voting closes at, false when the
+// deadline of the deployment applies.
+func (s Settings) Deadline() (time.Duration, bool) {
+	t, err := time.Parse("15:04", s.VoteDeadline)
+	if err != nil {
+		return 0, false
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, true
+}
+
+// Location returns the time zone of the organization, UTC by default.
+func (s Settings) Location() *time.Location {
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// Today returns the date of now in the time zone of the organization, at
+// midnight UTC like the dates read from the database.
+func (s Settings) Today(now time.Time) time.Time {
+	now = now.In(s.Location())
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// Notifies reports whether the winner is announced on the channel.
+func (s Settings) Notifies(channel string) bool {
+	for _, c := range s.Channels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// UpdateSettings defines what information may be provided to modify the
+// settings. All fields are optional so clients can send just the fields
+// they want changed. A blank deadline or timezone goes back to the default.
+type UpdateSettings struct {
+	VotingMode     *string  `json:"voting_mode" validate:"omitempty,oneof=restaurant dish"`
+	AnonymousVotes *bool    `json:"anonymous_votes"`
+	VoteDeadline   *string  `json:"vote_deadline"`
+	Timezone       *string  `json:"timezone"`
+	StreakLimit    *int     `json:"streak_limit" validate:"omitempty,min=0,max=30"`
+	TieBreak       *string  `json:"tie_break" validate:"omitempty,oneof=first_vote random"`
+	Channels       []string `json:"notification_channels" validate:"omitempty,dive,oneof=email slack telegram in_app"`
+}
+
+// RetrieveSettings gets the settings of the organization.
+func RetrieveSettings(ctx context.Context, db *sqlx.DB, id string) (*Settings, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.organization.RetrieveSettings")
+	defer span.End()
+
+	return LoadSettings(ctx, database.Conn(ctx, db), id)
+}
+
+// LoadSettings gets the settings of the organization, the defaults when it
+// has none stored. The business packages read them with it when applying
+// the rules so changes take effect right away.
+func LoadSettings(ctx context.Context, q sqlx.QueryerContext, id string) (*Settings, error) {
+	s := Settings{
+		OrgID:      id,
+		VotingMode: VotingRestaurant,
+		TieBreak:   TieFirstVote,
+		Channels:   pq.StringArray{ChannelEmail, ChannelSlack, ChannelTelegram, ChannelInApp},
+	}
+	const qs = `SELECT * FROM org_settings WHERE org_id::text = $1`
+	if err := sqlx.GetContext(ctx, q, &s, qs, id); err != nil && err != sql.ErrNoRows {
+		return nil, errors.Wrapf(err, "selecting settings of organization %q", id)
+	}
+
+	return &s, nil
+}
+
+// Today returns the date of now in the time zone of the organization.
+func Today(ctx context.Context, q sqlx.QueryerContext, id string, now time.Time) (time.Time, error) {
+	s, err := LoadSettings(ctx, q, id)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return s.Today(now), nil
+}
+
+// VotingMode returns the voting mode of the organization.
+func VotingMode(ctx context.Context, q sqlx.QueryerContext, id string) (string, error) {
+	s, err := LoadSettings(ctx, q, id)
+	if err != nil {
+		return "", err
+	}
+	return s.VotingMode, nil
+}
+
+// ChangeSettings modifies the settings of the organization. Votes already
+// cast in the previous voting mode are kept and still get their winner.
+// Switching to voting for dishes fails with featureflag.ErrDisabled while
+// the feature is off for the organization.
+func ChangeSettings(ctx context.Context, db *sqlx.DB, id string, us UpdateSettings, now time.Time) (*Settings, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.organization.ChangeSettings")
+	defer span.End()
+
+	if _, err := Retrieve(ctx, db, id); err != nil {
+		return nil, err
+	}
+
+	s, err := LoadSettings(ctx, db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if us.VotingMode != nil {
+		if *us.VotingMode == VotingDish && s.VotingMode != VotingDish {
+			on, err := featureflag.Enabled(ctx, database.Conn(ctx, db), featureflag.DishVoting, id)
+			if err != nil {
+				return nil, err
+			}
+			if !on {
+				return nil, featureflag.ErrDisabled
+			}
+		}
+		s.VotingMode = *us.VotingMode
+	}
+	if us.AnonymousVotes != nil {
+		s.AnonymousVotes = *us.AnonymousVotes
+	}
+	if us.VoteDeadline != nil {
+		if _, err := time.Parse("15:04", *us.VoteDeadline); *us.VoteDeadline != "" && err != nil {
+			return nil, ErrInvalidSettings
+		}
+		s.VoteDeadline = *us.VoteDeadline
+	}
+	if us.Timezone != nil {
+		if _, err := time.LoadLocation(*us.Timezone); err != nil {
+			return nil, ErrInvalidSettings
+		}
+		s.Timezone = *us.Timezone
+	}
+	if us.StreakLimit != nil {
+		s.StreakLimit = *us.StreakLimit
+	}
+	if us.TieBreak != nil {
+		s.TieBreak = *us.TieBreak
+	}
+	if us.Channels != nil {
+		s.Channels = us.Channels
+	}
+	s.DateUpdated = now.UTC()
+
+	const q = `INSERT INTO org_settings
+		(org_id, voting_mode, anonymous_votes, vote_deadline, timezone, streak_limit, tie_break, notification_channels, date_updated)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (org_id) DO UPDATE SET
+		"voting_mode" = EXCLUDED.voting_mode,
+		"anonymous_votes" = EXCLUDED.anonymous_votes,
+		"vote_deadline" = EXCLUDED.vote_deadline,
+		"timezone" = EXCLUDED.timezone,
+		"streak_limit" = EXCLUDED.streak_limit,
+		"tie_break" = EXCLUDED.tie_break,
+		"notification_channels" = EXCLUDED.notification_channels,
+		"date_updated" = EXCLUDED.date_updated`
+	_, err = db.ExecContext(ctx, q, s.OrgID, s.VotingMode, s.AnonymousVotes, s.VoteDeadline, s.Timezone,
+		s.StreakLimit, s.TieBreak, s.Channels, s.DateUpdated)
+	if err != nil {
+		return nil, errors.Wrapf(err, "updating settings of organization %s", id)
+	}
+
+	return s, nil
+}
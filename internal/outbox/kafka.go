@@ -0,0 +1,101 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+)
+
+// Kafka publishes events to a Kafka topic through a Kafka REST Proxy. The
+// ID of the aggregate is the key of the record so all the events of one
+// aggregate land on the same partition and keep their order.
+type Kafka struct {
+	url    string
+	topic  string
+	client *http.Client
+}
+
+// NewKafka constructs a Kafka publisher for the REST Proxy at the URL.
+func NewKafka(proxyURL, topic string) *Kafka {
+	return &Kafka{
+		url:   strings.TrimSuffix(proxyURL, "/"),
+		topic: topic,
+		client: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
+		},
+	}
+}
+
+// Name returns the name of the publisher.
+func (k *Kafka) Name() string {
+	return "kafka"
+}
+
+// Publish produces the events as records of the topic.
+func (k *Kafka) Publish(ctx context.Context, events []Event) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.outbox.Kafka.Publish")
+	defer span.End()
+
+	type record struct {
+		Key   string `json:"key"`
+		Value Event  `json:"value"`
+	}
+
+	var body struct {
+		Records []record `json:"records"`
+	}
+	for _, e := range events {
+		body.Records = append(body.Records, record{Key: e.AggregateID, Value: e})
+	}
+
+	b, err := json.Marshal(body)
+	if err != nil {
+		return errors.Wrap(err, "encoding records")
+	}
+
+	u := fmt.Sprintf("%s/topics/%s", k.url, url.PathEscape(k.topic))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(b))
+	if err != nil {
+		return errors.Wrap(err, "creating request")
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "producing records")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("producing records: unexpected status %s", resp.Status)
+	}
+
+	// The proxy answers 200 even when some records failed; each of them has
+	// an error of its own.
+	var result struct {
+		Offsets []struct {
+			Error string `json:"error"`
+		} `json:"offsets"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return errors.Wrap(err, "decoding produce response")
+	}
+	for i, o := range result.Offsets {
+		if o.Error != "" {
+			return errors.Errorf("producing event %s: %s", events[i].ID, o.Error)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,26 @@
+package outbox
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// These are the types of the domain events recorded in the outbox.
+const (
+	TypeRestaurantCreated = "RestaurantCreated"
+	TypeMenuUpdated       = "MenuUpdated"
+	TypeVoteCast          = "VoteCast"
+	TypeVoteRetracted     = "VoteRetracted"
+)
+
+// Event is a change to the domain recorded in the same transaction as the
+// change itself. Events are published in the order of Seq.
+type Event struct {
+	ID            string          `db:"event_id" json:"id"`
+	Seq           int64           `db:"seq" json:"seq"`
+	Type          string          `db:"type" json:"type"`
+	AggregateID   string          `db:"aggregate_id" json:"aggregate_id"`
+	Payload       json.RawMessage `db:"payload" json:"payload"`
+	DateCreated   time.Time       `db:"date_created" json:"date_created"`
+	DatePublished *time.Time      `db:"date_published" json:"-"`
+}
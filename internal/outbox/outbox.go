@@ -0,0 +1,96 @@
+// Package outbox records domain events in the transaction of the change they
+// describe and publishes them to a message bus afterwards. Consumers never
+// receive a change which was rolled back, without the service writing to the
+// database and the bus separately. A bus which acknowledges the events it
+// stores, like Kafka, delivers every committed change at least once; core
+// NATS keeps nothing, so its subscribers miss the events published while
+// they are away.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+)
+
+// Add records the event of the aggregate in the outbox. It must be given
+// the transaction making the change so the event is only kept when the
+// change is committed.
+func Add(ctx context.Context, tx sqlx.ExecerContext, typ, aggregateID string, data interface{}, now time.Time) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.outbox.Add")
+	defer span.End()
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return errors.Wrapf(err, "encoding %s event", typ)
+	}
+
+	const q = `INSERT INTO outbox
+		(event_id, type, aggregate_id, payload, date_created)
+		VALUES ($1, $2, $3, $4, $5)`
+	if _, err := tx.ExecContext(ctx, q, uuid.New().String(), typ, aggregateID, payload, now.UTC()); err != nil {
+		return errors.Wrapf(err, "inserting %s event", typ)
+	}
+
+	return nil
+}
+
+// pending locks the oldest unpublished events. Events locked by another
+// transaction are skipped so several relays can run side by side.
+func pending(ctx context.Context, tx *sqlx.Tx, limit int) ([]Event, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.outbox.pending")
+	defer span.End()
+
+	events := []Event{}
+	const q = `SELECT * FROM outbox WHERE date_published IS NULL
+		ORDER BY seq LIMIT $1
+		FOR UPDATE SKIP LOCKED`
+	if err := tx.SelectContext(ctx, &events, q, limit); err != nil {
+		return nil, errors.Wrap(err, "selecting pending events")
+	}
+
+	return events, nil
+}
+
+// markPublished records the events were published.
+func markPublished(ctx context.Context, tx *sqlx.Tx, events []Event, now time.Time) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.outbox.markPublished")
+	defer span.End()
+
+	ids := make([]string, len(events))
+	for i, e := range events {
+		ids[i] = e.ID
+	}
+
+	const q = `UPDATE outbox SET date_published = $2 WHERE event_id = ANY($1)`
+	if _, err := tx.ExecContext(ctx, q, pq.Array(ids), now.UTC()); err != nil {
+		return errors.Wrap(err, "marking events published")
+	}
+
+	return nil
+}
+
+// prune deletes the events published before the given time.
+func prune(ctx context.Context, db *sqlx.DB, before time.Time) (int64, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.outbox.prune")
+	defer span.End()
+
+	const q = `DELETE FROM outbox WHERE date_published < $1`
+	res, err := db.ExecContext(ctx, q, before.UTC())
+	if err != nil {
+		return 0, errors.Wrap(err, "pruning published events")
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, errors.Wrap(err, "counting pruned events")
+	}
+
+	return n, nil
+}
@@ -0,0 +1,25 @@
+package outbox
+
+import "context"
+
+// Publisher ships events to a message bus. Publish either delivers every
+// event of the batch in order or returns an error, in which case the batch
+// is retried later and consumers may see some events twice.
+type Publisher interface {
+	Name() string
+	Publish(ctx context.Context, events []Event) error
+}
+
+// Discard is the Publisher used when no message bus is configured. The
+// events are dropped once they are pruned from the outbox.
+type Discard struct{}
+
+// Name returns the name of the publisher.
+func (Discard) Name() string {
+	return "none"
+}
+
+// Publish does nothing.
+func (Discard) Publish(ctx context.Context, events []Event) error {
+	return nil
+}
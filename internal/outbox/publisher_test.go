@@ -0,0 +1,145 @@
+package outbox
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// events are published by the tests.
+var events = []Event{
+	{ID: "e1", Seq: 1, Type: TypeRestaurantCreated, AggregateID: "r1", Payload: json.RawMessage(`{"name":"Pasta"}`), DateCreated: time.Date(2020, 3, 2, 12, 0, 0, 0, time.UTC)},
+	{ID: "e2", Seq: 2, Type: TypeVoteCast, AggregateID: "r1", Payload: json.RawMessage(`{"user_id":"u1"}`), DateCreated: time.Date(2020, 3, 2, 12, 1, 0, 0, time.UTC)},
+}
+
+// TestNATS validates events are published with the NATS client protocol.
+func TestNATS(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	subjects := make(chan []string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		fmt.Fprint(conn, "INFO {\"server_id\":\"test\"}\r\n")
+
+		var got []string
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			f := strings.Fields(line)
+			switch f[0] {
+			case "PUB":
+				n, _ := strconv.Atoi(f[2])
+				r.Discard(n + 2)
+				got = append(got, f[1])
+			case "PING":
+				fmt.Fprint(conn, "PONG\r\n")
+				subjects <- got
+				return
+			}
+		}
+	}()
+
+	t.Log("Given the need to publish events to NATS.")
+	{
+		t.Log("\tTest 0:\tWhen publishing a batch of events.")
+		{
+			pub, err := NewNATS("nats://"+ln.Addr().String(), "restaurant.events")
+			if err != nil {
+				t.Fatalf("\t%s\tShould parse the url : %s.", tests.Failed, err)
+			}
+
+			if err := pub.Publish(context.Background(), events); err != nil {
+				t.Fatalf("\t%s\tShould publish the events : %s.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould publish the events.", tests.Success)
+
+			got := <-subjects
+			want := []string{"restaurant.events.RestaurantCreated", "restaurant.events.VoteCast"}
+			if strings.Join(got, " ") != strings.Join(want, " ") {
+				t.Fatalf("\t%s\tShould publish on the subject of each type : got %v.", tests.Failed, got)
+			}
+			t.Logf("\t%s\tShould publish on the subject of each type.", tests.Success)
+		}
+	}
+}
+
+// TestKafka validates events are produced through the Kafka REST Proxy.
+func TestKafka(t *testing.T) {
+	var keys []string
+	var fail bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/topics/restaurant.events" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		var body struct {
+			Records []struct {
+				Key   string `json:"key"`
+				Value Event  `json:"value"`
+			} `json:"records"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		keys = keys[:0]
+		for _, rec := range body.Records {
+			keys = append(keys, rec.Key)
+		}
+
+		errMsg := "null"
+		if fail {
+			errMsg = `"Leader not available"`
+		}
+		fmt.Fprintf(w, `{"offsets":[{"partition":0,"offset":1,"error":null},{"partition":0,"offset":2,"error":%s}]}`, errMsg)
+	}))
+	defer srv.Close()
+
+	pub := NewKafka(srv.URL+"/", "restaurant.events")
+
+	t.Log("Given the need to publish events to Kafka.")
+	{
+		t.Log("\tTest 0:\tWhen the proxy accepts every record.")
+		{
+			if err := pub.Publish(context.Background(), events); err != nil {
+				t.Fatalf("\t%s\tShould publish the events : %s.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould publish the events.", tests.Success)
+
+			if strings.Join(keys, " ") != "r1 r1" {
+				t.Fatalf("\t%s\tShould key the records by aggregate : got %v.", tests.Failed, keys)
+			}
+			t.Logf("\t%s\tShould key the records by aggregate.", tests.Success)
+		}
+
+		t.Log("\tTest 1:\tWhen the proxy fails a record.")
+		{
+			fail = true
+			if err := pub.Publish(context.Background(), events); err == nil {
+				t.Fatalf("\t%s\tShould report the failure.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould report the failure.", tests.Success)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package outbox
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/platform/job"
+)
+
+const (
+	// batchSize is the most events published at once.
+	batchSize = 100
+
+	// retention is how long published events are kept for troubleshooting.
+	retention = 7 * 24 * time.Hour
+)
+
+// Relay publishes the events of the outbox in the background.
+type Relay struct {
+	log       *log.Logger
+	db        *sqlx.DB
+	pub       Publisher
+	interval  time.Duration
+	tracker   *job.Tracker
+	lastPrune time.Time
+}
+
+// NewRelay constructs a Relay looking for new events every interval.
+func NewRelay(log *log.Logger, db *sqlx.DB, pub Publisher, interval time.Duration) *Relay {
+	return &Relay{
+		log:      log,
+		db:       db,
+		pub:      pub,
+		interval: interval,
+		tracker:  job.NewTracker("outbox_relay"),
+	}
+}
+
+// Status reports the state of the relay to the health check.
+func (r *Relay) Status() job.Status {
+	return r.tracker.Status()
+}
+
+// Run publishes events until the context is canceled.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		r.tick(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// tick publishes batches of events until the outbox is drained and prunes
+// old events once an hour.
+func (r *Relay) tick(ctx context.Context, now time.Time) {
+	for {
+		n, err := r.publish(ctx, now)
+		if err != nil {
+			r.log.Printf("outbox : %s : ERROR : %+v", r.pub.Name(), err)
+			r.tracker.Record(err, now)
+			return
+		}
+		if n < batchSize {
+			break
+		}
+	}
+
+	if now.Sub(r.lastPrune) >= time.Hour {
+		n, err := prune(ctx, r.db, now.Add(-retention))
+		if err != nil {
+			r.log.Printf("outbox : ERROR : %+v", err)
+			r.tracker.Record(err, now)
+			return
+		}
+		if n > 0 {
+			r.log.Printf("outbox : pruned %d published events", n)
+		}
+		r.lastPrune = now
+	}
+
+	r.tracker.Record(nil, now)
+}
+
+// publish sends a batch of pending events and returns how many were sent.
+// The events stay locked until they are marked published so another relay
+// does not send them at the same time.
+func (r *Relay) publish(ctx context.Context, now time.Time) (int, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, errors.Wrap(err, "beginning transaction")
+	}
+	defer tx.Rollback()
+
+	events, err := pending(ctx, tx, batchSize)
+	if err != nil {
+		return 0, err
+	}
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	if err := r.pub.Publish(ctx, events); err != nil {
+		return 0, errors.Wrapf(err, "publishing %d events", len(events))
+	}
+
+	if err := markPublished(ctx, tx, events, now); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, errors.Wrap(err, "committing published events")
+	}
+
+	return len(events), nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/dgrijalva/jwt-go"
 	"github.com/pkg/errors"
+	"time"
 )
 
 // KeyLookupFunc is used to map a JWT key id (kid) to the corresponding public key.
@@ -26,6 +27,7 @@ type Authenticator struct {
 	algorithm        string
 	pubKeyLookupFunc KeyLookupFunc
 	parser           *jwt.Parser
+	clockSkew        time.Duration
 }
 
 // NewAuthenticator creates an *Authenticator for use. It will error if:
@@ -50,8 +52,11 @@ func NewAuthenticator(privateKey *rsa.PrivateKey, activeKID, algorithm string, p
 	// Create the token parser to use. The algorithm used to sign the JWT must be
 	// validated to avoid a critical vulnerability:
 	// https://auth0.com/blog/critical-vulnerabilities-in-json-web-token-libraries/
+	//
+	// The claims are validated by ParseClaims to allow for clock skew.
 	parser := jwt.Parser{
-		ValidMethods: []string{algorithm},
+		ValidMethods:         []string{algorithm},
+		SkipClaimsValidation: true,
 	}
 
 	a := Authenticator{
@@ -65,6 +70,12 @@ func NewAuthenticator(privateKey *rsa.PrivateKey, activeKID, algorithm string, p
 	return &a, nil
 }
 
+// SetClockSkew sets how far the clock of the token issuer may be off from
+// ours. Tokens are accepted for this long after they expire and before they
+// are issued or become valid.
+func (a *Authenticator) SetClockSkew(d time.Duration) {
+	a.clockSkew = d
+}
 
 // GenerateToken generates a signed JWT token string representing the user Claims.
 func (a *Authenticator) GenerateToken(claims Claims) (string, error) {
@@ -125,5 +136,9 @@ func (a *Authenticator) ParseClaims(tokenStr string) (Claims, error) {
 		return Claims{}, errors.New("invalid token")
 	}
 
+	if err := claims.validAt(time.Now(), a.clockSkew); err != nil {
+		return Claims{}, errors.Wrap(err, "parsing token")
+	}
+
 	return claims, nil
 }
@@ -0,0 +1,162 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+const kid = "4754d86b-7a6d-4df5-9c65-224741361492"
+
+// TestParseClaims validates tokens are only accepted when they are signed by
+// our key with the expected algorithm and are valid at this time.
+func TestParseClaims(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := key.Public().(*rsa.PublicKey)
+
+	a, err := auth.NewAuthenticator(key, kid, "RS256", auth.NewSimpleKeyLookupFunc(kid, pub))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.SetClockSkew(30 * time.Second)
+
+	now := time.Now()
+	sign := func(method jwt.SigningMethod, signKey interface{}, keyID string, claims auth.Claims) string {
+		tkn := jwt.NewWithClaims(method, claims)
+		if keyID != "" {
+			tkn.Header["kid"] = keyID
+		}
+		str, err := tkn.SignedString(signKey)
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to sign the token : %v.", tests.Failed, err)
+		}
+		return str
+	}
+	claims := func(issued time.Time, expires time.Duration, roles ...string) auth.Claims {
+		return auth.NewClaims("5cf37266-3473-4006-984f-9325122678b7", roles, issued, expires)
+	}
+
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER})
+
+	tt := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{"valid token", sign(jwt.SigningMethodRS256, key, kid, claims(now, time.Hour, auth.RoleUser)), true},
+		{"token expired within the skew", sign(jwt.SigningMethodRS256, key, kid, claims(now.Add(-time.Hour), time.Hour-10*time.Second, auth.RoleUser)), true},
+		{"token expired beyond the skew", sign(jwt.SigningMethodRS256, key, kid, claims(now.Add(-time.Hour), time.Hour-time.Minute, auth.RoleUser)), false},
+		{"token issued ahead within the skew", sign(jwt.SigningMethodRS256, key, kid, claims(now.Add(10*time.Second), time.Hour, auth.RoleUser)), true},
+		{"token issued ahead beyond the skew", sign(jwt.SigningMethodRS256, key, kid, claims(now.Add(time.Minute), time.Hour, auth.RoleUser)), false},
+		{"token with an unknown role", sign(jwt.SigningMethodRS256, key, kid, claims(now, time.Hour, "ROOT")), false},
+		{"token with a wrong kid", sign(jwt.SigningMethodRS256, key, "other", claims(now, time.Hour, auth.RoleUser)), false},
+		{"token without a kid", sign(jwt.SigningMethodRS256, key, "", claims(now, time.Hour, auth.RoleUser)), false},
+		{"token signed by another key", sign(jwt.SigningMethodRS256, mustKey(t), kid, claims(now, time.Hour, auth.RoleUser)), false},
+		{"token with the none algorithm", sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, kid, claims(now, time.Hour, auth.RoleAdmin)), false},
+		{"token signed with HS256 and the public key", sign(jwt.SigningMethodHS256, pubPEM, kid, claims(now, time.Hour, auth.RoleAdmin)), false},
+		{"token with tampered claims", tamper(t, sign(jwt.SigningMethodRS256, key, kid, claims(now, time.Hour, auth.RoleUser))), false},
+		{"malformed token", "not.a.token", false},
+	}
+
+	t.Log("Given the need to validate tokens.")
+	{
+		for i, tc := range tt {
+			t.Logf("\tTest %d:\tWhen parsing a %s.", i, tc.name)
+			{
+				_, err := a.ParseClaims(tc.token)
+				if tc.valid && err != nil {
+					t.Fatalf("\t%s\tShould accept the token : %v.", tests.Failed, err)
+				}
+				if !tc.valid && err == nil {
+					t.Fatalf("\t%s\tShould reject the token.", tests.Failed)
+				}
+				t.Logf("\t%s\tShould %s the token.", tests.Success, map[bool]string{true: "accept", false: "reject"}[tc.valid])
+			}
+		}
+	}
+}
+
+// mustKey generates a new private key.
+func mustKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// tamper gives the user of the token the admin role without signing it again.
+func tamper(t *testing.T, token string) string {
+	parts := strings.Split(token, ".")
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var claims map[string]interface{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		t.Fatal(err)
+	}
+	claims["roles"] = []string{auth.RoleAdmin}
+
+	payload, err = json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts[1] = base64.RawURLEncoding.EncodeToString(payload)
+
+	return strings.Join(parts, ".")
+}
+
+// TestOrg validates the organization the stores are scoped to.
+func TestOrg(t *testing.T) {
+	const acme = "0b1c9e0e-2f4f-4d36-9f5c-3f5f0d6c1a77"
+
+	t.Log("Given the need to scope the stores to the organization of the user.")
+	{
+		t.Log("\tTest 0:\tWhen the context carries no claims.")
+		{
+			if org := auth.Org(context.Background()); org != "" {
+				t.Fatalf("\t%s\tShould not be scoped : got %q.", tests.Failed, org)
+			}
+			t.Logf("\t%s\tShould not be scoped.", tests.Success)
+		}
+
+		t.Log("\tTest 1:\tWhen the claims carry no organization.")
+		{
+			ctx := context.WithValue(context.Background(), auth.Key, auth.Claims{})
+			if org := auth.Org(ctx); org != auth.DefaultOrg {
+				t.Fatalf("\t%s\tShould be scoped to the default organization : got %q.", tests.Failed, org)
+			}
+			t.Logf("\t%s\tShould be scoped to the default organization.", tests.Success)
+		}
+
+		t.Log("\tTest 2:\tWhen the claims carry an organization.")
+		{
+			ctx := context.WithValue(context.Background(), auth.Key, auth.Claims{OrgID: acme})
+			if org := auth.Org(ctx); org != acme {
+				t.Fatalf("\t%s\tShould be scoped to it : got %q.", tests.Failed, org)
+			}
+			t.Logf("\t%s\tShould be scoped to it.", tests.Success)
+		}
+	}
+}
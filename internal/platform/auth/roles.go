@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"fmt"
 	"github.com/dgrijalva/jwt-go"
 	"github.com/pkg/errors"
@@ -19,9 +20,14 @@ type ctxKey int
 // Key is used to store/retrieve a Claims value from a context.Context.
 const Key ctxKey = 1
 
+// DefaultOrg is the organization of the data created before organizations
+// existed and of the tokens which do not carry one.
+const DefaultOrg = "00000000-0000-0000-0000-000000000001"
+
 // Claims represents the authorization claims transmitted via a JWT.
 type Claims struct {
 	Roles []string `json:"roles"`
+	OrgID string   `json:"org,omitempty"`
 	jwt.StandardClaims
 }
 
@@ -43,6 +49,12 @@ func NewClaims(subject string, roles []string, now time.Time, expires time.Durat
 
 // Valid is called during the parsing of a token.
 func (c Claims) Valid() error {
+	return c.validAt(time.Now(), 0)
+}
+
+// validAt checks the claims at the time now. The time based claims are
+// allowed to be off by skew.
+func (c Claims) validAt(now time.Time, skew time.Duration) error {
 	for _, r := range c.Roles {
 		switch r {
 		case RoleAdmin, RoleUser: // Role is valid.
@@ -50,12 +62,48 @@ func (c Claims) Valid() error {
 			return fmt.Errorf("invalid role %q", r)
 		}
 	}
-	if err := c.StandardClaims.Valid(); err != nil {
-		return errors.Wrap(err, "validating standard claims")
+
+	switch {
+	case !c.VerifyExpiresAt(now.Add(-skew).Unix(), false):
+		return errors.New("validating standard claims: token is expired")
+	case !c.VerifyIssuedAt(now.Add(skew).Unix(), false):
+		return errors.New("validating standard claims: token used before issued")
+	case !c.VerifyNotBefore(now.Add(skew).Unix(), false):
+		return errors.New("validating standard claims: token is not valid yet")
 	}
+
 	return nil
 }
 
+// Org returns the organization the user belongs to.
+func (c Claims) Org() string {
+	if c.OrgID == "" {
+		return DefaultOrg
+	}
+	return c.OrgID
+}
+
+// Org returns the organization of the claims in ctx, which the queries of the
+// stores are scoped to. It is blank when ctx carries no claims, like in the
+// background jobs working across all organizations.
+func Org(ctx context.Context) string {
+	claims, ok := ctx.Value(Key).(Claims)
+	if !ok {
+		return ""
+	}
+	return claims.Org()
+}
+
+// Subject returns the user of the claims in the context. It is blank when
+// the context carries no claims.
+func Subject(ctx context.Context) string {
+	claims, ok := ctx.Value(Key).(Claims)
+	if !ok {
+		return ""
+	}
+	return claims.Subject
+}
+
 // HasRole returns true if the claims has at least one of the provided roles.
 func (c Claims) HasRole(roles ...string) bool {
 	for _, has := range c.Roles {
@@ -0,0 +1,161 @@
+// Package breaker provides a circuit breaker so calls to the database fail
+// fast while it is unreachable instead of piling up until they time out.
+package breaker
+
+import (
+	"context"
+	"database/sql/driver"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/platform/database"
+)
+
+// ErrOpen is returned instead of calling the database while the breaker is
+// open.
+var ErrOpen = errors.New("Database unavailable, try again later")
+
+// The states of a breaker. A closed breaker lets every call through, an open
+// one none and a half-open one a single call probing if the database is back.
+const (
+	StateClosed   = "closed"
+	StateOpen     = "open"
+	StateHalfOpen = "half-open"
+)
+
+// Status describes the state of a breaker.
+type Status struct {
+	State    string     `json:"state"`
+	Failures int        `json:"failures"`
+	Opened   *time.Time `json:"opened,omitempty"`
+}
+
+// Breaker opens after threshold consecutive calls failed because the
+// database was unreachable. Once open it rejects calls for the cooldown, then
+// lets a single call through: the breaker closes when it succeeds and opens
+// again when it fails. It is safe for concurrent use.
+type Breaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	state    string
+	failures int
+	opened   time.Time
+	probing  bool
+}
+
+// New constructs a closed Breaker.
+func New(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+		state:     StateClosed,
+	}
+}
+
+// Do calls fn unless the breaker is open, in which case it returns ErrOpen.
+// The error of fn is returned as is.
+func (b *Breaker) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := b.allow(); err != nil {
+		return err
+	}
+
+	err := fn(ctx)
+	b.record(err)
+	return err
+}
+
+// Status returns the state of the breaker.
+func (b *Breaker) Status() Status {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s := Status{
+		State:    b.state,
+		Failures: b.failures,
+	}
+	if b.state != StateClosed {
+		opened := b.opened
+		s.Opened = &opened
+	}
+	return s
+}
+
+// allow reports if a call may go through.
+func (b *Breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		if b.now().Sub(b.opened) < b.cooldown {
+			return ErrOpen
+		}
+		b.state = StateHalfOpen
+		b.probing = true
+		return nil
+
+	case StateHalfOpen:
+		if b.probing {
+			return ErrOpen
+		}
+		b.probing = true
+		return nil
+	}
+
+	return nil
+}
+
+// record updates the state of the breaker with the outcome of a call.
+func (b *Breaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+
+	if !IsOutage(err) {
+		b.state = StateClosed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == StateHalfOpen || b.failures >= b.threshold {
+		b.state = StateOpen
+		b.opened = b.now()
+	}
+}
+
+// IsOutage reports if the error means the database could not be reached, as
+// opposed to rejecting a query.
+func IsOutage(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	// Connection exceptions and the server shutting down or starting up.
+	if dbErr, ok := database.AsError(err); ok {
+		switch dbErr.Code {
+		case "57P01", "57P02", "57P03":
+			return true
+		}
+		return strings.HasPrefix(dbErr.Code, "08")
+	}
+
+	return false
+}
@@ -0,0 +1,80 @@
+package breaker
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// TestBreaker validates the breaker opens on outages and closes once the
+// database is back.
+func TestBreaker(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2020, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	down := func(ctx context.Context) error { return errors.Wrap(driver.ErrBadConn, "selecting") }
+	up := func(ctx context.Context) error { return nil }
+
+	t.Log("Given the need to fail fast while the database is down.")
+	{
+		b := New(2, time.Minute)
+		b.now = func() time.Time { return now }
+
+		t.Log("\tTest 0:\tWhen queries are rejected by the database.")
+		{
+			rejected := func(ctx context.Context) error { return errors.New("duplicate key") }
+			for i := 0; i < 3; i++ {
+				b.Do(ctx, rejected)
+			}
+			if s := b.Status(); s.State != StateClosed {
+				t.Fatalf("\t%s\tShould stay closed : got %s.", tests.Failed, s.State)
+			}
+			t.Logf("\t%s\tShould stay closed.", tests.Success)
+		}
+
+		t.Log("\tTest 1:\tWhen the database is unreachable.")
+		{
+			b.Do(ctx, down)
+			if s := b.Status(); s.State != StateClosed {
+				t.Fatalf("\t%s\tShould stay closed below the threshold : got %s.", tests.Failed, s.State)
+			}
+			b.Do(ctx, down)
+			if s := b.Status(); s.State != StateOpen {
+				t.Fatalf("\t%s\tShould open at the threshold : got %s.", tests.Failed, s.State)
+			}
+			t.Logf("\t%s\tShould open at the threshold.", tests.Success)
+
+			called := false
+			err := b.Do(ctx, func(ctx context.Context) error { called = true; return nil })
+			if err != ErrOpen || called {
+				t.Fatalf("\t%s\tShould fail fast while open : got %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould fail fast while open.", tests.Success)
+		}
+
+		t.Log("\tTest 2:\tWhen the cooldown is over.")
+		{
+			now = now.Add(time.Minute)
+			if err := b.Do(ctx, down); err == ErrOpen {
+				t.Fatalf("\t%s\tShould let a probe through.", tests.Failed)
+			}
+			if s := b.Status(); s.State != StateOpen {
+				t.Fatalf("\t%s\tShould open again when the probe fails : got %s.", tests.Failed, s.State)
+			}
+			t.Logf("\t%s\tShould open again when the probe fails.", tests.Success)
+
+			now = now.Add(time.Minute)
+			if err := b.Do(ctx, up); err != nil {
+				t.Fatalf("\t%s\tShould let a probe through : %v.", tests.Failed, err)
+			}
+			if s := b.Status(); s.State != StateClosed || s.Failures != 0 {
+				t.Fatalf("\t%s\tShould close when the probe succeeds : got %+v.", tests.Failed, s)
+			}
+			t.Logf("\t%s\tShould close when the probe succeeds.", tests.Success)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+// Package clock tells the time to the handlers and the background jobs, so
+// tests can stop it at the moment they check, like the close of the vote.
+package clock
+
+import "time"
+
+// Clock tells the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+// System is the clock of the system. It tells the time in UTC so the
+// timestamps of the responses are all formatted as RFC 3339 in UTC.
+var System Clock = systemClock{}
+
+// systemClock is the clock of the system.
+type systemClock struct{}
+
+// Now returns the current time in UTC.
+func (systemClock) Now() time.Time {
+	return time.Now().UTC()
+}
+
+// Fixed is a clock stopped at a time.
+type Fixed time.Time
+
+// Now returns the time the clock is stopped at.
+func (f Fixed) Now() time.Time {
+	return time.Time(f)
+}
@@ -0,0 +1,105 @@
+// Package coalesce merges identical concurrent calls into one so a burst of
+// requests for the same data results in a single query to the database.
+package coalesce
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// call is a call in flight or just completed.
+type call struct {
+	wg    sync.WaitGroup
+	val   interface{}
+	err   error
+	dups  int
+	panic interface{}
+}
+
+// Group coalesces calls by key. The zero value is ready to use.
+type Group struct {
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+// Do calls fn unless a call with the same key is in flight, in which case it
+// waits for that call and returns its result. The value is shared by every
+// caller so it must not be modified. Shared reports if the result was handed
+// to more than one caller.
+func (g *Group) Do(key string, fn func() (interface{}, error)) (v interface{}, err error, shared bool) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*call)
+	}
+	if c, ok := g.calls[key]; ok {
+		c.dups++
+		g.mu.Unlock()
+		c.wg.Wait()
+		if c.panic != nil {
+			panic(c.panic)
+		}
+		return c.val, c.err, true
+	}
+
+	c := new(call)
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	g.run(key, c, fn)
+
+	if c.panic != nil {
+		panic(c.panic)
+	}
+	return c.val, c.err, c.dups > 0
+}
+
+// run calls fn and releases the callers waiting for it, even when it panics.
+func (g *Group) run(key string, c *call, fn func() (interface{}, error)) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.panic = r
+		}
+
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		c.wg.Done()
+	}()
+
+	c.val, c.err = fn()
+}
+
+// Detach returns a context carrying the values of ctx but neither its
+// deadline nor its cancellation. A merged call runs with it so it is not cut
+// short when the caller which started it goes away while others still wait
+// for its result.
+func Detach(ctx context.Context) context.Context {
+	return detached{parent: ctx}
+}
+
+// detached is a context keeping only the values of its parent.
+type detached struct {
+	parent context.Context
+}
+
+// Deadline implements the context.Context interface.
+func (detached) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+// Done implements the context.Context interface.
+func (detached) Done() <-chan struct{} {
+	return nil
+}
+
+// Err implements the context.Context interface.
+func (detached) Err() error {
+	return nil
+}
+
+// Value implements the context.Context interface.
+func (d detached) Value(key interface{}) interface{} {
+	return d.parent.Value(key)
+}
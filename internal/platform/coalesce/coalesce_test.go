@@ -0,0 +1,120 @@
+package coalesce
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// TestGroup validates identical concurrent calls are merged.
+func TestGroup(t *testing.T) {
+	t.Log("Given the need to merge identical concurrent calls.")
+	{
+		t.Log("\tTest 0:\tWhen calls with the same key are in flight.")
+		{
+			var g Group
+			var calls int32
+			release := make(chan struct{})
+			started := make(chan struct{})
+
+			fn := func() (interface{}, error) {
+				atomic.AddInt32(&calls, 1)
+				close(started)
+				<-release
+				return "menu", nil
+			}
+
+			const callers = 10
+			var wg sync.WaitGroup
+			results := make(chan interface{}, callers)
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				v, _, _ := g.Do("today", fn)
+				results <- v
+			}()
+			<-started
+
+			for i := 1; i < callers; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					v, _, _ := g.Do("today", fn)
+					results <- v
+				}()
+			}
+
+			// Wait for the callers to join the call in flight.
+			for {
+				g.mu.Lock()
+				dups := g.calls["today"].dups
+				g.mu.Unlock()
+				if dups == callers-1 {
+					break
+				}
+			}
+			close(release)
+			wg.Wait()
+			close(results)
+
+			if n := atomic.LoadInt32(&calls); n != 1 {
+				t.Fatalf("\t%s\tShould call once : got %d calls.", tests.Failed, n)
+			}
+			t.Logf("\t%s\tShould call once.", tests.Success)
+
+			for v := range results {
+				if v != "menu" {
+					t.Fatalf("\t%s\tShould share the result : got %v.", tests.Failed, v)
+				}
+			}
+			t.Logf("\t%s\tShould share the result.", tests.Success)
+		}
+
+		t.Log("\tTest 1:\tWhen a call has completed.")
+		{
+			var g Group
+			var calls int
+			fn := func() (interface{}, error) {
+				calls++
+				return nil, errors.New("down")
+			}
+
+			if _, err, shared := g.Do("today", fn); err == nil || shared {
+				t.Fatalf("\t%s\tShould return the error of its own call : got %v, %v.", tests.Failed, err, shared)
+			}
+			g.Do("today", fn)
+			if calls != 2 {
+				t.Fatalf("\t%s\tShould call again : got %d calls.", tests.Failed, calls)
+			}
+			t.Logf("\t%s\tShould call again.", tests.Success)
+		}
+	}
+}
+
+func TestDetach(t *testing.T) {
+	t.Log("Given the need to run a merged call past the caller which started it.")
+	{
+		t.Log("\tTest 0:\tWhen the context of the caller is canceled.")
+		{
+			type key struct{}
+			ctx, cancel := context.WithCancel(context.WithValue(context.Background(), key{}, "org"))
+			d := Detach(ctx)
+			cancel()
+
+			if err := d.Err(); err != nil {
+				t.Fatalf("\t%s\tShould not be canceled : got %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not be canceled.", tests.Success)
+
+			if v := d.Value(key{}); v != "org" {
+				t.Fatalf("\t%s\tShould keep the values : got %v.", tests.Failed, v)
+			}
+			t.Logf("\t%s\tShould keep the values.", tests.Success)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+// Package conntrack counts the connections accepted by a server and times
+// their TLS handshakes. The counts are published with expvar on the debug
+// listener under "connections".
+package conntrack
+
+import (
+	"crypto/tls"
+	"expvar"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// handshakeBuckets are the upper bounds in milliseconds of the TLS handshake
+// duration histogram.
+var handshakeBuckets = []int64{10, 50, 100, 250, 500, 1000, 5000}
+
+// m contains the connection counters of the application.
+var m = struct {
+	conns     *expvar.Map
+	handshake *expvar.Map
+}{
+	conns:     expvar.NewMap("connections"),
+	handshake: expvar.NewMap("tls_handshake_ms"),
+}
+
+// Listener is a net.Listener which counts the connections it accepts and
+// the connections which are closed again.
+type Listener struct {
+	net.Listener
+}
+
+// NewListener wraps the listener so its connections are counted.
+func NewListener(l net.Listener) *Listener {
+	return &Listener{Listener: l}
+}
+
+// Accept waits for and returns the next connection to the listener.
+func (l *Listener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		m.conns.Add("accept_errors", 1)
+		return nil, err
+	}
+
+	m.conns.Add("accepted", 1)
+	m.conns.Add("active", 1)
+
+	return &conn{Conn: c}, nil
+}
+
+// conn counts itself as closed the first time it is closed.
+type conn struct {
+	net.Conn
+	once sync.Once
+}
+
+// Close closes the connection.
+func (c *conn) Close() error {
+	c.once.Do(func() {
+		m.conns.Add("active", -1)
+		m.conns.Add("closed", 1)
+	})
+	return c.Conn.Close()
+}
+
+// TLSConfig returns a copy of cfg which times the TLS handshake of every
+// connection. Handshakes started but never completed are failed handshakes.
+func TLSConfig(cfg *tls.Config) *tls.Config {
+	base := cfg.Clone()
+
+	// http.Server adds HTTP/2 to its own copy of the config which is not the
+	// one handed out per connection below.
+	if len(base.NextProtos) == 0 {
+		base.NextProtos = []string{"h2", "http/1.1"}
+	}
+
+	timed := cfg.Clone()
+	timed.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		start := time.Now()
+		m.handshake.Add("started", 1)
+
+		c := base
+		if base.GetConfigForClient != nil {
+			alt, err := base.GetConfigForClient(hello)
+			if err != nil {
+				return nil, err
+			}
+			if alt != nil {
+				c = alt
+			}
+		}
+
+		// The config returned here is only used for this connection so the
+		// start of the handshake can be captured by its callback.
+		c = c.Clone()
+		verify := c.VerifyConnection
+		c.VerifyConnection = func(cs tls.ConnectionState) error {
+			if verify != nil {
+				if err := verify(cs); err != nil {
+					return err
+				}
+			}
+			observeHandshake(time.Since(start))
+			return nil
+		}
+
+		return c, nil
+	}
+
+	return timed
+}
+
+// observeHandshake records a completed handshake in the histogram.
+func observeHandshake(d time.Duration) {
+	ms := d.Milliseconds()
+
+	m.handshake.Add("completed", 1)
+	m.handshake.Add("total", ms)
+
+	for _, b := range handshakeBuckets {
+		if ms <= b {
+			m.handshake.Add("le_"+strconv.FormatInt(b, 10), 1)
+			return
+		}
+	}
+	m.handshake.Add("le_inf", 1)
+}
@@ -0,0 +1,55 @@
+package conntrack
+
+import (
+	"expvar"
+	"net"
+	"testing"
+)
+
+// TestListener validates connections are counted when accepted and closed.
+func TestListener(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := NewListener(ln)
+	defer l.Close()
+
+	count := func(key string) int64 {
+		v, ok := m.conns.Get(key).(*expvar.Int)
+		if !ok {
+			return 0
+		}
+		return v.Value()
+	}
+	accepted, active, closed := count("accepted"), count("active"), count("closed")
+
+	t.Log("Given the need to count connections.")
+	{
+		t.Log("\tTest 0:\tWhen a connection is accepted and closed twice.")
+		{
+			go func() {
+				c, err := net.Dial("tcp", ln.Addr().String())
+				if err == nil {
+					c.Close()
+				}
+			}()
+
+			c, err := l.Accept()
+			if err != nil {
+				t.Fatalf("\t✗\tShould accept the connection : %v.", err)
+			}
+			if count("accepted") != accepted+1 || count("active") != active+1 {
+				t.Fatalf("\t✗\tShould count the connection as accepted and active.")
+			}
+			t.Log("\t✓\tShould count the connection as accepted and active.")
+
+			c.Close()
+			c.Close()
+			if count("closed") != closed+1 || count("active") != active {
+				t.Fatalf("\t✗\tShould count the connection as closed once.")
+			}
+			t.Log("\t✓\tShould count the connection as closed once.")
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package database
+
+import (
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+// These are the codes of the constraint violations reported by AsViolation.
+const (
+	codeForeignKeyViolation = "23503"
+	codeUniqueViolation     = "23505"
+)
+
+// Violation is a constraint of the schema violated by a statement, like a
+// value which must be unique or a reference to a row which does not exist.
+type Violation struct {
+	Constraint string
+
+	// Columns are the columns of the constraint as reported by PostgreSQL,
+	// like restaurant_id. They are empty when it does not report them.
+	Columns []string
+
+	// Unique is set for a unique constraint and not for a foreign key.
+	Unique bool
+}
+
+// Error is an error reported by PostgreSQL, whichever driver received it.
+type Error struct {
+	// Code is the SQLSTATE code of the error, like 23505.
+	Code       string
+	Constraint string
+	Detail     string
+}
+
+// AsError returns the error PostgreSQL reported to the lib/pq or the pgx
+// driver, so the callers do not depend on the driver in use.
+func AsError(err error) (Error, bool) {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return Error{Code: string(pqErr.Code), Constraint: pqErr.Constraint, Detail: pqErr.Detail}, true
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return Error{Code: pgErr.Code, Constraint: pgErr.ConstraintName, Detail: pgErr.Detail}, true
+	}
+
+	return Error{}, false
+}
+
+// AsViolation returns the violation of a unique or foreign key constraint
+// the error reports.
+func AsViolation(err error) (Violation, bool) {
+	dbErr, ok := AsError(err)
+	if !ok {
+		return Violation{}, false
+	}
+
+	switch dbErr.Code {
+	case codeUniqueViolation, codeForeignKeyViolation:
+	default:
+		return Violation{}, false
+	}
+
+	v := Violation{
+		Constraint: dbErr.Constraint,
+		Columns:    detailColumns(dbErr.Detail),
+		Unique:     dbErr.Code == codeUniqueViolation,
+	}
+	return v, true
+}
+
+// detailColumns returns the columns of the key of the detail of a violation,
+// as in Key (restaurant_id, field)=(...) already exists.
+func detailColumns(detail string) []string {
+	if !strings.HasPrefix(detail, "Key (") {
+		return nil
+	}
+	end := strings.Index(detail, ")=(")
+	if end < 0 {
+		return nil
+	}
+
+	var columns []string
+	for _, c := range strings.Split(detail[len("Key ("):end], ",") {
+		columns = append(columns, strings.TrimSpace(c))
+	}
+	return columns
+}
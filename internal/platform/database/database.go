@@ -2,21 +2,131 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"database/sql/driver"
+	"expvar"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
 	"github.com/jmoiron/sqlx"
-	"go.opencensus.io/trace"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"log"
 	"net/url"
+	"strconv"
+	"time"
 )
 
+// These are the drivers talking to PostgreSQL.
+const (
+	// DriverPQ is the lib/pq driver, the default.
+	DriverPQ = "postgres"
+
+	// DriverPgx is the pgx driver used through its database/sql adapter. It
+	// sends the parameters in the binary format and caches the statements it
+	// prepares on every connection.
+	DriverPgx = "pgx"
+)
+
+// pgxExecModes are the modes pgx runs the statements in by their name in
+// Config.PgxExecMode.
+var pgxExecModes = map[string]pgx.QueryExecMode{
+	"cache_statement": pgx.QueryExecModeCacheStatement,
+	"cache_describe":  pgx.QueryExecModeCacheDescribe,
+	"describe_exec":   pgx.QueryExecModeDescribeExec,
+	"exec":            pgx.QueryExecModeExec,
+	"simple_protocol": pgx.QueryExecModeSimpleProtocol,
+}
+
 // Config is used to hold the required properties to use database.
 type Config struct {
+	// Driver is DriverPQ when empty, or DriverPgx.
+	Driver string
+
+	// PgxExecMode is the mode pgx runs the statements in, cache_statement
+	// when empty. Behind a pooler like PgBouncer in transaction mode, which
+	// does not keep prepared statements, use describe_exec or
+	// simple_protocol. It is ignored by lib/pq.
+	PgxExecMode string
+
 	User string
 	Password string
 	Host string
 	Name string
 	DisableTLS bool
+
+	// ReadHost is the host of a read-only replica of the database reached
+	// with the same credentials. Empty means there is no replica.
+	ReadHost string
+
+	// MaxOpenConns limits the connections open to the database. Zero means
+	// no limit.
+	MaxOpenConns int
+
+	// MaxIdleConns limits the connections kept idle in the pool. Zero keeps
+	// the default of the database/sql package.
+	MaxIdleConns int
+
+	// ConnMaxLifetime closes connections once they are this old. Zero
+	// reuses connections forever.
+	ConnMaxLifetime time.Duration
+
+	// PasswordFunc returns the password of every new connection in place of
+	// Password, so a password rotated by a secrets backend is picked up
+	// without restarting.
+	PasswordFunc func() string
+
+	// StatementTimeout makes the database cancel the statements running
+	// longer, so a runaway query does not hold a connection of the pool.
+	// Zero leaves them unbounded.
+	StatementTimeout time.Duration
+
+	// SlowQueryThreshold is the duration above which the statements are
+	// logged to Log, without the values of their parameters. Zero or a nil
+	// Log logs nothing. Every statement is traced either way.
+	SlowQueryThreshold time.Duration
+	Log                *log.Logger
 }
+
+// Open knows how to open a database connection based on the configuration.
 func Open(cfg Config) (*sqlx.DB, error) {
+	var c driver.Connector
+	switch cfg.Driver {
+	case "", DriverPQ:
+		cfg.Driver = DriverPQ
+		c = connector{cfg: cfg}
+	case DriverPgx:
+		mode := pgx.QueryExecModeCacheStatement
+		if cfg.PgxExecMode != "" {
+			m, ok := pgxExecModes[cfg.PgxExecMode]
+			if !ok {
+				return nil, errors.Errorf("unknown pgx exec mode %q", cfg.PgxExecMode)
+			}
+			mode = m
+		}
+		c = pgxConnector{cfg: cfg, mode: mode}
+	default:
+		return nil, errors.Errorf("unknown database driver %q", cfg.Driver)
+	}
+
+	tc := tracedConnector{
+		Connector: c,
+		log:       cfg.Log,
+		slow:      cfg.SlowQueryThreshold,
+	}
+	db := sqlx.NewDb(sql.OpenDB(tc), cfg.Driver)
 
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	if cfg.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+
+	return db, nil
+}
+
+// dsn returns the connection string of the database with the password.
+func (cfg Config) dsn(password string) string {
 	sslMode := "rquire"
 	if cfg.DisableTLS {
 		sslMode = "disable"
@@ -25,22 +135,124 @@ func Open(cfg Config) (*sqlx.DB, error) {
 	q := make(url.Values)
 	q.Set("sslmode", sslMode)
 	q.Set("timezone", "utc")
+	if cfg.StatementTimeout > 0 {
+		q.Set("statement_timeout", strconv.FormatInt(cfg.StatementTimeout.Milliseconds(), 10))
+	}
 
 	u := url.URL{
-		Scheme:     "postgres",
-		User:       url.UserPassword(cfg.User, cfg.Password),
-		Host:       cfg.Host,
-		Path:       cfg.Name,
-		RawQuery:   q.Encode(),
+		Scheme:   "postgres",
+		User:     url.UserPassword(cfg.User, password),
+		Host:     cfg.Host,
+		Path:     cfg.Name,
+		RawQuery: q.Encode(),
+	}
+	return u.String()
+}
+
+// password returns the password of a new connection, the one PasswordFunc
+// returns at the time or Password without PasswordFunc.
+func (cfg Config) password() string {
+	if cfg.PasswordFunc != nil {
+		return cfg.PasswordFunc()
 	}
+	return cfg.Password
+}
+
+// connector opens every connection with lib/pq and the current password.
+type connector struct {
+	cfg Config
+}
+
+// Connect implements the driver.Connector interface.
+func (c connector) Connect(ctx context.Context) (driver.Conn, error) {
+	pc, err := pq.NewConnector(c.cfg.dsn(c.cfg.password()))
+	if err != nil {
+		return nil, err
+	}
+	return pc.Connect(ctx)
+}
+
+// Driver implements the driver.Connector interface.
+func (c connector) Driver() driver.Driver {
+	return &pq.Driver{}
+}
+
+// pgxConnector opens every connection with pgx and the current password.
+type pgxConnector struct {
+	cfg  Config
+	mode pgx.QueryExecMode
+}
+
+// Connect implements the driver.Connector interface.
+func (c pgxConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	cc, err := pgx.ParseConfig(c.cfg.dsn(c.cfg.password()))
+	if err != nil {
+		return nil, err
+	}
+	cc.DefaultQueryExecMode = c.mode
+	return stdlib.GetConnector(*cc).Connect(ctx)
+}
+
+// Driver implements the driver.Connector interface.
+func (c pgxConnector) Driver() driver.Driver {
+	return stdlib.GetDefaultDriver()
+}
+
+// maxRetryDelay caps the delay between attempts to reach the database.
+const maxRetryDelay = 5 * time.Second
+
+// OpenAndWait opens the database like Open and waits for it to answer,
+// retrying with exponential backoff until the context is done. It lets the
+// service start before the database is up.
+func OpenAndWait(ctx context.Context, cfg Config) (*sqlx.DB, error) {
+	db, err := Open(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := wait(ctx, db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// wait pings the database until it answers, doubling the delay between
+// attempts up to maxRetryDelay.
+func wait(ctx context.Context, db *sqlx.DB) error {
+	delay := 100 * time.Millisecond
+	for {
+		err := db.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Wrap(err, "waiting for database")
+		case <-time.After(delay):
+		}
+
+		if delay *= 2; delay > maxRetryDelay {
+			delay = maxRetryDelay
+		}
+	}
+}
 
-	return sqlx.Open("postgres", u.String())
+// PublishStats publishes the connection pool statistics of the database with
+// expvar on the debug listener under the provided name. It must be called
+// once per name.
+func PublishStats(name string, db *sqlx.DB) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return db.Stats()
+	}))
 }
 
 // StatusCheck returns nil if it can successfully talk to the database. It
 // returns a non-nil error otherwise.
 func StatusCheck(ctx context.Context, db *sqlx.DB) error {
-	ctx, span := trace.StartSpan(ctx, "platform.DB.StatusCheck")
+	ctx, span := otel.Tracer("").Start(ctx, "platform.DB.StatusCheck")
 	defer span.End()
 
 	// Run a simple query to determine connectivity. The db has a "Ping" method
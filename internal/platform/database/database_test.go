@@ -0,0 +1,169 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+// TestOpenAndWait validates the wait for an unreachable database is bounded
+// by the context.
+func TestOpenAndWait(t *testing.T) {
+	cfg := Config{
+		User:       "postgres",
+		Password:   "postgres",
+		Host:       "127.0.0.1:1",
+		Name:       "postgres",
+		DisableTLS: true,
+	}
+
+	t.Log("Given the need to wait for the database at startup.")
+	{
+		t.Log("\tTest 0:\tWhen the database never answers.")
+		{
+			ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+			defer cancel()
+
+			start := time.Now()
+			db, err := OpenAndWait(ctx, cfg)
+			if err == nil {
+				db.Close()
+				t.Fatal("\t✗\tShould fail to open the database.")
+			}
+			t.Log("\t✓\tShould fail to open the database.")
+
+			if d := time.Since(start); d > 2*time.Second {
+				t.Fatalf("\t✗\tShould give up once the context is done : took %v", d)
+			}
+			t.Log("\t✓\tShould give up once the context is done.")
+		}
+	}
+}
+
+// TestReplica validates reads only go to the replica when there is one.
+func TestReplica(t *testing.T) {
+	primary := sqlx.NewDb(&sql.DB{}, "postgres")
+	replica := sqlx.NewDb(&sql.DB{}, "postgres")
+
+	t.Log("Given the need to route reads to a replica.")
+	{
+		t.Log("\tTest 0:\tWhen there is no replica.")
+		{
+			db := NewDB(primary, nil)
+			if db.Primary() != primary || db.Replica() != primary {
+				t.Fatal("\t✗\tShould send every query to the primary.")
+			}
+			t.Log("\t✓\tShould send every query to the primary.")
+		}
+
+		t.Log("\tTest 1:\tWhen there is a replica.")
+		{
+			db := NewDB(primary, replica)
+			if db.Primary() != primary || db.Replica() != replica {
+				t.Fatal("\t✗\tShould send reads to the replica.")
+			}
+			t.Log("\t✓\tShould send reads to the replica.")
+		}
+
+		t.Log("\tTest 2:\tWhen configuring the replica.")
+		{
+			cfg := Config{Host: "db:5432", ReadHost: "db-replica:5432", Name: "postgres"}
+			rc := cfg.Replica()
+			if rc.Host != cfg.ReadHost || rc.ReadHost != "" || rc.Name != cfg.Name {
+				t.Fatalf("\t✗\tShould connect to the replica host : got %+v", rc)
+			}
+			t.Log("\t✓\tShould connect to the replica host.")
+		}
+	}
+}
+
+// TestTimeouts validates the statements and the calls of the stores are
+// bounded by their timeouts.
+func TestTimeouts(t *testing.T) {
+	t.Log("Given the need to bound the time spent in the database.")
+	{
+		t.Log("\tTest 0:\tWhen configuring a statement timeout.")
+		{
+			cfg := Config{Host: "db:5432", Name: "postgres", StatementTimeout: 30 * time.Second}
+			if dsn := cfg.dsn("secret"); !strings.Contains(dsn, "statement_timeout=30000") {
+				t.Fatalf("\t✗\tShould set the statement timeout in milliseconds : got %s", dsn)
+			}
+			t.Log("\t✓\tShould set the statement timeout in milliseconds.")
+		}
+
+		t.Log("\tTest 1:\tWhen calling a store with a query timeout.")
+		{
+			db := NewDB(sqlx.NewDb(&sql.DB{}, "postgres"), nil)
+			db.SetQueryTimeout(time.Second)
+
+			ctx, cancel := db.Deadline(context.Background())
+			defer cancel()
+			if deadline, ok := ctx.Deadline(); !ok || time.Until(deadline) > time.Second {
+				t.Fatalf("\t✗\tShould end the call after the timeout : got %v", deadline)
+			}
+			t.Log("\t✓\tShould end the call after the timeout.")
+		}
+
+		t.Log("\tTest 2:\tWhen calling a store without a query timeout.")
+		{
+			db := NewDB(sqlx.NewDb(&sql.DB{}, "postgres"), nil)
+
+			ctx, cancel := db.Deadline(context.Background())
+			defer cancel()
+			if _, ok := ctx.Deadline(); ok {
+				t.Fatal("\t✗\tShould not end the call.")
+			}
+			t.Log("\t✓\tShould not end the call.")
+		}
+	}
+}
+
+// TestDrivers validates both drivers are opened and report the same
+// violations.
+func TestDrivers(t *testing.T) {
+	t.Log("Given the need to talk to PostgreSQL with lib/pq or pgx.")
+	{
+		t.Log("\tTest 0:\tWhen opening the database with a driver.")
+		{
+			for _, driver := range []string{"", DriverPQ, DriverPgx} {
+				db, err := Open(Config{Driver: driver})
+				if err != nil {
+					t.Fatalf("\t✗\tShould open with driver %q : %s.", driver, err)
+				}
+				db.Close()
+			}
+			t.Log("\t✓\tShould open with both drivers.")
+
+			if _, err := Open(Config{Driver: "mysql"}); err == nil {
+				t.Fatal("\t✗\tShould reject an unknown driver.")
+			}
+			if _, err := Open(Config{Driver: DriverPgx, PgxExecMode: "fast"}); err == nil {
+				t.Fatal("\t✗\tShould reject an unknown pgx exec mode.")
+			}
+			t.Log("\t✓\tShould reject an unknown driver or pgx exec mode.")
+		}
+
+		t.Log("\tTest 1:\tWhen a unique constraint is violated.")
+		{
+			const detail = "Key (owner_user_id, name)=(x, y) already exists."
+			errs := []error{
+				&pq.Error{Code: "23505", Constraint: "restaurant_owner_name_idx", Detail: detail},
+				&pgconn.PgError{Code: "23505", ConstraintName: "restaurant_owner_name_idx", Detail: detail},
+			}
+			for _, err := range errs {
+				v, ok := AsViolation(errors.Wrap(err, "inserting restaurant"))
+				if !ok || !v.Unique || v.Constraint != "restaurant_owner_name_idx" || strings.Join(v.Columns, ",") != "owner_user_id,name" {
+					t.Fatalf("\t✗\tShould report the violation of %T : got %+v.", err, v)
+				}
+			}
+			t.Log("\t✓\tShould report the violation whichever driver received it.")
+		}
+	}
+}
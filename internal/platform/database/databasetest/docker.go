@@ -14,7 +14,7 @@ type Container struct {
 }
 
 // StartContainer runs a postgres container to execute commands.
-func StartContainer(t *testing.T) *Container {
+func StartContainer(t testing.TB) *Container {
 	t.Helper()
 
 	cmd := exec.Command("docker", "run", "-P", "-d", "postgres:11.1-alpine")
@@ -61,7 +61,7 @@ func StartContainer(t *testing.T) *Container {
 }
 
 // StopContainer stops and removes the specified container.
-func StopContainer(t *testing.T, c *Container) {
+func StopContainer(t testing.TB, c *Container) {
 	t.Helper()
 
 	if err := exec.Command("docker", "stop", c.ID).Run(); err != nil {
@@ -76,7 +76,7 @@ func StopContainer(t *testing.T, c *Container) {
 }
 
 // DumpContainerLogs runs "docker logs" against the container and send it to t.Log
-func DumpContainerLogs(t *testing.T, c *Container) {
+func DumpContainerLogs(t testing.TB, c *Container) {
 	t.Helper()
 
 	out, err := exec.Command("docker", "logs", c.ID).CombinedOutput()
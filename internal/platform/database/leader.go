@@ -0,0 +1,138 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// Leader elects one process among the replicas of the service with the
+// advisory lock of a key: the process holding the lock leads and the others
+// stand by, ready to take over once its connection is gone. It is safe for
+// concurrent use.
+type Leader struct {
+	db  *sqlx.DB
+	key int64
+
+	mu    sync.Mutex
+	conn  *sql.Conn
+	since time.Time
+}
+
+// NewLeader constructs a Leader elected with the advisory lock of the key.
+func NewLeader(db *sqlx.DB, key int64) *Leader {
+	return &Leader{
+		db:  db,
+		key: key,
+	}
+}
+
+// Elect reports whether the process leads, trying to take the lock when it
+// does not. A leader keeps the lock until Resign is called or its connection
+// is lost, when it reports the error and stands by.
+func (l *Leader) Elect(ctx context.Context) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// The lock lives as long as the session, so a leader whose connection
+	// still answers still holds it.
+	if l.conn != nil {
+		if _, err := l.conn.ExecContext(ctx, `SELECT 1`); err != nil {
+			l.drop()
+			return false, errors.Wrapf(err, "checking advisory lock %d", l.key)
+		}
+		return true, nil
+	}
+
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return false, errors.Wrap(err, "reserving lock connection")
+	}
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, l.key).Scan(&locked); err != nil {
+		conn.Close()
+		return false, errors.Wrapf(err, "taking advisory lock %d", l.key)
+	}
+	if !locked {
+		conn.Close()
+		return false, nil
+	}
+
+	l.conn = conn
+	l.since = time.Now()
+	return true, nil
+}
+
+// Since returns when the process was elected, the zero time while it stands
+// by.
+func (l *Leader) Since() time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.since
+}
+
+// Resign releases the lock so another replica can lead.
+func (l *Leader) Resign() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn == nil {
+		return nil
+	}
+
+	_, err := l.conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, l.key)
+	if err != nil {
+		l.drop()
+		return errors.Wrapf(err, "releasing advisory lock %d", l.key)
+	}
+
+	err = l.conn.Close()
+	l.conn = nil
+	l.since = time.Time{}
+	return err
+}
+
+// drop closes the connection of the lock without returning it to the pool,
+// as the lock may still belong to its session.
+func (l *Leader) drop() {
+	l.conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+	l.conn.Close()
+	l.conn = nil
+	l.since = time.Time{}
+}
+
+// Holder is the session holding an advisory lock.
+type Holder struct {
+	PID     int    `db:"pid" json:"pid"`
+	Address string `db:"address" json:"address"`
+	// Connected is when the session holding the lock started.
+	Connected time.Time `db:"connected" json:"connected_at"`
+}
+
+// LockHolder returns the session holding the advisory lock of the key, nil
+// when the lock is free.
+func LockHolder(ctx context.Context, db *sqlx.DB, key int64) (*Holder, error) {
+	// A lock on a bigint key is shown by pg_locks split in two halves.
+	const q = `SELECT l.pid, COALESCE(host(a.client_addr), '') AS address, a.backend_start AS connected
+		FROM pg_locks l
+		JOIN pg_stat_activity a ON a.pid = l.pid
+		WHERE l.locktype = 'advisory' AND l.granted AND l.objsubid = 1
+		AND l.classid = $1::bigint::oid AND l.objid = $2::bigint::oid`
+
+	var h Holder
+	err := db.GetContext(ctx, &h, q, int64(uint64(key)>>32), int64(uint64(key)&0xffffffff))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "finding holder of advisory lock %d", key)
+	}
+	return &h, nil
+}
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+// Notification is a message sent on a channel with NOTIFY. A Notification
+// with an empty Channel follows a lost connection to the database, as the
+// notifications sent in the meantime were missed.
+type Notification struct {
+	Channel string
+	Payload string
+}
+
+// Listener receives the notifications sent on the channels it listens on,
+// reconnecting when the connection to the database is lost.
+type Listener struct {
+	pl *pq.Listener
+	c  chan Notification
+}
+
+// Listen starts listening on the channels. It holds a connection of its own,
+// outside of the pool of Open, opened with lib/pq whichever driver the pool
+// uses and with the password of the time it is called.
+func Listen(cfg Config, log *log.Logger, channels ...string) (*Listener, error) {
+	report := func(ev pq.ListenerEventType, err error) {
+		if err != nil && log != nil {
+			log.Printf("database : LISTEN : %v", err)
+		}
+	}
+	pl := pq.NewListener(cfg.dsn(cfg.password()), time.Second, time.Minute, report)
+
+	for _, channel := range channels {
+		if err := pl.Listen(channel); err != nil {
+			pl.Close()
+			return nil, errors.Wrapf(err, "listening on %s", channel)
+		}
+	}
+
+	l := Listener{
+		pl: pl,
+		c:  make(chan Notification),
+	}
+	go l.forward()
+
+	return &l, nil
+}
+
+// Notifications returns the channel receiving the notifications. It is
+// closed once the Listener is closed.
+func (l *Listener) Notifications() <-chan Notification {
+	return l.c
+}
+
+// Close stops listening and closes the connection.
+func (l *Listener) Close() error {
+	return l.pl.Close()
+}
+
+// forward hands the notifications of lib/pq over until it is closed.
+func (l *Listener) forward() {
+	defer close(l.c)
+	for n := range l.pl.Notify {
+		if n == nil {
+			l.c <- Notification{}
+			continue
+		}
+		l.c <- Notification{Channel: n.Channel, Payload: n.Extra}
+	}
+}
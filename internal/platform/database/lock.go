@@ -0,0 +1,70 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// These are the keys of the advisory locks taken by the service. Every
+// process taking the same key excludes the others, whichever database
+// objects they touch.
+const (
+	// LockMigrations is held while migrating the schema.
+	LockMigrations int64 = 7301
+
+	// LockVoteWinner is held by the replica computing the vote winners.
+	LockVoteWinner int64 = 7302
+
+	// LockRetention is held by the replica purging the data past its
+	// retention.
+	LockRetention int64 = 7303
+)
+
+// lockPoll is how often WithLock tries again to take a lock held elsewhere.
+const lockPoll = 250 * time.Millisecond
+
+// WithLock runs fn holding the advisory lock of the key, waiting until the
+// lock is free or ctx is done. The lock belongs to a connection set aside for
+// it, so fn runs its statements on db as usual and the lock is released when
+// fn returns or the connection is lost.
+func WithLock(ctx context.Context, db *sqlx.DB, key int64, fn func() error) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return errors.Wrap(err, "reserving lock connection")
+	}
+	defer conn.Close()
+
+	// The lock is polled rather than waited for so the wait is bounded by ctx
+	// and not by the statement timeout.
+	for {
+		var locked bool
+		if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, key).Scan(&locked); err != nil {
+			return errors.Wrapf(err, "taking advisory lock %d", key)
+		}
+		if locked {
+			break
+		}
+
+		t := time.NewTimer(lockPoll)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return errors.Wrapf(ctx.Err(), "waiting for advisory lock %d", key)
+		case <-t.C:
+		}
+	}
+
+	defer func() {
+		// The lock goes with the session should the unlock fail, so the
+		// connection is dropped rather than returned to the pool.
+		if _, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, key); err != nil {
+			conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+		}
+	}()
+
+	return fn()
+}
@@ -0,0 +1,60 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Replica returns the configuration of the read-only replica. It is only
+// meaningful when ReadHost is set.
+func (cfg Config) Replica() Config {
+	cfg.Host = cfg.ReadHost
+	cfg.ReadHost = ""
+	return cfg
+}
+
+// DB routes queries between the primary database and a read-only replica.
+// Writes, and reads which must see them, go to the primary. Reads which can
+// tolerate the replication lag go to the replica.
+type DB struct {
+	primary *sqlx.DB
+	replica *sqlx.DB
+	timeout time.Duration
+}
+
+// NewDB constructs a DB. A nil replica sends every query to the primary.
+func NewDB(primary, replica *sqlx.DB) *DB {
+	if replica == nil {
+		replica = primary
+	}
+	return &DB{primary: primary, replica: replica}
+}
+
+// Primary returns the database which writes are sent to.
+func (db *DB) Primary() *sqlx.DB {
+	return db.primary
+}
+
+// Replica returns the database which reads are sent to. Queries made through
+// Conn within a transaction still run on the primary.
+func (db *DB) Replica() *sqlx.DB {
+	return db.replica
+}
+
+// SetQueryTimeout bounds the calls of the stores, see Deadline. Zero leaves
+// them unbounded.
+func (db *DB) SetQueryTimeout(d time.Duration) {
+	db.timeout = d
+}
+
+// Deadline returns the context of a call of a store, ending after the query
+// timeout unless the context ends first. The returned cancel must be called
+// once the call is done.
+func (db *DB) Deadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if db.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, db.timeout)
+}
@@ -0,0 +1,132 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"log"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// tracedConnector opens connections recording every statement as a span and
+// logging the statements slower than slow. A nil log or a zero slow logs
+// nothing.
+type tracedConnector struct {
+	driver.Connector
+	log  *log.Logger
+	slow time.Duration
+}
+
+// Connect implements the driver.Connector interface.
+func (c tracedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedConn{Conn: conn, log: c.log, slow: c.slow}, nil
+}
+
+// tracedConn times the statements run on a connection. Statements prepared
+// beforehand are run as they are, this service does not prepare any.
+type tracedConn struct {
+	driver.Conn
+	log  *log.Logger
+	slow time.Duration
+}
+
+// QueryContext implements the driver.QueryerContext interface.
+func (c *tracedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	qc, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	ctx, span := otel.Tracer("").Start(ctx, "internal.platform.database.Query")
+	defer span.End()
+	span.SetAttributes(attribute.String("db.statement", query))
+
+	start := time.Now()
+	rows, err := qc.QueryContext(ctx, query, args)
+	c.observe(query, args, time.Since(start))
+	if err != nil && err != driver.ErrSkip {
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	return rows, err
+}
+
+// ExecContext implements the driver.ExecerContext interface.
+func (c *tracedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	ec, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	ctx, span := otel.Tracer("").Start(ctx, "internal.platform.database.Exec")
+	defer span.End()
+	span.SetAttributes(attribute.String("db.statement", query))
+
+	start := time.Now()
+	res, err := ec.ExecContext(ctx, query, args)
+	c.observe(query, args, time.Since(start))
+	if err != nil && err != driver.ErrSkip {
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	return res, err
+}
+
+// PrepareContext implements the driver.ConnPrepareContext interface.
+func (c *tracedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if pc, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return pc.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+// BeginTx implements the driver.ConnBeginTx interface.
+func (c *tracedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if bc, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return bc.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+// Ping implements the driver.Pinger interface.
+func (c *tracedConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+// ResetSession implements the driver.SessionResetter interface.
+func (c *tracedConn) ResetSession(ctx context.Context) error {
+	if sr, ok := c.Conn.(driver.SessionResetter); ok {
+		return sr.ResetSession(ctx)
+	}
+	return nil
+}
+
+// CheckNamedValue implements the driver.NamedValueChecker interface. The
+// values are converted by the driver, or by database/sql when it does not
+// convert them itself.
+func (c *tracedConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if nc, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return nc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// observe logs the statement when it took longer than the threshold. The
+// values of its parameters are left out as they may hold personal data.
+func (c *tracedConn) observe(query string, args []driver.NamedValue, d time.Duration) {
+	if c.log == nil || c.slow <= 0 || d < c.slow {
+		return
+	}
+	c.log.Printf("database : SLOW : %s : %s : %d parameters redacted", d.Round(time.Millisecond), strings.Join(strings.Fields(query), " "), len(args))
+}
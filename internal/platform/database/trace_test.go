@@ -0,0 +1,84 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slowConn is a connection whose statements take delay.
+type slowConn struct {
+	delay time.Duration
+}
+
+func (c slowConn) Prepare(query string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c slowConn) Close() error                              { return nil }
+func (c slowConn) Begin() (driver.Tx, error)                 { return nil, driver.ErrSkip }
+
+// ExecContext implements the driver.ExecerContext interface.
+func (c slowConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	time.Sleep(c.delay)
+	return driver.RowsAffected(1), nil
+}
+
+// slowConnector opens slowConns.
+type slowConnector struct {
+	delay time.Duration
+}
+
+func (c slowConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return slowConn{c.delay}, nil
+}
+func (c slowConnector) Driver() driver.Driver { return nil }
+
+// TestSlowQueries validates the statements slower than the threshold are
+// logged without the values of their parameters.
+func TestSlowQueries(t *testing.T) {
+	const q = `UPDATE users
+		SET email = $1 WHERE user_id = $2`
+
+	tt := []struct {
+		name   string
+		delay  time.Duration
+		logged bool
+	}{
+		{"a fast statement", 0, false},
+		{"a slow statement", 20 * time.Millisecond, true},
+	}
+
+	t.Log("Given the need to find the slow statements.")
+	{
+		for i, tc := range tt {
+			t.Logf("\tTest %d:\tWhen running %s.", i, tc.name)
+			{
+				var buf bytes.Buffer
+				db := sql.OpenDB(tracedConnector{
+					Connector: slowConnector{tc.delay},
+					log:       log.New(&buf, "", 0),
+					slow:      10 * time.Millisecond,
+				})
+
+				if _, err := db.ExecContext(context.Background(), q, "admin@example.com", "42"); err != nil {
+					t.Fatalf("\t✗\tShould run the statement : %v.", err)
+				}
+				db.Close()
+
+				logged := buf.String()
+				if (logged != "") != tc.logged {
+					t.Fatalf("\t✗\tShould log the statement %v : got %q.", tc.logged, logged)
+				}
+				t.Logf("\t✓\tShould log the statement %v.", tc.logged)
+
+				if tc.logged && (!strings.Contains(logged, "UPDATE users SET email = $1 WHERE user_id = $2 : 2 parameters redacted") || strings.Contains(logged, "admin@example.com")) {
+					t.Fatalf("\t✗\tShould redact the parameters : got %q.", logged)
+				}
+				t.Log("\t✓\tShould redact the parameters.")
+			}
+		}
+	}
+}
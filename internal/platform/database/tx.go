@@ -0,0 +1,142 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// txKey is the context key of the transaction of a request.
+type txKey struct{}
+
+// afterCommitKey is the context key of the functions to run once the
+// transaction of a request is committed.
+type afterCommitKey struct{}
+
+// afterCommit holds the functions to run once a transaction is committed.
+type afterCommit struct {
+	mu  sync.Mutex
+	fns []func()
+}
+
+// WithTx returns a copy of ctx carrying the transaction. Queries made through
+// Conn and transactions started with Begin then run inside it, and the
+// functions given to AfterCommit wait for Committed.
+func WithTx(ctx context.Context, tx *sqlx.Tx) context.Context {
+	ctx = context.WithValue(ctx, txKey{}, tx)
+	return context.WithValue(ctx, afterCommitKey{}, &afterCommit{})
+}
+
+// AfterCommit runs fn once the transaction carried by ctx is committed, or
+// right away when there is none. It is meant for the work outside of the
+// database following a change, like waking up a stream, which must not see
+// the change before it is committed. fn is dropped when the transaction is
+// rolled back.
+func AfterCommit(ctx context.Context, fn func()) {
+	ac, ok := ctx.Value(afterCommitKey{}).(*afterCommit)
+	if !ok {
+		fn()
+		return
+	}
+
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	ac.fns = append(ac.fns, fn)
+}
+
+// Committed runs the functions given to AfterCommit for the transaction
+// carried by ctx. It must be called once the transaction was committed.
+func Committed(ctx context.Context) {
+	ac, ok := ctx.Value(afterCommitKey{}).(*afterCommit)
+	if !ok {
+		return
+	}
+
+	ac.mu.Lock()
+	fns := ac.fns
+	ac.fns = nil
+	ac.mu.Unlock()
+
+	for _, fn := range fns {
+		fn()
+	}
+}
+
+// Conn returns the transaction carried by ctx, or db when there is none.
+func Conn(ctx context.Context, db *sqlx.DB) sqlx.ExtContext {
+	if tx, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok {
+		return tx
+	}
+	return db
+}
+
+// InTx reports whether ctx carries a transaction, which Conn then returns
+// instead of the database.
+func InTx(ctx context.Context) bool {
+	_, ok := ctx.Value(txKey{}).(*sqlx.Tx)
+	return ok
+}
+
+// Tx is a transaction started by Begin.
+type Tx interface {
+	sqlx.ExtContext
+	Commit() error
+	Rollback() error
+}
+
+// Begin starts a transaction. Within the transaction carried by ctx it sets
+// a savepoint instead, so committing only releases the savepoint and rolling
+// back undoes the changes made since Begin while the outer transaction goes
+// on. Like sql.Tx, Rollback after Commit does nothing.
+func Begin(ctx context.Context, db *sqlx.DB) (Tx, error) {
+	outer, ok := ctx.Value(txKey{}).(*sqlx.Tx)
+	if !ok {
+		return db.BeginTxx(ctx, nil)
+	}
+
+	sp := savepoint{
+		Tx:   outer,
+		name: fmt.Sprintf("sp_%d", atomic.AddUint64(&savepoints, 1)),
+	}
+	if _, err := outer.ExecContext(ctx, "SAVEPOINT "+sp.name); err != nil {
+		return nil, errors.Wrap(err, "setting savepoint")
+	}
+
+	return &sp, nil
+}
+
+// savepoints numbers the savepoints so nested ones get distinct names.
+var savepoints uint64
+
+// savepoint is a transaction nested in another using a SAVEPOINT.
+type savepoint struct {
+	*sqlx.Tx
+	name string
+	done bool
+}
+
+// Commit releases the savepoint keeping its changes in the outer transaction.
+func (s *savepoint) Commit() error {
+	if s.done {
+		return errors.New("savepoint already committed or rolled back")
+	}
+	s.done = true
+
+	_, err := s.Tx.Exec("RELEASE SAVEPOINT " + s.name)
+	return err
+}
+
+// Rollback undoes the changes made since the savepoint was set.
+func (s *savepoint) Rollback() error {
+	if s.done {
+		return nil
+	}
+	s.done = true
+
+	_, err := s.Tx.Exec("ROLLBACK TO SAVEPOINT " + s.name)
+	return err
+}
@@ -0,0 +1,45 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// TestAfterCommit validates the functions deferred to the commit of a
+// transaction run once it is committed, and right away without one.
+func TestAfterCommit(t *testing.T) {
+	t.Log("Given the need to wait for a transaction to be committed.")
+	{
+		t.Log("\tTest 0:\tWhen there is no transaction.")
+		{
+			ran := 0
+			AfterCommit(context.Background(), func() { ran++ })
+			if ran != 1 {
+				t.Fatalf("\t✗\tShould run the function right away : ran %d times.", ran)
+			}
+			t.Log("\t✓\tShould run the function right away.")
+		}
+
+		t.Log("\tTest 1:\tWhen there is a transaction.")
+		{
+			ctx := WithTx(context.Background(), &sqlx.Tx{})
+
+			var ran []int
+			AfterCommit(ctx, func() { ran = append(ran, 1) })
+			AfterCommit(ctx, func() { ran = append(ran, 2) })
+			if len(ran) != 0 {
+				t.Fatalf("\t✗\tShould wait for the commit : ran %v.", ran)
+			}
+			t.Log("\t✓\tShould wait for the commit.")
+
+			Committed(ctx)
+			Committed(ctx)
+			if len(ran) != 2 || ran[0] != 1 || ran[1] != 2 {
+				t.Fatalf("\t✗\tShould run the functions once in order : ran %v.", ran)
+			}
+			t.Log("\t✓\tShould run the functions once in order.")
+		}
+	}
+}
@@ -0,0 +1,66 @@
+// Package geo provides the geometry of positions on the surface of the Earth.
+package geo
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// EarthRadius is the radius in meters of the sphere approximating the Earth.
+// It matches the one of the PostgreSQL earthdistance extension so distances
+// computed in Go agree with the ones of the database.
+const EarthRadius = 6378168
+
+// ErrInvalidPoint is used when a position is not in its proper form or out of
+// the valid range of coordinates.
+var ErrInvalidPoint = errors.New("Location is not in its proper form")
+
+// Point is a position on Earth in decimal degrees.
+type Point struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// Valid reports whether the point is within the range of the coordinates.
+func (p Point) Valid() bool {
+	return p.Lat >= -90 && p.Lat <= 90 && p.Lng >= -180 && p.Lng <= 180
+}
+
+// ParsePoint parses a point written as "lat,lng".
+func ParsePoint(s string) (Point, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 2 {
+		return Point{}, ErrInvalidPoint
+	}
+
+	lat, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil {
+		return Point{}, ErrInvalidPoint
+	}
+	lng, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil {
+		return Point{}, ErrInvalidPoint
+	}
+
+	p := Point{Lat: lat, Lng: lng}
+	if !p.Valid() {
+		return Point{}, ErrInvalidPoint
+	}
+	return p, nil
+}
+
+// Distance returns the great circle distance in meters between two points.
+func Distance(a, b Point) float64 {
+	lat1, lat2 := radians(a.Lat), radians(b.Lat)
+	dLat, dLng := lat2-lat1, radians(b.Lng-a.Lng)
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * EarthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
@@ -0,0 +1,73 @@
+package geo
+
+import (
+	"math"
+	"testing"
+
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// TestParsePoint validates the parsing of the points of the queries.
+func TestParsePoint(t *testing.T) {
+	tt := []struct {
+		in    string
+		point Point
+		err   error
+	}{
+		{"54.6872,25.2797", Point{Lat: 54.6872, Lng: 25.2797}, nil},
+		{" -33.8688 , 151.2093 ", Point{Lat: -33.8688, Lng: 151.2093}, nil},
+		{"54.6872", Point{}, ErrInvalidPoint},
+		{"54.6872,25.2797,1", Point{}, ErrInvalidPoint},
+		{"north,25.2797", Point{}, ErrInvalidPoint},
+		{"91,25.2797", Point{}, ErrInvalidPoint},
+		{"54.6872,-181", Point{}, ErrInvalidPoint},
+	}
+
+	t.Log("Given the need to parse points written as lat,lng.")
+	{
+		for i, tc := range tt {
+			t.Logf("\tTest %d:\tWhen parsing %q.", i, tc.in)
+			{
+				p, err := ParsePoint(tc.in)
+				if err != tc.err {
+					t.Fatalf("\t%s\tShould get error %v : got %v.", tests.Failed, tc.err, err)
+				}
+				if p != tc.point {
+					t.Fatalf("\t%s\tShould get point %v : got %v.", tests.Failed, tc.point, p)
+				}
+				t.Logf("\t%s\tShould get point %v.", tests.Success, tc.point)
+			}
+		}
+	}
+}
+
+// TestDistance validates the great circle distances between points.
+func TestDistance(t *testing.T) {
+	vilnius := Point{Lat: 54.6872, Lng: 25.2797}
+	kaunas := Point{Lat: 54.8985, Lng: 23.9036}
+
+	t.Log("Given the need to measure the distance between points.")
+	{
+		t.Log("\tWhen measuring from a point to itself.")
+		{
+			if d := Distance(vilnius, vilnius); d != 0 {
+				t.Fatalf("\t%s\tShould be 0 meters away : got %f.", tests.Failed, d)
+			}
+			t.Logf("\t%s\tShould be 0 meters away.", tests.Success)
+		}
+
+		t.Log("\tWhen measuring between two cities.")
+		{
+			d := Distance(vilnius, kaunas)
+			if math.Abs(d-92000) > 1000 {
+				t.Fatalf("\t%s\tShould be about 92 km away : got %f.", tests.Failed, d)
+			}
+			t.Logf("\t%s\tShould be about 92 km away.", tests.Success)
+
+			if Distance(kaunas, vilnius) != d {
+				t.Fatalf("\t%s\tShould be the same distance both ways.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould be the same distance both ways.", tests.Success)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+// Package job keeps track of the runs of background jobs so their state can
+// be reported by the health check.
+package job
+
+import (
+	"sync"
+	"time"
+)
+
+// Status describes the state of a background job.
+type Status struct {
+	Name      string    `json:"name"`
+	Runs      int       `json:"runs"`
+	Failures  int       `json:"failures"`
+	LastRun   time.Time `json:"last_run"`
+	LastError string    `json:"last_error,omitempty"`
+	Backlog   int       `json:"backlog"`
+
+	// Role tells whether the replica runs a job elected to run on one
+	// replica only. It is empty for the jobs running on every replica.
+	Role        string     `json:"role,omitempty"`
+	LeaderSince *time.Time `json:"leader_since,omitempty"`
+}
+
+// These are the roles of a replica in a job running on one replica only.
+const (
+	RoleLeader  = "leader"
+	RoleStandby = "standby"
+)
+
+// Tracker records the runs of a background job. It is safe for concurrent
+// use.
+type Tracker struct {
+	mu     sync.Mutex
+	status Status
+}
+
+// NewTracker constructs a Tracker for the named job.
+func NewTracker(name string) *Tracker {
+	return &Tracker{
+		status: Status{Name: name},
+	}
+}
+
+// Record stores the outcome of a run which finished at now.
+func (t *Tracker) Record(err error, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.status.Runs++
+	t.status.LastRun = now.UTC()
+	t.status.LastError = ""
+	if err != nil {
+		t.status.Failures++
+		t.status.LastError = err.Error()
+	}
+}
+
+// Elected stores whether the replica leads the job, and since when.
+func (t *Tracker) Elected(leading bool, since time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.status.Role = RoleStandby
+	t.status.LeaderSince = nil
+	if leading {
+		since = since.UTC()
+		t.status.Role = RoleLeader
+		t.status.LeaderSince = &since
+	}
+}
+
+// Status returns the state of the job.
+func (t *Tracker) Status() Status {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.status
+}
@@ -0,0 +1,96 @@
+// Package loglevel filters the lines written by a log.Logger by their level
+// so the verbosity of the service can be changed while it runs. The level
+// of a line is read from its message, errors being logged as "ERROR :",
+// warnings as "WARN :" and debug lines as "DEBUG :". Every other line is
+// informational.
+package loglevel
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+// Level is the severity of a log line.
+type Level int32
+
+// These are the levels from the most to the least verbose.
+const (
+	Debug Level = iota
+	Info
+	Warn
+	Error
+)
+
+// names are the names of the levels, indexed by level.
+var names = []string{"debug", "info", "warn", "error"}
+
+// ErrInvalidLevel is used when parsing the name of an unknown level.
+var ErrInvalidLevel = errors.New("Log level must be one of debug, info, warn or error")
+
+// Parse returns the level of the name.
+func Parse(name string) (Level, error) {
+	for i, n := range names {
+		if strings.EqualFold(name, n) {
+			return Level(i), nil
+		}
+	}
+	return 0, ErrInvalidLevel
+}
+
+// String returns the name of the level.
+func (l Level) String() string {
+	if l < Debug || l > Error {
+		return "unknown"
+	}
+	return names[l]
+}
+
+// Filter writes to its output the lines of a level at least as severe as
+// its own. It is safe for concurrent use.
+type Filter struct {
+	out   io.Writer
+	level int32
+}
+
+// NewFilter constructs a Filter writing the lines of the level and above to
+// out.
+func NewFilter(out io.Writer, level Level) *Filter {
+	return &Filter{out: out, level: int32(level)}
+}
+
+// Level returns the level of the filter.
+func (f *Filter) Level() Level {
+	return Level(atomic.LoadInt32(&f.level))
+}
+
+// SetLevel changes the level of the filter for the lines written next.
+func (f *Filter) SetLevel(level Level) {
+	atomic.StoreInt32(&f.level, int32(level))
+}
+
+// Write implements the io.Writer interface. A log.Logger writes each line
+// with a single call so lines are never split. Dropped lines are reported
+// as written.
+func (f *Filter) Write(p []byte) (int, error) {
+	if levelOf(p) < f.Level() {
+		return len(p), nil
+	}
+	return f.out.Write(p)
+}
+
+// levelOf returns the level of the line from its message.
+func levelOf(line []byte) Level {
+	switch {
+	case bytes.Contains(line, []byte(": ERROR :")):
+		return Error
+	case bytes.Contains(line, []byte(": WARN :")):
+		return Warn
+	case bytes.Contains(line, []byte(": DEBUG :")):
+		return Debug
+	}
+	return Info
+}
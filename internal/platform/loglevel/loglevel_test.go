@@ -0,0 +1,60 @@
+package loglevel
+
+import (
+	"bytes"
+	"log"
+	"testing"
+
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// TestFilter validates the lines below the level of the filter are dropped.
+func TestFilter(t *testing.T) {
+	var buf bytes.Buffer
+	f := NewFilter(&buf, Info)
+	logger := log.New(f, "RESTAURANT : ", 0)
+
+	t.Log("Given the need to change the verbosity of the logs at runtime.")
+	{
+		t.Log("\tTest 0:\tWhen the level is info.")
+		{
+			logger.Printf("vote : DEBUG : tallies %v", []int{1, 2})
+			logger.Printf("main : Started")
+			logger.Printf("vote : ERROR : down")
+
+			want := "RESTAURANT : main : Started\nRESTAURANT : vote : ERROR : down\n"
+			if buf.String() != want {
+				t.Fatalf("\t%s\tShould drop the debug lines : got %q.", tests.Failed, buf.String())
+			}
+			t.Logf("\t%s\tShould drop the debug lines.", tests.Success)
+		}
+
+		t.Log("\tTest 1:\tWhen the level is changed to error.")
+		{
+			buf.Reset()
+			lvl, err := Parse("ERROR")
+			if err != nil {
+				t.Fatalf("\t%s\tShould parse the level : %v.", tests.Failed, err)
+			}
+			f.SetLevel(lvl)
+
+			logger.Printf("main : Started")
+			logger.Printf("vote : WARN : slow")
+			logger.Printf("vote : ERROR : down")
+
+			want := "RESTAURANT : vote : ERROR : down\n"
+			if buf.String() != want {
+				t.Fatalf("\t%s\tShould only keep the errors : got %q.", tests.Failed, buf.String())
+			}
+			t.Logf("\t%s\tShould only keep the errors.", tests.Success)
+		}
+
+		t.Log("\tTest 2:\tWhen the level is unknown.")
+		{
+			if _, err := Parse("verbose"); err != ErrInvalidLevel {
+				t.Fatalf("\t%s\tShould fail with ErrInvalidLevel : got %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould fail with ErrInvalidLevel.", tests.Success)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+// Package pdf writes simple PDF documents made of text, lines and images
+// using the standard fonts every PDF reader has, so no font is embedded.
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// The size of an A4 page in points.
+const (
+	PageWidth  = 595.28
+	PageHeight = 841.89
+)
+
+// Font is one of the standard fonts.
+type Font int
+
+// These are the fonts text is written with.
+const (
+	Helvetica Font = iota
+	HelveticaBold
+)
+
+// baseFonts are the PDF names of the fonts.
+var baseFonts = []string{
+	Helvetica:     "Helvetica",
+	HelveticaBold: "Helvetica-Bold",
+}
+
+// Document is a PDF document being built page by page.
+type Document struct {
+	pages  []*Page
+	images []image.Image
+}
+
+// New constructs an empty Document.
+func New() *Document {
+	return &Document{}
+}
+
+// Page is a page of a Document. Positions are in points from the top left
+// corner of the page.
+type Page struct {
+	doc     *Document
+	content bytes.Buffer
+	images  []int
+}
+
+// AddPage adds an A4 page to the end of the document.
+func (d *Document) AddPage() *Page {
+	p := Page{doc: d}
+	d.pages = append(d.pages, &p)
+	return &p
+}
+
+// Text writes the text with its baseline at y.
+func (p *Page) Text(x, y float64, font Font, size float64, s string) {
+	fmt.Fprintf(&p.content, "BT /F%d %s Tf %s %s Td (%s) Tj ET\n",
+		font+1, num(size), num(x), num(PageHeight-y), escape(s))
+}
+
+// Line draws a line of the width from one point to the other.
+func (p *Page) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(&p.content, "%s w %s %s m %s %s l S\n",
+		num(width), num(x1), num(PageHeight-y1), num(x2), num(PageHeight-y2))
+}
+
+// Image draws the image in the box of the width and height whose top left
+// corner is at x, y.
+func (p *Page) Image(img image.Image, x, y, width, height float64) {
+	p.doc.images = append(p.doc.images, img)
+	n := len(p.doc.images)
+	p.images = append(p.images, n)
+
+	fmt.Fprintf(&p.content, "q %s 0 0 %s %s %s cm /Im%d Do Q\n",
+		num(width), num(height), num(x), num(PageHeight-y-height), n)
+}
+
+// Width returns the width in points of the text written with the font.
+func Width(font Font, size float64, s string) float64 {
+	widths := helveticaWidths
+	if font == HelveticaBold {
+		widths = helveticaBoldWidths
+	}
+
+	var w int
+	for _, b := range encode(s) {
+		if b >= ' ' && int(b-' ') < len(widths) {
+			w += widths[b-' ']
+		} else {
+			w += 556
+		}
+	}
+	return float64(w) * size / 1000
+}
+
+// Wrap splits the text into lines no wider than width, breaking between
+// words. A word wider than width is left on its own line.
+func Wrap(font Font, size, width float64, s string) []string {
+	var lines []string
+	var line string
+	for _, word := range strings.Fields(s) {
+		next := word
+		if line != "" {
+			next = line + " " + word
+		}
+		if line != "" && Width(font, size, next) > width {
+			lines = append(lines, line)
+			next = word
+		}
+		line = next
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// WriteTo writes the document to w.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	var offsets []int
+
+	// obj starts the next object and returns its number.
+	obj := func() int {
+		offsets = append(offsets, buf.Len())
+		n := len(offsets)
+		fmt.Fprintf(&buf, "%d 0 obj\n", n)
+		return n
+	}
+
+	// The catalog, the page tree and the fonts come first so the numbers of
+	// the objects of the pages and images follow from their positions.
+	const firstImage = 5
+	firstPage := firstImage + len(d.images)
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	obj()
+	buf.WriteString("<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+
+	obj()
+	buf.WriteString("<< /Type /Pages /Kids [")
+	for i := range d.pages {
+		fmt.Fprintf(&buf, " %d 0 R", firstPage+2*i)
+	}
+	fmt.Fprintf(&buf, " ] /Count %d >>\nendobj\n", len(d.pages))
+
+	for _, name := range baseFonts {
+		obj()
+		fmt.Fprintf(&buf, "<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>\nendobj\n", name)
+	}
+
+	for _, img := range d.images {
+		data, width, height, err := pixels(img)
+		if err != nil {
+			return 0, err
+		}
+
+		obj()
+		fmt.Fprintf(&buf, "<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /FlateDecode /Length %d >>\nstream\n",
+			width, height, len(data))
+		buf.Write(data)
+		buf.WriteString("\nendstream\nendobj\n")
+	}
+
+	for _, p := range d.pages {
+		n := obj()
+		fmt.Fprintf(&buf, "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Contents %d 0 R /Resources << /Font <<",
+			num(PageWidth), num(PageHeight), n+1)
+		for i := range baseFonts {
+			fmt.Fprintf(&buf, " /F%d %d 0 R", i+1, 3+i)
+		}
+		buf.WriteString(" >>")
+		if len(p.images) > 0 {
+			buf.WriteString(" /XObject <<")
+			for _, img := range p.images {
+				fmt.Fprintf(&buf, " /Im%d %d 0 R", img, firstImage+img-1)
+			}
+			buf.WriteString(" >>")
+		}
+		buf.WriteString(" >> >>\nendobj\n")
+
+		obj()
+		fmt.Fprintf(&buf, "<< /Length %d >>\nstream\n", p.content.Len())
+		buf.Write(p.content.Bytes())
+		buf.WriteString("endstream\nendobj\n")
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	n, err := w.Write(buf.Bytes())
+	return int64(n), err
+}
+
+// pixels returns the compressed RGB samples of the image.
+func pixels(img image.Image) ([]byte, int, int, error) {
+	b := img.Bounds()
+
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	row := make([]byte, 3*b.Dx())
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, _ := img.At(x, y).RGBA()
+			i := 3 * (x - b.Min.X)
+			row[i], row[i+1], row[i+2] = byte(r>>8), byte(g>>8), byte(bl>>8)
+		}
+		if _, err := zw.Write(row); err != nil {
+			return nil, 0, 0, errors.Wrap(err, "compressing image")
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, 0, 0, errors.Wrap(err, "compressing image")
+	}
+
+	return buf.Bytes(), b.Dx(), b.Dy(), nil
+}
+
+// num formats a number of points.
+func num(f float64) string {
+	s := fmt.Sprintf("%.2f", f)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// escape encodes the text for a PDF string.
+func escape(s string) string {
+	var b strings.Builder
+	for _, c := range encode(s) {
+		switch c {
+		case '(', ')', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// encode converts the text to the WinAnsi encoding of the standard fonts.
+// Characters it lacks are replaced with a question mark.
+func encode(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r == '€':
+			out = append(out, 0x80)
+		case r >= ' ' && r < 0x7f, r >= 0xa0 && r <= 0xff:
+			out = append(out, byte(r))
+		default:
+			out = append(out, '?')
+		}
+	}
+	return out
+}
+
+// helveticaWidths are the widths of the printable ASCII characters of
+// Helvetica in thousandths of the font size.
+var helveticaWidths = []int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+// helveticaBoldWidths are the widths of the printable ASCII characters of
+// Helvetica-Bold in thousandths of the font size.
+var helveticaBoldWidths = []int{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+}
@@ -0,0 +1,104 @@
+package pdf
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// TestDocument validates documents are written with their text, images and
+// cross-reference table.
+func TestDocument(t *testing.T) {
+	t.Log("Given the need to print documents.")
+	{
+		doc := New()
+		p := doc.AddPage()
+		p.Text(56, 80, HelveticaBold, 22, "Pasta (fresh) \\ 12 €")
+		p.Line(56, 90, 540, 90, 0.5)
+
+		img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+		img.Set(0, 0, color.RGBA{R: 255, A: 255})
+		p.Image(img, 400, 56, 96, 96)
+		doc.AddPage().Text(56, 80, Helvetica, 12, "Page 2")
+
+		var buf bytes.Buffer
+		if _, err := doc.WriteTo(&buf); err != nil {
+			t.Fatalf("\t%s\tShould write the document : %s.", tests.Failed, err)
+		}
+		out := buf.String()
+
+		t.Log("\tTest 0:\tWhen writing a document of two pages.")
+		{
+			if !strings.HasPrefix(out, "%PDF-1.4\n") || !strings.HasSuffix(out, "%%EOF\n") {
+				t.Fatalf("\t%s\tShould be a PDF file : got %q.", tests.Failed, out)
+			}
+			t.Logf("\t%s\tShould be a PDF file.", tests.Success)
+
+			if !strings.Contains(out, "/Count 2") {
+				t.Fatalf("\t%s\tShould have two pages.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould have two pages.", tests.Success)
+
+			if !strings.Contains(out, "(Pasta \\(fresh\\) \\\\ 12 \x80) Tj") {
+				t.Fatalf("\t%s\tShould escape the text.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould escape the text.", tests.Success)
+
+			if !strings.Contains(out, "/Subtype /Image /Width 2 /Height 2") || !strings.Contains(out, "/Im1 Do") {
+				t.Fatalf("\t%s\tShould draw the image.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould draw the image.", tests.Success)
+
+			// Every object the table points at must start where it says.
+			xref := strings.Index(out, "xref\n")
+			rows := strings.Split(out[xref:], "\n")[3:]
+			for i, row := range rows {
+				if !strings.HasSuffix(row, " n ") {
+					break
+				}
+				off, err := strconv.Atoi(row[:10])
+				if err != nil {
+					t.Fatalf("\t%s\tShould parse the offset of %q : %s.", tests.Failed, row, err)
+				}
+				if want := strconv.Itoa(i+1) + " 0 obj"; !strings.HasPrefix(out[off:], want) {
+					t.Fatalf("\t%s\tShould point at object %d : got %q.", tests.Failed, i+1, out[off:off+10])
+				}
+			}
+			t.Logf("\t%s\tShould point at every object.", tests.Success)
+		}
+	}
+}
+
+// TestWrap validates text is split between words to fit its width.
+func TestWrap(t *testing.T) {
+	tt := []struct {
+		name  string
+		width float64
+		text  string
+		lines []string
+	}{
+		{"fits", 500, "Pesto Pasta", []string{"Pesto Pasta"}},
+		{"wraps", 70, "Pesto Pasta with Basil", []string{"Pesto Pasta", "with Basil"}},
+		{"long word", 10, "Spaghetti alla", []string{"Spaghetti", "alla"}},
+		{"blank", 100, "  ", nil},
+	}
+
+	t.Log("Given the need to wrap long lines.")
+	{
+		for i, tc := range tt {
+			t.Logf("\tTest %d:\tWhen wrapping %s text.", i, tc.name)
+			{
+				got := Wrap(Helvetica, 12, tc.width, tc.text)
+				if strings.Join(got, "|") != strings.Join(tc.lines, "|") {
+					t.Fatalf("\t%s\tShould wrap to %q : got %q.", tests.Failed, tc.lines, got)
+				}
+				t.Logf("\t%s\tShould wrap to %q.", tests.Success, tc.lines)
+			}
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// Package ratelimit provides token buckets used to limit how often clients
+// may call the API. Buckets are kept in a Store so they can be shared between
+// instances of the service by using a networked backend such as Redis.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit describes a token bucket. Rate tokens are added every second up to
+// Burst tokens and each request takes one. A zero Limit disables limiting.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Disabled reports whether the limit lets every request through.
+func (l Limit) Disabled() bool {
+	return l.Rate <= 0 || l.Burst <= 0
+}
+
+// Store keeps the token buckets of every client.
+type Store interface {
+
+	// Take removes a token from the bucket identified by key. When the bucket
+	// is empty it reports false and how long to wait for the next token.
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (bool, time.Duration, error)
+}
+
+// bucket is the state of a single token bucket.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// sweepInterval is how often idle buckets are removed from a Memory store.
+const sweepInterval = time.Minute
+
+// Memory is a Store keeping buckets in process memory. It is only suitable
+// when a single instance of the service is running.
+type Memory struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	limits    map[string]Limit
+	lastSweep time.Time
+}
+
+// NewMemory constructs an empty in memory Store.
+func NewMemory() *Memory {
+	return &Memory{
+		buckets: make(map[string]*bucket),
+		limits:  make(map[string]Limit),
+	}
+}
+
+// Take implements the Store interface.
+func (m *Memory) Take(ctx context.Context, key string, limit Limit, now time.Time) (bool, time.Duration, error) {
+	if limit.Disabled() {
+		return true, 0, nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if now.Sub(m.lastSweep) > sweepInterval {
+		m.sweep(now)
+	}
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), last: now}
+		m.buckets[key] = b
+	}
+	m.limits[key] = limit
+
+	// Refill the bucket for the time passed since it was last used.
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(limit.Burst), b.tokens+elapsed*limit.Rate)
+		b.last = now
+	}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0, nil
+	}
+
+	wait := time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second))
+	return false, wait, nil
+}
+
+// sweep removes buckets which have refilled completely as they are no
+// different from a new bucket.
+func (m *Memory) sweep(now time.Time) {
+	for key, b := range m.buckets {
+		limit := m.limits[key]
+		if b.tokens+now.Sub(b.last).Seconds()*limit.Rate >= float64(limit.Burst) {
+			delete(m.buckets, key)
+			delete(m.limits, key)
+		}
+	}
+	m.lastSweep = now
+}
@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// TestMemory validates the token bucket behavior of the in memory store.
+func TestMemory(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2020, time.March, 1, 12, 0, 0, 0, time.UTC)
+	limit := Limit{Rate: 1, Burst: 2}
+
+	t.Log("Given the need to limit the rate of requests.")
+	{
+		t.Log("\tWhen taking tokens from a new bucket.")
+		{
+			m := NewMemory()
+
+			for i := 0; i < limit.Burst; i++ {
+				if ok, _, _ := m.Take(ctx, "a", limit, now); !ok {
+					t.Fatalf("\t%s\tShould allow a burst of %d requests.", tests.Failed, limit.Burst)
+				}
+			}
+			t.Logf("\t%s\tShould allow a burst of %d requests.", tests.Success, limit.Burst)
+
+			ok, wait, _ := m.Take(ctx, "a", limit, now)
+			if ok {
+				t.Fatalf("\t%s\tShould reject a request once the bucket is empty.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould reject a request once the bucket is empty.", tests.Success)
+
+			if wait != time.Second {
+				t.Fatalf("\t%s\tShould wait a second for the next token : got %v.", tests.Failed, wait)
+			}
+			t.Logf("\t%s\tShould wait a second for the next token.", tests.Success)
+
+			if ok, _, _ := m.Take(ctx, "b", limit, now); !ok {
+				t.Fatalf("\t%s\tShould keep separate buckets per key.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould keep separate buckets per key.", tests.Success)
+
+			if ok, _, _ := m.Take(ctx, "a", limit, now.Add(time.Second)); !ok {
+				t.Fatalf("\t%s\tShould refill the bucket over time.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould refill the bucket over time.", tests.Success)
+		}
+
+		t.Log("\tWhen the limit is disabled.")
+		{
+			m := NewMemory()
+
+			for i := 0; i < 100; i++ {
+				if ok, _, _ := m.Take(ctx, "a", Limit{}, now); !ok {
+					t.Fatalf("\t%s\tShould allow every request.", tests.Failed)
+				}
+			}
+			t.Logf("\t%s\tShould allow every request.", tests.Success)
+		}
+	}
+}
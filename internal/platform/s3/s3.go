@@ -0,0 +1,85 @@
+// Package s3 reads and writes the objects of a bucket of any S3 compatible
+// object storage.
+package s3
+
+import (
+	"bytes"
+	"context"
+	"io"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/pkg/errors"
+)
+
+// ErrNotFound is used when an object does not exist.
+var ErrNotFound = errors.New("object not found")
+
+// Config is used to hold the required properties to use S3 compatible
+// object storage.
+type Config struct {
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	DisableTLS      bool
+}
+
+// Bucket is a bucket of the object storage.
+type Bucket struct {
+	client *minio.Client
+	name   string
+}
+
+// Open constructs a client of the configured bucket. No request is made so
+// a misconfigured bucket only shows on first use.
+func Open(cfg Config) (*Bucket, error) {
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKeyID, cfg.SecretAccessKey, ""),
+		Secure: !cfg.DisableTLS,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "creating s3 client")
+	}
+
+	return &Bucket{client: client, name: cfg.Bucket}, nil
+}
+
+// Put writes the object with the key, replacing any previous one.
+func (b *Bucket) Put(ctx context.Context, key, contentType string, data []byte) error {
+	opts := minio.PutObjectOptions{
+		ContentType: contentType,
+	}
+
+	if _, err := b.client.PutObject(ctx, b.name, key, bytes.NewReader(data), int64(len(data)), opts); err != nil {
+		return errors.Wrapf(err, "putting object %s", key)
+	}
+
+	return nil
+}
+
+// Get reads the object with the key.
+func (b *Bucket) Get(ctx context.Context, key string) ([]byte, error) {
+	obj, err := b.client.GetObject(ctx, b.name, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting object %s", key)
+	}
+	defer obj.Close()
+
+	data, err := io.ReadAll(obj)
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, ErrNotFound
+		}
+		return nil, errors.Wrapf(err, "reading object %s", key)
+	}
+
+	return data, nil
+}
+
+// URL returns the address of the bucket on the endpoint.
+func (b *Bucket) URL() string {
+	return b.client.EndpointURL().String() + "/" + b.name
+}
@@ -0,0 +1,163 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+)
+
+// AWSConfig configures the access to AWS Secrets Manager. The session token
+// is only needed with temporary credentials. Endpoint overrides the endpoint
+// of the region, for example for a VPC endpoint.
+type AWSConfig struct {
+	Region          string
+	SecretID        string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Endpoint        string
+}
+
+// AWS reads the secrets from a secret of AWS Secrets Manager whose string is
+// a JSON object, each key of it holding one of them.
+type AWS struct {
+	cfg    AWSConfig
+	client *http.Client
+}
+
+// NewAWS constructs an AWS reading the secret of the configuration.
+func NewAWS(cfg AWSConfig, timeout time.Duration) *AWS {
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = fmt.Sprintf("https://secretsmanager.%s.amazonaws.com", cfg.Region)
+	}
+	return &AWS{
+		cfg: cfg,
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
+		},
+	}
+}
+
+// Fetch implements the Source interface.
+func (a *AWS) Fetch(ctx context.Context) (map[string]string, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.platform.secrets.AWS.Fetch")
+	defer span.End()
+
+	body, err := json.Marshal(struct {
+		SecretID string `json:"SecretId"`
+	}{a.cfg.SecretID})
+	if err != nil {
+		return nil, errors.Wrap(err, "encoding aws request")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.cfg.Endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.Wrap(err, "building aws request")
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	if a.cfg.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", a.cfg.SessionToken)
+	}
+	sign(req, body, a.cfg.AccessKeyID, a.cfg.SecretAccessKey, a.cfg.Region, "secretsmanager", time.Now())
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading aws secret")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, errors.Errorf("aws answered %s: %s", resp.Status, msg)
+	}
+
+	var secret struct {
+		SecretString string `json:"SecretString"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&secret); err != nil {
+		return nil, errors.Wrap(err, "decoding aws secret")
+	}
+
+	values := make(map[string]string)
+	if err := json.Unmarshal([]byte(secret.SecretString), &values); err != nil {
+		return nil, errors.Wrap(err, "decoding aws secret string")
+	}
+
+	return values, nil
+}
+
+// sign adds the Signature Version 4 authorization of the request with the
+// body to it, signing every header set along with the host.
+func sign(req *http.Request, body []byte, accessKeyID, secretAccessKey, region, service string, now time.Time) {
+	stamp := now.UTC().Format("20060102T150405Z")
+	date := stamp[:8]
+	req.Header.Set("X-Amz-Date", stamp)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	query := strings.Replace(req.URL.Query().Encode(), "+", "%20", -1)
+
+	payload := sha256.Sum256(body)
+	canonical := strings.Join([]string{
+		req.Method,
+		path,
+		query,
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payload[:]),
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	hash := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + stamp + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := hmacSHA256([]byte("AWS4"+secretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, toSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKeyID, scope, signedHeaders, signature))
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of the data with the key.
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
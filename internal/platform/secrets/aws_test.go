@@ -0,0 +1,36 @@
+package secrets
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// TestSign validates requests are signed like the example of the Signature
+// Version 4 documentation of AWS.
+func TestSign(t *testing.T) {
+	t.Log("Given the need to sign the requests to AWS.")
+	{
+		t.Log("\tTest 0:\tWhen signing the ListUsers request of the example.")
+		{
+			req, err := http.NewRequest(http.MethodGet, "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08", nil)
+			if err != nil {
+				t.Fatalf("\t%s\tShould build the request : %v.", tests.Failed, err)
+			}
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+
+			now := time.Date(2015, time.August, 30, 12, 36, 0, 0, time.UTC)
+			sign(req, nil, "AKIDEXAMPLE", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "us-east-1", "iam", now)
+
+			want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, " +
+				"SignedHeaders=content-type;host;x-amz-date, " +
+				"Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7"
+			if got := req.Header.Get("Authorization"); got != want {
+				t.Fatalf("\t%s\tShould get the signature of the example : got %q.", tests.Failed, got)
+			}
+			t.Logf("\t%s\tShould get the signature of the example.", tests.Success)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+// Package secrets fetches the credentials of the service, like the database
+// password or the private key signing the tokens, from a secrets backend
+// instead of files and environment variables, and refreshes them
+// periodically.
+package secrets
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/platform/job"
+)
+
+// These are the names of the secrets the service reads from the backend.
+const (
+	DBPassword           = "db_password"
+	AuthPrivateKey       = "auth_private_key"
+	EmailPassword        = "email_password"
+	SlackWebhookURL      = "slack_webhook_url"
+	TelegramToken        = "telegram_token"
+	MediaSecretAccessKey = "media_secret_access_key"
+	EnrichmentAPIKey     = "enrichment_api_key"
+)
+
+// Source fetches every secret of the service from a backend.
+type Source interface {
+	Fetch(ctx context.Context) (map[string]string, error)
+}
+
+// Store keeps the secrets last fetched from its source. It is safe for
+// concurrent use.
+type Store struct {
+	src     Source
+	mu      sync.RWMutex
+	values  map[string]string
+	tracker *job.Tracker
+}
+
+// NewStore constructs a Store holding the secrets fetched from the source.
+func NewStore(ctx context.Context, src Source) (*Store, error) {
+	s := Store{
+		src:     src,
+		tracker: job.NewTracker("secrets"),
+	}
+	if _, err := s.Refresh(ctx); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Get returns the secret of the name, or fallback when the backend does not
+// have it.
+func (s *Store) Get(name, fallback string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if v, ok := s.values[name]; ok {
+		return v
+	}
+	return fallback
+}
+
+// Func returns a function getting the latest value of the secret of the
+// name, for the consumers picking up rotated secrets.
+func (s *Store) Func(name, fallback string) func() string {
+	return func() string {
+		return s.Get(name, fallback)
+	}
+}
+
+// Refresh fetches the secrets again and returns the names of those which
+// changed, sorted.
+func (s *Store) Refresh(ctx context.Context) ([]string, error) {
+	values, err := s.src.Fetch(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetching secrets")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var changed []string
+	for name, v := range values {
+		if old, ok := s.values[name]; !ok || old != v {
+			changed = append(changed, name)
+		}
+	}
+	for name := range s.values {
+		if _, ok := values[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+
+	s.values = values
+	return changed, nil
+}
+
+// Status reports the state of the refreshes to the health check.
+func (s *Store) Status() job.Status {
+	return s.tracker.Status()
+}
+
+// Run refreshes the secrets every interval until the context is canceled.
+// The secrets which changed are logged by name, calling changed with them.
+// A failed refresh keeps the secrets fetched last.
+func (s *Store) Run(ctx context.Context, log *log.Logger, interval time.Duration, changed func(names []string)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		names, err := s.Refresh(ctx)
+		s.tracker.Record(err, time.Now())
+		if err != nil {
+			log.Printf("secrets : ERROR : %+v", err)
+			continue
+		}
+		if len(names) > 0 {
+			log.Printf("secrets : changed %v", names)
+			if changed != nil {
+				changed(names)
+			}
+		}
+	}
+}
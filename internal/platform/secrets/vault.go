@@ -0,0 +1,76 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+)
+
+// Vault reads the secrets from a secret of the KV version 2 secrets engine
+// of HashiCorp Vault, each key of the secret holding one of them.
+type Vault struct {
+	addr   string
+	token  string
+	mount  string
+	path   string
+	client *http.Client
+}
+
+// NewVault constructs a Vault reading the secret at the path of the engine
+// mounted at mount, authenticating with the token.
+func NewVault(addr, token, mount, path string, timeout time.Duration) *Vault {
+	return &Vault{
+		addr:  strings.TrimSuffix(addr, "/"),
+		token: token,
+		mount: strings.Trim(mount, "/"),
+		path:  strings.Trim(path, "/"),
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
+		},
+	}
+}
+
+// Fetch implements the Source interface.
+func (v *Vault) Fetch(ctx context.Context) (map[string]string, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.platform.secrets.Vault.Fetch")
+	defer span.End()
+
+	url := fmt.Sprintf("%s/v1/%s/data/%s", v.addr, v.mount, v.path)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "building vault request")
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading vault secret")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, errors.Errorf("vault answered %s: %s", resp.Status, body)
+	}
+
+	var secret struct {
+		Data struct {
+			Data map[string]string `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&secret); err != nil {
+		return nil, errors.Wrap(err, "decoding vault secret")
+	}
+
+	return secret.Data.Data, nil
+}
@@ -0,0 +1,113 @@
+// Package tracing configures OpenTelemetry for the service. Spans are created
+// throughout the code with otel.Tracer and leave the process through the
+// exporter selected in Config.
+package tracing
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/jaeger"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+)
+
+// These are the supported values for Config.Exporter.
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterJaeger = "jaeger"
+	ExporterOTLP   = "otlp"
+)
+
+// Config is used to hold the required properties to export traces.
+type Config struct {
+	ServiceName string
+	Version     string
+	Exporter    string
+	URL         string
+	Probability float64
+}
+
+// Init installs a global tracer provider exporting spans as configured. The
+// W3C TraceContext standard is used to propagate traces across services.
+// https://w3c.github.io/trace-context/
+//
+// The returned function flushes any buffered spans and must be called before
+// the program exits.
+func Init(cfg Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	if cfg.Exporter == ExporterNone {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := newExporter(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	res := resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(cfg.ServiceName),
+		semconv.ServiceVersion(cfg.Version),
+	)
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.Probability))),
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(tp)
+
+	return tp.Shutdown, nil
+}
+
+// newExporter constructs the span exporter named in the config.
+func newExporter(cfg Config) (sdktrace.SpanExporter, error) {
+	switch cfg.Exporter {
+	case ExporterStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+		if err != nil {
+			return nil, errors.Wrap(err, "creating stdout exporter")
+		}
+		return exporter, nil
+
+	case ExporterJaeger:
+		exporter, err := jaeger.New(jaeger.WithCollectorEndpoint(jaeger.WithEndpoint(cfg.URL)))
+		if err != nil {
+			return nil, errors.Wrap(err, "creating jaeger exporter")
+		}
+		return exporter, nil
+
+	case ExporterOTLP:
+		u, err := url.Parse(cfg.URL)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing otlp url %q", cfg.URL)
+		}
+
+		opts := []otlptracehttp.Option{
+			otlptracehttp.WithEndpoint(u.Host),
+		}
+		if u.Path != "" {
+			opts = append(opts, otlptracehttp.WithURLPath(u.Path))
+		}
+		if u.Scheme == "http" {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+
+		exporter, err := otlptracehttp.New(context.Background(), opts...)
+		if err != nil {
+			return nil, errors.Wrap(err, "creating otlp exporter")
+		}
+		return exporter, nil
+	}
+
+	return nil, errors.Errorf("unknown trace exporter %q", cfg.Exporter)
+}
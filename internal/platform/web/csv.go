@@ -0,0 +1,84 @@
+package web
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// csvFlushRows is how many rows are buffered before they are sent.
+const csvFlushRows = 100
+
+// WantsCSV reports if the client asked for CSV with the format query
+// parameter instead of JSON.
+func WantsCSV(r *http.Request) bool {
+	return strings.EqualFold(r.URL.Query().Get("format"), "csv")
+}
+
+// CSVWriter streams the rows of a CSV response.
+type CSVWriter struct {
+	w       *csv.Writer
+	flusher http.Flusher
+	rows    int
+}
+
+// RespondCSV starts a CSV response downloaded as the named file and writes
+// its header row. The rows follow with Write and the response is completed
+// with Close.
+func RespondCSV(ctx context.Context, w http.ResponseWriter, filename string, header []string) (*CSVWriter, error) {
+	v, ok := ctx.Value(KeyValues).(*Values)
+	if !ok {
+		return nil, NewShutdownError("web value missing from context")
+	}
+	v.StatusCode = http.StatusOK
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+
+	cw := CSVWriter{
+		w: csv.NewWriter(w),
+	}
+	cw.flusher, _ = w.(http.Flusher)
+
+	if err := cw.Write(header...); err != nil {
+		return nil, err
+	}
+
+	return &cw, nil
+}
+
+// Write sends a row. Values which a spreadsheet would take for a formula are
+// escaped so opening an export never runs one.
+func (cw *CSVWriter) Write(record ...string) error {
+	for i, s := range record {
+		if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+			record[i] = "'" + s
+		}
+	}
+
+	if err := cw.w.Write(record); err != nil {
+		return err
+	}
+
+	cw.rows++
+	if cw.rows%csvFlushRows == 0 {
+		cw.w.Flush()
+		if cw.flusher != nil {
+			cw.flusher.Flush()
+		}
+	}
+
+	return cw.w.Error()
+}
+
+// Close sends the rows still buffered.
+func (cw *CSVWriter) Close() error {
+	cw.w.Flush()
+	if cw.flusher != nil {
+		cw.flusher.Flush()
+	}
+	return cw.w.Error()
+}
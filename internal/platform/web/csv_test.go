@@ -0,0 +1,40 @@
+package web
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestRespondCSV validates CSV responses are downloads with escaped values.
+func TestRespondCSV(t *testing.T) {
+	t.Log("Given the need to respond with CSV.")
+	{
+		t.Log("\tTest 0:\tWhen writing rows.")
+		{
+			ctx := context.WithValue(context.Background(), KeyValues, &Values{})
+			w := httptest.NewRecorder()
+
+			cw, err := RespondCSV(ctx, w, "restaurants.csv", []string{"name", "address"})
+			if err != nil {
+				t.Fatalf("\t✗\tShould start the response : %s.", err)
+			}
+			cw.Write("Pizza, Pasta", "Main St")
+			cw.Write("=HYPERLINK(\"x\")", "-1")
+			if err := cw.Close(); err != nil {
+				t.Fatalf("\t✗\tShould complete the response : %s.", err)
+			}
+
+			if got := w.Header().Get("Content-Disposition"); got != `attachment; filename="restaurants.csv"` {
+				t.Fatalf("\t✗\tShould be downloaded as a file : got %q.", got)
+			}
+			t.Log("\t✓\tShould be downloaded as a file.")
+
+			want := "name,address\n\"Pizza, Pasta\",Main St\n\"'=HYPERLINK(\"\"x\"\")\",'-1\n"
+			if got := w.Body.String(); got != want {
+				t.Fatalf("\t✗\tShould quote and escape the values : got %q.", got)
+			}
+			t.Log("\t✓\tShould quote and escape the values.")
+		}
+	}
+}
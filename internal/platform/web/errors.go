@@ -1,6 +1,10 @@
 package web
 
-import "github.com/pkg/errors"
+import (
+	"net/http"
+
+	"github.com/pkg/errors"
+)
 
 // FieldError is used to indicate an error with a specific request field.
 type FieldError struct {
@@ -9,9 +13,42 @@ type FieldError struct {
 }
 
 // ErrorResponse is the form used for API responses from failures in the API.
+// Code is stable so clients can branch on it instead of the message. Internal
+// errors carry the TraceID of the request so support can find it in the logs
+// and traces.
 type ErrorResponse struct {
-	Error  string       `json:"error"`
-	Fields []FieldError `json:"fields,omitempty"`
+	Code    string       `json:"code"`
+	Error   string       `json:"error"`
+	Fields  []FieldError `json:"fields,omitempty"`
+	TraceID string       `json:"trace_id,omitempty"`
+}
+
+// Codes of errors which are not specific to a part of the API.
+const (
+	CodeValidationFailed = "VALIDATION_FAILED"
+	CodeInternal         = "INTERNAL_ERROR"
+	CodeInvalidID        = "INVALID_ID"
+	CodeAlreadyExists    = "ALREADY_EXISTS"
+	CodeInvalidReference = "INVALID_REFERENCE"
+)
+
+// ErrInvalidParam is used when a typed parameter of the route of a request is
+// not of its type.
+var ErrInvalidParam = errors.New("ID is not in its proper form")
+
+// statusCodes are the codes used for errors which were not given a code.
+var statusCodes = map[int]string{
+	http.StatusBadRequest:            "BAD_REQUEST",
+	http.StatusUnauthorized:          "UNAUTHORIZED",
+	http.StatusForbidden:             "FORBIDDEN",
+	http.StatusNotFound:              "NOT_FOUND",
+	http.StatusConflict:              "CONFLICT",
+	http.StatusRequestEntityTooLarge: "REQUEST_TOO_LARGE",
+	http.StatusUnprocessableEntity:   "UNPROCESSABLE",
+	http.StatusTooManyRequests:       "RATE_LIMITED",
+	http.StatusNotImplemented:        "NOT_IMPLEMENTED",
+	http.StatusServiceUnavailable:    "UNAVAILABLE",
+	http.StatusGatewayTimeout:        "TIMEOUT",
 }
 
 // Error is used to pass an error during the request through the
@@ -19,13 +56,39 @@ type ErrorResponse struct {
 type Error struct {
 	Err    error
 	Status int
+	Code   string
 	Fields []FieldError
 }
 
 // NewRequestError wraps a provided error with an HTTP status code. This
 // function should be used when handlers encounter expected errors.
 func NewRequestError(err error, status int) error {
-	return &Error{err, status, nil}
+	return &Error{Err: err, Status: status}
+}
+
+// NewCodedError wraps a provided error with an HTTP status code and the code
+// clients use to identify it. A blank code falls back to the code of the
+// status.
+func NewCodedError(err error, status int, code string) error {
+	return &Error{Err: err, Status: status, Code: code}
+}
+
+// code returns the code of the error reported to the client.
+func (err *Error) code() string {
+	switch {
+	case err.Code != "":
+		return err.Code
+	case len(err.Fields) > 0:
+		return CodeValidationFailed
+	}
+
+	if code, ok := statusCodes[err.Status]; ok {
+		return code
+	}
+	if err.Status >= http.StatusInternalServerError {
+		return CodeInternal
+	}
+	return statusCodes[http.StatusBadRequest]
 }
 
 // Error implements the error interface. It uses the default message of the
@@ -0,0 +1,63 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Fields returns the members of the objects of a response the client asked
+// for with the fields query parameter, as in ?fields=id,name. It is nil when
+// the client wants all of them.
+func Fields(r *http.Request) []string {
+	var fields []string
+	seen := make(map[string]bool)
+	for _, f := range strings.Split(r.URL.Query().Get("fields"), ",") {
+		f = strings.TrimSpace(f)
+		if f == "" || seen[f] {
+			continue
+		}
+		seen[f] = true
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// selectFields keeps the named members of the JSON object in the order of
+// fields. Members the object does not have are skipped and values which are
+// not objects are kept as they are.
+func selectFields(item []byte, fields []string) ([]byte, error) {
+	if len(item) == 0 || item[0] != '{' {
+		return item, nil
+	}
+
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(item, &members); err != nil {
+		return nil, errors.Wrap(err, "decoding item")
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for _, f := range fields {
+		value, ok := members[f]
+		if !ok {
+			continue
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(f)
+		if err != nil {
+			return nil, errors.Wrap(err, "encoding field name")
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,36 @@
+package web
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Links are the URLs of the resources related to the one of a response by
+// the name of their relation, like self, so clients follow them instead of
+// building URLs from templates of their own.
+type Links map[string]string
+
+// Link builds the URL of a route with the values of its parameters in order,
+// as in Link("/v1/restaurant/:id/votes", id). The parameters may be typed like
+// in the routes given to App.Handle. It panics when the number of values does
+// not match the parameters of the route as that is a programming error.
+func Link(route string, values ...string) string {
+	segments := strings.Split(route, "/")
+	n := 0
+	for i, segment := range segments {
+		if !strings.HasPrefix(segment, ":") {
+			continue
+		}
+		if n == len(values) {
+			panic(fmt.Sprintf("web: missing value of parameter %s of route %s", segment, route))
+		}
+		segments[i] = url.PathEscape(values[n])
+		n++
+	}
+	if n != len(values) {
+		panic(fmt.Sprintf("web: %d values given to route %s with %d parameters", len(values), route, n))
+	}
+
+	return strings.Join(segments, "/")
+}
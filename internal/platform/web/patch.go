@@ -0,0 +1,111 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+)
+
+// MergePatchType is the media type of the JSON merge patches of RFC 7396.
+const MergePatchType = "application/merge-patch+json"
+
+// MergePatch applies the JSON merge patch to the target document as defined
+// by RFC 7396. Members of the patch set to null are removed from the target,
+// objects are merged member by member and any other value replaces the one of
+// the target.
+func MergePatch(target, patch []byte) ([]byte, error) {
+	var t, p interface{}
+	if err := unmarshalNumbers(target, &t); err != nil {
+		return nil, errors.Wrap(err, "decoding target")
+	}
+	if err := unmarshalNumbers(patch, &p); err != nil {
+		return nil, errors.Wrap(err, "decoding patch")
+	}
+
+	return json.Marshal(mergePatch(t, p))
+}
+
+// mergePatch is the MergePatch algorithm of RFC 7396 on decoded documents.
+func mergePatch(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = make(map[string]interface{}, len(p))
+	}
+	for name, value := range p {
+		if value == nil {
+			delete(t, name)
+			continue
+		}
+		t[name] = mergePatch(t[name], value)
+	}
+	return t
+}
+
+// unmarshalNumbers decodes the JSON document keeping its numbers as written
+// so large integers survive the patch.
+func unmarshalNumbers(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// DecodePatch reads a JSON merge patch from the body of an HTTP request and
+// applies it to the provided value, which holds the current state of the
+// resource. Members the patch sets to null leave the zero value in their
+// field, which is how clients clear a field explicitly.
+//
+// The body must be sent as application/merge-patch+json or application/json.
+// Like with Decode, members the value does not know are rejected and the
+// patched value is checked for validation tags.
+func DecodePatch(r *http.Request, val interface{}) error {
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		mt, _, err := mime.ParseMediaType(ct)
+		if err != nil || (mt != MergePatchType && mt != "application/json") {
+			err := fmt.Errorf("request body must be sent as %s", MergePatchType)
+			return NewRequestError(err, http.StatusUnsupportedMediaType)
+		}
+	}
+
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		return decodeError(err)
+	}
+	if len(bytes.TrimSpace(patch)) == 0 {
+		return decodeError(io.EOF)
+	}
+	if !json.Valid(patch) {
+		return NewRequestError(errors.New("request body contains badly-formed JSON"), http.StatusBadRequest)
+	}
+
+	doc, err := json.Marshal(val)
+	if err != nil {
+		return errors.Wrap(err, "encoding value to patch")
+	}
+	patched, err := MergePatch(doc, patch)
+	if err != nil {
+		return errors.Wrap(err, "applying patch")
+	}
+
+	// Decode the patched document into a zero value so the members removed
+	// by the patch clear their fields.
+	v := reflect.ValueOf(val).Elem()
+	v.Set(reflect.Zero(v.Type()))
+
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(val); err != nil {
+		return decodeError(err)
+	}
+
+	return Validate(val)
+}
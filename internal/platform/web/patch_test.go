@@ -0,0 +1,109 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestMergePatch validates patches are applied like the examples of RFC 7396.
+func TestMergePatch(t *testing.T) {
+	tt := []struct {
+		target string
+		patch  string
+		want   string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+		{`{"id":9007199254740993}`, `{"a":1}`, `{"a":1,"id":9007199254740993}`},
+	}
+
+	t.Log("Given the need to apply JSON merge patches.")
+	{
+		for i, tc := range tt {
+			t.Logf("\tTest %d:\tWhen patching %s with %s.", i, tc.target, tc.patch)
+			{
+				got, err := MergePatch([]byte(tc.target), []byte(tc.patch))
+				if err != nil {
+					t.Fatalf("\t✗\tShould be able to apply the patch : %v.", err)
+				}
+				if string(got) != tc.want {
+					t.Fatalf("\t✗\tShould get %s : got %s.", tc.want, got)
+				}
+				t.Logf("\t✓\tShould get %s.", tc.want)
+			}
+		}
+	}
+}
+
+// TestDecodePatch validates patched values clear the fields set to null and
+// are validated like decoded ones.
+func TestDecodePatch(t *testing.T) {
+	type doc struct {
+		Name    string `json:"name" validate:"required"`
+		Website string `json:"website"`
+		Age     int    `json:"age"`
+	}
+	current := doc{Name: "bill", Website: "https://bill.example", Age: 40}
+
+	tt := []struct {
+		name        string
+		contentType string
+		body        string
+		status      int
+		want        doc
+	}{
+		{"changing a field", MergePatchType, `{"age":41}`, 0, doc{Name: "bill", Website: "https://bill.example", Age: 41}},
+		{"clearing a field", MergePatchType, `{"website":null}`, 0, doc{Name: "bill", Age: 40}},
+		{"sent as JSON", "application/json", `{"age":41}`, 0, doc{Name: "bill", Website: "https://bill.example", Age: 41}},
+		{"clearing a required field", MergePatchType, `{"name":null}`, http.StatusBadRequest, doc{}},
+		{"an unknown field", MergePatchType, `{"email":"x"}`, http.StatusBadRequest, doc{}},
+		{"a badly-formed body", MergePatchType, `{"age":}`, http.StatusBadRequest, doc{}},
+		{"an empty body", MergePatchType, ``, http.StatusBadRequest, doc{}},
+		{"another media type", "text/plain", `{"age":41}`, http.StatusUnsupportedMediaType, doc{}},
+	}
+
+	t.Log("Given the need to decode merge patches.")
+	{
+		for i, tc := range tt {
+			t.Logf("\tTest %d:\tWhen decoding %s.", i, tc.name)
+			{
+				r := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(tc.body))
+				r.Header.Set("Content-Type", tc.contentType)
+
+				got := current
+				err := DecodePatch(r, &got)
+				if tc.status != 0 {
+					webErr, ok := err.(*Error)
+					if !ok || webErr.Status != tc.status {
+						t.Fatalf("\t✗\tShould fail with a status code of %d : got %v.", tc.status, err)
+					}
+					t.Logf("\t✓\tShould fail with a status code of %d.", tc.status)
+					continue
+				}
+
+				if err != nil {
+					t.Fatalf("\t✗\tShould be able to decode the patch : %v.", err)
+				}
+				if got != tc.want {
+					t.Fatalf("\t✗\tShould get %+v : got %+v.", tc.want, got)
+				}
+				t.Logf("\t✓\tShould get %+v.", tc.want)
+			}
+		}
+	}
+}
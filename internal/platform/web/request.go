@@ -3,12 +3,15 @@ package web
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/go-playground/locales/en"
 	ut "github.com/go-playground/universal-translator"
 	validator "gopkg.in/go-playground/validator.v9"
 	entranslations "gopkg.in/go-playground/validator.v9/translations/en"
+	"io"
 	"net/http"
 	"reflect"
+	"sort"
 	"strings"
 )
 
@@ -44,14 +47,52 @@ func init() {
 // Decode reads the body of an HTTP request looking for a JSON document. The
 // body is decoded into the provided value.
 //
+// Unknown fields, trailing data and bodies larger than the limit set by
+// http.MaxBytesReader are rejected with an error naming the problem so
+// clients can fix their request.
+//
 // If the provided value is a struct then it is checked for validation tags.
 func Decode(r *http.Request, val interface{}) error {
 	decoder := json.NewDecoder(r.Body)
 	decoder.DisallowUnknownFields()
 	if err := decoder.Decode(val); err != nil {
-		return NewRequestError(err, http.StatusBadRequest)
+		return decodeError(err)
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return NewRequestError(errors.New("request body must only contain a single JSON document"), http.StatusBadRequest)
+	}
+
+	return Validate(val)
+}
+
+// DecodeFile reads the file sent in the field of a multipart/form-data
+// request. Bodies larger than the limit are rejected like by Decode.
+func DecodeFile(w http.ResponseWriter, r *http.Request, field string, limit int64) ([]byte, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, limit)
+
+	f, _, err := r.FormFile(field)
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			err := fmt.Errorf("request body must not be larger than %d bytes", maxErr.Limit)
+			return nil, NewRequestError(err, http.StatusRequestEntityTooLarge)
+		}
+		return nil, NewRequestError(fmt.Errorf("request must be multipart/form-data with a %q file", field), http.StatusBadRequest)
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, NewRequestError(fmt.Errorf("reading %q file: %v", field, err), http.StatusBadRequest)
 	}
 
+	return data, nil
+}
+
+// Validate checks the validation tags of the struct, and then its Check method
+// when it is a Checker. A failure is returned as an *Error listing the
+// offending fields.
+func Validate(val interface{}) error {
 	if err := validate.Struct(val); err != nil {
 
 		// Use a type assertion to get the real error value.
@@ -80,5 +121,79 @@ func Decode(r *http.Request, val interface{}) error {
 		}
 	}
 
+	if c, ok := val.(Checker); ok {
+		if failed := c.Check(); len(failed) > 0 {
+			fields := make([]FieldError, 0, len(failed))
+			for field, msg := range failed {
+				fields = append(fields, FieldError{Field: field, Error: msg})
+			}
+			sort.Slice(fields, func(i, j int) bool { return fields[i].Field < fields[j].Field })
+
+			return &Error{
+				Err:    errors.New("field validation error"),
+				Status: http.StatusBadRequest,
+				Fields: fields,
+			}
+		}
+	}
+
 	return nil
 }
+
+// Checker is implemented by values with rules their validation tags can not
+// express, like a date within a window of time. Validate calls Check once the
+// tags pass. It returns the message of each failing field keyed by the JSON
+// name of the field.
+type Checker interface {
+	Check() map[string]string
+}
+
+// decodeError converts an error from decoding a request body into an *Error
+// describing what is wrong with the body.
+func decodeError(err error) error {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		err := fmt.Errorf("request body must not be larger than %d bytes", maxErr.Limit)
+		return NewRequestError(err, http.StatusRequestEntityTooLarge)
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case err == io.EOF:
+		return NewRequestError(errors.New("request body must not be empty"), http.StatusBadRequest)
+
+	case err == io.ErrUnexpectedEOF:
+		return NewRequestError(errors.New("request body contains badly-formed JSON"), http.StatusBadRequest)
+
+	case errors.As(err, &syntaxErr):
+		err := fmt.Errorf("request body contains badly-formed JSON (at position %d)", syntaxErr.Offset)
+		return NewRequestError(err, http.StatusBadRequest)
+
+	case errors.As(err, &typeErr):
+		return &Error{
+			Err:    errors.New("field validation error"),
+			Status: http.StatusBadRequest,
+			Fields: []FieldError{{
+				Field: typeErr.Field,
+				Error: fmt.Sprintf("%s must be of type %s", typeErr.Field, typeErr.Type),
+			}},
+		}
+
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+
+		// The json package has no error type for unknown fields so the field
+		// name is taken from the message.
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return &Error{
+			Err:    errors.New("field validation error"),
+			Status: http.StatusBadRequest,
+			Fields: []FieldError{{
+				Field: field,
+				Error: fmt.Sprintf("%s is not a known field", field),
+			}},
+		}
+	}
+
+	return NewRequestError(err, http.StatusBadRequest)
+}
@@ -0,0 +1,123 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestDecode validates badly formed request bodies are rejected with an
+// error explaining the problem.
+func TestDecode(t *testing.T) {
+	type doc struct {
+		Name string `json:"name" validate:"required"`
+		Age  int    `json:"age"`
+	}
+
+	tt := []struct {
+		name   string
+		body   string
+		limit  int64
+		status int
+		fields []FieldError
+	}{
+		{"valid", `{"name":"bill"}`, 0, 0, nil},
+		{"empty", ``, 0, http.StatusBadRequest, nil},
+		{"syntax", `{"name":}`, 0, http.StatusBadRequest, nil},
+		{"trailing", `{"name":"bill"} {}`, 0, http.StatusBadRequest, nil},
+		{"unknown", `{"name":"bill","email":"x"}`, 0, http.StatusBadRequest, []FieldError{{Field: "email", Error: "email is not a known field"}}},
+		{"type", `{"name":"bill","age":"old"}`, 0, http.StatusBadRequest, []FieldError{{Field: "age", Error: "age must be of type int"}}},
+		{"size", `{"name":"bill"}`, 5, http.StatusRequestEntityTooLarge, nil},
+	}
+
+	t.Log("Given the need to decode request bodies.")
+	{
+		for i, tc := range tt {
+			t.Logf("\tTest %d:\tWhen decoding a %s body.", i, tc.name)
+			{
+				r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body))
+				if tc.limit > 0 {
+					r.Body = http.MaxBytesReader(httptest.NewRecorder(), r.Body, tc.limit)
+				}
+
+				var d doc
+				err := Decode(r, &d)
+
+				if tc.status == 0 {
+					if err != nil {
+						t.Fatalf("\t✗\tShould decode the body : %v.", err)
+					}
+					t.Log("\t✓\tShould decode the body.")
+					continue
+				}
+
+				webErr, ok := err.(*Error)
+				if !ok {
+					t.Fatalf("\t✗\tShould get a request error : got %v.", err)
+				}
+				if webErr.Status != tc.status {
+					t.Fatalf("\t✗\tShould get status %d : got %d.", tc.status, webErr.Status)
+				}
+				t.Logf("\t✓\tShould get status %d.", tc.status)
+
+				if diff := cmp.Diff(tc.fields, webErr.Fields); diff != "" {
+					t.Fatalf("\t✗\tShould get the expected fields. Diff:\n%s", diff)
+				}
+				t.Log("\t✓\tShould get the expected fields.")
+			}
+		}
+	}
+}
+
+// checked is a request value with a rule its validation tags can not express.
+type checked struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+// Check implements the Checker interface.
+func (c checked) Check() map[string]string {
+	if c.To < c.From {
+		return map[string]string{"to": "to must not be before from"}
+	}
+	return nil
+}
+
+// TestDecodeChecker validates the failures of Check are reported like those
+// of the validation tags.
+func TestDecodeChecker(t *testing.T) {
+	t.Log("Given the need to check values beyond their validation tags.")
+	{
+		t.Log("\tTest 0:\tWhen decoding a body failing the check.")
+		{
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"from":2,"to":1}`))
+
+			var c checked
+			webErr, ok := Decode(r, &c).(*Error)
+			if !ok || webErr.Status != http.StatusBadRequest {
+				t.Fatalf("\t✗\tShould get status 400 : got %v.", webErr)
+			}
+			t.Log("\t✓\tShould get status 400.")
+
+			want := []FieldError{{Field: "to", Error: "to must not be before from"}}
+			if diff := cmp.Diff(want, webErr.Fields); diff != "" {
+				t.Fatalf("\t✗\tShould get the expected fields. Diff:\n%s", diff)
+			}
+			t.Log("\t✓\tShould get the expected fields.")
+		}
+
+		t.Log("\tTest 1:\tWhen decoding a body passing the check.")
+		{
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"from":1,"to":2}`))
+
+			var c checked
+			if err := Decode(r, &c); err != nil {
+				t.Fatalf("\t✗\tShould decode the body : %v.", err)
+			}
+			t.Log("\t✓\tShould decode the body.")
+		}
+	}
+}
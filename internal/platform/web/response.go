@@ -1,12 +1,79 @@
 package web
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"github.com/pkg/errors"
 	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
+// maxPooledBuffer is the capacity above which a buffer is not put back into
+// the pool so a single large response does not pin its memory.
+const maxPooledBuffer = 64 << 10
+
+// encoder is a JSON encoder bound to its own buffer.
+type encoder struct {
+	buf bytes.Buffer
+	enc *json.Encoder
+}
+
+// encoders reuses encoders and their buffers across responses.
+var encoders = sync.Pool{
+	New: func() interface{} {
+		var e encoder
+		e.enc = json.NewEncoder(&e.buf)
+		return &e
+	},
+}
+
+// WantsRepresentation reports if the client asked to receive the resource its
+// request changed, with the Prefer header of RFC 7240 set to
+// return=representation or the return query parameter set to representation.
+func WantsRepresentation(r *http.Request) bool {
+	if strings.EqualFold(r.URL.Query().Get("return"), "representation") {
+		return true
+	}
+	for _, h := range r.Header.Values("Prefer") {
+		for _, pref := range strings.Split(h, ",") {
+			pref = strings.SplitN(pref, ";", 2)[0]
+			kv := strings.SplitN(pref, "=", 2)
+			if len(kv) == 2 && strings.EqualFold(strings.TrimSpace(kv[0]), "return") &&
+				strings.EqualFold(strings.Trim(strings.TrimSpace(kv[1]), `"`), "representation") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RespondUpdated answers a request which changed a resource. The resource is
+// sent back with a 200 when the client asked for it, see WantsRepresentation,
+// and the response is a 204 without a body otherwise.
+func RespondUpdated(ctx context.Context, w http.ResponseWriter, r *http.Request, data interface{}) error {
+	if !WantsRepresentation(r) {
+		return Respond(ctx, w, nil, http.StatusNoContent)
+	}
+	w.Header().Set("Preference-Applied", "return=representation")
+	return Respond(ctx, w, data, http.StatusOK)
+}
+
+// LastModified sets the Last-Modified header of the response so Respond can
+// answer If-Modified-Since requests. It must be called before Respond.
+func LastModified(w http.ResponseWriter, t time.Time) {
+	if t.IsZero() {
+		return
+	}
+	w.Header().Set("Last-Modified", t.UTC().Format(http.TimeFormat))
+}
+
 // Respond converts a Go value to JSON and sends it to the client.
 func Respond(ctx context.Context, w http.ResponseWriter, data interface{}, statusCode int) error {
 
@@ -26,11 +93,144 @@ func Respond(ctx context.Context, w http.ResponseWriter, data interface{}, statu
 	}
 
 	// Convert the response value to JSON.
-	jsonData, err := json.Marshal(data)
-	if err != nil {
+	e := encoders.Get().(*encoder)
+	defer func() {
+		if e.buf.Cap() <= maxPooledBuffer {
+			e.buf.Reset()
+			encoders.Put(e)
+		}
+	}()
+
+	if err := e.enc.Encode(data); err != nil {
 		return err
 	}
 
+	return send(v, w, e.buf.Bytes(), statusCode)
+}
+
+// streamThreshold is the size of a list response above which RespondList
+// streams it instead of sending it as a whole.
+const streamThreshold = maxPooledBuffer / 2
+
+// RespondList sends a list to the client like Respond. A list encoding to
+// less than streamThreshold is sent as a whole with its ETag. A larger one is
+// streamed in chunks so its JSON is never held in memory at once, which means
+// it carries no ETag. Values which are not slices are sent with Respond.
+//
+// From version 2 of the API on, see Version, the list is sent in an envelope
+// as {"count":2,"data":[...]}.
+func RespondList(ctx context.Context, w http.ResponseWriter, list interface{}, statusCode int) error {
+	return RespondListFields(ctx, w, list, nil, statusCode)
+}
+
+// RespondListFields sends a list like RespondList keeping only the members
+// of its objects named in fields, in that order. Without fields it sends all
+// the members. See Fields for reading them from the request.
+func RespondListFields(ctx context.Context, w http.ResponseWriter, list interface{}, fields []string, statusCode int) error {
+	items := reflect.ValueOf(list)
+	if items.Kind() != reflect.Slice {
+		return Respond(ctx, w, list, statusCode)
+	}
+
+	v, ok := ctx.Value(KeyValues).(*Values)
+	if !ok {
+		return NewShutdownError("web value missing from context")
+	}
+	v.StatusCode = statusCode
+
+	e := encoders.Get().(*encoder)
+	defer func() {
+		if e.buf.Cap() <= maxPooledBuffer {
+			e.buf.Reset()
+			encoders.Put(e)
+		}
+	}()
+
+	// encode appends an item to the buffer without the newline the encoder
+	// ends every value with. The item is encoded through its address so it
+	// is not copied into an interface.
+	encode := func(i int) error {
+		if i > 0 {
+			e.buf.WriteByte(',')
+		}
+		start := e.buf.Len()
+		if err := e.enc.Encode(items.Index(i).Addr().Interface()); err != nil {
+			return err
+		}
+		e.buf.Truncate(e.buf.Len() - 1)
+
+		if len(fields) > 0 {
+			item, err := selectFields(e.buf.Bytes()[start:], fields)
+			if err != nil {
+				return err
+			}
+			e.buf.Truncate(start)
+			e.buf.Write(item)
+		}
+		return nil
+	}
+
+	head, tail := "[", "]\n"
+	if Version(ctx) >= 2 {
+		head, tail = `{"count":`+strconv.Itoa(items.Len())+`,"data":[`, "]}\n"
+	}
+
+	e.buf.WriteString(head)
+	i := 0
+	for ; i < items.Len() && e.buf.Len() < streamThreshold; i++ {
+		if err := encode(i); err != nil {
+			return err
+		}
+	}
+
+	if i == items.Len() {
+		e.buf.WriteString(tail)
+		return send(v, w, e.buf.Bytes(), statusCode)
+	}
+
+	// The list is too large to be buffered. Once the status code is written
+	// an error can no longer be reported to the client, it is only returned.
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	for ; i < items.Len(); i++ {
+		if e.buf.Len() >= streamThreshold {
+			if _, err := w.Write(e.buf.Bytes()); err != nil {
+				return err
+			}
+			e.buf.Reset()
+		}
+		if err := encode(i); err != nil {
+			return err
+		}
+	}
+
+	e.buf.WriteString(tail)
+	if _, err := w.Write(e.buf.Bytes()); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// send writes the JSON of a response. Successful reads are answered with a
+// 304 when the client already has the same JSON.
+func send(v *Values, w http.ResponseWriter, jsonData []byte, statusCode int) error {
+
+	// Successful reads carry a validator so clients polling the same
+	// resource get a 304 without a body while nothing changed.
+	if statusCode == http.StatusOK && (v.Method == http.MethodGet || v.Method == http.MethodHead) {
+		sum := sha256.Sum256(jsonData)
+		etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+		w.Header().Set("ETag", etag)
+
+		if notModified(v.Header, w.Header()) {
+			v.StatusCode = http.StatusNotModified
+			w.WriteHeader(http.StatusNotModified)
+			return nil
+		}
+	}
+
 	// Set the content type and headers once we know marshaling has succeeded.
 	w.Header().Set("Content-Type", "application/json")
 
@@ -52,6 +252,7 @@ func RespondError(ctx context.Context, w http.ResponseWriter, err error) error {
 	// a specific status code and error to return.
 	if webErr, ok := errors.Cause(err).(*Error); ok {
 		er := ErrorResponse{
+			Code:   webErr.code(),
 			Error:  webErr.Err.Error(),
 			Fields: webErr.Fields,
 		}
@@ -63,10 +264,40 @@ func RespondError(ctx context.Context, w http.ResponseWriter, err error) error {
 
 	// If not, the handler sent any arbitrary error value so use 500.
 	er := ErrorResponse{
+		Code:  CodeInternal,
 		Error: http.StatusText(http.StatusInternalServerError),
 	}
+	if v, ok := ctx.Value(KeyValues).(*Values); ok {
+		er.TraceID = v.TraceID
+	}
 	if err := Respond(ctx, w, er, http.StatusInternalServerError); err != nil {
 		return err
 	}
 	return nil
 }
+
+// notModified reports if the validators sent by the client still match the
+// response. If-None-Match takes precedence over If-Modified-Since as
+// described in RFC 7232.
+func notModified(req http.Header, resp http.Header) bool {
+	if inm := req.Get("If-None-Match"); inm != "" {
+		etag := resp.Get("ETag")
+		for _, tag := range strings.Split(inm, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	ims, err := http.ParseTime(req.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	lm, err := http.ParseTime(resp.Get("Last-Modified"))
+	if err != nil {
+		return false
+	}
+	return !lm.After(ims)
+}
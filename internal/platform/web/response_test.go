@@ -0,0 +1,416 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// TestRespondConditional validates GET responses carry an ETag and are
+// answered with 304 when the client already has the current representation.
+func TestRespondConditional(t *testing.T) {
+	updated := time.Date(2020, time.March, 1, 12, 0, 0, 0, time.UTC)
+	data := map[string]string{"name": "Pizza Place"}
+
+	respond := func(method string, header http.Header) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		v := Values{Method: method, Header: header}
+		ctx := context.WithValue(context.Background(), KeyValues, &v)
+
+		LastModified(w, updated)
+		if err := Respond(ctx, w, data, http.StatusOK); err != nil {
+			t.Fatalf("\t✗\tShould be able to respond : %v.", err)
+		}
+		return w
+	}
+
+	t.Log("Given the need to answer conditional requests.")
+	{
+		t.Log("\tTest 0:\tWhen handling a GET without validators.")
+		{
+			w := respond(http.MethodGet, http.Header{})
+			if w.Code != http.StatusOK {
+				t.Fatalf("\t✗\tShould receive a status code of 200 : got %d.", w.Code)
+			}
+			t.Log("\t✓\tShould receive a status code of 200.")
+
+			etag := w.Header().Get("ETag")
+			if etag == "" {
+				t.Fatal("\t✗\tShould receive an ETag.")
+			}
+			t.Log("\t✓\tShould receive an ETag.")
+
+			t.Log("\tTest 1:\tWhen handling a GET with a matching If-None-Match.")
+			{
+				w := respond(http.MethodGet, http.Header{"If-None-Match": {`"other", W/` + etag}})
+				if w.Code != http.StatusNotModified {
+					t.Fatalf("\t✗\tShould receive a status code of 304 : got %d.", w.Code)
+				}
+				if w.Body.Len() != 0 {
+					t.Fatalf("\t✗\tShould receive an empty body : got %q.", w.Body.String())
+				}
+				t.Log("\t✓\tShould receive a status code of 304 without a body.")
+			}
+
+			t.Log("\tTest 2:\tWhen handling a GET with a stale If-None-Match.")
+			{
+				ims := updated.Add(time.Hour).Format(http.TimeFormat)
+				w := respond(http.MethodGet, http.Header{"If-None-Match": {`"other"`}, "If-Modified-Since": {ims}})
+				if w.Code != http.StatusOK {
+					t.Fatalf("\t✗\tShould receive a status code of 200 : got %d.", w.Code)
+				}
+				t.Log("\t✓\tShould ignore If-Modified-Since and receive a status code of 200.")
+			}
+		}
+
+		t.Log("\tTest 3:\tWhen handling a GET with If-Modified-Since.")
+		{
+			w := respond(http.MethodGet, http.Header{"If-Modified-Since": {updated.Format(http.TimeFormat)}})
+			if w.Code != http.StatusNotModified {
+				t.Fatalf("\t✗\tShould receive a status code of 304 : got %d.", w.Code)
+			}
+			t.Log("\t✓\tShould receive a status code of 304 when not modified since.")
+
+			w = respond(http.MethodGet, http.Header{"If-Modified-Since": {updated.Add(-time.Hour).Format(http.TimeFormat)}})
+			if w.Code != http.StatusOK {
+				t.Fatalf("\t✗\tShould receive a status code of 200 : got %d.", w.Code)
+			}
+			t.Log("\t✓\tShould receive a status code of 200 when modified since.")
+		}
+
+		t.Log("\tTest 4:\tWhen handling a POST.")
+		{
+			w := respond(http.MethodPost, http.Header{"If-None-Match": {"*"}})
+			if w.Code != http.StatusOK {
+				t.Fatalf("\t✗\tShould receive a status code of 200 : got %d.", w.Code)
+			}
+			if w.Header().Get("ETag") != "" {
+				t.Fatal("\t✗\tShould not receive an ETag.")
+			}
+			t.Log("\t✓\tShould not evaluate validators.")
+		}
+	}
+}
+
+// benchRestaurant mirrors the shape of the restaurant list response.
+type benchRestaurant struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Address     string    `json:"address"`
+	OwnerUserID string    `json:"owner_user_id"`
+	Website     string    `json:"website"`
+	Phone       string    `json:"phone"`
+	Photos      []string  `json:"photos"`
+	Public      bool      `json:"public"`
+	DateCreated time.Time `json:"date_created"`
+	DateUpdated time.Time `json:"date_updated"`
+}
+
+// benchRestaurants returns a list of n restaurants.
+func benchRestaurants(n int) []benchRestaurant {
+	now := time.Date(2020, time.March, 1, 12, 0, 0, 0, time.UTC)
+	rs := make([]benchRestaurant, n)
+	for i := range rs {
+		rs[i] = benchRestaurant{
+			ID:          "a2b0639f-2cc6-44b8-b97b-15d69dbb511e",
+			Name:        "Pizza Place",
+			Address:     "1 Main Street",
+			OwnerUserID: "5cf37266-3473-4006-984f-9325122678b7",
+			Website:     "https://pizza.example.com",
+			Phone:       "+370 600 00000",
+			Photos:      []string{"https://pizza.example.com/1.jpg", "https://pizza.example.com/2.jpg"},
+			DateCreated: now,
+			DateUpdated: now,
+		}
+	}
+	return rs
+}
+
+// discard is a ResponseWriter which throws away the response.
+type discard struct {
+	header http.Header
+}
+
+func (d *discard) Header() http.Header         { return d.header }
+func (d *discard) Write(b []byte) (int, error) { return len(b), nil }
+func (d *discard) WriteHeader(int)             {}
+
+// BenchmarkRespond measures responding with a list of restaurants.
+func BenchmarkRespond(b *testing.B) {
+	for _, n := range []int{1, 50, 5000} {
+		data := benchRestaurants(n)
+
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			v := Values{Method: http.MethodGet, Header: http.Header{}}
+			ctx := context.WithValue(context.Background(), KeyValues, &v)
+			w := discard{header: http.Header{}}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := Respond(ctx, &w, data, http.StatusOK); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkMarshal is the baseline of encoding the same list with
+// json.Marshal, which allocates a new buffer for every response.
+func BenchmarkMarshal(b *testing.B) {
+	for _, n := range []int{1, 50, 5000} {
+		data := benchRestaurants(n)
+
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := json.Marshal(data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkRespondList measures streaming a list of restaurants. Large lists
+// keep the memory of a response bounded instead of growing with the list.
+func BenchmarkRespondList(b *testing.B) {
+	for _, n := range []int{1, 50, 5000} {
+		data := benchRestaurants(n)
+
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			v := Values{Method: http.MethodGet, Header: http.Header{}}
+			ctx := context.WithValue(context.Background(), KeyValues, &v)
+			w := discard{header: http.Header{}}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := RespondList(ctx, &w, data, http.StatusOK); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// TestRespondList validates lists are sent whole when small and streamed when
+// large, with the same JSON either way.
+func TestRespondList(t *testing.T) {
+	respond := func(list interface{}) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		v := Values{Method: http.MethodGet, Header: http.Header{}}
+		ctx := context.WithValue(context.Background(), KeyValues, &v)
+
+		if err := RespondList(ctx, w, list, http.StatusOK); err != nil {
+			t.Fatalf("\t✗\tShould be able to respond : %v.", err)
+		}
+		return w
+	}
+
+	t.Log("Given the need to send lists of any size.")
+	{
+		for i, n := range []int{0, 3, 5000} {
+			t.Logf("\tTest %d:\tWhen sending a list of %d items.", i, n)
+			{
+				w := respond(benchRestaurants(n))
+				if w.Code != http.StatusOK {
+					t.Fatalf("\t✗\tShould receive a status code of 200 : got %d.", w.Code)
+				}
+
+				var got []benchRestaurant
+				if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+					t.Fatalf("\t✗\tShould receive valid JSON : %v.", err)
+				}
+				if len(got) != n {
+					t.Fatalf("\t✗\tShould receive %d items : got %d.", n, len(got))
+				}
+				t.Logf("\t✓\tShould receive %d items.", n)
+
+				streamed := w.Body.Len() > streamThreshold
+				if etag := w.Header().Get("ETag"); (etag == "") != streamed {
+					t.Fatalf("\t✗\tShould only receive an ETag when not streamed : got %q.", etag)
+				}
+				t.Log("\t✓\tShould only receive an ETag when not streamed.")
+			}
+		}
+	}
+}
+
+// TestRespondErrorCode validates error responses carry a code clients can
+// branch on.
+func TestRespondErrorCode(t *testing.T) {
+	tt := []struct {
+		name string
+		err  error
+		code string
+	}{
+		{"coded error", NewCodedError(errors.New("Restaurant not found"), http.StatusNotFound, "RESTAURANT_NOT_FOUND"), "RESTAURANT_NOT_FOUND"},
+		{"request error", NewRequestError(errors.New("no"), http.StatusForbidden), "FORBIDDEN"},
+		{"field error", &Error{Err: errors.New("field validation error"), Status: http.StatusBadRequest, Fields: []FieldError{{Field: "name"}}}, CodeValidationFailed},
+		{"unexpected error", errors.New("db down"), CodeInternal},
+	}
+
+	t.Log("Given the need to identify errors by code.")
+	{
+		for i, tc := range tt {
+			t.Logf("\tTest %d:\tWhen responding with a %s.", i, tc.name)
+			{
+				w := httptest.NewRecorder()
+				ctx := context.WithValue(context.Background(), KeyValues, &Values{})
+				if err := RespondError(ctx, w, tc.err); err != nil {
+					t.Fatalf("\t✗\tShould be able to respond : %v.", err)
+				}
+
+				var er ErrorResponse
+				if err := json.NewDecoder(w.Body).Decode(&er); err != nil {
+					t.Fatalf("\t✗\tShould be able to decode the response : %v.", err)
+				}
+				if er.Code != tc.code {
+					t.Fatalf("\t✗\tShould receive code %s : got %s.", tc.code, er.Code)
+				}
+				t.Logf("\t✓\tShould receive code %s.", tc.code)
+			}
+		}
+	}
+}
+
+// TestRespondUpdated validates updates answer with the resource only when the
+// client asked for it.
+func TestRespondUpdated(t *testing.T) {
+	data := map[string]int{"version": 2}
+
+	tt := []struct {
+		name   string
+		target string
+		prefer string
+		status int
+	}{
+		{"without a preference", "/v1/restaurant/1", "", http.StatusNoContent},
+		{"preferring a minimal response", "/v1/restaurant/1", "return=minimal", http.StatusNoContent},
+		{"preferring the representation", "/v1/restaurant/1", "respond-async, RETURN=\"representation\"; foo=bar", http.StatusOK},
+		{"asking with the query", "/v1/restaurant/1?return=representation", "", http.StatusOK},
+	}
+
+	t.Log("Given the need to return updated resources on request.")
+	{
+		for i, tc := range tt {
+			t.Logf("\tTest %d:\tWhen updating %s.", i, tc.name)
+			{
+				r := httptest.NewRequest(http.MethodPut, tc.target, nil)
+				if tc.prefer != "" {
+					r.Header.Set("Prefer", tc.prefer)
+				}
+				w := httptest.NewRecorder()
+				v := Values{Method: http.MethodPut, Header: r.Header}
+				ctx := context.WithValue(context.Background(), KeyValues, &v)
+
+				if err := RespondUpdated(ctx, w, r, data); err != nil {
+					t.Fatalf("\t✗\tShould be able to respond : %v.", err)
+				}
+				if w.Code != tc.status {
+					t.Fatalf("\t✗\tShould receive a status code of %d : got %d.", tc.status, w.Code)
+				}
+				t.Logf("\t✓\tShould receive a status code of %d.", tc.status)
+
+				if tc.status == http.StatusNoContent {
+					if w.Body.Len() != 0 {
+						t.Fatalf("\t✗\tShould receive an empty body : got %q.", w.Body.String())
+					}
+					t.Log("\t✓\tShould receive an empty body.")
+					continue
+				}
+
+				var got map[string]int
+				if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil || got["version"] != 2 {
+					t.Fatalf("\t✗\tShould receive the updated resource : %q.", w.Body.String())
+				}
+				t.Log("\t✓\tShould receive the updated resource.")
+				if pa := w.Header().Get("Preference-Applied"); pa != "return=representation" {
+					t.Fatalf("\t✗\tShould receive the applied preference : got %q.", pa)
+				}
+				t.Log("\t✓\tShould receive the applied preference.")
+			}
+		}
+	}
+}
+
+// TestRespondListFields validates lists are trimmed to the members the
+// client asked for with the fields query parameter.
+func TestRespondListFields(t *testing.T) {
+	respond := func(list interface{}, query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		v := Values{Method: http.MethodGet, Header: http.Header{}}
+		ctx := context.WithValue(context.Background(), KeyValues, &v)
+
+		r := httptest.NewRequest(http.MethodGet, "/"+query, nil)
+		if err := RespondListFields(ctx, w, list, Fields(r), http.StatusOK); err != nil {
+			t.Fatalf("\t✗\tShould be able to respond : %v.", err)
+		}
+		return w
+	}
+
+	const item = `{"id":"a2b0639f-2cc6-44b8-b97b-15d69dbb511e","name":"Pizza Place","address":"1 Main Street"}`
+
+	t.Log("Given the need to send the fields of a list the client asked for.")
+	{
+		t.Log("\tTest 0:\tWhen asking for some fields.")
+		{
+			w := respond(benchRestaurants(2), "?fields=id,%20name,address,name,unknown")
+			if want := "[" + item + "," + item + "]\n"; w.Body.String() != want {
+				t.Fatalf("\t✗\tShould only receive the fields : got %s.", w.Body)
+			}
+			t.Log("\t✓\tShould only receive the fields.")
+		}
+
+		t.Log("\tTest 1:\tWhen asking for the fields in another order.")
+		{
+			w := respond(benchRestaurants(1), "?fields=name,id")
+			if want := `[{"name":"Pizza Place","id":"a2b0639f-2cc6-44b8-b97b-15d69dbb511e"}]` + "\n"; w.Body.String() != want {
+				t.Fatalf("\t✗\tShould receive the fields in the order asked : got %s.", w.Body)
+			}
+			t.Log("\t✓\tShould receive the fields in the order asked.")
+		}
+
+		t.Log("\tTest 2:\tWhen streaming a list with some fields.")
+		{
+			w := respond(benchRestaurants(5000), "?fields=id")
+			var got []map[string]string
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("\t✗\tShould receive valid JSON : %v.", err)
+			}
+			if len(got) != 5000 || len(got[4999]) != 1 || got[4999]["id"] == "" {
+				t.Fatalf("\t✗\tShould only receive the ids of every item : got %d items.", len(got))
+			}
+			t.Log("\t✓\tShould only receive the ids of every item.")
+		}
+
+		t.Log("\tTest 3:\tWhen asking for no fields.")
+		{
+			w := respond(benchRestaurants(1), "?fields=")
+			var got []map[string]interface{}
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("\t✗\tShould receive valid JSON : %v.", err)
+			}
+			if len(got) != 1 || len(got[0]) != 10 {
+				t.Fatalf("\t✗\tShould receive all the fields : got %v.", got)
+			}
+			t.Log("\t✓\tShould receive all the fields.")
+		}
+
+		t.Log("\tTest 4:\tWhen the items are not objects.")
+		{
+			w := respond([]string{"a", "b"}, "?fields=id")
+			if want := `["a","b"]` + "\n"; w.Body.String() != want {
+				t.Fatalf("\t✗\tShould receive the items as they are : got %s.", w.Body)
+			}
+			t.Log("\t✓\tShould receive the items as they are.")
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// mediaTypeVersion is the beginning of the media types clients accept to ask
+// for a version of the API, as in application/vnd.restaurant.v2+json.
+const mediaTypeVersion = "application/vnd.restaurant.v"
+
+// Versions creates a group whose routes are mounted once per version of the
+// API, under /v1, /v2 and so on. The handlers are shared by the versions and
+// tell which one they serve with Version, so a version can change the shape
+// of the responses without breaking the clients of the others.
+func (a *App) Versions(versions []int, mw ...Middleware) *Group {
+	return &Group{
+		app:      a,
+		mw:       mw,
+		versions: versions,
+	}
+}
+
+// Version returns the version of the API serving the request. Routes which
+// are not versioned are served by the first version.
+func Version(ctx context.Context) int {
+	v, ok := ctx.Value(KeyValues).(*Values)
+	if !ok || v.Version == 0 {
+		return 1
+	}
+	return v.Version
+}
+
+// setVersion sets the version of the API serving the requests of a route.
+func setVersion(version int) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+			if v, ok := ctx.Value(KeyValues).(*Values); ok {
+				v.Version = version
+			}
+			return next(ctx, w, r, params)
+		}
+	}
+}
+
+// NegotiateVersion lets the clients of versioned routes ask for one of the
+// versions with the Accept header, as in
+// Accept: application/vnd.restaurant.v2+json, whatever the version of the
+// path. Other versions are ignored and the request is served by the version
+// of its path.
+func NegotiateVersion(versions ...int) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+			w.Header().Add("Vary", "Accept")
+
+			if v, ok := ctx.Value(KeyValues).(*Values); ok {
+				if version, ok := acceptedVersion(r.Header, versions); ok {
+					v.Version = version
+				}
+			}
+			return next(ctx, w, r, params)
+		}
+	}
+}
+
+// acceptedVersion returns the first of the versions the client accepts.
+func acceptedVersion(h http.Header, versions []int) (int, bool) {
+	for _, accept := range h.Values("Accept") {
+		for _, mt := range strings.Split(accept, ",") {
+			mt = strings.TrimSpace(strings.SplitN(mt, ";", 2)[0])
+			if !strings.HasPrefix(mt, mediaTypeVersion) || !strings.HasSuffix(mt, "+json") {
+				continue
+			}
+			version, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(mt, mediaTypeVersion), "+json"))
+			if err != nil {
+				continue
+			}
+			for _, v := range versions {
+				if v == version {
+					return version, true
+				}
+			}
+		}
+	}
+	return 0, false
+}
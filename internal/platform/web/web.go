@@ -2,12 +2,17 @@ package web
 
 import (
 	"context"
+	"fmt"
 	"github.com/dimfeld/httptreemux/v5"
-	"go.opencensus.io/plugin/ochttp"
-	"go.opencensus.io/plugin/ochttp/propagation/tracecontext"
-	"go.opencensus.io/trace"
+	"github.com/google/uuid"
+	"github.com/remisb/restaurant/internal/platform/clock"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
 	"net/http"
 	"os"
+	"regexp"
+	"sort"
+	"strings"
 	"syscall"
 	"time"
 )
@@ -23,6 +28,15 @@ type Values struct {
 	TraceID    string
 	Now        time.Time
 	StatusCode int
+
+	// Method and Header of the request, used by Respond to answer
+	// conditional requests.
+	Method string
+	Header http.Header
+
+	// Version is the version of the API serving the request, zero when the
+	// route is not versioned. See Version.
+	Version int
 }
 
 // A Handler is a type that handles an http request within our own little mini
@@ -34,9 +48,10 @@ type Handler func(ctx context.Context, w http.ResponseWriter, r *http.Request, p
 // data/logic on this App struct
 type App struct{
 	*httptreemux.TreeMux
-	och *ochttp.Handler
+	otmux http.Handler
 	shutdown chan os.Signal
 	mw []Middleware
+	clock clock.Clock
 }
 
 // NewApp creates an App value that handle a set of routes for the application.
@@ -45,22 +60,25 @@ func NewApp(shutdown chan os.Signal, mw ...Middleware) *App {
 		TreeMux: httptreemux.New(),
 		shutdown: shutdown,
 		mw: mw,
+		clock: clock.System,
 	}
 
-	// Create an OpenCensus HTTP Handler which wraps the router. This will start
+	// Create an OpenTelemetry HTTP Handler which wraps the router. This will start
 	// the initial span and annotate it with information about the request/response.
 	//
-	// This is configured to use the W3C TraceContext standard to set the remote
-	// parent if an client request includes the appropriate headers.
-	// https://w3c.github.io/trace-context/
-	app.och = &ochttp.Handler{
-		Handler:     app.TreeMux,
-		Propagation: &tracecontext.HTTPFormat{},
-	}
+	// The remote parent is taken from the request headers using the globally
+	// configured propagator, see the tracing package.
+	app.otmux = otelhttp.NewHandler(app.TreeMux, "request")
 
 	return &app
 }
 
+// SetClock sets the clock telling the time of the requests, see Values.Now.
+// The clock of the system is used by default.
+func (a *App) SetClock(c clock.Clock) {
+	a.clock = c
+}
+
 // SignalShutdown is used to gracefully shutdown the app when an integrity
 // issue is identified.
 func (a *App) SignalShutdown() {
@@ -69,7 +87,18 @@ func (a *App) SignalShutdown() {
 
 // Handle is our mechanism for mounting Handlers for a given HTTP verb and path
 // pair, this makes for really easy, convenient routing.
+//
+// A parameter of the path can be given a type, as in /users/:id<uuid>. A
+// request whose parameter is not of its type is answered with a 400 before the
+// handler runs. See paramTypes for the known types.
 func (a *App) Handle(verb, path string, handler Handler, mw ...Middleware) {
+	path, types := parsePath(path)
+
+	// Check the typed parameters right before the handler, so the request
+	// went through authentication and the other middleware first.
+	if len(types) > 0 {
+		handler = checkParams(types, handler)
+	}
 
 	// First wrap handler specific middleware around this handler.
 	handler = wrapMiddleware(mw, handler)
@@ -79,14 +108,16 @@ func (a *App) Handle(verb, path string, handler Handler, mw ...Middleware) {
 
 	// The function to execute for each request.
 	h := func(w http.ResponseWriter, r *http.Request, params map[string]string) {
-		ctx, span := trace.StartSpan(r.Context(), "internal.platform.web")
+		ctx, span := otel.Tracer("").Start(r.Context(), "internal.platform.web")
 		defer span.End()
 
 		// Set the context with the required values to
 		// process the request.
 		v := Values{
-			TraceID: span.SpanContext().TraceID.String(),
-			Now:     time.Now(),
+			TraceID: span.SpanContext().TraceID().String(),
+			Now:     a.clock.Now(),
+			Method:  r.Method,
+			Header:  r.Header,
 		}
 		ctx = context.WithValue(ctx, KeyValues, &v)
 
@@ -101,9 +132,141 @@ func (a *App) Handle(verb, path string, handler Handler, mw ...Middleware) {
 	a.TreeMux.Handle(verb, path, h)
 }
 
+// paramType is a type of route parameter.
+type paramType struct {
+	valid func(value string) bool
+	err   string
+}
+
+// paramTypes are the types a route parameter can be given.
+var paramTypes = map[string]paramType{
+	"uuid": {
+		valid: func(value string) bool {
+			_, err := uuid.Parse(value)
+			return err == nil
+		},
+		err: "must be a UUID",
+	},
+	"slug": {
+		valid: regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`).MatchString,
+		err:   "must be lowercase letters and digits joined by hyphens",
+	},
+	"snake": {
+		valid: regexp.MustCompile(`^[a-z0-9]+(_[a-z0-9]+)*$`).MatchString,
+		err:   "must be lowercase letters and digits joined by underscores",
+	},
+}
+
+// parsePath removes the types from the parameters of the path, which is how
+// the router expects it, and returns the type of each typed parameter. It
+// panics on an unknown type since routes are mounted at startup.
+func parsePath(path string) (string, map[string]paramType) {
+	var types map[string]paramType
+
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if !strings.HasPrefix(segment, ":") || !strings.HasSuffix(segment, ">") {
+			continue
+		}
+		open := strings.Index(segment, "<")
+		if open < 0 {
+			continue
+		}
+
+		name, typ := segment[1:open], segment[open+1:len(segment)-1]
+		pt, ok := paramTypes[typ]
+		if !ok {
+			panic(fmt.Sprintf("web: unknown type %q of parameter %q in route %s", typ, name, path))
+		}
+		if types == nil {
+			types = make(map[string]paramType)
+		}
+		types[name] = pt
+		segments[i] = ":" + name
+	}
+
+	return strings.Join(segments, "/"), types
+}
+
+// checkParams wraps the handler with the check of the typed parameters of its
+// route. The error of a malformed parameter names it so clients can tell
+// which one is wrong.
+func checkParams(types map[string]paramType, handler Handler) Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+		var fields []FieldError
+		for name, pt := range types {
+			if !pt.valid(params[name]) {
+				fields = append(fields, FieldError{Field: name, Error: pt.err})
+			}
+		}
+		if len(fields) > 0 {
+			sort.Slice(fields, func(i, j int) bool { return fields[i].Field < fields[j].Field })
+			return &Error{
+				Err:    ErrInvalidParam,
+				Status: http.StatusBadRequest,
+				Code:   CodeInvalidID,
+				Fields: fields,
+			}
+		}
+
+		return handler(ctx, w, r, params)
+	}
+}
+
+// Group creates a set of routes which share the path prefix and middleware.
+// The group middleware runs after the application middleware and before the
+// middleware of the route.
+func (a *App) Group(prefix string, mw ...Middleware) *Group {
+	return &Group{
+		app:    a,
+		prefix: prefix,
+		mw:     mw,
+	}
+}
+
+// Group is a set of routes of an App sharing a path prefix and middleware.
+type Group struct {
+	app      *App
+	prefix   string
+	mw       []Middleware
+	versions []int
+}
+
+// Group creates a nested group. Its prefix is appended to the prefix of g and
+// its middleware runs after the middleware of g.
+func (g *Group) Group(prefix string, mw ...Middleware) *Group {
+	return &Group{
+		app:      g.app,
+		prefix:   g.prefix + prefix,
+		mw:       join(g.mw, mw),
+		versions: g.versions,
+	}
+}
+
+// Handle mounts the handler for the HTTP verb and the path within the group.
+// The routes of a group of versions are mounted once per version.
+func (g *Group) Handle(verb, path string, handler Handler, mw ...Middleware) {
+	if len(g.versions) == 0 {
+		g.app.Handle(verb, g.prefix+path, handler, join(g.mw, mw)...)
+		return
+	}
+	for _, version := range g.versions {
+		prefix := fmt.Sprintf("/v%d", version) + g.prefix
+		g.app.Handle(verb, prefix+path, handler, join(join([]Middleware{setVersion(version)}, g.mw), mw)...)
+	}
+}
+
+// join returns the middleware of a followed by the middleware of b without
+// sharing the backing array of a.
+func join(a, b []Middleware) []Middleware {
+	mw := make([]Middleware, 0, len(a)+len(b))
+	mw = append(mw, a...)
+	return append(mw, b...)
+}
+
 // ServeHTTP implements the http.Handler interface. It overrides the ServeHTTP
-// of the embedded TreeMux by using the ochttp.Handler instead. That Handler
+// of the embedded TreeMux by using the otelhttp Handler instead. That Handler
 // wraps the TreeMux handler so the routes are served.
 func (a *App) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	a.och.ServeHTTP(w, r)
+	a.otmux.ServeHTTP(w, r)
 }
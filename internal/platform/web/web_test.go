@@ -0,0 +1,256 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/remisb/restaurant/internal/platform/clock"
+)
+
+// TestGroup validates routes of a group are mounted below its prefix and run
+// the middleware of the app, the groups and the route in that order.
+func TestGroup(t *testing.T) {
+	var order []string
+	mark := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+				order = append(order, name)
+				return next(ctx, w, r, params)
+			}
+		}
+	}
+
+	app := NewApp(make(chan os.Signal, 1), mark("app"))
+	v1 := app.Group("/v1", mark("v1"))
+	users := v1.Group("/users", mark("users"))
+	v1.Handle(http.MethodGet, "/health", func(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+		order = append(order, "health")
+		return nil
+	})
+	users.Handle(http.MethodGet, "/:id", func(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+		order = append(order, "user "+params["id"])
+		return nil
+	}, mark("route"))
+
+	tt := []struct {
+		path  string
+		order string
+	}{
+		{"/v1/health", "app v1 health"},
+		{"/v1/users/42", "app v1 users route user 42"},
+	}
+
+	t.Log("Given the need to group routes.")
+	{
+		for i, tc := range tt {
+			t.Logf("\tTest %d:\tWhen requesting %s.", i, tc.path)
+			{
+				order = nil
+				app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+				if got := strings.Join(order, " "); got != tc.order {
+					t.Fatalf("\t✗\tShould run %q : got %q.", tc.order, got)
+				}
+				t.Logf("\t✓\tShould run %q.", tc.order)
+			}
+		}
+	}
+}
+
+// TestTypedParams validates typed route parameters are checked before the
+// handler runs.
+func TestTypedParams(t *testing.T) {
+	const id = "5cf37266-3473-4006-984f-9325122678b7"
+
+	respondErrors := func(next Handler) Handler {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+			if err := next(ctx, w, r, params); err != nil {
+				return RespondError(ctx, w, err)
+			}
+			return nil
+		}
+	}
+
+	var called string
+	app := NewApp(make(chan os.Signal, 1), respondErrors)
+	app.Handle(http.MethodGet, "/restaurant/:restaurantId<uuid>/menu/:menuId<uuid>", func(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+		called = params["restaurantId"] + " " + params["menuId"]
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	})
+	app.Handle(http.MethodGet, "/tags/:slug<slug>", func(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+		called = params["slug"]
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	})
+
+	tt := []struct {
+		path   string
+		status int
+		called string
+		body   string
+	}{
+		{"/restaurant/" + id + "/menu/" + id, http.StatusNoContent, id + " " + id, ""},
+		{"/restaurant/abc/menu/" + id, http.StatusBadRequest, "", `{"code":"INVALID_ID","error":"ID is not in its proper form","fields":[{"field":"restaurantId","error":"must be a UUID"}]}`},
+		{"/restaurant/abc/menu/42", http.StatusBadRequest, "", `{"code":"INVALID_ID","error":"ID is not in its proper form","fields":[{"field":"menuId","error":"must be a UUID"},{"field":"restaurantId","error":"must be a UUID"}]}`},
+		{"/tags/gluten-free", http.StatusNoContent, "gluten-free", ""},
+		{"/tags/Gluten_Free", http.StatusBadRequest, "", `{"code":"INVALID_ID","error":"ID is not in its proper form","fields":[{"field":"slug","error":"must be lowercase letters and digits joined by hyphens"}]}`},
+	}
+
+	t.Log("Given the need to check typed route parameters.")
+	{
+		for i, tc := range tt {
+			t.Logf("\tTest %d:\tWhen requesting %s.", i, tc.path)
+			{
+				called = ""
+				w := httptest.NewRecorder()
+				app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+				if w.Code != tc.status {
+					t.Fatalf("\t✗\tShould receive a status code of %d : got %d.", tc.status, w.Code)
+				}
+				t.Logf("\t✓\tShould receive a status code of %d.", tc.status)
+
+				if called != tc.called {
+					t.Fatalf("\t✗\tShould call the handler with %q : got %q.", tc.called, called)
+				}
+				t.Logf("\t✓\tShould call the handler with %q.", tc.called)
+
+				if got := strings.TrimSpace(w.Body.String()); tc.body != "" && got != tc.body {
+					t.Fatalf("\t✗\tShould name the malformed parameters : got %s.", got)
+				}
+				t.Logf("\t✓\tShould name the malformed parameters.")
+			}
+		}
+	}
+
+	t.Log("\tTest 5:\tWhen a route uses an unknown type.")
+	{
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("\t✗\tShould panic when mounting the route.")
+			}
+			t.Logf("\t✓\tShould panic when mounting the route.")
+		}()
+		app.Handle(http.MethodGet, "/teams/:id<int>", func(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+			return nil
+		})
+	}
+}
+
+// TestLink validates the URLs of routes are built from the values of their
+// parameters.
+func TestLink(t *testing.T) {
+	const id = "5cf37266-3473-4006-984f-9325122678b7"
+
+	tt := []struct {
+		route  string
+		values []string
+		link   string
+	}{
+		{"/v1/restaurant/:id", []string{id}, "/v1/restaurant/" + id},
+		{"/v1/restaurant/:restaurantId<uuid>/menu/:menuId<uuid>", []string{id, "42"}, "/v1/restaurant/" + id + "/menu/42"},
+		{"/v1/tags/:slug", []string{"fish & chips/takeaway"}, "/v1/tags/fish%20&%20chips%2Ftakeaway"},
+		{"/v1/health", nil, "/v1/health"},
+	}
+
+	t.Log("Given the need to link to the resources of routes.")
+	{
+		for i, tc := range tt {
+			t.Logf("\tTest %d:\tWhen linking to %s.", i, tc.route)
+			{
+				if got := Link(tc.route, tc.values...); got != tc.link {
+					t.Fatalf("\t✗\tShould link to %s : got %s.", tc.link, got)
+				}
+				t.Logf("\t✓\tShould link to %s.", tc.link)
+			}
+		}
+
+		t.Logf("\tTest %d:\tWhen a value is missing.", len(tt))
+		{
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("\t✗\tShould panic when building the link.")
+				}
+				t.Logf("\t✓\tShould panic when building the link.")
+			}()
+			Link("/v1/restaurant/:restaurantId/menu/:menuId", id)
+		}
+	}
+}
+
+// TestVersions validates the routes of a group of versions are mounted once
+// per version and that clients may ask for a version with the Accept header.
+func TestVersions(t *testing.T) {
+	list := func(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+		return RespondList(ctx, w, []string{"a", "b"}, http.StatusOK)
+	}
+
+	app := NewApp(make(chan os.Signal, 1))
+	app.Versions([]int{1, 2}).Handle(http.MethodGet, "/items", list)
+	app.Versions([]int{1, 2}, NegotiateVersion(1, 2)).Handle(http.MethodGet, "/negotiated", list)
+
+	tt := []struct {
+		path   string
+		accept string
+		body   string
+	}{
+		{"/v1/items", "", `["a","b"]`},
+		{"/v2/items", "", `{"count":2,"data":["a","b"]}`},
+		{"/v1/items", "application/vnd.restaurant.v2+json", `["a","b"]`},
+		{"/v1/negotiated", "application/vnd.restaurant.v2+json", `{"count":2,"data":["a","b"]}`},
+		{"/v2/negotiated", "text/html, application/vnd.restaurant.v1+json;q=0.9", `["a","b"]`},
+		{"/v2/negotiated", "application/vnd.restaurant.v3+json", `{"count":2,"data":["a","b"]}`},
+	}
+
+	t.Log("Given the need to serve several versions of the API.")
+	{
+		for i, tc := range tt {
+			t.Logf("\tTest %d:\tWhen requesting %s accepting %q.", i, tc.path, tc.accept)
+			{
+				r := httptest.NewRequest(http.MethodGet, tc.path, nil)
+				if tc.accept != "" {
+					r.Header.Set("Accept", tc.accept)
+				}
+				w := httptest.NewRecorder()
+				app.ServeHTTP(w, r)
+
+				if got := strings.TrimSpace(w.Body.String()); got != tc.body {
+					t.Fatalf("\t✗\tShould receive %s : got %s.", tc.body, got)
+				}
+				t.Logf("\t✓\tShould receive %s.", tc.body)
+			}
+		}
+	}
+}
+
+// TestClock validates the requests are given the time of the clock of the
+// app.
+func TestClock(t *testing.T) {
+	now := time.Date(2020, time.March, 2, 11, 0, 0, 0, time.UTC)
+
+	var got time.Time
+	app := NewApp(make(chan os.Signal, 1))
+	app.SetClock(clock.Fixed(now))
+	app.Handle(http.MethodGet, "/now", func(ctx context.Context, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+		got = ctx.Value(KeyValues).(*Values).Now
+		return nil
+	})
+
+	t.Log("Given the need to tell the time of requests.")
+	{
+		t.Log("\tTest 0:\tWhen the clock of the app is stopped.")
+		{
+			app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/now", nil))
+			if !got.Equal(now) {
+				t.Fatalf("\t✗\tShould be given the time of the clock : got %v.", got)
+			}
+			t.Logf("\t✓\tShould be given the time of the clock.")
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package report
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/notify/email"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/job"
+	"go.opentelemetry.io/otel"
+)
+
+// Mailer emails the report of the month which just ended to the admins of
+// every organization. The reports sent are recorded so each is sent once,
+// however often the service restarts.
+type Mailer struct {
+	log      *log.Logger
+	db       *sqlx.DB
+	queue    *email.Queue
+	interval time.Duration
+	tracker  *job.Tracker
+}
+
+// NewMailer constructs a Mailer checking for unsent reports every interval.
+func NewMailer(log *log.Logger, db *sqlx.DB, queue *email.Queue, interval time.Duration) *Mailer {
+	return &Mailer{
+		log:      log,
+		db:       db,
+		queue:    queue,
+		interval: interval,
+		tracker:  job.NewTracker("monthly_report"),
+	}
+}
+
+// Status reports the state of the mailer to the health check.
+func (m *Mailer) Status() job.Status {
+	return m.tracker.Status()
+}
+
+// Run sends the reports until the context is canceled.
+func (m *Mailer) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		m.tracker.Record(m.send(ctx, time.Now()), time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// send emails the report of the last month to the organizations which did
+// not receive it yet.
+func (m *Mailer) send(ctx context.Context, now time.Time) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.report.Mailer.send")
+	defer span.End()
+
+	month, err := ParseMonth("", now)
+	if err != nil {
+		return err
+	}
+
+	var orgs []string
+	const q = `SELECT org_id FROM organization AS o
+		WHERE NOT EXISTS (SELECT 1 FROM report_delivery AS d WHERE d.org_id = o.org_id AND d.month = $1)`
+	if err := m.db.SelectContext(ctx, &orgs, q, month); err != nil {
+		return errors.Wrap(err, "selecting organizations")
+	}
+
+	// An organization failing does not keep the others from their report.
+	var failed error
+	for _, org := range orgs {
+		if err := m.sendOrg(ctx, org, month, now); err != nil {
+			m.log.Printf("report : %s : %s : ERROR : %+v", month.Format("2006-01"), org, err)
+			failed = err
+		}
+	}
+
+	return failed
+}
+
+// sendOrg emails the report of the month to the admins of the organization.
+// The delivery is claimed first so only one instance of the service sends
+// it, and released when the report could not be generated.
+func (m *Mailer) sendOrg(ctx context.Context, org string, month, now time.Time) error {
+	const qc = `INSERT INTO report_delivery (org_id, month, date_sent) VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING`
+	res, err := m.db.ExecContext(ctx, qc, org, month, now.UTC())
+	if err != nil {
+		return errors.Wrap(err, "claiming delivery")
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "claiming delivery")
+	}
+	if n == 0 {
+		return nil
+	}
+
+	msgs, err := m.messages(ctx, org, month)
+	if err != nil {
+		const qr = `DELETE FROM report_delivery WHERE org_id = $1 AND month = $2`
+		if _, rerr := m.db.ExecContext(ctx, qr, org, month); rerr != nil {
+			m.log.Printf("report : %s : %s : releasing delivery : ERROR : %+v", month.Format("2006-01"), org, rerr)
+		}
+		return err
+	}
+
+	dropped := 0
+	for _, msg := range msgs {
+		if !m.queue.Enqueue(msg) {
+			dropped++
+		}
+	}
+	if dropped > 0 {
+		return errors.Errorf("email queue full, %d of %d reports dropped", dropped, len(msgs))
+	}
+
+	m.log.Printf("report : %s : %s : sent to %d admins", month.Format("2006-01"), org, len(msgs))
+	return nil
+}
+
+// messages renders the report of the month for every admin of the
+// organization.
+func (m *Mailer) messages(ctx context.Context, org string, month time.Time) ([]email.Message, error) {
+	rep, err := Generate(ctx, m.db, org, month)
+	if err != nil {
+		return nil, err
+	}
+
+	var admins []struct {
+		Name  string `db:"name"`
+		Email string `db:"email"`
+	}
+	const q = `SELECT name, email FROM users
+		WHERE org_id = $1 AND $2 = ANY(roles) AND deleted_at IS NULL`
+	if err := m.db.SelectContext(ctx, &admins, q, org, auth.RoleAdmin); err != nil {
+		return nil, errors.Wrap(err, "selecting admins")
+	}
+
+	data := email.MonthlyReport{
+		Month:  month,
+		Days:   rep.Days,
+		Votes:  rep.Votes,
+		Orders: rep.Orders,
+		Spend:  FormatCents(rep.Spend),
+	}
+	for _, t := range rep.Teams {
+		data.Teams = append(data.Teams, email.MonthlyTeam{
+			Name:          t.Name,
+			Participation: int(math.Round(100 * t.Participation)),
+		})
+	}
+	for _, r := range rep.Restaurants {
+		data.Restaurants = append(data.Restaurants, email.MonthlyRestaurant{
+			Name:  r.Name,
+			Wins:  r.Wins,
+			Votes: r.Votes,
+		})
+	}
+
+	msgs := make([]email.Message, 0, len(admins))
+	for _, a := range admins {
+		data.Name = a.Name
+		msg, err := email.Render(email.TemplateMonthlyReport, a.Email, data)
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, msg)
+	}
+
+	return msgs, nil
+}
+
+// FormatCents formats an amount in cents with two decimals.
+func FormatCents(cents int) string {
+	return fmt.Sprintf("%d.%02d", cents/100, cents%100)
+}
@@ -0,0 +1,144 @@
+// Package report summarizes a month of lunches of an organization: how much
+// its teams took part in the votes, which restaurants won and what was spent
+// on orders.
+package report
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/platform/database"
+	"go.opentelemetry.io/otel"
+)
+
+// Predefined errors identify expected failure conditions.
+var (
+	// ErrInvalidMonth is used when a month is not formatted as YYYY-MM.
+	ErrInvalidMonth = errors.New("month must be formatted as YYYY-MM")
+)
+
+// Monthly is the summary of a month of an organization. Days counts the dates
+// with votes and Spend the orders which were not cancelled, in cents.
+type Monthly struct {
+	OrgID       string       `json:"org_id"`
+	Month       string       `json:"month"`
+	Days        int          `db:"days" json:"days"`
+	Votes       int          `db:"votes" json:"votes"`
+	Orders      int          `json:"orders"`
+	Spend       int          `json:"spend"`
+	Teams       []Team       `json:"teams"`
+	Restaurants []Restaurant `json:"restaurants"`
+}
+
+// Team is how much the members of a team took part in the votes of a month.
+// Participation is the share of the members who voted on an average day.
+type Team struct {
+	TeamID        string  `db:"team_id" json:"team_id"`
+	Name          string  `db:"name" json:"name"`
+	Members       int     `db:"members" json:"members"`
+	Voters        int     `db:"voters" json:"voters"`
+	Votes         int     `db:"votes" json:"votes"`
+	Participation float64 `json:"participation"`
+}
+
+// Restaurant is what a restaurant received in a month. Spend leaves the
+// cancelled orders out and is in cents.
+type Restaurant struct {
+	RestaurantID string `db:"restaurant_id" json:"restaurant_id"`
+	Name         string `db:"name" json:"name"`
+	Votes        int    `db:"votes" json:"votes"`
+	Wins         int    `db:"wins" json:"wins"`
+	Orders       int    `db:"orders" json:"orders"`
+	Spend        int    `db:"spend" json:"spend"`
+}
+
+// ParseMonth returns the first day of the month formatted as YYYY-MM, or of
+// the month before now when it is blank.
+func ParseMonth(month string, now time.Time) (time.Time, error) {
+	if month == "" {
+		now = now.UTC()
+		return time.Date(now.Year(), now.Month()-1, 1, 0, 0, 0, 0, time.UTC), nil
+	}
+
+	m, err := time.Parse("2006-01", month)
+	if err != nil {
+		return time.Time{}, ErrInvalidMonth
+	}
+
+	return m, nil
+}
+
+// Generate summarizes the month starting on the date of month for the
+// organization.
+func Generate(ctx context.Context, db *sqlx.DB, org string, month time.Time) (*Monthly, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.report.Generate")
+	defer span.End()
+
+	from, to := month, month.AddDate(0, 1, 0)
+	conn := database.Conn(ctx, db)
+
+	rep := Monthly{
+		OrgID: org,
+		Month: month.Format("2006-01"),
+	}
+
+	const qv = `SELECT COUNT(DISTINCT date) AS days, COUNT(*) AS votes FROM vote
+		WHERE org_id = $1 AND date >= $2 AND date < $3`
+	if err := sqlx.GetContext(ctx, conn, &rep, qv, org, from, to); err != nil {
+		return nil, errors.Wrap(err, "selecting vote totals")
+	}
+
+	rep.Teams = []Team{}
+	const qt = `SELECT t.team_id, t.name,
+			(SELECT COUNT(*) FROM team_member AS tm
+				JOIN users AS u ON u.user_id = tm.user_id
+				WHERE tm.team_id = t.team_id AND u.deleted_at IS NULL) AS members,
+			COUNT(DISTINCT v.user_id) AS voters,
+			COUNT(v.user_id) AS votes
+		FROM team AS t
+		LEFT JOIN team_member AS tm ON tm.team_id = t.team_id
+		LEFT JOIN vote AS v ON v.user_id = tm.user_id AND v.org_id = t.org_id AND v.date >= $2 AND v.date < $3
+		WHERE t.org_id = $1 AND t.deleted_at IS NULL
+		GROUP BY t.team_id, t.name
+		ORDER BY t.name`
+	if err := sqlx.SelectContext(ctx, conn, &rep.Teams, qt, org, from, to); err != nil {
+		return nil, errors.Wrap(err, "selecting teams")
+	}
+
+	// A user votes at most once a day so the votes of a day are its voters.
+	for i := range rep.Teams {
+		t := &rep.Teams[i]
+		if t.Members > 0 && rep.Days > 0 {
+			t.Participation = float64(t.Votes) / float64(t.Members*rep.Days)
+		}
+	}
+
+	rep.Restaurants = []Restaurant{}
+	const qr = `SELECT r.restaurant_id, r.name,
+			COALESCE(v.votes, 0) AS votes, COALESCE(w.wins, 0) AS wins,
+			COALESCE(o.orders, 0) AS orders, COALESCE(o.spend, 0) AS spend
+		FROM restaurant AS r
+		LEFT JOIN (SELECT restaurant_id, COUNT(*) AS votes FROM vote
+			WHERE org_id = $1 AND date >= $2 AND date < $3
+			GROUP BY restaurant_id) AS v ON v.restaurant_id = r.restaurant_id
+		LEFT JOIN (SELECT restaurant_id, COUNT(*) AS wins FROM winner
+			WHERE org_id = $1 AND date >= $2 AND date < $3
+			GROUP BY restaurant_id) AS w ON w.restaurant_id = r.restaurant_id
+		LEFT JOIN (SELECT restaurant_id, COUNT(*) AS orders, SUM(total) AS spend FROM orders
+			WHERE date >= $2 AND date < $3 AND status <> 'CANCELLED'
+			GROUP BY restaurant_id) AS o ON o.restaurant_id = r.restaurant_id
+		WHERE r.org_id = $1 AND (v.votes IS NOT NULL OR w.wins IS NOT NULL OR o.orders IS NOT NULL)
+		ORDER BY wins DESC, votes DESC, r.name`
+	if err := sqlx.SelectContext(ctx, conn, &rep.Restaurants, qr, org, from, to); err != nil {
+		return nil, errors.Wrap(err, "selecting restaurants")
+	}
+
+	for _, r := range rep.Restaurants {
+		rep.Orders += r.Orders
+		rep.Spend += r.Spend
+	}
+
+	return &rep, nil
+}
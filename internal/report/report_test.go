@@ -0,0 +1,57 @@
+package report
+
+import (
+	"testing"
+	"time"
+
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// TestParseMonth validates the months reports are generated for.
+func TestParseMonth(t *testing.T) {
+	now := time.Date(2020, time.January, 15, 12, 0, 0, 0, time.UTC)
+
+	tt := []struct {
+		name  string
+		month string
+		want  time.Time
+		err   error
+	}{
+		{"blank", "", time.Date(2019, time.December, 1, 0, 0, 0, 0, time.UTC), nil},
+		{"given", "2019-06", time.Date(2019, time.June, 1, 0, 0, 0, 0, time.UTC), nil},
+		{"malformed", "2019-6-1", time.Time{}, ErrInvalidMonth},
+	}
+
+	t.Log("Given the need to pick the month of a report.")
+	{
+		for i, tc := range tt {
+			t.Logf("\tTest %d:\tWhen the month is %s.", i, tc.name)
+			{
+				got, err := ParseMonth(tc.month, now)
+				if err != tc.err {
+					t.Fatalf("\t%s\tShould fail with %v : got %v.", tests.Failed, tc.err, err)
+				}
+				if !got.Equal(tc.want) {
+					t.Fatalf("\t%s\tShould be %s : got %s.", tests.Failed, tc.want, got)
+				}
+				t.Logf("\t%s\tShould be %s.", tests.Success, tc.want.Format("2006-01"))
+			}
+		}
+	}
+}
+
+// TestFormatCents validates amounts are formatted with two decimals.
+func TestFormatCents(t *testing.T) {
+	t.Log("Given the need to show the spend of a month.")
+	{
+		t.Log("\tTest 0:\tWhen formatting amounts in cents.")
+		{
+			for cents, want := range map[int]string{0: "0.00", 5: "0.05", 1250: "12.50", 48000: "480.00"} {
+				if got := FormatCents(cents); got != want {
+					t.Fatalf("\t%s\tShould format %d as %s : got %s.", tests.Failed, cents, want, got)
+				}
+			}
+			t.Logf("\t%s\tShould format the amounts.", tests.Success)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package restaurant
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/database"
+	"go.opentelemetry.io/otel"
+)
+
+// AddFavorite marks the restaurant as a favorite of the user. Marking it
+// again does nothing.
+func AddFavorite(ctx context.Context, db *sqlx.DB, userID, id string, now time.Time) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.restaurant.AddFavorite")
+	defer span.End()
+
+	if _, err := Retrieve(ctx, db, id); err != nil {
+		return err
+	}
+
+	const q = `INSERT INTO favorite
+		(user_id, restaurant_id, date_created)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, restaurant_id) DO NOTHING`
+
+	if _, err := database.Conn(ctx, db).ExecContext(ctx, q, userID, id, now.UTC()); err != nil {
+		return errors.Wrapf(err, "inserting favorite %s", id)
+	}
+
+	return nil
+}
+
+// RemoveFavorite removes the restaurant from the favorites of the user.
+// Removing a restaurant which is not a favorite does nothing.
+func RemoveFavorite(ctx context.Context, db *sqlx.DB, userID, id string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.restaurant.RemoveFavorite")
+	defer span.End()
+
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrInvalidID
+	}
+
+	const q = `DELETE FROM favorite WHERE user_id = $1 AND restaurant_id = $2`
+
+	if _, err := database.Conn(ctx, db).ExecContext(ctx, q, userID, id); err != nil {
+		return errors.Wrapf(err, "deleting favorite %s", id)
+	}
+
+	return nil
+}
+
+// ListFavorites returns the favorite restaurants of the user, the most
+// recently added first. Deleted restaurants are left out.
+func ListFavorites(ctx context.Context, db *sqlx.DB, userID string) ([]Restaurant, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.restaurant.ListFavorites")
+	defer span.End()
+
+	restaurants := []Restaurant{}
+	const q = `SELECT r.* FROM restaurant AS r
+		JOIN favorite AS f ON f.restaurant_id = r.restaurant_id
+		WHERE f.user_id = $1 AND r.deleted_at IS NULL AND ($2 = '' OR r.org_id::text = $2)
+		ORDER BY f.date_created DESC`
+	if err := sqlx.SelectContext(ctx, database.Conn(ctx, db), &restaurants, q, userID, auth.Org(ctx)); err != nil {
+		return nil, errors.Wrap(err, "selecting favorites")
+	}
+	return restaurants, nil
+}
@@ -0,0 +1,72 @@
+package restaurant
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/platform/database"
+	"go.opentelemetry.io/otel"
+)
+
+// AddPhoto adds the uploaded photo and its thumbnail to the photos of the
+// restaurant. Checking the caller may change the restaurant is left to the
+// caller.
+func AddPhoto(ctx context.Context, db *sqlx.DB, id, url, thumbnailURL string, now time.Time) (*Restaurant, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.restaurant.AddPhoto")
+	defer span.End()
+
+	r, err := Retrieve(ctx, db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	r.SetPhotos(append(r.Photos, url))
+	r.Thumbnails[len(r.Thumbnails)-1] = thumbnailURL
+	r.DateUpdated = now.UTC()
+
+	const q = `UPDATE restaurant SET
+		"photos" = $2,
+		"thumbnails" = $3,
+		"date_updated" = $4,
+		"version" = version + 1
+		WHERE restaurant_id = $1 AND version = $5 AND deleted_at IS NULL`
+	res, err := database.Conn(ctx, db).ExecContext(ctx, q, id, r.Photos, r.Thumbnails, r.DateUpdated, r.Version)
+	if err != nil {
+		return nil, errors.Wrapf(err, "adding photo to restaurant %s", id)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return nil, ErrVersionConflict
+	}
+	r.Version++
+
+	return r, nil
+}
+
+// SetMenuImage sets the uploaded image and its thumbnail as the image of the
+// menu of the restaurant.
+func SetMenuImage(ctx context.Context, db *sqlx.DB, restaurantID, menuID, url, thumbnailURL string) (*Menu, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.restaurant.SetMenuImage")
+	defer span.End()
+
+	m, err := MenuRetrieve(ctx, db, menuID)
+	if err != nil {
+		return nil, err
+	}
+	if m.RestaurantID != restaurantID {
+		return nil, ErrNotFound
+	}
+
+	m.ImageURL, m.ThumbnailURL = url, thumbnailURL
+
+	const q = `UPDATE menu SET
+		"image_url" = $2,
+		"thumbnail_url" = $3
+		WHERE menu_id = $1 AND deleted_at IS NULL`
+	if _, err := database.Conn(ctx, db).ExecContext(ctx, q, m.ID, m.ImageURL, m.ThumbnailURL); err != nil {
+		return nil, errors.Wrapf(err, "setting image of menu %s", m.ID)
+	}
+
+	return m, nil
+}
@@ -0,0 +1,134 @@
+package restaurant
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/organization"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/database"
+	"go.opentelemetry.io/otel"
+)
+
+// These are the related data which can be embedded in a list of restaurants.
+const (
+	IncludeMenu   = "menu"
+	IncludeVotes  = "votes"
+	IncludeRating = "rating"
+)
+
+// ErrInvalidInclude is used when asking to embed related data which is not
+// one of IncludeMenu, IncludeVotes and IncludeRating.
+var ErrInvalidInclude = errors.New("Include must list menu, votes or rating")
+
+// Include tells which related data to embed in a list of restaurants.
+type Include struct {
+	Menu   bool
+	Votes  bool
+	Rating bool
+}
+
+// ParseInclude parses the comma separated related data to embed, as given
+// in the include query parameter. A blank list embeds nothing.
+func ParseInclude(s string) (Include, error) {
+	var in Include
+	for _, name := range strings.Split(s, ",") {
+		switch strings.TrimSpace(name) {
+		case "":
+		case IncludeMenu:
+			in.Menu = true
+		case IncludeVotes:
+			in.Votes = true
+		case IncludeRating:
+			in.Rating = true
+		default:
+			return Include{}, ErrInvalidInclude
+		}
+	}
+	return in, nil
+}
+
+// Any reports whether anything is embedded.
+func (in Include) Any() bool {
+	return in.Menu || in.Votes || in.Rating
+}
+
+// Included is the related data embedded in a restaurant of a list. Menu is
+// the menu of today, left out when there is none, Votes the votes cast for
+// the restaurant today and Rating the average of its ratings, left out until
+// it is rated. The data which was not asked for is left out too.
+type Included struct {
+	Menu   *Menu    `json:"menu,omitempty"`
+	Votes  *int     `json:"votes,omitempty"`
+	Rating *float64 `json:"rating,omitempty"`
+}
+
+// ListIncluded gets the related data of the identified restaurants, today
+// being the date of now in the time zone of the organization. The menus are
+// read with a single query and the votes and ratings with another, joined on
+// the restaurants, whatever the number of restaurants.
+func ListIncluded(ctx context.Context, db *sqlx.DB, ids []string, in Include, now time.Time) (map[string]Included, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.restaurant.ListIncluded")
+	defer span.End()
+
+	included := make(map[string]Included, len(ids))
+	if len(ids) == 0 || !in.Any() {
+		return included, nil
+	}
+
+	conn := database.Conn(ctx, db)
+	org := auth.Org(ctx)
+	today, err := organization.Today(ctx, conn, org, now)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.Menu {
+		menus := []Menu{}
+		const q = `SELECT m.* FROM restaurant AS r
+			JOIN menu AS m ON m.restaurant_id = r.restaurant_id AND m.date = $2 AND m.deleted_at IS NULL
+			WHERE r.restaurant_id = ANY($1::uuid[]) AND ($3 = '' OR r.org_id::text = $3)`
+		if err := sqlx.SelectContext(ctx, conn, &menus, q, pq.Array(ids), today, org); err != nil {
+			return nil, errors.Wrap(err, "selecting menus of today")
+		}
+		for i := range menus {
+			inc := included[menus[i].RestaurantID]
+			inc.Menu = &menus[i]
+			included[menus[i].RestaurantID] = inc
+		}
+	}
+
+	if in.Votes || in.Rating {
+		var counts []struct {
+			RestaurantID string   `db:"restaurant_id"`
+			Votes        int      `db:"votes"`
+			Rating       *float64 `db:"rating"`
+		}
+		const q = `SELECT r.restaurant_id, COALESCE(t.votes, 0) AS votes, AVG(rt.score)::float8 AS rating
+			FROM restaurant AS r
+			LEFT JOIN menu_vote_tally AS t ON t.restaurant_id = r.restaurant_id AND t.date = $2
+			LEFT JOIN restaurant_rating AS rt ON rt.restaurant_id = r.restaurant_id
+			WHERE r.restaurant_id = ANY($1::uuid[]) AND ($3 = '' OR r.org_id::text = $3)
+			GROUP BY r.restaurant_id, t.votes`
+		if err := sqlx.SelectContext(ctx, conn, &counts, q, pq.Array(ids), today, org); err != nil {
+			return nil, errors.Wrap(err, "selecting votes and ratings")
+		}
+		for _, c := range counts {
+			inc := included[c.RestaurantID]
+			if in.Votes {
+				votes := c.Votes
+				inc.Votes = &votes
+			}
+			if in.Rating {
+				inc.Rating = c.Rating
+			}
+			included[c.RestaurantID] = inc
+		}
+	}
+
+	return included, nil
+}
@@ -0,0 +1,41 @@
+package restaurant
+
+import (
+	"testing"
+
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// TestParseInclude validates the related data to embed in a list is parsed
+// from the include query parameter.
+func TestParseInclude(t *testing.T) {
+	tt := []struct {
+		include string
+		want    Include
+		err     error
+	}{
+		{"", Include{}, nil},
+		{"menu", Include{Menu: true}, nil},
+		{"votes, rating", Include{Votes: true, Rating: true}, nil},
+		{"menu,votes,rating,", Include{Menu: true, Votes: true, Rating: true}, nil},
+		{"menu,owner", Include{}, ErrInvalidInclude},
+		{"MENU", Include{}, ErrInvalidInclude},
+	}
+
+	t.Log("Given the need to parse the related data to embed.")
+	{
+		for i, tc := range tt {
+			t.Logf("\tTest %d:\tWhen including %q.", i, tc.include)
+			{
+				got, err := ParseInclude(tc.include)
+				if err != tc.err {
+					t.Fatalf("\t%s\tShould get the error %v : got %v.", tests.Failed, tc.err, err)
+				}
+				if got != tc.want {
+					t.Fatalf("\t%s\tShould include %+v : got %+v.", tests.Failed, tc.want, got)
+				}
+				t.Logf("\t%s\tShould include %+v.", tests.Success, tc.want)
+			}
+		}
+	}
+}
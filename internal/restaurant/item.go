@@ -0,0 +1,147 @@
+package restaurant
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Allergens are the allergens menu items are flagged with, the ones food
+// businesses of the EU must declare. The oneof list validating MenuItem
+// must be kept in sync.
+var Allergens = []string{
+	"celery", "crustaceans", "eggs", "fish", "gluten", "lupin", "milk",
+	"molluscs", "mustard", "nuts", "peanuts", "sesame", "soy", "sulphites",
+}
+
+// ErrUnknownAllergen is used when an allergen is not one of Allergens.
+var ErrUnknownAllergen = errors.New("Allergen is not one of the known allergens")
+
+// DefaultCategory is the category of the menu items given none, which are
+// voted for as main courses.
+const DefaultCategory = "main"
+
+// MenuItem is a dish of a structured menu. Prices are in cents.
+type MenuItem struct {
+	Name      string     `json:"name" validate:"required"`
+	Category  string     `json:"category,omitempty" validate:"omitempty,max=50"`
+	Price     int        `json:"price" validate:"min=0"`
+	Allergens []string   `json:"allergens" validate:"dive,oneof=celery crustaceans eggs fish gluten lupin milk molluscs mustard nuts peanuts sesame soy sulphites"`
+	Nutrition *Nutrition `json:"nutrition,omitempty"`
+}
+
+// Nutrition holds the optional nutrition values of a MenuItem per serving.
+// Weights are in grams.
+type Nutrition struct {
+	Calories      *int     `json:"calories,omitempty" validate:"omitempty,min=0"`
+	Protein       *float64 `json:"protein,omitempty" validate:"omitempty,min=0"`
+	Carbohydrates *float64 `json:"carbohydrates,omitempty" validate:"omitempty,min=0"`
+	Fat           *float64 `json:"fat,omitempty" validate:"omitempty,min=0"`
+	Salt          *float64 `json:"salt,omitempty" validate:"omitempty,min=0"`
+}
+
+// MenuItems are the items of a menu stored as a JSON document.
+type MenuItems []MenuItem
+
+// Value implements the driver.Valuer interface.
+func (items MenuItems) Value() (driver.Value, error) {
+	if items == nil {
+		items = MenuItems{}
+	}
+	b, err := json.Marshal(items)
+	if err != nil {
+		return nil, errors.Wrap(err, "encoding menu items")
+	}
+	return string(b), nil
+}
+
+// Scan implements the sql.Scanner interface.
+func (items *MenuItems) Scan(src interface{}) error {
+	var b []byte
+	switch v := src.(type) {
+	case nil:
+		*items = MenuItems{}
+		return nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return errors.Errorf("cannot scan %T into menu items", src)
+	}
+
+	if err := json.Unmarshal(b, items); err != nil {
+		return errors.Wrap(err, "decoding menu items")
+	}
+	return nil
+}
+
+// Exclude returns the items containing none of the allergens.
+func (items MenuItems) Exclude(allergens []string) MenuItems {
+	kept := MenuItems{}
+	for _, it := range items {
+		if !it.contains(allergens) {
+			kept = append(kept, it)
+		}
+	}
+	return kept
+}
+
+// Find returns the item named name.
+func (items MenuItems) Find(name string) (MenuItem, bool) {
+	for _, it := range items {
+		if it.Name == name {
+			return it, true
+		}
+	}
+	return MenuItem{}, false
+}
+
+// Kind returns the category of the item, DefaultCategory when it has none.
+func (it MenuItem) Kind() string {
+	if it.Category == "" {
+		return DefaultCategory
+	}
+	return strings.ToLower(it.Category)
+}
+
+// contains reports whether the item contains any of the allergens.
+func (it MenuItem) contains(allergens []string) bool {
+	for _, a := range it.Allergens {
+		for _, ex := range allergens {
+			if a == ex {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ParseAllergens parses a comma separated list of allergens like
+// "nuts,gluten". Every allergen must be one of Allergens.
+func ParseAllergens(s string) ([]string, error) {
+	var allergens []string
+	for _, a := range strings.Split(s, ",") {
+		a = strings.ToLower(strings.TrimSpace(a))
+		if a == "" {
+			continue
+		}
+		if !knownAllergen(a) {
+			return nil, ErrUnknownAllergen
+		}
+		allergens = append(allergens, a)
+	}
+	return allergens, nil
+}
+
+// knownAllergen reports whether a is one of Allergens.
+func knownAllergen(a string) bool {
+	for _, known := range Allergens {
+		if a == known {
+			return true
+		}
+	}
+	return false
+}
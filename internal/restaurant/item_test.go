@@ -0,0 +1,66 @@
+package restaurant
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// TestMenuItems validates storing and filtering the items of menus.
+func TestMenuItems(t *testing.T) {
+	calories := 650
+	items := MenuItems{
+		{Name: "Pesto Pasta", Price: 1200, Allergens: []string{"gluten", "nuts"}, Nutrition: &Nutrition{Calories: &calories}},
+		{Name: "Salad", Price: 900},
+	}
+
+	t.Log("Given the need to keep the items of menus.")
+	{
+		t.Log("\tTest 0:\tWhen storing the items.")
+		{
+			v, err := items.Value()
+			if err != nil {
+				t.Fatalf("\t%s\tShould encode the items : %s.", tests.Failed, err)
+			}
+
+			var scanned MenuItems
+			if err := scanned.Scan([]byte(v.(string))); err != nil {
+				t.Fatalf("\t%s\tShould decode the items : %s.", tests.Failed, err)
+			}
+			if !reflect.DeepEqual(scanned, items) {
+				t.Fatalf("\t%s\tShould read back the same items : got %+v.", tests.Failed, scanned)
+			}
+			t.Logf("\t%s\tShould read back the same items.", tests.Success)
+		}
+
+		t.Log("\tTest 1:\tWhen excluding allergens.")
+		{
+			allergens, err := ParseAllergens(" Nuts ,milk")
+			if err != nil {
+				t.Fatalf("\t%s\tShould parse the allergens : %s.", tests.Failed, err)
+			}
+			kept := items.Exclude(allergens)
+			if len(kept) != 1 || kept[0].Name != "Salad" {
+				t.Fatalf("\t%s\tShould keep the items without the allergens : got %+v.", tests.Failed, kept)
+			}
+			t.Logf("\t%s\tShould keep the items without the allergens.", tests.Success)
+
+			if _, err := ParseAllergens("nuts,chocolate"); err != ErrUnknownAllergen {
+				t.Fatalf("\t%s\tShould reject unknown allergens : got %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould reject unknown allergens.", tests.Success)
+		}
+
+		t.Log("\tTest 2:\tWhen validating the allergens of items.")
+		{
+			f, _ := reflect.TypeOf(MenuItem{}).FieldByName("Allergens")
+			tag := f.Tag.Get("validate")
+			if want := "dive,oneof=" + strings.Join(Allergens, " "); tag != want {
+				t.Fatalf("\t%s\tShould validate against the known allergens : got %q.", tests.Failed, tag)
+			}
+			t.Logf("\t%s\tShould validate against the known allergens.", tests.Success)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package restaurant
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/database"
+	"github.com/remisb/restaurant/internal/platform/geo"
+	"go.opentelemetry.io/otel"
+)
+
+// Radius limits in meters of the searches for nearby restaurants.
+const (
+	DefaultRadius = 5000
+	MaxRadius     = 100000
+)
+
+// ErrInvalidRadius is used when the radius of a search is not a positive
+// number of meters up to MaxRadius.
+var ErrInvalidRadius = errors.New("Radius is not in its proper form")
+
+// SetLocation stores the position of the restaurant found for its address.
+// The restaurant is left unchanged when its address is no longer the given
+// one, the position being the one of an outdated address.
+func SetLocation(ctx context.Context, db *sqlx.DB, id, address string, p geo.Point, now time.Time) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.restaurant.SetLocation")
+	defer span.End()
+
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrInvalidID
+	}
+
+	const q = `UPDATE restaurant SET
+		"latitude" = $3,
+		"longitude" = $4,
+		"date_updated" = $5,
+		"version" = version + 1
+		WHERE restaurant_id = $1 AND address = $2 AND deleted_at IS NULL`
+	if _, err := database.Conn(ctx, db).ExecContext(ctx, q, id, address, p.Lat, p.Lng, now.UTC()); err != nil {
+		return errors.Wrapf(err, "setting location of restaurant %s", id)
+	}
+
+	return nil
+}
+
+// ListNearby gets the restaurants within radius meters of a position, the
+// closest first. Restaurants without a location are left out.
+func ListNearby(ctx context.Context, db *sqlx.DB, near geo.Point, radius float64) ([]Nearby, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.restaurant.ListNearby")
+	defer span.End()
+
+	// The bounding box lets the search use the location index before the
+	// exact distance filters out the corners of the box.
+	restaurants := []Nearby{}
+	const q = `SELECT r.*, earth_distance(ll_to_earth($1, $2), ll_to_earth(r.latitude, r.longitude)) AS distance
+		FROM restaurant AS r
+		WHERE r.deleted_at IS NULL AND r.latitude IS NOT NULL AND r.longitude IS NOT NULL
+		AND earth_box(ll_to_earth($1, $2), $3) @> ll_to_earth(r.latitude, r.longitude)
+		AND earth_distance(ll_to_earth($1, $2), ll_to_earth(r.latitude, r.longitude)) <= $3
+		AND ($4 = '' OR r.org_id::text = $4)
+		ORDER BY distance`
+	if err := sqlx.SelectContext(ctx, database.Conn(ctx, db), &restaurants, q, near.Lat, near.Lng, radius, auth.Org(ctx)); err != nil {
+		return nil, errors.Wrap(err, "selecting nearby restaurants")
+	}
+	return restaurants, nil
+}
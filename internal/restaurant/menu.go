@@ -6,38 +6,87 @@ import (
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/audit"
+	"github.com/remisb/restaurant/internal/authz"
+	"github.com/remisb/restaurant/internal/organization"
+	"github.com/remisb/restaurant/internal/outbox"
 	"github.com/remisb/restaurant/internal/platform/auth"
-	"go.opencensus.io/trace"
+	"github.com/remisb/restaurant/internal/platform/database"
+	"go.opentelemetry.io/otel"
 	"time"
 )
 
+// CreateMenu adds the menu of a restaurant for the day it is served, today in
+// the time zone of the organization when the NewMenu has no date. It errors
+// with ErrDateOutOfRange when the day is not within MenuDateWindow of today.
 func CreateMenu(ctx context.Context, db *sqlx.DB, user auth.Claims, nm NewMenu, now time.Time) (*Menu, error) {
-	ctx, span := trace.StartSpan(ctx, "internal.Restaurant.CreateMenu")
+	ctx, span := otel.Tracer("").Start(ctx, "internal.Restaurant.CreateMenu")
 	defer span.End()
 
+	today, err := organization.Today(ctx, database.Conn(ctx, db), user.Org(), now)
+	if err != nil {
+		return nil, err
+	}
+	date, err := ParseMenuDate(nm.Date, today)
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckMenuDate(date, today); err != nil {
+		return nil, err
+	}
 
-	currentTime := now.UTC()
 	m := Menu{
 		ID: uuid.New().String(),
 		RestaurantID: nm.RestaurantID,
-		Date: currentTime,
+		Date: date,
 		Menu: nm.Menu,
+		Items: nm.Items,
+		Version: 1,
+	}
+	if m.Items == nil {
+		m.Items = MenuItems{}
 	}
 
 	const q = `INSERT INTO menu 
-	  (menu_id, restaurant_id, date, menu, votes)
-	  VALUES ($1, $2, $3, $4, $5)`
+	  (menu_id, restaurant_id, date, menu, items, votes)
+	  VALUES ($1, $2, $3, $4, $5, $6)`
 
-	_, err := db.ExecContext(ctx, q, m.ID, m.RestaurantID, m.Date, m.Menu, 0)
+	tx, err := database.Begin(ctx, db)
+	if err != nil {
+		return nil, errors.Wrap(err, "beginning transaction")
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, q, m.ID, m.RestaurantID, m.Date, m.Menu, m.Items, 0)
 	if err != nil {
 		return nil, errors.Wrap(err, "inserting menu")
 	}
+
+	if err := outbox.Add(ctx, tx, outbox.TypeMenuUpdated, m.RestaurantID, m, now); err != nil {
+		return nil, err
+	}
+
+	ne := audit.NewEntry{
+		EntityType:   audit.EntityMenu,
+		EntityID:     m.ID,
+		RestaurantID: m.RestaurantID,
+		Action:       audit.ActionCreate,
+		UserID:       user.Subject,
+		After:        m,
+	}
+	if err := audit.Record(ctx, tx, ne, now); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrap(err, "committing menu")
+	}
 	return &m, nil
 }
 
 // Retrieve finds the restaurant identified by a given ID.
 func MenuRetrieve(ctx context.Context, db *sqlx.DB, id string) (*Menu, error) {
-	ctx, span := trace.StartSpan(ctx, "internal.restaurant.Retrieve")
+	ctx, span := otel.Tracer("").Start(ctx, "internal.restaurant.Retrieve")
 	defer span.End()
 
 	if _, err := uuid.Parse(id); err != nil {
@@ -46,9 +95,11 @@ func MenuRetrieve(ctx context.Context, db *sqlx.DB, id string) (*Menu, error) {
 
 	var m Menu
 
-	const q = `SELECT * FROM menu AS r WHERE menu_id =  $1`
+	const q = `SELECT m.* FROM menu AS m
+		JOIN restaurant AS r ON r.restaurant_id = m.restaurant_id
+		WHERE m.menu_id = $1 AND m.deleted_at IS NULL AND ($2 = '' OR r.org_id::text = $2)`
 
-	if err := db.GetContext(ctx, &m, q, id); err != nil {
+	if err := sqlx.GetContext(ctx, database.Conn(ctx, db), &m, q, id, auth.Org(ctx)); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrNotFound
 		}
@@ -59,38 +110,110 @@ func MenuRetrieve(ctx context.Context, db *sqlx.DB, id string) (*Menu, error) {
 	return &m, nil
 }
 
-func MenuUpdate(ctx context.Context, db *sqlx.DB, user auth.Claims, restaurantId string, update UpdateMenu, now time.Time) error {
-	ctx, span := trace.StartSpan(ctx, "internal.Restaurant.MenuUpdate")
+// MenuList gets the menus of the identified restaurant starting from the
+// given date, oldest first.
+func MenuList(ctx context.Context, db *sqlx.DB, restaurantID string, from time.Time) ([]Menu, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.restaurant.MenuList")
+	defer span.End()
+
+	if _, err := uuid.Parse(restaurantID); err != nil {
+		return nil, ErrInvalidID
+	}
+
+	menus := []Menu{}
+	const q = `SELECT m.* FROM menu AS m
+		JOIN restaurant AS r ON r.restaurant_id = m.restaurant_id
+		WHERE m.restaurant_id = $1 AND m.date >= $2 AND m.deleted_at IS NULL AND ($3 = '' OR r.org_id::text = $3)
+		ORDER BY m.date`
+	if err := sqlx.SelectContext(ctx, database.Conn(ctx, db), &menus, q, restaurantID, from, auth.Org(ctx)); err != nil {
+		return nil, errors.Wrap(err, "selecting menus")
+	}
+
+	return menus, nil
+}
+
+func MenuUpdate(ctx context.Context, db *sqlx.DB, user auth.Claims, restaurantId string, update UpdateMenu, now time.Time) (*Menu, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.Restaurant.MenuUpdate")
 	defer span.End()
 
 	r, err := Retrieve(ctx, db, restaurantId)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if r.OwnerUserID != user.Subject {
-		return ErrForbidden
+	if !authz.Allowed(user, authz.Update, authz.Resource{Kind: authz.Menu, Owner: r.OwnerUserID}) {
+		return nil, ErrForbidden
 	}
 
 	m, err := MenuRetrieve(ctx, db, update.ID)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if m.RestaurantID != r.ID {
+		return nil, ErrNotFound
 	}
 
+	if update.Version != nil && *update.Version != m.Version {
+		return nil, ErrVersionConflict
+	}
+
+	before := *m
 	if update.Menu != "" {
 		m.Menu = update.Menu
-		m.Date = update.Date
 	}
+	if update.Date != "" {
+		if m.Date, err = ParseMenuDate(update.Date, m.Date); err != nil {
+			return nil, err
+		}
+	}
+	if update.Items != nil {
+		m.Items = update.Items
+	}
+	m.Version++
 
+	// The version in the WHERE clause catches changes made since the menu was
+	// retrieved above, like another owner editing it at the same time.
 	const q = `UPDATE menu SET
 		"menu" = $2,
-		"date" = $3
-		WHERE menu_id =  $1`
+		"date" = $3,
+		"items" = $4,
+		"version" = $5
+		WHERE menu_id = $1 AND version = $6 AND deleted_at IS NULL`
 
-	_, err = db.ExecContext(ctx, q, update.ID, update.Menu, update.Date)
+	tx, err := database.Begin(ctx, db)
 	if err != nil {
-		return errors.Wrap(err, "updating menu")
+		return nil, errors.Wrap(err, "beginning transaction")
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, q, m.ID, m.Menu, m.Date, m.Items, m.Version, m.Version-1)
+	if err != nil {
+		return nil, errors.Wrap(err, "updating menu")
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return nil, ErrVersionConflict
+	}
+
+	if err := outbox.Add(ctx, tx, outbox.TypeMenuUpdated, m.RestaurantID, m, now); err != nil {
+		return nil, err
+	}
+
+	ne := audit.NewEntry{
+		EntityType:   audit.EntityMenu,
+		EntityID:     m.ID,
+		RestaurantID: m.RestaurantID,
+		Action:       audit.ActionUpdate,
+		UserID:       user.Subject,
+		Before:       before,
+		After:        m,
+	}
+	if err := audit.Record(ctx, tx, ne, now); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrap(err, "committing menu")
 	}
 
-	return nil
+	return m, nil
 }
@@ -1,22 +1,100 @@
 package restaurant
 
-import "time"
+import (
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/remisb/restaurant/internal/platform/geo"
+)
 
 // Restaurant entity stored in DB
 type Restaurant struct {
-	ID          string    `db:"restaurant_id" json:"id"`
-	Name        string    `db:"name" json:"name"`
-	Address     string    `db:"address" json:"address"`
-	OwnerUserID string    `db:"owner_user_id" json:"owner_user_id"`
-	DateCreated time.Time `db:"date_created" json:"date_created"`
-	DateUpdated time.Time `db:"date_updated" json:"date_updated"`
+	ID             string         `db:"restaurant_id" json:"id"`
+	Name           string         `db:"name" json:"name"`
+	Address        string         `db:"address" json:"address"`
+	OwnerUserID    string         `db:"owner_user_id" json:"owner_user_id"`
+	OrgID          string         `db:"org_id" json:"-"`
+	Website        string         `db:"website" json:"website"`
+	Phone          string         `db:"phone" json:"phone"`
+	Photos         pq.StringArray `db:"photos" json:"photos"`
+	Thumbnails     pq.StringArray `db:"thumbnails" json:"thumbnails"`
+	Tags           pq.StringArray `db:"tags" json:"tags"`
+	Public         bool           `db:"public" json:"public"`
+	AnonymousVotes bool           `db:"anonymous_votes" json:"anonymous_votes"`
+	Latitude       *float64       `db:"latitude" json:"latitude"`
+	Longitude      *float64       `db:"longitude" json:"longitude"`
+	Version        int            `db:"version" json:"version"`
+	DateCreated    time.Time      `db:"date_created" json:"date_created"`
+	DateUpdated    time.Time      `db:"date_updated" json:"date_updated"`
+	DateDeleted    *time.Time     `db:"deleted_at" json:"-"`
+}
+
+// SetPhotos replaces the photos of the restaurant. The thumbnails of the
+// photos which are kept stay along with them, Thumbnails[i] being the
+// thumbnail of Photos[i] or blank for the photos which were not uploaded.
+func (r *Restaurant) SetPhotos(photos []string) {
+	thumbs := make(map[string]string, len(r.Photos))
+	for i, p := range r.Photos {
+		if i < len(r.Thumbnails) {
+			thumbs[p] = r.Thumbnails[i]
+		}
+	}
+
+	r.Photos = photos
+	r.Thumbnails = make(pq.StringArray, len(photos))
+	for i, p := range photos {
+		r.Thumbnails[i] = thumbs[p]
+	}
+}
+
+// Location returns the position of the restaurant and whether it is known.
+func (r Restaurant) Location() (geo.Point, bool) {
+	if r.Latitude == nil || r.Longitude == nil {
+		return geo.Point{}, false
+	}
+	return geo.Point{Lat: *r.Latitude, Lng: *r.Longitude}, true
+}
+
+// HasTags reports whether the restaurant has every one of the tags.
+func (r Restaurant) HasTags(tags []string) bool {
+	for _, t := range tags {
+		found := false
+		for _, rt := range r.Tags {
+			if rt == t {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Nearby is a restaurant found around a position along with its distance in
+// meters from it.
+type Nearby struct {
+	Restaurant
+	Distance float64 `db:"distance" json:"distance"`
 }
 
 // NewRestaurant is what we require from clients when adding a Restaurant.
 type NewRestaurant struct {
-	Name    string `json:"name" validate:"required"`
-	Address string `json:"address" validate:"required"`
+	Name    string `json:"name" validate:"required,max=200"`
+	Address string `json:"address" validate:"required,max=500"`
 	//OwnerUserID string `json:"owner_user_id" validate:"required"`
+
+	// Latitude and Longitude are given together. When left out the address
+	// is geocoded instead.
+	Latitude  *float64 `json:"latitude" validate:"omitempty,min=-90,max=90"`
+	Longitude *float64 `json:"longitude" validate:"omitempty,min=-180,max=180"`
+}
+
+// Location returns the position given for the new restaurant, nil when none
+// is. It fails with geo.ErrInvalidPoint unless both coordinates are given.
+func (nr NewRestaurant) Location() (*geo.Point, error) {
+	return location(nr.Latitude, nr.Longitude)
 }
 
 // UpdateRestaurant defines what information may be provided to modify an
@@ -25,34 +103,259 @@ type NewRestaurant struct {
 // between a field that was not provided and field that was provided as
 // explicitly blank. Normally we do not want to use pointers to basic types but
 // we make exceptions around marshalling/unmarshalling.
+//
+// Fields which are given are checked like those of a NewRestaurant, a name or
+// address can not be blanked.
 type UpdateRestaurant struct {
-	Name    *string `json:"name"`
-	Address *string `json:"address"`
+	Name    *string  `json:"name" validate:"omitempty,min=1,max=200"`
+	Address *string  `json:"address" validate:"omitempty,min=1,max=500"`
+	Website *string  `json:"website" validate:"omitempty,max=2048"`
+	Phone   *string  `json:"phone" validate:"omitempty,max=50"`
+	Photos  []string `json:"photos" validate:"max=20,dive,required,max=2048"`
+	Public  *bool    `json:"public"`
+
+	// AnonymousVotes hides who voted for the restaurant from the results even
+	// when its organization shows them.
+	AnonymousVotes *bool `json:"anonymous_votes"`
+
+	// Latitude and Longitude are given together.
+	Latitude  *float64 `json:"latitude" validate:"omitempty,min=-90,max=90"`
+	Longitude *float64 `json:"longitude" validate:"omitempty,min=-180,max=180"`
+
+	// Version is the version of the Restaurant the changes are based on. The
+	// update is rejected when someone else changed it in the meantime.
+	Version *int `json:"version" validate:"required"`
+}
+
+// PatchRestaurant is the state of a Restaurant a JSON merge patch applies to.
+// Unlike with UpdateRestaurant every field is sent along, so a field the patch
+// sets to null is cleared rather than left as it is.
+type PatchRestaurant struct {
+	Name           string   `json:"name" validate:"required,max=200"`
+	Address        string   `json:"address" validate:"required,max=500"`
+	Website        string   `json:"website" validate:"max=2048"`
+	Phone          string   `json:"phone" validate:"max=50"`
+	Photos         []string `json:"photos" validate:"max=20,dive,required,max=2048"`
+	Public         bool     `json:"public"`
+	AnonymousVotes bool     `json:"anonymous_votes"`
+
+	// Latitude and Longitude can not be cleared, removing both keeps the
+	// position the restaurant has.
+	Latitude  *float64 `json:"latitude" validate:"omitempty,min=-90,max=90"`
+	Longitude *float64 `json:"longitude" validate:"omitempty,min=-180,max=180"`
+
+	// Version is the version of the Restaurant the patch is based on, the
+	// current one unless the patch gives it.
+	Version int `json:"version"`
+}
+
+// NewPatchRestaurant returns the state of the restaurant to apply a patch to.
+func NewPatchRestaurant(r Restaurant) PatchRestaurant {
+	return PatchRestaurant{
+		Name:           r.Name,
+		Address:        r.Address,
+		Website:        r.Website,
+		Phone:          r.Phone,
+		Photos:         r.Photos,
+		Public:         r.Public,
+		AnonymousVotes: r.AnonymousVotes,
+		Latitude:       r.Latitude,
+		Longitude:      r.Longitude,
+		Version:        r.Version,
+	}
+}
+
+// Update returns the update setting every field of the restaurant to its
+// patched value.
+func (p PatchRestaurant) Update() UpdateRestaurant {
+	photos := p.Photos
+	if photos == nil {
+		photos = []string{}
+	}
+
+	return UpdateRestaurant{
+		Name:           &p.Name,
+		Address:        &p.Address,
+		Website:        &p.Website,
+		Phone:          &p.Phone,
+		Photos:         photos,
+		Public:         &p.Public,
+		AnonymousVotes: &p.AnonymousVotes,
+		Latitude:       p.Latitude,
+		Longitude:      p.Longitude,
+		Version:        &p.Version,
+	}
+}
+
+// Location returns the position the restaurant is moved to, nil when it is
+// not. It fails with geo.ErrInvalidPoint unless both coordinates are given.
+func (up UpdateRestaurant) Location() (*geo.Point, error) {
+	return location(up.Latitude, up.Longitude)
+}
+
+// location pairs the coordinates of a position.
+func location(lat, lng *float64) (*geo.Point, error) {
+	if lat == nil && lng == nil {
+		return nil, nil
+	}
+	if lat == nil || lng == nil {
+		return nil, geo.ErrInvalidPoint
+	}
+
+	p := geo.Point{Lat: *lat, Lng: *lng}
+	if !p.Valid() {
+		return nil, geo.ErrInvalidPoint
+	}
+	return &p, nil
 }
 
 type Menu struct {
-	ID           string    `db:"menu_id" json:"id"`
-	RestaurantID string    `db:"restaurant_id" json:"restaurant_id"`
-	Date         time.Time `db:"date" json:"date"`
-	Menu         string    `db:"menu" json:"menu"`
-	Votes        int       `db:"votes" json:"votes"`
+	ID           string     `db:"menu_id" json:"id"`
+	RestaurantID string     `db:"restaurant_id" json:"restaurant_id"`
+	Date         time.Time  `db:"date" json:"date"`
+	Menu         string     `db:"menu" json:"menu"`
+	Items        MenuItems  `db:"items" json:"items"`
+	Votes        int        `db:"votes" json:"votes"`
+	ImageURL     string     `db:"image_url" json:"image_url,omitempty"`
+	ThumbnailURL string     `db:"thumbnail_url" json:"thumbnail_url,omitempty"`
+	Version      int        `db:"version" json:"version"`
+	DateDeleted  *time.Time `db:"deleted_at" json:"-"`
 }
 
+// NewMenu is what we require from clients when adding a Menu. The Date is the
+// day the menu is served, formatted as YYYY-MM-DD. A blank date is today in
+// the time zone of the organization.
 type NewMenu struct {
-	RestaurantID string    `db:"restaurant_id" json:"restaurant_id"`
-	Date         time.Time `db:"date" json:"date"`
-	Menu         string    `db:"menu" json:"menu"`
+	RestaurantID string `db:"restaurant_id" json:"restaurant_id"`
+	Date         string `db:"date" json:"date"`
+	Menu         string `db:"menu" json:"menu" validate:"max=10000"`
+
+	// Items are the dishes of the menu with their allergens, optionally
+	// given along with the free text of the menu.
+	Items MenuItems `json:"items" validate:"max=100,dive"`
 }
 
+// Check implements the web.Checker interface.
+func (nm NewMenu) Check() map[string]string {
+	if _, err := ParseMenuDate(nm.Date, time.Time{}); err != nil {
+		return map[string]string{"date": "date must be formatted as YYYY-MM-DD"}
+	}
+	return nil
+}
+
+// UpdateMenu defines what information may be provided to modify an existing
+// Menu. A blank menu text or date is left as it is. The date is formatted as
+// YYYY-MM-DD and must be within MenuDateWindow of today.
 type UpdateMenu struct {
-	ID   string    `db:"menu_id" json:"id"`
-	Menu string    `db:"menu" json:"menu"`
-	Date time.Time `db:"date" json:"date"`
+	ID   string `db:"menu_id" json:"id"`
+	Menu string `db:"menu" json:"menu" validate:"max=10000"`
+	Date string `db:"date" json:"date"`
+
+	// Items replace the items of the menu when given.
+	Items MenuItems `json:"items" validate:"max=100,dive"`
+
+	// Version is the version of the Menu the changes are based on. The update
+	// is rejected when someone else changed it in the meantime.
+	Version *int `json:"version" validate:"required"`
+
+	// Today is the time of the request the date is checked against. It is
+	// set by the handler before decoding the update, never by the client.
+	Today time.Time `json:"-"`
 }
 
-type Vote struct {
-	ID     string    `db:"vote_id" json:"id"`
-	Date   time.Time `db:"date" json:"date"`
-	UserID string    `db:"user_id" json:"user_id"`
-	MenuID string    `db:"menu_id" json:"menu_id"`
+// MenuDateWindow is how far in the past or in the future the date of a menu
+// may be when it is changed.
+const MenuDateWindow = 365 * 24 * time.Hour
+
+// MenuDateLayout is the layout of the dates of the menus sent by clients.
+const MenuDateLayout = "2006-01-02"
+
+// Check implements the web.Checker interface.
+func (um UpdateMenu) Check() map[string]string {
+	if um.Date == "" {
+		return nil
+	}
+	return checkMenuDate(um.Date, um.Today)
+}
+
+// ParseMenuDate parses the YYYY-MM-DD date a menu is served on. A blank date
+// is today. A timestamp, as sent by older clients, gives the day it is
+// written in.
+func ParseMenuDate(date string, today time.Time) (time.Time, error) {
+	if date == "" {
+		return today, nil
+	}
+
+	d, err := time.Parse(MenuDateLayout, date)
+	if err != nil {
+		t, err := time.Parse(time.RFC3339, date)
+		if err != nil {
+			return time.Time{}, ErrInvalidDate
+		}
+		d = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	return d, nil
+}
+
+// CheckMenuDate returns ErrDateOutOfRange when the date of a menu is not
+// within MenuDateWindow of today.
+func CheckMenuDate(date, today time.Time) error {
+	if date.Before(today.Add(-MenuDateWindow)) || date.After(today.Add(MenuDateWindow)) {
+		return ErrDateOutOfRange
+	}
+	return nil
+}
+
+// checkMenuDate fails the date of a menu which is not formatted as YYYY-MM-DD
+// or is outside MenuDateWindow of today.
+func checkMenuDate(date string, today time.Time) map[string]string {
+	d, err := ParseMenuDate(date, today)
+	if err != nil {
+		return map[string]string{"date": "date must be formatted as YYYY-MM-DD"}
+	}
+
+	if err := CheckMenuDate(d, today); err != nil {
+		return map[string]string{"date": "date must be within a year of today"}
+	}
+	return nil
+}
+
+// PatchMenu is the state of a Menu a JSON merge patch applies to. Items the
+// patch sets to null are cleared.
+type PatchMenu struct {
+	Menu  string    `json:"menu" validate:"required,max=10000"`
+	Date  string    `json:"date" validate:"required"`
+	Items MenuItems `json:"items" validate:"max=100,dive"`
+
+	// Version is the version of the Menu the patch is based on, the current
+	// one unless the patch gives it.
+	Version int `json:"version"`
+}
+
+// NewPatchMenu returns the state of the menu to apply a patch to.
+func NewPatchMenu(m Menu) PatchMenu {
+	return PatchMenu{
+		Menu:    m.Menu,
+		Date:    m.Date.Format(MenuDateLayout),
+		Items:   m.Items,
+		Version: m.Version,
+	}
+}
+
+// Update returns the update of the menu identified by id setting every field
+// to its patched value. Its date is checked against today.
+func (p PatchMenu) Update(id string, today time.Time) UpdateMenu {
+	items := p.Items
+	if items == nil {
+		items = MenuItems{}
+	}
+
+	return UpdateMenu{
+		ID:      id,
+		Menu:    p.Menu,
+		Date:    p.Date,
+		Items:   items,
+		Version: &p.Version,
+		Today:   today,
+	}
 }
@@ -0,0 +1,97 @@
+package restaurant
+
+import (
+	"testing"
+	"time"
+
+	"github.com/remisb/restaurant/internal/platform/web"
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// TestUpdateValidation validates updates with blank or absurd values are
+// rejected naming the offending field.
+func TestUpdateValidation(t *testing.T) {
+	version := 1
+	blank := ""
+	long := string(make([]byte, 201))
+	name := "Pasta Place"
+	today := time.Date(2020, time.March, 10, 0, 0, 0, 0, time.UTC)
+	date := func(years int) string {
+		return today.AddDate(years, 0, 0).Format(MenuDateLayout)
+	}
+
+	tt := []struct {
+		name  string
+		val   interface{}
+		field string
+	}{
+		{"a restaurant update", UpdateRestaurant{Name: &name, Website: &blank, Version: &version}, ""},
+		{"a blank name", UpdateRestaurant{Name: &blank, Version: &version}, "name"},
+		{"a name too long", UpdateRestaurant{Name: &long, Version: &version}, "name"},
+		{"a blank photo", UpdateRestaurant{Photos: []string{""}, Version: &version}, "photos[0]"},
+		{"a menu update", UpdateMenu{Menu: "Pizza", Date: date(0), Version: &version, Today: today}, ""},
+		{"a menu update keeping the date", UpdateMenu{Items: MenuItems{{Name: "Pizza"}}, Version: &version, Today: today}, ""},
+		{"a menu dated with a timestamp", UpdateMenu{Date: today.Format(time.RFC3339), Version: &version, Today: today}, ""},
+		{"a menu dated years ago", UpdateMenu{Menu: "Pizza", Date: date(-3), Version: &version, Today: today}, "date"},
+		{"a menu dated years ahead", UpdateMenu{Menu: "Pizza", Date: date(3), Version: &version, Today: today}, "date"},
+		{"a menu dated badly", UpdateMenu{Date: "monday", Version: &version, Today: today}, "date"},
+		{"a new menu without a date", NewMenu{Menu: "Pizza"}, ""},
+		{"a new menu dated badly", NewMenu{Menu: "Pizza", Date: "10/03/2020"}, "date"},
+		{"a patched menu dated years ago", PatchMenu{Menu: "Pizza", Date: date(-3)}.Update("", today), "date"},
+		{"a patched menu dated badly", PatchMenu{Menu: "Pizza", Date: "tomorrow"}.Update("", today), "date"},
+	}
+
+	t.Log("Given the need to check the values of updates.")
+	{
+		for i, tc := range tt {
+			t.Logf("\tTest %d:\tWhen validating %s.", i, tc.name)
+			{
+				err := web.Validate(tc.val)
+				if tc.field == "" {
+					if err != nil {
+						t.Fatalf("\t%s\tShould be valid : %s.", tests.Failed, err)
+					}
+					t.Logf("\t%s\tShould be valid.", tests.Success)
+					continue
+				}
+
+				webErr, ok := err.(*web.Error)
+				if !ok || len(webErr.Fields) != 1 || webErr.Fields[0].Field != tc.field {
+					t.Fatalf("\t%s\tShould fail on %s : got %v.", tests.Failed, tc.field, err)
+				}
+				t.Logf("\t%s\tShould fail on %s.", tests.Success, tc.field)
+			}
+		}
+	}
+}
+
+// TestParseMenuDate validates the dates of the menus sent by clients.
+func TestParseMenuDate(t *testing.T) {
+	today := time.Date(2020, time.March, 10, 0, 0, 0, 0, time.UTC)
+
+	tt := []struct {
+		date string
+		want time.Time
+		err  error
+	}{
+		{"", today, nil},
+		{"2020-03-12", time.Date(2020, time.March, 12, 0, 0, 0, 0, time.UTC), nil},
+		{"2020-03-12T23:30:00-05:00", time.Date(2020, time.March, 12, 0, 0, 0, 0, time.UTC), nil},
+		{"2020-03-12T01:30:00+03:00", time.Date(2020, time.March, 12, 0, 0, 0, 0, time.UTC), nil},
+		{"12/03/2020", time.Time{}, ErrInvalidDate},
+	}
+
+	t.Log("Given the need to parse the dates of menus.")
+	{
+		for i, tc := range tt {
+			t.Logf("\tTest %d:\tWhen parsing %q.", i, tc.date)
+			{
+				got, err := ParseMenuDate(tc.date, today)
+				if err != tc.err || !got.Equal(tc.want) {
+					t.Fatalf("\t%s\tShould get %v : got %v, %v.", tests.Failed, tc.want, got, err)
+				}
+				t.Logf("\t%s\tShould get %v.", tests.Success, tc.want)
+			}
+		}
+	}
+}
@@ -5,9 +5,14 @@ import (
 	"database/sql"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/audit"
+	"github.com/remisb/restaurant/internal/authz"
+	"github.com/remisb/restaurant/internal/outbox"
 	"github.com/remisb/restaurant/internal/platform/auth"
-	"go.opencensus.io/trace"
+	"github.com/remisb/restaurant/internal/platform/database"
+	"go.opentelemetry.io/otel"
 	"time"
 )
 
@@ -22,50 +27,112 @@ var (
 	// ErrForbidden occurs when a user tries to do something that is forbidden to
 	// them according to our access control policies.
 	ErrForbidden = errors.New("Attempted action is not allowed")
+
+	// ErrVersionConflict occurs when updating a restaurant or a menu which was
+	// changed since the version the update is based on.
+	ErrVersionConflict = errors.New("Changed by someone else since the given version")
+
+	// ErrNoMenu is used when a restaurant has no menu for the requested date.
+	ErrNoMenu = errors.New("Menu not found for this date")
+
+	// ErrInvalidDate is used when the date of a menu is not formatted as
+	// YYYY-MM-DD.
+	ErrInvalidDate = errors.New("Date must be formatted as YYYY-MM-DD")
+
+	// ErrDateOutOfRange is used when the date of a menu is not within
+	// MenuDateWindow of today.
+	ErrDateOutOfRange = errors.New("Date must be within a year of today")
+
+	// ErrDuplicateName is used when the owner already has a restaurant with
+	// the name, ignoring case.
+	ErrDuplicateName = errors.New("Owner already has a restaurant with this name")
 )
 
 func List(ctx context.Context, db *sqlx.DB) ([]Restaurant, error) {
-	ctx, span := trace.StartSpan(ctx, "internal.restaurant.List")
+	ctx, span := otel.Tracer("").Start(ctx, "internal.restaurant.List")
 	defer span.End()
 
 	restaurants := []Restaurant{}
-	const q = `SELECT * FROM restaurant`
-	if err := db.SelectContext(ctx, &restaurants, q); err != nil {
+	const q = `SELECT * FROM restaurant WHERE deleted_at IS NULL AND ($1 = '' OR org_id::text = $1)`
+	if err := sqlx.SelectContext(ctx, database.Conn(ctx, db), &restaurants, q, auth.Org(ctx)); err != nil {
 		return nil, errors.Wrap(err, "selecting restaurants")
 	}
 	return restaurants, nil
 }
 
 func Create(ctx context.Context, db *sqlx.DB, user auth.Claims, nr NewRestaurant, now time.Time) (*Restaurant, error) {
-	ctx, span := trace.StartSpan(ctx, "internal.restaurant.Create")
+	ctx, span := otel.Tracer("").Start(ctx, "internal.restaurant.Create")
 	defer span.End()
 
+	loc, err := nr.Location()
+	if err != nil {
+		return nil, err
+	}
+
 	currentTime := now.UTC()
 	r := Restaurant{
 		ID:          uuid.New().String(),
 		Name:        nr.Name,
 		Address:     nr.Address,
 		OwnerUserID: user.Subject,
+		OrgID:       user.Org(),
+		Photos:      pq.StringArray{},
+		Thumbnails:  pq.StringArray{},
+		Tags:        pq.StringArray{},
+		Version:     1,
 		DateCreated: currentTime,
 		DateUpdated:  currentTime,
 	}
+	if loc != nil {
+		r.Latitude, r.Longitude = &loc.Lat, &loc.Lng
+	}
 
 	const q = `INSERT INTO restaurant
-	    (restaurant_id, name, address, owner_user_id, date_created, date_updated)
-	    VALUES ($1, $2, $3, $4, $5, $6)`
+	    (restaurant_id, name, address, owner_user_id, org_id, latitude, longitude, date_created, date_updated)
+	    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+	tx, err := database.Begin(ctx, db)
+	if err != nil {
+		return nil, errors.Wrap(err, "beginning transaction")
+	}
+	defer tx.Rollback()
 
-	_, err := db.ExecContext(ctx, q, r.ID, r.Name, r.Address, r.OwnerUserID, r.DateCreated, r.DateUpdated)
+	_, err = tx.ExecContext(ctx, q, r.ID, r.Name, r.Address, r.OwnerUserID, r.OrgID, r.Latitude, r.Longitude, r.DateCreated, r.DateUpdated)
 	if err != nil {
+		if duplicateName(err) {
+			return nil, ErrDuplicateName
+		}
 		return nil, errors.Wrap(err, "inserting restaurant")
 	}
 
+	if err := outbox.Add(ctx, tx, outbox.TypeRestaurantCreated, r.ID, r, now); err != nil {
+		return nil, err
+	}
+
+	ne := audit.NewEntry{
+		EntityType:   audit.EntityRestaurant,
+		EntityID:     r.ID,
+		RestaurantID: r.ID,
+		Action:       audit.ActionCreate,
+		UserID:       user.Subject,
+		After:        r,
+	}
+	if err := audit.Record(ctx, tx, ne, now); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrap(err, "committing restaurant")
+	}
+
 	return &r, nil
 }
 
 
-// Retrieve finds the restaurant identified by a given ID.
+// Retrieve finds the restaurant identified by a given ID. Restaurants of
+// other organizations are not found.
 func Retrieve(ctx context.Context, db *sqlx.DB, id string) (*Restaurant, error) {
-	ctx, span := trace.StartSpan(ctx, "internal.restaurant.Retrieve")
+	ctx, span := otel.Tracer("").Start(ctx, "internal.restaurant.Retrieve")
 	defer span.End()
 
 	if _, err := uuid.Parse(id); err != nil {
@@ -74,9 +141,10 @@ func Retrieve(ctx context.Context, db *sqlx.DB, id string) (*Restaurant, error)
 
 	var r Restaurant
 
-	const q = `SELECT r.* FROM restaurant AS r WHERE r.restaurant_id = $1`
+	const q = `SELECT r.* FROM restaurant AS r WHERE r.restaurant_id = $1 AND r.deleted_at IS NULL
+		AND ($2 = '' OR r.org_id::text = $2)`
 
-	if err := db.GetContext(ctx, &r, q, id); err != nil {
+	if err := sqlx.GetContext(ctx, database.Conn(ctx, db), &r, q, id, auth.Org(ctx)); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrNotFound
 		}
@@ -87,61 +155,172 @@ func Retrieve(ctx context.Context, db *sqlx.DB, id string) (*Restaurant, error)
 	return &r, nil
 }
 
-// Update modifies data about a Restaurant. It will error if the specified ID is
-// invalid or does not reference an existing Restaurant.
-func Update(ctx context.Context, db *sqlx.DB, user auth.Claims, id string, update UpdateRestaurant, now time.Time) error {
-	ctx, span := trace.StartSpan(ctx, "internal.restaurant.Update")
+// Update modifies data about a Restaurant and returns it as updated. It will
+// error if the specified ID is invalid or does not reference an existing
+// Restaurant, and with ErrVersionConflict if the Restaurant is no longer at
+// the version of the update.
+func Update(ctx context.Context, db *sqlx.DB, user auth.Claims, id string, update UpdateRestaurant, now time.Time) (*Restaurant, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.restaurant.Update")
 	defer span.End()
 
+	loc, err := update.Location()
+	if err != nil {
+		return nil, err
+	}
+
 	r, err := Retrieve(ctx, db, id)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// If you do not have the admin role ...
 	// and you are not the owner of this product ...
 	// then get outta here!
-	if !user.HasRole(auth.RoleAdmin) && r.OwnerUserID != user.Subject {
-		return ErrForbidden
+	if !authz.Allowed(user, authz.Update, authz.Resource{Kind: authz.Restaurant, Owner: r.OwnerUserID}) {
+		return nil, ErrForbidden
+	}
+
+	if update.Version != nil && *update.Version != r.Version {
+		return nil, ErrVersionConflict
 	}
 
+	before := *r
 	if update.Name != nil {
 		r.Name = *update.Name
 	}
 	if update.Address != nil {
 		r.Address = *update.Address
 	}
+	if update.Website != nil {
+		r.Website = *update.Website
+	}
+	if update.Phone != nil {
+		r.Phone = *update.Phone
+	}
+	if update.Photos != nil {
+		r.SetPhotos(update.Photos)
+	}
+	if update.Public != nil {
+		r.Public = *update.Public
+	}
+	if update.AnonymousVotes != nil {
+		r.AnonymousVotes = *update.AnonymousVotes
+	}
+	if loc != nil {
+		r.Latitude, r.Longitude = &loc.Lat, &loc.Lng
+	}
 	r.DateUpdated = now
 
+	// The version in the WHERE clause catches changes made since the
+	// restaurant was retrieved above.
 	const q = `UPDATE restaurant SET
 		"name" = $2,
 		"address" = $3,
-		"date_updated" = $4
-		WHERE restaurant_id = $1`
-	_, err = db.ExecContext(ctx, q, id,
-		r.Name, r.Address, r.DateUpdated,
+		"website" = $4,
+		"phone" = $5,
+		"photos" = $6,
+		"thumbnails" = $7,
+		"public" = $8,
+		"anonymous_votes" = $9,
+		"latitude" = $10,
+		"longitude" = $11,
+		"date_updated" = $12,
+		"version" = version + 1
+		WHERE restaurant_id = $1 AND version = $13 AND deleted_at IS NULL`
+
+	tx, err := database.Begin(ctx, db)
+	if err != nil {
+		return nil, errors.Wrap(err, "beginning transaction")
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, q, id,
+		r.Name, r.Address, r.Website, r.Phone, r.Photos, r.Thumbnails, r.Public, r.AnonymousVotes, r.Latitude, r.Longitude, r.DateUpdated, r.Version,
 	)
 	if err != nil {
-		return errors.Wrap(err, "updating restaurant")
+		if duplicateName(err) {
+			return nil, ErrDuplicateName
+		}
+		return nil, errors.Wrap(err, "updating restaurant")
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return nil, ErrVersionConflict
 	}
+	r.Version++
 
-	return nil
+	ne := audit.NewEntry{
+		EntityType:   audit.EntityRestaurant,
+		EntityID:     id,
+		RestaurantID: id,
+		Action:       audit.ActionUpdate,
+		UserID:       user.Subject,
+		Before:       before,
+		After:        r,
+	}
+	if err := audit.Record(ctx, tx, ne, now); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrap(err, "committing restaurant")
+	}
+
+	return r, nil
 }
 
-// Delete removes the product identified by a given ID.
-func Delete(ctx context.Context, db *sqlx.DB, id string) error {
-	ctx, span := trace.StartSpan(ctx, "internal.restaurant.Delete")
+// Delete removes the restaurant identified by a given ID. The row is kept,
+// marked as deleted, so the votes and menus referencing it stay intact. It
+// errors with ErrNotFound when there is no such restaurant or it was already
+// deleted.
+func Delete(ctx context.Context, db *sqlx.DB, id string, now time.Time) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.restaurant.Delete")
 	defer span.End()
 
 	if _, err := uuid.Parse(id); err != nil {
 		return ErrInvalidID
 	}
 
-	const q = `DELETE FROM restaurant WHERE restaurant_id = $1`
+	const q = `UPDATE restaurant SET
+		"deleted_at" = $2,
+		"version" = version + 1
+		WHERE restaurant_id = $1 AND deleted_at IS NULL AND ($3 = '' OR org_id::text = $3)`
 
-	if _, err := db.ExecContext(ctx, q, id); err != nil {
+	tx, err := database.Begin(ctx, db)
+	if err != nil {
+		return errors.Wrap(err, "beginning transaction")
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, q, id, now.UTC(), auth.Org(ctx))
+	if err != nil {
 		return errors.Wrapf(err, "deleting restaurant %s", id)
 	}
 
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+
+	ne := audit.NewEntry{
+		EntityType:   audit.EntityRestaurant,
+		EntityID:     id,
+		RestaurantID: id,
+		Action:       audit.ActionDelete,
+		UserID:       auth.Subject(ctx),
+	}
+	if err := audit.Record(ctx, tx, ne, now); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Wrapf(err, "committing deletion of restaurant %s", id)
+	}
+
 	return nil
 }
+
+// duplicateName reports whether the error is the violation of the unique
+// name of the restaurants of an owner.
+func duplicateName(err error) bool {
+	v, ok := database.AsViolation(err)
+	return ok && v.Unique && v.Constraint == "restaurant_owner_name_idx"
+}
@@ -0,0 +1,128 @@
+package restaurant
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/database"
+	"go.opentelemetry.io/otel"
+)
+
+// Similarities from which a restaurant is likely a duplicate of another.
+// The names must be that similar, the addresses too when both are known.
+const (
+	DuplicateNameSimilarity    = 0.6
+	DuplicateAddressSimilarity = 0.5
+)
+
+// maxMatches is the most similar restaurants returned by FindSimilar.
+const maxMatches = 5
+
+// ErrDuplicate is used when creating a restaurant similar to existing ones.
+var ErrDuplicate = errors.New("Similar restaurants already exist")
+
+// Match is an existing restaurant similar to a new one, along with the
+// similarities from 0 to 1 of their names and addresses.
+type Match struct {
+	Restaurant
+	NameSimilarity    float64 `db:"name_similarity" json:"name_similarity"`
+	AddressSimilarity float64 `db:"address_similarity" json:"address_similarity"`
+}
+
+// FindSimilar gets the restaurants whose name and address are similar enough
+// to the given ones to be duplicates, the most similar first. Similarities
+// are those of the trigrams of pg_trgm.
+func FindSimilar(ctx context.Context, db *sqlx.DB, name, address string) ([]Match, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.restaurant.FindSimilar")
+	defer span.End()
+
+	// The % operator lets the search use the trigram index before the
+	// exact similarities filter out the looser matches.
+	matches := []Match{}
+	const q = `SELECT r.*,
+		similarity(r.name, $1) AS name_similarity,
+		similarity(COALESCE(r.address, ''), $2) AS address_similarity
+		FROM restaurant AS r
+		WHERE r.deleted_at IS NULL AND r.name % $1
+		AND similarity(r.name, $1) >= $3
+		AND ($2 = '' OR COALESCE(r.address, '') = '' OR similarity(r.address, $2) >= $4)
+		AND ($5 = '' OR r.org_id::text = $5)
+		ORDER BY name_similarity DESC, address_similarity DESC
+		LIMIT $6`
+	err := sqlx.SelectContext(ctx, database.Conn(ctx, db), &matches, q,
+		name, address, DuplicateNameSimilarity, DuplicateAddressSimilarity, auth.Org(ctx), maxMatches)
+	if err != nil {
+		return nil, errors.Wrap(err, "selecting similar restaurants")
+	}
+	return matches, nil
+}
+
+// Duplicates returns the restaurants of the list which are likely duplicates
+// of one with the name and address, the most similar first. It matches like
+// FindSimilar for the stores without trigram support.
+func Duplicates(restaurants []Restaurant, name, address string) []Match {
+	matches := []Match{}
+	for _, r := range restaurants {
+		m := Match{
+			Restaurant:        r,
+			NameSimilarity:    similarity(r.Name, name),
+			AddressSimilarity: similarity(r.Address, address),
+		}
+		if m.NameSimilarity < DuplicateNameSimilarity {
+			continue
+		}
+		if address != "" && r.Address != "" && m.AddressSimilarity < DuplicateAddressSimilarity {
+			continue
+		}
+		matches = append(matches, m)
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].NameSimilarity != matches[j].NameSimilarity {
+			return matches[i].NameSimilarity > matches[j].NameSimilarity
+		}
+		return matches[i].AddressSimilarity > matches[j].AddressSimilarity
+	})
+	if len(matches) > maxMatches {
+		matches = matches[:maxMatches]
+	}
+	return matches
+}
+
+// similarity returns the share of the trigrams of a and b they have in
+// common, like the similarity function of pg_trgm.
+func similarity(a, b string) float64 {
+	ta, tb := trigrams(a), trigrams(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+
+	common := 0
+	for t := range ta {
+		if tb[t] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(ta)+len(tb)-common)
+}
+
+// trigrams returns the set of trigrams of the words of s. As in pg_trgm the
+// words are lowercased and padded with two spaces before and one after.
+func trigrams(s string) map[string]bool {
+	ts := make(map[string]bool)
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, w := range words {
+		rs := []rune("  " + w + " ")
+		for i := 0; i+3 <= len(rs); i++ {
+			ts[string(rs[i:i+3])] = true
+		}
+	}
+	return ts
+}
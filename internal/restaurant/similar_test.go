@@ -0,0 +1,46 @@
+package restaurant
+
+import (
+	"math"
+	"testing"
+
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// TestDuplicates validates the restaurants likely added twice are found.
+func TestDuplicates(t *testing.T) {
+	restaurants := []Restaurant{
+		{ID: "1", Name: "Pizza Place", Address: "12 Main St"},
+		{ID: "2", Name: "Pizza Palace", Address: "Harbour Rd 3"},
+		{ID: "3", Name: "Sushi Bar", Address: "12 Main St"},
+		{ID: "4", Name: "The Pizza Place"},
+	}
+
+	t.Log("Given the need to detect restaurants added twice.")
+	{
+		t.Log("\tTest 0:\tWhen comparing names like pg_trgm.")
+		{
+			if got := similarity("word", "two words"); math.Abs(got-0.363636) > 1e-6 {
+				t.Fatalf("\t%s\tShould match the similarity of pg_trgm : got %f.", tests.Failed, got)
+			}
+			t.Logf("\t%s\tShould match the similarity of pg_trgm.", tests.Success)
+		}
+
+		t.Log("\tTest 1:\tWhen adding a restaurant with a similar name.")
+		{
+			matches := Duplicates(restaurants, "pizza place!", "12 Main Street")
+			if len(matches) != 2 || matches[0].ID != "1" || matches[1].ID != "4" {
+				t.Fatalf("\t%s\tShould find the similar restaurants at a similar address : got %+v.", tests.Failed, matches)
+			}
+			t.Logf("\t%s\tShould find the similar restaurants at a similar address.", tests.Success)
+		}
+
+		t.Log("\tTest 2:\tWhen adding a restaurant with another name.")
+		{
+			if matches := Duplicates(restaurants, "Kebab House", "12 Main St"); len(matches) != 0 {
+				t.Fatalf("\t%s\tShould not find any : got %+v.", tests.Failed, matches)
+			}
+			t.Logf("\t%s\tShould not find any.", tests.Success)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package restaurant
+
+import (
+	"context"
+	"time"
+
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/database"
+	"github.com/remisb/restaurant/internal/platform/geo"
+)
+
+// Store is the set of restaurant operations used by the API handlers. It lets
+// the handlers run against an in-memory fake in unit tests.
+type Store interface {
+	List(ctx context.Context) ([]Restaurant, error)
+	ListNearby(ctx context.Context, near geo.Point, radius float64) ([]Nearby, error)
+	FindSimilar(ctx context.Context, name, address string) ([]Match, error)
+	Create(ctx context.Context, user auth.Claims, nr NewRestaurant, now time.Time) (*Restaurant, error)
+	Retrieve(ctx context.Context, id string) (*Restaurant, error)
+	Update(ctx context.Context, user auth.Claims, id string, update UpdateRestaurant, now time.Time) (*Restaurant, error)
+	Delete(ctx context.Context, id string, now time.Time) error
+	AddFavorite(ctx context.Context, userID, id string, now time.Time) error
+	RemoveFavorite(ctx context.Context, userID, id string) error
+	ListFavorites(ctx context.Context, userID string) ([]Restaurant, error)
+	ListIncluded(ctx context.Context, ids []string, in Include, now time.Time) (map[string]Included, error)
+}
+
+// DBStore implements Store on top of the database. Lists and lookups are
+// read from the replica when there is one. Every call ends after the query
+// timeout of the database.
+type DBStore struct {
+	db *database.DB
+}
+
+// NewStore constructs a Store backed by the database.
+func NewStore(db *database.DB) *DBStore {
+	return &DBStore{db: db}
+}
+
+// List implements the Store interface.
+func (s *DBStore) List(ctx context.Context) ([]Restaurant, error) {
+	ctx, cancel := s.db.Deadline(ctx)
+	defer cancel()
+
+	return List(ctx, s.db.Replica())
+}
+
+// ListNearby implements the Store interface.
+func (s *DBStore) ListNearby(ctx context.Context, near geo.Point, radius float64) ([]Nearby, error) {
+	ctx, cancel := s.db.Deadline(ctx)
+	defer cancel()
+
+	return ListNearby(ctx, s.db.Replica(), near, radius)
+}
+
+// FindSimilar implements the Store interface. The restaurants are read from
+// the primary so one just created is found.
+func (s *DBStore) FindSimilar(ctx context.Context, name, address string) ([]Match, error) {
+	ctx, cancel := s.db.Deadline(ctx)
+	defer cancel()
+
+	return FindSimilar(ctx, s.db.Primary(), name, address)
+}
+
+// Create implements the Store interface.
+func (s *DBStore) Create(ctx context.Context, user auth.Claims, nr NewRestaurant, now time.Time) (*Restaurant, error) {
+	ctx, cancel := s.db.Deadline(ctx)
+	defer cancel()
+
+	return Create(ctx, s.db.Primary(), user, nr, now)
+}
+
+// Retrieve implements the Store interface.
+func (s *DBStore) Retrieve(ctx context.Context, id string) (*Restaurant, error) {
+	ctx, cancel := s.db.Deadline(ctx)
+	defer cancel()
+
+	return Retrieve(ctx, s.db.Replica(), id)
+}
+
+// Update implements the Store interface.
+func (s *DBStore) Update(ctx context.Context, user auth.Claims, id string, update UpdateRestaurant, now time.Time) (*Restaurant, error) {
+	ctx, cancel := s.db.Deadline(ctx)
+	defer cancel()
+
+	return Update(ctx, s.db.Primary(), user, id, update, now)
+}
+
+// Delete implements the Store interface.
+func (s *DBStore) Delete(ctx context.Context, id string, now time.Time) error {
+	ctx, cancel := s.db.Deadline(ctx)
+	defer cancel()
+
+	return Delete(ctx, s.db.Primary(), id, now)
+}
+
+// AddFavorite implements the Store interface.
+func (s *DBStore) AddFavorite(ctx context.Context, userID, id string, now time.Time) error {
+	ctx, cancel := s.db.Deadline(ctx)
+	defer cancel()
+
+	return AddFavorite(ctx, s.db.Primary(), userID, id, now)
+}
+
+// RemoveFavorite implements the Store interface.
+func (s *DBStore) RemoveFavorite(ctx context.Context, userID, id string) error {
+	ctx, cancel := s.db.Deadline(ctx)
+	defer cancel()
+
+	return RemoveFavorite(ctx, s.db.Primary(), userID, id)
+}
+
+// ListFavorites implements the Store interface. The favorites are read from
+// the primary so a restaurant just marked is listed.
+func (s *DBStore) ListFavorites(ctx context.Context, userID string) ([]Restaurant, error) {
+	ctx, cancel := s.db.Deadline(ctx)
+	defer cancel()
+
+	return ListFavorites(ctx, s.db.Primary(), userID)
+}
+
+// ListIncluded implements the Store interface.
+func (s *DBStore) ListIncluded(ctx context.Context, ids []string, in Include, now time.Time) (map[string]Included, error) {
+	ctx, cancel := s.db.Deadline(ctx)
+	defer cancel()
+
+	return ListIncluded(ctx, s.db.Replica(), ids, in, now)
+}
+
+// MenuStore is the set of menu operations used by the API handlers.
+type MenuStore interface {
+	CreateMenu(ctx context.Context, user auth.Claims, nm NewMenu, now time.Time) (*Menu, error)
+	RetrieveMenu(ctx context.Context, id string) (*Menu, error)
+	ListMenus(ctx context.Context, restaurantID string, from time.Time) ([]Menu, error)
+	UpdateMenu(ctx context.Context, user auth.Claims, restaurantID string, update UpdateMenu, now time.Time) (*Menu, error)
+}
+
+// DBMenuStore implements MenuStore on top of the database. Lists and lookups
+// are read from the replica when there is one. Every call ends after the
+// query timeout of the database.
+type DBMenuStore struct {
+	db *database.DB
+}
+
+// NewMenuStore constructs a MenuStore backed by the database.
+func NewMenuStore(db *database.DB) *DBMenuStore {
+	return &DBMenuStore{db: db}
+}
+
+// CreateMenu implements the MenuStore interface.
+func (s *DBMenuStore) CreateMenu(ctx context.Context, user auth.Claims, nm NewMenu, now time.Time) (*Menu, error) {
+	ctx, cancel := s.db.Deadline(ctx)
+	defer cancel()
+
+	return CreateMenu(ctx, s.db.Primary(), user, nm, now)
+}
+
+// RetrieveMenu implements the MenuStore interface.
+func (s *DBMenuStore) RetrieveMenu(ctx context.Context, id string) (*Menu, error) {
+	ctx, cancel := s.db.Deadline(ctx)
+	defer cancel()
+
+	return MenuRetrieve(ctx, s.db.Replica(), id)
+}
+
+// ListMenus implements the MenuStore interface.
+func (s *DBMenuStore) ListMenus(ctx context.Context, restaurantID string, from time.Time) ([]Menu, error) {
+	ctx, cancel := s.db.Deadline(ctx)
+	defer cancel()
+
+	return MenuList(ctx, s.db.Replica(), restaurantID, from)
+}
+
+// UpdateMenu implements the MenuStore interface.
+func (s *DBMenuStore) UpdateMenu(ctx context.Context, user auth.Claims, restaurantID string, update UpdateMenu, now time.Time) (*Menu, error) {
+	ctx, cancel := s.db.Deadline(ctx)
+	defer cancel()
+
+	return MenuUpdate(ctx, s.db.Primary(), user, restaurantID, update, now)
+}
@@ -0,0 +1,115 @@
+package retention
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/remisb/restaurant/internal/platform/clock"
+	"github.com/remisb/restaurant/internal/platform/database"
+	"github.com/remisb/restaurant/internal/platform/job"
+)
+
+// Purger applies the retention policies in the background.
+type Purger struct {
+	log      *log.Logger
+	db       *sqlx.DB
+	policies []Policy
+	interval time.Duration
+	tracker  *job.Tracker
+	clock    clock.Clock
+	leader   *database.Leader
+}
+
+// NewPurger constructs a Purger applying the policies every interval.
+func NewPurger(log *log.Logger, db *sqlx.DB, policies []Policy, interval time.Duration) *Purger {
+	return &Purger{
+		log:      log,
+		db:       db,
+		policies: policies,
+		interval: interval,
+		tracker:  job.NewTracker("retention"),
+		clock:    clock.System,
+	}
+}
+
+// Clock sets the clock telling which rows are past their retention, the
+// clock of the system by default. It must be called before Run.
+func (p *Purger) Clock(c clock.Clock) *Purger {
+	p.clock = c
+	return p
+}
+
+// Leader sets the election the purger must win to apply the policies, so
+// that only one replica of the service purges at a time. It must be called
+// before Run. Without it the purger always runs.
+func (p *Purger) Leader(l *database.Leader) *Purger {
+	p.leader = l
+	return p
+}
+
+// Status reports the state of the purger to the health check.
+func (p *Purger) Status() job.Status {
+	return p.tracker.Status()
+}
+
+// Run applies the policies until the context is canceled.
+func (p *Purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	defer p.resign()
+
+	for {
+		if p.lead(ctx) {
+			p.purge(ctx, p.clock.Now())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// lead reports whether the purger may run, standing for election when it
+// has a leader to elect.
+func (p *Purger) lead(ctx context.Context) bool {
+	if p.leader == nil {
+		return true
+	}
+
+	leading, err := p.leader.Elect(ctx)
+	if err != nil {
+		p.log.Printf("retention : election : ERROR : %+v", err)
+	}
+	p.tracker.Elected(leading, p.leader.Since())
+	return leading
+}
+
+// resign lets another replica purge once the purger stops.
+func (p *Purger) resign() {
+	if p.leader == nil {
+		return
+	}
+
+	if err := p.leader.Resign(); err != nil {
+		p.log.Printf("retention : election : ERROR : %+v", err)
+	}
+	p.tracker.Elected(false, time.Time{})
+}
+
+// purge applies the policies once.
+func (p *Purger) purge(ctx context.Context, now time.Time) {
+	purges, err := Apply(ctx, p.db, p.policies, false, now)
+	if err != nil {
+		p.log.Printf("retention : ERROR : %+v", err)
+	}
+	for _, pg := range purges {
+		if pg.Rows > 0 {
+			p.log.Printf("retention : %s : purged %d rows of %s before %s", pg.Dataset, pg.Rows, pg.Table, pg.Before.Format(time.RFC3339))
+		}
+	}
+	p.tracker.Record(err, now)
+}
@@ -0,0 +1,139 @@
+// Package retention purges the data kept longer than the retention policies
+// of the deployment allow, like the votes of past years or the restaurants
+// deleted a while ago.
+package retention
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/platform/database"
+	"go.opentelemetry.io/otel"
+)
+
+// These are the datasets retention policies apply to.
+const (
+	Votes              = "votes"
+	DeletedRestaurants = "deleted_restaurants"
+)
+
+// Policy keeps the rows of a dataset for Age. The rows of a policy with a
+// zero age are kept forever.
+type Policy struct {
+	Dataset string
+	Age     time.Duration
+}
+
+// Purge reports the rows of a table purged by a policy, or which would be
+// during a dry run.
+type Purge struct {
+	Dataset string    `json:"dataset"`
+	Table   string    `json:"table"`
+	Before  time.Time `json:"before"`
+	Rows    int64     `json:"rows"`
+}
+
+// step purges the rows of a table matching where, which takes the cutoff
+// of the policy as $1.
+type step struct {
+	table string
+	where string
+}
+
+// purgeable selects the restaurants deleted before the cutoff which nothing
+// kept refers to anymore. Restaurants which won a lunch or were ordered from
+// are part of the history and never purged.
+const purgeable = `SELECT r.restaurant_id FROM restaurant AS r
+	WHERE r.deleted_at < $1
+	AND NOT EXISTS (SELECT 1 FROM vote AS v WHERE v.restaurant_id = r.restaurant_id)
+	AND NOT EXISTS (SELECT 1 FROM dish_vote AS dv WHERE dv.restaurant_id = r.restaurant_id)
+	AND NOT EXISTS (SELECT 1 FROM winner AS w WHERE w.restaurant_id = r.restaurant_id)
+	AND NOT EXISTS (SELECT 1 FROM team_winner AS tw WHERE tw.restaurant_id = r.restaurant_id)
+	AND NOT EXISTS (SELECT 1 FROM dish_winner AS dw WHERE dw.restaurant_id = r.restaurant_id)
+	AND NOT EXISTS (SELECT 1 FROM orders AS o WHERE o.restaurant_id = r.restaurant_id)`
+
+// datasets lists the steps purging every dataset, in the order they run so
+// no foreign key is left dangling.
+var datasets = map[string][]step{
+	Votes: {
+		{table: "vote", where: `date < $1`},
+		{table: "vote_event", where: `date < $1`},
+		{table: "dish_vote", where: `date < $1`},
+	},
+	DeletedRestaurants: {
+		{table: "menu_view", where: `menu_id IN (SELECT menu_id FROM menu WHERE restaurant_id IN (` + purgeable + `))`},
+		{table: "menu_comment", where: `restaurant_id IN (` + purgeable + `)`},
+		{table: "menu", where: `restaurant_id IN (` + purgeable + `)`},
+		{table: "menu_vote_tally", where: `restaurant_id IN (` + purgeable + `)`},
+		{table: "favorite", where: `restaurant_id IN (` + purgeable + `)`},
+		{table: "restaurant_rating", where: `restaurant_id IN (` + purgeable + `)`},
+		{table: "coupon_redemption", where: `coupon_id IN (SELECT coupon_id FROM coupon WHERE restaurant_id IN (` + purgeable + `))`},
+		{table: "coupon", where: `restaurant_id IN (` + purgeable + `)`},
+		{table: "restaurant", where: `restaurant_id IN (` + purgeable + `)`},
+	},
+}
+
+// Apply purges the rows older than the age of every policy. With dryRun set
+// nothing is deleted, the rows which would be are counted instead. Each
+// policy is applied in a transaction of its own.
+func Apply(ctx context.Context, db *sqlx.DB, policies []Policy, dryRun bool, now time.Time) ([]Purge, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.retention.Apply")
+	defer span.End()
+
+	purges := []Purge{}
+	for _, p := range policies {
+		if p.Age <= 0 {
+			continue
+		}
+		steps, ok := datasets[p.Dataset]
+		if !ok {
+			return nil, errors.Errorf("unknown retention dataset %q", p.Dataset)
+		}
+
+		ps, err := apply(ctx, db, p.Dataset, steps, now.UTC().Add(-p.Age), dryRun)
+		if err != nil {
+			return nil, errors.Wrapf(err, "applying retention of %s", p.Dataset)
+		}
+		purges = append(purges, ps...)
+	}
+
+	return purges, nil
+}
+
+// apply runs the steps purging a dataset of the rows older than before.
+func apply(ctx context.Context, db *sqlx.DB, dataset string, steps []step, before time.Time, dryRun bool) ([]Purge, error) {
+	tx, err := database.Begin(ctx, db)
+	if err != nil {
+		return nil, errors.Wrap(err, "beginning transaction")
+	}
+	defer tx.Rollback()
+
+	purges := make([]Purge, len(steps))
+	for i, s := range steps {
+		purges[i] = Purge{Dataset: dataset, Table: s.table, Before: before}
+
+		if dryRun {
+			q := `SELECT COUNT(*) FROM ` + s.table + ` WHERE ` + s.where
+			if err := sqlx.GetContext(ctx, tx, &purges[i].Rows, q, before); err != nil {
+				return nil, errors.Wrapf(err, "counting %s", s.table)
+			}
+			continue
+		}
+
+		res, err := tx.ExecContext(ctx, `DELETE FROM `+s.table+` WHERE `+s.where, before)
+		if err != nil {
+			return nil, errors.Wrapf(err, "purging %s", s.table)
+		}
+		if purges[i].Rows, err = res.RowsAffected(); err != nil {
+			return nil, errors.Wrapf(err, "counting purged %s", s.table)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrap(err, "committing purge")
+	}
+
+	return purges, nil
+}
@@ -0,0 +1,186 @@
+package retention_test
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/remisb/restaurant/internal/platform/clock"
+	"github.com/remisb/restaurant/internal/platform/database"
+	"github.com/remisb/restaurant/internal/platform/job"
+	"github.com/remisb/restaurant/internal/retention"
+	"github.com/remisb/restaurant/internal/schema"
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// history is a vote of last year, one of this year and a restaurant deleted
+// last year nothing refers to.
+const history = `
+INSERT INTO vote (date, user_id, restaurant_id, time_voted) VALUES
+	('2019-02-01', '5cf37266-3473-4006-984f-9325122678b7', '0ce90028-69cb-4e9c-9af0-7bbada50d5b6', '2019-02-01 09:00:00'),
+	('2020-03-02', '5cf37266-3473-4006-984f-9325122678b7', '0ce90028-69cb-4e9c-9af0-7bbada50d5b6', '2020-03-02 09:00:00');
+UPDATE restaurant SET deleted_at = '2019-06-01 00:00:00' WHERE restaurant_id = '8800c4d0-0219-49d5-9eb0-db457ee015e5';
+`
+
+// policies keep the votes for a year and the deleted restaurants for a month.
+var policies = []retention.Policy{
+	{Dataset: retention.Votes, Age: 365 * 24 * time.Hour},
+	{Dataset: retention.DeletedRestaurants, Age: 30 * 24 * time.Hour},
+}
+
+// rows returns the rows of the table kept by the test data.
+func rows(t *testing.T, db *sqlx.DB) (votes, restaurants int) {
+	if err := db.Get(&votes, `SELECT COUNT(*) FROM vote`); err != nil {
+		t.Fatalf("counting votes: %s", err)
+	}
+	if err := db.Get(&restaurants, `SELECT COUNT(*) FROM restaurant WHERE restaurant_id = '8800c4d0-0219-49d5-9eb0-db457ee015e5'`); err != nil {
+		t.Fatalf("counting restaurants: %s", err)
+	}
+	return votes, restaurants
+}
+
+// purged returns the rows of the table reported by the purges.
+func purged(purges []retention.Purge, table string) int64 {
+	var n int64
+	for _, p := range purges {
+		if p.Table == table {
+			n += p.Rows
+		}
+	}
+	return n
+}
+
+// TestApply validates the rows past their retention are counted by a dry
+// run and deleted otherwise.
+func TestApply(t *testing.T) {
+	db, teardown := tests.NewUnit(t)
+	defer teardown()
+
+	if err := schema.SeedProfile(db, "dev"); err != nil {
+		t.Fatalf("seeding: %s", err)
+	}
+	if _, err := db.Exec(history); err != nil {
+		t.Fatalf("seeding history: %s", err)
+	}
+
+	now := time.Date(2020, time.March, 2, 12, 0, 0, 0, time.UTC)
+
+	t.Log("Given the need to keep the data no longer than the retention policies allow.")
+	{
+		ctx := tests.Context()
+
+		t.Log("\tTest 0:\tWhen the policies are dry run.")
+		{
+			purges, err := retention.Apply(ctx, db, policies, true, now)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to dry run the policies : %s.", tests.Failed, err)
+			}
+			if purged(purges, "vote") != 1 || purged(purges, "restaurant") != 1 {
+				t.Fatalf("\t%s\tShould count the rows past their retention : got %+v.", tests.Failed, purges)
+			}
+			t.Logf("\t%s\tShould count the rows past their retention.", tests.Success)
+
+			if votes, restaurants := rows(t, db); votes != 2 || restaurants != 1 {
+				t.Fatalf("\t%s\tShould not delete them : got %d votes, %d restaurants.", tests.Failed, votes, restaurants)
+			}
+			t.Logf("\t%s\tShould not delete them.", tests.Success)
+		}
+
+		t.Log("\tTest 1:\tWhen the policies are applied.")
+		{
+			purges, err := retention.Apply(ctx, db, policies, false, now)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to apply the policies : %s.", tests.Failed, err)
+			}
+			if purged(purges, "vote") != 1 || purged(purges, "restaurant") != 1 {
+				t.Fatalf("\t%s\tShould report the rows deleted : got %+v.", tests.Failed, purges)
+			}
+			t.Logf("\t%s\tShould report the rows deleted.", tests.Success)
+
+			if votes, restaurants := rows(t, db); votes != 1 || restaurants != 0 {
+				t.Fatalf("\t%s\tShould delete the rows past their retention only : got %d votes, %d restaurants.", tests.Failed, votes, restaurants)
+			}
+			t.Logf("\t%s\tShould delete the rows past their retention only.", tests.Success)
+		}
+	}
+}
+
+// TestPurger validates a single replica purges at a time.
+func TestPurger(t *testing.T) {
+	db, teardown := tests.NewUnit(t)
+	defer teardown()
+
+	if err := schema.SeedProfile(db, "dev"); err != nil {
+		t.Fatalf("seeding: %s", err)
+	}
+	if _, err := db.Exec(history); err != nil {
+		t.Fatalf("seeding history: %s", err)
+	}
+
+	now := time.Date(2020, time.March, 2, 12, 0, 0, 0, time.UTC)
+
+	// wait polls the status of the purger until ok holds.
+	wait := func(p *retention.Purger, ok func(job.Status) bool) job.Status {
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			st := p.Status()
+			if ok(st) || time.Now().After(deadline) {
+				return st
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	t.Log("Given the need to purge from one replica of the service.")
+	{
+		other := database.NewLeader(db, database.LockRetention)
+		if leading, err := other.Elect(context.Background()); err != nil || !leading {
+			t.Fatalf("\t%s\tShould let another replica lead : got %v, %v.", tests.Failed, leading, err)
+		}
+
+		p := retention.NewPurger(log.New(ioutil.Discard, "", 0), db, policies, 10*time.Millisecond).
+			Clock(clock.Fixed(now)).
+			Leader(database.NewLeader(db, database.LockRetention))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			p.Run(ctx)
+			close(done)
+		}()
+
+		t.Log("\tTest 0:\tWhen another replica leads.")
+		{
+			st := wait(p, func(st job.Status) bool { return st.Role == job.RoleStandby })
+			if st.Role != job.RoleStandby || st.Runs != 0 {
+				t.Fatalf("\t%s\tShould stand by : got %+v.", tests.Failed, st)
+			}
+			if votes, _ := rows(t, db); votes != 2 {
+				t.Fatalf("\t%s\tShould not purge : got %d votes.", tests.Failed, votes)
+			}
+			t.Logf("\t%s\tShould stand by without purging.", tests.Success)
+		}
+
+		t.Log("\tTest 1:\tWhen the other replica resigns.")
+		{
+			if err := other.Resign(); err != nil {
+				t.Fatalf("\t%s\tShould let the other replica resign : %s.", tests.Failed, err)
+			}
+
+			st := wait(p, func(st job.Status) bool { return st.Runs > 0 })
+			if st.Role != job.RoleLeader || st.Runs == 0 || st.Failures != 0 {
+				t.Fatalf("\t%s\tShould take over : got %+v.", tests.Failed, st)
+			}
+			if votes, restaurants := rows(t, db); votes != 1 || restaurants != 0 {
+				t.Fatalf("\t%s\tShould purge : got %d votes, %d restaurants.", tests.Failed, votes, restaurants)
+			}
+			t.Logf("\t%s\tShould take over and purge.", tests.Success)
+		}
+
+		cancel()
+		<-done
+	}
+}
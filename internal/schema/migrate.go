@@ -1,67 +1,506 @@
 package schema
 
 import (
-	"github.com/dimiro1/darwin"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/platform/database"
 )
 
-func Migrate(db *sqlx.DB) error {
-	driver := darwin.NewGenericDriver(db.DB, darwin.PostgresDialect{})
-	d := darwin.New(driver, migrations, nil)
-	return d.Migrate()
-}
-
-var migrations = []darwin.Migration {
-	{
-		Version: 1,
-		Description: "Add restaurant", // Add restaurant
-	Script: `
-CREATE TABLE restaurant (
-	restaurant_id UUID,
-	name          TEXT NOT NULL,
-	address       TEXT,
-    owner_user_id TEXT NOT NULL,
-	date_created  TIMESTAMP,
-	date_updated  TIMESTAMP,
-	PRIMARY KEY (restaurant_id)
-);`},
-	{
-		Version:     2,
-		Description: "Add menu",
-		Script: `
-CREATE TABLE menu (
-		menu_id       UUID,
-		restaurant_id UUID,
-		date          DATE NOT NULL DEFAULT CURRENT_DATE,
-		menu          VARCHAR(1024),
-		votes         INTEGER,
-        PRIMARY KEY(restaurant_id, date)
-)`},
-	{
-		Version:     3,
-		Description: "Add votes",
-		Script: `
-CREATE TABLE vote (
-    date          TIMESTAMP NOT NULL,
-    user_id       UUID,
-	restaurant_id UUID,
-	time_voted    TIMESTAMP,
-
-	PRIMARY KEY (date, user_id),
-	FOREIGN KEY (restaurant_id) REFERENCES restaurant(restaurant_id)
-);`},
-	{
-		Version:     4,
-		Description: "Add users",
-		Script: `
-CREATE TABLE users (
-	user_id       UUID,
-	name          TEXT,
-	email         TEXT UNIQUE,
-	roles         TEXT[],
-	password_hash TEXT,
-	date_created TIMESTAMP,
-	date_updated TIMESTAMP,
-	PRIMARY KEY (user_id)
-);`},
+// ErrPending is returned when the database is not migrated to the latest
+// version of the schema.
+var ErrPending = errors.New("database migrations are pending")
+
+// ErrUnknownVersion is returned when forcing a version which has no migration.
+var ErrUnknownVersion = errors.New("unknown migration version")
+
+// ErrChecksumMismatch is returned when the script of an applied migration is
+// not the one the binary holds, meaning it was edited after being applied.
+var ErrChecksumMismatch = errors.New("applied migration was edited")
+
+// files holds the migrations of the schema. Every version has a file named
+// NNNN_description.up.sql applying it and NNNN_description.down.sql reverting
+// it. Applied up scripts are checksummed so they must never be edited, add a
+// new version instead.
+//
+//go:embed migrations/*.sql
+var files embed.FS
+
+// migrations are the migrations of the schema ordered by version.
+var migrations = mustLoad(files)
+
+// Migration is a version of the schema along with the scripts applying and
+// reverting it.
+type Migration struct {
+	Version     int
+	Description string
+	Up          string
+	Down        string
+}
+
+// Checksum returns the SHA-256 of the up script recorded when the migration
+// is applied.
+func (m Migration) Checksum() string {
+	return checksum(m.Up)
+}
+
+// checksum returns the hex encoded SHA-256 of the script.
+func checksum(script string) string {
+	sum := sha256.Sum256([]byte(script))
+	return hex.EncodeToString(sum[:])
+}
+
+// Migrate applies the pending migrations in order of version. Every migration
+// is applied and recorded in its own transaction so a failure leaves the
+// schema at the last version applied successfully. It refuses to run when an
+// applied migration was edited. Processes migrating the same database at once
+// take turns, the later ones finding nothing left to apply.
+func Migrate(ctx context.Context, db *sqlx.DB) error {
+	return database.WithLock(ctx, db, database.LockMigrations, func() error {
+		return migrate(ctx, db)
+	})
+}
+
+// migrate applies the pending migrations.
+func migrate(ctx context.Context, db *sqlx.DB) error {
+	if err := createTable(ctx, db); err != nil {
+		return err
+	}
+
+	applied, err := appliedMigrations(ctx, db)
+	if err != nil {
+		return err
+	}
+	if err := verify(applied); err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		if err := apply(ctx, db, m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Validate checks the schema of the database is the one the binary expects.
+// It returns ErrPending when some migrations have not been applied and
+// ErrChecksumMismatch when an applied one was edited. Versions newer than the
+// binary knows are accepted so the previous release keeps running while a
+// new one rolls out.
+func Validate(ctx context.Context, db *sqlx.DB) error {
+	applied, err := appliedMigrations(ctx, db)
+	if err != nil {
+		return err
+	}
+	if err := verify(applied); err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; !ok {
+			return ErrPending
+		}
+	}
+	return nil
+}
+
+// verify returns ErrChecksumMismatch when the checksum of an applied
+// migration is not the one of its script.
+func verify(applied map[int]appliedMigration) error {
+	for _, m := range migrations {
+		a, ok := applied[m.Version]
+		if ok && a.Checksum != m.Checksum() {
+			return errors.Wrapf(ErrChecksumMismatch, "migration %d", m.Version)
+		}
+	}
+	return nil
+}
+
+// MigrationStatus reports if a migration has been applied to the database.
+type MigrationStatus struct {
+	Version     int
+	Description string
+	AppliedAt   *time.Time
+}
+
+// Status gets the status of every migration of the schema.
+func Status(ctx context.Context, db *sqlx.DB) ([]MigrationStatus, error) {
+	applied, err := appliedMigrations(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, len(migrations))
+	for i, m := range migrations {
+		statuses[i] = MigrationStatus{Version: m.Version, Description: m.Description}
+		if a, ok := applied[m.Version]; ok {
+			t := a.AppliedAt
+			statuses[i].AppliedAt = &t
+		}
+	}
+
+	return statuses, nil
+}
+
+// Down reverts the n most recently applied migrations, newest first. Every
+// migration is reverted in its own transaction so a failure leaves the schema
+// at the last version reverted successfully.
+func Down(ctx context.Context, db *sqlx.DB, n int) error {
+	return database.WithLock(ctx, db, database.LockMigrations, func() error {
+		return down(ctx, db, n)
+	})
+}
+
+// down reverts the n most recently applied migrations.
+func down(ctx context.Context, db *sqlx.DB, n int) error {
+	applied, err := appliedMigrations(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	for i := len(migrations) - 1; i >= 0 && n > 0; i-- {
+		m := migrations[i]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+
+		if err := revert(ctx, db, m); err != nil {
+			return err
+		}
+		n--
+	}
+
+	return nil
+}
+
+// Force records the schema as migrated to exactly the version without running
+// any script. It is meant to repair the bookkeeping after a migration failed
+// halfway and the database was fixed by hand.
+func Force(ctx context.Context, db *sqlx.DB, version int) error {
+	known := version == 0
+	for _, m := range migrations {
+		if m.Version == version {
+			known = true
+		}
+	}
+	if !known {
+		return ErrUnknownVersion
+	}
+
+	return database.WithLock(ctx, db, database.LockMigrations, func() error {
+		return force(ctx, db, version)
+	})
+}
+
+// force records the schema as migrated to exactly the version.
+func force(ctx context.Context, db *sqlx.DB, version int) error {
+	if err := createTable(ctx, db); err != nil {
+		return err
+	}
+
+	applied, err := appliedMigrations(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "starting transaction")
+	}
+	defer tx.Rollback()
+
+	const qd = `DELETE FROM schema_migrations WHERE version > $1`
+	if _, err := tx.ExecContext(ctx, qd, version); err != nil {
+		return errors.Wrap(err, "deleting newer migrations")
+	}
+
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; ok || m.Version > version {
+			continue
+		}
+		if err := record(ctx, tx, m, 0); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// apply runs the up script of the migration and records it was applied.
+func apply(ctx context.Context, db *sqlx.DB, m Migration) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "starting transaction")
+	}
+	defer tx.Rollback()
+
+	// Migrations may rightly run longer than the statements of requests.
+	if _, err := tx.ExecContext(ctx, `SET LOCAL statement_timeout = 0`); err != nil {
+		return errors.Wrap(err, "lifting statement timeout")
+	}
+
+	start := time.Now()
+	if _, err := tx.ExecContext(ctx, m.Up); err != nil {
+		return errors.Wrapf(err, "applying migration %d", m.Version)
+	}
+	if err := record(ctx, tx, m, time.Since(start)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// record records the migration was applied, taking d.
+func record(ctx context.Context, tx *sqlx.Tx, m Migration, d time.Duration) error {
+	const q = `INSERT INTO schema_migrations
+		(version, description, checksum, applied_at, execution_ms)
+		VALUES ($1, $2, $3, $4, $5)`
+	if _, err := tx.ExecContext(ctx, q, m.Version, m.Description, m.Checksum(), time.Now().UTC(), d.Milliseconds()); err != nil {
+		return errors.Wrapf(err, "recording migration %d", m.Version)
+	}
+	return nil
+}
+
+// revert runs the down script of the migration and forgets it was applied.
+func revert(ctx context.Context, db *sqlx.DB, m Migration) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "starting transaction")
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SET LOCAL statement_timeout = 0`); err != nil {
+		return errors.Wrap(err, "lifting statement timeout")
+	}
+
+	if _, err := tx.ExecContext(ctx, m.Down); err != nil {
+		return errors.Wrapf(err, "reverting migration %d", m.Version)
+	}
+
+	const q = `DELETE FROM schema_migrations WHERE version = $1`
+	if _, err := tx.ExecContext(ctx, q, m.Version); err != nil {
+		return errors.Wrapf(err, "deleting migration %d", m.Version)
+	}
+
+	return tx.Commit()
+}
+
+// createTable creates the table of the applied migrations. The databases
+// migrated before it existed have their migrations in darwin_migrations,
+// with MD5 checksums; they are copied once, with the SHA-256 checksum of the
+// script when the MD5 one matches it and the stale MD5 one otherwise so the
+// edit is reported.
+func createTable(ctx context.Context, db *sqlx.DB) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "starting transaction")
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	const qe = `SELECT to_regclass('schema_migrations') IS NOT NULL`
+	if err := tx.GetContext(ctx, &exists, qe); err != nil {
+		return errors.Wrap(err, "checking migrations table")
+	}
+	if exists {
+		return nil
+	}
+
+	const qc = `CREATE TABLE schema_migrations (
+		version      INTEGER PRIMARY KEY,
+		description  TEXT NOT NULL,
+		checksum     TEXT NOT NULL,
+		applied_at   TIMESTAMPTZ NOT NULL,
+		execution_ms BIGINT NOT NULL
+	)`
+	if _, err := tx.ExecContext(ctx, qc); err != nil {
+		return errors.Wrap(err, "creating migrations table")
+	}
+
+	var legacy bool
+	const ql = `SELECT to_regclass('darwin_migrations') IS NOT NULL`
+	if err := tx.GetContext(ctx, &legacy, ql); err != nil {
+		return errors.Wrap(err, "checking legacy migrations table")
+	}
+	if legacy {
+		if err := copyLegacy(ctx, tx); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// copyLegacy copies the migrations recorded in darwin_migrations.
+func copyLegacy(ctx context.Context, tx *sqlx.Tx) error {
+	var rows []struct {
+		Version       float64 `db:"version"`
+		Description   string  `db:"description"`
+		Checksum      string  `db:"checksum"`
+		AppliedAt     int64   `db:"applied_at"`
+		ExecutionTime float64 `db:"execution_time"`
+	}
+	const qs = `SELECT version, description, checksum, applied_at, execution_time FROM darwin_migrations`
+	if err := tx.SelectContext(ctx, &rows, qs); err != nil {
+		return errors.Wrap(err, "selecting legacy migrations")
+	}
+
+	scripts := make(map[int]string, len(migrations))
+	for _, m := range migrations {
+		scripts[m.Version] = m.Up
+	}
+
+	const qi = `INSERT INTO schema_migrations
+		(version, description, checksum, applied_at, execution_ms)
+		VALUES ($1, $2, $3, $4, $5)`
+	for _, r := range rows {
+		version := int(r.Version)
+		sum := r.Checksum
+		if script, ok := scripts[version]; ok && fmt.Sprintf("%x", md5.Sum([]byte(script))) == r.Checksum {
+			sum = checksum(script)
+		}
+
+		// darwin recorded the execution time in nanoseconds.
+		ms := time.Duration(r.ExecutionTime).Milliseconds()
+		if _, err := tx.ExecContext(ctx, qi, version, r.Description, sum, time.Unix(r.AppliedAt, 0).UTC(), ms); err != nil {
+			return errors.Wrapf(err, "copying legacy migration %d", version)
+		}
+	}
+
+	return nil
+}
+
+// appliedMigration is a migration recorded as applied.
+type appliedMigration struct {
+	Checksum  string
+	AppliedAt time.Time
+}
+
+// appliedMigrations gets the applied migrations by version. A database which
+// was never migrated has none.
+func appliedMigrations(ctx context.Context, db *sqlx.DB) (map[int]appliedMigration, error) {
+	var exists bool
+	const qe = `SELECT to_regclass('schema_migrations') IS NOT NULL`
+	if err := db.GetContext(ctx, &exists, qe); err != nil {
+		return nil, errors.Wrap(err, "checking migrations table")
+	}
+
+	applied := make(map[int]appliedMigration)
+	if !exists {
+		return applied, nil
+	}
+
+	var rows []struct {
+		Version   int       `db:"version"`
+		Checksum  string    `db:"checksum"`
+		AppliedAt time.Time `db:"applied_at"`
+	}
+	const q = `SELECT version, checksum, applied_at FROM schema_migrations`
+	if err := db.SelectContext(ctx, &rows, q); err != nil {
+		return nil, errors.Wrap(err, "selecting applied migrations")
+	}
+	for _, r := range rows {
+		applied[r.Version] = appliedMigration{Checksum: r.Checksum, AppliedAt: r.AppliedAt.UTC()}
+	}
+
+	return applied, nil
+}
+
+// mustLoad loads the migrations of the file system. It panics when the files
+// are malformed as the schema is compiled into the binary.
+func mustLoad(fsys fs.FS) []Migration {
+	ms, err := load(fsys)
+	if err != nil {
+		panic(err)
+	}
+	return ms
+}
+
+// load loads the migrations of the file system ordered by version.
+func load(fsys fs.FS) ([]Migration, error) {
+	names, err := fs.Glob(fsys, "migrations/*.sql")
+	if err != nil {
+		return nil, errors.Wrap(err, "listing migrations")
+	}
+
+	var ms []Migration
+	downs := make(map[int]string)
+	for _, name := range names {
+		base := path.Base(name)
+		version, description, direction, err := parseName(base)
+		if err != nil {
+			return nil, err
+		}
+
+		b, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading %s", base)
+		}
+
+		switch direction {
+		case "up":
+			ms = append(ms, Migration{Version: version, Description: description, Up: string(b)})
+		case "down":
+			downs[version] = string(b)
+		}
+	}
+
+	sort.Slice(ms, func(i, j int) bool { return ms[i].Version < ms[j].Version })
+	for i := range ms {
+		m := &ms[i]
+		if i > 0 && ms[i-1].Version == m.Version {
+			return nil, errors.Errorf("duplicate migration %d", m.Version)
+		}
+		down, ok := downs[m.Version]
+		if !ok {
+			return nil, errors.Errorf("migration %d has no down script", m.Version)
+		}
+		m.Down = down
+	}
+
+	return ms, nil
+}
+
+// parseName splits a name like 0005_add_restaurant_enrichment.up.sql into the
+// version, a description like "Add restaurant enrichment" and the direction.
+func parseName(name string) (int, string, string, error) {
+	parts := strings.SplitN(strings.TrimSuffix(name, ".sql"), "_", 2)
+	if len(parts) != 2 {
+		return 0, "", "", errors.Errorf("malformed migration name %s", name)
+	}
+
+	version, err := strconv.Atoi(parts[0])
+	if err != nil || version <= 0 {
+		return 0, "", "", errors.Errorf("malformed migration version %s", name)
+	}
+
+	ext := path.Ext(parts[1])
+	direction := strings.TrimPrefix(ext, ".")
+	if direction != "up" && direction != "down" {
+		return 0, "", "", errors.Errorf("migration %s is neither up nor down", name)
+	}
+
+	description := strings.ReplaceAll(strings.TrimSuffix(parts[1], ext), "_", " ")
+	if description != "" {
+		description = strings.ToUpper(description[:1]) + description[1:]
+	}
+
+	return version, description, direction, nil
 }
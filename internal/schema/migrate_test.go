@@ -0,0 +1,75 @@
+package schema
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/pkg/errors"
+)
+
+// TestMigrations validates every migration has a version and a down script.
+func TestMigrations(t *testing.T) {
+	t.Log("Given the need to load the migrations of the schema.")
+	{
+		t.Log("\tTest 0:\tWhen loading the embedded migrations.")
+		{
+			for i, m := range migrations {
+				if m.Version != i+1 {
+					t.Fatalf("\t✗\tShould number the versions from 1 without gaps : got %v at %d", m.Version, i)
+				}
+				if m.Down == "" {
+					t.Fatalf("\t✗\tShould be able to revert every version : %v has no down script", m.Version)
+				}
+			}
+			t.Log("\t✓\tShould number the versions from 1 without gaps.")
+			t.Log("\t✓\tShould be able to revert every version.")
+
+			if got := migrations[4].Description; got != "Add restaurant enrichment" {
+				t.Fatalf("\t✗\tShould describe the migration by its name : got %q", got)
+			}
+			t.Log("\t✓\tShould describe the migration by its name.")
+		}
+
+		t.Log("\tTest 1:\tWhen a migration has no down script.")
+		{
+			fsys := fstest.MapFS{
+				"migrations/0001_add_a.up.sql":   {Data: []byte("CREATE TABLE a ();")},
+				"migrations/0001_add_a.down.sql": {Data: []byte("DROP TABLE a;")},
+				"migrations/0002_add_b.up.sql":   {Data: []byte("CREATE TABLE b ();")},
+			}
+			if _, err := load(fsys); err == nil {
+				t.Fatal("\t✗\tShould fail to load the migrations.")
+			}
+			t.Log("\t✓\tShould fail to load the migrations.")
+		}
+
+		t.Log("\tTest 2:\tWhen a migration is misnamed.")
+		{
+			fsys := fstest.MapFS{
+				"migrations/add_a.up.sql": {Data: []byte("CREATE TABLE a ();")},
+			}
+			if _, err := load(fsys); err == nil {
+				t.Fatal("\t✗\tShould fail to load the migrations.")
+			}
+			t.Log("\t✓\tShould fail to load the migrations.")
+		}
+
+		t.Log("\tTest 3:\tWhen an applied migration was edited.")
+		{
+			applied := make(map[int]appliedMigration)
+			for _, m := range migrations {
+				applied[m.Version] = appliedMigration{Checksum: m.Checksum()}
+			}
+			if err := verify(applied); err != nil {
+				t.Fatalf("\t✗\tShould accept the migrations as applied : %v", err)
+			}
+			t.Log("\t✓\tShould accept the migrations as applied.")
+
+			applied[3] = appliedMigration{Checksum: checksum(migrations[2].Up + "\n-- edited")}
+			if err := verify(applied); errors.Cause(err) != ErrChecksumMismatch {
+				t.Fatalf("\t✗\tShould report the edited migration : got %v", err)
+			}
+			t.Log("\t✓\tShould report the edited migration.")
+		}
+	}
+}
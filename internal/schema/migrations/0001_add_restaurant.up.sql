@@ -0,0 +1,10 @@
+
+CREATE TABLE restaurant (
+	restaurant_id UUID,
+	name          TEXT NOT NULL,
+	address       TEXT,
+    owner_user_id TEXT NOT NULL,
+	date_created  TIMESTAMP,
+	date_updated  TIMESTAMP,
+	PRIMARY KEY (restaurant_id)
+);
\ No newline at end of file
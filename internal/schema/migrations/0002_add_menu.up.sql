@@ -0,0 +1,9 @@
+
+CREATE TABLE menu (
+		menu_id       UUID,
+		restaurant_id UUID,
+		date          DATE NOT NULL DEFAULT CURRENT_DATE,
+		menu          VARCHAR(1024),
+		votes         INTEGER,
+        PRIMARY KEY(restaurant_id, date)
+)
\ No newline at end of file
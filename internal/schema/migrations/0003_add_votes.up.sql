@@ -0,0 +1,10 @@
+
+CREATE TABLE vote (
+    date          TIMESTAMP NOT NULL,
+    user_id       UUID,
+	restaurant_id UUID,
+	time_voted    TIMESTAMP,
+
+	PRIMARY KEY (date, user_id),
+	FOREIGN KEY (restaurant_id) REFERENCES restaurant(restaurant_id)
+);
\ No newline at end of file
@@ -0,0 +1,11 @@
+
+CREATE TABLE users (
+	user_id       UUID,
+	name          TEXT,
+	email         TEXT UNIQUE,
+	roles         TEXT[],
+	password_hash TEXT,
+	date_created TIMESTAMP,
+	date_updated TIMESTAMP,
+	PRIMARY KEY (user_id)
+);
\ No newline at end of file
@@ -0,0 +1,6 @@
+DROP TABLE restaurant_suggestion;
+
+ALTER TABLE restaurant
+	DROP COLUMN website,
+	DROP COLUMN phone,
+	DROP COLUMN photos;
@@ -0,0 +1,20 @@
+
+ALTER TABLE restaurant
+	ADD COLUMN website TEXT NOT NULL DEFAULT '',
+	ADD COLUMN phone   TEXT NOT NULL DEFAULT '',
+	ADD COLUMN photos  TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE TABLE restaurant_suggestion (
+	suggestion_id UUID,
+	restaurant_id UUID NOT NULL,
+	field         TEXT NOT NULL,
+	value         TEXT NOT NULL,
+	provider      TEXT NOT NULL,
+	source_id     TEXT NOT NULL,
+	status        TEXT NOT NULL,
+	date_fetched  TIMESTAMP NOT NULL,
+	date_decided  TIMESTAMP,
+	PRIMARY KEY (suggestion_id),
+	UNIQUE (restaurant_id, field, value),
+	FOREIGN KEY (restaurant_id) REFERENCES restaurant(restaurant_id) ON DELETE CASCADE
+);
\ No newline at end of file
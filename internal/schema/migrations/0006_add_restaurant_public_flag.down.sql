@@ -0,0 +1,2 @@
+ALTER TABLE restaurant
+	DROP COLUMN public;
@@ -0,0 +1,3 @@
+
+ALTER TABLE restaurant
+	ADD COLUMN public BOOLEAN NOT NULL DEFAULT FALSE;
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE archive_manifest;
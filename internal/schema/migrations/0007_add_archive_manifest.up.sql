@@ -0,0 +1,9 @@
+
+CREATE TABLE archive_manifest (
+	dataset       TEXT NOT NULL,
+	month         DATE NOT NULL,
+	object_key    TEXT NOT NULL,
+	row_count     INTEGER NOT NULL,
+	date_exported TIMESTAMP NOT NULL,
+	PRIMARY KEY (dataset, month)
+);
\ No newline at end of file
@@ -0,0 +1,2 @@
+DROP TABLE changelog_seen;
+DROP TABLE changelog;
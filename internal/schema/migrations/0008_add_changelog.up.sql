@@ -0,0 +1,17 @@
+
+CREATE TABLE changelog (
+	entry_id     UUID,
+	version      TEXT NOT NULL UNIQUE,
+	title        TEXT NOT NULL,
+	notes        TEXT NOT NULL,
+	date_created TIMESTAMP,
+	date_updated TIMESTAMP,
+	PRIMARY KEY (entry_id)
+);
+
+CREATE TABLE changelog_seen (
+	user_id   UUID,
+	version   TEXT NOT NULL,
+	date_seen TIMESTAMP,
+	PRIMARY KEY (user_id)
+);
\ No newline at end of file
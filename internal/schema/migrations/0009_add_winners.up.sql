@@ -0,0 +1,9 @@
+
+CREATE TABLE winner (
+	date          TIMESTAMP NOT NULL,
+	restaurant_id UUID NOT NULL,
+	votes         INTEGER NOT NULL,
+	date_computed TIMESTAMP NOT NULL,
+	PRIMARY KEY (date),
+	FOREIGN KEY (restaurant_id) REFERENCES restaurant(restaurant_id)
+);
\ No newline at end of file
@@ -0,0 +1,3 @@
+DROP TABLE broadcast_confirmation;
+DROP TABLE broadcast_delivery;
+DROP TABLE broadcast;
@@ -0,0 +1,27 @@
+
+CREATE TABLE broadcast (
+	broadcast_id   UUID,
+	message        TEXT NOT NULL,
+	sender_user_id UUID NOT NULL,
+	date_created   TIMESTAMP NOT NULL,
+	PRIMARY KEY (broadcast_id)
+);
+
+CREATE TABLE broadcast_delivery (
+	broadcast_id UUID NOT NULL,
+	user_id      UUID NOT NULL,
+	channel      TEXT NOT NULL,
+	status       TEXT NOT NULL,
+	error        TEXT NOT NULL DEFAULT '',
+	date_sent    TIMESTAMP,
+	PRIMARY KEY (broadcast_id, user_id, channel),
+	FOREIGN KEY (broadcast_id) REFERENCES broadcast(broadcast_id) ON DELETE CASCADE
+);
+
+CREATE TABLE broadcast_confirmation (
+	broadcast_id   UUID NOT NULL,
+	user_id        UUID NOT NULL,
+	date_confirmed TIMESTAMP NOT NULL,
+	PRIMARY KEY (broadcast_id, user_id),
+	FOREIGN KEY (broadcast_id) REFERENCES broadcast(broadcast_id) ON DELETE CASCADE
+);
\ No newline at end of file
@@ -0,0 +1,11 @@
+
+CREATE TABLE idempotency_key (
+	idempotency_key TEXT NOT NULL,
+	user_id         TEXT NOT NULL,
+	request_hash    TEXT NOT NULL,
+	status          INT NOT NULL,
+	content_type    TEXT NOT NULL,
+	body            BYTEA NOT NULL,
+	date_created    TIMESTAMP NOT NULL,
+	PRIMARY KEY (idempotency_key, user_id)
+);
\ No newline at end of file
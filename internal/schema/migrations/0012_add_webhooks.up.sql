@@ -0,0 +1,27 @@
+
+CREATE TABLE webhook (
+	webhook_id   UUID,
+	url          TEXT NOT NULL,
+	events       TEXT[] NOT NULL,
+	secret       TEXT NOT NULL,
+	date_created TIMESTAMP NOT NULL,
+	PRIMARY KEY (webhook_id)
+);
+
+CREATE TABLE webhook_delivery (
+	delivery_id    UUID,
+	webhook_id     UUID NOT NULL,
+	event          TEXT NOT NULL,
+	payload        JSONB NOT NULL,
+	status         TEXT NOT NULL,
+	attempts       INT NOT NULL DEFAULT 0,
+	response_code  INT NOT NULL DEFAULT 0,
+	error          TEXT NOT NULL DEFAULT '',
+	next_attempt   TIMESTAMP NOT NULL,
+	date_created   TIMESTAMP NOT NULL,
+	date_delivered TIMESTAMP,
+	PRIMARY KEY (delivery_id),
+	FOREIGN KEY (webhook_id) REFERENCES webhook(webhook_id) ON DELETE CASCADE
+);
+
+CREATE INDEX webhook_delivery_due_idx ON webhook_delivery (next_attempt) WHERE status = 'PENDING';
\ No newline at end of file
@@ -0,0 +1,13 @@
+
+CREATE TABLE outbox (
+	event_id       UUID,
+	seq            BIGSERIAL NOT NULL,
+	type           TEXT NOT NULL,
+	aggregate_id   TEXT NOT NULL,
+	payload        JSONB NOT NULL,
+	date_created   TIMESTAMP NOT NULL,
+	date_published TIMESTAMP,
+	PRIMARY KEY (event_id)
+);
+
+CREATE INDEX outbox_pending_idx ON outbox (seq) WHERE date_published IS NULL;
\ No newline at end of file
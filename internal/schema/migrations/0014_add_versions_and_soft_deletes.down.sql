@@ -0,0 +1,11 @@
+ALTER TABLE restaurant
+	DROP COLUMN version,
+	DROP COLUMN deleted_at;
+
+ALTER TABLE menu
+	DROP COLUMN version,
+	DROP COLUMN deleted_at;
+
+ALTER TABLE users
+	DROP COLUMN version,
+	DROP COLUMN deleted_at;
@@ -0,0 +1,12 @@
+
+ALTER TABLE restaurant
+	ADD COLUMN version    INT NOT NULL DEFAULT 1,
+	ADD COLUMN deleted_at TIMESTAMP;
+
+ALTER TABLE menu
+	ADD COLUMN version    INT NOT NULL DEFAULT 1,
+	ADD COLUMN deleted_at TIMESTAMP;
+
+ALTER TABLE users
+	ADD COLUMN version    INT NOT NULL DEFAULT 1,
+	ADD COLUMN deleted_at TIMESTAMP;
@@ -0,0 +1 @@
+DROP TABLE menu_vote_tally;
@@ -0,0 +1,11 @@
+
+CREATE TABLE menu_vote_tally (
+	date          TIMESTAMP NOT NULL,
+	restaurant_id UUID NOT NULL,
+	votes         INTEGER NOT NULL,
+	PRIMARY KEY (date, restaurant_id)
+);
+
+INSERT INTO menu_vote_tally (date, restaurant_id, votes)
+	SELECT date, restaurant_id, COUNT(*) FROM vote
+	GROUP BY date, restaurant_id;
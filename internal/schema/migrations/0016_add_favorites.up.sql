@@ -0,0 +1,7 @@
+
+CREATE TABLE favorite (
+	user_id       UUID NOT NULL,
+	restaurant_id UUID NOT NULL,
+	date_created  TIMESTAMP NOT NULL,
+	PRIMARY KEY (user_id, restaurant_id)
+);
@@ -0,0 +1,26 @@
+
+CREATE TABLE orders (
+	order_id      UUID NOT NULL,
+	menu_id       UUID NOT NULL,
+	restaurant_id UUID NOT NULL,
+	user_id       UUID NOT NULL,
+	date          TIMESTAMP NOT NULL,
+	status        TEXT NOT NULL,
+	total         INTEGER NOT NULL,
+	date_created  TIMESTAMP NOT NULL,
+	date_updated  TIMESTAMP NOT NULL,
+	PRIMARY KEY (order_id),
+	FOREIGN KEY (restaurant_id) REFERENCES restaurant(restaurant_id)
+);
+
+CREATE INDEX orders_restaurant_date_idx ON orders (restaurant_id, date);
+
+CREATE TABLE order_item (
+	order_id   UUID NOT NULL,
+	position   INTEGER NOT NULL,
+	name       TEXT NOT NULL,
+	quantity   INTEGER NOT NULL,
+	unit_price INTEGER NOT NULL,
+	PRIMARY KEY (order_id, position),
+	FOREIGN KEY (order_id) REFERENCES orders(order_id) ON DELETE CASCADE
+);
@@ -0,0 +1,13 @@
+
+CREATE TABLE notification (
+	notification_id UUID NOT NULL,
+	user_id         UUID NOT NULL,
+	type            TEXT NOT NULL,
+	message         TEXT NOT NULL,
+	data            JSONB NOT NULL,
+	date_created    TIMESTAMP NOT NULL,
+	date_read       TIMESTAMP,
+	PRIMARY KEY (notification_id)
+);
+
+CREATE INDEX notification_user_idx ON notification (user_id, date_created);
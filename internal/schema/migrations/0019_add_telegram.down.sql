@@ -0,0 +1,2 @@
+DROP TABLE telegram_code;
+DROP TABLE telegram_chat;
@@ -0,0 +1,16 @@
+
+CREATE TABLE telegram_chat (
+	chat_id     BIGINT NOT NULL,
+	user_id     UUID NOT NULL,
+	date_linked TIMESTAMP NOT NULL,
+	PRIMARY KEY (chat_id)
+);
+
+CREATE UNIQUE INDEX telegram_chat_user_idx ON telegram_chat (user_id);
+
+CREATE TABLE telegram_code (
+	code       TEXT NOT NULL,
+	user_id    UUID NOT NULL,
+	expires_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (code)
+);
@@ -0,0 +1,14 @@
+DROP INDEX vote_org_idx;
+DROP INDEX restaurant_org_idx;
+DROP INDEX users_org_idx;
+
+DELETE FROM winner WHERE org_id <> '00000000-0000-0000-0000-000000000001';
+ALTER TABLE winner DROP CONSTRAINT winner_pkey;
+ALTER TABLE winner ADD PRIMARY KEY (date);
+
+ALTER TABLE winner DROP COLUMN org_id;
+ALTER TABLE vote DROP COLUMN org_id;
+ALTER TABLE restaurant DROP COLUMN org_id;
+ALTER TABLE users DROP COLUMN org_id;
+
+DROP TABLE organization;
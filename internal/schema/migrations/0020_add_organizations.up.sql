@@ -0,0 +1,22 @@
+
+CREATE TABLE organization (
+	org_id       UUID NOT NULL,
+	name         TEXT NOT NULL,
+	date_created TIMESTAMP NOT NULL,
+	PRIMARY KEY (org_id)
+);
+
+INSERT INTO organization (org_id, name, date_created)
+	VALUES ('00000000-0000-0000-0000-000000000001', 'Default', NOW());
+
+ALTER TABLE users ADD COLUMN org_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES organization (org_id);
+ALTER TABLE restaurant ADD COLUMN org_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES organization (org_id);
+ALTER TABLE vote ADD COLUMN org_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001';
+ALTER TABLE winner ADD COLUMN org_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001';
+
+ALTER TABLE winner DROP CONSTRAINT winner_pkey;
+ALTER TABLE winner ADD PRIMARY KEY (date, org_id);
+
+CREATE INDEX users_org_idx ON users (org_id);
+CREATE INDEX restaurant_org_idx ON restaurant (org_id);
+CREATE INDEX vote_org_idx ON vote (org_id, date);
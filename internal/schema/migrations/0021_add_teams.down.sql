@@ -0,0 +1,3 @@
+DROP TABLE team_winner;
+DROP TABLE team_member;
+DROP TABLE team;
@@ -0,0 +1,30 @@
+
+CREATE TABLE team (
+	team_id      UUID NOT NULL,
+	org_id       UUID NOT NULL REFERENCES organization (org_id),
+	name         TEXT NOT NULL,
+	date_created TIMESTAMP NOT NULL,
+	date_updated TIMESTAMP NOT NULL,
+	deleted_at   TIMESTAMP,
+	PRIMARY KEY (team_id)
+);
+
+CREATE INDEX team_org_idx ON team (org_id);
+
+CREATE TABLE team_member (
+	team_id    UUID NOT NULL REFERENCES team (team_id),
+	user_id    UUID NOT NULL,
+	date_added TIMESTAMP NOT NULL,
+	PRIMARY KEY (team_id, user_id)
+);
+
+CREATE INDEX team_member_user_idx ON team_member (user_id);
+
+CREATE TABLE team_winner (
+	date          TIMESTAMP NOT NULL,
+	team_id       UUID NOT NULL REFERENCES team (team_id),
+	restaurant_id UUID NOT NULL,
+	votes         INTEGER NOT NULL,
+	date_computed TIMESTAMP NOT NULL,
+	PRIMARY KEY (date, team_id)
+);
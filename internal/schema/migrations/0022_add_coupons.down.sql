@@ -0,0 +1,3 @@
+ALTER TABLE orders DROP COLUMN discount;
+DROP TABLE coupon_redemption;
+DROP TABLE coupon;
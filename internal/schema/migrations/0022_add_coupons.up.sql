@@ -0,0 +1,30 @@
+
+CREATE TABLE coupon (
+	coupon_id       UUID NOT NULL,
+	restaurant_id   UUID NOT NULL REFERENCES restaurant (restaurant_id),
+	code            TEXT NOT NULL,
+	kind            TEXT NOT NULL,
+	value           INTEGER NOT NULL,
+	valid_from      TIMESTAMP NOT NULL,
+	valid_until     TIMESTAMP NOT NULL,
+	max_redemptions INTEGER,
+	max_per_user    INTEGER,
+	date_created    TIMESTAMP NOT NULL,
+	deleted_at      TIMESTAMP,
+	PRIMARY KEY (coupon_id)
+);
+
+CREATE UNIQUE INDEX coupon_code_idx ON coupon (restaurant_id, code) WHERE deleted_at IS NULL;
+
+CREATE TABLE coupon_redemption (
+	order_id      UUID NOT NULL,
+	coupon_id     UUID NOT NULL REFERENCES coupon (coupon_id),
+	user_id       UUID NOT NULL,
+	discount      INTEGER NOT NULL,
+	date_redeemed TIMESTAMP NOT NULL,
+	PRIMARY KEY (order_id)
+);
+
+CREATE INDEX coupon_redemption_coupon_idx ON coupon_redemption (coupon_id);
+
+ALTER TABLE orders ADD COLUMN discount INTEGER NOT NULL DEFAULT 0;
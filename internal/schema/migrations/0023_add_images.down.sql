@@ -0,0 +1,3 @@
+ALTER TABLE menu DROP COLUMN thumbnail_url;
+ALTER TABLE menu DROP COLUMN image_url;
+ALTER TABLE restaurant DROP COLUMN thumbnails;
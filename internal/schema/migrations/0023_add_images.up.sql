@@ -0,0 +1,5 @@
+
+ALTER TABLE restaurant ADD COLUMN thumbnails TEXT[] NOT NULL DEFAULT '{}';
+
+ALTER TABLE menu ADD COLUMN image_url TEXT NOT NULL DEFAULT '';
+ALTER TABLE menu ADD COLUMN thumbnail_url TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+DROP INDEX restaurant_location_idx;
+ALTER TABLE restaurant DROP COLUMN longitude;
+ALTER TABLE restaurant DROP COLUMN latitude;
@@ -0,0 +1,3 @@
+DROP INDEX restaurant_tags_idx;
+ALTER TABLE restaurant DROP COLUMN tags;
+DROP TABLE tag;
@@ -0,0 +1,13 @@
+
+CREATE TABLE tag (
+	org_id       UUID NOT NULL REFERENCES organization (org_id),
+	slug         TEXT NOT NULL,
+	name         TEXT NOT NULL,
+	date_created TIMESTAMP NOT NULL,
+	date_updated TIMESTAMP NOT NULL,
+	PRIMARY KEY (org_id, slug)
+);
+
+ALTER TABLE restaurant ADD COLUMN tags TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX restaurant_tags_idx ON restaurant USING gin (tags);
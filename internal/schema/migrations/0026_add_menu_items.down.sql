@@ -0,0 +1 @@
+ALTER TABLE menu DROP COLUMN items;
@@ -0,0 +1,2 @@
+
+ALTER TABLE menu ADD COLUMN items JSONB NOT NULL DEFAULT '[]';
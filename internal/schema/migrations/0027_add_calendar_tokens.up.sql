@@ -0,0 +1,9 @@
+
+CREATE TABLE calendar_token (
+	token_hash   TEXT NOT NULL,
+	user_id      UUID NOT NULL,
+	date_created TIMESTAMP NOT NULL,
+	PRIMARY KEY (token_hash)
+);
+
+CREATE UNIQUE INDEX calendar_token_user_idx ON calendar_token (user_id);
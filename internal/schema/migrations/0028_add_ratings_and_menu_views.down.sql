@@ -0,0 +1,2 @@
+DROP TABLE menu_view;
+DROP TABLE restaurant_rating;
@@ -0,0 +1,15 @@
+
+CREATE TABLE restaurant_rating (
+	restaurant_id UUID NOT NULL,
+	user_id       UUID NOT NULL,
+	score         SMALLINT NOT NULL CHECK (score BETWEEN 1 AND 5),
+	date_updated  TIMESTAMP NOT NULL,
+	PRIMARY KEY (restaurant_id, user_id),
+	FOREIGN KEY (restaurant_id) REFERENCES restaurant(restaurant_id)
+);
+
+CREATE TABLE menu_view (
+	menu_id UUID NOT NULL,
+	views   INTEGER NOT NULL,
+	PRIMARY KEY (menu_id)
+);
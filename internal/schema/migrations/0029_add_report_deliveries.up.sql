@@ -0,0 +1,7 @@
+
+CREATE TABLE report_delivery (
+	org_id    UUID NOT NULL REFERENCES organization (org_id),
+	month     DATE NOT NULL,
+	date_sent TIMESTAMP NOT NULL,
+	PRIMARY KEY (org_id, month)
+);
@@ -0,0 +1,14 @@
+
+CREATE TABLE entity_audit (
+	audit_id      UUID NOT NULL,
+	entity_type   TEXT NOT NULL,
+	entity_id     UUID NOT NULL,
+	restaurant_id UUID NOT NULL,
+	action        TEXT NOT NULL,
+	user_id       TEXT NOT NULL,
+	changes       JSONB NOT NULL,
+	date_created  TIMESTAMP NOT NULL,
+	PRIMARY KEY (audit_id)
+);
+
+CREATE INDEX entity_audit_restaurant_idx ON entity_audit (restaurant_id, date_created);
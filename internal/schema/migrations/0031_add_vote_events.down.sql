@@ -0,0 +1 @@
+DROP TABLE vote_event;
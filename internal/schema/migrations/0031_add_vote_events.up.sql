@@ -0,0 +1,18 @@
+
+CREATE TABLE vote_event (
+	seq           BIGSERIAL,
+	type          TEXT NOT NULL,
+	date          TIMESTAMP NOT NULL,
+	user_id       UUID NOT NULL,
+	restaurant_id UUID NOT NULL,
+	org_id        UUID NOT NULL,
+	time_occurred TIMESTAMP NOT NULL,
+	PRIMARY KEY (seq)
+);
+
+CREATE INDEX vote_event_date_idx ON vote_event (date, seq);
+
+INSERT INTO vote_event (type, date, user_id, restaurant_id, org_id, time_occurred)
+	SELECT 'cast', date, user_id, restaurant_id, org_id, COALESCE(time_voted, date) FROM vote
+	WHERE user_id IS NOT NULL AND restaurant_id IS NOT NULL
+	ORDER BY date, time_voted;
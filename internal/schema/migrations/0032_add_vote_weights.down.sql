@@ -0,0 +1,6 @@
+DROP TABLE vote_delegation;
+DROP TABLE vote_weight;
+ALTER TABLE vote_event DROP COLUMN cast_by;
+ALTER TABLE vote_event DROP COLUMN weight;
+ALTER TABLE vote DROP COLUMN cast_by;
+ALTER TABLE vote DROP COLUMN weight;
@@ -0,0 +1,25 @@
+
+ALTER TABLE vote ADD COLUMN weight INT NOT NULL DEFAULT 1;
+ALTER TABLE vote ADD COLUMN cast_by TEXT NOT NULL DEFAULT '';
+ALTER TABLE vote_event ADD COLUMN weight INT NOT NULL DEFAULT 1;
+ALTER TABLE vote_event ADD COLUMN cast_by TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE vote_weight (
+	weight_id    UUID NOT NULL,
+	org_id       UUID NOT NULL REFERENCES organization (org_id),
+	role         TEXT NOT NULL DEFAULT '',
+	team_id      UUID REFERENCES team (team_id),
+	max_count    INT NOT NULL CHECK (max_count >= 1),
+	date_created TIMESTAMP NOT NULL,
+	PRIMARY KEY (weight_id)
+);
+
+CREATE INDEX vote_weight_org_idx ON vote_weight (org_id);
+
+CREATE TABLE vote_delegation (
+	user_id      UUID NOT NULL REFERENCES users (user_id),
+	delegate_id  UUID NOT NULL REFERENCES users (user_id),
+	org_id       UUID NOT NULL REFERENCES organization (org_id),
+	date_created TIMESTAMP NOT NULL,
+	PRIMARY KEY (user_id)
+);
@@ -0,0 +1,5 @@
+DROP TABLE dish_winner;
+DROP TABLE dish_vote;
+DROP TABLE org_settings;
+
+ALTER TABLE menu DROP CONSTRAINT menu_menu_id_key;
@@ -0,0 +1,37 @@
+
+CREATE TABLE org_settings (
+	org_id       UUID NOT NULL REFERENCES organization (org_id),
+	voting_mode  TEXT NOT NULL DEFAULT 'restaurant',
+	date_updated TIMESTAMP NOT NULL,
+	PRIMARY KEY (org_id)
+);
+
+-- Menus are keyed by restaurant and date, the dish votes point at the menu
+-- itself.
+ALTER TABLE menu ADD CONSTRAINT menu_menu_id_key UNIQUE (menu_id);
+
+CREATE TABLE dish_vote (
+	date          TIMESTAMP NOT NULL,
+	user_id       UUID NOT NULL REFERENCES users (user_id),
+	category      TEXT NOT NULL,
+	menu_id       UUID NOT NULL REFERENCES menu (menu_id),
+	restaurant_id UUID NOT NULL REFERENCES restaurant (restaurant_id),
+	item          TEXT NOT NULL,
+	org_id        UUID NOT NULL REFERENCES organization (org_id),
+	time_voted    TIMESTAMP NOT NULL,
+	PRIMARY KEY (date, user_id, category)
+);
+
+CREATE INDEX dish_vote_org_idx ON dish_vote (date, org_id);
+
+CREATE TABLE dish_winner (
+	date          TIMESTAMP NOT NULL,
+	org_id        UUID NOT NULL REFERENCES organization (org_id),
+	category      TEXT NOT NULL,
+	menu_id       UUID NOT NULL REFERENCES menu (menu_id),
+	restaurant_id UUID NOT NULL REFERENCES restaurant (restaurant_id),
+	item          TEXT NOT NULL,
+	votes         INT NOT NULL,
+	date_computed TIMESTAMP NOT NULL,
+	PRIMARY KEY (date, org_id, category)
+);
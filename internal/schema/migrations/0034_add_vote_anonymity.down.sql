@@ -0,0 +1,2 @@
+ALTER TABLE restaurant DROP COLUMN anonymous_votes;
+ALTER TABLE org_settings DROP COLUMN anonymous_votes;
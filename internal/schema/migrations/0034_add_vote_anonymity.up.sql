@@ -0,0 +1,3 @@
+
+ALTER TABLE org_settings ADD COLUMN anonymous_votes BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE restaurant ADD COLUMN anonymous_votes BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,16 @@
+
+CREATE TABLE menu_comment (
+	comment_id    UUID NOT NULL,
+	menu_id       UUID NOT NULL,
+	restaurant_id UUID NOT NULL REFERENCES restaurant (restaurant_id),
+	parent_id     UUID REFERENCES menu_comment (comment_id),
+	user_id       UUID NOT NULL REFERENCES users (user_id),
+	body          TEXT NOT NULL,
+	by_owner      BOOLEAN NOT NULL DEFAULT FALSE,
+	date_created  TIMESTAMP NOT NULL,
+	deleted_at    TIMESTAMP,
+	PRIMARY KEY (comment_id)
+);
+
+CREATE INDEX menu_comment_menu_idx ON menu_comment (menu_id, date_created);
+CREATE INDEX menu_comment_parent_idx ON menu_comment (parent_id);
@@ -0,0 +1,5 @@
+ALTER TABLE org_settings DROP COLUMN notification_channels;
+ALTER TABLE org_settings DROP COLUMN tie_break;
+ALTER TABLE org_settings DROP COLUMN streak_limit;
+ALTER TABLE org_settings DROP COLUMN timezone;
+ALTER TABLE org_settings DROP COLUMN vote_deadline;
@@ -0,0 +1,6 @@
+
+ALTER TABLE org_settings ADD COLUMN vote_deadline TEXT NOT NULL DEFAULT '';
+ALTER TABLE org_settings ADD COLUMN timezone TEXT NOT NULL DEFAULT '';
+ALTER TABLE org_settings ADD COLUMN streak_limit INT NOT NULL DEFAULT 0;
+ALTER TABLE org_settings ADD COLUMN tie_break TEXT NOT NULL DEFAULT 'first_vote';
+ALTER TABLE org_settings ADD COLUMN notification_channels TEXT[] NOT NULL DEFAULT '{email,slack,telegram,in_app}';
@@ -0,0 +1,11 @@
+
+CREATE TABLE feature_flag (
+	name         TEXT NOT NULL,
+	description  TEXT NOT NULL DEFAULT '',
+	enabled      BOOLEAN NOT NULL DEFAULT FALSE,
+	orgs         TEXT[] NOT NULL DEFAULT '{}',
+	percentage   INT NOT NULL DEFAULT 0,
+	date_created TIMESTAMP NOT NULL,
+	date_updated TIMESTAMP NOT NULL,
+	PRIMARY KEY (name)
+);
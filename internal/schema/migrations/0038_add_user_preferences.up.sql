@@ -0,0 +1,10 @@
+
+CREATE TABLE user_preferences (
+	user_id          UUID NOT NULL REFERENCES users (user_id),
+	channel          TEXT NOT NULL DEFAULT 'email',
+	digest_frequency TEXT NOT NULL DEFAULT 'daily',
+	dietary_filters  TEXT[] NOT NULL DEFAULT '{}',
+	favorites_only   BOOLEAN NOT NULL DEFAULT FALSE,
+	date_updated     TIMESTAMP NOT NULL,
+	PRIMARY KEY (user_id)
+);
@@ -0,0 +1 @@
+DROP INDEX restaurant_name_trgm_idx;
@@ -0,0 +1,4 @@
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX restaurant_name_trgm_idx ON restaurant USING gin (name gin_trgm_ops) WHERE deleted_at IS NULL;
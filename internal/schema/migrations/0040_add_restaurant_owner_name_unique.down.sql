@@ -0,0 +1 @@
+DROP INDEX restaurant_owner_name_idx;
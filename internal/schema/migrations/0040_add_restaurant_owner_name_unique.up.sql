@@ -0,0 +1,33 @@
+-- The restaurants an owner named alike are renamed "Name (n)" except the
+-- oldest. The first n giving a name none of the restaurants of the owner has
+-- is used, so a renamed restaurant never takes the name of another.
+DO $$
+DECLARE
+	d         RECORD;
+	n         INTEGER;
+	candidate TEXT;
+BEGIN
+	FOR d IN
+		SELECT restaurant_id, owner_user_id, name FROM (
+			SELECT restaurant_id, owner_user_id, name,
+				ROW_NUMBER() OVER (PARTITION BY owner_user_id, lower(name) ORDER BY date_created, restaurant_id) AS n
+			FROM restaurant WHERE deleted_at IS NULL
+		) AS dup
+		WHERE dup.n > 1
+		ORDER BY owner_user_id, lower(name), dup.n
+	LOOP
+		n := 2;
+		LOOP
+			candidate := d.name || ' (' || n || ')';
+			EXIT WHEN NOT EXISTS (
+				SELECT 1 FROM restaurant
+				WHERE owner_user_id = d.owner_user_id AND lower(name) = lower(candidate) AND deleted_at IS NULL
+			);
+			n := n + 1;
+		END LOOP;
+		UPDATE restaurant SET name = candidate WHERE restaurant_id = d.restaurant_id;
+	END LOOP;
+END;
+$$;
+
+CREATE UNIQUE INDEX restaurant_owner_name_idx ON restaurant (owner_user_id, lower(name)) WHERE deleted_at IS NULL;
@@ -0,0 +1,3 @@
+DROP TRIGGER vote_notify_change ON vote;
+DROP TRIGGER menu_notify_change ON menu;
+DROP FUNCTION notify_restaurant_change();
@@ -0,0 +1,28 @@
+-- Every change of a menu or a vote is announced on the restaurant_change
+-- channel, whichever replica or job made it, so the replicas of the API
+-- refresh their caches and vote streams.
+CREATE FUNCTION notify_restaurant_change() RETURNS trigger AS $$
+BEGIN
+	IF TG_OP <> 'INSERT' THEN
+		PERFORM pg_notify('restaurant_change', json_build_object(
+			'table', TG_TABLE_NAME,
+			'restaurant_id', OLD.restaurant_id,
+			'date', to_char(OLD.date, 'YYYY-MM-DD')
+		)::text);
+	END IF;
+	IF TG_OP <> 'DELETE' THEN
+		PERFORM pg_notify('restaurant_change', json_build_object(
+			'table', TG_TABLE_NAME,
+			'restaurant_id', NEW.restaurant_id,
+			'date', to_char(NEW.date, 'YYYY-MM-DD')
+		)::text);
+	END IF;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER menu_notify_change AFTER INSERT OR UPDATE OR DELETE ON menu
+	FOR EACH ROW EXECUTE PROCEDURE notify_restaurant_change();
+
+CREATE TRIGGER vote_notify_change AFTER INSERT OR UPDATE OR DELETE ON vote
+	FOR EACH ROW EXECUTE PROCEDURE notify_restaurant_change();
@@ -0,0 +1,5 @@
+DROP INDEX broadcast_org_idx;
+DROP INDEX webhook_org_idx;
+
+ALTER TABLE broadcast DROP COLUMN org_id;
+ALTER TABLE webhook DROP COLUMN org_id;
@@ -0,0 +1,8 @@
+ALTER TABLE webhook ADD COLUMN org_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES organization (org_id);
+ALTER TABLE broadcast ADD COLUMN org_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES organization (org_id);
+
+-- The broadcasts sent so far belong to the organization of their sender.
+UPDATE broadcast AS b SET org_id = u.org_id FROM users AS u WHERE u.user_id = b.sender_user_id;
+
+CREATE INDEX webhook_org_idx ON webhook (org_id);
+CREATE INDEX broadcast_org_idx ON broadcast (org_id);
@@ -0,0 +1,5 @@
+DROP TRIGGER broadcast_delivery_notify_stream ON broadcast_delivery;
+DROP FUNCTION notify_broadcast_stream();
+
+DROP INDEX push_device_user_idx;
+DROP TABLE push_device;
@@ -0,0 +1,28 @@
+-- The mobile devices of the users receive the broadcasts as push
+-- notifications.
+CREATE TABLE push_device (
+	token        TEXT,
+	user_id      UUID NOT NULL,
+	platform     TEXT NOT NULL,
+	date_created TIMESTAMP NOT NULL,
+	PRIMARY KEY (token),
+	FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+CREATE INDEX push_device_user_idx ON push_device (user_id);
+
+-- Every delivery of a broadcast over server-sent events is announced on the
+-- restaurant_broadcast channel so the replica the recipient streams from
+-- pushes it, whichever replica created the broadcast.
+CREATE FUNCTION notify_broadcast_stream() RETURNS trigger AS $$
+BEGIN
+	PERFORM pg_notify('restaurant_broadcast', json_build_object(
+		'broadcast_id', NEW.broadcast_id,
+		'user_id', NEW.user_id
+	)::text);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER broadcast_delivery_notify_stream AFTER INSERT ON broadcast_delivery
+	FOR EACH ROW WHEN (NEW.channel = 'sse') EXECUTE PROCEDURE notify_broadcast_stream();
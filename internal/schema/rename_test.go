@@ -0,0 +1,50 @@
+package schema_test
+
+import (
+	"testing"
+
+	"github.com/remisb/restaurant/internal/schema"
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// TestUniqueOwnerNames validates the restaurants an owner named alike are
+// renamed to names of their own when the names are made unique.
+func TestUniqueOwnerNames(t *testing.T) {
+	db, teardown := tests.NewUnit(t)
+	defer teardown()
+
+	t.Log("Given the need to make the names of the restaurants of an owner unique.")
+	{
+		t.Log("\tTest 0:\tWhen an owner has a restaurant named like the renamed one.")
+		{
+			ctx := tests.Context()
+
+			// Reverting the migrations down to 0039 drops the unique index.
+			if err := schema.Down(ctx, db, 4); err != nil {
+				t.Fatalf("\t%s\tShould be able to revert the unique names : %s.", tests.Failed, err)
+			}
+
+			const q = `INSERT INTO restaurant (restaurant_id, name, address, owner_user_id, date_created, date_updated) VALUES
+				('0ce90028-69cb-4e9c-9af0-7bbada50d5b6', 'Paikis', '', 'owner', '2019-03-24 00:00:00', '2019-03-24 00:00:00'),
+				('71b8fb90-24eb-4012-9048-3ba210aac0f6', 'paikis', '', 'owner', '2019-03-25 00:00:00', '2019-03-25 00:00:00'),
+				('2df32931-3072-4d11-8109-d1f0988c26b3', 'Paikis (2)', '', 'owner', '2019-03-26 00:00:00', '2019-03-26 00:00:00')`
+			if _, err := db.ExecContext(ctx, q); err != nil {
+				t.Fatalf("\t%s\tShould be able to create the restaurants : %s.", tests.Failed, err)
+			}
+
+			if err := schema.Migrate(ctx, db); err != nil {
+				t.Fatalf("\t%s\tShould be able to make the names unique : %s.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to make the names unique.", tests.Success)
+
+			var name string
+			if err := db.GetContext(ctx, &name, `SELECT name FROM restaurant WHERE restaurant_id = '71b8fb90-24eb-4012-9048-3ba210aac0f6'`); err != nil {
+				t.Fatalf("\t%s\tShould be able to retrieve the renamed restaurant : %s.", tests.Failed, err)
+			}
+			if name != "paikis (3)" {
+				t.Fatalf("\t%s\tShould rename the restaurant to a name not taken : got %q.", tests.Failed, name)
+			}
+			t.Logf("\t%s\tShould rename the restaurant to a name not taken.", tests.Success)
+		}
+	}
+}
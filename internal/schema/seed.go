@@ -1,37 +1,185 @@
 package schema
 
-import "github.com/jmoiron/sqlx"
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
 
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// ErrUnknownProfile is returned when seeding a profile which does not exist.
+var ErrUnknownProfile = errors.New("unknown seed profile")
+
+// profiles holds the seed profiles, one SQL file per profile.
+//
+//go:embed seeds/*.sql
+var profiles embed.FS
+
+// Seed loads the dev profile, which the tests rely on.
 func Seed(db *sqlx.DB) error {
+	return SeedProfile(db, "dev")
+}
+
+// Profiles lists the names of the seed profiles.
+func Profiles() []string {
+	names, _ := fs.Glob(profiles, "seeds/*.sql")
+	for i, name := range names {
+		names[i] = strings.TrimSuffix(path.Base(name), ".sql")
+	}
+	return names
+}
+
+// SeedProfile loads the named seed profile like dev, demo or load-test.
+func SeedProfile(db *sqlx.DB, name string) error {
+	b, err := profiles.ReadFile("seeds/" + name + ".sql")
+	if err != nil {
+		return ErrUnknownProfile
+	}
+	return exec(db, string(b))
+}
+
+// SeedFile loads the seed file at the path. It is either an SQL script or a
+// YAML file mapping table names to the rows to insert:
+//
+//	restaurant:
+//	  - restaurant_id: 0ce90028-69cb-4e9c-9af0-7bbada50d5b6
+//	    name: Paikis
+//	    owner_user_id: 5cf37266-3473-4006-984f-9325122678b7
+//
+// Tables are filled in the order of the file and rows which already exist are
+// left as they are.
+func SeedFile(db *sqlx.DB, file string) error {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return errors.Wrap(err, "reading seed file")
+	}
+
+	switch filepath.Ext(file) {
+	case ".sql":
+		return exec(db, string(b))
+	case ".yaml", ".yml":
+		q, err := yamlSeeds(b)
+		if err != nil {
+			return err
+		}
+		return exec(db, q...)
+	default:
+		return errors.Errorf("seed file %s is neither SQL nor YAML", file)
+	}
+}
+
+// exec runs the statements in a single transaction. Every statement is the
+// query followed by its arguments.
+func exec(db *sqlx.DB, stmts ...interface{}) error {
 	tx, err := db.Begin()
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	if _, err := tx.Exec(seeds); err != nil {
-		return err
+	for _, s := range stmts {
+		var err error
+		switch s := s.(type) {
+		case string:
+			_, err = tx.Exec(s)
+		case statement:
+			_, err = tx.Exec(s.query, s.args...)
+		}
+		if err != nil {
+			return err
+		}
 	}
 	return tx.Commit()
 }
 
-const seeds = `
+// statement is a query along with its arguments.
+type statement struct {
+	query string
+	args  []interface{}
+}
+
+// identifier matches the table and column names accepted in YAML seeds.
+var identifier = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// yamlSeeds turns YAML seeds into the statements inserting their rows.
+func yamlSeeds(b []byte) ([]interface{}, error) {
+	var tables yaml.MapSlice
+	if err := yaml.Unmarshal(b, &tables); err != nil {
+		return nil, errors.Wrap(err, "decoding YAML seeds")
+	}
+
+	var stmts []interface{}
+	for _, t := range tables {
+		table := fmt.Sprint(t.Key)
+		if !identifier.MatchString(table) {
+			return nil, errors.Errorf("invalid table name %q", table)
+		}
+
+		rows, ok := t.Value.([]interface{})
+		if !ok {
+			return nil, errors.Errorf("table %s must hold a list of rows", table)
+		}
 
-INSERT INTO restaurant (restaurant_id, name, address, owner_user_id, date_created, date_updated) VALUES
-  ('0ce90028-69cb-4e9c-9af0-7bbada50d5b6', 'Paikis', 'A. Smetonos g. 5, Vilnius 01115', '5cf37266-3473-4006-984f-9325122678b7', '2019-03-24 00:00:00', '2019-03-24 00:00:00'),
-  ('71b8fb90-24eb-4012-9048-3ba210aac0f6', 'Seeet Root', 'Užupio g. 22, Vilnius 01203', '5cf37266-3473-4006-984f-9325122678b7', '2019-03-24 00:00:00', '2019-03-24 00:00:00'),
-  ('2df32931-3072-4d11-8109-d1f0988c26b3', 'Lauro lapas', 'Pamėnkalnio g. 24, Vilnius 01114', '5cf37266-3473-4006-984f-9325122678b7', '2019-03-24 00:00:00', '2019-03-24 00:00:00'),
-  ('8800c4d0-0219-49d5-9eb0-db457ee015e5', 'Mykolo 4', 'Šv. Mykolo g. 4, Vilnius 01124', '5cf37266-3473-4006-984f-9325122678b7', '2019-03-24 00:00:00', '2019-03-24 00:00:00'),
-  ('5828612a-1f8a-403c-b6d1-6cb66fbf0c66', 'Lokys', 'Stiklių g. 10, Vilnius 01131', '5cf37266-3473-4006-984f-9325122678b7', '2019-03-24 00:00:00', '2019-03-24 00:00:00')
-  ON CONFLICT DO NOTHING;
+		for i, r := range rows {
+			// The maps nested in a MapSlice are decoded as MapSlices too.
+			items, ok := r.(yaml.MapSlice)
+			if !ok {
+				return nil, errors.Errorf("row %d of table %s must map columns to values", i, table)
+			}
+			row := make(map[interface{}]interface{}, len(items))
+			for _, item := range items {
+				row[item.Key] = item.Value
+			}
 
-INSERT INTO menu (restaurant_id, date, menu, votes) VALUES
-	('5828612a-1f8a-403c-b6d1-6cb66fbf0c66', '2020-03-01 00:00:00', 'Lokys menu for 2020-03-01', 0),
-	('5828612a-1f8a-403c-b6d1-6cb66fbf0c66', '2020-03-02 00:00:00', 'Lokys menu for 2020-03-02', 0)
-	ON CONFLICT DO NOTHING;
+			s, err := insert(table, row)
+			if err != nil {
+				return nil, errors.Wrapf(err, "row %d of table %s", i, table)
+			}
+			stmts = append(stmts, s)
+		}
+	}
+
+	return stmts, nil
+}
+
+// insert builds the statement inserting the row in the table.
+func insert(table string, row map[interface{}]interface{}) (statement, error) {
+	columns := make([]string, 0, len(row))
+	for c := range row {
+		col := fmt.Sprint(c)
+		if !identifier.MatchString(col) {
+			return statement{}, errors.Errorf("invalid column name %q", col)
+		}
+		columns = append(columns, col)
+	}
+	sort.Strings(columns)
+
+	s := statement{args: make([]interface{}, len(columns))}
+	params := make([]string, len(columns))
+	for i, col := range columns {
+		v := row[col]
+		if list, ok := v.([]interface{}); ok {
+			arr := make([]string, len(list))
+			for j, e := range list {
+				arr[j] = fmt.Sprint(e)
+			}
+			v = pq.Array(arr)
+		}
+		s.args[i] = v
+		params[i] = fmt.Sprintf("$%d", i+1)
+	}
 
--- Create admin and regular User with password "gophers"
-INSERT INTO users (user_id, name, email, roles, password_hash, date_created, date_updated) VALUES
-	('5cf37266-3473-4006-984f-9325122678b7', 'Admin Gopher', 'admin@example.com', '{ADMIN,USER}', '$2a$10$1ggfMVZV6Js0ybvJufLRUOWHS5f6KneuP0XwwHpJ8L8ipdry9f2/a', '2019-03-24 00:00:00', '2019-03-24 00:00:00'),
-	('45b5fbd3-755f-4379-8f07-a58d4a30fa2f', 'User Gopher', 'user@example.com', '{USER}', '$2a$10$9/XASPKBbJKVfCAZKDH.UuhsuALDr5vVm6VrYA9VFR8rccK86C1hW', '2019-03-24 00:00:00', '2019-03-24 00:00:00')
-	ON CONFLICT DO NOTHING;
-`
+	s.query = fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON CONFLICT DO NOTHING",
+		table, strings.Join(columns, ", "), strings.Join(params, ", "))
+	return s, nil
+}
@@ -0,0 +1,77 @@
+package schema
+
+import (
+	"testing"
+)
+
+// TestYAMLSeeds validates YAML seeds become inserts in the order of the file.
+func TestYAMLSeeds(t *testing.T) {
+	t.Log("Given the need to seed the database from YAML.")
+	{
+		t.Log("\tTest 0:\tWhen seeding users and restaurants.")
+		{
+			const seeds = `
+users:
+  - user_id: 5cf37266-3473-4006-984f-9325122678b7
+    name: Admin Gopher
+    roles: [ADMIN, USER]
+restaurant:
+  - restaurant_id: 0ce90028-69cb-4e9c-9af0-7bbada50d5b6
+    name: Paikis
+    owner_user_id: 5cf37266-3473-4006-984f-9325122678b7
+`
+			stmts, err := yamlSeeds([]byte(seeds))
+			if err != nil {
+				t.Fatalf("\t✗\tShould decode the seeds : %v", err)
+			}
+			t.Log("\t✓\tShould decode the seeds.")
+
+			want := []string{
+				"INSERT INTO users (name, roles, user_id) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING",
+				"INSERT INTO restaurant (name, owner_user_id, restaurant_id) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING",
+			}
+			if len(stmts) != len(want) {
+				t.Fatalf("\t✗\tShould insert every row : got %d statements", len(stmts))
+			}
+			for i, s := range stmts {
+				if q := s.(statement).query; q != want[i] {
+					t.Fatalf("\t✗\tShould insert the rows in order : got %q, want %q", q, want[i])
+				}
+			}
+			t.Log("\t✓\tShould insert the rows in order.")
+		}
+
+		t.Log("\tTest 1:\tWhen a column name is not an identifier.")
+		{
+			const seeds = `
+users:
+  - "name); DROP TABLE users; --": x
+`
+			if _, err := yamlSeeds([]byte(seeds)); err == nil {
+				t.Fatal("\t✗\tShould reject the seeds.")
+			}
+			t.Log("\t✓\tShould reject the seeds.")
+		}
+	}
+}
+
+// TestProfiles validates the seed profiles are embedded.
+func TestProfiles(t *testing.T) {
+	t.Log("Given the need to choose a seed profile.")
+	{
+		t.Log("\tTest 0:\tWhen listing the profiles.")
+		{
+			got := Profiles()
+			want := []string{"demo", "dev", "load-test"}
+			if len(got) != len(want) {
+				t.Fatalf("\t✗\tShould list every profile : got %v", got)
+			}
+			for i := range want {
+				if got[i] != want[i] {
+					t.Fatalf("\t✗\tShould list every profile : got %v", got)
+				}
+			}
+			t.Log("\t✓\tShould list every profile.")
+		}
+	}
+}
@@ -0,0 +1,35 @@
+-- The demo profile holds public restaurants with menus for the coming days and
+-- a few votes for today, so the API has something to show right away. All
+-- users have the password "gophers".
+INSERT INTO users (user_id, name, email, roles, password_hash, date_created, date_updated) VALUES
+	('5cf37266-3473-4006-984f-9325122678b7', 'Admin Gopher', 'admin@example.com', '{ADMIN,USER}', '$2a$10$1ggfMVZV6Js0ybvJufLRUOWHS5f6KneuP0XwwHpJ8L8ipdry9f2/a', now(), now()),
+	('45b5fbd3-755f-4379-8f07-a58d4a30fa2f', 'User Gopher', 'user@example.com', '{USER}', '$2a$10$9/XASPKBbJKVfCAZKDH.UuhsuALDr5vVm6VrYA9VFR8rccK86C1hW', now(), now()),
+	('b6b2a5c4-1f0e-4f43-9a6a-2c3d0e5f7a11', 'Ona Gopher', 'ona@example.com', '{USER}', '$2a$10$9/XASPKBbJKVfCAZKDH.UuhsuALDr5vVm6VrYA9VFR8rccK86C1hW', now(), now()),
+	('c3d9e8f1-6a2b-4c5d-8e7f-9a0b1c2d3e22', 'Jonas Gopher', 'jonas@example.com', '{USER}', '$2a$10$9/XASPKBbJKVfCAZKDH.UuhsuALDr5vVm6VrYA9VFR8rccK86C1hW', now(), now())
+	ON CONFLICT DO NOTHING;
+
+INSERT INTO restaurant (restaurant_id, name, address, owner_user_id, website, phone, public, date_created, date_updated) VALUES
+	('0ce90028-69cb-4e9c-9af0-7bbada50d5b6', 'Paikis', 'A. Smetonos g. 5, Vilnius 01115', '5cf37266-3473-4006-984f-9325122678b7', 'https://paikis.example.com', '+370 600 00001', TRUE, now(), now()),
+	('71b8fb90-24eb-4012-9048-3ba210aac0f6', 'Seeet Root', 'Užupio g. 22, Vilnius 01203', '5cf37266-3473-4006-984f-9325122678b7', 'https://sweetroot.example.com', '+370 600 00002', TRUE, now(), now()),
+	('2df32931-3072-4d11-8109-d1f0988c26b3', 'Lauro lapas', 'Pamėnkalnio g. 24, Vilnius 01114', '5cf37266-3473-4006-984f-9325122678b7', 'https://laurolapas.example.com', '+370 600 00003', TRUE, now(), now()),
+	('8800c4d0-0219-49d5-9eb0-db457ee015e5', 'Mykolo 4', 'Šv. Mykolo g. 4, Vilnius 01124', '5cf37266-3473-4006-984f-9325122678b7', 'https://mykolo4.example.com', '+370 600 00004', TRUE, now(), now()),
+	('5828612a-1f8a-403c-b6d1-6cb66fbf0c66', 'Lokys', 'Stiklių g. 10, Vilnius 01131', '5cf37266-3473-4006-984f-9325122678b7', 'https://lokys.example.com', '+370 600 00005', TRUE, now(), now())
+	ON CONFLICT DO NOTHING;
+
+-- Every restaurant gets a menu for today and the next six days.
+INSERT INTO menu (menu_id, restaurant_id, date, menu, votes)
+	SELECT md5(r.restaurant_id::text || d::text)::uuid, r.restaurant_id, d, r.name || ' menu for ' || to_char(d, 'YYYY-MM-DD'), 0
+	FROM restaurant AS r, generate_series(CURRENT_DATE, CURRENT_DATE + 6, interval '1 day') AS d
+	WHERE r.public
+	ON CONFLICT DO NOTHING;
+
+INSERT INTO vote (date, user_id, restaurant_id, time_voted) VALUES
+	(CURRENT_DATE, '45b5fbd3-755f-4379-8f07-a58d4a30fa2f', '5828612a-1f8a-403c-b6d1-6cb66fbf0c66', now()),
+	(CURRENT_DATE, 'b6b2a5c4-1f0e-4f43-9a6a-2c3d0e5f7a11', '5828612a-1f8a-403c-b6d1-6cb66fbf0c66', now()),
+	(CURRENT_DATE, 'c3d9e8f1-6a2b-4c5d-8e7f-9a0b1c2d3e22', '2df32931-3072-4d11-8109-d1f0988c26b3', now())
+	ON CONFLICT DO NOTHING;
+
+INSERT INTO vote_event (type, date, user_id, restaurant_id, org_id, time_occurred)
+	SELECT 'cast', v.date, v.user_id, v.restaurant_id, v.org_id, v.time_voted FROM vote AS v
+	WHERE NOT EXISTS (SELECT 1 FROM vote_event AS e WHERE e.date = v.date AND e.user_id = v.user_id)
+	ORDER BY v.date, v.time_voted;
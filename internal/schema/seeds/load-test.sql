@@ -0,0 +1,28 @@
+-- The load-test profile generates 1000 users, 200 restaurants with a menu for
+-- every day of the last 30 days and the votes of every user on those days.
+-- IDs are derived from the row numbers so seeding twice adds nothing. All
+-- users have the password "gophers".
+INSERT INTO users (user_id, name, email, roles, password_hash, date_created, date_updated)
+	SELECT md5('user' || i)::uuid, 'Load Gopher ' || i, 'load' || i || '@example.com', '{USER}', '$2a$10$9/XASPKBbJKVfCAZKDH.UuhsuALDr5vVm6VrYA9VFR8rccK86C1hW', now(), now()
+	FROM generate_series(1, 1000) AS i
+	ON CONFLICT DO NOTHING;
+
+INSERT INTO restaurant (restaurant_id, name, address, owner_user_id, public, date_created, date_updated)
+	SELECT md5('restaurant' || i)::uuid, 'Load Restaurant ' || i, 'Gedimino pr. ' || i || ', Vilnius', md5('user' || (i % 1000 + 1))::uuid::text, i % 2 = 0, now(), now()
+	FROM generate_series(1, 200) AS i
+	ON CONFLICT DO NOTHING;
+
+INSERT INTO menu (menu_id, restaurant_id, date, menu, votes)
+	SELECT md5('menu' || i || d::text)::uuid, md5('restaurant' || i)::uuid, d, 'Load Restaurant ' || i || ' menu for ' || to_char(d, 'YYYY-MM-DD'), 0
+	FROM generate_series(1, 200) AS i, generate_series(CURRENT_DATE - 29, CURRENT_DATE, interval '1 day') AS d
+	ON CONFLICT DO NOTHING;
+
+INSERT INTO vote (date, user_id, restaurant_id, time_voted)
+	SELECT d, md5('user' || u)::uuid, md5('restaurant' || ((u * 7 + extract(doy FROM d)::int) % 200 + 1))::uuid, d + interval '10 hours'
+	FROM generate_series(1, 1000) AS u, generate_series(CURRENT_DATE - 29, CURRENT_DATE, interval '1 day') AS d
+	ON CONFLICT DO NOTHING;
+
+INSERT INTO vote_event (type, date, user_id, restaurant_id, org_id, time_occurred)
+	SELECT 'cast', v.date, v.user_id, v.restaurant_id, v.org_id, v.time_voted FROM vote AS v
+	WHERE NOT EXISTS (SELECT 1 FROM vote_event AS e WHERE e.date = v.date AND e.user_id = v.user_id)
+	ORDER BY v.date, v.time_voted;
@@ -0,0 +1,140 @@
+// Package sentry reports the panics of the handlers to Sentry through its
+// store endpoint, without the weight of the Sentry SDK.
+package sentry
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/mid"
+)
+
+// ErrInvalidDSN is used when the DSN is not formatted as
+// https://key@host/project.
+var ErrInvalidDSN = errors.New("DSN must be formatted as https://key@host/project")
+
+// Reporter sends the panics to the Sentry project of its DSN. It implements
+// the mid.PanicReporter interface.
+type Reporter struct {
+	log     *log.Logger
+	url     string
+	key     string
+	release string
+	client  *http.Client
+}
+
+// New constructs a Reporter for the project of the DSN tagging the events
+// with the release of the service.
+func New(log *log.Logger, dsn, release string, timeout time.Duration) (*Reporter, error) {
+	u, err := url.Parse(dsn)
+	if err != nil || u.User == nil || u.User.Username() == "" {
+		return nil, ErrInvalidDSN
+	}
+	dir, project := path.Split(strings.TrimSuffix(u.Path, "/"))
+	if project == "" {
+		return nil, ErrInvalidDSN
+	}
+
+	store := url.URL{
+		Scheme: u.Scheme,
+		Host:   u.Host,
+		Path:   path.Join(dir, "api", project, "store") + "/",
+	}
+
+	r := Reporter{
+		log:     log,
+		url:     store.String(),
+		key:     u.User.Username(),
+		release: release,
+		client:  &http.Client{Timeout: timeout},
+	}
+	return &r, nil
+}
+
+// event is a Sentry event as accepted by the store endpoint.
+type event struct {
+	EventID   string            `json:"event_id"`
+	Timestamp string            `json:"timestamp"`
+	Level     string            `json:"level"`
+	Platform  string            `json:"platform"`
+	Release   string            `json:"release,omitempty"`
+	Message   string            `json:"message"`
+	Tags      map[string]string `json:"tags"`
+	Request   eventRequest      `json:"request"`
+	Extra     map[string]string `json:"extra"`
+}
+
+// eventRequest is the request an event happened during.
+type eventRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+}
+
+// ReportPanic implements the mid.PanicReporter interface. The event is sent
+// in the background so the request is not held up by Sentry.
+func (rp *Reporter) ReportPanic(ctx context.Context, r *http.Request, traceID string, err *mid.PanicError) {
+	ev := event{
+		EventID:   newEventID(),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Level:     "fatal",
+		Platform:  "go",
+		Release:   rp.release,
+		Message:   err.Error(),
+		Tags:      map[string]string{"trace_id": traceID},
+		Request:   eventRequest{Method: r.Method, URL: r.URL.Path},
+		Extra:     map[string]string{"stack": string(err.Stack)},
+	}
+
+	go func() {
+		if err := rp.send(ev); err != nil {
+			rp.log.Printf("sentry : ERROR : %s : %+v", traceID, err)
+		}
+	}()
+}
+
+// send posts the event to the store endpoint.
+func (rp *Reporter) send(ev event) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return errors.Wrap(err, "encoding event")
+	}
+
+	req, err := http.NewRequest(http.MethodPost, rp.url, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "creating request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", fmt.Sprintf("Sentry sentry_version=7, sentry_client=restaurant-api, sentry_key=%s", rp.key))
+
+	resp, err := rp.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "posting event")
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return errors.Errorf("posting event: status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// newEventID returns a random ID of an event, 32 hexadecimal characters.
+func newEventID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
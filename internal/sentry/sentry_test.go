@@ -0,0 +1,63 @@
+package sentry
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// TestSend validates events are posted to the store endpoint of the project
+// of the DSN with its key.
+func TestSend(t *testing.T) {
+	t.Log("Given the need to report panics to Sentry.")
+	{
+		var path, auth string
+		var got event
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path, auth = r.URL.Path, r.Header.Get("X-Sentry-Auth")
+			if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+			}
+		}))
+		defer srv.Close()
+
+		t.Log("\tTest 0:\tWhen the DSN is malformed.")
+		{
+			if _, err := New(nil, "https://sentry.example.com/42", "develop", time.Second); err != ErrInvalidDSN {
+				t.Fatalf("\t%s\tShould refuse a DSN without a key : got %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould refuse a DSN without a key.", tests.Success)
+		}
+
+		t.Log("\tTest 1:\tWhen sending an event.")
+		{
+			dsn := strings.Replace(srv.URL, "http://", "http://public@", 1) + "/42"
+			rp, err := New(log.New(ioutil.Discard, "", 0), dsn, "develop", time.Second)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to construct the reporter : %s.", tests.Failed, err)
+			}
+
+			if err := rp.send(event{EventID: newEventID(), Message: "panic: out of lunch"}); err != nil {
+				t.Fatalf("\t%s\tShould be able to send the event : %s.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to send the event.", tests.Success)
+
+			if path != "/api/42/store/" || !strings.Contains(auth, "sentry_key=public") {
+				t.Fatalf("\t%s\tShould post to the store of the project : got %s with %q.", tests.Failed, path, auth)
+			}
+			t.Logf("\t%s\tShould post to the store of the project.", tests.Success)
+
+			if got.Message != "panic: out of lunch" || len(got.EventID) != 32 {
+				t.Fatalf("\t%s\tShould send the event : got %+v.", tests.Failed, got)
+			}
+			t.Logf("\t%s\tShould send the event.", tests.Success)
+		}
+	}
+}
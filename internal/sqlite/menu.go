@@ -0,0 +1,156 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/authz"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/restaurant"
+	"go.opentelemetry.io/otel"
+)
+
+// Menus is a restaurant.MenuStore backed by SQLite.
+type Menus struct {
+	db *sqlx.DB
+}
+
+// NewMenus constructs a Menus store on top of the database.
+func NewMenus(db *sqlx.DB) *Menus {
+	return &Menus{db: db}
+}
+
+// CreateMenu implements the restaurant.MenuStore interface. A restaurant has
+// a single menu per day like with PostgreSQL, where the date column drops the
+// time of day. Organizations have no time zone here so a menu without a date
+// is for today in UTC.
+func (s *Menus) CreateMenu(ctx context.Context, user auth.Claims, nm restaurant.NewMenu, now time.Time) (*restaurant.Menu, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.sqlite.Menus.CreateMenu")
+	defer span.End()
+
+	date, err := restaurant.ParseMenuDate(nm.Date, day(now))
+	if err != nil {
+		return nil, err
+	}
+	if err := restaurant.CheckMenuDate(date, day(now)); err != nil {
+		return nil, err
+	}
+
+	m := restaurant.Menu{
+		ID:           uuid.New().String(),
+		RestaurantID: nm.RestaurantID,
+		Date:         date,
+		Menu:         nm.Menu,
+		Items:        nm.Items,
+		Version:      1,
+	}
+	if m.Items == nil {
+		m.Items = restaurant.MenuItems{}
+	}
+
+	const q = `INSERT INTO menu
+		(menu_id, restaurant_id, date, menu, items, votes)
+		VALUES (?, ?, ?, ?, ?, 0)`
+	if _, err := s.db.ExecContext(ctx, q, m.ID, m.RestaurantID, m.Date, m.Menu, m.Items); err != nil {
+		return nil, errors.Wrap(err, "inserting menu")
+	}
+
+	return &m, nil
+}
+
+// RetrieveMenu implements the restaurant.MenuStore interface.
+func (s *Menus) RetrieveMenu(ctx context.Context, id string) (*restaurant.Menu, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.sqlite.Menus.RetrieveMenu")
+	defer span.End()
+
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, restaurant.ErrInvalidID
+	}
+
+	var m restaurant.Menu
+	const q = `SELECT * FROM menu WHERE menu_id = ? AND deleted_at IS NULL`
+	if err := s.db.GetContext(ctx, &m, q, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, restaurant.ErrNotFound
+		}
+		return nil, errors.Wrap(err, "selecting single menu")
+	}
+
+	return &m, nil
+}
+
+// ListMenus implements the restaurant.MenuStore interface.
+func (s *Menus) ListMenus(ctx context.Context, restaurantID string, from time.Time) ([]restaurant.Menu, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.sqlite.Menus.ListMenus")
+	defer span.End()
+
+	if _, err := uuid.Parse(restaurantID); err != nil {
+		return nil, restaurant.ErrInvalidID
+	}
+
+	menus := []restaurant.Menu{}
+	const q = `SELECT * FROM menu
+		WHERE restaurant_id = ? AND datetime(date) >= datetime(?) AND deleted_at IS NULL
+		ORDER BY datetime(date)`
+	if err := s.db.SelectContext(ctx, &menus, q, restaurantID, from.UTC()); err != nil {
+		return nil, errors.Wrap(err, "selecting menus")
+	}
+
+	return menus, nil
+}
+
+// UpdateMenu implements the restaurant.MenuStore interface.
+func (s *Menus) UpdateMenu(ctx context.Context, user auth.Claims, restaurantID string, update restaurant.UpdateMenu, now time.Time) (*restaurant.Menu, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.sqlite.Menus.UpdateMenu")
+	defer span.End()
+
+	r, err := NewRestaurants(s.db).Retrieve(ctx, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !authz.Allowed(user, authz.Update, authz.Resource{Kind: authz.Menu, Owner: r.OwnerUserID}) {
+		return nil, restaurant.ErrForbidden
+	}
+
+	m, err := s.RetrieveMenu(ctx, update.ID)
+	if err != nil {
+		return nil, err
+	}
+	if m.RestaurantID != r.ID {
+		return nil, restaurant.ErrNotFound
+	}
+
+	if update.Version != nil && *update.Version != m.Version {
+		return nil, restaurant.ErrVersionConflict
+	}
+
+	if update.Menu != "" {
+		m.Menu = update.Menu
+	}
+	if update.Date != "" {
+		if m.Date, err = restaurant.ParseMenuDate(update.Date, m.Date); err != nil {
+			return nil, err
+		}
+	}
+	if update.Items != nil {
+		m.Items = update.Items
+	}
+
+	const q = `UPDATE menu SET menu = ?, date = ?, items = ?, version = version + 1
+		WHERE menu_id = ? AND version = ? AND deleted_at IS NULL`
+	res, err := s.db.ExecContext(ctx, q, m.Menu, m.Date, m.Items, m.ID, m.Version)
+	if err != nil {
+		return nil, errors.Wrap(err, "updating menu")
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return nil, restaurant.ErrVersionConflict
+	}
+	m.Version++
+
+	return m, nil
+}
@@ -0,0 +1,67 @@
+CREATE TABLE IF NOT EXISTS restaurant (
+	restaurant_id TEXT NOT NULL,
+	name          TEXT NOT NULL,
+	address       TEXT,
+	owner_user_id TEXT NOT NULL,
+	date_created  TIMESTAMP,
+	date_updated  TIMESTAMP,
+	website       TEXT NOT NULL DEFAULT '',
+	phone         TEXT NOT NULL DEFAULT '',
+	photos        TEXT NOT NULL DEFAULT '{}',
+	tags          TEXT NOT NULL DEFAULT '{}',
+	public        BOOLEAN NOT NULL DEFAULT FALSE,
+	latitude      REAL,
+	longitude     REAL,
+	version       INTEGER NOT NULL DEFAULT 1,
+	deleted_at    TIMESTAMP,
+	PRIMARY KEY (restaurant_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS restaurant_owner_name_idx
+	ON restaurant (owner_user_id, lower(name)) WHERE deleted_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS menu (
+	-- The seeds leave the ID out, it is then a random UUID.
+	menu_id       TEXT NOT NULL DEFAULT (lower(
+		hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' ||
+		substr(hex(randomblob(2)), 2) || '-' ||
+		substr('89ab', 1 + abs(random()) % 4, 1) ||
+		substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6))
+	)),
+	restaurant_id TEXT NOT NULL,
+	date          DATE NOT NULL,
+	menu          TEXT,
+	items         TEXT NOT NULL DEFAULT '[]',
+	votes         INTEGER,
+	version       INTEGER NOT NULL DEFAULT 1,
+	deleted_at    TIMESTAMP,
+	PRIMARY KEY (restaurant_id, date)
+);
+
+CREATE TABLE IF NOT EXISTS users (
+	user_id       TEXT NOT NULL,
+	name          TEXT,
+	email         TEXT UNIQUE,
+	roles         TEXT NOT NULL DEFAULT '{}',
+	password_hash TEXT,
+	date_created  TIMESTAMP,
+	date_updated  TIMESTAMP,
+	version       INTEGER NOT NULL DEFAULT 1,
+	deleted_at    TIMESTAMP,
+	PRIMARY KEY (user_id)
+);
+
+CREATE TABLE IF NOT EXISTS vote (
+	date          TIMESTAMP NOT NULL,
+	user_id       TEXT NOT NULL,
+	restaurant_id TEXT NOT NULL,
+	time_voted    TIMESTAMP,
+	PRIMARY KEY (date, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS favorite (
+	user_id       TEXT NOT NULL,
+	restaurant_id TEXT NOT NULL,
+	date_created  TIMESTAMP NOT NULL,
+	PRIMARY KEY (user_id, restaurant_id)
+);
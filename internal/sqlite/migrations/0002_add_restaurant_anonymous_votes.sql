@@ -0,0 +1 @@
+ALTER TABLE restaurant ADD COLUMN anonymous_votes BOOLEAN NOT NULL DEFAULT FALSE;
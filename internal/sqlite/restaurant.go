@@ -0,0 +1,349 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/authz"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/geo"
+	"github.com/remisb/restaurant/internal/restaurant"
+	"go.opentelemetry.io/otel"
+)
+
+// Restaurants is a restaurant.Store backed by SQLite.
+type Restaurants struct {
+	db *sqlx.DB
+}
+
+// NewRestaurants constructs a Restaurants store on top of the database.
+func NewRestaurants(db *sqlx.DB) *Restaurants {
+	return &Restaurants{db: db}
+}
+
+// List implements the restaurant.Store interface.
+func (s *Restaurants) List(ctx context.Context) ([]restaurant.Restaurant, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.sqlite.Restaurants.List")
+	defer span.End()
+
+	restaurants := []restaurant.Restaurant{}
+	const q = `SELECT * FROM restaurant WHERE deleted_at IS NULL`
+	if err := s.db.SelectContext(ctx, &restaurants, q); err != nil {
+		return nil, errors.Wrap(err, "selecting restaurants")
+	}
+	return restaurants, nil
+}
+
+// ListNearby implements the restaurant.Store interface. SQLite has no
+// geographic functions so the distances are computed after reading the
+// restaurants with a location.
+func (s *Restaurants) ListNearby(ctx context.Context, near geo.Point, radius float64) ([]restaurant.Nearby, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.sqlite.Restaurants.ListNearby")
+	defer span.End()
+
+	located := []restaurant.Restaurant{}
+	const q = `SELECT * FROM restaurant
+		WHERE deleted_at IS NULL AND latitude IS NOT NULL AND longitude IS NOT NULL`
+	if err := s.db.SelectContext(ctx, &located, q); err != nil {
+		return nil, errors.Wrap(err, "selecting located restaurants")
+	}
+
+	restaurants := []restaurant.Nearby{}
+	for _, r := range located {
+		p, _ := r.Location()
+		if d := geo.Distance(near, p); d <= radius {
+			restaurants = append(restaurants, restaurant.Nearby{Restaurant: r, Distance: d})
+		}
+	}
+	sort.Slice(restaurants, func(i, j int) bool { return restaurants[i].Distance < restaurants[j].Distance })
+	return restaurants, nil
+}
+
+// FindSimilar implements the restaurant.Store interface. SQLite has no
+// trigram similarity so it is computed after reading the restaurants.
+func (s *Restaurants) FindSimilar(ctx context.Context, name, address string) ([]restaurant.Match, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.sqlite.Restaurants.FindSimilar")
+	defer span.End()
+
+	restaurants, err := s.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return restaurant.Duplicates(restaurants, name, address), nil
+}
+
+// Create implements the restaurant.Store interface.
+func (s *Restaurants) Create(ctx context.Context, user auth.Claims, nr restaurant.NewRestaurant, now time.Time) (*restaurant.Restaurant, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.sqlite.Restaurants.Create")
+	defer span.End()
+
+	loc, err := nr.Location()
+	if err != nil {
+		return nil, err
+	}
+
+	r := restaurant.Restaurant{
+		ID:          uuid.New().String(),
+		Name:        nr.Name,
+		Address:     nr.Address,
+		OwnerUserID: user.Subject,
+		Photos:      pq.StringArray{},
+		Thumbnails:  pq.StringArray{},
+		Tags:        pq.StringArray{},
+		Version:     1,
+		DateCreated: now.UTC(),
+		DateUpdated: now.UTC(),
+	}
+	if loc != nil {
+		r.Latitude, r.Longitude = &loc.Lat, &loc.Lng
+	}
+
+	const q = `INSERT INTO restaurant
+		(restaurant_id, name, address, owner_user_id, latitude, longitude, date_created, date_updated)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	if _, err := s.db.ExecContext(ctx, q, r.ID, r.Name, r.Address, r.OwnerUserID, r.Latitude, r.Longitude, r.DateCreated, r.DateUpdated); err != nil {
+		if duplicateName(err) {
+			return nil, restaurant.ErrDuplicateName
+		}
+		return nil, errors.Wrap(err, "inserting restaurant")
+	}
+
+	return &r, nil
+}
+
+// Retrieve implements the restaurant.Store interface.
+func (s *Restaurants) Retrieve(ctx context.Context, id string) (*restaurant.Restaurant, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.sqlite.Restaurants.Retrieve")
+	defer span.End()
+
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, restaurant.ErrInvalidID
+	}
+
+	var r restaurant.Restaurant
+	const q = `SELECT * FROM restaurant WHERE restaurant_id = ? AND deleted_at IS NULL`
+	if err := s.db.GetContext(ctx, &r, q, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, restaurant.ErrNotFound
+		}
+		return nil, errors.Wrap(err, "selecting single restaurant")
+	}
+
+	return &r, nil
+}
+
+// Update implements the restaurant.Store interface.
+func (s *Restaurants) Update(ctx context.Context, user auth.Claims, id string, update restaurant.UpdateRestaurant, now time.Time) (*restaurant.Restaurant, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.sqlite.Restaurants.Update")
+	defer span.End()
+
+	loc, err := update.Location()
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := s.Retrieve(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !authz.Allowed(user, authz.Update, authz.Resource{Kind: authz.Restaurant, Owner: r.OwnerUserID}) {
+		return nil, restaurant.ErrForbidden
+	}
+
+	if update.Version != nil && *update.Version != r.Version {
+		return nil, restaurant.ErrVersionConflict
+	}
+
+	if update.Name != nil {
+		r.Name = *update.Name
+	}
+	if update.Address != nil {
+		r.Address = *update.Address
+	}
+	if update.Website != nil {
+		r.Website = *update.Website
+	}
+	if update.Phone != nil {
+		r.Phone = *update.Phone
+	}
+	if update.Photos != nil {
+		r.SetPhotos(update.Photos)
+	}
+	if update.Public != nil {
+		r.Public = *update.Public
+	}
+	if update.AnonymousVotes != nil {
+		r.AnonymousVotes = *update.AnonymousVotes
+	}
+	if loc != nil {
+		r.Latitude, r.Longitude = &loc.Lat, &loc.Lng
+	}
+	r.DateUpdated = now.UTC()
+
+	const q = `UPDATE restaurant SET
+		name = ?, address = ?, website = ?, phone = ?, photos = ?, public = ?,
+		anonymous_votes = ?, latitude = ?, longitude = ?, date_updated = ?, version = version + 1
+		WHERE restaurant_id = ? AND version = ? AND deleted_at IS NULL`
+	res, err := s.db.ExecContext(ctx, q,
+		r.Name, r.Address, r.Website, r.Phone, r.Photos, r.Public, r.AnonymousVotes, r.Latitude, r.Longitude, r.DateUpdated,
+		id, r.Version,
+	)
+	if err != nil {
+		if duplicateName(err) {
+			return nil, restaurant.ErrDuplicateName
+		}
+		return nil, errors.Wrap(err, "updating restaurant")
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return nil, restaurant.ErrVersionConflict
+	}
+	r.Version++
+
+	return r, nil
+}
+
+// Delete implements the restaurant.Store interface.
+func (s *Restaurants) Delete(ctx context.Context, id string, now time.Time) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.sqlite.Restaurants.Delete")
+	defer span.End()
+
+	if _, err := uuid.Parse(id); err != nil {
+		return restaurant.ErrInvalidID
+	}
+
+	const q = `UPDATE restaurant SET deleted_at = ?, version = version + 1
+		WHERE restaurant_id = ? AND deleted_at IS NULL`
+	res, err := s.db.ExecContext(ctx, q, now.UTC(), id)
+	if err != nil {
+		return errors.Wrapf(err, "deleting restaurant %s", id)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return restaurant.ErrNotFound
+	}
+
+	return nil
+}
+
+// AddFavorite implements the restaurant.Store interface.
+func (s *Restaurants) AddFavorite(ctx context.Context, userID, id string, now time.Time) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.sqlite.Restaurants.AddFavorite")
+	defer span.End()
+
+	if _, err := s.Retrieve(ctx, id); err != nil {
+		return err
+	}
+
+	const q = `INSERT INTO favorite
+		(user_id, restaurant_id, date_created)
+		VALUES (?, ?, ?)
+		ON CONFLICT (user_id, restaurant_id) DO NOTHING`
+	if _, err := s.db.ExecContext(ctx, q, userID, id, now.UTC()); err != nil {
+		return errors.Wrapf(err, "inserting favorite %s", id)
+	}
+
+	return nil
+}
+
+// RemoveFavorite implements the restaurant.Store interface.
+func (s *Restaurants) RemoveFavorite(ctx context.Context, userID, id string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.sqlite.Restaurants.RemoveFavorite")
+	defer span.End()
+
+	if _, err := uuid.Parse(id); err != nil {
+		return restaurant.ErrInvalidID
+	}
+
+	const q = `DELETE FROM favorite WHERE user_id = ? AND restaurant_id = ?`
+	if _, err := s.db.ExecContext(ctx, q, userID, id); err != nil {
+		return errors.Wrapf(err, "deleting favorite %s", id)
+	}
+
+	return nil
+}
+
+// ListFavorites implements the restaurant.Store interface.
+func (s *Restaurants) ListFavorites(ctx context.Context, userID string) ([]restaurant.Restaurant, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.sqlite.Restaurants.ListFavorites")
+	defer span.End()
+
+	restaurants := []restaurant.Restaurant{}
+	const q = `SELECT r.* FROM restaurant AS r
+		JOIN favorite AS f ON f.restaurant_id = r.restaurant_id
+		WHERE f.user_id = ? AND r.deleted_at IS NULL
+		ORDER BY datetime(f.date_created) DESC`
+	if err := s.db.SelectContext(ctx, &restaurants, q, userID); err != nil {
+		return nil, errors.Wrap(err, "selecting favorites")
+	}
+	return restaurants, nil
+}
+
+// ListIncluded implements the restaurant.Store interface. Organizations have
+// no time zone here so today is in UTC, and there are no ratings to embed.
+func (s *Restaurants) ListIncluded(ctx context.Context, ids []string, in restaurant.Include, now time.Time) (map[string]restaurant.Included, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.sqlite.Restaurants.ListIncluded")
+	defer span.End()
+
+	included := make(map[string]restaurant.Included, len(ids))
+	if len(ids) == 0 || !in.Any() {
+		return included, nil
+	}
+	today := day(now)
+
+	if in.Menu {
+		q, args, err := sqlx.In(`SELECT m.* FROM restaurant AS r
+			JOIN menu AS m ON m.restaurant_id = r.restaurant_id AND datetime(m.date) = datetime(?) AND m.deleted_at IS NULL
+			WHERE r.restaurant_id IN (?)`, today, ids)
+		if err != nil {
+			return nil, errors.Wrap(err, "expanding restaurant ids")
+		}
+		menus := []restaurant.Menu{}
+		if err := s.db.SelectContext(ctx, &menus, s.db.Rebind(q), args...); err != nil {
+			return nil, errors.Wrap(err, "selecting menus of today")
+		}
+		for i := range menus {
+			inc := included[menus[i].RestaurantID]
+			inc.Menu = &menus[i]
+			included[menus[i].RestaurantID] = inc
+		}
+	}
+
+	if in.Votes {
+		q, args, err := sqlx.In(`SELECT r.restaurant_id, COUNT(v.user_id) AS votes FROM restaurant AS r
+			LEFT JOIN vote AS v ON v.restaurant_id = r.restaurant_id AND v.date = ?
+			WHERE r.restaurant_id IN (?)
+			GROUP BY r.restaurant_id`, today, ids)
+		if err != nil {
+			return nil, errors.Wrap(err, "expanding restaurant ids")
+		}
+		var counts []struct {
+			RestaurantID string `db:"restaurant_id"`
+			Votes        int    `db:"votes"`
+		}
+		if err := s.db.SelectContext(ctx, &counts, s.db.Rebind(q), args...); err != nil {
+			return nil, errors.Wrap(err, "counting votes")
+		}
+		for _, c := range counts {
+			inc := included[c.RestaurantID]
+			votes := c.Votes
+			inc.Votes = &votes
+			included[c.RestaurantID] = inc
+		}
+	}
+
+	return included, nil
+}
+
+// duplicateName reports whether the error is the violation of the unique
+// name of the restaurants of an owner. The driver is not imported so the
+// message is matched, SQLite naming the index as it is on an expression.
+func duplicateName(err error) bool {
+	return strings.Contains(err.Error(), "UNIQUE constraint failed: index 'restaurant_owner_name_idx'")
+}
@@ -0,0 +1,119 @@
+// Package sqlite implements the store interfaces on top of an SQLite database
+// so the API can run locally without PostgreSQL. It covers the restaurants,
+// menus, users and votes; the other features need PostgreSQL.
+//
+// The driver is not part of the default build, the binary has to be built
+// with the sqlite tag.
+package sqlite
+
+import (
+	"embed"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// DriverName is the name the modernc.org/sqlite driver registers.
+const DriverName = "sqlite"
+
+// files holds the migrations of the SQLite schema, named NNNN_description.sql.
+// They mirror the PostgreSQL migrations of the tables the stores use so the
+// same models are scanned from both. Applied migrations must never be
+// edited, add a new version instead.
+//
+//go:embed migrations/*.sql
+var files embed.FS
+
+// Open opens the SQLite database in the file at the path and applies the
+// migrations it is missing.
+func Open(path string) (*sqlx.DB, error) {
+
+	// Times are stored in the format of the SQLite date functions so they
+	// can be compared in queries.
+	db, err := sqlx.Open(DriverName, path+"?_time_format=sqlite")
+	if err != nil {
+		return nil, errors.Wrap(err, "opening sqlite database, is the binary built with the sqlite tag")
+	}
+
+	// SQLite allows a single writer at a time. Sharing one connection makes
+	// concurrent requests wait for each other instead of failing as busy.
+	db.SetMaxOpenConns(1)
+
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// migrate applies the migrations newer than the version of the database in
+// order, each in its own transaction along with recording its version.
+func migrate(db *sqlx.DB) error {
+	const qt = `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version      INTEGER NOT NULL,
+		date_applied TIMESTAMP NOT NULL,
+		PRIMARY KEY (version)
+	)`
+	if _, err := db.Exec(qt); err != nil {
+		return errors.Wrap(err, "creating sqlite migrations table")
+	}
+
+	var current int
+	if err := db.Get(&current, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`); err != nil {
+		return errors.Wrap(err, "selecting sqlite schema version")
+	}
+
+	names, err := fs.Glob(files, "migrations/*.sql")
+	if err != nil {
+		return errors.Wrap(err, "listing sqlite migrations")
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		base := path.Base(name)
+		version, err := strconv.Atoi(strings.SplitN(base, "_", 2)[0])
+		if err != nil {
+			return errors.Errorf("sqlite migration %s is not named NNNN_description.sql", base)
+		}
+		if version <= current {
+			continue
+		}
+
+		script, err := files.ReadFile(name)
+		if err != nil {
+			return errors.Wrapf(err, "reading sqlite migration %s", base)
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			return errors.Wrap(err, "beginning transaction")
+		}
+		if _, err := tx.Exec(string(script)); err != nil {
+			tx.Rollback()
+			return errors.Wrapf(err, "applying sqlite migration %s", base)
+		}
+		const qi = `INSERT INTO schema_migrations (version, date_applied) VALUES ($1, $2)`
+		if _, err := tx.Exec(qi, version, time.Now().UTC()); err != nil {
+			tx.Rollback()
+			return errors.Wrapf(err, "recording sqlite migration %s", base)
+		}
+		if err := tx.Commit(); err != nil {
+			return errors.Wrapf(err, "committing sqlite migration %s", base)
+		}
+	}
+
+	return nil
+}
+
+// day truncates the time to midnight UTC of its day.
+func day(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,108 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/restaurant"
+	"github.com/remisb/restaurant/internal/schema"
+	"github.com/remisb/restaurant/internal/tests"
+	"github.com/remisb/restaurant/internal/vote"
+	_ "modernc.org/sqlite"
+)
+
+// TestStores validates the SQLite stores behave like the PostgreSQL ones.
+func TestStores(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "restaurant.db"))
+	if err != nil {
+		t.Fatalf("opening database: %s", err)
+	}
+	defer db.Close()
+
+	if err := schema.Seed(db); err != nil {
+		t.Fatalf("seeding database: %s", err)
+	}
+
+	ctx := tests.Context()
+	now := time.Date(2020, time.March, 2, 9, 0, 0, 0, time.UTC)
+	owner := auth.NewClaims("5cf37266-3473-4006-984f-9325122678b7", []string{auth.RoleUser}, now, time.Hour)
+
+	t.Log("Given the need to keep the data in SQLite.")
+	{
+		t.Log("\tTest 0:\tWhen working with restaurants.")
+		{
+			restaurants := NewRestaurants(db)
+
+			r, err := restaurants.Create(ctx, owner, restaurant.NewRestaurant{Name: "Gaspar", Address: "Vilnius"}, now)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to create a restaurant : %s.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to create a restaurant.", tests.Success)
+
+			upd := restaurant.UpdateRestaurant{Name: tests.StringPointer("Gaspar 2"), Version: tests.IntPointer(1)}
+			if _, err := restaurants.Update(ctx, owner, r.ID, upd, now); err != nil {
+				t.Fatalf("\t%s\tShould be able to update the restaurant : %s.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to update the restaurant.", tests.Success)
+
+			if _, err := restaurants.Update(ctx, owner, r.ID, upd, now); err != restaurant.ErrVersionConflict {
+				t.Fatalf("\t%s\tShould reject a stale update : %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould reject a stale update.", tests.Success)
+
+			if err := restaurants.Delete(ctx, r.ID, now); err != nil {
+				t.Fatalf("\t%s\tShould be able to delete the restaurant : %s.", tests.Failed, err)
+			}
+			if _, err := restaurants.Retrieve(ctx, r.ID); err != restaurant.ErrNotFound {
+				t.Fatalf("\t%s\tShould not find the deleted restaurant : %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould not find the deleted restaurant.", tests.Success)
+		}
+
+		t.Log("\tTest 1:\tWhen listing the seeded menus.")
+		{
+			from := time.Date(2020, time.March, 1, 0, 0, 0, 0, time.UTC)
+			menus, err := NewMenus(db).ListMenus(ctx, "5828612a-1f8a-403c-b6d1-6cb66fbf0c66", from)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to list menus : %s.", tests.Failed, err)
+			}
+			if len(menus) != 2 {
+				t.Fatalf("\t%s\tShould get both menus from the date : got %d.", tests.Failed, len(menus))
+			}
+			t.Logf("\t%s\tShould get both menus from the date.", tests.Success)
+		}
+
+		t.Log("\tTest 2:\tWhen voting.")
+		{
+			policy := vote.Policy{MaxDaysAhead: 7, Deadline: 11 * time.Hour}
+			votes := NewVotes(db, policy)
+
+			nv := vote.NewVote{RestaurantID: "5828612a-1f8a-403c-b6d1-6cb66fbf0c66"}
+			if _, err := votes.Cast(ctx, owner, nv, policy, now); err != nil {
+				t.Fatalf("\t%s\tShould be able to vote : %s.", tests.Failed, err)
+			}
+			if _, err := votes.Cast(ctx, owner, nv, policy, now.Add(time.Minute)); err != nil {
+				t.Fatalf("\t%s\tShould be able to vote again : %s.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be able to vote.", tests.Success)
+
+			tallies, err := votes.Tallies(ctx, now)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to tally the votes : %s.", tests.Failed, err)
+			}
+			if len(tallies) != 1 || tallies[0].Votes != 1 {
+				t.Fatalf("\t%s\tShould count a single vote per user : got %+v.", tests.Failed, tallies)
+			}
+			t.Logf("\t%s\tShould count a single vote per user.", tests.Success)
+
+			if _, err := votes.Cast(ctx, owner, nv, policy, now.Add(3*time.Hour)); err != vote.ErrLocked {
+				t.Fatalf("\t%s\tShould reject changing the vote after the deadline : %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould reject changing the vote after the deadline.", tests.Success)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/authz"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/user"
+	"go.opentelemetry.io/otel"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Users is a user.Store backed by SQLite.
+type Users struct {
+	db *sqlx.DB
+}
+
+// NewUsers constructs a Users store on top of the database.
+func NewUsers(db *sqlx.DB) *Users {
+	return &Users{db: db}
+}
+
+// List implements the user.Store interface.
+func (s *Users) List(ctx context.Context) ([]user.User, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.sqlite.Users.List")
+	defer span.End()
+
+	users := []user.User{}
+	const q = `SELECT * FROM users WHERE deleted_at IS NULL`
+	if err := s.db.SelectContext(ctx, &users, q); err != nil {
+		return nil, errors.Wrap(err, "selecting users")
+	}
+
+	return users, nil
+}
+
+// Retrieve implements the user.Store interface.
+func (s *Users) Retrieve(ctx context.Context, claims auth.Claims, id string) (*user.User, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.sqlite.Users.Retrieve")
+	defer span.End()
+
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, user.ErrInvalidID
+	}
+
+	if !authz.Allowed(claims, authz.Read, authz.Resource{Kind: authz.User, Owner: id}) {
+		return nil, user.ErrForbidden
+	}
+
+	var u user.User
+	const q = `SELECT * FROM users WHERE user_id = ? AND deleted_at IS NULL`
+	if err := s.db.GetContext(ctx, &u, q, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, user.ErrNotFound
+		}
+		return nil, errors.Wrapf(err, "selecting user %q", id)
+	}
+
+	return &u, nil
+}
+
+// Create implements the user.Store interface.
+func (s *Users) Create(ctx context.Context, n user.NewUser, now time.Time) (*user.User, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.sqlite.Users.Create")
+	defer span.End()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(n.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, errors.Wrap(err, "generating password hash")
+	}
+
+	u := user.User{
+		ID:           uuid.New().String(),
+		Name:         n.Name,
+		Email:        n.Email,
+		PasswordHash: hash,
+		Roles:        n.Roles,
+		Version:      1,
+		DateCreated:  now.UTC(),
+		DateUpdated:  now.UTC(),
+	}
+
+	const q = `INSERT INTO users
+		(user_id, name, email, password_hash, roles, date_created, date_updated)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`
+	if _, err := s.db.ExecContext(ctx, q, u.ID, u.Name, u.Email, u.PasswordHash, u.Roles, u.DateCreated, u.DateUpdated); err != nil {
+		return nil, errors.Wrap(err, "inserting user")
+	}
+
+	return &u, nil
+}
+
+// Update implements the user.Store interface.
+func (s *Users) Update(ctx context.Context, claims auth.Claims, id string, upd user.UpdateUser, now time.Time) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.sqlite.Users.Update")
+	defer span.End()
+
+	u, err := s.Retrieve(ctx, claims, id)
+	if err != nil {
+		return err
+	}
+
+	if upd.Version != nil && *upd.Version != u.Version {
+		return user.ErrVersionConflict
+	}
+
+	if upd.Name != nil {
+		u.Name = *upd.Name
+	}
+	if upd.Email != nil {
+		u.Email = *upd.Email
+	}
+	if upd.Roles != nil {
+		u.Roles = upd.Roles
+	}
+	if upd.Password != nil {
+		pw, err := bcrypt.GenerateFromPassword([]byte(*upd.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return errors.Wrap(err, "generating password hash")
+		}
+		u.PasswordHash = pw
+	}
+	u.DateUpdated = now.UTC()
+
+	const q = `UPDATE users SET
+		name = ?, email = ?, roles = ?, password_hash = ?, date_updated = ?,
+		version = version + 1
+		WHERE user_id = ? AND version = ? AND deleted_at IS NULL`
+	res, err := s.db.ExecContext(ctx, q,
+		u.Name, u.Email, u.Roles, u.PasswordHash, u.DateUpdated,
+		id, u.Version,
+	)
+	if err != nil {
+		return errors.Wrap(err, "updating user")
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return user.ErrVersionConflict
+	}
+
+	return nil
+}
+
+// Delete implements the user.Store interface.
+func (s *Users) Delete(ctx context.Context, id string, now time.Time) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.sqlite.Users.Delete")
+	defer span.End()
+
+	if _, err := uuid.Parse(id); err != nil {
+		return user.ErrInvalidID
+	}
+
+	const q = `UPDATE users SET deleted_at = ?, version = version + 1
+		WHERE user_id = ? AND deleted_at IS NULL`
+	if _, err := s.db.ExecContext(ctx, q, now.UTC(), id); err != nil {
+		return errors.Wrapf(err, "deleting user %s", id)
+	}
+
+	return nil
+}
+
+// Authenticate implements the user.Store interface.
+func (s *Users) Authenticate(ctx context.Context, now time.Time, email, password string) (auth.Claims, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.sqlite.Users.Authenticate")
+	defer span.End()
+
+	var u user.User
+	const q = `SELECT * FROM users WHERE email = ? AND deleted_at IS NULL`
+	if err := s.db.GetContext(ctx, &u, q, email); err != nil {
+		if err == sql.ErrNoRows {
+			return auth.Claims{}, user.ErrAuthenticationFailure
+		}
+		return auth.Claims{}, errors.Wrap(err, "selecting single user")
+	}
+
+	if err := bcrypt.CompareHashAndPassword(u.PasswordHash, []byte(password)); err != nil {
+		return auth.Claims{}, user.ErrAuthenticationFailure
+	}
+
+	return auth.NewClaims(u.ID, u.Roles, now, time.Hour), nil
+}
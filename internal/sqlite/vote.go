@@ -0,0 +1,242 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/team"
+	"github.com/remisb/restaurant/internal/vote"
+	"go.opentelemetry.io/otel"
+)
+
+// Votes is a vote.Store backed by SQLite. There is no scheduler storing the
+// winners so the winner of a date is computed from its votes when asked for
+// once voting has closed.
+type Votes struct {
+	db     *sqlx.DB
+	policy vote.Policy
+}
+
+// NewVotes constructs a Votes store on top of the database. The policy tells
+// when the winner of a date is known.
+func NewVotes(db *sqlx.DB, policy vote.Policy) *Votes {
+	return &Votes{db: db, policy: policy}
+}
+
+// Cast implements the vote.Store interface.
+func (s *Votes) Cast(ctx context.Context, user auth.Claims, nv vote.NewVote, policy vote.Policy, now time.Time) (*vote.Vote, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.sqlite.Votes.Cast")
+	defer span.End()
+
+	date, err := vote.ParseDate(nv.Date, policy.Today(now))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := policy.Check(date, now); err != nil {
+		if err == vote.ErrClosed {
+			if voted, verr := s.voted(ctx, date, user.Subject); verr == nil && voted {
+				return nil, vote.ErrLocked
+			}
+		}
+		return nil, err
+	}
+
+	// Weights and delegations need PostgreSQL so every user casts a single
+	// vote of their own.
+	if nv.OnBehalfOf != "" && nv.OnBehalfOf != user.Subject {
+		return nil, vote.ErrNotDelegated
+	}
+	if nv.Count > 1 {
+		return nil, vote.ErrWeightExceeded
+	}
+
+	if _, err := NewRestaurants(s.db).Retrieve(ctx, nv.RestaurantID); err != nil {
+		return nil, err
+	}
+
+	v := vote.Vote{
+		Date:         date,
+		UserID:       user.Subject,
+		RestaurantID: nv.RestaurantID,
+		Weight:       1,
+		TimeVoted:    now.UTC(),
+	}
+
+	const q = `INSERT INTO vote
+		(date, user_id, restaurant_id, time_voted)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (date, user_id) DO UPDATE SET
+		restaurant_id = excluded.restaurant_id,
+		time_voted = excluded.time_voted`
+	if _, err := s.db.ExecContext(ctx, q, v.Date, v.UserID, v.RestaurantID, v.TimeVoted); err != nil {
+		return nil, errors.Wrap(err, "inserting vote")
+	}
+
+	return &v, nil
+}
+
+// Retract implements the vote.Store interface.
+func (s *Votes) Retract(ctx context.Context, user auth.Claims, date time.Time, policy vote.Policy, now time.Time) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.sqlite.Votes.Retract")
+	defer span.End()
+
+	if err := policy.Check(date, now); err == vote.ErrClosed {
+		return vote.ErrLocked
+	}
+
+	const q = `DELETE FROM vote WHERE date = ? AND user_id = ?`
+	res, err := s.db.ExecContext(ctx, q, day(date), user.Subject)
+	if err != nil {
+		return errors.Wrap(err, "deleting vote")
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return vote.ErrNotVoted
+	}
+
+	return nil
+}
+
+// voted reports whether the user has a vote for the date.
+func (s *Votes) voted(ctx context.Context, date time.Time, userID string) (bool, error) {
+	var n int
+	const q = `SELECT COUNT(*) FROM vote WHERE date = ? AND user_id = ?`
+	if err := s.db.GetContext(ctx, &n, q, day(date), userID); err != nil {
+		return false, errors.Wrap(err, "counting votes")
+	}
+	return n > 0, nil
+}
+
+// Tallies implements the vote.Store interface.
+func (s *Votes) Tallies(ctx context.Context, date time.Time) ([]vote.Tally, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.sqlite.Votes.Tallies")
+	defer span.End()
+
+	tallies := []vote.Tally{}
+	const q = `SELECT restaurant_id, COUNT(*) AS votes FROM vote
+		WHERE date = ?
+		GROUP BY restaurant_id
+		ORDER BY votes DESC, MIN(time_voted)`
+	if err := s.db.SelectContext(ctx, &tallies, q, day(date)); err != nil {
+		return nil, errors.Wrap(err, "selecting tallies")
+	}
+
+	return tallies, nil
+}
+
+// RetrieveTally implements the vote.Store interface. The votes are counted,
+// there is no tally table in SQLite.
+func (s *Votes) RetrieveTally(ctx context.Context, restaurantID string, date time.Time) (*vote.Tally, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.sqlite.Votes.RetrieveTally")
+	defer span.End()
+
+	t := vote.Tally{RestaurantID: restaurantID}
+	const q = `SELECT COUNT(*) FROM vote WHERE date = ? AND restaurant_id = ?`
+	if err := s.db.GetContext(ctx, &t.Votes, q, day(date), restaurantID); err != nil {
+		return nil, errors.Wrap(err, "counting votes")
+	}
+
+	return &t, nil
+}
+
+// Voters implements the vote.Store interface.
+func (s *Votes) Voters(ctx context.Context, restaurantID string, date time.Time) ([]string, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.sqlite.Votes.Voters")
+	defer span.End()
+
+	r, err := NewRestaurants(s.db).Retrieve(ctx, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	if r.AnonymousVotes {
+		return nil, vote.ErrAnonymous
+	}
+
+	voters := []string{}
+	const q = `SELECT user_id FROM vote WHERE date = ? AND restaurant_id = ?
+		ORDER BY datetime(time_voted)`
+	if err := s.db.SelectContext(ctx, &voters, q, day(date), restaurantID); err != nil {
+		return nil, errors.Wrap(err, "selecting voters")
+	}
+
+	return voters, nil
+}
+
+// RetrieveWinner implements the vote.Store interface.
+func (s *Votes) RetrieveWinner(ctx context.Context, date time.Time) (*vote.Winner, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.sqlite.Votes.RetrieveWinner")
+	defer span.End()
+
+	now := time.Now()
+	if s.policy.Check(date, now) != vote.ErrClosed {
+		return nil, vote.ErrNoWinner
+	}
+
+	tallies, err := s.Tallies(ctx, date)
+	if err != nil {
+		return nil, err
+	}
+	if len(tallies) == 0 {
+		return nil, vote.ErrNoWinner
+	}
+
+	w := vote.Winner{
+		Date:         date,
+		RestaurantID: tallies[0].RestaurantID,
+		Votes:        tallies[0].Votes,
+		DateComputed: now.UTC(),
+	}
+	return &w, nil
+}
+
+// TeamTallies implements the vote.Store interface. Teams need PostgreSQL so
+// none is ever found.
+func (s *Votes) TeamTallies(ctx context.Context, teamID string, date time.Time) ([]vote.Tally, error) {
+	return nil, team.ErrNotFound
+}
+
+// RetrieveTeamWinner implements the vote.Store interface. Teams need
+// PostgreSQL so none is ever found.
+func (s *Votes) RetrieveTeamWinner(ctx context.Context, teamID string, date time.Time) (*vote.Winner, error) {
+	return nil, team.ErrNotFound
+}
+
+// Today implements the vote.Store interface. Organizations need PostgreSQL
+// so the time zone of the policy is used.
+func (s *Votes) Today(ctx context.Context, policy vote.Policy, now time.Time) (time.Time, error) {
+	return policy.Today(now), nil
+}
+
+// History implements the vote.Store interface. Organizations need
+// PostgreSQL so only the restaurants make their votes anonymous.
+func (s *Votes) History(ctx context.Context, from, to time.Time, fn func(vote.Vote) error) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.sqlite.Votes.History")
+	defer span.End()
+
+	const q = `SELECT v.date, v.restaurant_id, v.time_voted,
+		CASE WHEN COALESCE(r.anonymous_votes, FALSE) THEN '' ELSE v.user_id END AS user_id
+		FROM vote AS v
+		LEFT JOIN restaurant AS r ON r.restaurant_id = v.restaurant_id
+		WHERE datetime(v.date) >= datetime(?) AND datetime(v.date) <= datetime(?)
+		ORDER BY datetime(v.date), datetime(v.time_voted)`
+	rows, err := s.db.QueryxContext(ctx, q, day(from), day(to))
+	if err != nil {
+		return errors.Wrap(err, "selecting votes")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var v vote.Vote
+		if err := rows.StructScan(&v); err != nil {
+			return errors.Wrap(err, "scanning vote")
+		}
+		if err := fn(v); err != nil {
+			return err
+		}
+	}
+
+	return errors.Wrap(rows.Err(), "reading votes")
+}
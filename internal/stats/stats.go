@@ -0,0 +1,197 @@
+// Package stats measures how the restaurants are doing for their owners: the
+// votes they receive and win, their ratings, orders and the views of their
+// menus.
+package stats
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/platform/database"
+	"go.opentelemetry.io/otel"
+)
+
+// Restaurant is the dashboard of a restaurant for the dates From through To.
+// Rating is the average of all the ratings of the restaurant, nil until it
+// is rated, and Orders leaves the cancelled orders out.
+type Restaurant struct {
+	RestaurantID string      `json:"restaurant_id"`
+	From         time.Time   `json:"from"`
+	To           time.Time   `json:"to"`
+	Votes        int         `db:"votes" json:"votes"`
+	DailyVotes   []DailyVote `json:"daily_votes"`
+	Wins         int         `db:"wins" json:"wins"`
+	Rating       *float64    `db:"rating" json:"rating"`
+	Ratings      int         `db:"ratings" json:"ratings"`
+	Orders       int         `db:"orders" json:"orders"`
+	OrderTotal   int         `db:"order_total" json:"order_total"`
+	MenuViews    int         `db:"menu_views" json:"menu_views"`
+}
+
+// DailyVote is the number of votes a restaurant received for a date.
+type DailyVote struct {
+	Date  time.Time `db:"date" json:"date"`
+	Votes int       `db:"votes" json:"votes"`
+}
+
+// NewRating is what we require from users when rating a restaurant.
+type NewRating struct {
+	Score int `json:"score" validate:"required,min=1,max=5"`
+}
+
+// Store computes the dashboards from the replica and caches them for a
+// while, as owners reload them far more often than they change. The ratings
+// and views are written to the primary. A nil Store counts no views.
+type Store struct {
+	db  *database.DB
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[cacheKey]cacheEntry
+}
+
+// cacheKey identifies a cached dashboard.
+type cacheKey struct {
+	restaurantID string
+	from, to     time.Time
+}
+
+// cacheEntry is a cached dashboard and when it goes stale.
+type cacheEntry struct {
+	stats   Restaurant
+	expires time.Time
+}
+
+// NewStore constructs a Store caching the dashboards for ttl.
+func NewStore(db *database.DB, ttl time.Duration) *Store {
+	return &Store{
+		db:      db,
+		ttl:     ttl,
+		entries: make(map[cacheKey]cacheEntry),
+	}
+}
+
+// Restaurant returns the dashboard of the restaurant for the dates from
+// through to.
+func (s *Store) Restaurant(ctx context.Context, restaurantID string, from, to, now time.Time) (*Restaurant, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.stats.Restaurant")
+	defer span.End()
+
+	key := cacheKey{restaurantID: restaurantID, from: from, to: to}
+
+	s.mu.Lock()
+	e, ok := s.entries[key]
+	s.mu.Unlock()
+	if ok && now.Before(e.expires) {
+		return &e.stats, nil
+	}
+
+	st, err := compute(ctx, s.db.Replica(), restaurantID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Stale entries are dropped on the way so the cache only holds the
+	// dashboards viewed within ttl.
+	for k, e := range s.entries {
+		if !now.Before(e.expires) {
+			delete(s.entries, k)
+		}
+	}
+	s.entries[key] = cacheEntry{stats: *st, expires: now.Add(s.ttl)}
+
+	return st, nil
+}
+
+// Rate sets the rating of the restaurant by the user, replacing the previous
+// one, and drops the cached dashboards of the restaurant.
+func (s *Store) Rate(ctx context.Context, userID, restaurantID string, nr NewRating, now time.Time) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.stats.Rate")
+	defer span.End()
+
+	const q = `INSERT INTO restaurant_rating (restaurant_id, user_id, score, date_updated)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (restaurant_id, user_id) DO UPDATE SET score = EXCLUDED.score, date_updated = EXCLUDED.date_updated`
+	if _, err := s.db.Primary().ExecContext(ctx, q, restaurantID, userID, nr.Score, now.UTC()); err != nil {
+		return errors.Wrap(err, "inserting rating")
+	}
+
+	s.Invalidate(restaurantID)
+
+	return nil
+}
+
+// Invalidate drops the cached dashboards of the restaurant, or every cached
+// dashboard when restaurantID is empty, so they are computed again.
+func (s *Store) Invalidate(restaurantID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for k := range s.entries {
+		if restaurantID == "" || k.restaurantID == restaurantID {
+			delete(s.entries, k)
+		}
+	}
+}
+
+// CountView counts a view of the menu.
+func (s *Store) CountView(ctx context.Context, menuID string) error {
+	if s == nil {
+		return nil
+	}
+
+	ctx, span := otel.Tracer("").Start(ctx, "internal.stats.CountView")
+	defer span.End()
+
+	const q = `INSERT INTO menu_view (menu_id, views) VALUES ($1, 1)
+		ON CONFLICT (menu_id) DO UPDATE SET views = menu_view.views + 1`
+	if _, err := s.db.Primary().ExecContext(ctx, q, menuID); err != nil {
+		return errors.Wrapf(err, "counting view of menu %s", menuID)
+	}
+
+	return nil
+}
+
+// compute computes the dashboard of the restaurant from the database.
+func compute(ctx context.Context, db *sqlx.DB, restaurantID string, from, to time.Time) (*Restaurant, error) {
+	st := Restaurant{
+		RestaurantID: restaurantID,
+		From:         from,
+		To:           to,
+		DailyVotes:   []DailyVote{},
+	}
+
+	const qd = `SELECT date, votes FROM menu_vote_tally
+		WHERE restaurant_id = $1 AND date >= $2 AND date <= $3 AND votes > 0
+		ORDER BY date`
+	if err := db.SelectContext(ctx, &st.DailyVotes, qd, restaurantID, from, to); err != nil {
+		return nil, errors.Wrap(err, "selecting daily votes")
+	}
+	for _, d := range st.DailyVotes {
+		st.Votes += d.Votes
+	}
+
+	const q = `SELECT
+		(SELECT COUNT(*) FROM winner
+			WHERE restaurant_id = $1 AND date >= $2 AND date <= $3) AS wins,
+		(SELECT AVG(score)::float8 FROM restaurant_rating WHERE restaurant_id = $1) AS rating,
+		(SELECT COUNT(*) FROM restaurant_rating WHERE restaurant_id = $1) AS ratings,
+		(SELECT COUNT(*) FROM orders
+			WHERE restaurant_id = $1 AND date >= $2 AND date <= $3 AND status <> 'CANCELLED') AS orders,
+		(SELECT COALESCE(SUM(total), 0) FROM orders
+			WHERE restaurant_id = $1 AND date >= $2 AND date <= $3 AND status <> 'CANCELLED') AS order_total,
+		(SELECT COALESCE(SUM(v.views), 0) FROM menu_view AS v
+			JOIN menu AS m ON m.menu_id = v.menu_id
+			WHERE m.restaurant_id = $1 AND m.date >= $2 AND m.date <= $3) AS menu_views`
+	if err := db.GetContext(ctx, &st, q, restaurantID, from, to); err != nil {
+		return nil, errors.Wrap(err, "selecting restaurant stats")
+	}
+
+	return &st, nil
+}
@@ -0,0 +1,48 @@
+package stats
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// TestCache validates dashboards are served from the cache until they go
+// stale.
+func TestCache(t *testing.T) {
+	now := time.Date(2019, time.March, 24, 12, 0, 0, 0, time.UTC)
+	from, to := now.AddDate(0, 0, -30), now
+	const id = "a2b0639f-2cc6-44b8-b97b-15d69dbb511e"
+
+	// The store has no database so anything not cached fails loudly.
+	s := NewStore(nil, time.Minute)
+	s.entries[cacheKey{restaurantID: id, from: from, to: to}] = cacheEntry{
+		stats:   Restaurant{RestaurantID: id, Votes: 12},
+		expires: now.Add(time.Minute),
+	}
+
+	t.Log("Given the need to serve dashboards without recomputing them.")
+	{
+		t.Log("\tTest 0:\tWhen the dashboard was computed within the ttl.")
+		{
+			st, err := s.Restaurant(context.Background(), id, from, to, now.Add(30*time.Second))
+			if err != nil {
+				t.Fatalf("\t%s\tShould serve the dashboard : %s.", tests.Failed, err)
+			}
+			if st.Votes != 12 {
+				t.Fatalf("\t%s\tShould serve the cached dashboard : got %d votes.", tests.Failed, st.Votes)
+			}
+			t.Logf("\t%s\tShould serve the cached dashboard.", tests.Success)
+		}
+
+		t.Log("\tTest 1:\tWhen counting a view without a store.")
+		{
+			var s *Store
+			if err := s.CountView(context.Background(), id); err != nil {
+				t.Fatalf("\t%s\tShould ignore the view : %s.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould ignore the view.", tests.Success)
+		}
+	}
+}
@@ -0,0 +1,284 @@
+// Package tag manages the taxonomy of tags, like italian, vegan or sushi,
+// describing the restaurants of an organization.
+package tag
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/authz"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/database"
+	"github.com/remisb/restaurant/internal/restaurant"
+	"go.opentelemetry.io/otel"
+)
+
+// Predefined errors identify expected failure conditions.
+var (
+	// ErrNotFound is used when a specific Tag is requested but does not exist
+	// in the organization of the caller.
+	ErrNotFound = errors.New("Tag not found")
+
+	// ErrInvalidSlug is used when a slug is not made of lowercase letters,
+	// digits and dashes.
+	ErrInvalidSlug = errors.New("Tag slug is not in its proper form")
+
+	// ErrDuplicateSlug occurs when creating a tag with the slug of another tag
+	// of the organization.
+	ErrDuplicateSlug = errors.New("Tag slug is already used")
+)
+
+// validSlug matches the slugs identifying the tags in URLs and queries.
+var validSlug = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// Tag describes restaurants sharing a trait, like their cuisine.
+type Tag struct {
+	OrgID       string    `db:"org_id" json:"-"`
+	Slug        string    `db:"slug" json:"slug"`
+	Name        string    `db:"name" json:"name"`
+	DateCreated time.Time `db:"date_created" json:"date_created"`
+	DateUpdated time.Time `db:"date_updated" json:"date_updated"`
+
+	// Restaurants is the number of restaurants having the tag, letting
+	// clients build filters showing how many restaurants each one keeps.
+	Restaurants int `db:"restaurants" json:"restaurants"`
+}
+
+// NewTag is what we require from admins when creating a Tag. The slug is
+// derived from the name when left out.
+type NewTag struct {
+	Slug string `json:"slug" validate:"omitempty,max=32"`
+	Name string `json:"name" validate:"required"`
+}
+
+// UpdateTag defines what information may be provided to modify an existing
+// Tag. The slug identifying it never changes.
+type UpdateTag struct {
+	Name *string `json:"name" validate:"omitempty,min=1"`
+}
+
+// Slugify derives a slug from the name of a tag, "Middle Eastern" becoming
+// "middle-eastern".
+func Slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z' || r >= '0' && r <= '9':
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		default:
+			dash = true
+		}
+	}
+	return b.String()
+}
+
+// selectTags selects the tags along with the number of live restaurants of
+// their organization having them.
+const selectTags = `SELECT t.*,
+	(SELECT COUNT(*) FROM restaurant AS r
+		WHERE r.org_id = t.org_id AND r.deleted_at IS NULL AND r.tags @> ARRAY[t.slug]) AS restaurants
+	FROM tag AS t`
+
+// Create adds a tag to the organization of the user.
+func Create(ctx context.Context, db *sqlx.DB, user auth.Claims, nt NewTag, now time.Time) (*Tag, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.tag.Create")
+	defer span.End()
+
+	slug := nt.Slug
+	if slug == "" {
+		slug = Slugify(nt.Name)
+	}
+	if !validSlug.MatchString(slug) {
+		return nil, ErrInvalidSlug
+	}
+
+	t := Tag{
+		OrgID:       user.Org(),
+		Slug:        slug,
+		Name:        nt.Name,
+		DateCreated: now.UTC(),
+		DateUpdated: now.UTC(),
+	}
+
+	const q = `INSERT INTO tag
+		(org_id, slug, name, date_created, date_updated)
+		VALUES ($1, $2, $3, $4, $5)`
+	if _, err := db.ExecContext(ctx, q, t.OrgID, t.Slug, t.Name, t.DateCreated, t.DateUpdated); err != nil {
+		if v, ok := database.AsViolation(err); ok && v.Unique {
+			return nil, ErrDuplicateSlug
+		}
+		return nil, errors.Wrap(err, "inserting tag")
+	}
+
+	return &t, nil
+}
+
+// List returns the tags of the organization of the claims in ctx by name.
+func List(ctx context.Context, db *sqlx.DB) ([]Tag, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.tag.List")
+	defer span.End()
+
+	tags := []Tag{}
+	const q = selectTags + ` WHERE ($1 = '' OR t.org_id::text = $1) ORDER BY t.name`
+	if err := db.SelectContext(ctx, &tags, q, auth.Org(ctx)); err != nil {
+		return nil, errors.Wrap(err, "selecting tags")
+	}
+
+	return tags, nil
+}
+
+// Retrieve finds the tag identified by a given slug in the organization of
+// the claims in ctx.
+func Retrieve(ctx context.Context, db *sqlx.DB, slug string) (*Tag, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.tag.Retrieve")
+	defer span.End()
+
+	if !validSlug.MatchString(slug) {
+		return nil, ErrInvalidSlug
+	}
+
+	var t Tag
+	const q = selectTags + ` WHERE t.slug = $1 AND ($2 = '' OR t.org_id::text = $2)`
+	if err := db.GetContext(ctx, &t, q, slug, auth.Org(ctx)); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, errors.Wrapf(err, "selecting tag %q", slug)
+	}
+
+	return &t, nil
+}
+
+// Update modifies data about a Tag.
+func Update(ctx context.Context, db *sqlx.DB, slug string, ut UpdateTag, now time.Time) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.tag.Update")
+	defer span.End()
+
+	t, err := Retrieve(ctx, db, slug)
+	if err != nil {
+		return err
+	}
+
+	if ut.Name != nil {
+		t.Name = *ut.Name
+	}
+	t.DateUpdated = now.UTC()
+
+	const q = `UPDATE tag SET
+		"name" = $3,
+		"date_updated" = $4
+		WHERE org_id = $1 AND slug = $2`
+	if _, err := db.ExecContext(ctx, q, t.OrgID, t.Slug, t.Name, t.DateUpdated); err != nil {
+		return errors.Wrapf(err, "updating tag %q", slug)
+	}
+
+	return nil
+}
+
+// Delete removes the tag from the organization and from its restaurants.
+func Delete(ctx context.Context, db *sqlx.DB, slug string, now time.Time) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.tag.Delete")
+	defer span.End()
+
+	t, err := Retrieve(ctx, db, slug)
+	if err != nil {
+		if err == ErrNotFound {
+			return nil
+		}
+		return err
+	}
+
+	tx, err := database.Begin(ctx, db)
+	if err != nil {
+		return errors.Wrap(err, "beginning transaction")
+	}
+	defer tx.Rollback()
+
+	const qr = `UPDATE restaurant SET
+		"tags" = array_remove(tags, $2),
+		"date_updated" = $3,
+		"version" = version + 1
+		WHERE org_id = $1 AND tags @> ARRAY[$2]`
+	if _, err := tx.ExecContext(ctx, qr, t.OrgID, t.Slug, now.UTC()); err != nil {
+		return errors.Wrapf(err, "removing tag %q from restaurants", slug)
+	}
+
+	const q = `DELETE FROM tag WHERE org_id = $1 AND slug = $2`
+	if _, err := tx.ExecContext(ctx, q, t.OrgID, t.Slug); err != nil {
+		return errors.Wrapf(err, "deleting tag %q", slug)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, "committing tag deletion")
+	}
+
+	return nil
+}
+
+// Assign replaces the tags of the identified restaurant. Every tag must be
+// one of the organization of the restaurant. Only the owner of the
+// restaurant or an admin may tag it.
+func Assign(ctx context.Context, db *sqlx.DB, user auth.Claims, restaurantID string, slugs []string, now time.Time) (*restaurant.Restaurant, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.tag.Assign")
+	defer span.End()
+
+	r, err := restaurant.Retrieve(ctx, db, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	if !authz.Allowed(user, authz.Update, authz.Resource{Kind: authz.Restaurant, Owner: r.OwnerUserID}) {
+		return nil, restaurant.ErrForbidden
+	}
+
+	tags := pq.StringArray{}
+	seen := make(map[string]bool, len(slugs))
+	for _, s := range slugs {
+		if !validSlug.MatchString(s) {
+			return nil, ErrInvalidSlug
+		}
+		if !seen[s] {
+			seen[s] = true
+			tags = append(tags, s)
+		}
+	}
+	sort.Strings(tags)
+
+	var known int
+	const qk = `SELECT COUNT(*) FROM tag WHERE org_id = $1 AND slug = ANY($2)`
+	if err := db.GetContext(ctx, &known, qk, r.OrgID, tags); err != nil {
+		return nil, errors.Wrap(err, "counting tags")
+	}
+	if known != len(tags) {
+		return nil, ErrNotFound
+	}
+
+	const q = `UPDATE restaurant SET
+		"tags" = $2,
+		"date_updated" = $3,
+		"version" = version + 1
+		WHERE restaurant_id = $1 AND version = $4 AND deleted_at IS NULL`
+	res, err := database.Conn(ctx, db).ExecContext(ctx, q, r.ID, tags, now.UTC(), r.Version)
+	if err != nil {
+		return nil, errors.Wrapf(err, "tagging restaurant %s", r.ID)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return nil, restaurant.ErrVersionConflict
+	}
+
+	r.Tags = tags
+	r.DateUpdated = now.UTC()
+	r.Version++
+	return r, nil
+}
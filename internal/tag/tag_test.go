@@ -0,0 +1,37 @@
+package tag
+
+import (
+	"testing"
+
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// TestSlugify validates the slugs derived from the names of tags.
+func TestSlugify(t *testing.T) {
+	tt := []struct {
+		name string
+		slug string
+	}{
+		{"Italian", "italian"},
+		{"Middle Eastern", "middle-eastern"},
+		{"  Fish & Chips!", "fish-chips"},
+		{"Dim-Sum 2 Go", "dim-sum-2-go"},
+	}
+
+	t.Log("Given the need to derive slugs from tag names.")
+	{
+		for i, tc := range tt {
+			t.Logf("\tTest %d:\tWhen slugifying %q.", i, tc.name)
+			{
+				slug := Slugify(tc.name)
+				if slug != tc.slug {
+					t.Fatalf("\t%s\tShould get %q : got %q.", tests.Failed, tc.slug, slug)
+				}
+				if !validSlug.MatchString(slug) {
+					t.Fatalf("\t%s\tShould get a valid slug : got %q.", tests.Failed, slug)
+				}
+				t.Logf("\t%s\tShould get %q.", tests.Success, tc.slug)
+			}
+		}
+	}
+}
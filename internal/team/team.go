@@ -0,0 +1,243 @@
+// Package team manages the teams of an organization, like departments or
+// floors, whose members vote for lunch among themselves in addition to the
+// vote of the whole organization.
+package team
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"go.opentelemetry.io/otel"
+)
+
+// Predefined errors identify expected failure conditions.
+var (
+	// ErrNotFound is used when a specific Team is requested but does not exist
+	// in the organization of the caller.
+	ErrNotFound = errors.New("Team not found")
+
+	// ErrInvalidID is used when an invalid UUID is provided.
+	ErrInvalidID = errors.New("ID is not in its proper form")
+
+	// ErrUserNotFound is used when adding a user who is not a member of the
+	// organization of the team.
+	ErrUserNotFound = errors.New("User not found")
+)
+
+// Team is a group of users of an organization voting together.
+type Team struct {
+	ID          string     `db:"team_id" json:"id"`
+	OrgID       string     `db:"org_id" json:"org_id"`
+	Name        string     `db:"name" json:"name"`
+	DateCreated time.Time  `db:"date_created" json:"date_created"`
+	DateUpdated time.Time  `db:"date_updated" json:"date_updated"`
+	DeletedAt   *time.Time `db:"deleted_at" json:"-"`
+}
+
+// NewTeam is what we require from admins when creating a Team.
+type NewTeam struct {
+	Name string `json:"name" validate:"required"`
+}
+
+// UpdateTeam defines what information may be provided to modify an existing
+// Team.
+type UpdateTeam struct {
+	Name *string `json:"name" validate:"omitempty,min=1"`
+}
+
+// Member is a user of a Team.
+type Member struct {
+	UserID    string    `db:"user_id" json:"user_id"`
+	Name      string    `db:"name" json:"name"`
+	Email     string    `db:"email" json:"email"`
+	DateAdded time.Time `db:"date_added" json:"date_added"`
+}
+
+// Create adds a team to the organization of the user.
+func Create(ctx context.Context, db *sqlx.DB, user auth.Claims, nt NewTeam, now time.Time) (*Team, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.team.Create")
+	defer span.End()
+
+	t := Team{
+		ID:          uuid.New().String(),
+		OrgID:       user.Org(),
+		Name:        nt.Name,
+		DateCreated: now.UTC(),
+		DateUpdated: now.UTC(),
+	}
+
+	const q = `INSERT INTO team
+		(team_id, org_id, name, date_created, date_updated)
+		VALUES ($1, $2, $3, $4, $5)`
+	if _, err := db.ExecContext(ctx, q, t.ID, t.OrgID, t.Name, t.DateCreated, t.DateUpdated); err != nil {
+		return nil, errors.Wrap(err, "inserting team")
+	}
+
+	return &t, nil
+}
+
+// List returns the teams of the organization of the claims in ctx by name.
+func List(ctx context.Context, db *sqlx.DB) ([]Team, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.team.List")
+	defer span.End()
+
+	teams := []Team{}
+	const q = `SELECT * FROM team WHERE deleted_at IS NULL AND ($1 = '' OR org_id::text = $1)
+		ORDER BY name`
+	if err := db.SelectContext(ctx, &teams, q, auth.Org(ctx)); err != nil {
+		return nil, errors.Wrap(err, "selecting teams")
+	}
+
+	return teams, nil
+}
+
+// Retrieve finds the team identified by a given ID in the organization of
+// the claims in ctx.
+func Retrieve(ctx context.Context, db *sqlx.DB, id string) (*Team, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.team.Retrieve")
+	defer span.End()
+
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrInvalidID
+	}
+
+	var t Team
+	const q = `SELECT * FROM team WHERE team_id = $1 AND deleted_at IS NULL
+		AND ($2 = '' OR org_id::text = $2)`
+	if err := db.GetContext(ctx, &t, q, id, auth.Org(ctx)); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, errors.Wrapf(err, "selecting team %q", id)
+	}
+
+	return &t, nil
+}
+
+// Update modifies data about a Team.
+func Update(ctx context.Context, db *sqlx.DB, id string, ut UpdateTeam, now time.Time) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.team.Update")
+	defer span.End()
+
+	t, err := Retrieve(ctx, db, id)
+	if err != nil {
+		return err
+	}
+
+	if ut.Name != nil {
+		t.Name = *ut.Name
+	}
+	t.DateUpdated = now.UTC()
+
+	const q = `UPDATE team SET
+		"name" = $2,
+		"date_updated" = $3
+		WHERE team_id = $1`
+	if _, err := db.ExecContext(ctx, q, id, t.Name, t.DateUpdated); err != nil {
+		return errors.Wrapf(err, "updating team %s", id)
+	}
+
+	return nil
+}
+
+// Delete removes the team. Its members are left in the organization and the
+// winners it had are kept.
+func Delete(ctx context.Context, db *sqlx.DB, id string, now time.Time) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.team.Delete")
+	defer span.End()
+
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrInvalidID
+	}
+
+	const q = `UPDATE team SET
+		"deleted_at" = $2
+		WHERE team_id = $1 AND deleted_at IS NULL AND ($3 = '' OR org_id::text = $3)`
+	if _, err := db.ExecContext(ctx, q, id, now.UTC(), auth.Org(ctx)); err != nil {
+		return errors.Wrapf(err, "deleting team %s", id)
+	}
+
+	return nil
+}
+
+// AddMember adds the user to the team. The user must be a member of the
+// organization of the team. Adding a member again does nothing.
+func AddMember(ctx context.Context, db *sqlx.DB, id, userID string, now time.Time) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.team.AddMember")
+	defer span.End()
+
+	t, err := Retrieve(ctx, db, id)
+	if err != nil {
+		return err
+	}
+	if _, err := uuid.Parse(userID); err != nil {
+		return ErrInvalidID
+	}
+
+	const q = `INSERT INTO team_member
+		(team_id, user_id, date_added)
+		SELECT $1, user_id, $3 FROM users
+		WHERE user_id = $2 AND org_id = $4 AND deleted_at IS NULL
+		ON CONFLICT (team_id, user_id) DO NOTHING`
+	if _, err := db.ExecContext(ctx, q, id, userID, now.UTC(), t.OrgID); err != nil {
+		return errors.Wrapf(err, "adding user %s to team %s", userID, id)
+	}
+
+	var exists bool
+	const qe = `SELECT EXISTS (SELECT 1 FROM team_member WHERE team_id = $1 AND user_id = $2)`
+	if err := db.GetContext(ctx, &exists, qe, id, userID); err != nil {
+		return errors.Wrapf(err, "selecting member %s of team %s", userID, id)
+	}
+	if !exists {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// RemoveMember removes the user from the team. Removing a user who is not a
+// member does nothing.
+func RemoveMember(ctx context.Context, db *sqlx.DB, id, userID string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.team.RemoveMember")
+	defer span.End()
+
+	if _, err := Retrieve(ctx, db, id); err != nil {
+		return err
+	}
+	if _, err := uuid.Parse(userID); err != nil {
+		return ErrInvalidID
+	}
+
+	const q = `DELETE FROM team_member WHERE team_id = $1 AND user_id = $2`
+	if _, err := db.ExecContext(ctx, q, id, userID); err != nil {
+		return errors.Wrapf(err, "removing user %s from team %s", userID, id)
+	}
+
+	return nil
+}
+
+// Members returns the users of the team by name.
+func Members(ctx context.Context, db *sqlx.DB, id string) ([]Member, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.team.Members")
+	defer span.End()
+
+	if _, err := Retrieve(ctx, db, id); err != nil {
+		return nil, err
+	}
+
+	members := []Member{}
+	const q = `SELECT u.user_id, u.name, u.email, m.date_added FROM team_member AS m
+		JOIN users AS u ON u.user_id = m.user_id
+		WHERE m.team_id = $1 AND u.deleted_at IS NULL
+		ORDER BY u.name`
+	if err := db.SelectContext(ctx, &members, q, id); err != nil {
+		return nil, errors.Wrapf(err, "selecting members of team %s", id)
+	}
+
+	return members, nil
+}
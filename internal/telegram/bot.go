@@ -0,0 +1,272 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/organization"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/job"
+	"github.com/remisb/restaurant/internal/restaurant"
+	"github.com/remisb/restaurant/internal/vote"
+	"go.opentelemetry.io/otel"
+)
+
+// retryDelay is how long the bot waits after failing to get updates.
+const retryDelay = 5 * time.Second
+
+// help is the reply to the commands the bot does not know.
+const help = `Vote for lunch from here:
+/link CODE - link this chat to your account with a code from the app
+/menus - list today's menus
+/vote N - vote for the restaurant of menu N
+/unlink - unlink this chat from your account`
+
+// Bot answers the commands sent to it through Telegram. It is a
+// vote.Announcer telling every linked chat the winner.
+type Bot struct {
+	log     *log.Logger
+	db      *sqlx.DB
+	client  *Client
+	policy  vote.Policy
+	tracker *job.Tracker
+}
+
+// NewBot constructs a Bot answering through the client.
+func NewBot(log *log.Logger, db *sqlx.DB, client *Client, policy vote.Policy) *Bot {
+	return &Bot{
+		log:     log,
+		db:      db,
+		client:  client,
+		policy:  policy,
+		tracker: job.NewTracker("telegram"),
+	}
+}
+
+// Status reports the state of the bot to the health check.
+func (b *Bot) Status() job.Status {
+	return b.tracker.Status()
+}
+
+// Run answers the messages sent to the bot until the context is canceled.
+func (b *Bot) Run(ctx context.Context) {
+	var offset int64
+	for {
+		updates, err := b.client.Updates(ctx, offset)
+		if ctx.Err() != nil {
+			return
+		}
+		b.tracker.Record(err, time.Now())
+		if err != nil {
+			b.log.Printf("telegram : ERROR : %+v", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(retryDelay):
+			}
+			continue
+		}
+
+		for _, u := range updates {
+			offset = u.ID + 1
+			if u.Message == nil {
+				continue
+			}
+			reply := b.handle(ctx, u.Message.Chat.ID, u.Message.Text, time.Now())
+			if err := b.client.Send(ctx, u.Message.Chat.ID, reply); err != nil {
+				b.log.Printf("telegram : %d : ERROR : %+v", u.Message.Chat.ID, err)
+			}
+		}
+	}
+}
+
+// Channel implements the vote.Announcer interface.
+func (b *Bot) Channel() string {
+	return organization.ChannelTelegram
+}
+
+// Announce implements the vote.Announcer interface.
+func (b *Bot) Announce(ctx context.Context, w vote.Winner) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.telegram.Bot.Announce")
+	defer span.End()
+
+	var name string
+	const qr = `SELECT name FROM restaurant WHERE restaurant_id = $1`
+	if err := b.db.GetContext(ctx, &name, qr, w.RestaurantID); err != nil {
+		return errors.Wrap(err, "selecting restaurant name")
+	}
+
+	var chats []int64
+	const qc = `SELECT c.chat_id FROM telegram_chat AS c
+		JOIN users AS u ON u.user_id = c.user_id
+		WHERE u.org_id = $1 AND u.deleted_at IS NULL`
+	if err := b.db.SelectContext(ctx, &chats, qc, w.OrgID); err != nil {
+		return errors.Wrap(err, "selecting chats")
+	}
+
+	text := fmt.Sprintf("%s won the vote for lunch on %s with %d votes.", name, w.Date.Format("Monday, January 2"), w.Votes)
+
+	var failed error
+	for _, c := range chats {
+		if err := b.client.Send(ctx, c, text); err != nil {
+			failed = err
+		}
+	}
+	return failed
+}
+
+// handle runs the command of the text sent to the chat and returns the reply.
+func (b *Bot) handle(ctx context.Context, chatID int64, text string, now time.Time) string {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.telegram.Bot.handle")
+	defer span.End()
+
+	cmd, arg := parseCommand(text)
+
+	var (
+		reply string
+		err   error
+	)
+	switch cmd {
+	case "/link":
+		reply, err = b.link(ctx, chatID, arg, now)
+	case "/unlink":
+		reply, err = "This chat is no longer linked to your account.", Unlink(ctx, b.db, chatID)
+	case "/menus":
+		reply, err = b.menus(ctx, chatID, now)
+	case "/vote":
+		reply, err = b.vote(ctx, chatID, arg, now)
+	default:
+		reply = help
+	}
+
+	if err != nil {
+		b.log.Printf("telegram : %d : %s : ERROR : %+v", chatID, cmd, err)
+		return "Something went wrong, please try again later."
+	}
+	return reply
+}
+
+// link links the chat with the code.
+func (b *Bot) link(ctx context.Context, chatID int64, code string, now time.Time) (string, error) {
+	if code == "" {
+		return "Send /link followed by the code shown in the app.", nil
+	}
+
+	if _, err := Link(ctx, b.db, chatID, strings.ToUpper(code), now); err != nil {
+		if err == ErrInvalidCode {
+			return "This code is invalid or expired, get a new one in the app.", nil
+		}
+		return "", err
+	}
+
+	return "This chat is now linked to your account. Send /menus to see today's menus.", nil
+}
+
+// menus lists the numbered menus of the day in the organization of the user
+// linked to the chat.
+func (b *Bot) menus(ctx context.Context, chatID int64, now time.Time) (string, error) {
+	claims, err := ChatUser(ctx, b.db, chatID, now, time.Minute)
+	if err != nil {
+		if err == ErrNotLinked {
+			return "Link this chat to your account first with /link CODE.", nil
+		}
+		return "", err
+	}
+
+	date, err := vote.ParseDate("", now)
+	if err != nil {
+		return "", err
+	}
+
+	ms, err := menus(ctx, b.db, date, claims.Org())
+	if err != nil {
+		return "", err
+	}
+	if len(ms) == 0 {
+		return "There are no menus today.", nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Today's menus:")
+	for i, m := range ms {
+		fmt.Fprintf(&sb, "\n%d. %s: %s", i+1, m.Restaurant, m.Menu)
+	}
+	sb.WriteString("\nSend /vote N to vote for menu N.")
+
+	return sb.String(), nil
+}
+
+// vote casts the vote of the user linked to the chat for the restaurant of
+// the menu numbered as listed by menus.
+func (b *Bot) vote(ctx context.Context, chatID int64, arg string, now time.Time) (string, error) {
+	claims, err := ChatUser(ctx, b.db, chatID, now, time.Minute)
+	if err != nil {
+		if err == ErrNotLinked {
+			return "Link this chat to your account first with /link CODE.", nil
+		}
+		return "", err
+	}
+
+	n, err := strconv.Atoi(arg)
+	if err != nil {
+		return "Send /vote followed by the number of a menu listed by /menus.", nil
+	}
+
+	date, err := vote.ParseDate("", now)
+	if err != nil {
+		return "", err
+	}
+
+	ms, err := menus(ctx, b.db, date, claims.Org())
+	if err != nil {
+		return "", err
+	}
+	if n < 1 || n > len(ms) {
+		return "There is no such menu, send /menus to list them.", nil
+	}
+	m := ms[n-1]
+
+	// The claims scope the lookups of the vote to the organization like for
+	// the requests to the API.
+	ctx = context.WithValue(ctx, auth.Key, claims)
+	nv := vote.NewVote{
+		RestaurantID: m.RestaurantID,
+		Date:         date.Format("2006-01-02"),
+	}
+	if _, err := vote.Cast(ctx, b.db, claims, nv, b.policy, now); err != nil {
+		switch err {
+		case vote.ErrClosed:
+			return "Voting for today has closed.", nil
+		case vote.ErrLocked:
+			return "Voting for today has closed, your vote can no longer be changed.", nil
+		case restaurant.ErrNotFound:
+			return "This restaurant no longer exists, send /menus to list them.", nil
+		default:
+			return "", err
+		}
+	}
+
+	return fmt.Sprintf("You voted for %s.", m.Restaurant), nil
+}
+
+// parseCommand splits the text into the command and its argument. The bot
+// name Telegram appends to commands in groups is dropped.
+func parseCommand(text string) (string, string) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return "", ""
+	}
+
+	cmd := strings.ToLower(fields[0])
+	if i := strings.IndexByte(cmd, '@'); i >= 0 {
+		cmd = cmd[:i]
+	}
+
+	return cmd, strings.Join(fields[1:], " ")
+}
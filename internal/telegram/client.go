@@ -0,0 +1,119 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// Update is an incoming update of the bot. Only messages are handled.
+type Update struct {
+	ID      int64    `json:"update_id"`
+	Message *Message `json:"message"`
+}
+
+// Message is a message sent to the bot.
+type Message struct {
+	Chat struct {
+		ID int64 `json:"id"`
+	} `json:"chat"`
+	Text string `json:"text"`
+}
+
+// Client calls the methods of the Telegram Bot API for the bot of its token.
+type Client struct {
+	url    string
+	client *http.Client
+}
+
+// NewClient constructs a Client for the bot of the token. The requests time
+// out after the timeout on top of the wait of Updates.
+func NewClient(apiURL, token string, timeout time.Duration) *Client {
+	return &Client{
+		url: apiURL + "/bot" + token + "/",
+		client: &http.Client{
+			Timeout:   timeout + pollWait,
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
+		},
+	}
+}
+
+// pollWait is how long Updates waits for an update before returning none.
+const pollWait = 25 * time.Second
+
+// Updates returns the updates from the offset on. It waits for one when
+// there are none yet.
+func (c *Client) Updates(ctx context.Context, offset int64) ([]Update, error) {
+	params := struct {
+		Offset         int64    `json:"offset"`
+		Timeout        int      `json:"timeout"`
+		AllowedUpdates []string `json:"allowed_updates"`
+	}{offset, int(pollWait / time.Second), []string{"message"}}
+
+	var updates []Update
+	if err := c.call(ctx, "getUpdates", params, &updates); err != nil {
+		return nil, err
+	}
+
+	return updates, nil
+}
+
+// Send sends the text to the chat.
+func (c *Client) Send(ctx context.Context, chatID int64, text string) error {
+	params := struct {
+		ChatID int64  `json:"chat_id"`
+		Text   string `json:"text"`
+	}{chatID, text}
+
+	return c.call(ctx, "sendMessage", params, nil)
+}
+
+// call calls the method with the params and decodes its result into result
+// unless it is nil.
+func (c *Client) call(ctx context.Context, method string, params, result interface{}) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return errors.Wrapf(err, "encoding %s params", method)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url+method, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrapf(err, "creating %s request", method)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		// The token is part of the URL, so it must not end up in the logs.
+		if uerr, ok := err.(interface{ Unwrap() error }); ok {
+			err = uerr.Unwrap()
+		}
+		return errors.Wrapf(err, "calling %s", method)
+	}
+	defer resp.Body.Close()
+
+	var r struct {
+		OK          bool            `json:"ok"`
+		Description string          `json:"description"`
+		Result      json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return errors.Wrapf(err, "decoding %s response: status %s", method, resp.Status)
+	}
+	if !r.OK {
+		return errors.Errorf("calling %s: %s", method, r.Description)
+	}
+
+	if result != nil {
+		if err := json.Unmarshal(r.Result, result); err != nil {
+			return errors.Wrapf(err, "decoding %s result", method)
+		}
+	}
+
+	return nil
+}
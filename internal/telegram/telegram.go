@@ -0,0 +1,186 @@
+// Package telegram lets users vote for lunch from Telegram. A user links a
+// chat to their account with a short lived code generated by the API, then
+// lists the menus of the day and votes through the bot, which also announces
+// the winner to every linked chat.
+package telegram
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/database"
+	"go.opentelemetry.io/otel"
+)
+
+// Predefined errors identify expected failure conditions.
+var (
+	// ErrInvalidCode is used when a link code does not exist or expired.
+	ErrInvalidCode = errors.New("Link code is invalid or expired")
+
+	// ErrNotLinked is used when a chat is not linked to a user account.
+	ErrNotLinked = errors.New("Chat is not linked to an account")
+)
+
+// codeTTL is how long a link code can be used after it was created.
+const codeTTL = 15 * time.Minute
+
+// codeAlphabet leaves out the characters easily mistaken for each other.
+const codeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// Code is a one time code linking a Telegram chat to the account of the user
+// who created it.
+type Code struct {
+	Code      string    `db:"code" json:"code"`
+	UserID    string    `db:"user_id" json:"-"`
+	ExpiresAt time.Time `db:"expires_at" json:"expires_at"`
+}
+
+// CreateCode creates a link code for the user, replacing the previous ones.
+func CreateCode(ctx context.Context, db *sqlx.DB, userID string, now time.Time) (*Code, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.telegram.CreateCode")
+	defer span.End()
+
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return nil, errors.Wrap(err, "generating code")
+	}
+	for i := range b {
+		b[i] = codeAlphabet[int(b[i])%len(codeAlphabet)]
+	}
+
+	c := Code{
+		Code:      string(b),
+		UserID:    userID,
+		ExpiresAt: now.UTC().Add(codeTTL),
+	}
+
+	tx, err := database.Begin(ctx, db)
+	if err != nil {
+		return nil, errors.Wrap(err, "beginning transaction")
+	}
+	defer tx.Rollback()
+
+	const qd = `DELETE FROM telegram_code WHERE user_id = $1`
+	if _, err := tx.ExecContext(ctx, qd, c.UserID); err != nil {
+		return nil, errors.Wrap(err, "deleting previous codes")
+	}
+
+	const qi = `INSERT INTO telegram_code (code, user_id, expires_at) VALUES ($1, $2, $3)`
+	if _, err := tx.ExecContext(ctx, qi, c.Code, c.UserID, c.ExpiresAt); err != nil {
+		return nil, errors.Wrap(err, "inserting code")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrap(err, "committing code")
+	}
+
+	return &c, nil
+}
+
+// Link links the chat to the account of the user who created the code. The
+// code is used up and a chat linked before to the user is unlinked.
+func Link(ctx context.Context, db *sqlx.DB, chatID int64, code string, now time.Time) (string, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.telegram.Link")
+	defer span.End()
+
+	tx, err := database.Begin(ctx, db)
+	if err != nil {
+		return "", errors.Wrap(err, "beginning transaction")
+	}
+	defer tx.Rollback()
+
+	var userID string
+	const qc = `DELETE FROM telegram_code WHERE code = $1 AND expires_at > $2 RETURNING user_id`
+	if err := sqlx.GetContext(ctx, tx, &userID, qc, code, now.UTC()); err != nil {
+		if err == sql.ErrNoRows {
+			return "", ErrInvalidCode
+		}
+		return "", errors.Wrap(err, "using code")
+	}
+
+	const qu = `DELETE FROM telegram_chat WHERE user_id = $1 OR chat_id = $2`
+	if _, err := tx.ExecContext(ctx, qu, userID, chatID); err != nil {
+		return "", errors.Wrap(err, "unlinking previous chat")
+	}
+
+	const qi = `INSERT INTO telegram_chat (chat_id, user_id, date_linked) VALUES ($1, $2, $3)`
+	if _, err := tx.ExecContext(ctx, qi, chatID, userID, now.UTC()); err != nil {
+		return "", errors.Wrap(err, "linking chat")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", errors.Wrap(err, "committing link")
+	}
+
+	return userID, nil
+}
+
+// Unlink unlinks the chat from the account it is linked to, if any.
+func Unlink(ctx context.Context, db *sqlx.DB, chatID int64) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.telegram.Unlink")
+	defer span.End()
+
+	const q = `DELETE FROM telegram_chat WHERE chat_id = $1`
+	if _, err := db.ExecContext(ctx, q, chatID); err != nil {
+		return errors.Wrap(err, "unlinking chat")
+	}
+
+	return nil
+}
+
+// ChatUser returns the claims of the user the chat is linked to, valid for
+// the duration from now.
+func ChatUser(ctx context.Context, db *sqlx.DB, chatID int64, now time.Time, expires time.Duration) (auth.Claims, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.telegram.ChatUser")
+	defer span.End()
+
+	var u struct {
+		ID    string         `db:"user_id"`
+		Roles pq.StringArray `db:"roles"`
+		OrgID string         `db:"org_id"`
+	}
+	const q = `SELECT u.user_id, u.roles, u.org_id FROM telegram_chat AS c
+		JOIN users AS u ON u.user_id = c.user_id
+		WHERE c.chat_id = $1 AND u.deleted_at IS NULL`
+	if err := db.GetContext(ctx, &u, q, chatID); err != nil {
+		if err == sql.ErrNoRows {
+			return auth.Claims{}, ErrNotLinked
+		}
+		return auth.Claims{}, errors.Wrap(err, "selecting chat user")
+	}
+
+	claims := auth.NewClaims(u.ID, u.Roles, now, expires)
+	claims.OrgID = u.OrgID
+	return claims, nil
+}
+
+// Menu is a menu of the day listed by the bot.
+type Menu struct {
+	RestaurantID string `db:"restaurant_id"`
+	Restaurant   string `db:"name"`
+	Menu         string `db:"menu"`
+}
+
+// menus returns the menus of the date in the organization ordered by
+// restaurant name, the order the bot numbers them in.
+func menus(ctx context.Context, db *sqlx.DB, date time.Time, org string) ([]Menu, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.telegram.menus")
+	defer span.End()
+
+	var ms []Menu
+	const q = `SELECT r.restaurant_id, r.name, m.menu FROM menu AS m
+		JOIN restaurant AS r ON r.restaurant_id = m.restaurant_id
+		WHERE m.date = $1 AND r.org_id = $2 AND m.deleted_at IS NULL AND r.deleted_at IS NULL
+		ORDER BY r.name, r.restaurant_id`
+	if err := db.SelectContext(ctx, &ms, q, date, org); err != nil {
+		return nil, errors.Wrap(err, "selecting menus")
+	}
+
+	return ms, nil
+}
@@ -0,0 +1,100 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// TestParseCommand validates the commands sent to the bot are recognized.
+func TestParseCommand(t *testing.T) {
+	tt := []struct {
+		text string
+		cmd  string
+		arg  string
+	}{
+		{"/menus", "/menus", ""},
+		{"/vote 2", "/vote", "2"},
+		{"  /Link   ab12cd34 ", "/link", "ab12cd34"},
+		{"/vote@LunchBot 3", "/vote", "3"},
+		{"", "", ""},
+	}
+
+	t.Log("Given the need to recognize the commands sent to the bot.")
+	{
+		for i, tc := range tt {
+			t.Logf("\tTest %d:\tWhen receiving %q.", i, tc.text)
+			{
+				cmd, arg := parseCommand(tc.text)
+				if cmd != tc.cmd || arg != tc.arg {
+					t.Fatalf("\t%s\tShould get %q %q : got %q %q.", tests.Failed, tc.cmd, tc.arg, cmd, arg)
+				}
+				t.Logf("\t%s\tShould get %q %q.", tests.Success, tc.cmd, tc.arg)
+			}
+		}
+	}
+}
+
+// TestClient validates the calls to the Telegram Bot API.
+func TestClient(t *testing.T) {
+	t.Log("Given the need to call the Telegram Bot API.")
+	{
+		var sent struct {
+			ChatID int64  `json:"chat_id"`
+			Text   string `json:"text"`
+		}
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/bottoken/getUpdates":
+				w.Write([]byte(`{"ok":true,"result":[{"update_id":7,"message":{"chat":{"id":42},"text":"/menus"}}]}`))
+			case "/bottoken/sendMessage":
+				json.NewDecoder(r.Body).Decode(&sent)
+				w.Write([]byte(`{"ok":true,"result":{}}`))
+			default:
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"ok":false,"description":"Unauthorized"}`))
+			}
+		}))
+		defer srv.Close()
+
+		ctx := context.Background()
+		c := NewClient(srv.URL, "token", time.Second)
+
+		t.Log("\tTest 0:\tWhen getting the updates.")
+		{
+			updates, err := c.Updates(ctx, 0)
+			if err != nil {
+				t.Fatalf("\t%s\tShould be able to get the updates : %s.", tests.Failed, err)
+			}
+			if len(updates) != 1 || updates[0].ID != 7 || updates[0].Message.Chat.ID != 42 || updates[0].Message.Text != "/menus" {
+				t.Fatalf("\t%s\tShould decode the updates : got %+v.", tests.Failed, updates)
+			}
+			t.Logf("\t%s\tShould be able to get the updates.", tests.Success)
+		}
+
+		t.Log("\tTest 1:\tWhen sending a message.")
+		{
+			if err := c.Send(ctx, 42, "hello"); err != nil {
+				t.Fatalf("\t%s\tShould be able to send the message : %s.", tests.Failed, err)
+			}
+			if sent.ChatID != 42 || sent.Text != "hello" {
+				t.Fatalf("\t%s\tShould send the chat and text : got %+v.", tests.Failed, sent)
+			}
+			t.Logf("\t%s\tShould be able to send the message.", tests.Success)
+		}
+
+		t.Log("\tTest 2:\tWhen the API rejects the call.")
+		{
+			c := NewClient(srv.URL, "wrong", time.Second)
+			if err := c.Send(ctx, 42, "hello"); err == nil {
+				t.Fatalf("\t%s\tShould fail when the API rejects the call.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould fail when the API rejects the call.", tests.Success)
+		}
+	}
+}
@@ -7,12 +7,13 @@ import (
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/remisb/restaurant/internal/platform/auth"
 	"github.com/remisb/restaurant/internal/platform/database"
 	"github.com/remisb/restaurant/internal/platform/database/databasetest"
 	"github.com/remisb/restaurant/internal/platform/web"
 	"github.com/remisb/restaurant/internal/schema"
-	"github.com/remisb/restaurant/internal/user"
+	"golang.org/x/crypto/bcrypt"
 	"log"
 	"os"
 	"testing"
@@ -30,16 +31,29 @@ type Test struct {
 	Log *log.Logger
 	Authenticator *auth.Authenticator
 
-	t *testing.T
+	t testing.TB
 	cleanup func()
 }
 
-func NewUnit(t *testing.T) (*sqlx.DB, func()) {
+func NewUnit(t testing.TB) (*sqlx.DB, func()) {
+	t.Helper()
+	return NewUnitDriver(t, database.DriverPQ)
+}
+
+// NewUnitDriver is NewUnit talking to the database with the driver, one of
+// the database.Driver constants.
+func NewUnitDriver(t testing.TB, driver string) (*sqlx.DB, func()) {
 	t.Helper()
 
 	c := databasetest.StartContainer(t)
 
-	db, err := database.Open(database.Config{
+	t.Log("waiting for database to be ready")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	db, err := database.OpenAndWait(ctx, database.Config{
+		Driver: driver,
 		User: "postgres",
 		Password: "postgres",
 		Host: c.Host,
@@ -47,30 +61,12 @@ func NewUnit(t *testing.T) (*sqlx.DB, func()) {
 		DisableTLS: true,
 	})
 	if err != nil {
-		t.Fatalf("opening database connection: %v", err)
-	}
-
-	t.Log("waiting for database to be ready")
-
-	// Wait for the database to be ready. Wait 100ms longer between each attempt.
-	// Do not try more than 20 times.
-	var pingError error
-	maxAttempts := 20
-	for attempts := 1; attempts <= maxAttempts; attempts++ {
-		pingError = db.Ping()
-		if pingError == nil {
-			break
-		}
-		time.Sleep(time.Duration(maxAttempts) * 100 * time.Millisecond)
-	}
-
-	if pingError != nil {
 		databasetest.DumpContainerLogs(t, c)
 		databasetest.StopContainer(t, c)
-		t.Fatalf("waiting for database to be ready: %v", pingError)
+		t.Fatalf("waiting for database to be ready: %v", err)
 	}
 
-	if err := schema.Migrate(db); err != nil {
+	if err := schema.Migrate(ctx, db); err != nil {
 		databasetest.StopContainer(t, c)
 		t.Fatalf("migrating: %s", err)
 	}
@@ -84,11 +80,18 @@ func NewUnit(t *testing.T) (*sqlx.DB, func()) {
 	return db, teardown
 }
 
-func NewIntegration(t *testing.T) *Test {
+func NewIntegration(t testing.TB) *Test {
+	t.Helper()
+	return NewIntegrationDriver(t, database.DriverPQ)
+}
+
+// NewIntegrationDriver is NewIntegration talking to the database with the
+// driver, one of the database.Driver constants.
+func NewIntegrationDriver(t testing.TB, driver string) *Test {
 	t.Helper()
 
 	// Initialize and seed database. Store the cleanup function call later.
-	db, cleanup := NewUnit(t)
+	db, cleanup := NewUnitDriver(t, driver)
 	if err := schema.Seed(db); err != nil {
 		t.Fatal(err)
 	}
@@ -124,17 +127,29 @@ func (test *Test) Teardown() {
 	test.cleanup()
 }
 
-// Token generates an authenticated token for a user.
+// Token generates an authenticated token for a user. It reads the user
+// itself rather than calling user.Authenticate so the tests of the user
+// package can use this package.
 func (test *Test) Token(email, pass string) string {
 	test.t.Helper()
 
-	claims, err := user.Authenticate(
-		context.Background(), test.DB, time.Now(),
-		email, pass,
-	)
-	if err != nil {
+	const q = `SELECT user_id, roles, org_id, password_hash FROM users WHERE email = $1 AND deleted_at IS NULL`
+
+	var u struct {
+		ID           string         `db:"user_id"`
+		Roles        pq.StringArray `db:"roles"`
+		OrgID        string         `db:"org_id"`
+		PasswordHash []byte         `db:"password_hash"`
+	}
+	if err := test.DB.GetContext(context.Background(), &u, q, email); err != nil {
 		test.t.Fatal(err)
 	}
+	if err := bcrypt.CompareHashAndPassword(u.PasswordHash, []byte(pass)); err != nil {
+		test.t.Fatal(err)
+	}
+
+	claims := auth.NewClaims(u.ID, u.Roles, time.Now(), time.Hour)
+	claims.OrgID = u.OrgID
 
 	tkn, err := test.Authenticator.GenerateToken(claims)
 	if err != nil {
@@ -175,7 +190,7 @@ func LogInfo(t *testing.T, number int, msg string) {
 
 func LogInfof(t *testing.T, number int, format string, args ...interface{}) {
 	t.Helper()
-	t.Logf("\tTest %d:\t%s", number, fmt.Sprintf(format, args))
+	t.Logf("\tTest %d:\t%s", number, fmt.Sprintf(format, args...))
 }
 
 func LogSuccess(t *testing.T, msg string) {
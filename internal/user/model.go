@@ -11,9 +11,12 @@ type User struct {
 	Name         string         `db:"name" json:"name"`
 	Email        string         `db:"email" json:"email"`
 	Roles        pq.StringArray `db:"roles" json:"roles"`
+	OrgID        string         `db:"org_id" json:"org_id"`
 	PasswordHash []byte         `db:"password_hash" json:"-"`
+	Version      int            `db:"version" json:"version"`
 	DateCreated  time.Time      `db:"date_created" json:"date_created"`
 	DateUpdated  time.Time      `db:"date_updated" json:"date_updated"`
+	DateDeleted  *time.Time     `db:"deleted_at" json:"-"`
 }
 
 // NewUser contains information needed to create a new User.
@@ -23,6 +26,10 @@ type NewUser struct {
 	Roles           []string `json:"roles" validate:"required"`
 	Password        string   `json:"password" validate:"required"`
 	PasswordConfirm string   `json:"password_confirm" validate:"eqfield=Password"`
+
+	// OrgID is the organization the user is a member of, the default one
+	// when blank. It is set from the claims of the admin creating the user.
+	OrgID string `json:"-"`
 }
 
 // UpdateUser defines what information may be provided to modify an existing
@@ -37,4 +44,8 @@ type UpdateUser struct {
 	Roles           []string `json:"roles"`
 	Password        *string  `json:"password"`
 	PasswordConfirm *string  `json:"password_confirm" validate:"omitempty,eqfield=Password"`
+
+	// Version is the version of the User the changes are based on. The update
+	// is rejected when someone else changed it in the meantime.
+	Version *int `json:"version" validate:"required"`
 }
@@ -0,0 +1,79 @@
+package user
+
+import (
+	"context"
+	"time"
+
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/database"
+)
+
+// Store is the set of user operations used by the API handlers. It lets the
+// handlers run against an in-memory fake in unit tests.
+type Store interface {
+	List(ctx context.Context) ([]User, error)
+	Retrieve(ctx context.Context, claims auth.Claims, id string) (*User, error)
+	Create(ctx context.Context, n NewUser, now time.Time) (*User, error)
+	Update(ctx context.Context, claims auth.Claims, id string, upd UpdateUser, now time.Time) error
+	Delete(ctx context.Context, id string, now time.Time) error
+	Authenticate(ctx context.Context, now time.Time, email, password string) (auth.Claims, error)
+}
+
+// DBStore implements Store on top of the primary database. Every call ends
+// after the query timeout of the database.
+type DBStore struct {
+	db *database.DB
+}
+
+// NewStore constructs a Store backed by the database.
+func NewStore(db *database.DB) *DBStore {
+	return &DBStore{db: db}
+}
+
+// List implements the Store interface.
+func (s *DBStore) List(ctx context.Context) ([]User, error) {
+	ctx, cancel := s.db.Deadline(ctx)
+	defer cancel()
+
+	return List(ctx, s.db.Primary())
+}
+
+// Retrieve implements the Store interface.
+func (s *DBStore) Retrieve(ctx context.Context, claims auth.Claims, id string) (*User, error) {
+	ctx, cancel := s.db.Deadline(ctx)
+	defer cancel()
+
+	return Retrieve(ctx, claims, s.db.Primary(), id)
+}
+
+// Create implements the Store interface.
+func (s *DBStore) Create(ctx context.Context, n NewUser, now time.Time) (*User, error) {
+	ctx, cancel := s.db.Deadline(ctx)
+	defer cancel()
+
+	return Create(ctx, s.db.Primary(), n, now)
+}
+
+// Update implements the Store interface.
+func (s *DBStore) Update(ctx context.Context, claims auth.Claims, id string, upd UpdateUser, now time.Time) error {
+	ctx, cancel := s.db.Deadline(ctx)
+	defer cancel()
+
+	return Update(ctx, claims, s.db.Primary(), id, upd, now)
+}
+
+// Delete implements the Store interface.
+func (s *DBStore) Delete(ctx context.Context, id string, now time.Time) error {
+	ctx, cancel := s.db.Deadline(ctx)
+	defer cancel()
+
+	return Delete(ctx, s.db.Primary(), id, now)
+}
+
+// Authenticate implements the Store interface.
+func (s *DBStore) Authenticate(ctx context.Context, now time.Time, email, password string) (auth.Claims, error) {
+	ctx, cancel := s.db.Deadline(ctx)
+	defer cancel()
+
+	return Authenticate(ctx, s.db.Primary(), now, email, password)
+}
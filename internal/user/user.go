@@ -8,8 +8,9 @@ import (
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/authz"
 	"github.com/remisb/restaurant/internal/platform/auth"
-	"go.opencensus.io/trace"
+	"go.opentelemetry.io/otel"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -20,17 +21,19 @@ var (
 	ErrInvalidID = errors.New("ID is not in its proper form")
 	ErrAuthenticationFailure = errors.New("AuthenticationFailed")
 	ErrForbidden = errors.New("Attempted action is not allowed")
+	ErrVersionConflict = errors.New("User was changed by someone else")
 )
 
-// List retrieves a list of existing users from the database.
+// List retrieves a list of existing users of the organization from the
+// database.
 func List(ctx context.Context, db *sqlx.DB) ([]User, error) {
-	ctx, span := trace.StartSpan(ctx, "internal.user.List")
+	ctx, span := otel.Tracer("").Start(ctx, "internal.user.List")
 	defer span.End()
 
 	users := []User{}
-	const q = `SELECT * FROM users`
+	const q = `SELECT * FROM users WHERE deleted_at IS NULL AND ($1 = '' OR org_id::text = $1)`
 
-	if err := db.SelectContext(ctx, &users, q); err != nil {
+	if err := db.SelectContext(ctx, &users, q, auth.Org(ctx)); err != nil {
 		return nil, errors.Wrap(err, "selecting users")
 	}
 
@@ -39,7 +42,7 @@ func List(ctx context.Context, db *sqlx.DB) ([]User, error) {
 
 // Retrieve gets the specified user from the database.
 func Retrieve(ctx context.Context, claims auth.Claims, db *sqlx.DB, id string) (*User, error) {
-	ctx, span := trace.StartSpan(ctx, "internal.user.Retrieve")
+	ctx, span := otel.Tracer("").Start(ctx, "internal.user.Retrieve")
 	defer span.End()
 
 	if _, err := uuid.Parse(id); err != nil {
@@ -47,13 +50,13 @@ func Retrieve(ctx context.Context, claims auth.Claims, db *sqlx.DB, id string) (
 	}
 
 	// If you are not an admin and looking to retrieve someone else then you are rejected.
-	if !claims.HasRole(auth.RoleAdmin) && claims.Subject != id {
+	if !authz.Allowed(claims, authz.Read, authz.Resource{Kind: authz.User, Owner: id}) {
 		return nil, ErrForbidden
 	}
 
 	var u User
-	const q = `SELECT * FROM users WHERE user_id = $1`
-	if err := db.GetContext(ctx, &u, q, id); err != nil {
+	const q = `SELECT * FROM users WHERE user_id = $1 AND deleted_at IS NULL AND ($2 = '' OR org_id::text = $2)`
+	if err := db.GetContext(ctx, &u, q, id, auth.Org(ctx)); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrNotFound
 		}
@@ -66,7 +69,7 @@ func Retrieve(ctx context.Context, claims auth.Claims, db *sqlx.DB, id string) (
 
 // Create inserts a new user into the database.
 func Create(ctx context.Context, db *sqlx.DB, n NewUser, now time.Time) (*User, error) {
-	ctx, span := trace.StartSpan(ctx, "internal.user.Create")
+	ctx, span := otel.Tracer("").Start(ctx, "internal.user.Create")
 	defer span.End()
 
 	hash, err := bcrypt.GenerateFromPassword([]byte(n.Password), bcrypt.DefaultCost)
@@ -80,17 +83,23 @@ func Create(ctx context.Context, db *sqlx.DB, n NewUser, now time.Time) (*User,
 		Email:        n.Email,
 		PasswordHash: hash,
 		Roles:        n.Roles,
+		OrgID:        n.OrgID,
+		Version:      1,
 		DateCreated:  now.UTC(),
 		DateUpdated:  now.UTC(),
 	}
 
+	if u.OrgID == "" {
+		u.OrgID = auth.DefaultOrg
+	}
+
 	const q = `INSERT INTO users
-		(user_id, name, email, password_hash, roles, date_created, date_updated)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+		(user_id, name, email, password_hash, roles, org_id, date_created, date_updated)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
 	_, err = db.ExecContext(
 		ctx, q,
 		u.ID, u.Name, u.Email,
-		u.PasswordHash, u.Roles,
+		u.PasswordHash, u.Roles, u.OrgID,
 		u.DateCreated, u.DateUpdated,
 	)
 	if err != nil {
@@ -102,7 +111,7 @@ func Create(ctx context.Context, db *sqlx.DB, n NewUser, now time.Time) (*User,
 
 // Update replaces a user document in the database.
 func Update(ctx context.Context, claims auth.Claims, db *sqlx.DB, id string, upd UpdateUser, now time.Time) error {
-	ctx, span := trace.StartSpan(ctx, "internal.user.Update")
+	ctx, span := otel.Tracer("").Start(ctx, "internal.user.Update")
 	defer span.End()
 
 	u, err := Retrieve(ctx, claims, db, id)
@@ -110,6 +119,10 @@ func Update(ctx context.Context, claims auth.Claims, db *sqlx.DB, id string, upd
 		return err
 	}
 
+	if upd.Version != nil && *upd.Version != u.Version {
+		return ErrVersionConflict
+	}
+
 	if upd.Name != nil {
 		u.Name = *upd.Name
 	}
@@ -134,31 +147,39 @@ func Update(ctx context.Context, claims auth.Claims, db *sqlx.DB, id string, upd
 		"email" = $3,
 		"roles" = $4,
 		"password_hash" = $5,
-		"date_updated" = $6
-		WHERE user_id = $1`
-	_, err = db.ExecContext(ctx, q, id,
+		"date_updated" = $6,
+		"version" = version + 1
+		WHERE user_id = $1 AND version = $7 AND deleted_at IS NULL`
+	res, err := db.ExecContext(ctx, q, id,
 		u.Name, u.Email, u.Roles,
-		u.PasswordHash, u.DateUpdated,
+		u.PasswordHash, u.DateUpdated, u.Version,
 	)
 	if err != nil {
 		return errors.Wrap(err, "updating user")
 	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrVersionConflict
+	}
 
 	return nil
 }
 
-// Delete removes a user from the database.
-func Delete(ctx context.Context, db *sqlx.DB, id string) error {
-	ctx, span := trace.StartSpan(ctx, "internal.user.Delete")
+// Delete removes a user from the database. The row is kept, marked as
+// deleted, so the votes of the user stay intact.
+func Delete(ctx context.Context, db *sqlx.DB, id string, now time.Time) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.user.Delete")
 	defer span.End()
 
 	if _, err := uuid.Parse(id); err != nil {
 		return ErrInvalidID
 	}
 
-	const q = `DELETE FROM users WHERE user_id = $1`
+	const q = `UPDATE users SET
+		"deleted_at" = $2,
+		"version" = version + 1
+		WHERE user_id = $1 AND deleted_at IS NULL AND ($3 = '' OR org_id::text = $3)`
 
-	if _, err := db.ExecContext(ctx, q, id); err != nil {
+	if _, err := db.ExecContext(ctx, q, id, now.UTC(), auth.Org(ctx)); err != nil {
 		return errors.Wrapf(err, "deleting user %s", id)
 	}
 
@@ -169,10 +190,10 @@ func Delete(ctx context.Context, db *sqlx.DB, id string) error {
 // success it returns a Claims value representing this user. The claims can be
 // used to generate a token for future authentication.
 func Authenticate(ctx context.Context, db *sqlx.DB, now time.Time, email, password string) (auth.Claims, error) {
-	ctx, span := trace.StartSpan(ctx, "internal.user.Authenticate")
+	ctx, span := otel.Tracer("").Start(ctx, "internal.user.Authenticate")
 	defer span.End()
 
-	const q = `SELECT * FROM users WHERE email = $1`
+	const q = `SELECT * FROM users WHERE email = $1 AND deleted_at IS NULL`
 
 	var u User
 	if err := db.GetContext(ctx, &u, q, email); err != nil {
@@ -195,5 +216,6 @@ func Authenticate(ctx context.Context, db *sqlx.DB, now time.Time, email, passwo
 	// If we are this far the request is valid. Create some claims for the user
 	// and generate their token.
 	claims := auth.NewClaims(u.ID, u.Roles, now, time.Hour)
+	claims.OrgID = u.OrgID
 	return claims, nil
 }
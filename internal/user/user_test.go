@@ -86,7 +86,12 @@ func TestUser(t *testing.T) {
 				t.Logf("\t%s\tShould be able to see updates to Email.", tests.Success)
 			}
 
-			if err := Delete(ctx, db, u.ID); err != nil {
+			if err := Update(ctx, claims, db, u.ID, UpdateUser{Version: tests.IntPointer(1)}, now); errors.Cause(err) != ErrVersionConflict {
+				t.Fatalf("\t%s\tShould NOT be able to update a stale version : %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould NOT be able to update a stale version.", tests.Success)
+
+			if err := Delete(ctx, db, u.ID, now); err != nil {
 				t.Fatalf("\t%s\tShould be able to delete user : %s.", tests.Failed, err)
 			}
 			t.Logf("\t%s\tShould be able to delete user.", tests.Success)
@@ -0,0 +1,209 @@
+package vote
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/organization"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/database"
+	"github.com/remisb/restaurant/internal/restaurant"
+	"go.opentelemetry.io/otel"
+)
+
+// checkMode returns ErrWrongMode unless the organization votes in the mode.
+func checkMode(ctx context.Context, db *sqlx.DB, org, mode string) error {
+	m, err := organization.VotingMode(ctx, database.Conn(ctx, db), org)
+	if err != nil {
+		return err
+	}
+	if m != mode {
+		return ErrWrongMode
+	}
+	return nil
+}
+
+// CastDish records the user's vote for an item of a menu served on the date
+// given in the NewDishVote. Each category of items is voted for separately,
+// and a previous vote of the user in the category of the item for the same
+// date is replaced until voting closes. The organization must vote for
+// dishes.
+func CastDish(ctx context.Context, db *sqlx.DB, user auth.Claims, nd NewDishVote, policy Policy, now time.Time) (*DishVote, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.vote.CastDish")
+	defer span.End()
+
+	policy, err := OrgPolicy(ctx, database.Conn(ctx, db), user.Org(), policy)
+	if err != nil {
+		return nil, err
+	}
+
+	date, err := ParseDate(nd.Date, policy.Today(now))
+	if err != nil {
+		return nil, err
+	}
+	if err := policy.Check(date, now); err != nil {
+		return nil, err
+	}
+
+	if err := checkMode(ctx, db, user.Org(), organization.VotingDish); err != nil {
+		return nil, err
+	}
+
+	m, err := restaurant.MenuRetrieve(ctx, db, nd.MenuID)
+	if err != nil {
+		return nil, err
+	}
+	if !day(m.Date).Equal(date) {
+		return nil, ErrMenuDate
+	}
+	it, ok := m.Items.Find(nd.Item)
+	if !ok {
+		return nil, ErrItemNotFound
+	}
+
+	v := DishVote{
+		Date:         date,
+		UserID:       user.Subject,
+		Category:     it.Kind(),
+		MenuID:       m.ID,
+		RestaurantID: m.RestaurantID,
+		Item:         it.Name,
+		OrgID:        user.Org(),
+		TimeVoted:    now.UTC(),
+	}
+
+	const q = `INSERT INTO dish_vote
+		(date, user_id, category, menu_id, restaurant_id, item, org_id, time_voted)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (date, user_id, category) DO UPDATE SET
+		"menu_id" = EXCLUDED.menu_id,
+		"restaurant_id" = EXCLUDED.restaurant_id,
+		"item" = EXCLUDED.item,
+		"org_id" = EXCLUDED.org_id,
+		"time_voted" = EXCLUDED.time_voted`
+	_, err = database.Conn(ctx, db).ExecContext(ctx, q, v.Date, v.UserID, v.Category, v.MenuID, v.RestaurantID, v.Item, v.OrgID, v.TimeVoted)
+	if err != nil {
+		return nil, errors.Wrap(err, "inserting dish vote")
+	}
+
+	return &v, nil
+}
+
+// DishTallies counts the votes for each menu item on the date, by category
+// and most votes first within it.
+func DishTallies(ctx context.Context, db *sqlx.DB, date time.Time) ([]DishTally, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.vote.DishTallies")
+	defer span.End()
+
+	return dishTallies(ctx, db, date, auth.Org(ctx))
+}
+
+// dishTallies counts the dish votes cast in the organization, or in all of
+// them when it is blank.
+func dishTallies(ctx context.Context, db *sqlx.DB, date time.Time, org string) ([]DishTally, error) {
+	tallies := []DishTally{}
+	const q = `SELECT category, menu_id, restaurant_id, item, COUNT(*) AS votes FROM dish_vote
+		WHERE date = $1 AND ($2 = '' OR org_id::text = $2)
+		GROUP BY category, menu_id, restaurant_id, item
+		ORDER BY category, votes DESC, MIN(time_voted), item`
+	if err := sqlx.SelectContext(ctx, database.Conn(ctx, db), &tallies, q, date, org); err != nil {
+		return nil, errors.Wrap(err, "selecting dish tallies")
+	}
+
+	return tallies, nil
+}
+
+// leaders returns the first tally of each category of tallies ordered by
+// category and most votes first.
+func leaders(tallies []DishTally) []DishTally {
+	var firsts []DishTally
+	for i, t := range tallies {
+		if i == 0 || t.Category != tallies[i-1].Category {
+			firsts = append(firsts, t)
+		}
+	}
+	return firsts
+}
+
+// RetrieveDishWinners gets the winners computed for the date in the
+// organization of the claims in ctx, one per category.
+func RetrieveDishWinners(ctx context.Context, db *sqlx.DB, date time.Time) ([]DishWinner, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.vote.RetrieveDishWinners")
+	defer span.End()
+
+	org := auth.Org(ctx)
+	if org == "" {
+		org = auth.DefaultOrg
+	}
+
+	winners := []DishWinner{}
+	const q = `SELECT * FROM dish_winner WHERE date = $1 AND org_id = $2 ORDER BY category`
+	if err := sqlx.SelectContext(ctx, database.Conn(ctx, db), &winners, q, date, org); err != nil {
+		return nil, errors.Wrap(err, "selecting dish winners")
+	}
+	if len(winners) == 0 {
+		return nil, ErrNoWinner
+	}
+
+	return winners, nil
+}
+
+// ComputeDishWinners stores the menu item with the most votes in each
+// category as the winner of the organization for the date. Ties go to the
+// item which received its first vote earliest. Nothing is stored when nobody
+// voted.
+func ComputeDishWinners(ctx context.Context, db *sqlx.DB, date time.Time, org string, now time.Time) ([]DishWinner, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.vote.ComputeDishWinners")
+	defer span.End()
+
+	tallies, err := dishTallies(ctx, db, date, org)
+	if err != nil {
+		return nil, err
+	}
+	if len(tallies) == 0 {
+		return nil, ErrNoWinner
+	}
+
+	const q = `INSERT INTO dish_winner
+		(date, org_id, category, menu_id, restaurant_id, item, votes, date_computed)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (date, org_id, category) DO NOTHING`
+
+	var winners []DishWinner
+	for _, t := range leaders(tallies) {
+		w := DishWinner{
+			Date:         date,
+			OrgID:        org,
+			Category:     t.Category,
+			MenuID:       t.MenuID,
+			RestaurantID: t.RestaurantID,
+			Item:         t.Item,
+			Votes:        t.Votes,
+			DateComputed: now.UTC(),
+		}
+		_, err := database.Conn(ctx, db).ExecContext(ctx, q, w.Date, w.OrgID, w.Category, w.MenuID, w.RestaurantID, w.Item, w.Votes, w.DateComputed)
+		if err != nil {
+			return nil, errors.Wrapf(err, "inserting dish winner of %s", w.Category)
+		}
+		winners = append(winners, w)
+	}
+
+	return winners, nil
+}
+
+// pendingDishDates returns the dates and organizations which received dish
+// votes, have closed and do not have their winners yet.
+func pendingDishDates(ctx context.Context, db *sqlx.DB, policy Policy, now time.Time) ([]pending, error) {
+	dates := []pending{}
+	const q = `SELECT DISTINCT v.date, v.org_id FROM dish_vote AS v
+		LEFT JOIN dish_winner AS w ON w.date = v.date AND w.org_id = v.org_id
+		WHERE w.date IS NULL AND v.date <= $1
+		ORDER BY v.date, v.org_id`
+	if err := sqlx.SelectContext(ctx, database.Conn(ctx, db), &dates, q, day(now).AddDate(0, 0, 1)); err != nil {
+		return nil, errors.Wrap(err, "selecting pending dish dates")
+	}
+
+	return closedDates(ctx, db, dates, policy, now)
+}
@@ -0,0 +1,45 @@
+package vote
+
+import (
+	"testing"
+
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// TestLeaders validates each category gets the item ranked first as its
+// winner.
+func TestLeaders(t *testing.T) {
+	t.Log("Given the need to pick the winning item of each category.")
+	{
+		t.Log("\tTest 0:\tWhen items of several categories received votes.")
+		{
+			tallies := []DishTally{
+				{Category: "dessert", Item: "Flan", Votes: 3},
+				{Category: "dessert", Item: "Churros", Votes: 1},
+				{Category: "main", Item: "Tacos", Votes: 4},
+				{Category: "main", Item: "Burrito", Votes: 4},
+				{Category: "starter", Item: "Nachos", Votes: 2},
+			}
+
+			got := leaders(tallies)
+			want := []string{"Flan", "Tacos", "Nachos"}
+			if len(got) != len(want) {
+				t.Fatalf("\t%s\tShould pick one item per category : got %+v.", tests.Failed, got)
+			}
+			for i, it := range want {
+				if got[i].Item != it {
+					t.Fatalf("\t%s\tShould pick the first item of each category : got %+v.", tests.Failed, got)
+				}
+			}
+			t.Logf("\t%s\tShould pick the first item of each category.", tests.Success)
+		}
+
+		t.Log("\tTest 1:\tWhen nobody voted.")
+		{
+			if got := leaders(nil); len(got) != 0 {
+				t.Fatalf("\t%s\tShould pick no item : got %+v.", tests.Failed, got)
+			}
+			t.Logf("\t%s\tShould pick no item.", tests.Success)
+		}
+	}
+}
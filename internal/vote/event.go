@@ -0,0 +1,202 @@
+package vote
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/platform/database"
+	"go.opentelemetry.io/otel"
+)
+
+// appendEvent adds the event to the vote log. It must be given the
+// transaction changing the votes so the log never misses a committed vote.
+// Events are never updated nor deleted once appended.
+func appendEvent(ctx context.Context, tx sqlx.ExecerContext, e Event) error {
+	const q = `INSERT INTO vote_event
+		(type, date, user_id, restaurant_id, org_id, weight, cast_by, time_occurred)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	if _, err := tx.ExecContext(ctx, q, e.Type, e.Date, e.UserID, e.RestaurantID, e.OrgID, e.Weight, e.CastBy, e.TimeOccurred); err != nil {
+		return errors.Wrapf(err, "appending %s event", e.Type)
+	}
+	return nil
+}
+
+// Log calls fn with every event of the vote log for the dates from through
+// to in the order they were appended. A blank org reads the events of all
+// organizations.
+func Log(ctx context.Context, db *sqlx.DB, from, to time.Time, org string, fn func(Event) error) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.vote.Log")
+	defer span.End()
+
+	return readLog(ctx, database.Conn(ctx, db), from, to, org, fn)
+}
+
+// readLog calls fn with the events of the dates in the order they were
+// appended.
+func readLog(ctx context.Context, q sqlx.QueryerContext, from, to time.Time, org string, fn func(Event) error) error {
+	const qe = `SELECT * FROM vote_event WHERE date >= $1 AND date <= $2 AND ($3 = '' OR org_id::text = $3)
+		ORDER BY seq`
+	rows, err := q.QueryxContext(ctx, qe, day(from), day(to), org)
+	if err != nil {
+		return errors.Wrap(err, "selecting vote events")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e Event
+		if err := rows.StructScan(&e); err != nil {
+			return errors.Wrap(err, "scanning vote event")
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+
+	return errors.Wrap(rows.Err(), "reading vote events")
+}
+
+// Recount replays the vote log of the date in the organization and returns
+// the tallies it yields, most votes first. Nothing is written so the result
+// can be compared with the stored tallies and winner of a disputed date.
+func Recount(ctx context.Context, db *sqlx.DB, date time.Time, org string) ([]Tally, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.vote.Recount")
+	defer span.End()
+
+	b := ballots{}
+	if err := Log(ctx, db, date, date, org, b.apply); err != nil {
+		return nil, err
+	}
+
+	return b.tallies(), nil
+}
+
+// Rebuild replaces the votes and tallies of the dates from through to, in
+// every organization, with the ones replayed from the vote log. It returns
+// the number of votes rebuilt. Purging an archived month removes its log
+// along with its votes, so rebuilding its dates leaves them empty.
+func Rebuild(ctx context.Context, db *sqlx.DB, from, to time.Time) (int, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.vote.Rebuild")
+	defer span.End()
+
+	tx, err := database.Begin(ctx, db)
+	if err != nil {
+		return 0, errors.Wrap(err, "beginning transaction")
+	}
+	defer tx.Rollback()
+
+	// Votes are locked against casting until the rebuild commits so none cast
+	// after the log is read gets lost. Reading them goes on.
+	if _, err := tx.ExecContext(ctx, `LOCK TABLE vote IN EXCLUSIVE MODE`); err != nil {
+		return 0, errors.Wrap(err, "locking votes")
+	}
+
+	b := ballots{}
+	if err := readLog(ctx, tx, from, to, "", b.apply); err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM vote WHERE date >= $1 AND date <= $2`, day(from), day(to)); err != nil {
+		return 0, errors.Wrap(err, "deleting votes")
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM menu_vote_tally WHERE date >= $1 AND date <= $2`, day(from), day(to)); err != nil {
+		return 0, errors.Wrap(err, "deleting tallies")
+	}
+
+	const qv = `INSERT INTO vote
+		(date, user_id, restaurant_id, org_id, weight, cast_by, time_voted)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	for _, v := range b {
+		if _, err := tx.ExecContext(ctx, qv, v.Date, v.UserID, v.RestaurantID, v.OrgID, v.Weight, v.CastBy, v.TimeVoted); err != nil {
+			return 0, errors.Wrap(err, "inserting vote")
+		}
+	}
+
+	const qt = `INSERT INTO menu_vote_tally (date, restaurant_id, votes)
+		SELECT date, restaurant_id, SUM(weight) FROM vote
+		WHERE date >= $1 AND date <= $2
+		GROUP BY date, restaurant_id`
+	if _, err := tx.ExecContext(ctx, qt, day(from), day(to)); err != nil {
+		return 0, errors.Wrap(err, "inserting tallies")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, errors.Wrap(err, "committing votes")
+	}
+
+	return len(b), nil
+}
+
+// ballot identifies the single vote a user has for a date.
+type ballot struct {
+	date   string
+	userID string
+}
+
+// ballots are the votes left standing by the events applied so far.
+type ballots map[ballot]Vote
+
+// apply folds the event into the votes. Casting and changing a vote both
+// leave the restaurant of the event as the user's vote, retracting removes it.
+func (b ballots) apply(e Event) error {
+	k := ballot{date: e.Date.Format("2006-01-02"), userID: e.UserID}
+
+	switch e.Type {
+	case EventCast, EventChanged:
+		b[k] = Vote{
+			Date:         e.Date,
+			UserID:       e.UserID,
+			RestaurantID: e.RestaurantID,
+			OrgID:        e.OrgID,
+			Weight:       e.Weight,
+			CastBy:       e.CastBy,
+			TimeVoted:    e.TimeOccurred,
+		}
+	case EventRetracted:
+		delete(b, k)
+	default:
+		return errors.Errorf("unknown vote event type %q", e.Type)
+	}
+
+	return nil
+}
+
+// tallies sums the weights of the votes for each restaurant, most votes
+// first. Ties go to
+// the restaurant which received its first vote earliest like they do when
+// the winner is computed.
+func (b ballots) tallies() []Tally {
+	counts := map[string]*Tally{}
+	first := map[string]time.Time{}
+	for _, v := range b {
+		t, ok := counts[v.RestaurantID]
+		if !ok {
+			t = &Tally{RestaurantID: v.RestaurantID}
+			counts[v.RestaurantID] = t
+			first[v.RestaurantID] = v.TimeVoted
+		}
+		t.Votes += v.Weight
+		if v.TimeVoted.Before(first[v.RestaurantID]) {
+			first[v.RestaurantID] = v.TimeVoted
+		}
+	}
+
+	tallies := make([]Tally, 0, len(counts))
+	for _, t := range counts {
+		tallies = append(tallies, *t)
+	}
+	sort.Slice(tallies, func(i, j int) bool {
+		ti, tj := tallies[i], tallies[j]
+		if ti.Votes != tj.Votes {
+			return ti.Votes > tj.Votes
+		}
+		if fi, fj := first[ti.RestaurantID], first[tj.RestaurantID]; !fi.Equal(fj) {
+			return fi.Before(fj)
+		}
+		return ti.RestaurantID < tj.RestaurantID
+	})
+
+	return tallies
+}
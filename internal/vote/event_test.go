@@ -0,0 +1,81 @@
+package vote
+
+import (
+	"testing"
+	"time"
+
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// TestReplay validates replaying the vote log leaves the last choice of each
+// user standing and counts the tallies like the winner is computed.
+func TestReplay(t *testing.T) {
+	monday := time.Date(2020, time.March, 2, 0, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return monday.Add(10*time.Hour + time.Duration(minutes)*time.Minute) }
+
+	t.Log("Given the need to derive the votes from the vote log.")
+	{
+		t.Log("\tTest 0:\tWhen votes are cast, changed and retracted.")
+		{
+			events := []Event{
+				{Type: EventCast, Date: monday, UserID: "u1", RestaurantID: "a", Weight: 1, TimeOccurred: at(0)},
+				{Type: EventCast, Date: monday, UserID: "u2", RestaurantID: "b", Weight: 1, TimeOccurred: at(1)},
+				{Type: EventCast, Date: monday, UserID: "u3", RestaurantID: "a", Weight: 1, TimeOccurred: at(2)},
+				{Type: EventChanged, Date: monday, UserID: "u3", RestaurantID: "b", Weight: 1, TimeOccurred: at(3)},
+				{Type: EventCast, Date: monday, UserID: "u4", RestaurantID: "c", Weight: 1, TimeOccurred: at(4)},
+				{Type: EventRetracted, Date: monday, UserID: "u4", RestaurantID: "c", Weight: 1, TimeOccurred: at(5)},
+			}
+
+			b := ballots{}
+			for _, e := range events {
+				if err := b.apply(e); err != nil {
+					t.Fatalf("\t%s\tShould apply the event : %s.", tests.Failed, err)
+				}
+			}
+			if len(b) != 3 {
+				t.Fatalf("\t%s\tShould leave 3 votes standing : got %d.", tests.Failed, len(b))
+			}
+			t.Logf("\t%s\tShould leave 3 votes standing.", tests.Success)
+
+			got := b.tallies()
+			want := []Tally{{RestaurantID: "b", Votes: 2}, {RestaurantID: "a", Votes: 1}}
+			if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+				t.Fatalf("\t%s\tShould count the remaining votes : got %+v, want %+v.", tests.Failed, got, want)
+			}
+			t.Logf("\t%s\tShould count the remaining votes.", tests.Success)
+		}
+
+		t.Log("\tTest 1:\tWhen restaurants tie.")
+		{
+			b := ballots{}
+			b.apply(Event{Type: EventCast, Date: monday, UserID: "u1", RestaurantID: "late", Weight: 1, TimeOccurred: at(5)})
+			b.apply(Event{Type: EventCast, Date: monday, UserID: "u2", RestaurantID: "early", Weight: 1, TimeOccurred: at(1)})
+
+			if got := b.tallies(); got[0].RestaurantID != "early" {
+				t.Fatalf("\t%s\tShould rank the restaurant voted for first ahead : got %+v.", tests.Failed, got)
+			}
+			t.Logf("\t%s\tShould rank the restaurant voted for first ahead.", tests.Success)
+		}
+
+		t.Log("\tTest 2:\tWhen votes have weights.")
+		{
+			b := ballots{}
+			b.apply(Event{Type: EventCast, Date: monday, UserID: "lead", RestaurantID: "a", Weight: 3, TimeOccurred: at(2)})
+			b.apply(Event{Type: EventCast, Date: monday, UserID: "u1", RestaurantID: "b", Weight: 1, TimeOccurred: at(0)})
+			b.apply(Event{Type: EventCast, Date: monday, UserID: "u2", RestaurantID: "b", Weight: 1, CastBy: "u1", TimeOccurred: at(1)})
+
+			if got := b.tallies(); got[0] != (Tally{RestaurantID: "a", Votes: 3}) {
+				t.Fatalf("\t%s\tShould count the weights of the votes : got %+v.", tests.Failed, got)
+			}
+			t.Logf("\t%s\tShould count the weights of the votes.", tests.Success)
+		}
+
+		t.Log("\tTest 3:\tWhen an event has an unknown type.")
+		{
+			if err := (ballots{}).apply(Event{Type: "stuffed"}); err == nil {
+				t.Fatalf("\t%s\tShould fail to apply it.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould fail to apply it.", tests.Success)
+		}
+	}
+}
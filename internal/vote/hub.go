@@ -0,0 +1,106 @@
+package vote
+
+import (
+	"sync"
+	"time"
+)
+
+// Hub tells the watchers of a date when votes for it change. It knows about
+// the votes cast through this instance of the service, and about the others
+// when it is told by a change.Feed.
+type Hub struct {
+	mu     sync.Mutex
+	subs   map[chan struct{}]time.Time
+	closed bool
+}
+
+// NewHub constructs an empty Hub.
+func NewHub() *Hub {
+	return &Hub{
+		subs: make(map[chan struct{}]time.Time),
+	}
+}
+
+// Subscribe watches the votes of the date. The channel receives a value
+// after one or more votes changed and is closed when the Hub is closed. The
+// returned function stops watching and must be called once done.
+func (h *Hub) Subscribe(date time.Time) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	h.subs[ch] = day(date)
+
+	unsubscribe := func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		if _, ok := h.subs[ch]; ok {
+			delete(h.subs, ch)
+			close(ch)
+		}
+	}
+
+	return ch, unsubscribe
+}
+
+// Notify tells the watchers of the date its votes changed. It never blocks;
+// a watcher which did not pick up the previous change yet sees both at once.
+// Notify on a nil Hub does nothing.
+func (h *Hub) Notify(date time.Time) {
+	if h == nil {
+		return
+	}
+
+	date = day(date)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch, d := range h.subs {
+		if !d.Equal(date) {
+			continue
+		}
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// NotifyAll tells every watcher the votes of its date may have changed, as
+// when changes made elsewhere could have been missed. NotifyAll on a nil Hub
+// does nothing.
+func (h *Hub) NotifyAll() {
+	if h == nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.subs {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Close ends every subscription so open streams finish when the service
+// shuts down. Later subscriptions end right away.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.subs {
+		delete(h.subs, ch)
+		close(ch)
+	}
+	h.closed = true
+}
@@ -0,0 +1,71 @@
+package vote
+
+import (
+	"testing"
+	"time"
+
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// TestHub validates watchers learn about the votes of their date only.
+func TestHub(t *testing.T) {
+	monday := time.Date(2020, time.March, 2, 0, 0, 0, 0, time.UTC)
+	tuesday := monday.AddDate(0, 0, 1)
+
+	t.Log("Given the need to watch votes change.")
+	{
+		h := NewHub()
+
+		mon, stopMon := h.Subscribe(monday)
+		tue, stopTue := h.Subscribe(tuesday)
+		defer stopTue()
+
+		t.Log("\tTest 0:\tWhen votes for a date change twice.")
+		{
+			h.Notify(monday.Add(10 * time.Hour))
+			h.Notify(monday)
+
+			select {
+			case <-mon:
+				t.Logf("\t%s\tShould notify the watcher of the date.", tests.Success)
+			default:
+				t.Fatalf("\t%s\tShould notify the watcher of the date.", tests.Failed)
+			}
+
+			select {
+			case <-mon:
+				t.Fatalf("\t%s\tShould coalesce changes not picked up yet.", tests.Failed)
+			case <-tue:
+				t.Fatalf("\t%s\tShould not notify watchers of other dates.", tests.Failed)
+			default:
+				t.Logf("\t%s\tShould coalesce changes and leave other dates alone.", tests.Success)
+			}
+		}
+
+		t.Log("\tTest 1:\tWhen a watcher stops.")
+		{
+			stopMon()
+			stopMon()
+			h.Notify(monday)
+
+			if _, ok := <-mon; ok {
+				t.Fatalf("\t%s\tShould close the channel.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould close the channel.", tests.Success)
+		}
+
+		t.Log("\tTest 2:\tWhen the hub is closed.")
+		{
+			h.Close()
+
+			if _, ok := <-tue; ok {
+				t.Fatalf("\t%s\tShould end open subscriptions.", tests.Failed)
+			}
+			late, _ := h.Subscribe(monday)
+			if _, ok := <-late; ok {
+				t.Fatalf("\t%s\tShould end later subscriptions.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould end open and later subscriptions.", tests.Success)
+		}
+	}
+}
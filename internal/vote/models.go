@@ -0,0 +1,183 @@
+package vote
+
+import "time"
+
+// Vote is a user's choice of restaurant for a lunch date. A user has a
+// single vote per date which is replaced when they vote again. Weight is the
+// number of votes it counts as. CastBy is the delegate who cast the vote on
+// behalf of the user, blank when they voted themselves.
+type Vote struct {
+	Date         time.Time `db:"date" json:"date"`
+	UserID       string    `db:"user_id" json:"user_id"`
+	RestaurantID string    `db:"restaurant_id" json:"restaurant_id"`
+	OrgID        string    `db:"org_id" json:"-"`
+	Weight       int       `db:"weight" json:"weight"`
+	CastBy       string    `db:"cast_by" json:"cast_by,omitempty"`
+	TimeVoted    time.Time `db:"time_voted" json:"time_voted"`
+}
+
+// These are the types of the events of the vote log.
+const (
+	EventCast      = "cast"
+	EventChanged   = "changed"
+	EventRetracted = "retracted"
+)
+
+// Event is an entry of the vote log. The votes and tallies are derived from
+// the log, which is only ever appended to, so they can be audited and
+// rebuilt by replaying it in the order of Seq. RestaurantID is the restaurant
+// voted for or, for a retraction, the one the retracted vote was for.
+type Event struct {
+	Seq          int64     `db:"seq" json:"seq"`
+	Type         string    `db:"type" json:"type"`
+	Date         time.Time `db:"date" json:"date"`
+	UserID       string    `db:"user_id" json:"user_id"`
+	RestaurantID string    `db:"restaurant_id" json:"restaurant_id"`
+	OrgID        string    `db:"org_id" json:"org_id"`
+	Weight       int       `db:"weight" json:"weight"`
+	CastBy       string    `db:"cast_by" json:"cast_by,omitempty"`
+	TimeOccurred time.Time `db:"time_occurred" json:"time_occurred"`
+}
+
+// NewVote is what we require from clients when casting a Vote. Date is the
+// lunch the vote is for and defaults to today when it is not provided. Count
+// is the number of votes cast at once, 1 when it is not provided, up to the
+// weight the caller is allowed. OnBehalfOf is the user who delegated their
+// vote to the caller when voting for them.
+type NewVote struct {
+	RestaurantID string `json:"restaurant_id" validate:"required,uuid"`
+	Date         string `json:"date"`
+	Count        int    `json:"count" validate:"omitempty,min=1"`
+	OnBehalfOf   string `json:"on_behalf_of" validate:"omitempty,uuid"`
+}
+
+// Tally is the number of votes a restaurant received for a date.
+type Tally struct {
+	RestaurantID string `db:"restaurant_id" json:"restaurant_id"`
+	Votes        int    `db:"votes" json:"votes"`
+}
+
+// Winner is the restaurant chosen by an organization, or by one of its teams
+// when TeamID is set, for a date once voting has closed.
+type Winner struct {
+	Date         time.Time `db:"date" json:"date"`
+	RestaurantID string    `db:"restaurant_id" json:"restaurant_id"`
+	OrgID        string    `db:"org_id" json:"org_id"`
+	TeamID       string    `db:"team_id" json:"team_id,omitempty"`
+	Votes        int       `db:"votes" json:"votes"`
+	DateComputed time.Time `db:"date_computed" json:"date_computed"`
+}
+
+// Weight allows the users matching it to cast up to MaxCount votes at once,
+// like a team lead voting for the members who are away. Blank Role and
+// TeamID match every user, otherwise users must have the role and be a
+// member of the team. Users matching no weight cast a single vote.
+type Weight struct {
+	ID          string    `db:"weight_id" json:"id"`
+	OrgID       string    `db:"org_id" json:"org_id"`
+	Role        string    `db:"role" json:"role,omitempty"`
+	TeamID      *string   `db:"team_id" json:"team_id,omitempty"`
+	MaxCount    int       `db:"max_count" json:"max_count"`
+	DateCreated time.Time `db:"date_created" json:"date_created"`
+}
+
+// NewWeight is what we require from admins when adding a Weight.
+type NewWeight struct {
+	Role     string  `json:"role"`
+	TeamID   *string `json:"team_id" validate:"omitempty,uuid"`
+	MaxCount int     `json:"max_count" validate:"required,min=1,max=100"`
+}
+
+// Delegation authorizes the delegate to vote on behalf of the user.
+type Delegation struct {
+	UserID      string    `db:"user_id" json:"user_id"`
+	DelegateID  string    `db:"delegate_id" json:"delegate_id"`
+	OrgID       string    `db:"org_id" json:"-"`
+	DateCreated time.Time `db:"date_created" json:"date_created"`
+}
+
+// NewDelegation is what we require from users delegating their vote.
+type NewDelegation struct {
+	DelegateID string `json:"delegate_id" validate:"required,uuid"`
+}
+
+// DishVote is a user's choice of menu item in one category for a lunch date
+// of an organization voting for dishes. A user has a single vote per
+// category and date which is replaced when they vote again.
+type DishVote struct {
+	Date         time.Time `db:"date" json:"date"`
+	UserID       string    `db:"user_id" json:"user_id"`
+	Category     string    `db:"category" json:"category"`
+	MenuID       string    `db:"menu_id" json:"menu_id"`
+	RestaurantID string    `db:"restaurant_id" json:"restaurant_id"`
+	Item         string    `db:"item" json:"item"`
+	OrgID        string    `db:"org_id" json:"-"`
+	TimeVoted    time.Time `db:"time_voted" json:"time_voted"`
+}
+
+// NewDishVote is what we require from clients when casting a DishVote. Item
+// is the name of the item in the menu, which must be served on the date.
+type NewDishVote struct {
+	MenuID string `json:"menu_id" validate:"required,uuid"`
+	Item   string `json:"item" validate:"required"`
+	Date   string `json:"date"`
+}
+
+// DishTally is the number of votes a menu item received for a date.
+type DishTally struct {
+	Category     string `db:"category" json:"category"`
+	MenuID       string `db:"menu_id" json:"menu_id"`
+	RestaurantID string `db:"restaurant_id" json:"restaurant_id"`
+	Item         string `db:"item" json:"item"`
+	Votes        int    `db:"votes" json:"votes"`
+}
+
+// DishWinner is the menu item chosen by an organization in a category for a
+// date once voting has closed.
+type DishWinner struct {
+	Date         time.Time `db:"date" json:"date"`
+	OrgID        string    `db:"org_id" json:"org_id"`
+	Category     string    `db:"category" json:"category"`
+	MenuID       string    `db:"menu_id" json:"menu_id"`
+	RestaurantID string    `db:"restaurant_id" json:"restaurant_id"`
+	Item         string    `db:"item" json:"item"`
+	Votes        int       `db:"votes" json:"votes"`
+	DateComputed time.Time `db:"date_computed" json:"date_computed"`
+}
+
+// Policy bounds when votes may be cast. Voting for a date closes at Deadline
+// on that day in Location, UTC when it is nil, and is open at most
+// MaxDaysAhead days in advance.
+type Policy struct {
+	MaxDaysAhead int
+	Deadline     time.Duration
+	Location     *time.Location
+}
+
+// closes returns the time voting for the date closes.
+func (p Policy) closes(date time.Time) time.Time {
+	if p.Location == nil {
+		return date.Add(p.Deadline)
+	}
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, p.Location).Add(p.Deadline).UTC()
+}
+
+// Today returns the date of now in the location of the policy.
+func (p Policy) Today(now time.Time) time.Time {
+	if p.Location != nil {
+		now = now.In(p.Location)
+	}
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// Check returns ErrClosed when voting for the date has closed by now and
+// ErrTooEarly when it has not opened yet.
+func (p Policy) Check(date, now time.Time) error {
+	if !now.UTC().Before(p.closes(date)) {
+		return ErrClosed
+	}
+	if date.After(p.Today(now).AddDate(0, 0, p.MaxDaysAhead)) {
+		return ErrTooEarly
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package vote
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/platform/clock"
+	"github.com/remisb/restaurant/internal/platform/database"
+	"github.com/remisb/restaurant/internal/platform/job"
+	"go.opentelemetry.io/otel"
+)
+
+// Reconciler repairs the vote tallies which drifted from the votes, like when
+// concurrent first votes of a user both counted. Only the dates from
+// yesterday on are checked, older ones were repaired before they closed.
+type Reconciler struct {
+	log      *log.Logger
+	db       *sqlx.DB
+	interval time.Duration
+	tracker  *job.Tracker
+	clock    clock.Clock
+}
+
+// NewReconciler constructs a Reconciler checking the tallies every interval.
+func NewReconciler(log *log.Logger, db *sqlx.DB, interval time.Duration) *Reconciler {
+	return &Reconciler{
+		log:      log,
+		db:       db,
+		interval: interval,
+		tracker:  job.NewTracker("vote_tally"),
+		clock:    clock.System,
+	}
+}
+
+// Clock sets the clock telling which dates are checked, the clock of the
+// system by default. It must be called before Run.
+func (rc *Reconciler) Clock(c clock.Clock) *Reconciler {
+	rc.clock = c
+	return rc
+}
+
+// Status reports the state of the reconciler to the health check.
+func (rc *Reconciler) Status() job.Status {
+	return rc.tracker.Status()
+}
+
+// Run reconciles the tallies until the context is canceled.
+func (rc *Reconciler) Run(ctx context.Context) {
+	ticker := time.NewTicker(rc.interval)
+	defer ticker.Stop()
+
+	for {
+		now := rc.clock.Now()
+		n, err := ReconcileTallies(ctx, rc.db, day(now).AddDate(0, 0, -1))
+		switch {
+		case err != nil:
+			rc.log.Printf("vote : ERROR : %+v", err)
+		case n > 0:
+			rc.log.Printf("vote : repaired %d tallies", n)
+		}
+		rc.tracker.Record(err, now)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ReconcileTallies recounts the votes of the dates from the given one on and
+// fixes the tallies which differ. It returns the number of tallies fixed.
+func ReconcileTallies(ctx context.Context, db *sqlx.DB, from time.Time) (int64, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.vote.ReconcileTallies")
+	defer span.End()
+
+	tx, err := database.Begin(ctx, db)
+	if err != nil {
+		return 0, errors.Wrap(err, "beginning transaction")
+	}
+	defer tx.Rollback()
+
+	const qu = `INSERT INTO menu_vote_tally (date, restaurant_id, votes)
+		SELECT date, restaurant_id, SUM(weight) FROM vote
+		WHERE date >= $1
+		GROUP BY date, restaurant_id
+		ON CONFLICT (date, restaurant_id) DO UPDATE SET
+		"votes" = EXCLUDED.votes
+		WHERE menu_vote_tally.votes <> EXCLUDED.votes`
+	res, err := tx.ExecContext(ctx, qu, from)
+	if err != nil {
+		return 0, errors.Wrap(err, "recounting tallies")
+	}
+	updated, _ := res.RowsAffected()
+
+	// Tallies of restaurants which no longer have any vote are not recounted
+	// above, they are zeroed instead.
+	const qz = `UPDATE menu_vote_tally AS t SET "votes" = 0
+		WHERE t.date >= $1 AND t.votes <> 0 AND NOT EXISTS (
+			SELECT 1 FROM vote AS v
+			WHERE v.date = t.date AND v.restaurant_id = t.restaurant_id
+		)`
+	res, err = tx.ExecContext(ctx, qz, from)
+	if err != nil {
+		return 0, errors.Wrap(err, "zeroing tallies")
+	}
+	zeroed, _ := res.RowsAffected()
+
+	if err := tx.Commit(); err != nil {
+		return 0, errors.Wrap(err, "committing tallies")
+	}
+
+	return updated + zeroed, nil
+}
@@ -0,0 +1,281 @@
+package vote
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/remisb/restaurant/internal/notification"
+	"github.com/remisb/restaurant/internal/organization"
+	"github.com/remisb/restaurant/internal/platform/clock"
+	"github.com/remisb/restaurant/internal/platform/database"
+	"github.com/remisb/restaurant/internal/platform/job"
+	"github.com/remisb/restaurant/internal/webhook"
+)
+
+// Announcer tells about the winner of a date outside of the API, for example
+// by email or in a chat. Channel names the notification channel of the
+// organization settings it announces on.
+type Announcer interface {
+	Announce(ctx context.Context, w Winner) error
+	Channel() string
+}
+
+// Opener tells about the lunch of the day once voting for it opens, for
+// example by posting the menus in a chat.
+type Opener interface {
+	Open(ctx context.Context, date time.Time) error
+}
+
+// Scheduler computes the winner of every date once voting for it closes and
+// notifies the webhooks and the announcers of the outcome. When openers are
+// set it also tells them about the lunch of the day every morning.
+type Scheduler struct {
+	log        *log.Logger
+	db         *sqlx.DB
+	policy     Policy
+	interval   time.Duration
+	announcers []Announcer
+	openAt     time.Duration
+	openers    []Opener
+	opened     time.Time
+	tracker    *job.Tracker
+	clock      clock.Clock
+	leader     *database.Leader
+}
+
+// NewScheduler constructs a Scheduler checking for closed dates every
+// interval.
+func NewScheduler(log *log.Logger, db *sqlx.DB, policy Policy, interval time.Duration, announcers ...Announcer) *Scheduler {
+	return &Scheduler{
+		log:        log,
+		db:         db,
+		policy:     policy,
+		interval:   interval,
+		announcers: announcers,
+		tracker:    job.NewTracker("vote_winner"),
+		clock:      clock.System,
+	}
+}
+
+// Clock sets the clock telling when voting closes, the clock of the system
+// by default. It must be called before Run.
+func (s *Scheduler) Clock(c clock.Clock) *Scheduler {
+	s.clock = c
+	return s
+}
+
+// OpenAt sets the openers told about the lunch of the day at the time of day
+// at, until voting for it closes. It must be called before Run. The openers
+// are told again when the service restarts during that time.
+func (s *Scheduler) OpenAt(at time.Duration, openers ...Opener) *Scheduler {
+	s.openAt = at
+	s.openers = openers
+	return s
+}
+
+// Leader sets the election the scheduler must win to compute the winners,
+// so that only one replica of the service computes and announces them. It
+// must be called before Run. Without it the scheduler always runs.
+func (s *Scheduler) Leader(l *database.Leader) *Scheduler {
+	s.leader = l
+	return s
+}
+
+// Status reports the state of the scheduler to the health check.
+func (s *Scheduler) Status() job.Status {
+	return s.tracker.Status()
+}
+
+// Run computes winners until the context is canceled.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	defer s.resign()
+
+	for {
+		if s.lead(ctx) {
+			s.tick(ctx, s.clock.Now())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// lead reports whether the scheduler may run, standing for election when it
+// has a leader to elect. A replica taking over tells the openers again when
+// voting for the lunch of the day is still open.
+func (s *Scheduler) lead(ctx context.Context) bool {
+	if s.leader == nil {
+		return true
+	}
+
+	leading, err := s.leader.Elect(ctx)
+	if err != nil {
+		s.log.Printf("vote : election : ERROR : %+v", err)
+	}
+	s.tracker.Elected(leading, s.leader.Since())
+	return leading
+}
+
+// resign lets another replica compute the winners once the scheduler stops.
+func (s *Scheduler) resign() {
+	if s.leader == nil {
+		return
+	}
+
+	if err := s.leader.Resign(); err != nil {
+		s.log.Printf("vote : election : ERROR : %+v", err)
+	}
+	s.tracker.Elected(false, time.Time{})
+}
+
+// tick tells the openers about the lunch of the day and computes the winner
+// of every closed date still missing one, in both voting modes.
+func (s *Scheduler) tick(ctx context.Context, now time.Time) {
+	failed := s.open(ctx, now)
+
+	dates, err := pendingDates(ctx, s.db, s.policy, now)
+	if err != nil {
+		s.log.Printf("vote : ERROR : %+v", err)
+		s.tracker.Record(err, now)
+		return
+	}
+
+	for _, d := range dates {
+		date := d.Date.Format("2006-01-02")
+
+		w, err := ComputeWinner(ctx, s.db, d.Date, d.OrgID, now)
+		if err != nil {
+			s.log.Printf("vote : %s : %s : ERROR : %+v", date, d.OrgID, err)
+			failed = err
+			continue
+		}
+		s.log.Printf("vote : %s : %s : winner %s with %d votes", date, d.OrgID, w.RestaurantID, w.Votes)
+
+		// The teams are only computed along with their organization so a
+		// failure leaves them without a winner for the date.
+		tws, err := ComputeTeamWinners(ctx, s.db, d.Date, d.OrgID, now)
+		if err != nil {
+			s.log.Printf("vote : %s : %s : teams : ERROR : %+v", date, d.OrgID, err)
+			failed = err
+		}
+		for _, tw := range tws {
+			s.log.Printf("vote : %s : %s : team %s : winner %s with %d votes", date, d.OrgID, tw.TeamID, tw.RestaurantID, tw.Votes)
+		}
+
+		settings, err := organization.LoadSettings(ctx, s.db, d.OrgID)
+		if err != nil {
+			s.log.Printf("vote : %s : %s : ERROR : %+v", date, d.OrgID, err)
+			failed = err
+			continue
+		}
+
+		if err := s.notify(ctx, *w, settings, now); err != nil {
+			s.log.Printf("vote : %s : %s : ERROR : %+v", date, d.OrgID, err)
+			failed = err
+		}
+
+		// An announcer failing does not keep the others from announcing.
+		for _, a := range s.announcers {
+			if !settings.Notifies(a.Channel()) {
+				continue
+			}
+			if err := a.Announce(ctx, *w); err != nil {
+				s.log.Printf("vote : %s : %s : announcing : ERROR : %+v", date, d.OrgID, err)
+				failed = err
+			}
+		}
+	}
+
+	if err := s.computeDishes(ctx, now); err != nil {
+		failed = err
+	}
+
+	s.tracker.Record(failed, now)
+}
+
+// computeDishes computes the winners of every closed date of the
+// organizations voting for dishes still missing them.
+func (s *Scheduler) computeDishes(ctx context.Context, now time.Time) error {
+	dates, err := pendingDishDates(ctx, s.db, s.policy, now)
+	if err != nil {
+		s.log.Printf("vote : dishes : ERROR : %+v", err)
+		return err
+	}
+
+	var failed error
+	for _, d := range dates {
+		date := d.Date.Format("2006-01-02")
+
+		ws, err := ComputeDishWinners(ctx, s.db, d.Date, d.OrgID, now)
+		if err != nil {
+			s.log.Printf("vote : %s : %s : dishes : ERROR : %+v", date, d.OrgID, err)
+			failed = err
+			continue
+		}
+		for _, w := range ws {
+			s.log.Printf("vote : %s : %s : %s : winner %q with %d votes", date, d.OrgID, w.Category, w.Item, w.Votes)
+		}
+	}
+	return failed
+}
+
+// open tells the openers about the lunch of the day once per day, between
+// the time they are told at and the deadline.
+func (s *Scheduler) open(ctx context.Context, now time.Time) error {
+	today := day(now)
+	if len(s.openers) == 0 || !s.opened.Before(today) {
+		return nil
+	}
+	if now.Before(today.Add(s.openAt)) || s.policy.Check(today, now) == ErrClosed {
+		return nil
+	}
+	s.opened = today
+
+	var failed error
+	for _, o := range s.openers {
+		if err := o.Open(ctx, today); err != nil {
+			s.log.Printf("vote : %s : opening : ERROR : %+v", today.Format("2006-01-02"), err)
+			failed = err
+		}
+	}
+	return failed
+}
+
+// notify queues the closing of the vote with its final tallies followed by
+// the announcement of the winner, which the voters are notified of as well
+// unless the organization turned in-app notifications off.
+func (s *Scheduler) notify(ctx context.Context, w Winner, settings *organization.Settings, now time.Time) error {
+	tallies, err := tallies(ctx, s.db, w.Date, w.OrgID)
+	if err != nil {
+		return err
+	}
+
+	closed := struct {
+		Date    time.Time `json:"date"`
+		OrgID   string    `json:"org_id"`
+		Tallies []Tally   `json:"tallies"`
+	}{
+		Date:    w.Date,
+		OrgID:   w.OrgID,
+		Tallies: tallies,
+	}
+	if err := webhook.Enqueue(ctx, s.db, w.OrgID, webhook.EventVoteClosed, closed, now); err != nil {
+		return err
+	}
+
+	if err := webhook.Enqueue(ctx, s.db, w.OrgID, webhook.EventWinnerAnnounced, w, now); err != nil {
+		return err
+	}
+
+	if !settings.Notifies(organization.ChannelInApp) {
+		return nil
+	}
+	return notification.WinnerAnnounced(ctx, s.db, w.Date, w.OrgID, w.RestaurantID, w, now)
+}
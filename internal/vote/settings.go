@@ -0,0 +1,105 @@
+package vote
+
+import (
+	"context"
+	"hash/fnv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/organization"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/database"
+)
+
+// OrgPolicy returns the policy of the organization, the deadline and time
+// zone of its settings replacing those of p when set. The settings are read
+// every time so changing them takes effect right away.
+func OrgPolicy(ctx context.Context, q sqlx.QueryerContext, org string, p Policy) (Policy, error) {
+	s, err := organization.LoadSettings(ctx, q, org)
+	if err != nil {
+		return Policy{}, err
+	}
+	if d, ok := s.Deadline(); ok {
+		p.Deadline = d
+	}
+	if s.Timezone != "" {
+		p.Location = s.Location()
+	}
+	return p, nil
+}
+
+// Today returns the date votes are cast for by now in the organization of the
+// claims in ctx, in its time zone or else the one of the policy.
+func Today(ctx context.Context, db *sqlx.DB, policy Policy, now time.Time) (time.Time, error) {
+	policy, err := OrgPolicy(ctx, database.Conn(ctx, db), auth.Org(ctx), policy)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return policy.Today(now), nil
+}
+
+// recentWinners returns the restaurants which won the lunches of the
+// organization before the date, the latest first.
+func recentWinners(ctx context.Context, q sqlx.QueryerContext, org string, date time.Time, n int) ([]string, error) {
+	ids := []string{}
+	const qs = `SELECT restaurant_id FROM winner
+		WHERE org_id = $1 AND date < $2
+		ORDER BY date DESC
+		LIMIT $3`
+	if err := sqlx.SelectContext(ctx, q, &ids, qs, org, date, n); err != nil {
+		return nil, errors.Wrap(err, "selecting recent winners")
+	}
+	return ids, nil
+}
+
+// barred returns the restaurant which won all of the n recent lunches, blank
+// when none did or no limit applies.
+func barred(recent []string, n int) string {
+	if n <= 0 || len(recent) < n {
+		return ""
+	}
+	for _, id := range recent[:n] {
+		if id != recent[0] {
+			return ""
+		}
+	}
+	return recent[0]
+}
+
+// pick returns the winner among the tallies, ordered by most votes first and
+// then by earliest vote. The barred restaurant only wins when nobody voted
+// for another one. Ties are broken the way the organization chose.
+func pick(tallies []Tally, date time.Time, tieBreak, barred string) Tally {
+	candidates := make([]Tally, 0, len(tallies))
+	for _, t := range tallies {
+		if t.RestaurantID != barred {
+			candidates = append(candidates, t)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = tallies
+	}
+
+	best := candidates[0]
+	if tieBreak != organization.TieRandom {
+		return best
+	}
+	for _, t := range candidates[1:] {
+		if t.Votes != best.Votes {
+			break
+		}
+		if draw(date, t.RestaurantID) < draw(date, best.RestaurantID) {
+			best = t
+		}
+	}
+	return best
+}
+
+// draw returns the number a restaurant draws for the date. Drawing by hash
+// gives the same outcome every time the winner is computed.
+func draw(date time.Time, restaurantID string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(date.Format("2006-01-02") + restaurantID))
+	return h.Sum32()
+}
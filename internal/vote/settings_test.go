@@ -0,0 +1,95 @@
+package vote
+
+import (
+	"testing"
+	"time"
+
+	"github.com/remisb/restaurant/internal/organization"
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// TestPick validates the winner follows the streak limit and the tie-break
+// of the organization.
+func TestPick(t *testing.T) {
+	date := time.Date(2020, time.March, 2, 0, 0, 0, 0, time.UTC)
+	tallies := []Tally{
+		{RestaurantID: "a", Votes: 3},
+		{RestaurantID: "b", Votes: 3},
+		{RestaurantID: "c", Votes: 3},
+		{RestaurantID: "d", Votes: 1},
+	}
+
+	t.Log("Given the need to pick the winner among the tallies.")
+	{
+		t.Log("\tTest 0:\tWhen ties go to the first vote.")
+		{
+			if got := pick(tallies, date, organization.TieFirstVote, ""); got.RestaurantID != "a" {
+				t.Fatalf("\t%s\tShould pick the first restaurant : got %q.", tests.Failed, got.RestaurantID)
+			}
+			t.Logf("\t%s\tShould pick the first restaurant.", tests.Success)
+		}
+
+		t.Log("\tTest 1:\tWhen ties are drawn.")
+		{
+			got := pick(tallies, date, organization.TieRandom, "")
+			if got.Votes != 3 {
+				t.Fatalf("\t%s\tShould pick a tied restaurant : got %+v.", tests.Failed, got)
+			}
+			t.Logf("\t%s\tShould pick a tied restaurant.", tests.Success)
+
+			if again := pick(tallies, date, organization.TieRandom, ""); again != got {
+				t.Fatalf("\t%s\tShould draw the same restaurant again : got %+v.", tests.Failed, again)
+			}
+			t.Logf("\t%s\tShould draw the same restaurant again.", tests.Success)
+		}
+
+		t.Log("\tTest 2:\tWhen the leading restaurant reached the streak limit.")
+		{
+			recent := []string{"a", "a", "b"}
+			if got := pick(tallies, date, organization.TieFirstVote, barred(recent, 2)); got.RestaurantID != "b" {
+				t.Fatalf("\t%s\tShould pick the next restaurant : got %q.", tests.Failed, got.RestaurantID)
+			}
+			t.Logf("\t%s\tShould pick the next restaurant.", tests.Success)
+
+			if b := barred(recent, 3); b != "" {
+				t.Fatalf("\t%s\tShould only bar a restaurant winning the whole streak : got %q.", tests.Failed, b)
+			}
+			t.Logf("\t%s\tShould only bar a restaurant winning the whole streak.", tests.Success)
+
+			only := []Tally{{RestaurantID: "a", Votes: 2}}
+			if got := pick(only, date, organization.TieFirstVote, "a"); got.RestaurantID != "a" {
+				t.Fatalf("\t%s\tShould pick the barred restaurant when it is the only one : got %q.", tests.Failed, got.RestaurantID)
+			}
+			t.Logf("\t%s\tShould pick the barred restaurant when it is the only one.", tests.Success)
+		}
+	}
+}
+
+// TestPolicyLocation validates voting closes at the deadline in the time
+// zone of the organization.
+func TestPolicyLocation(t *testing.T) {
+	loc := time.FixedZone("UTC+2", 2*60*60)
+	p := Policy{MaxDaysAhead: 7, Deadline: 11 * time.Hour, Location: loc}
+	date := time.Date(2020, time.March, 2, 0, 0, 0, 0, time.UTC)
+
+	t.Log("Given the need to close voting in the time zone of the organization.")
+	{
+		t.Log("\tTest 0:\tWhen the deadline passed there but not in UTC.")
+		{
+			now := time.Date(2020, time.March, 2, 9, 30, 0, 0, time.UTC)
+			if err := p.Check(date, now); err != ErrClosed {
+				t.Fatalf("\t%s\tShould be closed : got %v.", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould be closed.", tests.Success)
+		}
+
+		t.Log("\tTest 1:\tWhen the next day started there but not in UTC.")
+		{
+			now := time.Date(2020, time.March, 1, 23, 0, 0, 0, time.UTC)
+			if got := p.Today(now); !got.Equal(date) {
+				t.Fatalf("\t%s\tShould be the local date : got %v.", tests.Failed, got)
+			}
+			t.Logf("\t%s\tShould be the local date.", tests.Success)
+		}
+	}
+}
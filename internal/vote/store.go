@@ -0,0 +1,113 @@
+package vote
+
+import (
+	"context"
+	"time"
+
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/database"
+)
+
+// Store is the set of vote operations used by the API handlers. It lets the
+// handlers run against an in-memory fake in unit tests.
+type Store interface {
+	Cast(ctx context.Context, user auth.Claims, nv NewVote, policy Policy, now time.Time) (*Vote, error)
+	Retract(ctx context.Context, user auth.Claims, date time.Time, policy Policy, now time.Time) error
+	Tallies(ctx context.Context, date time.Time) ([]Tally, error)
+	RetrieveTally(ctx context.Context, restaurantID string, date time.Time) (*Tally, error)
+	Voters(ctx context.Context, restaurantID string, date time.Time) ([]string, error)
+	RetrieveWinner(ctx context.Context, date time.Time) (*Winner, error)
+	TeamTallies(ctx context.Context, teamID string, date time.Time) ([]Tally, error)
+	RetrieveTeamWinner(ctx context.Context, teamID string, date time.Time) (*Winner, error)
+	History(ctx context.Context, from, to time.Time, fn func(Vote) error) error
+	Today(ctx context.Context, policy Policy, now time.Time) (time.Time, error)
+}
+
+// DBStore implements Store on top of the primary database. Every call ends
+// after the query timeout of the database but History, which streams the
+// votes for as long as the export takes.
+type DBStore struct {
+	db *database.DB
+}
+
+// NewStore constructs a Store backed by the database.
+func NewStore(db *database.DB) *DBStore {
+	return &DBStore{db: db}
+}
+
+// Cast implements the Store interface.
+func (s *DBStore) Cast(ctx context.Context, user auth.Claims, nv NewVote, policy Policy, now time.Time) (*Vote, error) {
+	ctx, cancel := s.db.Deadline(ctx)
+	defer cancel()
+
+	return Cast(ctx, s.db.Primary(), user, nv, policy, now)
+}
+
+// Retract implements the Store interface.
+func (s *DBStore) Retract(ctx context.Context, user auth.Claims, date time.Time, policy Policy, now time.Time) error {
+	ctx, cancel := s.db.Deadline(ctx)
+	defer cancel()
+
+	return Retract(ctx, s.db.Primary(), user, date, policy, now)
+}
+
+// Tallies implements the Store interface.
+func (s *DBStore) Tallies(ctx context.Context, date time.Time) ([]Tally, error) {
+	ctx, cancel := s.db.Deadline(ctx)
+	defer cancel()
+
+	return Tallies(ctx, s.db.Primary(), date)
+}
+
+// RetrieveTally implements the Store interface.
+func (s *DBStore) RetrieveTally(ctx context.Context, restaurantID string, date time.Time) (*Tally, error) {
+	ctx, cancel := s.db.Deadline(ctx)
+	defer cancel()
+
+	return RetrieveTally(ctx, s.db.Primary(), restaurantID, date)
+}
+
+// Voters implements the Store interface.
+func (s *DBStore) Voters(ctx context.Context, restaurantID string, date time.Time) ([]string, error) {
+	ctx, cancel := s.db.Deadline(ctx)
+	defer cancel()
+
+	return Voters(ctx, s.db.Primary(), restaurantID, date)
+}
+
+// RetrieveWinner implements the Store interface.
+func (s *DBStore) RetrieveWinner(ctx context.Context, date time.Time) (*Winner, error) {
+	ctx, cancel := s.db.Deadline(ctx)
+	defer cancel()
+
+	return RetrieveWinner(ctx, s.db.Primary(), date)
+}
+
+// TeamTallies implements the Store interface.
+func (s *DBStore) TeamTallies(ctx context.Context, teamID string, date time.Time) ([]Tally, error) {
+	ctx, cancel := s.db.Deadline(ctx)
+	defer cancel()
+
+	return TeamTallies(ctx, s.db.Primary(), teamID, date)
+}
+
+// RetrieveTeamWinner implements the Store interface.
+func (s *DBStore) RetrieveTeamWinner(ctx context.Context, teamID string, date time.Time) (*Winner, error) {
+	ctx, cancel := s.db.Deadline(ctx)
+	defer cancel()
+
+	return RetrieveTeamWinner(ctx, s.db.Primary(), teamID, date)
+}
+
+// History implements the Store interface.
+func (s *DBStore) History(ctx context.Context, from, to time.Time, fn func(Vote) error) error {
+	return History(ctx, s.db.Primary(), from, to, fn)
+}
+
+// Today implements the Store interface.
+func (s *DBStore) Today(ctx context.Context, policy Policy, now time.Time) (time.Time, error) {
+	ctx, cancel := s.db.Deadline(ctx)
+	defer cancel()
+
+	return Today(ctx, s.db.Primary(), policy, now)
+}
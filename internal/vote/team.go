@@ -0,0 +1,121 @@
+package vote
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/organization"
+	"github.com/remisb/restaurant/internal/platform/database"
+	"github.com/remisb/restaurant/internal/team"
+	"go.opentelemetry.io/otel"
+)
+
+// TeamTallies counts the votes the members of the team cast for each
+// restaurant on the date, most votes first. The team must belong to the
+// organization of the claims in ctx.
+func TeamTallies(ctx context.Context, db *sqlx.DB, teamID string, date time.Time) ([]Tally, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.vote.TeamTallies")
+	defer span.End()
+
+	t, err := team.Retrieve(ctx, db, teamID)
+	if err != nil {
+		return nil, err
+	}
+
+	return teamTallies(ctx, db, date, t.ID, t.OrgID)
+}
+
+// teamTallies counts the votes the members of the team cast in its
+// organization.
+func teamTallies(ctx context.Context, db *sqlx.DB, date time.Time, teamID, org string) ([]Tally, error) {
+	tallies := []Tally{}
+	const q = `SELECT v.restaurant_id, SUM(v.weight) AS votes FROM vote AS v
+		JOIN team_member AS m ON m.user_id = v.user_id
+		WHERE v.date = $1 AND m.team_id = $2 AND v.org_id = $3
+		GROUP BY v.restaurant_id
+		ORDER BY votes DESC, MIN(v.time_voted)`
+	if err := sqlx.SelectContext(ctx, database.Conn(ctx, db), &tallies, q, date, teamID, org); err != nil {
+		return nil, errors.Wrap(err, "selecting team tallies")
+	}
+
+	return tallies, nil
+}
+
+// RetrieveTeamWinner gets the winner computed for the date in the team. The
+// team must belong to the organization of the claims in ctx.
+func RetrieveTeamWinner(ctx context.Context, db *sqlx.DB, teamID string, date time.Time) (*Winner, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.vote.RetrieveTeamWinner")
+	defer span.End()
+
+	if _, err := team.Retrieve(ctx, db, teamID); err != nil {
+		return nil, err
+	}
+
+	var w Winner
+	const q = `SELECT w.*, t.org_id FROM team_winner AS w
+		JOIN team AS t ON t.team_id = w.team_id
+		WHERE w.date = $1 AND w.team_id = $2`
+	if err := sqlx.GetContext(ctx, database.Conn(ctx, db), &w, q, date, teamID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNoWinner
+		}
+		return nil, errors.Wrap(err, "selecting team winner")
+	}
+
+	return &w, nil
+}
+
+// ComputeTeamWinners stores the winner of the date of every team of the
+// organization whose members voted, the same way ComputeWinner does for the
+// whole organization. The streak limit only applies to the organization.
+func ComputeTeamWinners(ctx context.Context, db *sqlx.DB, date time.Time, org string, now time.Time) ([]Winner, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.vote.ComputeTeamWinners")
+	defer span.End()
+
+	var teams []string
+	const qt = `SELECT team_id FROM team WHERE org_id = $1 AND deleted_at IS NULL ORDER BY team_id`
+	if err := sqlx.SelectContext(ctx, database.Conn(ctx, db), &teams, qt, org); err != nil {
+		return nil, errors.Wrap(err, "selecting teams")
+	}
+
+	s, err := organization.LoadSettings(ctx, database.Conn(ctx, db), org)
+	if err != nil {
+		return nil, err
+	}
+
+	winners := []Winner{}
+	for _, id := range teams {
+		tallies, err := teamTallies(ctx, db, date, id, org)
+		if err != nil {
+			return nil, err
+		}
+		if len(tallies) == 0 {
+			continue
+		}
+
+		t := pick(tallies, date, s.TieBreak, "")
+		w := Winner{
+			Date:         date,
+			RestaurantID: t.RestaurantID,
+			OrgID:        org,
+			TeamID:       id,
+			Votes:        t.Votes,
+			DateComputed: now.UTC(),
+		}
+
+		const q = `INSERT INTO team_winner
+			(date, team_id, restaurant_id, votes, date_computed)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (date, team_id) DO NOTHING`
+		if _, err := database.Conn(ctx, db).ExecContext(ctx, q, w.Date, w.TeamID, w.RestaurantID, w.Votes, w.DateComputed); err != nil {
+			return nil, errors.Wrap(err, "inserting team winner")
+		}
+
+		winners = append(winners, w)
+	}
+
+	return winners, nil
+}
@@ -0,0 +1,552 @@
+// Package vote handles users voting for where to have lunch. Votes may be
+// cast for today or for a later date allowed by the Policy, and the winner
+// of each date is computed once voting for it has closed.
+package vote
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/organization"
+	"github.com/remisb/restaurant/internal/outbox"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/database"
+	"github.com/remisb/restaurant/internal/restaurant"
+	"go.opentelemetry.io/otel"
+)
+
+// Predefined errors identify expected failure conditions.
+var (
+	// ErrInvalidDate is used when a date is not formatted as YYYY-MM-DD.
+	ErrInvalidDate = errors.New("Date must be formatted as YYYY-MM-DD")
+
+	// ErrClosed is used when voting for the requested date has closed.
+	ErrClosed = errors.New("Voting for this date has closed")
+
+	// ErrTooEarly is used when the requested date is further ahead than the
+	// policy allows.
+	ErrTooEarly = errors.New("Voting for this date has not opened yet")
+
+	// ErrNoWinner is used when the winner of a date has not been computed.
+	ErrNoWinner = errors.New("Winner not computed")
+
+	// ErrLocked is used when a user changes or retracts their vote for a date
+	// whose voting has closed.
+	ErrLocked = errors.New("Votes cannot be changed or retracted after the deadline")
+
+	// ErrNotVoted is used when a user retracts a vote they have not cast.
+	ErrNotVoted = errors.New("Vote not found")
+
+	// ErrInvalidID is used when an invalid UUID is provided.
+	ErrInvalidID = errors.New("ID is not in its proper form")
+
+	// ErrWeightExceeded is used when a user casts more votes at once than the
+	// weights they match allow.
+	ErrWeightExceeded = errors.New("Vote count exceeds the weight allowed")
+
+	// ErrWeightNotFound is used when a specific Weight is requested but does
+	// not exist.
+	ErrWeightNotFound = errors.New("Vote weight not found")
+
+	// ErrNotDelegated is used when a user votes on behalf of another user who
+	// has not delegated their vote to them.
+	ErrNotDelegated = errors.New("User has not delegated their vote to you")
+
+	// ErrInvalidDelegate is used when a vote is delegated to the user
+	// themselves or to a user outside of their organization.
+	ErrInvalidDelegate = errors.New("Votes can only be delegated to another user of the organization")
+
+	// ErrWrongMode is used when a vote does not match the voting mode of the
+	// organization, like voting for a restaurant while it votes for dishes.
+	ErrWrongMode = errors.New("Vote does not match the voting mode of the organization")
+
+	// ErrItemNotFound is used when a dish vote is for an item missing from
+	// the menu.
+	ErrItemNotFound = errors.New("Menu item not found")
+
+	// ErrMenuDate is used when a dish vote is for a menu not served on the
+	// date voted for.
+	ErrMenuDate = errors.New("Menu is not served on the date voted for")
+
+	// ErrAnonymous is used when the voters of a restaurant are requested
+	// while its votes are anonymous.
+	ErrAnonymous = errors.New("Votes are anonymous")
+)
+
+// ParseDate parses a YYYY-MM-DD date. A blank date means the day of now.
+func ParseDate(date string, now time.Time) (time.Time, error) {
+	if date == "" {
+		return day(now), nil
+	}
+
+	d, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return time.Time{}, ErrInvalidDate
+	}
+
+	return d, nil
+}
+
+// Cast records the user's vote for a restaurant on the date given in the
+// NewVote. A previous vote of the user for the same date is replaced until
+// voting closes, changing it afterwards fails with ErrLocked. A vote cast on
+// behalf of another user, who must have delegated their vote to the caller,
+// replaces the vote of that user and counts once. The deadline and time zone
+// of the organization replace those of the policy when set.
+func Cast(ctx context.Context, db *sqlx.DB, user auth.Claims, nv NewVote, policy Policy, now time.Time) (*Vote, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.vote.Cast")
+	defer span.End()
+
+	policy, err := OrgPolicy(ctx, database.Conn(ctx, db), user.Org(), policy)
+	if err != nil {
+		return nil, err
+	}
+
+	date, err := ParseDate(nv.Date, policy.Today(now))
+	if err != nil {
+		return nil, err
+	}
+
+	v := Vote{
+		Date:         date,
+		UserID:       user.Subject,
+		RestaurantID: nv.RestaurantID,
+		OrgID:        user.Org(),
+		Weight:       nv.Count,
+		TimeVoted:    now.UTC(),
+	}
+	if v.Weight == 0 {
+		v.Weight = 1
+	}
+	if nv.OnBehalfOf != "" && nv.OnBehalfOf != user.Subject {
+		v.UserID, v.CastBy = nv.OnBehalfOf, user.Subject
+	}
+
+	if err := policy.Check(date, now); err != nil {
+		if err == ErrClosed {
+			if _, verr := retrieve(ctx, db, date, v.UserID); verr == nil {
+				return nil, ErrLocked
+			}
+		}
+		return nil, err
+	}
+
+	limit := 1
+	if v.CastBy != "" {
+		ok, err := delegated(ctx, database.Conn(ctx, db), v.UserID, v.CastBy, v.OrgID)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, ErrNotDelegated
+		}
+	} else if limit, err = maxCount(ctx, database.Conn(ctx, db), user); err != nil {
+		return nil, err
+	}
+	if v.Weight > limit {
+		return nil, ErrWeightExceeded
+	}
+
+	if err := checkMode(ctx, db, v.OrgID, organization.VotingRestaurant); err != nil {
+		return nil, err
+	}
+
+	if _, err := restaurant.Retrieve(ctx, db, nv.RestaurantID); err != nil {
+		return nil, err
+	}
+
+	const q = `INSERT INTO vote
+		(date, user_id, restaurant_id, org_id, weight, cast_by, time_voted)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (date, user_id) DO UPDATE SET
+		"restaurant_id" = EXCLUDED.restaurant_id,
+		"org_id" = EXCLUDED.org_id,
+		"weight" = EXCLUDED.weight,
+		"cast_by" = EXCLUDED.cast_by,
+		"time_voted" = EXCLUDED.time_voted`
+
+	tx, err := database.Begin(ctx, db)
+	if err != nil {
+		return nil, errors.Wrap(err, "beginning transaction")
+	}
+	defer tx.Rollback()
+
+	// The tally moves from the restaurant of the previous vote of the user, if
+	// any, to the new one.
+	var previous struct {
+		RestaurantID string `db:"restaurant_id"`
+		Weight       int    `db:"weight"`
+	}
+	const qp = `SELECT restaurant_id, weight FROM vote WHERE date = $1 AND user_id = $2 FOR UPDATE`
+	if err := sqlx.GetContext(ctx, tx, &previous, qp, v.Date, v.UserID); err != nil && err != sql.ErrNoRows {
+		return nil, errors.Wrap(err, "selecting previous vote")
+	}
+
+	if _, err := tx.ExecContext(ctx, q, v.Date, v.UserID, v.RestaurantID, v.OrgID, v.Weight, v.CastBy, v.TimeVoted); err != nil {
+		return nil, errors.Wrap(err, "inserting vote")
+	}
+
+	e := Event{
+		Type:         EventCast,
+		Date:         v.Date,
+		UserID:       v.UserID,
+		RestaurantID: v.RestaurantID,
+		OrgID:        v.OrgID,
+		Weight:       v.Weight,
+		CastBy:       v.CastBy,
+		TimeOccurred: v.TimeVoted,
+	}
+	if previous.RestaurantID != "" {
+		e.Type = EventChanged
+	}
+	if err := appendEvent(ctx, tx, e); err != nil {
+		return nil, err
+	}
+
+	if previous.RestaurantID != v.RestaurantID || previous.Weight != v.Weight {
+		if err := addTally(ctx, tx, v.Date, v.RestaurantID, v.Weight); err != nil {
+			return nil, err
+		}
+		if previous.RestaurantID != "" {
+			if err := addTally(ctx, tx, v.Date, previous.RestaurantID, -previous.Weight); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := outbox.Add(ctx, tx, outbox.TypeVoteCast, v.RestaurantID, v, now); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrap(err, "committing vote")
+	}
+
+	return &v, nil
+}
+
+// Retract removes the user's vote for the date. Votes can only be retracted
+// until voting for their date closes.
+func Retract(ctx context.Context, db *sqlx.DB, user auth.Claims, date time.Time, policy Policy, now time.Time) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.vote.Retract")
+	defer span.End()
+
+	policy, err := OrgPolicy(ctx, database.Conn(ctx, db), user.Org(), policy)
+	if err != nil {
+		return err
+	}
+	if err := policy.Check(date, now); err == ErrClosed {
+		return ErrLocked
+	}
+
+	tx, err := database.Begin(ctx, db)
+	if err != nil {
+		return errors.Wrap(err, "beginning transaction")
+	}
+	defer tx.Rollback()
+
+	var v Vote
+	const qs = `SELECT * FROM vote WHERE date = $1 AND user_id = $2 FOR UPDATE`
+	if err := sqlx.GetContext(ctx, tx, &v, qs, date, user.Subject); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNotVoted
+		}
+		return errors.Wrap(err, "selecting vote")
+	}
+
+	const qd = `DELETE FROM vote WHERE date = $1 AND user_id = $2`
+	if _, err := tx.ExecContext(ctx, qd, date, user.Subject); err != nil {
+		return errors.Wrap(err, "deleting vote")
+	}
+
+	if err := addTally(ctx, tx, date, v.RestaurantID, -v.Weight); err != nil {
+		return err
+	}
+
+	e := Event{
+		Type:         EventRetracted,
+		Date:         date,
+		UserID:       v.UserID,
+		RestaurantID: v.RestaurantID,
+		OrgID:        v.OrgID,
+		Weight:       v.Weight,
+		TimeOccurred: now.UTC(),
+	}
+	if err := appendEvent(ctx, tx, e); err != nil {
+		return err
+	}
+
+	if err := outbox.Add(ctx, tx, outbox.TypeVoteRetracted, v.RestaurantID, v, now); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, "committing vote retraction")
+	}
+
+	return nil
+}
+
+// retrieve finds the user's vote for the date.
+func retrieve(ctx context.Context, db *sqlx.DB, date time.Time, userID string) (*Vote, error) {
+	var v Vote
+	const q = `SELECT * FROM vote WHERE date = $1 AND user_id = $2`
+	if err := sqlx.GetContext(ctx, database.Conn(ctx, db), &v, q, date, userID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotVoted
+		}
+		return nil, errors.Wrap(err, "selecting vote")
+	}
+	return &v, nil
+}
+
+// History calls fn with every vote cast for the dates from through to, oldest
+// date first. The votes are read as fn consumes them so a long history is
+// never held in memory. The user and delegate of the anonymous votes are
+// left blank by the query so they never leave the database.
+func History(ctx context.Context, db *sqlx.DB, from, to time.Time, fn func(Vote) error) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.vote.History")
+	defer span.End()
+
+	const q = `SELECT v.date, v.restaurant_id, v.org_id, v.weight, v.time_voted,
+		CASE WHEN ` + anonymous + ` THEN '' ELSE v.user_id::text END AS user_id,
+		CASE WHEN ` + anonymous + ` THEN '' ELSE v.cast_by END AS cast_by
+		FROM vote AS v
+		LEFT JOIN org_settings AS s ON s.org_id = v.org_id
+		LEFT JOIN restaurant AS r ON r.restaurant_id = v.restaurant_id
+		WHERE v.date >= $1 AND v.date <= $2 AND ($3 = '' OR v.org_id::text = $3)
+		ORDER BY v.date, v.time_voted`
+	rows, err := database.Conn(ctx, db).QueryxContext(ctx, q, day(from), day(to), auth.Org(ctx))
+	if err != nil {
+		return errors.Wrap(err, "selecting votes")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var v Vote
+		if err := rows.StructScan(&v); err != nil {
+			return errors.Wrap(err, "scanning vote")
+		}
+		if err := fn(v); err != nil {
+			return err
+		}
+	}
+
+	return errors.Wrap(rows.Err(), "reading votes")
+}
+
+// anonymous is the condition of the votes being anonymous, either in the
+// settings of the organization joined as s or in the restaurant joined as r.
+const anonymous = `(COALESCE(s.anonymous_votes, FALSE) OR COALESCE(r.anonymous_votes, FALSE))`
+
+// Voters returns the users who voted for the restaurant on the date in the
+// organization of the claims in ctx, first voter first. It fails with
+// ErrAnonymous when the votes of the restaurant are anonymous.
+func Voters(ctx context.Context, db *sqlx.DB, restaurantID string, date time.Time) ([]string, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.vote.Voters")
+	defer span.End()
+
+	org := auth.Org(ctx)
+	if org == "" {
+		org = auth.DefaultOrg
+	}
+
+	var anon bool
+	const qa = `SELECT ` + anonymous + ` FROM restaurant AS r
+		LEFT JOIN org_settings AS s ON s.org_id::text = $2
+		WHERE r.restaurant_id = $1`
+	if err := sqlx.GetContext(ctx, database.Conn(ctx, db), &anon, qa, restaurantID, org); err != nil && err != sql.ErrNoRows {
+		return nil, errors.Wrap(err, "selecting vote anonymity")
+	}
+	if anon {
+		return nil, ErrAnonymous
+	}
+
+	voters := []string{}
+	const q = `SELECT user_id FROM vote WHERE date = $1 AND restaurant_id = $2 AND org_id::text = $3
+		ORDER BY time_voted`
+	if err := sqlx.SelectContext(ctx, database.Conn(ctx, db), &voters, q, date, restaurantID, org); err != nil {
+		return nil, errors.Wrap(err, "selecting voters")
+	}
+
+	return voters, nil
+}
+
+// Tallies counts the votes for each restaurant on the date, most votes first.
+func Tallies(ctx context.Context, db *sqlx.DB, date time.Time) ([]Tally, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.vote.Tallies")
+	defer span.End()
+
+	return tallies(ctx, db, date, auth.Org(ctx))
+}
+
+// tallies counts the votes cast in the organization, or in all of them when
+// it is blank.
+func tallies(ctx context.Context, db *sqlx.DB, date time.Time, org string) ([]Tally, error) {
+	tallies := []Tally{}
+	const q = `SELECT restaurant_id, SUM(weight) AS votes FROM vote
+		WHERE date = $1 AND ($2 = '' OR org_id::text = $2)
+		GROUP BY restaurant_id
+		ORDER BY votes DESC, MIN(time_voted)`
+	if err := sqlx.SelectContext(ctx, database.Conn(ctx, db), &tallies, q, date, org); err != nil {
+		return nil, errors.Wrap(err, "selecting tallies")
+	}
+
+	return tallies, nil
+}
+
+// RetrieveTally gets the number of votes of the restaurant on the date from
+// the tally kept up to date by Cast. The restaurants of other organizations
+// than the one of the claims in ctx have no votes.
+func RetrieveTally(ctx context.Context, db *sqlx.DB, restaurantID string, date time.Time) (*Tally, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.vote.RetrieveTally")
+	defer span.End()
+
+	t := Tally{RestaurantID: restaurantID}
+	const q = `SELECT t.votes FROM menu_vote_tally AS t
+		JOIN restaurant AS r ON r.restaurant_id = t.restaurant_id
+		WHERE t.date = $1 AND t.restaurant_id = $2
+		AND ($3 = '' OR r.org_id::text = $3)`
+	if err := sqlx.GetContext(ctx, database.Conn(ctx, db), &t.Votes, q, date, restaurantID, auth.Org(ctx)); err != nil && err != sql.ErrNoRows {
+		return nil, errors.Wrap(err, "selecting tally")
+	}
+
+	return &t, nil
+}
+
+// addTally adds n votes to the tally of the restaurant on the date.
+func addTally(ctx context.Context, tx sqlx.ExecerContext, date time.Time, restaurantID string, n int) error {
+	const q = `INSERT INTO menu_vote_tally
+		(date, restaurant_id, votes)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (date, restaurant_id) DO UPDATE SET
+		"votes" = menu_vote_tally.votes + EXCLUDED.votes`
+	if _, err := tx.ExecContext(ctx, q, date, restaurantID, n); err != nil {
+		return errors.Wrap(err, "updating tally")
+	}
+	return nil
+}
+
+// RetrieveWinner gets the winner computed for the date in the organization
+// of the claims in ctx.
+func RetrieveWinner(ctx context.Context, db *sqlx.DB, date time.Time) (*Winner, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.vote.RetrieveWinner")
+	defer span.End()
+
+	org := auth.Org(ctx)
+	if org == "" {
+		org = auth.DefaultOrg
+	}
+
+	var w Winner
+	const q = `SELECT * FROM winner WHERE date = $1 AND org_id = $2`
+	if err := sqlx.GetContext(ctx, database.Conn(ctx, db), &w, q, date, org); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNoWinner
+		}
+		return nil, errors.Wrap(err, "selecting winner")
+	}
+
+	return &w, nil
+}
+
+// ComputeWinner stores the restaurant with the most votes in the organization
+// as its winner of the date. Ties go to the restaurant which received its
+// first vote earliest, or are drawn when the settings of the organization
+// say so. A restaurant which won the last lunches of the streak limit of the
+// organization only wins when nobody voted for another one. Nothing is
+// stored when nobody voted.
+func ComputeWinner(ctx context.Context, db *sqlx.DB, date time.Time, org string, now time.Time) (*Winner, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.vote.ComputeWinner")
+	defer span.End()
+
+	tallies, err := tallies(ctx, db, date, org)
+	if err != nil {
+		return nil, err
+	}
+	if len(tallies) == 0 {
+		return nil, ErrNoWinner
+	}
+
+	s, err := organization.LoadSettings(ctx, database.Conn(ctx, db), org)
+	if err != nil {
+		return nil, err
+	}
+	var recent []string
+	if s.StreakLimit > 0 {
+		if recent, err = recentWinners(ctx, database.Conn(ctx, db), org, date, s.StreakLimit); err != nil {
+			return nil, err
+		}
+	}
+	t := pick(tallies, date, s.TieBreak, barred(recent, s.StreakLimit))
+
+	w := Winner{
+		Date:         date,
+		RestaurantID: t.RestaurantID,
+		OrgID:        org,
+		Votes:        t.Votes,
+		DateComputed: now.UTC(),
+	}
+
+	const q = `INSERT INTO winner
+		(date, restaurant_id, org_id, votes, date_computed)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (date, org_id) DO NOTHING`
+	if _, err := database.Conn(ctx, db).ExecContext(ctx, q, w.Date, w.RestaurantID, w.OrgID, w.Votes, w.DateComputed); err != nil {
+		return nil, errors.Wrap(err, "inserting winner")
+	}
+
+	return &w, nil
+}
+
+// pending is a date an organization voted for.
+type pending struct {
+	Date  time.Time `db:"date"`
+	OrgID string    `db:"org_id"`
+}
+
+// pendingDates returns the dates and organizations which received votes, have
+// closed and do not have a winner yet.
+func pendingDates(ctx context.Context, db *sqlx.DB, policy Policy, now time.Time) ([]pending, error) {
+	dates := []pending{}
+	const q = `SELECT DISTINCT v.date, v.org_id FROM vote AS v
+		LEFT JOIN winner AS w ON w.date = v.date AND w.org_id = v.org_id
+		WHERE w.date IS NULL AND v.date <= $1
+		ORDER BY v.date, v.org_id`
+	if err := sqlx.SelectContext(ctx, database.Conn(ctx, db), &dates, q, day(now).AddDate(0, 0, 1)); err != nil {
+		return nil, errors.Wrap(err, "selecting pending dates")
+	}
+
+	return closedDates(ctx, db, dates, policy, now)
+}
+
+// closedDates keeps the dates whose voting has closed by now under the
+// policy of their organization. Organizations ahead of UTC may close the
+// next day of UTC already.
+func closedDates(ctx context.Context, db *sqlx.DB, dates []pending, policy Policy, now time.Time) ([]pending, error) {
+	policies := make(map[string]Policy)
+	kept := dates[:0]
+	for _, d := range dates {
+		p, ok := policies[d.OrgID]
+		if !ok {
+			var err error
+			if p, err = OrgPolicy(ctx, database.Conn(ctx, db), d.OrgID, policy); err != nil {
+				return nil, err
+			}
+			policies[d.OrgID] = p
+		}
+		if !now.UTC().Before(p.closes(d.Date)) {
+			kept = append(kept, d)
+		}
+	}
+	return kept, nil
+}
+
+// day truncates the time to midnight UTC of its day.
+func day(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
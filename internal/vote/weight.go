@@ -0,0 +1,164 @@
+package vote
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/database"
+	"github.com/remisb/restaurant/internal/team"
+	"go.opentelemetry.io/otel"
+)
+
+// CreateWeight adds the weight to the organization of the admin. The team of
+// the weight must belong to it.
+func CreateWeight(ctx context.Context, db *sqlx.DB, user auth.Claims, nw NewWeight, now time.Time) (*Weight, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.vote.CreateWeight")
+	defer span.End()
+
+	if nw.TeamID != nil {
+		if _, err := team.Retrieve(ctx, db, *nw.TeamID); err != nil {
+			return nil, err
+		}
+	}
+
+	w := Weight{
+		ID:          uuid.New().String(),
+		OrgID:       user.Org(),
+		Role:        nw.Role,
+		TeamID:      nw.TeamID,
+		MaxCount:    nw.MaxCount,
+		DateCreated: now.UTC(),
+	}
+
+	const q = `INSERT INTO vote_weight
+		(weight_id, org_id, role, team_id, max_count, date_created)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+	if _, err := db.ExecContext(ctx, q, w.ID, w.OrgID, w.Role, w.TeamID, w.MaxCount, w.DateCreated); err != nil {
+		return nil, errors.Wrap(err, "inserting vote weight")
+	}
+
+	return &w, nil
+}
+
+// ListWeights gets the weights of the organization of the claims in ctx.
+func ListWeights(ctx context.Context, db *sqlx.DB) ([]Weight, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.vote.ListWeights")
+	defer span.End()
+
+	weights := []Weight{}
+	const q = `SELECT * FROM vote_weight WHERE ($1 = '' OR org_id::text = $1)
+		ORDER BY date_created`
+	if err := db.SelectContext(ctx, &weights, q, auth.Org(ctx)); err != nil {
+		return nil, errors.Wrap(err, "selecting vote weights")
+	}
+
+	return weights, nil
+}
+
+// DeleteWeight removes the weight from the organization of the claims in
+// ctx.
+func DeleteWeight(ctx context.Context, db *sqlx.DB, id string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.vote.DeleteWeight")
+	defer span.End()
+
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrInvalidID
+	}
+
+	const q = `DELETE FROM vote_weight WHERE weight_id = $1 AND ($2 = '' OR org_id::text = $2)`
+	res, err := db.ExecContext(ctx, q, id, auth.Org(ctx))
+	if err != nil {
+		return errors.Wrapf(err, "deleting vote weight %s", id)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrWeightNotFound
+	}
+
+	return nil
+}
+
+// maxCount returns the most votes the user may cast at once, the largest
+// weight they match or 1 when they match none.
+func maxCount(ctx context.Context, q sqlx.QueryerContext, user auth.Claims) (int, error) {
+	var n int
+	const qw = `SELECT COALESCE(MAX(w.max_count), 1) FROM vote_weight AS w
+		WHERE w.org_id = $1
+		AND (w.role = '' OR w.role = ANY($2))
+		AND (w.team_id IS NULL OR EXISTS (
+			SELECT 1 FROM team_member AS m WHERE m.team_id = w.team_id AND m.user_id = $3
+		))`
+	if err := sqlx.GetContext(ctx, q, &n, qw, user.Org(), pq.StringArray(user.Roles), user.Subject); err != nil {
+		return 0, errors.Wrap(err, "selecting vote weight")
+	}
+	if n < 1 {
+		n = 1
+	}
+	return n, nil
+}
+
+// Delegate authorizes the delegate to vote on behalf of the user, replacing
+// the user's previous delegate. The delegate must be another user of the
+// organization.
+func Delegate(ctx context.Context, db *sqlx.DB, user auth.Claims, nd NewDelegation, now time.Time) (*Delegation, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.vote.Delegate")
+	defer span.End()
+
+	if nd.DelegateID == user.Subject {
+		return nil, ErrInvalidDelegate
+	}
+
+	d := Delegation{
+		UserID:      user.Subject,
+		DelegateID:  nd.DelegateID,
+		OrgID:       user.Org(),
+		DateCreated: now.UTC(),
+	}
+
+	const q = `INSERT INTO vote_delegation
+		(user_id, delegate_id, org_id, date_created)
+		SELECT $1, user_id, $3, $4 FROM users
+		WHERE user_id = $2 AND org_id = $3 AND deleted_at IS NULL
+		ON CONFLICT (user_id) DO UPDATE SET
+		"delegate_id" = EXCLUDED.delegate_id,
+		"org_id" = EXCLUDED.org_id,
+		"date_created" = EXCLUDED.date_created`
+	res, err := database.Conn(ctx, db).ExecContext(ctx, q, d.UserID, d.DelegateID, d.OrgID, d.DateCreated)
+	if err != nil {
+		return nil, errors.Wrap(err, "inserting vote delegation")
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return nil, ErrInvalidDelegate
+	}
+
+	return &d, nil
+}
+
+// Undelegate takes the user's vote back from their delegate.
+func Undelegate(ctx context.Context, db *sqlx.DB, user auth.Claims) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.vote.Undelegate")
+	defer span.End()
+
+	const q = `DELETE FROM vote_delegation WHERE user_id = $1`
+	if _, err := database.Conn(ctx, db).ExecContext(ctx, q, user.Subject); err != nil {
+		return errors.Wrap(err, "deleting vote delegation")
+	}
+
+	return nil
+}
+
+// delegated reports whether the user delegated their vote to the delegate
+// within the organization.
+func delegated(ctx context.Context, q sqlx.QueryerContext, userID, delegateID, org string) (bool, error) {
+	var n int
+	const qd = `SELECT COUNT(*) FROM vote_delegation
+		WHERE user_id = $1 AND delegate_id = $2 AND org_id = $3`
+	if err := sqlx.GetContext(ctx, q, &n, qd, userID, delegateID, org); err != nil {
+		return false, errors.Wrap(err, "selecting vote delegation")
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,74 @@
+package webhook
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// These are the events a Webhook can subscribe to.
+const (
+	EventRestaurantCreated = "restaurant.created"
+	EventMenuPublished     = "menu.published"
+	EventVoteClosed        = "vote.closed"
+	EventWinnerAnnounced   = "winner.announced"
+)
+
+// Events lists every event a Webhook can subscribe to.
+var Events = []string{
+	EventRestaurantCreated,
+	EventMenuPublished,
+	EventVoteClosed,
+	EventWinnerAnnounced,
+}
+
+// These are the states of a single Delivery.
+const (
+	StatusPending   = "PENDING"
+	StatusDelivered = "DELIVERED"
+	StatusFailed    = "FAILED"
+)
+
+// Webhook is a callback URL receiving a signed POST for every event it is
+// subscribed to. The secret is only returned when the Webhook is created.
+type Webhook struct {
+	ID          string         `db:"webhook_id" json:"id"`
+	OrgID       string         `db:"org_id" json:"org_id"`
+	URL         string         `db:"url" json:"url"`
+	Events      pq.StringArray `db:"events" json:"events"`
+	Secret      string         `db:"secret" json:"secret,omitempty"`
+	DateCreated time.Time      `db:"date_created" json:"date_created"`
+}
+
+// NewWebhook is what we require from admins when registering a Webhook. A
+// secret is generated when none is provided.
+type NewWebhook struct {
+	URL    string   `json:"url" validate:"required,url"`
+	Events []string `json:"events" validate:"required,min=1"`
+	Secret string   `json:"secret"`
+}
+
+// Delivery is a single event sent to a Webhook along with the outcome of the
+// latest attempt.
+type Delivery struct {
+	ID            string          `db:"delivery_id" json:"id"`
+	WebhookID     string          `db:"webhook_id" json:"webhook_id"`
+	Event         string          `db:"event" json:"event"`
+	Payload       json.RawMessage `db:"payload" json:"payload"`
+	Status        string          `db:"status" json:"status"`
+	Attempts      int             `db:"attempts" json:"attempts"`
+	ResponseCode  int             `db:"response_code" json:"response_code,omitempty"`
+	Error         string          `db:"error" json:"error,omitempty"`
+	NextAttempt   time.Time       `db:"next_attempt" json:"next_attempt"`
+	DateCreated   time.Time       `db:"date_created" json:"date_created"`
+	DateDelivered *time.Time      `db:"date_delivered" json:"date_delivered,omitempty"`
+}
+
+// envelope is the body POSTed to a Webhook.
+type envelope struct {
+	ID         string      `json:"id"`
+	Event      string      `json:"event"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Data       interface{} `json:"data"`
+}
@@ -0,0 +1,31 @@
+package webhook
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"github.com/remisb/restaurant/internal/platform/database"
+)
+
+// Notifier queues events for the handlers which do not work with the
+// database directly. A nil Notifier drops the events.
+type Notifier struct {
+	db *sqlx.DB
+}
+
+// NewNotifier constructs a Notifier queueing deliveries in the database.
+func NewNotifier(db *sqlx.DB) *Notifier {
+	return &Notifier{db: db}
+}
+
+// Notify queues a delivery of the event to every subscribed Webhook of the
+// organization of the claims in ctx. The deliveries are part of the
+// transaction carried by ctx, if any.
+func (n *Notifier) Notify(ctx context.Context, event string, data interface{}, now time.Time) error {
+	if n == nil {
+		return nil
+	}
+	return Enqueue(ctx, database.Conn(ctx, n.db), auth.Org(ctx), event, data, now)
+}
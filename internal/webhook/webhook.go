@@ -0,0 +1,233 @@
+// Package webhook lets admins register callback URLs which receive a signed
+// POST whenever an event they subscribed to happens. Deliveries are queued in
+// the database and sent by the Worker, which retries failures with backoff.
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/platform/auth"
+	"go.opentelemetry.io/otel"
+)
+
+// Predefined errors identify expected failure conditions.
+var (
+	// ErrNotFound is used when a specific Webhook is requested but does not exist.
+	ErrNotFound = errors.New("Webhook not found")
+
+	// ErrInvalidID is used when an invalid UUID is provided.
+	ErrInvalidID = errors.New("ID is not in its proper form")
+
+	// ErrUnknownEvent is used when subscribing to an event which does not exist.
+	ErrUnknownEvent = errors.New("Unknown webhook event")
+)
+
+// Create registers a Webhook for the events of the NewWebhook in the
+// organization of the user.
+func Create(ctx context.Context, db *sqlx.DB, user auth.Claims, nw NewWebhook, now time.Time) (*Webhook, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.webhook.Create")
+	defer span.End()
+
+	for _, e := range nw.Events {
+		if !known(e) {
+			return nil, ErrUnknownEvent
+		}
+	}
+
+	secret := nw.Secret
+	if secret == "" {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return nil, errors.Wrap(err, "generating secret")
+		}
+		secret = hex.EncodeToString(b)
+	}
+
+	wh := Webhook{
+		ID:          uuid.New().String(),
+		OrgID:       user.Org(),
+		URL:         nw.URL,
+		Events:      nw.Events,
+		Secret:      secret,
+		DateCreated: now.UTC(),
+	}
+
+	const q = `INSERT INTO webhook
+		(webhook_id, org_id, url, events, secret, date_created)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+	if _, err := db.ExecContext(ctx, q, wh.ID, wh.OrgID, wh.URL, wh.Events, wh.Secret, wh.DateCreated); err != nil {
+		return nil, errors.Wrap(err, "inserting webhook")
+	}
+
+	return &wh, nil
+}
+
+// List gets the Webhooks of the organization of the claims in ctx without
+// their secrets.
+func List(ctx context.Context, db *sqlx.DB) ([]Webhook, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.webhook.List")
+	defer span.End()
+
+	webhooks := []Webhook{}
+	const q = `SELECT webhook_id, org_id, url, events, '' AS secret, date_created
+		FROM webhook WHERE ($1 = '' OR org_id::text = $1)
+		ORDER BY date_created`
+	if err := db.SelectContext(ctx, &webhooks, q, auth.Org(ctx)); err != nil {
+		return nil, errors.Wrap(err, "selecting webhooks")
+	}
+
+	return webhooks, nil
+}
+
+// Delete removes the Webhook of the organization of the claims in ctx along
+// with its deliveries.
+func Delete(ctx context.Context, db *sqlx.DB, id string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.webhook.Delete")
+	defer span.End()
+
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrInvalidID
+	}
+
+	const q = `DELETE FROM webhook WHERE webhook_id = $1 AND ($2 = '' OR org_id::text = $2)`
+	res, err := db.ExecContext(ctx, q, id, auth.Org(ctx))
+	if err != nil {
+		return errors.Wrapf(err, "deleting webhook %s", id)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Deliveries gets the latest deliveries of the Webhook of the organization of
+// the claims in ctx, newest first.
+func Deliveries(ctx context.Context, db *sqlx.DB, id string, limit int) ([]Delivery, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.webhook.Deliveries")
+	defer span.End()
+
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrInvalidID
+	}
+
+	var exists bool
+	const qe = `SELECT EXISTS (SELECT 1 FROM webhook WHERE webhook_id = $1
+		AND ($2 = '' OR org_id::text = $2))`
+	if err := db.GetContext(ctx, &exists, qe, id, auth.Org(ctx)); err != nil {
+		return nil, errors.Wrap(err, "selecting webhook")
+	}
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	deliveries := []Delivery{}
+	const q = `SELECT * FROM webhook_delivery WHERE webhook_id = $1
+		ORDER BY date_created DESC LIMIT $2`
+	if err := db.SelectContext(ctx, &deliveries, q, id, limit); err != nil {
+		return nil, errors.Wrap(err, "selecting deliveries")
+	}
+
+	return deliveries, nil
+}
+
+// Enqueue queues a delivery of the event of the organization to every Webhook
+// of that organization subscribed to it. The data is sent as the data field
+// of the body.
+func Enqueue(ctx context.Context, db sqlx.ExtContext, orgID string, event string, data interface{}, now time.Time) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.webhook.Enqueue")
+	defer span.End()
+
+	now = now.UTC()
+	id := uuid.New().String()
+
+	payload, err := json.Marshal(envelope{
+		ID:         id,
+		Event:      event,
+		OccurredAt: now,
+		Data:       data,
+	})
+	if err != nil {
+		return errors.Wrap(err, "encoding payload")
+	}
+
+	var webhooks []string
+	const qs = `SELECT webhook_id FROM webhook WHERE org_id::text = $1 AND $2 = ANY(events)`
+	if err := sqlx.SelectContext(ctx, db, &webhooks, qs, orgID, event); err != nil {
+		return errors.Wrap(err, "selecting subscribed webhooks")
+	}
+
+	// Every webhook gets its own delivery of the same payload so receivers
+	// can spot duplicates by the ID of the event.
+	const qi = `INSERT INTO webhook_delivery
+		(delivery_id, webhook_id, event, payload, status, attempts, response_code, error, next_attempt, date_created)
+		VALUES ($1, $2, $3, $4, $5, 0, 0, '', $6, $6)`
+	for _, wh := range webhooks {
+		if _, err := db.ExecContext(ctx, qi, uuid.New().String(), wh, event, payload, StatusPending, now); err != nil {
+			return errors.Wrapf(err, "queueing %s delivery", event)
+		}
+	}
+
+	return nil
+}
+
+// due locks the pending deliveries whose next attempt is due along with the
+// Webhook they are sent to. Deliveries locked by another transaction are
+// skipped so the workers of several replicas never send the same one.
+func due(ctx context.Context, tx *sqlx.Tx, now time.Time, limit int) ([]dueDelivery, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.webhook.due")
+	defer span.End()
+
+	deliveries := []dueDelivery{}
+	const q = `SELECT d.*, w.url, w.secret FROM webhook_delivery AS d
+		JOIN webhook AS w ON w.webhook_id = d.webhook_id
+		WHERE d.status = $1 AND d.next_attempt <= $2
+		ORDER BY d.next_attempt LIMIT $3
+		FOR UPDATE OF d SKIP LOCKED`
+	if err := tx.SelectContext(ctx, &deliveries, q, StatusPending, now.UTC(), limit); err != nil {
+		return nil, errors.Wrap(err, "selecting due deliveries")
+	}
+
+	return deliveries, nil
+}
+
+// record stores the outcome of an attempt to send the delivery.
+func record(ctx context.Context, tx *sqlx.Tx, d Delivery) error {
+	ctx, span := otel.Tracer("").Start(ctx, "internal.webhook.record")
+	defer span.End()
+
+	const q = `UPDATE webhook_delivery SET
+		status = $2, attempts = $3, response_code = $4, error = $5,
+		next_attempt = $6, date_delivered = $7
+		WHERE delivery_id = $1`
+	_, err := tx.ExecContext(ctx, q, d.ID, d.Status, d.Attempts, d.ResponseCode, d.Error, d.NextAttempt, d.DateDelivered)
+	if err != nil {
+		return errors.Wrapf(err, "recording delivery %s", d.ID)
+	}
+
+	return nil
+}
+
+// dueDelivery is a delivery along with where it is sent.
+type dueDelivery struct {
+	Delivery
+	URL    string `db:"url"`
+	Secret string `db:"secret"`
+}
+
+// known reports if the event can be subscribed to.
+func known(event string) bool {
+	for _, e := range Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+
+	"github.com/remisb/restaurant/internal/tests"
+)
+
+// TestBackoff validates retries are spread out exponentially and capped.
+func TestBackoff(t *testing.T) {
+	tt := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{5, 8 * time.Minute},
+		{10, 4*time.Hour + 16*time.Minute},
+		{11, 6 * time.Hour},
+		{50, 6 * time.Hour},
+	}
+
+	t.Log("Given the need to retry failed deliveries.")
+	{
+		for i, tc := range tt {
+			t.Logf("\tTest %d:\tWhen %d attempts failed.", i, tc.attempts)
+			{
+				if got := Backoff(tc.attempts); got != tc.want {
+					t.Fatalf("\t%s\tShould wait %v : got %v.", tests.Failed, tc.want, got)
+				}
+				t.Logf("\t%s\tShould wait %v.", tests.Success, tc.want)
+			}
+		}
+	}
+}
+
+// TestSign validates the signature receivers use to verify deliveries.
+func TestSign(t *testing.T) {
+	t.Log("Given the need to sign deliveries.")
+	{
+		t.Log("\tTest 0:\tWhen signing a body.")
+		{
+			// echo -n '1583150400.{"event":"restaurant.created"}' | openssl dgst -sha256 -hmac secret
+			const want = "b3839efd70ad81c4cfd9200e2416783dbae86220bb6b23de78dad98e5ddc8447"
+
+			got := Sign("secret", "1583150400", []byte(`{"event":"restaurant.created"}`))
+			if got != want {
+				t.Fatalf("\t%s\tShould get the HMAC-SHA256 of the timestamp and body : got %q.", tests.Failed, got)
+			}
+			t.Logf("\t%s\tShould get the HMAC-SHA256 of the timestamp and body.", tests.Success)
+
+			if Sign("other", "1583150400", []byte(`{"event":"restaurant.created"}`)) == got {
+				t.Fatalf("\t%s\tShould depend on the secret.", tests.Failed)
+			}
+			if Sign("secret", "1583150401", []byte(`{"event":"restaurant.created"}`)) == got {
+				t.Fatalf("\t%s\tShould depend on the timestamp.", tests.Failed)
+			}
+			t.Logf("\t%s\tShould depend on the secret and the timestamp.", tests.Success)
+		}
+	}
+}
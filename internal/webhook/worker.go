@@ -0,0 +1,200 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/remisb/restaurant/internal/platform/job"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// batchSize is the number of due deliveries sent per run of the Worker.
+const batchSize = 100
+
+// Worker sends the queued deliveries. A failed delivery is retried with an
+// exponential backoff until it was attempted MaxAttempts times. The workers
+// of every replica share the deliveries, each is sent by one of them.
+type Worker struct {
+	log         *log.Logger
+	db          *sqlx.DB
+	client      *http.Client
+	interval    time.Duration
+	maxAttempts int
+	tracker     *job.Tracker
+}
+
+// NewWorker constructs a Worker looking for due deliveries every interval.
+func NewWorker(log *log.Logger, db *sqlx.DB, interval, timeout time.Duration, maxAttempts int) *Worker {
+	return &Worker{
+		log: log,
+		db:  db,
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
+		},
+		interval:    interval,
+		maxAttempts: maxAttempts,
+		tracker:     job.NewTracker("webhook_delivery"),
+	}
+}
+
+// Status reports the state of the worker to the health check.
+func (w *Worker) Status() job.Status {
+	return w.tracker.Status()
+}
+
+// Run sends due deliveries until the context is canceled.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.tick(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// tick attempts every due delivery once.
+func (w *Worker) tick(ctx context.Context, now time.Time) {
+	err := w.deliver(ctx, now)
+	if err != nil {
+		w.log.Printf("webhook : ERROR : %+v", err)
+	}
+	w.tracker.Record(err, now)
+}
+
+// deliver sends a batch of due deliveries and records their outcome. The
+// deliveries stay locked until then so the worker of another replica does
+// not send them at the same time.
+func (w *Worker) deliver(ctx context.Context, now time.Time) error {
+	tx, err := w.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "beginning transaction")
+	}
+	defer tx.Rollback()
+
+	deliveries, err := due(ctx, tx, now, batchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range deliveries {
+		sent := w.send(ctx, d, now)
+		if err := record(ctx, tx, sent); err != nil {
+			return err
+		}
+		if sent.Status == StatusFailed {
+			w.log.Printf("webhook : %s : giving up after %d attempts : %s", d.ID, sent.Attempts, sent.Error)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, "committing deliveries")
+	}
+
+	return nil
+}
+
+// send makes an attempt to deliver the event and returns the delivery
+// updated with the outcome.
+func (w *Worker) send(ctx context.Context, d dueDelivery, now time.Time) Delivery {
+	out := d.Delivery
+	out.Attempts++
+
+	code, err := w.post(ctx, d)
+	out.ResponseCode = code
+
+	if err == nil {
+		delivered := time.Now().UTC()
+		out.Status = StatusDelivered
+		out.Error = ""
+		out.DateDelivered = &delivered
+		return out
+	}
+
+	out.Error = err.Error()
+	if out.Attempts >= w.maxAttempts {
+		out.Status = StatusFailed
+		return out
+	}
+	out.NextAttempt = now.UTC().Add(Backoff(out.Attempts))
+
+	return out
+}
+
+// post sends the payload to the URL of the webhook. Any response outside of
+// the 2xx range is a failure.
+func (w *Worker) post(ctx context.Context, d dueDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", d.Event)
+	req.Header.Set("X-Webhook-Delivery", d.ID)
+	req.Header.Set("X-Webhook-Timestamp", ts)
+	req.Header.Set("X-Webhook-Signature", "sha256="+Sign(d.Secret, ts, d.Payload))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of the timestamp and the body
+// keyed by the secret of the webhook. Receivers compute the same value to
+// verify the request came from us and reject old timestamps to prevent
+// replays.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Backoff returns how long to wait before the next attempt once a delivery
+// failed the given number of attempts. It doubles from 30 seconds up to six
+// hours.
+func Backoff(attempts int) time.Duration {
+	const (
+		base = 30 * time.Second
+		max  = 6 * time.Hour
+	)
+
+	d := base
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= max {
+			return max
+		}
+	}
+	return d
+}